@@ -0,0 +1,131 @@
+package boringcrypto
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The settings approved by the FIPS 140-2 policy of crypto/tls/fipsonly,
+// which is applied to every TLS connection when Enabled is true. Settings
+// which aren't approved would make connections fail at handshake time, so
+// they're rejected when River blocks are loaded instead.
+var (
+	approvedTLSVersions = []uint16{
+		tls.VersionTLS12,
+		tls.VersionTLS13,
+	}
+	approvedCipherSuites = []uint16{
+		tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	}
+	approvedCurves = []tls.CurveID{
+		tls.CurveP256,
+		tls.CurveP384,
+		tls.CurveP521,
+	}
+)
+
+// CheckTLSVersion returns an error if Enabled is true and the TLS version v,
+// set by the attribute named name, isn't FIPS-approved. A zero v, which
+// selects the default version, is always allowed.
+func CheckTLSVersion(name string, v uint16) error {
+	if !Enabled {
+		return nil
+	}
+	return checkTLSVersion(name, v)
+}
+
+func checkTLSVersion(name string, v uint16) error {
+	if v == 0 || contains(approvedTLSVersions, v) {
+		return nil
+	}
+	return fmt.Errorf("%s %s is not FIPS-approved; use TLS 1.2 or later", name, tls.VersionName(v))
+}
+
+// CheckCipherSuites returns an error if Enabled is true and any of the cipher
+// suites set by the attribute named name isn't FIPS-approved.
+func CheckCipherSuites(name string, suites []uint16) error {
+	if !Enabled {
+		return nil
+	}
+	return checkCipherSuites(name, suites)
+}
+
+func checkCipherSuites(name string, suites []uint16) error {
+	for _, s := range suites {
+		if !contains(approvedCipherSuites, s) {
+			approved := make([]string, 0, len(approvedCipherSuites))
+			for _, a := range approvedCipherSuites {
+				approved = append(approved, tls.CipherSuiteName(a))
+			}
+			return fmt.Errorf("%s: cipher suite %s is not FIPS-approved; approved cipher suites are %s", name, tls.CipherSuiteName(s), strings.Join(approved, ", "))
+		}
+	}
+	return nil
+}
+
+// CheckCurves returns an error if Enabled is true and any of the curves set
+// by the attribute named name isn't FIPS-approved.
+func CheckCurves(name string, curves []tls.CurveID) error {
+	if !Enabled {
+		return nil
+	}
+	return checkCurves(name, curves)
+}
+
+func checkCurves(name string, curves []tls.CurveID) error {
+	for _, c := range curves {
+		if !contains(approvedCurves, c) {
+			return fmt.Errorf("%s: curve %s is not FIPS-approved; approved curves are CurveP256, CurveP384, CurveP521", name, c)
+		}
+	}
+	return nil
+}
+
+// CheckAlgorithm returns an error if Enabled is true and the algorithm value,
+// set by the attribute named name, isn't one of the FIPS-approved algorithms
+// listed in approved. It's used for settings which aren't TLS settings, such
+// as the authentication protocols of SNMPv3.
+func CheckAlgorithm(name, value string, approved ...string) error {
+	if !Enabled {
+		return nil
+	}
+	return checkAlgorithm(name, value, approved)
+}
+
+func checkAlgorithm(name, value string, approved []string) error {
+	if contains(approved, value) {
+		return nil
+	}
+	return fmt.Errorf("%s %q is not FIPS-approved; approved values are %s", name, value, strings.Join(approved, ", "))
+}
+
+func contains[T comparable](values []T, v T) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCollector returns a collector which exposes whether the agent was built
+// with FIPS-validated cryptography.
+func NewCollector() prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "agent_boringcrypto_enabled",
+		Help: "Whether the agent was built with FIPS-validated cryptography and rejects non-FIPS-approved settings (1) or not (0).",
+	}, func() float64 {
+		if Enabled {
+			return 1
+		}
+		return 0
+	})
+}
@@ -0,0 +1,41 @@
+package boringcrypto
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTLSVersion(t *testing.T) {
+	require.NoError(t, checkTLSVersion("min_version", 0))
+	require.NoError(t, checkTLSVersion("min_version", tls.VersionTLS12))
+	require.NoError(t, checkTLSVersion("min_version", tls.VersionTLS13))
+	require.EqualError(t, checkTLSVersion("min_version", tls.VersionTLS10), "min_version TLS 1.0 is not FIPS-approved; use TLS 1.2 or later")
+}
+
+func TestCheckCipherSuites(t *testing.T) {
+	require.NoError(t, checkCipherSuites("cipher_suites", nil))
+	require.NoError(t, checkCipherSuites("cipher_suites", []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}))
+	require.EqualError(t,
+		checkCipherSuites("cipher_suites", []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}),
+		"cipher_suites: cipher suite TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256 is not FIPS-approved; approved cipher suites are "+
+			"TLS_RSA_WITH_AES_128_GCM_SHA256, TLS_RSA_WITH_AES_256_GCM_SHA384, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	)
+}
+
+func TestCheckCurves(t *testing.T) {
+	require.NoError(t, checkCurves("curve_preferences", []tls.CurveID{tls.CurveP256, tls.CurveP521}))
+	require.EqualError(t,
+		checkCurves("curve_preferences", []tls.CurveID{tls.X25519}),
+		"curve_preferences: curve X25519 is not FIPS-approved; approved curves are CurveP256, CurveP384, CurveP521",
+	)
+}
+
+func TestCheckAlgorithm(t *testing.T) {
+	require.NoError(t, checkAlgorithm("auth_protocol", "SHA256", []string{"SHA", "SHA256"}))
+	require.EqualError(t,
+		checkAlgorithm("auth_protocol", "MD5", []string{"SHA", "SHA256"}),
+		`auth_protocol "MD5" is not FIPS-approved; approved values are SHA, SHA256`,
+	)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+// maxLatency is the highest latency which is measured precisely. Higher
+// latencies are counted as maxLatency.
+const maxLatency = 2 * time.Minute
+
+// endpoint is a fake remote_write endpoint, which counts the samples it
+// receives and measures their latency.
+type endpoint struct {
+	srv *httptest.Server
+
+	mut      sync.Mutex
+	requests uint64
+	samples  uint64
+	// latencies counts the samples received for each latency in milliseconds,
+	// which is the time between the timestamp of a sample and its receipt.
+	latencies []uint64
+}
+
+func newEndpoint() *endpoint {
+	e := &endpoint{
+		latencies: make([]uint64, maxLatency.Milliseconds()+1),
+	}
+	e.srv = httptest.NewServer(http.HandlerFunc(e.handle))
+	return e
+}
+
+func (e *endpoint) handle(w http.ResponseWriter, r *http.Request) {
+	req, err := remote.DecodeWriteRequest(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UnixMilli()
+
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	e.requests++
+	for _, ts := range req.Timeseries {
+		for _, s := range ts.Samples {
+			e.observe(now - s.Timestamp)
+		}
+		for _, h := range ts.Histograms {
+			e.observe(now - h.Timestamp)
+		}
+	}
+}
+
+func (e *endpoint) observe(latencyMs int64) {
+	e.samples++
+	latencyMs = max(0, min(latencyMs, int64(len(e.latencies)-1)))
+	e.latencies[latencyMs]++
+}
+
+// URL returns the URL to send samples to.
+func (e *endpoint) URL() string {
+	return e.srv.URL + "/api/v1/push"
+}
+
+// endpointStats holds what the endpoint received.
+type endpointStats struct {
+	Requests uint64
+	Samples  uint64
+
+	LatencyP50, LatencyP90, LatencyP99 time.Duration
+}
+
+// Stats returns what the endpoint has received so far.
+func (e *endpoint) Stats() endpointStats {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	return endpointStats{
+		Requests:   e.requests,
+		Samples:    e.samples,
+		LatencyP50: e.quantile(0.50),
+		LatencyP90: e.quantile(0.90),
+		LatencyP99: e.quantile(0.99),
+	}
+}
+
+// quantile returns the q-quantile of the latencies, rounded up to the
+// millisecond.
+func (e *endpoint) quantile(q float64) time.Duration {
+	if e.samples == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(e.samples))
+	var seen uint64
+	for ms, count := range e.latencies {
+		seen += count
+		if seen > rank {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return maxLatency
+}
+
+// Close stops the endpoint.
+func (e *endpoint) Close() {
+	e.srv.Close()
+}
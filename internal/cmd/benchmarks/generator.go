@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// generatorTick is how often the load generator appends a batch of samples.
+const generatorTick = 100 * time.Millisecond
+
+// loadGenerator appends samples of a fixed set of series to a receiver at a
+// constant rate, as a receiving component such as prometheus.receive_http
+// would.
+type loadGenerator struct {
+	next   storage.Appendable
+	series []labels.Labels
+	rate   int
+}
+
+func newLoadGenerator(next storage.Appendable, series, rate int) *loadGenerator {
+	g := &loadGenerator{next: next, rate: rate}
+	for i := 0; i < series; i++ {
+		g.series = append(g.series, labels.FromStrings(
+			"__name__", "benchmarks_series",
+			"job", "benchmarks",
+			"series", strconv.Itoa(i),
+		))
+	}
+	return g
+}
+
+// Run appends samples until ctx is canceled. The samples of each tick are
+// appended in a single commit, cycling through the series.
+func (g *loadGenerator) Run(ctx context.Context) {
+	ticker := time.NewTicker(generatorTick)
+	defer ticker.Stop()
+
+	perTick := max(1, g.rate*int(generatorTick)/int(time.Second))
+	next := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			app := g.next.Appender(ctx)
+			ts := now.UnixMilli()
+			for i := 0; i < perTick; i++ {
+				// Errors are only returned once the pipeline is stopping.
+				_, _ = app.Append(0, g.series[next], ts, float64(i))
+				next = (next + 1) % len(g.series)
+			}
+			_ = app.Commit()
+		}
+	}
+}
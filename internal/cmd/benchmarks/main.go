@@ -0,0 +1,115 @@
+// Command benchmarks runs soak and benchmark scenarios of the metrics
+// pipeline in-process, and reports its throughput, latency and allocations.
+//
+// Each scenario sends samples through prometheus.relabel and
+// prometheus.remote_write to a fake remote_write endpoint. The samples are
+// either scraped by prometheus.scrape from a farm of synthetic targets, or
+// appended directly by a load generator.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	cfg := defaultConfig
+
+	rootCmd := &cobra.Command{
+		Use:   "benchmarks",
+		Short: "Run benchmarks of the metrics pipeline",
+		Long: `Runs a benchmark scenario of the scrape, relabel, queue and send paths of
+the metrics pipeline in-process, and reports its throughput, latency and
+allocations once the scenario ends.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return run(cmd.Context(), cfg, os.Stdout)
+		},
+	}
+
+	fs := rootCmd.Flags()
+	fs.StringVar(&cfg.Scenario, "scenario", cfg.Scenario, fmt.Sprintf("Scenario to run (%q or %q)", scenarioScrape, scenarioAppend))
+	fs.DurationVar(&cfg.Duration, "duration", cfg.Duration, "How long to run the scenario for")
+	fs.IntVar(&cfg.Targets, "targets", cfg.Targets, "Number of synthetic targets to scrape")
+	fs.IntVar(&cfg.Series, "series", cfg.Series, "Number of series exposed by each target, or appended by the load generator")
+	fs.DurationVar(&cfg.ScrapeInterval, "scrape-interval", cfg.ScrapeInterval, "How often the synthetic targets are scraped")
+	fs.IntVar(&cfg.Rate, "rate", cfg.Rate, "Number of samples appended per second by the load generator")
+	fs.DurationVar(&cfg.BatchSendDeadline, "batch-send-deadline", cfg.BatchSendDeadline, "Maximum time samples wait in the remote_write queue before being sent")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, fmt.Sprintf("Format of the report (%q or %q)", formatText, formatJSON))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Scenarios which can be run.
+const (
+	scenarioScrape = "scrape"
+	scenarioAppend = "append"
+)
+
+// Formats of the report.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+type config struct {
+	Scenario string
+	Duration time.Duration
+
+	Targets        int
+	Series         int
+	ScrapeInterval time.Duration
+	Rate           int
+
+	BatchSendDeadline time.Duration
+
+	Format string
+}
+
+var defaultConfig = config{
+	Scenario: scenarioScrape,
+	Duration: time.Minute,
+
+	Targets:        100,
+	Series:         1000,
+	ScrapeInterval: 10 * time.Second,
+	Rate:           100_000,
+
+	BatchSendDeadline: 5 * time.Second,
+
+	Format: formatText,
+}
+
+func (c config) validate() error {
+	switch c.Scenario {
+	case scenarioScrape, scenarioAppend:
+	default:
+		return fmt.Errorf("unknown scenario %q", c.Scenario)
+	}
+	switch c.Format {
+	case formatText, formatJSON:
+	default:
+		return fmt.Errorf("unknown report format %q", c.Format)
+	}
+	if c.Duration <= 0 {
+		return fmt.Errorf("duration must be greater than 0")
+	}
+	if c.Targets <= 0 || c.Series <= 0 || c.Rate <= 0 {
+		return fmt.Errorf("targets, series and rate must be greater than 0")
+	}
+	if c.ScrapeInterval <= 0 || c.BatchSendDeadline <= 0 {
+		return fmt.Errorf("scrape-interval and batch-send-deadline must be greater than 0")
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/prometheus/relabel"
+	"github.com/grafana/agent/internal/component/prometheus/remotewrite"
+	"github.com/grafana/agent/internal/component/prometheus/scrape"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/cluster"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/storage"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// IDs of the components of the pipeline.
+const (
+	scrapeID      = "prometheus.scrape.benchmarks"
+	relabelID     = "prometheus.relabel.benchmarks"
+	remoteWriteID = "prometheus.remote_write.benchmarks"
+)
+
+// relabelConfig exercises prometheus.relabel with a rule which matches every
+// series.
+const relabelConfig = `
+forward_to = []
+
+rule {
+	action       = "replace"
+	target_label = "pipeline"
+	replacement  = "benchmarks"
+}
+`
+
+const remoteWriteConfig = `
+endpoint {
+	url = %q
+
+	queue_config {
+		batch_send_deadline = %q
+	}
+
+	metadata_config {
+		send = false
+	}
+}
+`
+
+// pipeline runs the components of the metrics pipeline which forward samples
+// to a remote_write endpoint: prometheus.scrape, prometheus.relabel and
+// prometheus.remote_write.
+type pipeline struct {
+	logger   *logging.Logger
+	reg      *prometheus.Registry
+	ls       labelstore.LabelStore
+	dataPath string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	relabel storage.Appendable
+}
+
+// newPipeline starts prometheus.relabel and prometheus.remote_write, which
+// sends samples to endpointURL with the batch send deadline of cfg.
+func newPipeline(cfg config, endpointURL string) (*pipeline, error) {
+	logger, err := logging.New(os.Stderr, logging.Options{
+		Level:  logging.LevelWarn,
+		Format: logging.FormatLogfmt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dataPath, err := os.MkdirTemp("", "agent-benchmarks-*")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &pipeline{
+		logger:   logger,
+		reg:      prometheus.NewRegistry(),
+		dataPath: dataPath,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	p.ls = labelstore.New(logger, p.reg)
+
+	var rwArgs remotewrite.Arguments
+	if err := river.Unmarshal([]byte(fmt.Sprintf(remoteWriteConfig, endpointURL, cfg.BatchSendDeadline)), &rwArgs); err != nil {
+		p.Stop()
+		return nil, fmt.Errorf("building prometheus.remote_write arguments: %w", err)
+	}
+	var rwExports remotewrite.Exports
+	rw, err := remotewrite.New(p.options(remoteWriteID, func(e component.Exports) {
+		rwExports = e.(remotewrite.Exports)
+	}), rwArgs)
+	if err != nil {
+		p.Stop()
+		return nil, err
+	}
+	p.run(rw)
+
+	var relabelArgs relabel.Arguments
+	if err := river.Unmarshal([]byte(relabelConfig), &relabelArgs); err != nil {
+		p.Stop()
+		return nil, fmt.Errorf("building prometheus.relabel arguments: %w", err)
+	}
+	relabelArgs.ForwardTo = []storage.Appendable{rwExports.Receiver}
+	var relabelExports relabel.Exports
+	rl, err := relabel.New(p.options(relabelID, func(e component.Exports) {
+		relabelExports = e.(relabel.Exports)
+	}), relabelArgs)
+	if err != nil {
+		p.Stop()
+		return nil, err
+	}
+	p.run(rl)
+	p.relabel = relabelExports.Receiver
+
+	return p, nil
+}
+
+// Scrape starts prometheus.scrape, which scrapes targets every interval and
+// forwards the samples to prometheus.relabel.
+func (p *pipeline) Scrape(targets []discovery.Target, interval time.Duration) error {
+	var args scrape.Arguments
+	args.SetToDefault()
+	args.Targets = targets
+	args.ForwardTo = []storage.Appendable{p.relabel}
+	args.JobName = "benchmarks"
+	args.ScrapeInterval = interval
+	args.ScrapeTimeout = interval
+	if err := args.Validate(); err != nil {
+		return err
+	}
+
+	s, err := scrape.New(p.options(scrapeID, func(component.Exports) {}), args)
+	if err != nil {
+		return err
+	}
+	p.run(s)
+	return nil
+}
+
+// Receiver returns the receiver of prometheus.relabel.
+func (p *pipeline) Receiver() storage.Appendable {
+	return p.relabel
+}
+
+// SamplesForwarded returns the number of samples forwarded by
+// prometheus.relabel to prometheus.remote_write.
+func (p *pipeline) SamplesForwarded() uint64 {
+	families, err := p.reg.Gather()
+	if err != nil {
+		level.Warn(p.logger).Log("msg", "failed to gather metrics", "err", err)
+		return 0
+	}
+	for _, mf := range families {
+		if mf.GetName() != "agent_prometheus_forwarded_samples_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "component_id" && l.GetValue() == relabelID {
+					return uint64(m.GetCounter().GetValue())
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// Stop stops the components and removes their data.
+func (p *pipeline) Stop() {
+	p.cancel()
+	p.wg.Wait()
+	_ = os.RemoveAll(p.dataPath)
+}
+
+func (p *pipeline) run(c component.Component) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := c.Run(p.ctx); err != nil {
+			level.Error(p.logger).Log("msg", "component exited with an error", "err", err)
+		}
+	}()
+}
+
+func (p *pipeline) options(id string, onStateChange func(component.Exports)) component.Options {
+	return component.Options{
+		ID:     id,
+		Logger: log.With(p.logger, "component", id),
+		Registerer: prometheus.WrapRegistererWith(prometheus.Labels{
+			"component_id": id,
+		}, p.reg),
+		Tracer:         noop.NewTracerProvider(),
+		DataPath:       filepath.Join(p.dataPath, id),
+		OnStateChange:  onStateChange,
+		GetServiceData: p.getServiceData,
+	}
+}
+
+func (p *pipeline) getServiceData(name string) (interface{}, error) {
+	switch name {
+	case labelstore.ServiceName:
+		return p.ls, nil
+	case cluster.ServiceName:
+		return cluster.Mock(), nil
+	case http_service.ServiceName:
+		return http_service.Data{
+			HTTPListenAddr:   "127.0.0.1:0",
+			MemoryListenAddr: "benchmarks.internal:12345",
+			BaseHTTPPath:     "/",
+			DialFunc:         (&net.Dialer{}).DialContext,
+		}, nil
+	default:
+		return nil, fmt.Errorf("service %q does not exist", name)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+)
+
+// report holds the results of a scenario.
+type report struct {
+	Scenario        string  `json:"scenario"`
+	DurationSeconds float64 `json:"duration_seconds"`
+
+	// SamplesForwarded is the number of samples forwarded by
+	// prometheus.relabel to prometheus.remote_write.
+	SamplesForwarded uint64 `json:"samples_forwarded"`
+	// SamplesReceived is the number of samples received by the endpoint.
+	SamplesReceived  uint64  `json:"samples_received"`
+	Requests         uint64  `json:"requests"`
+	SamplesPerSecond float64 `json:"samples_per_second"`
+
+	// Latencies between the timestamp of the samples and their receipt by the
+	// endpoint.
+	LatencyP50Seconds float64 `json:"latency_p50_seconds"`
+	LatencyP90Seconds float64 `json:"latency_p90_seconds"`
+	LatencyP99Seconds float64 `json:"latency_p99_seconds"`
+
+	// Allocations of the whole process while the scenario ran, including the
+	// synthetic targets and the endpoint.
+	Allocs          uint64  `json:"allocs"`
+	AllocBytes      uint64  `json:"alloc_bytes"`
+	AllocsPerSample float64 `json:"allocs_per_sample"`
+	BytesPerSample  float64 `json:"bytes_per_sample"`
+	GCCycles        uint32  `json:"gc_cycles"`
+	HeapInuseBytes  uint64  `json:"heap_inuse_bytes"`
+}
+
+func newReport(scenario string, elapsed time.Duration, samplesForwarded uint64, stats endpointStats, before, after *runtime.MemStats) *report {
+	r := &report{
+		Scenario:        scenario,
+		DurationSeconds: elapsed.Seconds(),
+
+		SamplesForwarded: samplesForwarded,
+		SamplesReceived:  stats.Samples,
+		Requests:         stats.Requests,
+		SamplesPerSecond: float64(stats.Samples) / elapsed.Seconds(),
+
+		LatencyP50Seconds: stats.LatencyP50.Seconds(),
+		LatencyP90Seconds: stats.LatencyP90.Seconds(),
+		LatencyP99Seconds: stats.LatencyP99.Seconds(),
+
+		Allocs:         after.Mallocs - before.Mallocs,
+		AllocBytes:     after.TotalAlloc - before.TotalAlloc,
+		GCCycles:       after.NumGC - before.NumGC,
+		HeapInuseBytes: after.HeapInuse,
+	}
+	if stats.Samples > 0 {
+		r.AllocsPerSample = float64(r.Allocs) / float64(stats.Samples)
+		r.BytesPerSample = float64(r.AllocBytes) / float64(stats.Samples)
+	}
+	return r
+}
+
+// WriteJSON writes r to w as JSON.
+func (r *report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes r to w as a table.
+func (r *report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "scenario:\t%s\n", r.Scenario)
+	fmt.Fprintf(tw, "duration:\t%.1fs\n", r.DurationSeconds)
+	fmt.Fprintf(tw, "samples forwarded:\t%d\n", r.SamplesForwarded)
+	fmt.Fprintf(tw, "samples received:\t%d (%d requests)\n", r.SamplesReceived, r.Requests)
+	fmt.Fprintf(tw, "throughput:\t%.0f samples/s\n", r.SamplesPerSecond)
+	fmt.Fprintf(tw, "latency p50/p90/p99:\t%.3fs / %.3fs / %.3fs\n", r.LatencyP50Seconds, r.LatencyP90Seconds, r.LatencyP99Seconds)
+	fmt.Fprintf(tw, "allocations:\t%d (%.1f per sample)\n", r.Allocs, r.AllocsPerSample)
+	fmt.Fprintf(tw, "allocated bytes:\t%d (%.0f per sample)\n", r.AllocBytes, r.BytesPerSample)
+	fmt.Fprintf(tw, "gc cycles:\t%d\n", r.GCCycles)
+	fmt.Fprintf(tw, "heap in use:\t%d bytes\n", r.HeapInuseBytes)
+	return tw.Flush()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+// run runs the scenario of cfg and writes its report to w.
+func run(ctx context.Context, cfg config, w io.Writer) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	endpoint := newEndpoint()
+	defer endpoint.Close()
+
+	p, err := newPipeline(cfg, endpoint.URL())
+	if err != nil {
+		return err
+	}
+	defer p.Stop()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	switch cfg.Scenario {
+	case scenarioScrape:
+		farm := newTargetFarm(cfg.Targets, cfg.Series)
+		defer farm.Close()
+
+		if err := p.Scrape(farm.Targets(), cfg.ScrapeInterval); err != nil {
+			return err
+		}
+	case scenarioAppend:
+		gen := newLoadGenerator(p.Receiver(), cfg.Series, cfg.Rate)
+		go gen.Run(runCtx)
+	}
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	select {
+	case <-time.After(cfg.Duration):
+	case <-ctx.Done():
+	}
+	cancel()
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	r := newReport(cfg.Scenario, time.Since(start), p.SamplesForwarded(), endpoint.Stats(), &before, &after)
+	switch cfg.Format {
+	case formatJSON:
+		return r.WriteJSON(w)
+	case formatText:
+		return r.WriteText(w)
+	default:
+		return fmt.Errorf("unknown report format %q", cfg.Format)
+	}
+}
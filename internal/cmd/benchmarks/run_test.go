@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	cfg := defaultConfig
+	cfg.Scenario = scenarioAppend
+	cfg.Duration = 500 * time.Millisecond
+	cfg.Series = 10
+	cfg.Rate = 1000
+	cfg.BatchSendDeadline = 100 * time.Millisecond
+	cfg.Format = formatJSON
+
+	var buf bytes.Buffer
+	require.NoError(t, run(context.Background(), cfg, &buf))
+
+	// The remote_write queue reads the WAL every 15s, so the endpoint isn't
+	// expected to receive samples in such a short scenario.
+	var r report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &r))
+	require.Equal(t, scenarioAppend, r.Scenario)
+	require.NotZero(t, r.SamplesForwarded)
+	require.NotZero(t, r.Allocs)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	require.NoError(t, defaultConfig.validate())
+
+	cfg := defaultConfig
+	cfg.Scenario = "unknown"
+	require.EqualError(t, cfg.validate(), `unknown scenario "unknown"`)
+
+	cfg = defaultConfig
+	cfg.Duration = 0
+	require.EqualError(t, cfg.validate(), "duration must be greater than 0")
+}
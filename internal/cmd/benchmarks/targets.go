@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+
+	"github.com/grafana/agent/internal/component/discovery"
+)
+
+// targetFarm serves synthetic targets, which each expose the same number of
+// series. The targets are served by a single HTTP server, on a different path
+// each.
+type targetFarm struct {
+	srv     *httptest.Server
+	targets int
+}
+
+func newTargetFarm(targets, series int) *targetFarm {
+	// The exposition is rendered once, so that serving the targets barely
+	// allocates while the scenario runs.
+	body := renderExposition(series)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write(body)
+	}))
+	return &targetFarm{srv: srv, targets: targets}
+}
+
+func renderExposition(series int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# HELP benchmarks_series A synthetic series.\n")
+	buf.WriteString("# TYPE benchmarks_series gauge\n")
+	for i := 0; i < series; i++ {
+		fmt.Fprintf(&buf, "benchmarks_series{series=\"%d\"} %d\n", i, i)
+	}
+	return buf.Bytes()
+}
+
+// Targets returns the targets of the farm. Each target has a distinct target
+// label, so that the series of different targets don't collide.
+func (f *targetFarm) Targets() []discovery.Target {
+	u, _ := url.Parse(f.srv.URL)
+
+	targets := make([]discovery.Target, 0, f.targets)
+	for i := 0; i < f.targets; i++ {
+		id := strconv.Itoa(i)
+		targets = append(targets, discovery.Target{
+			"__address__":      u.Host,
+			"__metrics_path__": "/metrics/" + id,
+			"target":           id,
+		})
+	}
+	return targets
+}
+
+// Close stops serving the targets.
+func (f *targetFarm) Close() {
+	f.srv.Close()
+}
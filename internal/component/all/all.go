@@ -2,6 +2,9 @@
 package all
 
 import (
+	_ "github.com/grafana/agent/internal/component/auth/spiffe"                              // Import auth.spiffe
+	_ "github.com/grafana/agent/internal/component/cert/watch"                               // Import cert.watch
+	_ "github.com/grafana/agent/internal/component/collect/exec"                             // Import collect.exec
 	_ "github.com/grafana/agent/internal/component/discovery/aws"                            // Import discovery.aws.ec2 and discovery.aws.lightsail
 	_ "github.com/grafana/agent/internal/component/discovery/azure"                          // Import discovery.azure
 	_ "github.com/grafana/agent/internal/component/discovery/consul"                         // Import discovery.consul
@@ -13,6 +16,7 @@ import (
 	_ "github.com/grafana/agent/internal/component/discovery/eureka"                         // Import discovery.eureka
 	_ "github.com/grafana/agent/internal/component/discovery/file"                           // Import discovery.file
 	_ "github.com/grafana/agent/internal/component/discovery/gce"                            // Import discovery.gce
+	_ "github.com/grafana/agent/internal/component/discovery/geoip"                          // Import discovery.geoip
 	_ "github.com/grafana/agent/internal/component/discovery/hetzner"                        // Import discovery.hetzner
 	_ "github.com/grafana/agent/internal/component/discovery/http"                           // Import discovery.http
 	_ "github.com/grafana/agent/internal/component/discovery/ionos"                          // Import discovery.ionos
@@ -32,13 +36,18 @@ import (
 	_ "github.com/grafana/agent/internal/component/discovery/serverset"                      // Import discovery.serverset
 	_ "github.com/grafana/agent/internal/component/discovery/triton"                         // Import discovery.triton
 	_ "github.com/grafana/agent/internal/component/discovery/uyuni"                          // Import discovery.uyuni
+	_ "github.com/grafana/agent/internal/component/enrich/lookup"                            // Import enrich.lookup
 	_ "github.com/grafana/agent/internal/component/faro/receiver"                            // Import faro.receiver
+	_ "github.com/grafana/agent/internal/component/health/assert"                            // Import health.assert
+	_ "github.com/grafana/agent/internal/component/industrial/poll"                          // Import industrial.poll
 	_ "github.com/grafana/agent/internal/component/local/file"                               // Import local.file
 	_ "github.com/grafana/agent/internal/component/local/file_match"                         // Import local.file_match
 	_ "github.com/grafana/agent/internal/component/loki/echo"                                // Import loki.echo
 	_ "github.com/grafana/agent/internal/component/loki/process"                             // Import loki.process
 	_ "github.com/grafana/agent/internal/component/loki/relabel"                             // Import loki.relabel
+	_ "github.com/grafana/agent/internal/component/loki/route"                               // Import loki.route
 	_ "github.com/grafana/agent/internal/component/loki/rules/kubernetes"                    // Import loki.rules.kubernetes
+	_ "github.com/grafana/agent/internal/component/loki/source/alertmanager"                 // Import loki.source.alertmanager
 	_ "github.com/grafana/agent/internal/component/loki/source/api"                          // Import loki.source.api
 	_ "github.com/grafana/agent/internal/component/loki/source/aws_firehose"                 // Import loki.source.awsfirehose
 	_ "github.com/grafana/agent/internal/component/loki/source/azure_event_hubs"             // Import loki.source.azure_event_hubs
@@ -52,15 +61,19 @@ import (
 	_ "github.com/grafana/agent/internal/component/loki/source/kafka"                        // Import loki.source.kafka
 	_ "github.com/grafana/agent/internal/component/loki/source/kubernetes"                   // Import loki.source.kubernetes
 	_ "github.com/grafana/agent/internal/component/loki/source/kubernetes_events"            // Import loki.source.kubernetes_events
+	_ "github.com/grafana/agent/internal/component/loki/source/netflow"                      // Import loki.source.netflow
 	_ "github.com/grafana/agent/internal/component/loki/source/podlogs"                      // Import loki.source.podlogs
+	_ "github.com/grafana/agent/internal/component/loki/source/snmp_trap"                    // Import loki.source.snmp_trap
 	_ "github.com/grafana/agent/internal/component/loki/source/syslog"                       // Import loki.source.syslog
 	_ "github.com/grafana/agent/internal/component/loki/source/windowsevent"                 // Import loki.source.windowsevent
+	_ "github.com/grafana/agent/internal/component/loki/tometrics"                           // Import loki.tometrics
 	_ "github.com/grafana/agent/internal/component/loki/write"                               // Import loki.write
 	_ "github.com/grafana/agent/internal/component/mimir/rules/kubernetes"                   // Import mimir.rules.kubernetes
 	_ "github.com/grafana/agent/internal/component/module/file"                              // Import module.file
 	_ "github.com/grafana/agent/internal/component/module/git"                               // Import module.git
 	_ "github.com/grafana/agent/internal/component/module/http"                              // Import module.http
 	_ "github.com/grafana/agent/internal/component/module/string"                            // Import module.string
+	_ "github.com/grafana/agent/internal/component/mqtt/source"                              // Import mqtt.source
 	_ "github.com/grafana/agent/internal/component/otelcol/auth/basic"                       // Import otelcol.auth.basic
 	_ "github.com/grafana/agent/internal/component/otelcol/auth/bearer"                      // Import otelcol.auth.bearer
 	_ "github.com/grafana/agent/internal/component/otelcol/auth/headers"                     // Import otelcol.auth.headers
@@ -76,6 +89,7 @@ import (
 	_ "github.com/grafana/agent/internal/component/otelcol/exporter/otlp"                    // Import otelcol.exporter.otlp
 	_ "github.com/grafana/agent/internal/component/otelcol/exporter/otlphttp"                // Import otelcol.exporter.otlphttp
 	_ "github.com/grafana/agent/internal/component/otelcol/exporter/prometheus"              // Import otelcol.exporter.prometheus
+	_ "github.com/grafana/agent/internal/component/otelcol/exporter/spanevents"              // Import otelcol.exporter.spanevents
 	_ "github.com/grafana/agent/internal/component/otelcol/extension/jaeger_remote_sampling" // Import otelcol.extension.jaeger_remote_sampling
 	_ "github.com/grafana/agent/internal/component/otelcol/processor/attributes"             // Import otelcol.processor.attributes
 	_ "github.com/grafana/agent/internal/component/otelcol/processor/batch"                  // Import otelcol.processor.batch
@@ -96,22 +110,29 @@ import (
 	_ "github.com/grafana/agent/internal/component/otelcol/receiver/prometheus"              // Import otelcol.receiver.prometheus
 	_ "github.com/grafana/agent/internal/component/otelcol/receiver/vcenter"                 // Import otelcol.receiver.vcenter
 	_ "github.com/grafana/agent/internal/component/otelcol/receiver/zipkin"                  // Import otelcol.receiver.zipkin
+	_ "github.com/grafana/agent/internal/component/otelcol/storage/file"                     // Import otelcol.storage.file
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/apache"               // Import prometheus.exporter.apache
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/azure"                // Import prometheus.exporter.azure
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/blackbox"             // Import prometheus.exporter.blackbox
+	_ "github.com/grafana/agent/internal/component/prometheus/exporter/bmc"                  // Import prometheus.exporter.bmc
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/cadvisor"             // Import prometheus.exporter.cadvisor
+	_ "github.com/grafana/agent/internal/component/prometheus/exporter/cgroup"               // Import prometheus.exporter.cgroup
+	_ "github.com/grafana/agent/internal/component/prometheus/exporter/cloud_billing"        // Import prometheus.exporter.cloud_billing
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/cloudwatch"           // Import prometheus.exporter.cloudwatch
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/consul"               // Import prometheus.exporter.consul
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/dnsmasq"              // Import prometheus.exporter.dnsmasq
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/elasticsearch"        // Import prometheus.exporter.elasticsearch
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/gcp"                  // Import prometheus.exporter.gcp
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/github"               // Import prometheus.exporter.github
+	_ "github.com/grafana/agent/internal/component/prometheus/exporter/jmx"                  // Import prometheus.exporter.jmx
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/kafka"                // Import prometheus.exporter.kafka
+	_ "github.com/grafana/agent/internal/component/prometheus/exporter/kube_state"           // Import prometheus.exporter.kube_state
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/memcached"            // Import prometheus.exporter.memcached
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/mongodb"              // Import prometheus.exporter.mongodb
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/mssql"                // Import prometheus.exporter.mssql
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/mysql"                // Import prometheus.exporter.mysql
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/oracledb"             // Import prometheus.exporter.oracledb
+	_ "github.com/grafana/agent/internal/component/prometheus/exporter/perfcounter"          // Import prometheus.exporter.perfcounter
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/postgres"             // Import prometheus.exporter.postgres
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/process"              // Import prometheus.exporter.process
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/redis"                // Import prometheus.exporter.redis
@@ -126,12 +147,19 @@ import (
 	_ "github.com/grafana/agent/internal/component/prometheus/operator/podmonitors"          // Import prometheus.operator.podmonitors
 	_ "github.com/grafana/agent/internal/component/prometheus/operator/probes"               // Import prometheus.operator.probes
 	_ "github.com/grafana/agent/internal/component/prometheus/operator/servicemonitors"      // Import prometheus.operator.servicemonitors
+	_ "github.com/grafana/agent/internal/component/prometheus/query"                         // Import prometheus.query
+	_ "github.com/grafana/agent/internal/component/prometheus/receive_graphite"              // Import prometheus.receive_graphite
 	_ "github.com/grafana/agent/internal/component/prometheus/receive_http"                  // Import prometheus.receive_http
+	_ "github.com/grafana/agent/internal/component/prometheus/receive_influxdb"              // Import prometheus.receive_influxdb
+	_ "github.com/grafana/agent/internal/component/prometheus/receive_statsd"                // Import prometheus.receive_statsd
 	_ "github.com/grafana/agent/internal/component/prometheus/relabel"                       // Import prometheus.relabel
 	_ "github.com/grafana/agent/internal/component/prometheus/remotewrite"                   // Import prometheus.remote_write
+	_ "github.com/grafana/agent/internal/component/prometheus/route"                         // Import prometheus.route
 	_ "github.com/grafana/agent/internal/component/prometheus/scrape"                        // Import prometheus.scrape
+	_ "github.com/grafana/agent/internal/component/prometheus/write/parquet"                 // Import prometheus.write.parquet
 	_ "github.com/grafana/agent/internal/component/pyroscope/ebpf"                           // Import pyroscope.ebpf
 	_ "github.com/grafana/agent/internal/component/pyroscope/java"                           // Import pyroscope.java
+	_ "github.com/grafana/agent/internal/component/pyroscope/receive_http"                   // Import pyroscope.receive_http
 	_ "github.com/grafana/agent/internal/component/pyroscope/scrape"                         // Import pyroscope.scrape
 	_ "github.com/grafana/agent/internal/component/pyroscope/write"                          // Import pyroscope.write
 	_ "github.com/grafana/agent/internal/component/remote/http"                              // Import remote.http
@@ -139,4 +167,5 @@ import (
 	_ "github.com/grafana/agent/internal/component/remote/kubernetes/secret"                 // Import remote.kubernetes.secret
 	_ "github.com/grafana/agent/internal/component/remote/s3"                                // Import remote.s3
 	_ "github.com/grafana/agent/internal/component/remote/vault"                             // Import remote.vault
+	_ "github.com/grafana/agent/internal/component/schedule/cron"                            // Import schedule.cron
 )
@@ -0,0 +1,425 @@
+package spiffe
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/river/rivertypes"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "auth.spiffe",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments configures auth.spiffe.
+type Arguments struct {
+	// Address of the Workload API. The SPIFFE_ENDPOINT_SOCKET environment
+	// variable is used if it's empty.
+	Address string `river:"address,attr,optional"`
+
+	// SPIFFE ID of the SVID to export. The default SVID of the workload is
+	// exported if it's empty.
+	SPIFFEID string `river:"spiffe_id,attr,optional"`
+
+	// FetchTimeout is how long to wait for the first SVID when the component
+	// is created or its address changes.
+	FetchTimeout time.Duration `river:"fetch_timeout,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	FetchTimeout: 10 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.Address != "" {
+		if err := workloadapi.ValidateAddress(a.Address); err != nil {
+			return fmt.Errorf("invalid address: %w", err)
+		}
+	}
+	if a.SPIFFEID != "" {
+		if _, err := spiffeid.FromString(a.SPIFFEID); err != nil {
+			return fmt.Errorf("invalid spiffe_id: %w", err)
+		}
+	}
+	if a.FetchTimeout <= 0 {
+		return fmt.Errorf("fetch_timeout must be greater than 0")
+	}
+	return nil
+}
+
+// address returns the address of the Workload API.
+func (a *Arguments) address() (string, error) {
+	if a.Address != "" {
+		return a.Address, nil
+	}
+	addr, ok := workloadapi.GetDefaultAddress()
+	if !ok {
+		return "", fmt.Errorf("address must be set when the %s environment variable isn't", workloadapi.SocketEnv)
+	}
+	return addr, nil
+}
+
+// Exports is the values exported by auth.spiffe.
+type Exports struct {
+	// SPIFFEID is the SPIFFE ID of the exported SVID.
+	SPIFFEID string `river:"spiffe_id,attr"`
+
+	// CertPEM holds the certificate of the SVID, followed by its intermediate
+	// certificates.
+	CertPEM string `river:"cert_pem,attr"`
+
+	// KeyPEM holds the private key of the SVID.
+	KeyPEM rivertypes.Secret `river:"key_pem,attr"`
+
+	// BundlePEM holds the X.509 authorities of the trust domain of the SVID.
+	BundlePEM string `river:"bundle_pem,attr"`
+}
+
+// Component implements the auth.spiffe component.
+type Component struct {
+	opts   component.Options
+	log    log.Logger
+	expiry prometheus.Gauge
+
+	// clientUpdated is written to when client changes, so that Run watches
+	// the new client.
+	clientUpdated chan struct{}
+
+	mut     sync.RWMutex
+	args    Arguments
+	address string
+	client  *workloadapi.Client
+	latest  *workloadapi.X509Context // Latest X.509 context received from client
+	exports Exports
+	svid    *x509svid.SVID // Exported SVID
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+	_ component.DebugComponent  = (*Component)(nil)
+)
+
+// New creates a new auth.spiffe component. It will try to immediately fetch
+// the SVID from the Workload API and return an error if it can't be fetched.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts: opts,
+		log:  opts.Logger,
+		expiry: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_auth_spiffe_svid_expiry_timestamp_seconds",
+			Help: "Expiration time of the exported SVID in seconds since the Unix epoch",
+		}),
+		clientUpdated: make(chan struct{}, 1),
+	}
+	if err := opts.Registerer.Register(c.expiry); err != nil {
+		return nil, err
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run runs the auth.spiffe component, watching the Workload API for rotated
+// SVIDs and trust bundles until ctx is canceled.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+		_ = c.client.Close()
+	}()
+
+	var (
+		client    *workloadapi.Client
+		stopWatch = func() {}
+	)
+	defer func() { stopWatch() }()
+
+	for {
+		c.mut.RLock()
+		newClient := c.client
+		c.mut.RUnlock()
+
+		if newClient != client {
+			stopWatch()
+			client = newClient
+			stopWatch = c.watch(ctx, client)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.clientUpdated:
+		}
+	}
+}
+
+// watch watches client for X.509 contexts in the background until the
+// returned function is called.
+func (c *Component) watch(ctx context.Context, client *workloadapi.Client) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = client.WatchX509Context(ctx, &watcher{c: c, client: client})
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Update updates the auth.spiffe component. If the address of the Workload
+// API changes, it will try to immediately fetch the SVID from the new address
+// and return an error if it can't be fetched.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	addr, err := newArgs.address()
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.client != nil && addr == c.address {
+		if err := c.apply(c.latest, newArgs.SPIFFEID); err != nil {
+			return err
+		}
+		c.args = newArgs
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), newArgs.FetchTimeout)
+	defer cancel()
+
+	client, err := workloadapi.New(ctx, workloadapi.WithAddr(addr), workloadapi.WithLogger(spiffeLogger{c.log}))
+	if err != nil {
+		return fmt.Errorf("creating Workload API client: %w", err)
+	}
+	x509Context, err := client.FetchX509Context(ctx)
+	if err == nil {
+		err = c.apply(x509Context, newArgs.SPIFFEID)
+	}
+	if err != nil {
+		_ = client.Close()
+		return fmt.Errorf("fetching SVID from %s: %w", addr, err)
+	}
+
+	if c.client != nil {
+		// Closing the previous client stops Run from watching it.
+		_ = c.client.Close()
+	}
+	c.args = newArgs
+	c.address = addr
+	c.client = client
+	c.latest = x509Context
+
+	select {
+	case c.clientUpdated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// apply exports the SVID of x509Context with the given SPIFFE ID, or its
+// default SVID if id is empty. c.mut must be held when calling apply.
+func (c *Component) apply(x509Context *workloadapi.X509Context, id string) error {
+	svid, exports, err := exportsFromContext(x509Context, id)
+	if err != nil {
+		return err
+	}
+
+	c.svid = svid
+	c.expiry.Set(float64(svid.Certificates[0].NotAfter.Unix()))
+	c.setHealth(component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    fmt.Sprintf("exporting SVID %s", svid.ID),
+		UpdateTime: time.Now(),
+	})
+
+	if exports != c.exports {
+		c.exports = exports
+		c.opts.OnStateChange(exports)
+	}
+	return nil
+}
+
+// exportsFromContext returns the SVID of x509Context with the given SPIFFE
+// ID, or its default SVID if id is empty, and the exports for it.
+func exportsFromContext(x509Context *workloadapi.X509Context, id string) (*x509svid.SVID, Exports, error) {
+	if len(x509Context.SVIDs) == 0 {
+		return nil, Exports{}, fmt.Errorf("no SVIDs received")
+	}
+
+	svid := x509Context.DefaultSVID()
+	if id != "" {
+		svid = nil
+		for _, s := range x509Context.SVIDs {
+			if s.ID.String() == id {
+				svid = s
+				break
+			}
+		}
+		if svid == nil {
+			return nil, Exports{}, fmt.Errorf("no SVID received for %s", id)
+		}
+	}
+
+	certPEM, keyPEM, err := svid.Marshal()
+	if err != nil {
+		return nil, Exports{}, fmt.Errorf("encoding SVID %s: %w", svid.ID, err)
+	}
+	bundle, err := x509Context.Bundles.GetX509BundleForTrustDomain(svid.ID.TrustDomain())
+	if err != nil {
+		return nil, Exports{}, err
+	}
+	bundlePEM, err := bundle.Marshal()
+	if err != nil {
+		return nil, Exports{}, fmt.Errorf("encoding bundle of %s: %w", svid.ID.TrustDomain(), err)
+	}
+
+	return svid, Exports{
+		SPIFFEID:  svid.ID.String(),
+		CertPEM:   string(certPEM),
+		KeyPEM:    rivertypes.Secret(keyPEM),
+		BundlePEM: string(bundlePEM),
+	}, nil
+}
+
+func (c *Component) setHealth(h component.Health) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = h
+}
+
+// CurrentHealth returns the health of the component.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+// DebugInfo returns debug information about the auth.spiffe component. It
+// includes non-sensitive metadata about the exported SVID.
+func (c *Component) DebugInfo() interface{} {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	leaf := c.svid.Certificates[0]
+	return debugInfo{
+		Address:   c.address,
+		SPIFFEID:  c.svid.ID.String(),
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+	}
+}
+
+type debugInfo struct {
+	Address   string    `river:"address,attr"`
+	SPIFFEID  string    `river:"spiffe_id,attr"`
+	NotBefore time.Time `river:"not_before,attr"`
+	NotAfter  time.Time `river:"not_after,attr"`
+}
+
+// watcher receives the X.509 contexts of a Workload API client.
+type watcher struct {
+	c      *Component
+	client *workloadapi.Client
+}
+
+var _ workloadapi.X509ContextWatcher = (*watcher)(nil)
+
+// OnX509ContextUpdate implements workloadapi.X509ContextWatcher.
+func (w *watcher) OnX509ContextUpdate(x509Context *workloadapi.X509Context) {
+	c := w.c
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// Ignore updates from a client which is being replaced.
+	if c.client != w.client {
+		return
+	}
+	c.latest = x509Context
+
+	if err := c.apply(x509Context, c.args.SPIFFEID); err != nil {
+		level.Error(c.log).Log("msg", "failed to apply SVID update", "err", err)
+		c.setHealth(component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    err.Error(),
+			UpdateTime: time.Now(),
+		})
+		return
+	}
+	level.Info(c.log).Log("msg", "received SVID update", "spiffe_id", c.svid.ID, "not_after", c.svid.Certificates[0].NotAfter)
+}
+
+// OnX509ContextWatchError implements workloadapi.X509ContextWatcher.
+func (w *watcher) OnX509ContextWatchError(err error) {
+	if status.Code(err) == codes.Canceled {
+		return
+	}
+
+	// The client retries watching the Workload API; the exports keep the last
+	// SVID which was received in the meantime.
+	w.c.setHealth(component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    fmt.Sprintf("watching the Workload API: %s", err),
+		UpdateTime: time.Now(),
+	})
+}
+
+// spiffeLogger logs the messages of the Workload API client.
+type spiffeLogger struct{ l log.Logger }
+
+func (l spiffeLogger) Debugf(format string, args ...interface{}) {
+	level.Debug(l.l).Log("msg", fmt.Sprintf(format, args...))
+}
+
+func (l spiffeLogger) Infof(format string, args ...interface{}) {
+	level.Info(l.l).Log("msg", fmt.Sprintf(format, args...))
+}
+
+func (l spiffeLogger) Warnf(format string, args ...interface{}) {
+	level.Warn(l.l).Log("msg", fmt.Sprintf(format, args...))
+}
+
+func (l spiffeLogger) Errorf(format string, args ...interface{}) {
+	level.Error(l.l).Log("msg", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,255 @@
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/componenttest"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestSPIFFE(t *testing.T) {
+	var (
+		ctx = componenttest.TestContext(t)
+		l   = util.TestLogger(t)
+	)
+
+	ca := newTestCA(t)
+	api := newFakeWorkloadAPI(t, ca.svidResponse(t, "spiffe://example.org/agent"))
+
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`address = "unix://`+api.path+`"`), &args))
+
+	ctrl, err := componenttest.NewControllerFromID(l, "auth.spiffe")
+	require.NoError(t, err)
+
+	go func() {
+		require.NoError(t, ctrl.Run(ctx, args))
+	}()
+
+	require.NoError(t, ctrl.WaitRunning(time.Minute))
+	require.NoError(t, ctrl.WaitExports(time.Minute))
+
+	exports := ctrl.Exports().(Exports)
+	require.Equal(t, "spiffe://example.org/agent", exports.SPIFFEID)
+	require.Contains(t, exports.CertPEM, "BEGIN CERTIFICATE")
+	require.Contains(t, string(exports.KeyPEM), "BEGIN PRIVATE KEY")
+	require.Contains(t, exports.BundlePEM, "BEGIN CERTIFICATE")
+
+	// Rotate the SVID and wait for the exports to be updated.
+	api.push(ca.svidResponse(t, "spiffe://example.org/agent"))
+
+	require.Eventually(t, func() bool {
+		rotated := ctrl.Exports().(Exports)
+		return rotated.CertPEM != exports.CertPEM && rotated.KeyPEM != exports.KeyPEM
+	}, time.Minute, 10*time.Millisecond)
+}
+
+func TestSPIFFE_SelectSVID(t *testing.T) {
+	ca := newTestCA(t)
+
+	resp := ca.svidResponse(t, "spiffe://example.org/agent")
+	resp.Svids = append(resp.Svids, ca.svidResponse(t, "spiffe://example.org/other").Svids...)
+	api := newFakeWorkloadAPI(t, resp)
+
+	opts := component.Options{
+		ID:            "auth.spiffe.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prometheus.NewRegistry(),
+	}
+
+	args := DefaultArguments
+	args.Address = "unix://" + api.path
+	args.SPIFFEID = "spiffe://example.org/other"
+
+	c, err := New(opts, args)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.client.Close() })
+	require.Equal(t, "spiffe://example.org/other", c.exports.SPIFFEID)
+
+	args.SPIFFEID = "spiffe://example.org/missing"
+	require.EqualError(t, c.Update(args), "no SVID received for spiffe://example.org/missing")
+}
+
+func TestArguments_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    string
+		expect string
+	}{
+		{
+			name:   "invalid address",
+			cfg:    `address = "http://localhost"`,
+			expect: `invalid address: workload endpoint socket URI must have a "tcp" or "unix" scheme`,
+		},
+		{
+			name:   "invalid SPIFFE ID",
+			cfg:    `spiffe_id = "example.org/agent"`,
+			expect: `invalid spiffe_id: scheme is missing or invalid`,
+		},
+		{
+			name:   "invalid fetch timeout",
+			cfg:    `fetch_timeout = "0s"`,
+			expect: `fetch_timeout must be greater than 0`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			require.EqualError(t, river.Unmarshal([]byte(tc.cfg), &args), tc.expect)
+		})
+	}
+}
+
+// testCA issues SVIDs for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"SPIFFE"}},
+		URIs:                  []*url.URL{{Scheme: "spiffe", Host: "example.org"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+// svidResponse returns a Workload API response with a new SVID for id.
+func (ca *testCA) svidResponse(t *testing.T, id string) *workload.X509SVIDResponse {
+	t.Helper()
+
+	u, err := url.Parse(id)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		URIs:         []*url.URL{u},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return &workload.X509SVIDResponse{
+		Svids: []*workload.X509SVID{{
+			SpiffeId:    id,
+			X509Svid:    der,
+			X509SvidKey: keyDER,
+			Bundle:      ca.cert.Raw,
+		}},
+	}
+}
+
+// fakeWorkloadAPI is a Workload API server which streams the responses
+// pushed to it.
+type fakeWorkloadAPI struct {
+	workload.UnimplementedSpiffeWorkloadAPIServer
+
+	path string
+
+	mut     sync.Mutex
+	latest  *workload.X509SVIDResponse
+	updated chan struct{}
+}
+
+func newFakeWorkloadAPI(t *testing.T, initial *workload.X509SVIDResponse) *fakeWorkloadAPI {
+	t.Helper()
+
+	// Unix socket paths are limited in length, so do not use t.TempDir.
+	dir, err := os.MkdirTemp("", "spiffe")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	api := &fakeWorkloadAPI{
+		path:    filepath.Join(dir, "api.sock"),
+		latest:  initial,
+		updated: make(chan struct{}),
+	}
+
+	lis, err := net.Listen("unix", api.path)
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	workload.RegisterSpiffeWorkloadAPIServer(srv, api)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return api
+}
+
+// push sends resp to the clients of the server.
+func (api *fakeWorkloadAPI) push(resp *workload.X509SVIDResponse) {
+	api.mut.Lock()
+	defer api.mut.Unlock()
+
+	api.latest = resp
+	close(api.updated)
+	api.updated = make(chan struct{})
+}
+
+func (api *fakeWorkloadAPI) FetchX509SVID(_ *workload.X509SVIDRequest, stream workload.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	for {
+		api.mut.Lock()
+		var (
+			resp    = api.latest
+			updated = api.updated
+		)
+		api.mut.Unlock()
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-updated:
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package watch
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// TargetLabel is the label holding the name of the target a certificate was
+// read from.
+const TargetLabel = "target"
+
+// Arguments holds values which are used to configure the cert.watch
+// component.
+type Arguments struct {
+	Interval        time.Duration        `river:"interval,attr,optional"`
+	Timeout         time.Duration        `river:"timeout,attr,optional"`
+	ExpiryThreshold time.Duration        `river:"expiry_threshold,attr,optional"`
+	ForwardTo       []storage.Appendable `river:"forward_to,attr,optional"`
+
+	Endpoints []EndpointArguments `river:"endpoint,block,optional"`
+	Files     []FileArguments     `river:"file,block,optional"`
+}
+
+// EndpointArguments configures a TLS endpoint to read the certificate of.
+type EndpointArguments struct {
+	Name       string            `river:",label"`
+	Address    string            `river:"address,attr"`
+	ServerName string            `river:"server_name,attr,optional"`
+	CAFile     string            `river:"ca_file,attr,optional"`
+	CAPEM      string            `river:"ca_pem,attr,optional"`
+	Labels     map[string]string `river:"labels,attr,optional"`
+}
+
+// FileArguments configures a PEM file to read the certificate of.
+type FileArguments struct {
+	Name   string            `river:",label"`
+	Path   string            `river:"path,attr"`
+	CAFile string            `river:"ca_file,attr,optional"`
+	CAPEM  string            `river:"ca_pem,attr,optional"`
+	Labels map[string]string `river:"labels,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval:        time.Minute,
+	Timeout:         10 * time.Second,
+	ExpiryThreshold: 30 * 24 * time.Hour,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if args.Timeout > args.Interval {
+		return fmt.Errorf("timeout must not be greater than interval")
+	}
+	if args.ExpiryThreshold < 0 {
+		return fmt.Errorf("expiry_threshold must not be negative")
+	}
+	if len(args.Endpoints) == 0 && len(args.Files) == 0 {
+		return fmt.Errorf("at least one endpoint or file block must be set")
+	}
+
+	names := make(map[string]struct{}, len(args.Endpoints)+len(args.Files))
+	checkName := func(name string) error {
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("target %q is defined more than once", name)
+		}
+		names[name] = struct{}{}
+		return nil
+	}
+	for _, e := range args.Endpoints {
+		if err := checkName(e.Name); err != nil {
+			return err
+		}
+	}
+	for _, f := range args.Files {
+		if err := checkName(f.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate implements river.Validator.
+func (args *EndpointArguments) Validate() error {
+	if _, _, err := net.SplitHostPort(args.Address); err != nil {
+		return fmt.Errorf("endpoint %q: invalid address: %w", args.Name, err)
+	}
+	if args.CAFile != "" && args.CAPEM != "" {
+		return fmt.Errorf("endpoint %q: at most one of ca_file and ca_pem must be set", args.Name)
+	}
+	if err := validateLabels(args.Labels); err != nil {
+		return fmt.Errorf("endpoint %q: %w", args.Name, err)
+	}
+	return nil
+}
+
+// Validate implements river.Validator.
+func (args *FileArguments) Validate() error {
+	if args.Path == "" {
+		return fmt.Errorf("file %q: path must not be empty", args.Name)
+	}
+	if args.CAFile != "" && args.CAPEM != "" {
+		return fmt.Errorf("file %q: at most one of ca_file and ca_pem must be set", args.Name)
+	}
+	if err := validateLabels(args.Labels); err != nil {
+		return fmt.Errorf("file %q: %w", args.Name, err)
+	}
+	return nil
+}
+
+func validateLabels(labels map[string]string) error {
+	for name := range labels {
+		if !model.LabelName(name).IsValid() || name == model.MetricNameLabel || name == TargetLabel {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the cert.watch component.
+type Exports struct {
+	// Certificates holds the state of the certificate of every target, by
+	// name of target.
+	Certificates map[string]Certificate `river:"certificates,attr"`
+}
+
+// Certificate is the state of the certificate of a target, as of its last
+// check.
+type Certificate struct {
+	// Error is set when the certificate couldn't be read. Other fields are
+	// empty in that case.
+	Error string `river:"error,attr"`
+
+	Subject      string `river:"subject,attr"`
+	Issuer       string `river:"issuer,attr"`
+	SerialNumber string `river:"serial_number,attr"`
+	NotBefore    string `river:"not_before,attr"`
+	NotAfter     string `river:"not_after,attr"`
+
+	// ChainNotAfter is the earliest expiry of the certificates of the chain
+	// presented by the target, including the certificate.
+	ChainNotAfter string `river:"chain_not_after,attr"`
+
+	// Expiring is true when the certificate or its chain expire within the
+	// expiry threshold, or already expired.
+	Expiring bool `river:"expiring,attr"`
+
+	ChainValid bool   `river:"chain_valid,attr"`
+	ChainError string `river:"chain_error,attr"`
+
+	DNSNames       []string `river:"dns_names,attr"`
+	IPAddresses    []string `river:"ip_addresses,attr"`
+	EmailAddresses []string `river:"email_addresses,attr"`
+	URIs           []string `river:"uris,attr"`
+}
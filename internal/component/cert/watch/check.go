@@ -0,0 +1,199 @@
+package watch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/grafana/agent/internal/service/egress"
+)
+
+// target is a source of certificates.
+type target interface {
+	// Read returns the certificate of the target, followed by the other
+	// certificates of its chain, if any.
+	Read(ctx context.Context) ([]*x509.Certificate, error)
+
+	// ServerName returns the name the certificate is verified for. It's empty
+	// when the certificate isn't verified for a name.
+	ServerName() string
+}
+
+// endpointTarget reads the certificate presented by a TLS endpoint.
+type endpointTarget struct {
+	address    string
+	serverName string
+}
+
+func newEndpointTarget(args EndpointArguments) *endpointTarget {
+	serverName := args.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(args.Address)
+	}
+	return &endpointTarget{address: args.Address, serverName: serverName}
+}
+
+// Read implements target. The certificate isn't verified during the
+// handshake, so the certificates of invalid chains can still be read.
+func (t *endpointTarget) Read(ctx context.Context) ([]*x509.Certificate, error) {
+	conn, err := egress.DialContext(ctx, "tcp", t.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	sni := t.serverName
+	if net.ParseIP(sni) != nil {
+		// Servers don't expect IP addresses in the SNI extension.
+		sni = ""
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         sni,
+		InsecureSkipVerify: true, //nolint:gosec // Certificates are verified by the component.
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("endpoint didn't present a certificate")
+	}
+	return certs, nil
+}
+
+// ServerName implements target.
+func (t *endpointTarget) ServerName() string { return t.serverName }
+
+// fileTarget reads the certificates of a PEM file.
+type fileTarget struct {
+	path string
+}
+
+// Read implements target. The file is read on every call, so renewed
+// certificates are picked up.
+func (t *fileTarget) Read(context.Context) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.path, err)
+	}
+	return certs, nil
+}
+
+// ServerName implements target.
+func (t *fileTarget) ServerName() string { return "" }
+
+// parseCertificates parses the certificates of PEM data. Blocks other than
+// certificates, such as private keys, are ignored.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs, nil
+}
+
+// loadRoots returns the certificate pool of the CA certificates of caFile or
+// caPEM, or nil when both are empty so that the system pool is used.
+func loadRoots(caFile, caPEM string) (*x509.CertPool, error) {
+	data := []byte(caPEM)
+	if caFile != "" {
+		var err error
+		if data, err = os.ReadFile(caFile); err != nil {
+			return nil, err
+		}
+	} else if caPEM == "" {
+		return nil, nil
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// checkCertificate builds the state of the certificate certs[0], verifying
+// its chain at now with roots. The other certificates of certs are used as
+// intermediates.
+func checkCertificate(certs []*x509.Certificate, serverName string, roots *x509.CertPool, now time.Time, expiryThreshold time.Duration) Certificate {
+	var (
+		leaf          = certs[0]
+		chainNotAfter = chainExpiry(certs)
+		intermediates = x509.NewCertPool()
+	)
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	c := Certificate{
+		Subject:        leaf.Subject.String(),
+		Issuer:         leaf.Issuer.String(),
+		SerialNumber:   leaf.SerialNumber.Text(16),
+		NotBefore:      leaf.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:       leaf.NotAfter.UTC().Format(time.RFC3339),
+		ChainNotAfter:  chainNotAfter.UTC().Format(time.RFC3339),
+		Expiring:       chainNotAfter.Sub(now) < expiryThreshold,
+		DNSNames:       leaf.DNSNames,
+		EmailAddresses: leaf.EmailAddresses,
+	}
+	for _, ip := range leaf.IPAddresses {
+		c.IPAddresses = append(c.IPAddresses, ip.String())
+	}
+	for _, uri := range leaf.URIs {
+		c.URIs = append(c.URIs, uri.String())
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		c.ChainError = err.Error()
+	} else {
+		c.ChainValid = true
+	}
+	return c
+}
+
+// chainExpiry returns the earliest expiry of certs.
+func chainExpiry(certs []*x509.Certificate) time.Time {
+	notAfter := certs[0].NotAfter
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+	return notAfter
+}
@@ -0,0 +1,403 @@
+// Package watch implements the cert.watch component, which checks the
+// certificates of TLS endpoints and PEM files on an interval, and exports
+// their expiry, chain validity, and subject alternative names.
+package watch
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "cert.watch",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// namedTarget is a target along with its configuration.
+type namedTarget struct {
+	name   string
+	labels map[string]string
+	caFile string
+	caPEM  string
+	target target
+}
+
+// result is the outcome of checking a target.
+type result struct {
+	certs []*x509.Certificate
+	state Certificate
+	err   error
+}
+
+// Component implements the cert.watch component.
+type Component struct {
+	log    log.Logger
+	opts   component.Options
+	fanout *agentprom.Fanout
+
+	mut       sync.Mutex
+	args      Arguments
+	targets   []namedTarget
+	lastCheck time.Time
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+
+	checks        *prometheus_client.CounterVec
+	checkFailures *prometheus_client.CounterVec
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new cert.watch component. Certificates are checked once
+// before New returns, so that their state is exported when the components
+// referencing them are evaluated.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	data, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		log:     opts.Logger,
+		opts:    opts,
+		fanout:  agentprom.NewFanout(args.ForwardTo, opts.ID, opts.Registerer, ls),
+		updated: make(chan struct{}, 1),
+
+		health: component.Health{
+			Health:     component.HealthTypeUnknown,
+			Message:    "component started",
+			UpdateTime: time.Now(),
+		},
+
+		checks: prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+			Name: "agent_cert_watch_checks_total",
+			Help: "Total number of times the certificate of a target was checked.",
+		}, []string{"target"}),
+		checkFailures: prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+			Name: "agent_cert_watch_check_failures_total",
+			Help: "Total number of times the certificate of a target couldn't be read.",
+		}, []string{"target"}),
+	}
+	for _, m := range []prometheus_client.Collector{c.checks, c.checkFailures} {
+		if err := opts.Registerer.Register(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	c.check(context.Background())
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.nextCheck()):
+			c.check(ctx)
+		case <-c.updated:
+			// no-op; force the next wait to be reread.
+		}
+	}
+}
+
+// nextCheck returns how long to wait to check targets given the last time
+// they were checked. nextCheck returns 0 if targets should be checked
+// immediately.
+func (c *Component) nextCheck() time.Duration {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	nextCheck := c.lastCheck.Add(c.args.Interval)
+	now := time.Now()
+
+	if now.After(nextCheck) {
+		return 0
+	}
+	return nextCheck.Sub(now)
+}
+
+// check reads the certificates of every target concurrently, exports their
+// state, and forwards their metrics to the receivers. c.mut must not be held
+// when calling. After checking, the component's health is updated with the
+// targets which couldn't be read, if any.
+func (c *Component) check(ctx context.Context) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := time.Now()
+	c.lastCheck = now
+
+	checkCtx, cancel := context.WithTimeout(ctx, c.args.Timeout)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]result, len(c.targets))
+	)
+	for i, t := range c.targets {
+		wg.Add(1)
+		go func(i int, t namedTarget) {
+			defer wg.Done()
+
+			results[i] = c.checkTarget(checkCtx, t, now)
+			c.checks.WithLabelValues(t.name).Inc()
+			if results[i].err != nil {
+				c.checkFailures.WithLabelValues(t.name).Inc()
+				level.Error(c.log).Log("msg", "failed to check certificate", "target", t.name, "err", results[i].err)
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	var (
+		exports = Exports{Certificates: make(map[string]Certificate, len(c.targets))}
+		errs    []error
+		app     = c.fanout.Appender(ctx)
+		ts      = timestamp.FromTime(now)
+	)
+	for i, t := range c.targets {
+		exports.Certificates[t.name] = results[i].state
+		if results[i].err != nil {
+			errs = append(errs, fmt.Errorf("target %q: %w", t.name, results[i].err))
+		}
+		if err := appendResult(app, t, results[i], ts); err != nil {
+			level.Error(c.log).Log("msg", "failed to append metrics", "target", t.name, "err", err)
+			errs = append(errs, fmt.Errorf("target %q: appending metrics: %w", t.name, err))
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Error(c.log).Log("msg", "failed to forward metrics", "err", err)
+		errs = append(errs, fmt.Errorf("forwarding metrics: %w", err))
+	}
+
+	c.opts.OnStateChange(exports)
+	c.updateHealth(errors.Join(errs...))
+}
+
+func (c *Component) checkTarget(ctx context.Context, t namedTarget, now time.Time) result {
+	roots, err := loadRoots(t.caFile, t.caPEM)
+	if err != nil {
+		err = fmt.Errorf("loading CA certificates: %w", err)
+		return result{state: Certificate{Error: err.Error()}, err: err}
+	}
+	certs, err := t.target.Read(ctx)
+	if err != nil {
+		return result{state: Certificate{Error: err.Error()}, err: err}
+	}
+	return result{
+		certs: certs,
+		state: checkCertificate(certs, t.target.ServerName(), roots, now, c.args.ExpiryThreshold),
+	}
+}
+
+// metric is a gauge forwarded for the certificate of a target.
+type metric struct {
+	name   string
+	help   string
+	labels []string
+	value  float64
+}
+
+// appendResult appends the metrics of the certificate of a target as gauges,
+// along with their metadata.
+func appendResult(app storage.Appender, t namedTarget, r result, ts int64) error {
+	metrics := []metric{{
+		name:  "cert_watch_up",
+		help:  "Whether the certificate of the target could be read.",
+		value: boolToFloat(r.err == nil),
+	}}
+	if r.err == nil {
+		metrics = append(metrics, certificateMetrics(r.certs, r.state)...)
+	}
+
+	for _, m := range metrics {
+		lb := labels.NewBuilder(labels.EmptyLabels())
+		for n, v := range t.labels {
+			lb.Set(n, v)
+		}
+		lb.Set(labels.MetricName, m.name)
+		lb.Set(TargetLabel, t.name)
+		for i := 0; i < len(m.labels); i += 2 {
+			lb.Set(m.labels[i], m.labels[i+1])
+		}
+		lset := lb.Labels()
+
+		ref, err := app.Append(0, lset, ts, m.value)
+		if err != nil {
+			return err
+		}
+		_, err = app.UpdateMetadata(ref, lset, metadata.Metadata{
+			Type: textparse.MetricTypeGauge,
+			Help: m.help,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func certificateMetrics(certs []*x509.Certificate, state Certificate) []metric {
+	leaf := certs[0]
+	metrics := []metric{
+		{
+			name:   "cert_watch_info",
+			help:   "Information about the certificate of the target.",
+			labels: []string{"subject", state.Subject, "issuer", state.Issuer, "serial_number", state.SerialNumber},
+			value:  1,
+		},
+		{
+			name:  "cert_watch_not_before_timestamp_seconds",
+			help:  "Time the certificate of the target is valid from, as a Unix timestamp.",
+			value: float64(leaf.NotBefore.Unix()),
+		},
+		{
+			name:  "cert_watch_not_after_timestamp_seconds",
+			help:  "Time the certificate of the target expires, as a Unix timestamp.",
+			value: float64(leaf.NotAfter.Unix()),
+		},
+		{
+			name:  "cert_watch_chain_not_after_timestamp_seconds",
+			help:  "Time the first certificate of the chain of the target expires, as a Unix timestamp.",
+			value: float64(chainExpiry(certs).Unix()),
+		},
+		{
+			name:  "cert_watch_chain_valid",
+			help:  "Whether the chain of the certificate of the target could be verified.",
+			value: boolToFloat(state.ChainValid),
+		},
+	}
+
+	sans := []struct {
+		kind   string
+		values []string
+	}{
+		{"dns", state.DNSNames},
+		{"ip", state.IPAddresses},
+		{"email", state.EmailAddresses},
+		{"uri", state.URIs},
+	}
+	for _, s := range sans {
+		for _, v := range s.values {
+			metrics = append(metrics, metric{
+				name:   "cert_watch_san_info",
+				help:   "Subject alternative names of the certificate of the target.",
+				labels: []string{"type", s.kind, "san", v},
+				value:  1,
+			})
+		}
+	}
+	return metrics
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (c *Component) updateHealth(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+
+	if err == nil {
+		c.health = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "checked certificates",
+			UpdateTime: time.Now(),
+		}
+	} else {
+		c.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("checking certificates failed: %s", err),
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+// Update implements component.Component. Targets are checked again
+// immediately after an update.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	targets := make([]namedTarget, 0, len(newArgs.Endpoints)+len(newArgs.Files))
+	for _, e := range newArgs.Endpoints {
+		targets = append(targets, namedTarget{
+			name:   e.Name,
+			labels: e.Labels,
+			caFile: e.CAFile,
+			caPEM:  e.CAPEM,
+			target: newEndpointTarget(e),
+		})
+	}
+	for _, f := range newArgs.Files {
+		targets = append(targets, namedTarget{
+			name:   f.Name,
+			labels: f.Labels,
+			caFile: f.CAFile,
+			caPEM:  f.CAPEM,
+			target: &fileTarget{path: f.Path},
+		})
+	}
+
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = newArgs
+	c.targets = targets
+	c.lastCheck = time.Time{}
+
+	// Send an updated event if one wasn't already read.
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
@@ -0,0 +1,205 @@
+package watch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "valid",
+			config: `
+				endpoint "api" {
+					address     = "api.example.com:443"
+					server_name = "api.example.com"
+				}
+				file "ingress" {
+					path = "/etc/tls/tls.crt"
+				}
+			`,
+		},
+		{
+			name:   "no targets",
+			config: `interval = "1m"`,
+			err:    "at least one endpoint or file block must be set",
+		},
+		{
+			name: "duplicate target",
+			config: `
+				endpoint "a" { address = "api.example.com:443" }
+				file "a" { path = "/etc/tls/tls.crt" }
+			`,
+			err: `target "a" is defined more than once`,
+		},
+		{
+			name:   "missing port",
+			config: `endpoint "api" { address = "api.example.com" }`,
+			err:    `endpoint "api": invalid address: address api.example.com: missing port in address`,
+		},
+		{
+			name: "both CA settings",
+			config: `
+				file "ingress" {
+					path    = "/etc/tls/tls.crt"
+					ca_file = "/etc/tls/ca.crt"
+					ca_pem  = "-----BEGIN CERTIFICATE-----"
+				}
+			`,
+			err: `file "ingress": at most one of ca_file and ca_pem must be set`,
+		},
+		{
+			name: "reserved label",
+			config: `
+				endpoint "api" {
+					address = "api.example.com:443"
+					labels  = { target = "other" }
+				}
+			`,
+			err: `endpoint "api": invalid label name "target"`,
+		},
+		{
+			name: "timeout greater than interval",
+			config: `
+				interval = "5s"
+				endpoint "api" { address = "api.example.com:443" }
+			`,
+			err: "timeout must not be greater than interval",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.config), &args)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	require.NoError(t, os.WriteFile(certFile, []byte(encodePEM(srv.Certificate())), 0o600))
+
+	var (
+		mut     sync.Mutex
+		samples = make(map[string][]labels.Labels)
+	)
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	receiver := prometheus.NewInterceptor(nil, ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+			mut.Lock()
+			defer mut.Unlock()
+			samples[l.Get("__name__")] = append(samples[l.Get("__name__")], l)
+			return ref, nil
+		}),
+	)
+
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		endpoint "trusted" {
+			address = "`+srv.Listener.Addr().String()+`"
+			ca_file = "`+certFile+`"
+			labels  = { env = "test" }
+		}
+		endpoint "untrusted" {
+			address = "`+srv.Listener.Addr().String()+`"
+		}
+		file "ingress" {
+			path = "`+certFile+`"
+		}
+		file "missing" {
+			path = "`+filepath.Join(dir, "missing.crt")+`"
+		}
+	`), &args))
+	args.ForwardTo = []storage.Appendable{receiver}
+
+	var exports Exports
+	_, err := New(component.Options{
+		ID:     "cert.watch.test",
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			exports = e.(Exports)
+		},
+		Registerer: prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	// Certificates are checked before New returns.
+	require.Len(t, exports.Certificates, 4)
+
+	trusted := exports.Certificates["trusted"]
+	require.Empty(t, trusted.Error)
+	require.True(t, trusted.ChainValid, trusted.ChainError)
+	require.False(t, trusted.Expiring)
+	require.Equal(t, []string{"example.com", "*.example.com"}, trusted.DNSNames)
+	require.Equal(t, []string{"127.0.0.1", "::1"}, trusted.IPAddresses)
+	require.Equal(t, srv.Certificate().NotAfter.UTC().Format(time.RFC3339), trusted.NotAfter)
+
+	untrusted := exports.Certificates["untrusted"]
+	require.Empty(t, untrusted.Error)
+	require.False(t, untrusted.ChainValid)
+	require.NotEmpty(t, untrusted.ChainError)
+	require.Equal(t, trusted.SerialNumber, untrusted.SerialNumber)
+
+	ingress := exports.Certificates["ingress"]
+	require.Empty(t, ingress.Error)
+	require.Equal(t, trusted.SerialNumber, ingress.SerialNumber)
+
+	require.Contains(t, exports.Certificates["missing"].Error, "no such file or directory")
+
+	mut.Lock()
+	defer mut.Unlock()
+	require.Len(t, samples["cert_watch_up"], 4)
+	require.Len(t, samples["cert_watch_not_after_timestamp_seconds"], 3)
+	require.Len(t, samples["cert_watch_chain_valid"], 3)
+	// Each of the three certificates has two DNS names and two IP addresses.
+	require.Len(t, samples["cert_watch_san_info"], 12)
+	require.Equal(t, labels.FromStrings(
+		"__name__", "cert_watch_up",
+		"env", "test",
+		"target", "trusted",
+	), samples["cert_watch_up"][0])
+}
+
+func TestExportsEncode(t *testing.T) {
+	// Certificates must be usable from river expressions, such as
+	// cert.watch.LABEL.certificates["api"].expiring.
+	out, err := river.Marshal(struct {
+		Value Exports `river:"value,attr"`
+	}{Value: Exports{Certificates: map[string]Certificate{
+		"api": {Expiring: true, DNSNames: []string{"api.example.com"}},
+	}}})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "api = {")
+	require.Contains(t, string(out), `expiring        = true`)
+}
@@ -0,0 +1,445 @@
+// Package exec implements the collect.exec component.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "collect.exec",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Formats of the output of commands.
+const (
+	FormatPrometheus = "prometheus"
+	FormatJSON       = "json"
+)
+
+// Arguments holds values which are used to configure the collect.exec
+// component.
+type Arguments struct {
+	Command       []string             `river:"command,attr"`
+	WorkingDir    string               `river:"working_dir,attr,optional"`
+	Env           map[string]string    `river:"env,attr,optional"`
+	EnvAllowlist  []string             `river:"env_allowlist,attr,optional"`
+	Interval      time.Duration        `river:"interval,attr,optional"`
+	Timeout       time.Duration        `river:"timeout,attr,optional"`
+	MaxOutputSize units.Base2Bytes     `river:"max_output_size,attr,optional"`
+	Format        string               `river:"format,attr,optional"`
+	ForwardTo     []storage.Appendable `river:"forward_to,attr"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval:      1 * time.Minute,
+	Timeout:       10 * time.Second,
+	MaxOutputSize: 1 * units.MiB,
+	Format:        FormatPrometheus,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if len(args.Command) == 0 || args.Command[0] == "" {
+		return fmt.Errorf("command must not be empty")
+	}
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if args.Timeout > args.Interval {
+		return fmt.Errorf("timeout must not be greater than interval")
+	}
+	if args.MaxOutputSize <= 0 {
+		return fmt.Errorf("max_output_size must be greater than 0")
+	}
+	switch args.Format {
+	case FormatPrometheus, FormatJSON:
+	default:
+		return fmt.Errorf("unsupported format %q: must be %q or %q", args.Format, FormatPrometheus, FormatJSON)
+	}
+	for _, name := range args.EnvAllowlist {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("invalid environment variable name %q in env_allowlist", name)
+		}
+	}
+	for name := range args.Env {
+		if name == "" || strings.Contains(name, "=") {
+			return fmt.Errorf("invalid environment variable name %q in env", name)
+		}
+	}
+	return nil
+}
+
+// environ returns the environment of the command: the variables of the agent
+// in the allowlist, overridden by the variables of env.
+func (args *Arguments) environ() []string {
+	env := make(map[string]string, len(args.EnvAllowlist)+len(args.Env))
+	for _, name := range args.EnvAllowlist {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	for name, value := range args.Env {
+		env[name] = value
+	}
+
+	// A non-nil empty environment keeps the command from inheriting the
+	// environment of the agent.
+	res := make([]string, 0, len(env))
+	for name, value := range env {
+		res = append(res, name+"="+value)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Component implements the collect.exec component.
+type Component struct {
+	log    log.Logger
+	opts   component.Options
+	fanout *agentprom.Fanout
+
+	mut     sync.Mutex
+	args    Arguments
+	lastRun time.Time
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new collect.exec component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	data, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		log:     opts.Logger,
+		opts:    opts,
+		fanout:  agentprom.NewFanout(args.ForwardTo, opts.ID, opts.Registerer, ls),
+		updated: make(chan struct{}, 1),
+
+		health: component.Health{
+			Health:     component.HealthTypeUnknown,
+			Message:    "component started",
+			UpdateTime: time.Now(),
+		},
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.nextRun()):
+			c.collect(ctx)
+		case <-c.updated:
+			// no-op; force the next wait to be reread.
+		}
+	}
+}
+
+// nextRun returns how long to wait to run the command given the last time it
+// ran. nextRun returns 0 if the command should run immediately.
+func (c *Component) nextRun() time.Duration {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	nextRun := c.lastRun.Add(c.args.Interval)
+	now := time.Now()
+
+	if now.After(nextRun) {
+		return 0
+	}
+	return nextRun.Sub(now)
+}
+
+// collect runs the command and forwards the samples of its output to the
+// receivers. c.mut must not be held when calling. After collecting, the
+// component's health is updated with the failure, if any.
+func (c *Component) collect(ctx context.Context) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := time.Now()
+	c.lastRun = now
+
+	err := c.collectOnce(ctx, now)
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to collect", "command", c.args.Command[0], "err", err)
+	}
+	c.updateHealth(err)
+}
+
+func (c *Component) collectOnce(ctx context.Context, now time.Time) error {
+	out, err := runCommand(ctx, c.args)
+	if err != nil {
+		return err
+	}
+
+	app := c.fanout.Appender(ctx)
+	if err := appendOutput(app, c.args.Format, out, timestamp.FromTime(now)); err != nil {
+		_ = app.Rollback()
+		return fmt.Errorf("parsing output: %w", err)
+	}
+	if err := app.Commit(); err != nil {
+		return fmt.Errorf("forwarding samples: %w", err)
+	}
+	return nil
+}
+
+// errOutputTooLarge is returned when a command writes more than the maximum
+// output size to stdout.
+var errOutputTooLarge = errors.New("output exceeds max_output_size")
+
+// runCommand runs the command of args, returning its stdout. The command is
+// killed when it runs longer than the timeout or writes more than the maximum
+// output size.
+func runCommand(ctx context.Context, args Arguments) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, args.Timeout)
+	defer cancel()
+
+	var (
+		stdout = &limitedBuffer{limit: int(args.MaxOutputSize), onExceeded: cancel}
+		stderr = &limitedBuffer{limit: maxStderrSize}
+	)
+
+	cmd := osexec.CommandContext(ctx, args.Command[0], args.Command[1:]...)
+	cmd.Dir = args.WorkingDir
+	cmd.Env = args.environ()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Don't wait for processes inheriting the output of the command after it
+	// was killed.
+	cmd.WaitDelay = time.Second
+	sandbox(cmd)
+
+	err := cmd.Run()
+	switch {
+	case stdout.exceeded:
+		return nil, errOutputTooLarge
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("command timed out after %s", args.Timeout)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.buf.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+// maxStderrSize is the maximum size of the stderr of commands reported in
+// errors.
+const maxStderrSize = 4096
+
+// limitedBuffer is a buffer holding up to limit bytes. Writes past the limit
+// are discarded, calling onExceeded once.
+type limitedBuffer struct {
+	buf        bytes.Buffer
+	limit      int
+	exceeded   bool
+	onExceeded func()
+}
+
+var _ io.Writer = (*limitedBuffer)(nil)
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); len(p) > remaining {
+		b.buf.Write(p[:max(remaining, 0)])
+		if !b.exceeded && b.onExceeded != nil {
+			b.onExceeded()
+		}
+		b.exceeded = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// appendOutput appends the samples of the output of a command to app.
+// Samples without timestamps are appended at ts.
+func appendOutput(app storage.Appender, format string, out []byte, ts int64) error {
+	switch format {
+	case FormatJSON:
+		return appendJSON(app, out, ts)
+	default:
+		return appendPrometheus(app, out, ts)
+	}
+}
+
+func appendPrometheus(app storage.Appender, out []byte, ts int64) error {
+	p := textparse.NewPromParser(out)
+	for {
+		entry, err := p.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if entry != textparse.EntrySeries {
+			continue
+		}
+
+		_, sampleTS, value := p.Series()
+		var lset labels.Labels
+		p.Metric(&lset)
+
+		// Labels with empty values are the same as missing labels.
+		lb := labels.NewScratchBuilder(lset.Len())
+		lset.Range(func(l labels.Label) {
+			if l.Value != "" {
+				lb.Add(l.Name, l.Value)
+			}
+		})
+
+		t := ts
+		if sampleTS != nil {
+			t = *sampleTS
+		}
+		if _, err := app.Append(0, lb.Labels(), t, value); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonSample is a sample of the JSON output of a command.
+type jsonSample struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp *int64            `json:"timestamp,omitempty"` // Milliseconds since the epoch.
+}
+
+func appendJSON(app storage.Appender, out []byte, ts int64) error {
+	var samples []jsonSample
+	if err := json.Unmarshal(out, &samples); err != nil {
+		return err
+	}
+
+	for i, s := range samples {
+		if !model.IsValidMetricName(model.LabelValue(s.Name)) {
+			return fmt.Errorf("sample %d: invalid metric name %q", i, s.Name)
+		}
+
+		lb := labels.NewScratchBuilder(len(s.Labels) + 1)
+		lb.Add(labels.MetricName, s.Name)
+		for n, v := range s.Labels {
+			if !model.LabelName(n).IsValid() {
+				return fmt.Errorf("sample %d: invalid label name %q", i, n)
+			}
+			if n != labels.MetricName {
+				lb.Add(n, v)
+			}
+		}
+		lb.Sort()
+
+		t := ts
+		if s.Timestamp != nil {
+			t = *s.Timestamp
+		}
+		if _, err := app.Append(0, lb.Labels(), t, s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Component) updateHealth(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+
+	if err == nil {
+		c.health = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "collected samples",
+			UpdateTime: time.Now(),
+		}
+	} else {
+		c.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("collecting samples failed: %s", err),
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = newArgs
+
+	// Send an updated event if one wasn't already read.
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
@@ -0,0 +1,172 @@
+//go:build !windows
+
+package exec
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		command         = ["/usr/local/bin/collect.sh", "--all"]
+		env_allowlist   = ["HOME"]
+		max_output_size = "64KiB"
+		forward_to      = []
+	`), &args))
+	require.Equal(t, time.Minute, args.Interval)
+	require.Equal(t, 10*time.Second, args.Timeout)
+	require.Equal(t, FormatPrometheus, args.Format)
+	require.EqualValues(t, 64*1024, args.MaxOutputSize)
+
+	require.EqualError(t, river.Unmarshal([]byte(`
+		command    = ["collect.sh"]
+		interval   = "10s"
+		timeout    = "1m"
+		forward_to = []
+	`), &args), "timeout must not be greater than interval")
+
+	require.EqualError(t, river.Unmarshal([]byte(`
+		command    = ["collect.sh"]
+		format     = "csv"
+		forward_to = []
+	`), &args), `unsupported format "csv": must be "prometheus" or "json"`)
+}
+
+func TestCollect(t *testing.T) {
+	t.Setenv("COLLECT_EXEC_ALLOWED", "allowed")
+	t.Setenv("COLLECT_EXEC_DENIED", "denied")
+
+	tt := []struct {
+		name   string
+		script string
+		format string
+		expect map[string]float64
+		err    string
+	}{
+		{
+			name: "prometheus",
+			script: `
+				echo '# TYPE queue_length gauge'
+				echo 'queue_length{queue="a"} 3'
+				echo "env_value{allowed=\"$COLLECT_EXEC_ALLOWED\",denied=\"$COLLECT_EXEC_DENIED\",set=\"$COLLECT_EXEC_SET\"} 1"
+			`,
+			format: FormatPrometheus,
+			expect: map[string]float64{
+				`{__name__="queue_length", queue="a"}`:                   3,
+				`{__name__="env_value", allowed="allowed", set="value"}`: 1,
+			},
+		},
+		{
+			name:   "json",
+			script: `echo '[{"name": "queue_length", "labels": {"queue": "b"}, "value": 5}]'`,
+			format: FormatJSON,
+			expect: map[string]float64{
+				`{__name__="queue_length", queue="b"}`: 5,
+			},
+		},
+		{
+			name:   "failure",
+			script: `echo 'something went wrong' >&2; exit 3`,
+			format: FormatPrometheus,
+			err:    "exit status 3: something went wrong",
+		},
+		{
+			name:   "timeout",
+			script: `sleep 10`,
+			format: FormatPrometheus,
+			err:    "command timed out after 500ms",
+		},
+		{
+			name:   "output too large",
+			script: `while true; do echo 'queue_length{queue="a"} 3'; done`,
+			format: FormatPrometheus,
+			err:    "output exceeds max_output_size",
+		},
+		{
+			name:   "invalid output",
+			script: `echo 'not a metric'`,
+			format: FormatPrometheus,
+			err:    "parsing output",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ls := labelstore.New(nil, prom.DefaultRegisterer)
+			var (
+				mut      sync.Mutex
+				received = map[string]float64{}
+			)
+			receiver := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+				mut.Lock()
+				defer mut.Unlock()
+				received[l.String()] = v
+				return ref, nil
+			}))
+
+			args := DefaultArguments
+			args.Command = []string{"/bin/sh", "-c", tc.script}
+			args.Env = map[string]string{"COLLECT_EXEC_SET": "value"}
+			args.EnvAllowlist = []string{"COLLECT_EXEC_ALLOWED"}
+			args.Timeout = 500 * time.Millisecond
+			args.MaxOutputSize = 4096
+			args.Format = tc.format
+			args.ForwardTo = []storage.Appendable{receiver}
+
+			c, err := New(component.Options{
+				ID:         "collect.exec.test",
+				Logger:     util.TestFlowLogger(t),
+				Registerer: prom.NewRegistry(),
+				GetServiceData: func(name string) (interface{}, error) {
+					return ls, nil
+				},
+			}, args)
+			require.NoError(t, err)
+
+			c.collect(context.Background())
+
+			health := c.CurrentHealth()
+			if tc.err != "" {
+				require.Equal(t, component.HealthTypeUnhealthy, health.Health)
+				require.Contains(t, health.Message, tc.err)
+				require.Empty(t, received)
+				return
+			}
+			require.Equal(t, component.HealthTypeHealthy, health.Health, health.Message)
+			require.Equal(t, tc.expect, received)
+		})
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	var exceeded int
+	b := &limitedBuffer{limit: 5, onExceeded: func() { exceeded++ }}
+
+	n, err := b.Write([]byte("abc"))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.False(t, b.exceeded)
+
+	n, err = b.Write([]byte("defg"))
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.True(t, b.exceeded)
+
+	_, _ = b.Write([]byte("h"))
+	require.Equal(t, "abcde", b.buf.String())
+	require.Equal(t, 1, exceeded)
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package exec
+
+import (
+	osexec "os/exec"
+	"syscall"
+)
+
+// sandbox runs cmd in its own process group, so that killing the command
+// also kills the processes it started.
+func sandbox(cmd *osexec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// A negative PID signals every process of the group.
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,7 @@
+package exec
+
+import osexec "os/exec"
+
+// sandbox is a no-op on Windows, where killing the command doesn't kill the
+// processes it started.
+func sandbox(*osexec.Cmd) {}
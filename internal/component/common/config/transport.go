@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/grafana/agent/internal/service/egress"
+	"github.com/prometheus/common/config"
+)
+
+// TransportArguments configures the connection pool and keepalives of the
+// connections of a client. Zero values keep the defaults of the client.
+//
+// Not every client supports every setting; clients reject the settings they
+// don't support with CheckSupported.
+type TransportArguments struct {
+	MaxIdleConns        int           `river:"max_idle_conns,attr,optional"`
+	MaxIdleConnsPerHost int           `river:"max_idle_conns_per_host,attr,optional"`
+	IdleConnTimeout     time.Duration `river:"idle_conn_timeout,attr,optional"`
+	TCPKeepAlive        time.Duration `river:"tcp_keepalive,attr,optional"`
+	HTTP2PingInterval   time.Duration `river:"http2_ping_interval,attr,optional"`
+	HTTP2PingTimeout    time.Duration `river:"http2_ping_timeout,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (t *TransportArguments) Validate() error {
+	switch {
+	case t.MaxIdleConns < 0:
+		return fmt.Errorf("max_idle_conns must not be negative")
+	case t.MaxIdleConnsPerHost < 0:
+		return fmt.Errorf("max_idle_conns_per_host must not be negative")
+	case t.IdleConnTimeout < 0:
+		return fmt.Errorf("idle_conn_timeout must not be negative")
+	case t.TCPKeepAlive < 0:
+		return fmt.Errorf("tcp_keepalive must not be negative")
+	case t.HTTP2PingInterval < 0:
+		return fmt.Errorf("http2_ping_interval must not be negative")
+	case t.HTTP2PingTimeout < 0:
+		return fmt.Errorf("http2_ping_timeout must not be negative")
+	case t.HTTP2PingTimeout > 0 && t.HTTP2PingInterval == 0:
+		return fmt.Errorf("http2_ping_timeout requires http2_ping_interval to be set")
+	}
+	return nil
+}
+
+// Transport settings, as passed to CheckSupported.
+const (
+	TransportMaxIdleConns        = "max_idle_conns"
+	TransportMaxIdleConnsPerHost = "max_idle_conns_per_host"
+	TransportIdleConnTimeout     = "idle_conn_timeout"
+	TransportTCPKeepAlive        = "tcp_keepalive"
+	TransportHTTP2Ping           = "http2_ping_interval"
+)
+
+// CheckSupported returns an error if t sets any setting which isn't in
+// supported, naming the client which doesn't support it. CheckSupported
+// returns nil if t is nil.
+func (t *TransportArguments) CheckSupported(client string, supported ...string) error {
+	if t == nil {
+		return nil
+	}
+
+	set := map[string]bool{
+		TransportMaxIdleConns:        t.MaxIdleConns > 0,
+		TransportMaxIdleConnsPerHost: t.MaxIdleConnsPerHost > 0,
+		TransportIdleConnTimeout:     t.IdleConnTimeout > 0,
+		TransportTCPKeepAlive:        t.TCPKeepAlive > 0,
+		TransportHTTP2Ping:           t.HTTP2PingInterval > 0,
+	}
+	for _, name := range supported {
+		delete(set, name)
+	}
+	for _, name := range []string{
+		TransportMaxIdleConns,
+		TransportMaxIdleConnsPerHost,
+		TransportIdleConnTimeout,
+		TransportTCPKeepAlive,
+		TransportHTTP2Ping,
+	} {
+		if set[name] {
+			return fmt.Errorf("transport: %s is not supported by %s", name, client)
+		}
+	}
+	return nil
+}
+
+// HTTPClientOptions returns the options which apply the idle_conn_timeout and
+// tcp_keepalive settings of t to clients created from Prometheus HTTP client
+// configs. The options replace egress.HTTPClientOption, and connections are
+// still made with egress.DialContext. HTTPClientOptions returns nil if t is
+// nil.
+func (t *TransportArguments) HTTPClientOptions() []config.HTTPClientOption {
+	if t == nil {
+		return nil
+	}
+
+	var opts []config.HTTPClientOption
+	if t.IdleConnTimeout > 0 {
+		opts = append(opts, config.WithIdleConnTimeout(t.IdleConnTimeout))
+	}
+	if t.TCPKeepAlive > 0 {
+		opts = append(opts, config.WithDialContextFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := egress.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			if tc, ok := conn.(*net.TCPConn); ok {
+				_ = tc.SetKeepAlive(true)
+				_ = tc.SetKeepAlivePeriod(t.TCPKeepAlive)
+			}
+			return conn, nil
+		}))
+	}
+	return opts
+}
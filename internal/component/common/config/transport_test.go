@@ -0,0 +1,61 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/river"
+	"github.com/prometheus/common/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportArguments(t *testing.T) {
+	var args TransportArguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		max_idle_conns      = 10
+		idle_conn_timeout   = "30s"
+		tcp_keepalive       = "15s"
+		http2_ping_interval = "1m"
+		http2_ping_timeout  = "10s"
+	`), &args))
+	require.Equal(t, TransportArguments{
+		MaxIdleConns:      10,
+		IdleConnTimeout:   30 * time.Second,
+		TCPKeepAlive:      15 * time.Second,
+		HTTP2PingInterval: time.Minute,
+		HTTP2PingTimeout:  10 * time.Second,
+	}, args)
+
+	err := river.Unmarshal([]byte(`http2_ping_timeout = "10s"`), &TransportArguments{})
+	require.EqualError(t, err, "http2_ping_timeout requires http2_ping_interval to be set")
+
+	require.NoError(t, args.CheckSupported("test",
+		TransportMaxIdleConns, TransportIdleConnTimeout, TransportTCPKeepAlive, TransportHTTP2Ping))
+	require.EqualError(t,
+		args.CheckSupported("test", TransportIdleConnTimeout, TransportTCPKeepAlive),
+		"transport: max_idle_conns is not supported by test",
+	)
+	require.NoError(t, (*TransportArguments)(nil).CheckSupported("test"))
+}
+
+func TestTransportArguments_HTTPClientOptions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	require.Nil(t, (*TransportArguments)(nil).HTTPClientOptions())
+
+	args := &TransportArguments{IdleConnTimeout: time.Second, TCPKeepAlive: 5 * time.Second}
+	opts := args.HTTPClientOptions()
+	require.Len(t, opts, 2)
+
+	cli, err := config.NewClientFromConfig(config.HTTPClientConfig{}, "test", opts...)
+	require.NoError(t, err)
+	resp, err := cli.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
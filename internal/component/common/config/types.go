@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/grafana/agent/internal/boringcrypto"
 	"github.com/grafana/river/rivertypes"
 	"github.com/prometheus/common/config"
 )
@@ -353,7 +354,7 @@ func (t *TLSConfig) Validate() error {
 		return fmt.Errorf("exactly one of cert_pem or cert_file must be configured when a client key is configured")
 	}
 
-	return nil
+	return boringcrypto.CheckTLSVersion("min_version", uint16(t.MinVersion))
 }
 
 // OAuth2Config sets up the OAuth2 client.
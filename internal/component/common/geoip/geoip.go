@@ -0,0 +1,208 @@
+// Package geoip looks up IP addresses in MaxMind databases (MMDB), reloading
+// the database when its file changes and caching the results of lookups.
+package geoip
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/filedetector"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// DecodeFunc decodes the record of ip from r into the value cached for ip.
+type DecodeFunc func(r *maxminddb.Reader, ip net.IP) (any, error)
+
+// Options configure a Database.
+type Options struct {
+	Logger log.Logger
+
+	Path          string                // Path of the MMDB file.
+	Detector      filedetector.Detector // How to detect changes to the file.
+	PollFrequency time.Duration         // How often to poll the file.
+
+	// CacheSize is the maximum number of lookups to cache. Lookups aren't
+	// cached if CacheSize is 0.
+	CacheSize int
+
+	// Decode decodes the records looked up in the database.
+	Decode DecodeFunc
+
+	// OnReload is called after the database is reloaded because its file
+	// changed, with the error which prevented the reload, if any. After a
+	// failed reload, lookups keep using the previous database.
+	OnReload func(err error)
+}
+
+// fileStat identifies a version of a database file.
+type fileStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// Database is an MMDB database which is reloaded when its file changes.
+type Database struct {
+	opts     Options
+	detector io.Closer
+
+	mut    sync.RWMutex
+	reader *maxminddb.Reader
+	stat   fileStat
+	cache  *lru.Cache[string, any]
+	closed bool
+}
+
+// Open opens the database at opts.Path and starts watching its file.
+func Open(opts Options) (*Database, error) {
+	if opts.Logger == nil {
+		opts.Logger = log.NewNopLogger()
+	}
+	if opts.PollFrequency <= 0 {
+		opts.PollFrequency = time.Minute
+	}
+
+	db := &Database{opts: opts}
+	if opts.CacheSize > 0 {
+		cache, err := lru.New[string, any](opts.CacheSize)
+		if err != nil {
+			return nil, err
+		}
+		db.cache = cache
+	}
+
+	reader, stat, err := open(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	db.reader, db.stat = reader, stat
+
+	switch opts.Detector {
+	case filedetector.DetectorPoll:
+		db.detector = filedetector.NewPoller(filedetector.PollerOptions{
+			Filename:      opts.Path,
+			ReloadFile:    db.reload,
+			PollFrequency: opts.PollFrequency,
+		})
+	default:
+		db.detector, err = filedetector.NewFSNotify(filedetector.FSNotifyOptions{
+			Logger:        opts.Logger,
+			Filename:      opts.Path,
+			ReloadFile:    db.reload,
+			PollFrequency: opts.PollFrequency,
+		})
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func open(path string) (*maxminddb.Reader, fileStat, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fileStat{}, err
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fileStat{}, fmt.Errorf("opening GeoIP database %s: %w", path, err)
+	}
+	return reader, fileStat{size: fi.Size(), modTime: fi.ModTime()}, nil
+}
+
+// reload reopens the database if its file changed since it was opened.
+func (db *Database) reload() {
+	fi, err := os.Stat(db.opts.Path)
+	if err != nil {
+		// The file may be in the middle of being replaced. The next event or
+		// poll retries.
+		level.Warn(db.opts.Logger).Log("msg", "failed to check GeoIP database", "path", db.opts.Path, "err", err)
+		return
+	}
+
+	db.mut.RLock()
+	unchanged := db.closed || (fi.Size() == db.stat.size && fi.ModTime().Equal(db.stat.modTime))
+	db.mut.RUnlock()
+	if unchanged {
+		return
+	}
+
+	reader, stat, err := open(db.opts.Path)
+	if err == nil {
+		db.mut.Lock()
+		if db.closed {
+			db.mut.Unlock()
+			reader.Close()
+			return
+		}
+		old := db.reader
+		db.reader, db.stat = reader, stat
+		if db.cache != nil {
+			db.cache.Purge()
+		}
+		db.mut.Unlock()
+
+		old.Close()
+		level.Info(db.opts.Logger).Log("msg", "reloaded GeoIP database", "path", db.opts.Path)
+	} else {
+		level.Error(db.opts.Logger).Log("msg", "failed to reload GeoIP database", "path", db.opts.Path, "err", err)
+	}
+
+	if db.opts.OnReload != nil {
+		db.opts.OnReload(err)
+	}
+}
+
+// Lookup returns the decoded record of ip. Records are cached, including
+// errors, until the database is reloaded.
+func (db *Database) Lookup(ip net.IP) (any, error) {
+	key := string(ip.To16())
+
+	db.mut.RLock()
+	defer db.mut.RUnlock()
+	if db.closed {
+		return nil, fmt.Errorf("GeoIP database %s is closed", db.opts.Path)
+	}
+
+	if db.cache != nil {
+		if res, ok := db.cache.Get(key); ok {
+			if err, ok := res.(error); ok {
+				return nil, err
+			}
+			return res, nil
+		}
+	}
+
+	res, err := db.opts.Decode(db.reader, ip)
+	if db.cache != nil {
+		if err != nil {
+			db.cache.Add(key, err)
+		} else {
+			db.cache.Add(key, res)
+		}
+	}
+	return res, err
+}
+
+// Close stops watching the database file and closes the database.
+func (db *Database) Close() error {
+	err := db.detector.Close()
+
+	db.mut.Lock()
+	defer db.mut.Unlock()
+	if db.closed {
+		return err
+	}
+	db.closed = true
+	if cerr := db.reader.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package geoip
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/filedetector"
+	"github.com/grafana/agent/internal/util"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/require"
+)
+
+// testIP is in every test database.
+var testIP = net.ParseIP("192.0.2.1")
+
+func TestDecodeLabels(t *testing.T) {
+	tt := []struct {
+		dbType string
+		file   string
+		labels []string
+	}{
+		{
+			dbType: TypeCity,
+			file:   "testdata/geoip_maxmind_city.mmdb",
+			labels: []string{
+				LabelCityName, LabelCountryName, LabelCountryCode, LabelContinentName, LabelContinentCode,
+				LabelLatitude, LabelLongitude, LabelPostalCode, LabelTimezone, LabelSubdivisionName, LabelSubdivisionCode,
+			},
+		},
+		{
+			dbType: TypeCountry,
+			file:   "testdata/geoip_maxmind_country.mmdb",
+			labels: []string{LabelCountryName, LabelCountryCode, LabelContinentName, LabelContinentCode},
+		},
+		{
+			dbType: TypeASN,
+			file:   "testdata/geoip_maxmind_asn.mmdb",
+			labels: []string{LabelASN, LabelASNOrganization},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.dbType, func(t *testing.T) {
+			r, err := maxminddb.Open(tc.file)
+			require.NoError(t, err)
+			defer r.Close()
+
+			res, err := DecodeLabels(tc.dbType)(r, testIP)
+			require.NoError(t, err)
+			labels := res.(map[string]string)
+			for _, name := range tc.labels {
+				require.NotEmpty(t, labels[name], "label %s", name)
+			}
+			require.Len(t, labels, len(tc.labels))
+		})
+	}
+}
+
+func TestDatabase_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geoip.mmdb")
+	copyFile(t, "testdata/geoip_maxmind_country.mmdb", path)
+
+	var (
+		decodes int
+		reloads = make(chan error, 10)
+	)
+	db, err := Open(Options{
+		Logger:        util.TestLogger(t),
+		Path:          path,
+		Detector:      filedetector.DetectorPoll,
+		PollFrequency: 10 * time.Millisecond,
+		CacheSize:     10,
+		Decode: func(r *maxminddb.Reader, ip net.IP) (any, error) {
+			decodes++
+			return r.Metadata.DatabaseType, nil
+		},
+		OnReload: func(err error) { reloads <- err },
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Lookups are cached.
+	for i := 0; i < 2; i++ {
+		res, err := db.Lookup(testIP)
+		require.NoError(t, err)
+		require.Contains(t, res, "Country")
+	}
+	require.Equal(t, 1, decodes)
+
+	// Replacing the file reloads the database and clears the cache.
+	copyFile(t, "testdata/geoip_maxmind_asn.mmdb", path)
+	select {
+	case err := <-reloads:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "database was not reloaded")
+	}
+	res, err := db.Lookup(testIP)
+	require.NoError(t, err)
+	require.Contains(t, res, "ASN")
+	require.Equal(t, 2, decodes)
+
+	// An invalid file is reported, and the previous database is kept.
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0644))
+	select {
+	case err := <-reloads:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "database was not reloaded")
+	}
+	res, err = db.Lookup(testIP)
+	require.NoError(t, err)
+	require.Contains(t, res, "ASN")
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	bb, err := os.ReadFile(from)
+	require.NoError(t, err)
+	tmp := to + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, bb, 0644))
+	require.NoError(t, os.Rename(tmp, to))
+}
@@ -0,0 +1,107 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Types of databases.
+const (
+	TypeCity    = "city"
+	TypeCountry = "country"
+	TypeASN     = "asn"
+)
+
+// Labels set by DecodeLabels. They have the same names as the fields
+// extracted by the geoip stage of loki.process.
+const (
+	LabelCityName        = "geoip_city_name"
+	LabelCountryName     = "geoip_country_name"
+	LabelCountryCode     = "geoip_country_code"
+	LabelContinentName   = "geoip_continent_name"
+	LabelContinentCode   = "geoip_continent_code"
+	LabelLatitude        = "geoip_location_latitude"
+	LabelLongitude       = "geoip_location_longitude"
+	LabelPostalCode      = "geoip_postal_code"
+	LabelTimezone        = "geoip_timezone"
+	LabelSubdivisionName = "geoip_subdivision_name"
+	LabelSubdivisionCode = "geoip_subdivision_code"
+	LabelASN             = "geoip_autonomous_system_number"
+	LabelASNOrganization = "geoip_autonomous_system_organization"
+)
+
+// ValidateType returns an error if dbType isn't a known type of database.
+func ValidateType(dbType string) error {
+	switch dbType {
+	case TypeCity, TypeCountry, TypeASN:
+		return nil
+	default:
+		return fmt.Errorf("db_type must be %q, %q or %q, got %q", TypeCity, TypeCountry, TypeASN, dbType)
+	}
+}
+
+// DecodeLabels returns a DecodeFunc which decodes records of a database of
+// type dbType into a map[string]string of labels. Fields which are missing
+// from the record aren't set.
+func DecodeLabels(dbType string) DecodeFunc {
+	return func(r *maxminddb.Reader, ip net.IP) (any, error) {
+		labels := make(map[string]string)
+		set := func(name, value string) {
+			if value != "" {
+				labels[name] = value
+			}
+		}
+
+		switch dbType {
+		case TypeCity:
+			var record geoip2.City
+			if err := r.Lookup(ip, &record); err != nil {
+				return nil, err
+			}
+			set(LabelCityName, record.City.Names["en"])
+			set(LabelCountryName, record.Country.Names["en"])
+			set(LabelCountryCode, record.Country.IsoCode)
+			set(LabelContinentName, record.Continent.Names["en"])
+			set(LabelContinentCode, record.Continent.Code)
+			set(LabelPostalCode, record.Postal.Code)
+			set(LabelTimezone, record.Location.TimeZone)
+			if record.Location.Latitude != 0 || record.Location.Longitude != 0 {
+				set(LabelLatitude, strconv.FormatFloat(record.Location.Latitude, 'f', -1, 64))
+				set(LabelLongitude, strconv.FormatFloat(record.Location.Longitude, 'f', -1, 64))
+			}
+			if n := len(record.Subdivisions); n > 0 {
+				// The last subdivision is the most specific one.
+				set(LabelSubdivisionName, record.Subdivisions[n-1].Names["en"])
+				set(LabelSubdivisionCode, record.Subdivisions[n-1].IsoCode)
+			}
+
+		case TypeCountry:
+			var record geoip2.Country
+			if err := r.Lookup(ip, &record); err != nil {
+				return nil, err
+			}
+			set(LabelCountryName, record.Country.Names["en"])
+			set(LabelCountryCode, record.Country.IsoCode)
+			set(LabelContinentName, record.Continent.Names["en"])
+			set(LabelContinentCode, record.Continent.Code)
+
+		case TypeASN:
+			var record geoip2.ASN
+			if err := r.Lookup(ip, &record); err != nil {
+				return nil, err
+			}
+			if record.AutonomousSystemNumber != 0 {
+				set(LabelASN, strconv.FormatUint(uint64(record.AutonomousSystemNumber), 10))
+			}
+			set(LabelASNOrganization, record.AutonomousSystemOrganization)
+
+		default:
+			return nil, ValidateType(dbType)
+		}
+		return labels, nil
+	}
+}
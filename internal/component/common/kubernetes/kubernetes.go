@@ -7,6 +7,7 @@ import (
 	"github.com/go-kit/log"
 	commoncfg "github.com/grafana/agent/internal/component/common/config"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/egress"
 	"github.com/grafana/agent/internal/useragent"
 	promconfig "github.com/prometheus/common/config"
 	"k8s.io/client-go/rest"
@@ -69,7 +70,7 @@ func (args *ClientArguments) BuildRESTConfig(l log.Logger) (*rest.Config, error)
 		level.Info(l).Log("msg", "Using pod service account via in-cluster config")
 
 	default:
-		rt, err := promconfig.NewRoundTripperFromConfig(*args.HTTPClientConfig.Convert(), "component.common.kubernetes")
+		rt, err := promconfig.NewRoundTripperFromConfig(*args.HTTPClientConfig.Convert(), "component.common.kubernetes", egress.HTTPClientOption())
 		if err != nil {
 			return nil, err
 		}
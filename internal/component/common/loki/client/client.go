@@ -21,6 +21,7 @@ import (
 	"github.com/prometheus/common/model"
 
 	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/service/drops"
 	"github.com/grafana/agent/internal/util"
 	lokiutil "github.com/grafana/loki/pkg/util"
 )
@@ -59,9 +60,12 @@ type Metrics struct {
 	mutatedBytes                 *prometheus.CounterVec
 	requestDuration              *prometheus.HistogramVec
 	batchRetries                 *prometheus.CounterVec
+	cancelledRequests            *prometheus.CounterVec
 	countersWithHost             []*prometheus.CounterVec
 	countersWithHostTenant       []*prometheus.CounterVec
 	countersWithHostTenantReason []*prometheus.CounterVec
+
+	drops *drops.Recorder
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -103,9 +107,13 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		Name: "loki_write_batch_retries_total",
 		Help: "Number of times batches has had to be retried.",
 	}, []string{HostLabel, TenantLabel})
+	m.cancelledRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_write_cancelled_requests_total",
+		Help: "Number of send requests cancelled because the client was stopped.",
+	}, []string{HostLabel})
 
 	m.countersWithHost = []*prometheus.CounterVec{
-		m.encodedBytes, m.sentBytes, m.sentEntries,
+		m.encodedBytes, m.sentBytes, m.sentEntries, m.cancelledRequests,
 	}
 
 	m.countersWithHostTenant = []*prometheus.CounterVec{
@@ -126,11 +134,35 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		m.mutatedBytes = util.MustRegisterOrGet(reg, m.mutatedBytes).(*prometheus.CounterVec)
 		m.requestDuration = util.MustRegisterOrGet(reg, m.requestDuration).(*prometheus.HistogramVec)
 		m.batchRetries = util.MustRegisterOrGet(reg, m.batchRetries).(*prometheus.CounterVec)
+		m.cancelledRequests = util.MustRegisterOrGet(reg, m.cancelledRequests).(*prometheus.CounterVec)
 	}
 
 	return &m
 }
 
+// RecordDrops makes the clients using m also record the entries they drop
+// with r.
+func (m *Metrics) RecordDrops(r *drops.Recorder) {
+	m.drops = r
+}
+
+// countDropped counts the entries dropped by the client of host. status is
+// the status code of the last request which tried to send the entries, or 0
+// if they weren't sent.
+func (m *Metrics) countDropped(host, tenantID, reason string, status, entries int, bytes float64) {
+	m.droppedEntries.WithLabelValues(host, tenantID, reason).Add(float64(entries))
+	m.droppedBytes.WithLabelValues(host, tenantID, reason).Add(bytes)
+
+	switch {
+	case reason == ReasonLineTooLong || reason == ReasonStreamLimited:
+		m.drops.Add(drops.ReasonLimit, entries)
+	case status/100 == 4:
+		m.drops.Add(drops.ReasonRejected, entries)
+	default:
+		m.drops.Add(drops.ReasonSendFailed, entries)
+	}
+}
+
 // Client pushes entries to Loki and can be stopped
 type Client interface {
 	loki.EntryHandler
@@ -151,7 +183,7 @@ type client struct {
 	once sync.Once
 	wg   sync.WaitGroup
 
-	externalLabels model.LabelSet
+	externalLabels *externalLabels
 
 	// ctx is used in any upstream calls from the `client`.
 	ctx                 context.Context
@@ -186,7 +218,7 @@ func newClient(metrics *Metrics, cfg Config, maxStreams, maxLineSize int, maxLin
 		metrics: metrics,
 		name:    GetClientName(cfg),
 
-		externalLabels:      cfg.ExternalLabels.LabelSet,
+		externalLabels:      newExternalLabels(cfg.ExternalLabels.LabelSet),
 		ctx:                 ctx,
 		cancel:              cancel,
 		maxStreams:          maxStreams,
@@ -202,7 +234,7 @@ func newClient(metrics *Metrics, cfg Config, maxStreams, maxLineSize int, maxLin
 		return nil, err
 	}
 
-	c.client, err = config.NewClientFromConfig(cfg.Client, "GrafanaAgent", config.WithHTTP2Disabled())
+	c.client, err = config.NewClientFromConfig(cfg.Client, "GrafanaAgent", cfg.httpClientOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +283,9 @@ func (c *client) initBatchMetrics(tenantID string) {
 func (c *client) run() {
 	batches := map[string]*batch{}
 
+	// batchLabels are the external labels of the entries in batches.
+	var batchLabels *model.LabelSet
+
 	// Given the client handles multiple batches (1 per tenant) and each batch
 	// can be created at a different point in time, we look for batches whose
 	// max wait time has been reached every 10 times per BatchWait, so that the
@@ -282,13 +317,22 @@ func (c *client) run() {
 				return
 			}
 
-			e, tenantID := c.processEntry(e)
+			if lbls := c.externalLabels.Load(); lbls != batchLabels {
+				// Send the pending batches, so that the entries with the new
+				// external labels are sent in separate requests.
+				for tenantID, batch := range batches {
+					c.sendBatch(tenantID, batch)
+					delete(batches, tenantID)
+				}
+				batchLabels = lbls
+			}
+
+			e, tenantID := c.processEntry(e, *batchLabels)
 
 			// Either drop or mutate the log entry because its length is greater than maxLineSize. maxLineSize == 0 means disabled.
 			if c.maxLineSize != 0 && len(e.Line) > c.maxLineSize {
 				if !c.maxLineSizeTruncate {
-					c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Inc()
-					c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Add(float64(len(e.Line)))
+					c.metrics.countDropped(c.cfg.URL.Host, tenantID, ReasonLineTooLong, 0, 1, float64(len(e.Line)))
 					break
 				}
 
@@ -323,8 +367,7 @@ func (c *client) run() {
 				if err.Error() == errMaxStreamsLimitExceeded {
 					reason = ReasonStreamLimited
 				}
-				c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(float64(len(e.Line)))
-				c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Inc()
+				c.metrics.countDropped(c.cfg.URL.Host, tenantID, reason, 0, 1, float64(len(e.Line)))
 				return
 			}
 		case <-maxWaitCheck.C:
@@ -362,16 +405,22 @@ func (c *client) sendBatch(tenantID string, batch *batch) {
 	var status int
 	for {
 		start := time.Now()
-		// send uses `timeout` internally, so `context.Background` is good enough.
-		status, err = c.send(context.Background(), tenantID, buf)
+		// send uses `timeout` internally, and c.ctx cancels the request when
+		// the client is stopped.
+		status, err = c.send(c.ctx, tenantID, buf)
 
 		c.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), c.cfg.URL.Host).Observe(time.Since(start).Seconds())
 
+		if err != nil && c.ctx.Err() != nil {
+			level.Warn(c.logger).Log("msg", "request cancelled, the client is stopping", "tenant", tenantID)
+			c.metrics.cancelledRequests.WithLabelValues(c.cfg.URL.Host).Inc()
+			break
+		}
+
 		// Immediately drop rate limited batches to avoid HOL blocking for other tenants not experiencing throttling
 		if c.cfg.DropRateLimitedBatches && batchIsRateLimited(status) {
 			level.Warn(c.logger).Log("msg", "dropping batch due to rate limiting applied at ingester")
-			c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(bufBytes)
-			c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(float64(entriesCount))
+			c.metrics.countDropped(c.cfg.URL.Host, tenantID, ReasonRateLimited, status, entriesCount, bufBytes)
 			return
 		}
 
@@ -405,8 +454,7 @@ func (c *client) sendBatch(tenantID string, batch *batch) {
 		if batchIsRateLimited(status) {
 			dropReason = ReasonRateLimited
 		}
-		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(bufBytes)
-		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(float64(entriesCount))
+		c.metrics.countDropped(c.cfg.URL.Host, tenantID, dropReason, status, entriesCount, bufBytes)
 	}
 }
 
@@ -476,6 +524,11 @@ func (c *client) Stop() {
 	c.wg.Wait()
 }
 
+// cancelInFlight cancels the in-flight request and the retries of the client.
+func (c *client) cancelInFlight() {
+	c.cancel()
+}
+
 // StopNow stops the client without retries
 func (c *client) StopNow() {
 	// cancel will stop retrying http requests.
@@ -483,9 +536,15 @@ func (c *client) StopNow() {
 	c.Stop()
 }
 
-func (c *client) processEntry(e loki.Entry) (loki.Entry, string) {
-	if len(c.externalLabels) > 0 {
-		e.Labels = c.externalLabels.Merge(e.Labels)
+// SetExternalLabels changes the external labels added to the entries sent
+// by the client.
+func (c *client) SetExternalLabels(lbls model.LabelSet) {
+	c.externalLabels.Store(lbls)
+}
+
+func (c *client) processEntry(e loki.Entry, externalLabels model.LabelSet) (loki.Entry, string) {
+	if len(externalLabels) > 0 {
+		e.Labels = externalLabels.Merge(e.Labels)
 	}
 	tenantID := c.getTenantID(e.Labels)
 	return e, tenantID
@@ -21,7 +21,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/service/drops"
 	"github.com/grafana/loki/clients/pkg/promtail/utils"
 
 	"github.com/grafana/loki/pkg/logproto"
@@ -715,6 +717,90 @@ func TestClient_StopNow(t *testing.T) {
 	}
 }
 
+func TestClient_SetExternalLabels(t *testing.T) {
+	receivedReqsChan := make(chan utils.RemoteWriteRequest, 10)
+	server := utils.NewRemoteWriteServer(receivedReqsChan, 200)
+	require.NotNil(t, server)
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	// The batches are only sent when the client stops, or when the external
+	// labels change.
+	cfg := Config{
+		URL:            serverURL,
+		BatchWait:      time.Hour,
+		BatchSize:      1 << 20,
+		Client:         config.HTTPClientConfig{},
+		BackoffConfig:  backoff.Config{MinBackoff: 5 * time.Second, MaxBackoff: 10 * time.Second, MaxRetries: 1},
+		ExternalLabels: lokiflag.LabelSet{LabelSet: model.LabelSet{"region": "eu-west-1"}},
+		Timeout:        1 * time.Second,
+	}
+	cl, err := New(NewMetrics(prometheus.NewRegistry()), cfg, 0, 0, false, log.NewNopLogger())
+	require.NoError(t, err)
+
+	cl.Chan() <- logEntries[0]
+	cl.Chan() <- logEntries[1]
+	cl.(*client).SetExternalLabels(model.LabelSet{"region": "eu-west-2"})
+	cl.Chan() <- logEntries[2]
+	cl.Stop()
+	close(receivedReqsChan)
+
+	// The second entry may be batched before or after the labels change, but
+	// each request only holds entries with the same external labels.
+	var (
+		receivedLabels []string
+		receivedLines  []string
+	)
+	for req := range receivedReqsChan {
+		require.Len(t, req.Request.Streams, 1)
+		receivedLabels = append(receivedLabels, req.Request.Streams[0].Labels)
+		for _, e := range req.Request.Streams[0].Entries {
+			receivedLines = append(receivedLines, e.Line)
+		}
+	}
+	require.Equal(t, []string{`{region="eu-west-1"}`, `{region="eu-west-2"}`}, receivedLabels)
+	require.Equal(t, []string{"line1", "line2", "line3"}, receivedLines)
+}
+
+func TestClient_RecordDrops(t *testing.T) {
+	receivedReqsChan := make(chan utils.RemoteWriteRequest, 10)
+	server := utils.NewRemoteWriteServer(receivedReqsChan, http.StatusBadRequest)
+	require.NotNil(t, server)
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	cfg := Config{
+		URL:           serverURL,
+		BatchWait:     time.Hour,
+		BatchSize:     1 << 20,
+		Client:        config.HTTPClientConfig{},
+		BackoffConfig: backoff.Config{MinBackoff: 1 * time.Millisecond, MaxBackoff: 2 * time.Millisecond, MaxRetries: 1},
+		Timeout:       1 * time.Second,
+	}
+	reg := prometheus.NewRegistry()
+	m := NewMetrics(reg)
+	m.RecordDrops(drops.NewRecorder(component.Options{ID: "loki.write.test", Registerer: reg}, drops.SignalLogs))
+	cl, err := New(m, cfg, 0, len("line1"), false, log.NewNopLogger())
+	require.NoError(t, err)
+
+	cl.Chan() <- logEntries[0]
+	cl.Chan() <- loki.Entry{Labels: model.LabelSet{}, Entry: logproto.Entry{Timestamp: time.Unix(1, 0).UTC(), Line: "too long"}}
+	cl.Stop()
+	close(receivedReqsChan)
+
+	expect := `
+# HELP agent_dropped_total Total number of samples or log entries dropped by the component.
+# TYPE agent_dropped_total counter
+agent_dropped_total{reason="limit",signal="logs"} 1
+agent_dropped_total{reason="rejected",signal="logs"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), "agent_dropped_total"))
+}
+
 type RoundTripperFunc func(*http.Request) (*http.Response, error)
 
 func (r RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
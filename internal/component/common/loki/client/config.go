@@ -4,6 +4,8 @@ import (
 	"flag"
 	"time"
 
+	types "github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/service/egress"
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/flagext"
 	"github.com/prometheus/common/config"
@@ -47,6 +49,17 @@ type Config struct {
 
 	// Queue controls configuration parameters specific to the queue client
 	Queue QueueConfig
+
+	// Transport tunes the connections of the client. It's nil if the defaults
+	// are used.
+	Transport *types.TransportArguments `yaml:"-"`
+}
+
+// httpClientOptions returns the options used to create the HTTP client for
+// cfg.
+func (c Config) httpClientOptions() []config.HTTPClientOption {
+	opts := []config.HTTPClientOption{config.WithHTTP2Disabled(), egress.HTTPClientOption()}
+	return append(opts, c.Transport.HTTPClientOptions()...)
 }
 
 // QueueConfig holds configurations for the queue-based remote-write client.
@@ -121,3 +134,38 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	*c = Config(cfg)
 	return nil
 }
+
+// hashedConfig holds the fields of Config which GetClientName hashes. Fields
+// added to Config later, such as Transport, are hashed separately and only
+// when they're set, so that the names of existing clients don't change.
+type hashedConfig struct {
+	Name                   string
+	URL                    flagext.URLValue
+	BatchWait              time.Duration
+	BatchSize              int
+	Client                 config.HTTPClientConfig
+	Headers                map[string]string
+	BackoffConfig          backoff.Config
+	ExternalLabels         lokiflag.LabelSet
+	Timeout                time.Duration
+	TenantID               string
+	DropRateLimitedBatches bool
+	Queue                  QueueConfig
+}
+
+func (c Config) hashedFields() hashedConfig {
+	return hashedConfig{
+		Name:                   c.Name,
+		URL:                    c.URL,
+		BatchWait:              c.BatchWait,
+		BatchSize:              c.BatchSize,
+		Client:                 c.Client,
+		Headers:                c.Headers,
+		BackoffConfig:          c.BackoffConfig,
+		ExternalLabels:         c.ExternalLabels,
+		Timeout:                c.Timeout,
+		TenantID:               c.TenantID,
+		DropRateLimitedBatches: c.DropRateLimitedBatches,
+		Queue:                  c.Queue,
+	}
+}
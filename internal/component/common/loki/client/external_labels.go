@@ -0,0 +1,45 @@
+package client
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/common/model"
+)
+
+// externalLabelsSetter is implemented by clients whose external labels can be
+// changed without restarting them.
+type externalLabelsSetter interface {
+	SetExternalLabels(lbls model.LabelSet)
+}
+
+// externalLabels holds the external labels of a client, which may change
+// while the client runs.
+//
+// Clients compare the pointers returned by Load to detect changes, and send
+// their pending batches before batching entries with the new labels, so that
+// a single request never mixes both.
+type externalLabels struct {
+	ptr atomic.Pointer[model.LabelSet]
+}
+
+func newExternalLabels(lbls model.LabelSet) *externalLabels {
+	var l externalLabels
+	l.ptr.Store(&lbls)
+	return &l
+}
+
+// Load returns the current labels. The same pointer is returned until the
+// labels are changed.
+func (l *externalLabels) Load() *model.LabelSet {
+	return l.ptr.Load()
+}
+
+// Store changes the labels to lbls. It's a no-op if lbls are equal to the
+// current labels.
+func (l *externalLabels) Store(lbls model.LabelSet) {
+	if l.ptr.Load().Equal(lbls) {
+		return
+	}
+	lbls = lbls.Clone()
+	l.ptr.Store(&lbls)
+}
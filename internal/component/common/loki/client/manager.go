@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/internal/component/common/loki/client/internal"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 
 	"github.com/grafana/agent/internal/component/common/loki"
 	"github.com/grafana/agent/internal/component/common/loki/limit"
@@ -21,6 +23,7 @@ import (
 type WriterEventsNotifier interface {
 	SubscribeCleanup(subscriber wal.CleanupEventSubscriber)
 	SubscribeWrite(subscriber wal.WriteEventSubscriber)
+	RegisterMarker(marker wal.Marker)
 }
 
 var (
@@ -35,6 +38,8 @@ func (n nilNotifier) SubscribeCleanup(_ wal.CleanupEventSubscriber) {}
 
 func (n nilNotifier) SubscribeWrite(_ wal.WriteEventSubscriber) {}
 
+func (n nilNotifier) RegisterMarker(_ wal.Marker) {}
+
 type StoppableWatcher interface {
 	Stop()
 	Drain()
@@ -117,6 +122,8 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 				return nil, err
 			}
 			markerHandler := internal.NewMarkerHandler(markerFileHandler, walCfg.MaxSegmentAge, logger, walMarkerMetrics.WithCurriedId(clientName))
+			// register the marker so the writer doesn't reclaim segments the client hasn't delivered yet
+			notifier.RegisterMarker(markerHandler)
 
 			queue, err := NewQueue(metrics, queueClientMetrics.CurryWithId(clientName), cfg, limits.MaxStreams, limits.MaxLineSize.Val(), limits.MaxLineSizeTruncate, logger, markerHandler)
 			if err != nil {
@@ -196,6 +203,17 @@ func (m *Manager) startWithForward() {
 	}()
 }
 
+// SetExternalLabels changes the external labels of all clients without
+// restarting them. Clients send the entries they batched with the previous
+// labels before batching entries with the new labels.
+func (m *Manager) SetExternalLabels(lbls model.LabelSet) {
+	for _, pair := range m.pairs {
+		if setter, ok := pair.client.(externalLabelsSetter); ok {
+			setter.SetExternalLabels(lbls)
+		}
+	}
+}
+
 func (m *Manager) StopNow() {
 	for _, pair := range m.pairs {
 		pair.client.StopNow()
@@ -220,6 +238,33 @@ func (m *Manager) Stop() {
 // The shutdown procedure first stops the Watchers, allowing them to flush as much data into the clients as possible. Then
 // the clients are shut down accordingly.
 func (m *Manager) StopWithDrain(drain bool) {
+	m.StopWithTimeout(drain, 0)
+}
+
+// inFlightCanceller is implemented by clients whose in-flight requests can be
+// cancelled while they're stopping.
+type inFlightCanceller interface {
+	cancelInFlight()
+}
+
+// StopWithTimeout stops the manager like StopWithDrain, cancelling the
+// in-flight requests and retries of the clients which haven't stopped after
+// timeout, so that a stuck request doesn't block the stop. A timeout of 0
+// waits for the clients without a deadline.
+func (m *Manager) StopWithTimeout(drain bool, timeout time.Duration) {
+	if timeout > 0 {
+		// The forwarding routine blocks on clients which are sending, so the
+		// deadline covers stopping it too.
+		timer := time.AfterFunc(timeout, func() {
+			for _, pair := range m.pairs {
+				if c, ok := pair.client.(inFlightCanceller); ok {
+					c.cancelInFlight()
+				}
+			}
+		})
+		defer timer.Stop()
+	}
+
 	// first stop the receiving channel
 	m.once.Do(func() { close(m.entries) })
 	m.wg.Wait()
@@ -247,7 +292,10 @@ func GetClientName(cfg Config) string {
 	if cfg.Name != "" {
 		return cfg.Name
 	}
-	return asSha256(cfg)
+	if cfg.Transport != nil {
+		return asSha256([]interface{}{cfg.hashedFields(), *cfg.Transport})
+	}
+	return asSha256(cfg.hashedFields())
 }
 
 func asSha256(o interface{}) string {
@@ -3,9 +3,12 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,9 +16,11 @@ import (
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/flagext"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 
+	types "github.com/grafana/agent/internal/component/common/config"
 	"github.com/grafana/agent/internal/component/common/loki"
 	"github.com/grafana/agent/internal/component/common/loki/limit"
 	"github.com/grafana/agent/internal/component/common/loki/utils"
@@ -250,6 +255,65 @@ func TestManager_WALDisabled(t *testing.T) {
 	require.Len(t, seenEntries, totalLines)
 }
 
+func TestManager_StopWithTimeout(t *testing.T) {
+	// The server never answers, so requests only end when they're cancelled.
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body is read.
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	reg := prometheus.NewRegistry()
+	manager, err := NewManager(NewMetrics(reg), log.NewNopLogger(), testLimitsConfig, prometheus.NewRegistry(), wal.Config{}, NilNotifier, Config{
+		Name:      "stuck-client",
+		URL:       flagext.URLValue{URL: serverURL},
+		Timeout:   time.Hour,
+		BatchWait: 10 * time.Millisecond,
+		BatchSize: 1024,
+		BackoffConfig: backoff.Config{
+			MaxRetries: 10,
+			MinBackoff: time.Hour,
+		},
+	})
+	require.NoError(t, err)
+
+	manager.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"job": "stuck"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "line"},
+	}
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for the request")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		manager.StopWithTimeout(false, 100*time.Millisecond)
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "stopping the manager didn't cancel the in-flight request")
+	}
+
+	expected := `
+# HELP loki_write_cancelled_requests_total Number of send requests cancelled because the client was stopped.
+# TYPE loki_write_cancelled_requests_total counter
+loki_write_cancelled_requests_total{host="` + serverURL.Host + `"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "loki_write_cancelled_requests_total"))
+}
+
 func TestManager_WALDisabled_MultipleConfigs(t *testing.T) {
 	walConfig := wal.Config{}
 	// start all necessary resources
@@ -329,3 +393,25 @@ func TestManager_WALDisabled_MultipleConfigs(t *testing.T) {
 	}
 	require.Len(t, seenEntries, expectedTotalLines)
 }
+
+func TestGetClientName(t *testing.T) {
+	u, err := url.Parse("http://localhost:3100/loki/api/v1/push")
+	require.NoError(t, err)
+	cfg := Config{
+		URL:       flagext.URLValue{URL: u},
+		BatchWait: time.Second,
+		BatchSize: 100,
+		TenantID:  "tenant",
+		Headers:   map[string]string{"a": "b"},
+	}
+
+	// The names of clients without a transport block must not change, since
+	// the WAL tracks the progress of clients by name.
+	require.Equal(t, "12e45f", GetClientName(cfg))
+
+	cfg.Transport = &types.TransportArguments{IdleConnTimeout: time.Minute}
+	require.NotEqual(t, "12e45f", GetClientName(cfg))
+
+	cfg.Name = "named"
+	require.Equal(t, "named", GetClientName(cfg))
+}
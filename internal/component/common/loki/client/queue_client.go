@@ -157,13 +157,14 @@ type queueClient struct {
 	client    *http.Client
 
 	batches      map[string]*batch
+	batchLabels  *model.LabelSet // External labels of the entries in batches.
 	batchesMtx   sync.Mutex
 	sendQueue    *queue
 	drainTimeout time.Duration
 
 	wg sync.WaitGroup
 
-	externalLabels model.LabelSet
+	externalLabels *externalLabels
 
 	// series cache
 	series        map[chunks.HeadSeriesRef]model.LabelSet
@@ -206,7 +207,7 @@ func newQueueClient(metrics *Metrics, qcMetrics *QueueClientMetrics, cfg Config,
 		series:        make(map[chunks.HeadSeriesRef]model.LabelSet),
 		seriesSegment: make(map[chunks.HeadSeriesRef]int),
 
-		externalLabels:      cfg.ExternalLabels.LabelSet,
+		externalLabels:      newExternalLabels(cfg.ExternalLabels.LabelSet),
 		ctx:                 ctx,
 		cancel:              cancel,
 		maxStreams:          maxStreams,
@@ -224,7 +225,7 @@ func newQueueClient(metrics *Metrics, qcMetrics *QueueClientMetrics, cfg Config,
 		return nil, err
 	}
 
-	c.client, err = config.NewClientFromConfig(cfg.Client, "GrafanaAgent", config.WithHTTP2Disabled())
+	c.client, err = config.NewClientFromConfig(cfg.Client, "GrafanaAgent", cfg.httpClientOptions()...)
 	if err != nil {
 		return nil, err
 	}
@@ -305,13 +306,13 @@ func (c *queueClient) AppendEntries(entries wal.RefEntries, segment int) error {
 }
 
 func (c *queueClient) appendSingleEntry(segmentNum int, lbs model.LabelSet, e logproto.Entry) {
-	lbs, tenantID := c.processLabels(lbs)
+	externalLabels := c.externalLabels.Load()
+	lbs, tenantID := c.processLabels(lbs, *externalLabels)
 
 	// Either drop or mutate the log entry because its length is greater than maxLineSize. maxLineSize == 0 means disabled.
 	if c.maxLineSize != 0 && len(e.Line) > c.maxLineSize {
 		if !c.maxLineSizeTruncate {
-			c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Inc()
-			c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Add(float64(len(e.Line)))
+			c.metrics.countDropped(c.cfg.URL.Host, tenantID, ReasonLineTooLong, 0, 1, float64(len(e.Line)))
 			return
 		}
 
@@ -323,6 +324,19 @@ func (c *queueClient) appendSingleEntry(segmentNum int, lbs model.LabelSet, e lo
 	// TODO: can I make this locking more fine grained?
 	c.batchesMtx.Lock()
 
+	if externalLabels != c.batchLabels {
+		// Enqueue the pending batches, so that the entries with the new
+		// external labels are sent in separate requests.
+		for tenantID, batch := range c.batches {
+			c.sendQueue.enqueue(queuedBatch{
+				TenantID: tenantID,
+				Batch:    batch,
+			})
+			delete(c.batches, tenantID)
+		}
+		c.batchLabels = externalLabels
+	}
+
 	batch, ok := c.batches[tenantID]
 
 	// If the batch doesn't exist yet, we create a new one with the entry
@@ -365,8 +379,7 @@ func (c *queueClient) appendSingleEntry(segmentNum int, lbs model.LabelSet, e lo
 		if err.Error() == errMaxStreamsLimitExceeded {
 			reason = ReasonStreamLimited
 		}
-		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(float64(len(e.Line)))
-		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Inc()
+		c.metrics.countDropped(c.cfg.URL.Host, tenantID, reason, 0, 1, float64(len(e.Line)))
 	}
 }
 
@@ -465,11 +478,16 @@ func (c *queueClient) sendBatch(ctx context.Context, tenantID string, batch *bat
 
 		c.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), c.cfg.URL.Host).Observe(time.Since(start).Seconds())
 
+		if err != nil && ctx.Err() != nil {
+			level.Warn(c.logger).Log("msg", "request cancelled, the client is stopping", "tenant", tenantID)
+			c.metrics.cancelledRequests.WithLabelValues(c.cfg.URL.Host).Inc()
+			break
+		}
+
 		// Immediately drop rate limited batches to avoid HOL blocking for other tenants not experiencing throttling
 		if c.cfg.DropRateLimitedBatches && batchIsRateLimited(status) {
 			level.Warn(c.logger).Log("msg", "dropping batch due to rate limiting applied at ingester")
-			c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(bufBytes)
-			c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonRateLimited).Add(float64(entriesCount))
+			c.metrics.countDropped(c.cfg.URL.Host, tenantID, ReasonRateLimited, status, entriesCount, bufBytes)
 			return
 		}
 
@@ -503,8 +521,7 @@ func (c *queueClient) sendBatch(ctx context.Context, tenantID string, batch *bat
 		if batchIsRateLimited(status) {
 			dropReason = ReasonRateLimited
 		}
-		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(bufBytes)
-		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, dropReason).Add(float64(entriesCount))
+		c.metrics.countDropped(c.cfg.URL.Host, tenantID, dropReason, status, entriesCount, bufBytes)
 	}
 }
 
@@ -592,6 +609,12 @@ func (c *queueClient) Stop() {
 	c.markerHandler.Stop()
 }
 
+// cancelInFlight cancels the in-flight requests and the retries of the
+// client.
+func (c *queueClient) cancelInFlight() {
+	c.cancel()
+}
+
 // StopNow stops the client without retries or draining the send queue
 func (c *queueClient) StopNow() {
 	// cancel will stop retrying http requests.
@@ -602,9 +625,15 @@ func (c *queueClient) StopNow() {
 	c.markerHandler.Stop()
 }
 
-func (c *queueClient) processLabels(lbs model.LabelSet) (model.LabelSet, string) {
-	if len(c.externalLabels) > 0 {
-		lbs = c.externalLabels.Merge(lbs)
+// SetExternalLabels changes the external labels added to the entries sent
+// by the client.
+func (c *queueClient) SetExternalLabels(lbls model.LabelSet) {
+	c.externalLabels.Store(lbls)
+}
+
+func (c *queueClient) processLabels(lbs, externalLabels model.LabelSet) (model.LabelSet, string) {
+	if len(externalLabels) > 0 {
+		lbs = externalLabels.Merge(lbs)
 	}
 	tenantID := c.getTenantID(lbs)
 	return lbs, tenantID
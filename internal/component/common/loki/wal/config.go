@@ -31,6 +31,11 @@ type Config struct {
 	// Note that this functionality will likely be deprecated in favour of a programmatic cleanup mechanism.
 	MaxSegmentAge time.Duration
 
+	// MaxUnsentSegmentAge is threshold at which a WAL segment that hasn't yet been delivered by every client is considered
+	// old enough to be cleaned up. This allows keeping undelivered data around during long outages of the remote end.
+	// Values lower than MaxSegmentAge are ignored, and MaxSegmentAge is used instead.
+	MaxUnsentSegmentAge time.Duration
+
 	// WatchConfig configures the backoff retry used by a WAL watcher when reading from segments not via
 	// the notification channel.
 	WatchConfig WatchConfig
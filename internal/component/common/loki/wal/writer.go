@@ -2,6 +2,7 @@ package wal
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"sort"
@@ -57,15 +58,27 @@ type Writer struct {
 	writeSubscribersLock sync.RWMutex
 	writeSubscribers     []WriteEventSubscriber
 
+	markersLock sync.RWMutex
+	markers     []Marker
+
+	maxUnsentSegmentAge time.Duration
+	now                 func() time.Time // Returns the time segments are compared to when cleaning them.
+
 	reclaimedOldSegmentsSpaceCounter *prometheus.CounterVec
 	lastReclaimedSegment             *prometheus.GaugeVec
 	lastWrittenTimestamp             *prometheus.GaugeVec
+	retainedUnsentSegments           *prometheus.GaugeVec
 
 	closeCleaner chan struct{}
 }
 
 // NewWriter creates a new Writer.
 func NewWriter(walCfg Config, logger log.Logger, reg prometheus.Registerer) (*Writer, error) {
+	return newWriter(walCfg, logger, reg, time.Now)
+}
+
+// newWriter creates a new Writer which uses now to get the current time.
+func newWriter(walCfg Config, logger log.Logger, reg prometheus.Registerer, now func() time.Time) (*Writer, error) {
 	// Start WAL
 	wl, err := New(Config{
 		Dir:     walCfg.Dir,
@@ -82,6 +95,9 @@ func NewWriter(walCfg Config, logger log.Logger, reg prometheus.Registerer) (*Wr
 		wal:          wl,
 		entryWriter:  newEntryWriter(),
 		closeCleaner: make(chan struct{}, 1),
+
+		maxUnsentSegmentAge: walCfg.MaxUnsentSegmentAge,
+		now:                 now,
 	}
 
 	wrt.reclaimedOldSegmentsSpaceCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -103,11 +119,18 @@ func NewWriter(walCfg Config, logger log.Logger, reg prometheus.Registerer) (*Wr
 		Name:      "last_written_timestamp",
 		Help:      "Latest timestamp that was written to the WAL",
 	}, []string{})
+	wrt.retainedUnsentSegments = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "loki_write",
+		Subsystem: "wal_writer",
+		Name:      "retained_unsent_segments",
+		Help:      "Number of segments older than the max segment age retained because they haven't been delivered yet",
+	}, []string{})
 
 	if reg != nil {
 		_ = reg.Register(wrt.reclaimedOldSegmentsSpaceCounter)
 		_ = reg.Register(wrt.lastReclaimedSegment)
 		_ = reg.Register(wrt.lastWrittenTimestamp)
+		_ = reg.Register(wrt.retainedUnsentSegments)
 	}
 
 	wrt.start(walCfg.MaxSegmentAge)
@@ -182,8 +205,16 @@ func (wrt *Writer) Stop() {
 // deleted since it's likely there's active readers on it. In case there's multiple segments, each will be deleted if:
 // - It's not the last (highest numbered) segment
 // - It's last modified date is older than the max allowed age
+// - It has been delivered by every registered Marker, or it's older than the max allowed age for unsent segments
 func (wrt *Writer) cleanSegments(maxAge time.Duration) error {
-	maxModifiedAt := time.Now().Add(-maxAge)
+	now := wrt.now()
+	maxModifiedAt := now.Add(-maxAge)
+	maxUnsentModifiedAt := maxModifiedAt
+	if wrt.maxUnsentSegmentAge > maxAge {
+		maxUnsentModifiedAt = now.Add(-wrt.maxUnsentSegmentAge)
+	}
+	lastDelivered, tracksDelivery := wrt.lastDeliveredSegment()
+
 	walDir := wrt.wal.Dir()
 	segments, err := listSegments(walDir)
 	if err != nil {
@@ -201,8 +232,14 @@ func (wrt *Writer) cleanSegments(maxAge time.Duration) error {
 			lastSegment = segment.number
 		}
 	}
+	retainedUnsent := 0
 	for _, segment := range segments {
 		if segment.lastModified.Before(maxModifiedAt) && segment.number != lastSegment {
+			if tracksDelivery && segment.number > lastDelivered && !segment.lastModified.Before(maxUnsentModifiedAt) {
+				// segment hasn't been delivered yet, keep it around until it's older than the max age for unsent segments
+				retainedUnsent++
+				continue
+			}
 			// segment is older than allowed age, cleaning up
 			if err := os.Remove(filepath.Join(walDir, segment.name)); err != nil {
 				level.Error(wrt.log).Log("msg", "Error old wal segment", "err", err, "segmentNum", segment.number)
@@ -215,6 +252,7 @@ func (wrt *Writer) cleanSegments(maxAge time.Duration) error {
 			}
 		}
 	}
+	wrt.retainedUnsentSegments.WithLabelValues().Set(float64(retainedUnsent))
 	// if we reclaimed at least one segment, notify all subscribers
 	if maxReclaimed != -1 {
 		wrt.cleanupSubscribersLock.RLock()
@@ -241,6 +279,31 @@ func (wrt *Writer) SubscribeWrite(subscriber WriteEventSubscriber) {
 	wrt.writeSubscribers = append(wrt.writeSubscribers, subscriber)
 }
 
+// RegisterMarker adds a new Marker which tracks the segments delivered by a reader of the WAL. Segments which haven't
+// been delivered by every registered Marker are retained until they are older than the max age for unsent segments.
+func (wrt *Writer) RegisterMarker(marker Marker) {
+	wrt.markersLock.Lock()
+	defer wrt.markersLock.Unlock()
+	wrt.markers = append(wrt.markers, marker)
+}
+
+// lastDeliveredSegment returns the highest segment which has been delivered by every registered Marker. The second
+// return value is false if no Marker has been registered.
+func (wrt *Writer) lastDeliveredSegment() (int, bool) {
+	wrt.markersLock.RLock()
+	defer wrt.markersLock.RUnlock()
+	if len(wrt.markers) == 0 {
+		return -1, false
+	}
+	lowest := math.MaxInt
+	for _, m := range wrt.markers {
+		if marked := m.LastMarkedSegment(); marked < lowest {
+			lowest = marked
+		}
+	}
+	return lowest, true
+}
+
 // entryWriter writes loki.Entry to a WAL, keeping in memory a single Record object that's reused
 // across every write.
 type entryWriter struct {
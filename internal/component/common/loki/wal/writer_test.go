@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -73,16 +75,17 @@ func TestWriter_OldSegmentsAreCleanedUp(t *testing.T) {
 	logger := level.NewFilter(log.NewLogfmtLogger(os.Stdout), level.AllowDebug())
 	dir := t.TempDir()
 
-	maxSegmentAge := time.Second * 2
+	maxSegmentAge := time.Hour
+	clock := newTestClock()
 
 	subscriber1 := []int{}
 	subscriber2 := []int{}
 
-	writer, err := NewWriter(Config{
+	writer, err := newWriter(Config{
 		Dir:           dir,
 		Enabled:       true,
 		MaxSegmentAge: maxSegmentAge,
-	}, logger, prometheus.NewRegistry())
+	}, logger, prometheus.NewRegistry(), clock.Now)
 	require.NoError(t, err)
 	defer func() {
 		writer.Stop()
@@ -135,8 +138,9 @@ func TestWriter_OldSegmentsAreCleanedUp(t *testing.T) {
 	_, err = writer.wal.NextSegment()
 	require.NoError(t, err, "error closing current segment")
 
-	// wait for segment to be cleaned
-	time.Sleep(maxSegmentAge * 2)
+	// clean the segment once it's older than the max segment age
+	clock.Add(maxSegmentAge * 2)
+	require.NoError(t, writer.cleanSegments(maxSegmentAge))
 
 	watchAndLogDirEntries(t, dir)
 
@@ -156,19 +160,88 @@ func TestWriter_OldSegmentsAreCleanedUp(t *testing.T) {
 	require.NoError(t, err)
 }
 
+type staticMarker struct {
+	segment atomic.Int64
+}
+
+func (m *staticMarker) LastMarkedSegment() int {
+	return int(m.segment.Load())
+}
+
+func TestWriter_UnsentSegmentsAreRetained(t *testing.T) {
+	logger := level.NewFilter(log.NewLogfmtLogger(os.Stdout), level.AllowDebug())
+	dir := t.TempDir()
+
+	maxSegmentAge := time.Hour
+	clock := newTestClock()
+
+	writer, err := newWriter(Config{
+		Dir:                 dir,
+		Enabled:             true,
+		MaxSegmentAge:       maxSegmentAge,
+		MaxUnsentSegmentAge: 24 * time.Hour,
+	}, logger, prometheus.NewRegistry(), clock.Now)
+	require.NoError(t, err)
+	defer func() {
+		writer.Stop()
+	}()
+
+	// no segment has been delivered yet
+	marker := &staticMarker{}
+	marker.segment.Store(-1)
+	writer.RegisterMarker(marker)
+
+	writer.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"testing": "log"},
+		Entry: logproto.Entry{
+			Timestamp: time.Now(),
+			Line:      "some line",
+		},
+	}
+
+	// accessing the WAL inside, just for testing!
+	require.NoError(t, writer.wal.Sync(), "failed to sync wal")
+	eventuallyReadWAL(t, 1, dir)
+
+	// force close segment, so that it's old enough to be cleaned up
+	_, err = writer.wal.NextSegment()
+	require.NoError(t, err, "error closing current segment")
+
+	// the segment must be retained since it wasn't delivered
+	clock.Add(maxSegmentAge * 2)
+	require.NoError(t, writer.cleanSegments(maxSegmentAge))
+	require.FileExists(t, filepath.Join(dir, "00000000"))
+
+	// once delivered, the segment is cleaned up
+	marker.segment.Store(0)
+	require.NoError(t, writer.cleanSegments(maxSegmentAge))
+	require.NoFileExists(t, filepath.Join(dir, "00000000"))
+
+	// undelivered segments are cleaned up once they're older than the max age for unsent segments
+	_, err = writer.wal.NextSegment()
+	require.NoError(t, err, "error closing current segment")
+	clock.Add(maxSegmentAge * 2)
+	require.NoError(t, writer.cleanSegments(maxSegmentAge))
+	require.FileExists(t, filepath.Join(dir, "00000001"))
+	clock.Add(24 * time.Hour)
+	require.NoError(t, writer.cleanSegments(maxSegmentAge))
+	require.NoFileExists(t, filepath.Join(dir, "00000001"))
+}
+
 func TestWriter_NoSegmentIsCleanedUpIfTheresOnlyOne(t *testing.T) {
 	logger := level.NewFilter(log.NewLogfmtLogger(os.Stdout), level.AllowDebug())
 	dir := t.TempDir()
 
-	maxSegmentAge := time.Second * 2
+	maxSegmentAge := time.Hour
+	clock := newTestClock()
 
 	segmentsReclaimedNotificationsReceived := []int{}
 
-	writer, err := NewWriter(Config{
+	writer, err := newWriter(Config{
 		Dir:           dir,
 		Enabled:       true,
 		MaxSegmentAge: maxSegmentAge,
-	}, logger, prometheus.NewRegistry())
+	}, logger, prometheus.NewRegistry(), clock.Now)
 	require.NoError(t, err)
 	defer func() {
 		writer.Stop()
@@ -212,8 +285,9 @@ func TestWriter_NoSegmentIsCleanedUpIfTheresOnlyOne(t *testing.T) {
 	require.NoError(t, err)
 	require.GreaterOrEqual(t, fileInfo.Size(), int64(0), "first segment size should be >= 0")
 
-	// wait for segment to be cleaned
-	time.Sleep(maxSegmentAge * 2)
+	// run the cleanup once the segment is older than the max segment age
+	clock.Add(maxSegmentAge * 2)
+	require.NoError(t, writer.cleanSegments(maxSegmentAge))
 
 	watchAndLogDirEntries(t, dir)
 
@@ -222,6 +296,29 @@ func TestWriter_NoSegmentIsCleanedUpIfTheresOnlyOne(t *testing.T) {
 	require.Len(t, segmentsReclaimedNotificationsReceived, 0, "expected no notification")
 }
 
+// testClock is a clock for newWriter which only moves forward when advanced
+// with Add.
+type testClock struct {
+	mut sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Now()}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.now = c.now.Add(d)
+}
+
 func watchAndLogDirEntries(t *testing.T, path string) {
 	dirs, err := os.ReadDir(path)
 	if len(dirs) == 0 {
@@ -4,9 +4,11 @@ package net
 
 import (
 	"flag"
+	"fmt"
 	"math"
 	"time"
 
+	"github.com/grafana/agent/internal/service/ports"
 	dskit "github.com/grafana/dskit/server"
 )
 
@@ -44,6 +46,19 @@ type HTTPConfig struct {
 	ServerReadTimeout  time.Duration `river:"server_read_timeout,attr,optional"`
 	ServerWriteTimeout time.Duration `river:"server_write_timeout,attr,optional"`
 	ServerIdleTimeout  time.Duration `river:"server_idle_timeout,attr,optional"`
+
+	// ListenPortRange is a range of ports, in the form MIN-MAX, to listen on
+	// a free port of instead of ListenPort.
+	ListenPortRange string `river:"listen_port_range,attr,optional"`
+
+	// Limits configures limits on the requests accepted by the server. Requests
+	// aren't limited if Limits is nil.
+	Limits *LimitsConfig `river:"limits,block,optional"`
+}
+
+// Validate implements river.Validator.
+func (h *HTTPConfig) Validate() error {
+	return validatePortRange(h.ListenPort, h.ListenPortRange)
 }
 
 // Into applies the configs from HTTPConfig into a dskit.Into.
@@ -67,6 +82,20 @@ type GRPCConfig struct {
 	ServerMaxRecvMsg           int           `river:"server_max_recv_msg_size,attr,optional"`
 	ServerMaxSendMsg           int           `river:"server_max_send_msg_size,attr,optional"`
 	ServerMaxConcurrentStreams uint          `river:"server_max_concurrent_streams,attr,optional"`
+
+	// ListenPortRange is a range of ports, in the form MIN-MAX, to listen on
+	// a free port of instead of ListenPort.
+	ListenPortRange string `river:"listen_port_range,attr,optional"`
+
+	// HealthService registers the standard gRPC health checking service.
+	HealthService bool `river:"health_service,attr,optional"`
+	// Reflection registers the gRPC server reflection service.
+	Reflection bool `river:"reflection,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (g *GRPCConfig) Validate() error {
+	return validatePortRange(g.ListenPort, g.ListenPortRange)
 }
 
 // Into applies the configs from GRPCConfig into a dskit.Into.
@@ -101,6 +130,64 @@ func (c *ServerConfig) convert() dskit.Config {
 	return cfg
 }
 
+// validatePortRange validates the listen_port_range of a block.
+func validatePortRange(port int, portRange string) error {
+	if portRange == "" {
+		return nil
+	}
+	if port != 0 {
+		return fmt.Errorf("cannot specify both listen_port and listen_port_range")
+	}
+	_, err := ports.ParseRange(portRange)
+	return err
+}
+
+// AllocatePorts returns a copy of c whose listen ports are allocated by alloc
+// from the listen port ranges of c. The ports are allocated to the component
+// with the global ID owner, which must release them with alloc.Release once
+// it stops listening. AllocatePorts returns c if c has no listen port ranges.
+func (c *ServerConfig) AllocatePorts(alloc *ports.Allocator, owner string) (*ServerConfig, error) {
+	var (
+		usingHTTPRange = c.HTTP != nil && c.HTTP.ListenPortRange != ""
+		usingGRPCRange = c.GRPC != nil && c.GRPC.ListenPortRange != ""
+	)
+	if !usingHTTPRange && !usingGRPCRange {
+		return c, nil
+	}
+	if alloc == nil {
+		return nil, fmt.Errorf("listen_port_range requires the %s service", ports.ServiceName)
+	}
+
+	allocate := func(name, host, portRange string) (int, error) {
+		r, err := ports.ParseRange(portRange)
+		if err != nil {
+			return 0, err
+		}
+		return alloc.Allocate(owner, name, host, r)
+	}
+
+	res := *c
+	if usingHTTPRange {
+		http := *c.HTTP
+		port, err := allocate("http", http.ListenAddress, http.ListenPortRange)
+		if err != nil {
+			return nil, err
+		}
+		http.ListenPort = port
+		res.HTTP = &http
+	}
+	if usingGRPCRange {
+		grpc := *c.GRPC
+		port, err := allocate("grpc", grpc.ListenAddress, grpc.ListenPortRange)
+		if err != nil {
+			return nil, err
+		}
+		grpc.ListenPort = port
+		res.GRPC = &grpc
+	}
+	return &res, nil
+}
+
 // newdskitDefaultConfig creates a new dskit.Config object with some overridden defaults.
 func newdskitDefaultConfig() dskit.Config {
 	c := dskit.Config{}
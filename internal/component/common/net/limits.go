@@ -0,0 +1,128 @@
+package net
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// LimitsConfig configures limits on the requests accepted by the HTTP server.
+// Zero values disable the corresponding limit.
+type LimitsConfig struct {
+	MaxRequestBodySize    units.Base2Bytes `river:"max_request_body_size,attr,optional"`
+	MaxConcurrentRequests int              `river:"max_concurrent_requests,attr,optional"`
+	RateLimit             float64          `river:"rate_limit,attr,optional"`
+	RateLimitBurst        int              `river:"rate_limit_burst,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (l *LimitsConfig) Validate() error {
+	switch {
+	case l.MaxRequestBodySize < 0:
+		return fmt.Errorf("max_request_body_size must not be negative")
+	case l.MaxConcurrentRequests < 0:
+		return fmt.Errorf("max_concurrent_requests must not be negative")
+	case l.RateLimit < 0:
+		return fmt.Errorf("rate_limit must not be negative")
+	case l.RateLimitBurst < 0:
+		return fmt.Errorf("rate_limit_burst must not be negative")
+	case l.RateLimitBurst > 0 && l.RateLimit == 0:
+		return fmt.Errorf("rate_limit_burst requires rate_limit to be set")
+	}
+	return nil
+}
+
+// Reasons for rejecting requests, used as values of the reason label.
+const (
+	rejectBodySize         = "body_size"
+	rejectRateLimit        = "rate_limit"
+	rejectConcurrencyLimit = "concurrency_limit"
+)
+
+// limiter enforces a LimitsConfig on the requests served by a router.
+type limiter struct {
+	maxBodySize int64
+	rateLimiter *rate.Limiter // nil if requests aren't rate limited.
+	inflight    chan struct{} // nil if concurrent requests aren't limited.
+	rejected    *prometheus.CounterVec
+}
+
+func newLimiter(cfg LimitsConfig, metricsNamespace string, reg prometheus.Registerer) *limiter {
+	l := &limiter{
+		maxBodySize: int64(cfg.MaxRequestBodySize),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_rejected_total",
+			Help:      "Total number of requests rejected for exceeding the configured limits.",
+		}, []string{"reason"}),
+	}
+	if reg != nil {
+		l.rejected = util.MustRegisterOrGet(reg, l.rejected).(*prometheus.CounterVec)
+	}
+
+	if cfg.RateLimit > 0 {
+		burst := cfg.RateLimitBurst
+		if burst == 0 {
+			burst = max(1, int(cfg.RateLimit))
+		}
+		l.rateLimiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), burst)
+	}
+	if cfg.MaxConcurrentRequests > 0 {
+		l.inflight = make(chan struct{}, cfg.MaxConcurrentRequests)
+	}
+	return l
+}
+
+// Middleware returns a mux.MiddlewareFunc which rejects requests exceeding
+// the limits with 413 Request Entity Too Large or 429 Too Many Requests.
+func (l *limiter) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.maxBodySize > 0 {
+				// Requests without a Content-Length header are rejected by the
+				// handler once it reads past the limit.
+				if r.ContentLength > l.maxBodySize {
+					l.reject(w, rejectBodySize, http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, l.maxBodySize)
+			}
+
+			if l.rateLimiter != nil && !l.rateLimiter.Allow() {
+				w.Header().Set("Retry-After", retryAfter(l.rateLimiter.Limit()))
+				l.reject(w, rejectRateLimit, http.StatusTooManyRequests)
+				return
+			}
+
+			if l.inflight != nil {
+				select {
+				case l.inflight <- struct{}{}:
+					defer func() { <-l.inflight }()
+				default:
+					l.reject(w, rejectConcurrencyLimit, http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l *limiter) reject(w http.ResponseWriter, reason string, status int) {
+	l.rejected.WithLabelValues(reason).Inc()
+	http.Error(w, http.StatusText(status), status)
+}
+
+// retryAfter returns the value of the Retry-After header for requests
+// rejected by a rate limiter with the given limit, in whole seconds.
+func retryAfter(limit rate.Limit) string {
+	d := time.Duration(float64(time.Second) / float64(limit))
+	return fmt.Sprint(max(1, int((d+time.Second-1)/time.Second)))
+}
@@ -0,0 +1,112 @@
+package net
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsConfig(t *testing.T) {
+	var cfg ServerConfig
+	require.NoError(t, river.Unmarshal([]byte(`
+		http {
+			limits {
+				max_request_body_size   = "1MiB"
+				max_concurrent_requests = 10
+				rate_limit              = 5.5
+				rate_limit_burst        = 10
+			}
+		}
+	`), &cfg))
+	require.Equal(t, &LimitsConfig{
+		MaxRequestBodySize:    1 << 20,
+		MaxConcurrentRequests: 10,
+		RateLimit:             5.5,
+		RateLimitBurst:        10,
+	}, cfg.HTTP.Limits)
+
+	var invalid ServerConfig
+	err := river.Unmarshal([]byte(`http { limits { rate_limit_burst = 10 } }`), &invalid)
+	require.EqualError(t, err, "rate_limit_burst requires rate_limit to be set")
+}
+
+func TestTargetServer_Limits(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ts, err := NewTargetServer(util.TestLogger(t), "test_namespace", reg, &ServerConfig{
+		HTTP: &HTTPConfig{
+			ListenAddress: "127.0.0.1",
+			Limits: &LimitsConfig{
+				MaxRequestBodySize:    10,
+				MaxConcurrentRequests: 1,
+				RateLimit:             0.01,
+				RateLimitBurst:        3,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	err = ts.MountAndRun(func(router *mux.Router) {
+		router.Methods("POST").Path("/push").Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if _, err := io.ReadAll(req.Body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.URL.Query().Has("block") {
+				close(started)
+				<-release
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	})
+	require.NoError(t, err)
+	defer ts.StopAndShutdown()
+
+	push := func(query, body string) *http.Response {
+		url := fmt.Sprintf("http://%s/push%s", ts.HTTPListenAddr(), query)
+		res, err := http.Post(url, "text/plain", strings.NewReader(body))
+		require.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	// Bodies larger than the limit are rejected before using up the rate limit.
+	require.Equal(t, http.StatusRequestEntityTooLarge, push("", "more than ten bytes").StatusCode)
+
+	// Only one request is served at a time.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.Equal(t, http.StatusNoContent, push("?block", "").StatusCode)
+	}()
+	<-started
+	require.Equal(t, http.StatusTooManyRequests, push("", "").StatusCode)
+	close(release)
+	<-done
+
+	// The burst of three requests was used up by the previous two requests.
+	require.Equal(t, http.StatusNoContent, push("", "").StatusCode)
+	res := push("", "")
+	require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	require.Equal(t, "100", res.Header.Get("Retry-After"))
+
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP test_namespace_requests_rejected_total Total number of requests rejected for exceeding the configured limits.
+		# TYPE test_namespace_requests_rejected_total counter
+		test_namespace_requests_rejected_total{reason="body_size"} 1
+		test_namespace_requests_rejected_total{reason="concurrency_limit"} 1
+		test_namespace_requests_rejected_total{reason="rate_limit"} 1
+	`), "test_namespace_requests_rejected_total"))
+}
@@ -9,6 +9,9 @@ import (
 	dskit "github.com/grafana/dskit/server"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 // TargetServer is wrapper around dskit.Server that handles some common configuration used in all flow components
@@ -19,6 +22,11 @@ type TargetServer struct {
 	config           *dskit.Config
 	metricsNamespace string
 	server           *dskit.Server
+	limiter          *limiter // nil if requests aren't limited.
+
+	healthService bool
+	reflection    bool
+	health        *health.Server // nil if the health service isn't registered.
 }
 
 // NewTargetServer creates a new TargetServer, applying some defaults to the server configuration.
@@ -36,6 +44,12 @@ func NewTargetServer(logger log.Logger, metricsNamespace string, reg prometheus.
 	if config == nil {
 		config = DefaultServerConfig()
 	}
+	// Ports of listen port ranges are allocated by the component with
+	// ServerConfig.AllocatePorts.
+	if (config.HTTP != nil && config.HTTP.ListenPortRange != "" && config.HTTP.ListenPort == 0) ||
+		(config.GRPC != nil && config.GRPC.ListenPortRange != "" && config.GRPC.ListenPort == 0) {
+		return nil, fmt.Errorf("listen_port_range isn't supported by this component")
+	}
 
 	// convert from River into the dskit config
 	serverCfg := config.convert()
@@ -55,6 +69,14 @@ func NewTargetServer(logger log.Logger, metricsNamespace string, reg prometheus.
 	// Add logger to dskit
 	ts.config.Log = ts.logger
 
+	if config.HTTP != nil && config.HTTP.Limits != nil {
+		ts.limiter = newLimiter(*config.HTTP.Limits, ts.metricsNamespace, reg)
+	}
+	if config.GRPC != nil {
+		ts.healthService = config.GRPC.HealthService
+		ts.reflection = config.GRPC.Reflection
+	}
+
 	return ts, nil
 }
 
@@ -67,8 +89,19 @@ func (ts *TargetServer) MountAndRun(mountRoute func(router *mux.Router)) error {
 	}
 
 	ts.server = srv
+	if ts.limiter != nil {
+		ts.server.HTTP.Use(ts.limiter.Middleware())
+	}
 	mountRoute(ts.server.HTTP)
 
+	if ts.healthService {
+		ts.health = health.NewServer()
+		healthpb.RegisterHealthServer(ts.server.GRPC, ts.health)
+	}
+	if ts.reflection {
+		reflection.Register(ts.server.GRPC)
+	}
+
 	go func() {
 		err := srv.Run()
 		if err != nil {
@@ -91,6 +124,11 @@ func (ts *TargetServer) GRPCListenAddr() string {
 
 // StopAndShutdown stops and shuts down the underlying server.
 func (ts *TargetServer) StopAndShutdown() {
+	if ts.health != nil {
+		// Report the server as not serving to clients watching its health
+		// before it stops.
+		ts.health.Shutdown()
+	}
 	ts.server.Stop()
 	ts.server.Shutdown()
 }
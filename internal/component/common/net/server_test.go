@@ -1,6 +1,7 @@
 package net
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -10,6 +11,12 @@ import (
 	"github.com/grafana/agent/internal/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
 )
 
 func TestTargetServer(t *testing.T) {
@@ -53,3 +60,66 @@ func TestTargetServer_NilConfig(t *testing.T) {
 	require.Equal(t, "[::]:8080", ts.HTTPListenAddr())
 	// not asserting over grpc port since a random should have been assigned
 }
+
+func TestTargetServer_GRPCServices(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	config := testServerConfig()
+	config.GRPC.HealthService = true
+	config.GRPC.Reflection = true
+	ts, err := NewTargetServer(util.TestLogger(t), "test_namespace", reg, config)
+	require.NoError(t, err)
+
+	err = ts.MountAndRun(func(router *mux.Router) {})
+	require.NoError(t, err)
+	defer ts.StopAndShutdown()
+
+	conn, err := grpc.Dial(ts.GRPCListenAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	info, err := stream.Recv()
+	require.NoError(t, err)
+
+	var services []string
+	for _, svc := range info.GetListServicesResponse().GetService() {
+		services = append(services, svc.Name)
+	}
+	require.Contains(t, services, "grpc.health.v1.Health")
+}
+
+func TestTargetServer_NoGRPCServices(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ts, err := NewTargetServer(util.TestLogger(t), "test_namespace", reg, testServerConfig())
+	require.NoError(t, err)
+
+	err = ts.MountAndRun(func(router *mux.Router) {})
+	require.NoError(t, err)
+	defer ts.StopAndShutdown()
+
+	conn, err := grpc.Dial(ts.GRPCListenAddr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+// testServerConfig returns the default server config listening on random
+// local ports.
+func testServerConfig() *ServerConfig {
+	config := DefaultServerConfig()
+	config.HTTP.ListenAddress = "127.0.0.1"
+	config.HTTP.ListenPort = 0
+	config.GRPC.ListenAddress = "127.0.0.1"
+	return config
+}
@@ -0,0 +1,31 @@
+package component
+
+import "context"
+
+// Executor bounds how many goroutines may concurrently process data for a
+// set of components. Components acquire a worker from their Executor around
+// CPU-intensive processing, such as transforming or relabeling data, so that
+// this processing can be capped without affecting other components.
+type Executor interface {
+	// Acquire blocks until a worker is available or ctx is canceled. The
+	// returned release function must be called once processing is done, and
+	// must be called before handing data over to other components.
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// ExecutorFunc is a function which implements Executor.
+type ExecutorFunc func(ctx context.Context) (release func(), err error)
+
+// Acquire implements Executor.
+func (f ExecutorFunc) Acquire(ctx context.Context) (release func(), err error) {
+	return f(ctx)
+}
+
+// Acquire acquires a worker from e. If e is nil, Acquire returns immediately,
+// so that processing is unbounded.
+func Acquire(ctx context.Context, e Executor) (release func(), err error) {
+	if e == nil {
+		return func() {}, nil
+	}
+	return e.Acquire(ctx)
+}
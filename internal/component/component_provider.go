@@ -36,6 +36,25 @@ type Provider interface {
 	ListComponents(moduleID string, opts InfoOptions) ([]*Info, error)
 }
 
+// A SnapshotProvider is a Provider which can capture the state of a set of
+// components at once.
+type SnapshotProvider interface {
+	// GetComponentsSnapshot returns information about the components with the
+	// given global IDs, captured while none of the components of their modules
+	// are being evaluated, so that the arguments of a component are consistent
+	// with the exports of the components it was evaluated with.
+	//
+	// GetComponentsSnapshot returns ErrComponentNotFound if a component is not
+	// found.
+	GetComponentsSnapshot(ids []ID, opts InfoOptions) (*Snapshot, error)
+}
+
+// Snapshot is the state of a set of components captured at once.
+type Snapshot struct {
+	Time       time.Time `json:"time"`
+	Components []*Info   `json:"components"`
+}
+
 // ID is a globally unique identifier for a component.
 type ID struct {
 	ModuleID string // Unique ID of the module that the component is running in.
@@ -63,6 +82,11 @@ type InfoOptions struct {
 	GetArguments bool // When true, sets the Arguments field of returned components.
 	GetExports   bool // When true, sets the Exports field of returned components.
 	GetDebugInfo bool // When true, sets the DebugInfo field of returned components.
+
+	GetExportsHistory bool // When true, sets the ExportsHistory field of returned components.
+	GetErrorsHistory  bool // When true, sets the ErrorsHistory field of returned components.
+
+	GetExportReferences bool // When true, sets the ExportReferences field of returned components.
 }
 
 // String returns the "<ModuleID>/<LocalID>" string representation of the id.
@@ -89,6 +113,10 @@ type Info struct {
 	ID    ID     // ID of the component.
 	Label string // Component label. Not set for singleton components.
 
+	// Meta holds the metadata labels of the component from its meta block,
+	// such as the team owning it.
+	Meta map[string]string
+
 	// References and ReferencedBy are the list of IDs in the same module that
 	// this component depends on, or is depended on by, respectively.
 	References, ReferencedBy []string
@@ -96,9 +124,56 @@ type Info struct {
 	ComponentName string // Name of the component.
 	Health        Health // Current component health.
 
+	// NestingDepth is the number of modules or custom components the component
+	// is nested under. Components defined in the root configuration have a
+	// nesting depth of 0.
+	NestingDepth int
+
+	// ModuleSource describes the imported module which defines the component.
+	// ModuleSource is only set for custom components whose declare block was
+	// imported.
+	ModuleSource *ModuleSource
+
 	Arguments Arguments   // Current arguments value of the component.
 	Exports   Exports     // Current exports value of the component.
 	DebugInfo interface{} // Current debug info of the component.
+
+	// ExportsHistory holds past exports values of the component, ordered from
+	// oldest to newest. ExportsHistory is not included in the JSON
+	// representation of Info.
+	ExportsHistory []ExportsRecord
+
+	// ErrorsHistory holds past errors of the component, ordered from oldest to
+	// newest. ErrorsHistory is not included in the JSON representation of Info.
+	ErrorsHistory []ErrorRecord
+
+	// ExportReferences holds the expressions of the configuration which
+	// reference the component. ExportReferences is not included in the JSON
+	// representation of Info.
+	ExportReferences []ExportReference
+}
+
+// ModuleSource describes the imported module which defines a custom
+// component.
+type ModuleSource struct {
+	// Imports holds the IDs of the import blocks the module was imported
+	// through, starting from the import block of the module of the custom
+	// component. Nested imports have more than one entry.
+	Imports []string `json:"imports"`
+
+	// Location is where the module was imported from, such as a URL or a
+	// path. Location is empty for import.string blocks.
+	Location string `json:"location,omitempty"`
+
+	// Version is the version the module was resolved to, such as the commit
+	// of a Git revision, if the source has versions.
+	Version string `json:"version,omitempty"`
+
+	// SHA256 is the digest of the content of the module.
+	SHA256 string `json:"sha256"`
+
+	// Declare is the label of the declare block of the custom component.
+	Declare string `json:"declare"`
 }
 
 // MarshalJSON returns a JSON representation of cd. The format of the
@@ -117,9 +192,12 @@ func (info *Info) MarshalJSON() ([]byte, error) {
 			LocalID          string               `json:"localID"`
 			ModuleID         string               `json:"moduleID"`
 			Label            string               `json:"label,omitempty"`
+			Meta             map[string]string    `json:"meta,omitempty"`
 			References       []string             `json:"referencesTo"`
 			ReferencedBy     []string             `json:"referencedBy"`
 			Health           *componentHealthJSON `json:"health"`
+			NestingDepth     int                  `json:"nestingDepth"`
+			ModuleSource     *ModuleSource        `json:"moduleSource,omitempty"`
 			Original         string               `json:"original"`
 			Arguments        json.RawMessage      `json:"arguments,omitempty"`
 			Exports          json.RawMessage      `json:"exports,omitempty"`
@@ -162,6 +240,7 @@ func (info *Info) MarshalJSON() ([]byte, error) {
 		ModuleID:     info.ID.ModuleID,
 		LocalID:      info.ID.LocalID,
 		Label:        info.Label,
+		Meta:         info.Meta,
 		References:   references,
 		ReferencedBy: referencedBy,
 		Health: &componentHealthJSON{
@@ -169,6 +248,8 @@ func (info *Info) MarshalJSON() ([]byte, error) {
 			Message:     info.Health.Message,
 			UpdatedTime: info.Health.UpdateTime,
 		},
+		NestingDepth:     info.NestingDepth,
+		ModuleSource:     info.ModuleSource,
 		Arguments:        arguments,
 		Exports:          exports,
 		DebugInfo:        debugInfo,
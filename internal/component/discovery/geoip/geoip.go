@@ -0,0 +1,275 @@
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	common_geoip "github.com/grafana/agent/internal/component/common/geoip"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/filedetector"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/common/model"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "discovery.geoip",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the discovery.geoip
+// component.
+type Arguments struct {
+	// Targets contains the input 'targets' passed by a service discovery component.
+	Targets []discovery.Target `river:"targets,attr"`
+
+	// Path and type of the MMDB database.
+	DB     string `river:"db,attr"`
+	DBType string `river:"db_type,attr"`
+
+	// SourceLabel is the label holding the IP address to look up. The port
+	// is ignored if the label holds a <host>:<port> address.
+	SourceLabel string `river:"source_label,attr,optional"`
+
+	CacheSize     int                   `river:"max_cache_size,attr,optional"`
+	Detector      filedetector.Detector `river:"detector,attr,optional"`
+	PollFrequency time.Duration         `river:"poll_frequency,attr,optional"`
+}
+
+// DefaultArguments provides the default arguments for the discovery.geoip
+// component.
+var DefaultArguments = Arguments{
+	SourceLabel:   model.AddressLabel,
+	CacheSize:     10_000,
+	Detector:      filedetector.DetectorFSNotify,
+	PollFrequency: time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.DB == "" {
+		return fmt.Errorf("db must not be empty")
+	}
+	if err := common_geoip.ValidateType(args.DBType); err != nil {
+		return err
+	}
+	if args.SourceLabel == "" {
+		return fmt.Errorf("source_label must not be empty")
+	}
+	if args.CacheSize < 0 {
+		return fmt.Errorf("max_cache_size must not be negative, got %d", args.CacheSize)
+	}
+	if args.PollFrequency <= 0 {
+		return fmt.Errorf("poll_frequency must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the discovery.geoip component.
+type Exports struct {
+	Output []discovery.Target `river:"output,attr"`
+}
+
+// Component implements the discovery.geoip component.
+type Component struct {
+	opts component.Options
+
+	// reloadCh is written to when the database is reloaded, so that the
+	// targets are enriched again.
+	reloadCh chan struct{}
+
+	mut  sync.Mutex
+	args Arguments
+	db   *common_geoip.Database
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new discovery.geoip component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:     o,
+		reloadCh: make(chan struct{}, 1),
+	}
+
+	// Call to Update() to set the output once at the start
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+		if err := c.db.Close(); err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to close GeoIP database", "err", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.reloadCh:
+			c.mut.Lock()
+			c.enrich()
+			c.mut.Unlock()
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.db == nil || databaseChanged(c.args, newArgs) {
+		db, err := common_geoip.Open(common_geoip.Options{
+			Logger:        c.opts.Logger,
+			Path:          newArgs.DB,
+			Detector:      newArgs.Detector,
+			PollFrequency: newArgs.PollFrequency,
+			CacheSize:     newArgs.CacheSize,
+			Decode:        common_geoip.DecodeLabels(newArgs.DBType),
+			OnReload:      c.onReload,
+		})
+		if err != nil {
+			return err
+		}
+		if c.db != nil {
+			if err := c.db.Close(); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to close previous GeoIP database", "err", err)
+			}
+		}
+		c.db = db
+		c.setHealth(component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "opened GeoIP database",
+			UpdateTime: time.Now(),
+		})
+	}
+	c.args = newArgs
+
+	c.enrich()
+	return nil
+}
+
+// databaseChanged returns whether the database needs to be reopened when
+// the arguments change from prev to next.
+func databaseChanged(prev, next Arguments) bool {
+	return prev.DB != next.DB ||
+		prev.DBType != next.DBType ||
+		prev.CacheSize != next.CacheSize ||
+		prev.Detector != next.Detector ||
+		prev.PollFrequency != next.PollFrequency
+}
+
+func (c *Component) onReload(err error) {
+	if err != nil {
+		c.setHealth(component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("failed to reload GeoIP database, using the previous one: %s", err),
+			UpdateTime: time.Now(),
+		})
+		return
+	}
+	c.setHealth(component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    "reloaded GeoIP database",
+		UpdateTime: time.Now(),
+	})
+
+	select {
+	case c.reloadCh <- struct{}{}:
+	default:
+		// A reload is already queued.
+	}
+}
+
+// enrich exports the targets with the labels of their IP address. mut must
+// be held when called.
+func (c *Component) enrich() {
+	targets := make([]discovery.Target, 0, len(c.args.Targets))
+	for _, t := range c.args.Targets {
+		targets = append(targets, c.enrichTarget(t))
+	}
+	c.opts.OnStateChange(Exports{Output: targets})
+}
+
+// enrichTarget returns t with the labels of its IP address. t is returned
+// unchanged if it has no IP address or if it isn't in the database.
+func (c *Component) enrichTarget(t discovery.Target) discovery.Target {
+	ip := parseIP(t[c.args.SourceLabel])
+	if ip == nil {
+		return t
+	}
+
+	res, err := c.db.Lookup(ip)
+	if err != nil {
+		level.Debug(c.opts.Logger).Log("msg", "failed to look up IP address", "ip", ip, "err", err)
+		return t
+	}
+	labels := res.(map[string]string)
+	if len(labels) == 0 {
+		return t
+	}
+
+	enriched := make(discovery.Target, len(t)+len(labels))
+	for k, v := range t {
+		enriched[k] = v
+	}
+	for k, v := range labels {
+		enriched[k] = v
+	}
+	return enriched
+}
+
+// parseIP parses value as an IP address or as a <host>:<port> address whose
+// host is an IP address.
+func parseIP(value string) net.IP {
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return net.ParseIP(value)
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+func (c *Component) setHealth(h component.Health) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = h
+}
@@ -0,0 +1,73 @@
+package geoip_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/discovery/geoip"
+	"github.com/grafana/agent/internal/flow/componenttest"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	var args geoip.Arguments
+	err := river.Unmarshal([]byte("targets = []\ndb = \"geoip.mmdb\"\ndb_type = \"isp\""), &args)
+	require.EqualError(t, err, `db_type must be "city", "country" or "asn", got "isp"`)
+
+	var valid geoip.Arguments
+	require.NoError(t, river.Unmarshal([]byte("targets = []\ndb = \"geoip.mmdb\"\ndb_type = \"asn\""), &valid))
+	require.Equal(t, "__address__", valid.SourceLabel)
+}
+
+func TestEnrich(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geoip.mmdb")
+	copyFile(t, "../../common/geoip/testdata/geoip_maxmind_country.mmdb", path)
+
+	var args geoip.Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+targets = [
+	{ "__address__" = "192.0.2.1:9100", "job" = "in database" },
+	{ "__address__" = "198.51.100.1:9100", "job" = "not in database" },
+	{ "__address__" = "example.com:9100", "job" = "not an ip" },
+]
+db             = "`+filepath.ToSlash(path)+`"
+db_type        = "country"
+detector       = "poll"
+poll_frequency = "10ms"
+`), &args))
+
+	tc, err := componenttest.NewControllerFromID(nil, "discovery.geoip")
+	require.NoError(t, err)
+	go func() {
+		err = tc.Run(componenttest.TestContext(t), args)
+		require.NoError(t, err)
+	}()
+
+	require.NoError(t, tc.WaitExports(time.Second))
+	output := tc.Exports().(geoip.Exports).Output
+	require.Len(t, output, 3)
+	require.NotEmpty(t, output[0]["geoip_country_code"])
+	require.NotEmpty(t, output[0]["geoip_continent_code"])
+	require.Equal(t, discovery.Target{"__address__": "198.51.100.1:9100", "job": "not in database"}, output[1])
+	require.Equal(t, discovery.Target{"__address__": "example.com:9100", "job": "not an ip"}, output[2])
+
+	// Replacing the database enriches the targets again. The ASN test
+	// database has no country records.
+	copyFile(t, "../../common/geoip/testdata/geoip_maxmind_asn.mmdb", path)
+	require.NoError(t, tc.WaitExports(5*time.Second))
+	output = tc.Exports().(geoip.Exports).Output
+	require.Equal(t, discovery.Target{"__address__": "192.0.2.1:9100", "job": "in database"}, output[0])
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	bb, err := os.ReadFile(from)
+	require.NoError(t, err)
+	tmp := to + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, bb, 0644))
+	require.NoError(t, os.Rename(tmp, to))
+}
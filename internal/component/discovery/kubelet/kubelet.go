@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/agent/internal/component/common/config"
 	"github.com/grafana/agent/internal/component/discovery"
 	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/egress"
 	commonConfig "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/discovery/refresh"
@@ -121,7 +122,7 @@ type Discovery struct {
 }
 
 func NewKubeletDiscovery(args Arguments) (*Discovery, error) {
-	transport, err := commonConfig.NewRoundTripperFromConfig(*args.HTTPClientConfig.Convert(), "kubelet_sd")
+	transport, err := commonConfig.NewRoundTripperFromConfig(*args.HTTPClientConfig.Convert(), "kubelet_sd", egress.HTTPClientOption())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,86 @@
+package discovery
+
+import (
+	"reflect"
+
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+// RelabelPreview describes the result of applying relabeling rules to a
+// list of targets.
+type RelabelPreview struct {
+	Total   int `json:"total"`   // Number of targets relabeled.
+	Kept    int `json:"kept"`    // Number of targets kept by the rules.
+	Dropped int `json:"dropped"` // Number of targets dropped by the rules.
+
+	// Unique is the number of distinct label sets of the targets kept. Targets
+	// with the same label set after relabeling are duplicates of each other.
+	Unique int `json:"unique"`
+
+	// Samples holds the first targets relabeled, in the order of the input.
+	Samples []RelabelSample `json:"samples"`
+}
+
+// RelabelSample is a target before and after relabeling.
+type RelabelSample struct {
+	Before Target `json:"before"`
+	After  Target `json:"after,omitempty"` // After is nil for dropped targets.
+	Kept   bool   `json:"kept"`
+}
+
+// PreviewRelabel applies rules to targets, returning the number of targets
+// kept and dropped along with up to maxSamples relabeled targets.
+func PreviewRelabel(targets []Target, rules []*flow_relabel.Config, maxSamples int) RelabelPreview {
+	var (
+		rcs     = flow_relabel.ComponentToPromRelabelConfigs(rules)
+		unique  = make(map[uint64]struct{})
+		preview = RelabelPreview{
+			Total:   len(targets),
+			Samples: make([]RelabelSample, 0, min(maxSamples, len(targets))),
+		}
+	)
+	for _, t := range targets {
+		lset, keep := relabel.Process(labels.FromMap(t), rcs...)
+
+		sample := RelabelSample{Before: t, Kept: keep}
+		if keep {
+			preview.Kept++
+			unique[lset.Hash()] = struct{}{}
+			sample.After = lset.Map()
+		} else {
+			preview.Dropped++
+		}
+		if len(preview.Samples) < maxSamples {
+			preview.Samples = append(preview.Samples, sample)
+		}
+	}
+	preview.Unique = len(unique)
+	return preview
+}
+
+// ExportedTargets returns the targets exported by a component, such as the
+// targets of discovery components or the output of discovery.relabel. The
+// targets are the first field of exports holding a list of targets.
+// ExportedTargets returns false if exports doesn't hold targets.
+func ExportedTargets(exports any) ([]Target, bool) {
+	v := reflect.ValueOf(exports)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	targetsType := reflect.TypeOf([]Target(nil))
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() && v.Field(i).Type() == targetsType {
+			return v.Field(i).Interface().([]Target), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,71 @@
+package discovery
+
+import (
+	"testing"
+
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewRelabel(t *testing.T) {
+	var rules struct {
+		Rules []*flow_relabel.Config `river:"rule,block,optional"`
+	}
+	require.NoError(t, river.Unmarshal([]byte(`
+		rule {
+			source_labels = ["env"]
+			regex         = "dev"
+			action        = "drop"
+		}
+
+		rule {
+			action = "labeldrop"
+			regex  = "pod"
+		}
+	`), &rules))
+
+	targets := []Target{
+		{"__address__": "a:80", "env": "prod", "pod": "a-1"},
+		{"__address__": "a:80", "env": "prod", "pod": "a-2"},
+		{"__address__": "b:80", "env": "dev", "pod": "b-1"},
+		{"__address__": "c:80", "env": "prod", "pod": "c-1"},
+	}
+
+	preview := PreviewRelabel(targets, rules.Rules, 3)
+	require.Equal(t, RelabelPreview{
+		Total:   4,
+		Kept:    3,
+		Dropped: 1,
+		Unique:  2,
+		Samples: []RelabelSample{
+			{Before: targets[0], After: Target{"__address__": "a:80", "env": "prod"}, Kept: true},
+			{Before: targets[1], After: Target{"__address__": "a:80", "env": "prod"}, Kept: true},
+			{Before: targets[2], Kept: false},
+		},
+	}, preview)
+
+	// The input targets aren't modified.
+	require.Equal(t, Target{"__address__": "a:80", "env": "prod", "pod": "a-1"}, targets[0])
+}
+
+func TestExportedTargets(t *testing.T) {
+	targets := []Target{{"__address__": "a:80"}}
+
+	actual, ok := ExportedTargets(Exports{Targets: targets})
+	require.True(t, ok)
+	require.Equal(t, targets, actual)
+
+	// Targets can be held by any field, such as the output of discovery.relabel.
+	actual, ok = ExportedTargets(&struct {
+		Output []Target
+		Other  string
+	}{Output: targets})
+	require.True(t, ok)
+	require.Equal(t, targets, actual)
+
+	_, ok = ExportedTargets(struct{ Content string }{})
+	require.False(t, ok)
+	_, ok = ExportedTargets(nil)
+	require.False(t, ok)
+}
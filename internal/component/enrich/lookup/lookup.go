@@ -0,0 +1,303 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/river/rivertypes"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "enrich.lookup",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the enrich.lookup
+// component.
+type Arguments struct {
+	// Content of the lookup table, usually the export of a local.file or
+	// remote.http component.
+	Content   rivertypes.OptionalSecret `river:"content,attr"`
+	Format    string                    `river:"format,attr,optional"`
+	KeyColumn string                    `river:"key_column,attr,optional"`
+
+	// SourceLabel is the label whose value is looked up in the table.
+	SourceLabel string `river:"source_label,attr"`
+	Match       string `river:"match,attr,optional"`
+
+	Targets          []discovery.Target   `river:"targets,attr,optional"`
+	MetricsForwardTo []storage.Appendable `river:"metrics_forward_to,attr,optional"`
+	LogsForwardTo    []loki.LogsReceiver  `river:"logs_forward_to,attr,optional"`
+}
+
+// DefaultArguments provides the default arguments for the enrich.lookup
+// component.
+var DefaultArguments = Arguments{
+	Format: FormatCSV,
+	Match:  MatchExact,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Format != FormatCSV && args.Format != FormatJSON {
+		return fmt.Errorf("format must be %q or %q, got %q", FormatCSV, FormatJSON, args.Format)
+	}
+	if args.KeyColumn != "" && args.Format != FormatCSV {
+		return fmt.Errorf("key_column can only be set for the %q format", FormatCSV)
+	}
+	if args.Match != MatchExact && args.Match != MatchPrefix {
+		return fmt.Errorf("match must be %q or %q, got %q", MatchExact, MatchPrefix, args.Match)
+	}
+	if !model.LabelName(args.SourceLabel).IsValid() {
+		return fmt.Errorf("source_label %q is not a valid label name", args.SourceLabel)
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the enrich.lookup component.
+type Exports struct {
+	Output          []discovery.Target `river:"output,attr"`
+	MetricsReceiver storage.Appendable `river:"metrics_receiver,attr"`
+	LogsReceiver    loki.LogsReceiver  `river:"logs_receiver,attr"`
+}
+
+// Component implements the enrich.lookup component.
+type Component struct {
+	opts         component.Options
+	tableEntries prometheus_client.Gauge
+
+	metricsReceiver *prometheus.Interceptor
+	metricsFanout   *prometheus.Fanout
+	logsReceiver    loki.LogsReceiver
+	exited          atomic.Bool
+
+	mut        sync.RWMutex
+	args       Arguments
+	table      *table
+	logsFanout []loki.LogsReceiver
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new enrich.lookup component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts: o,
+		tableEntries: prometheus_client.NewGauge(prometheus_client.GaugeOpts{
+			Name: "agent_enrich_lookup_table_entries",
+			Help: "Number of entries in the lookup table",
+		}),
+		logsReceiver: loki.NewLogsReceiver(),
+	}
+	if err := o.Registerer.Register(c.tableEntries); err != nil {
+		return nil, err
+	}
+
+	c.metricsFanout = prometheus.NewFanout(args.MetricsForwardTo, o.ID, o.Registerer, ls)
+	c.metricsReceiver = prometheus.NewInterceptor(
+		c.metricsFanout,
+		ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if newLbls, ok := c.enrichLabels(l); ok {
+				return next.Append(0, newLbls, t, v)
+			}
+			return next.Append(ref, l, t, v)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if newLbls, ok := c.enrichLabels(l); ok {
+				return next.AppendExemplar(0, newLbls, e)
+			}
+			return next.AppendExemplar(ref, l, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if newLbls, ok := c.enrichLabels(l); ok {
+				return next.UpdateMetadata(0, newLbls, m)
+			}
+			return next.UpdateMetadata(ref, l, m)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if c.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+			if newLbls, ok := c.enrichLabels(l); ok {
+				return next.AppendHistogram(0, newLbls, t, h, fh)
+			}
+			return next.AppendHistogram(ref, l, t, h, fh)
+		}),
+	)
+
+	// Call to Update() to load the table and set the output once at the start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.exited.Store(true)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.logsReceiver.Chan():
+			c.mut.RLock()
+			entry.Labels = c.enrichLogLabels(entry.Labels)
+			fanout := c.logsFanout
+			c.mut.RUnlock()
+
+			for _, f := range fanout {
+				select {
+				case <-ctx.Done():
+					return nil
+				case f.Chan() <- entry:
+				}
+			}
+		}
+	}
+}
+
+// Update implements component.Component. If the table can't be parsed,
+// the previous table is kept.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.table == nil || tableChanged(c.args, newArgs) {
+		t, err := parseTable(newArgs.Content.Value, newArgs.Format, newArgs.KeyColumn, newArgs.Match)
+		if err != nil {
+			return err
+		}
+		c.table = t
+		c.tableEntries.Set(float64(t.Len()))
+	}
+	c.args = newArgs
+	c.logsFanout = newArgs.LogsForwardTo
+	c.metricsFanout.UpdateChildren(newArgs.MetricsForwardTo)
+
+	targets := make([]discovery.Target, 0, len(newArgs.Targets))
+	for _, t := range newArgs.Targets {
+		targets = append(targets, c.enrichTarget(t))
+	}
+	c.opts.OnStateChange(Exports{
+		Output:          targets,
+		MetricsReceiver: c.metricsReceiver,
+		LogsReceiver:    c.logsReceiver,
+	})
+	return nil
+}
+
+// tableChanged returns whether the table needs to be parsed again when the
+// arguments change from prev to next.
+func tableChanged(prev, next Arguments) bool {
+	return prev.Content != next.Content ||
+		prev.Format != next.Format ||
+		prev.KeyColumn != next.KeyColumn ||
+		prev.Match != next.Match
+}
+
+// enrichTarget returns t with the labels of its source label value. mut must
+// be held when called.
+func (c *Component) enrichTarget(t discovery.Target) discovery.Target {
+	found, ok := c.lookup(t[c.args.SourceLabel])
+	if !ok {
+		return t
+	}
+	enriched := make(discovery.Target, len(t)+len(found))
+	for k, v := range t {
+		enriched[k] = v
+	}
+	for k, v := range found {
+		enriched[k] = v
+	}
+	return enriched
+}
+
+// enrichLogLabels returns a copy of lbls with the labels of its source label
+// value. mut must be held when called.
+func (c *Component) enrichLogLabels(lbls model.LabelSet) model.LabelSet {
+	found, ok := c.lookup(string(lbls[model.LabelName(c.args.SourceLabel)]))
+	if !ok {
+		return lbls
+	}
+	enriched := lbls.Clone()
+	for k, v := range found {
+		enriched[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return enriched
+}
+
+// enrichLabels returns l with the labels of its source label value, and
+// whether any labels were added.
+func (c *Component) enrichLabels(l labels.Labels) (labels.Labels, bool) {
+	c.mut.RLock()
+	found, ok := c.lookup(l.Get(c.args.SourceLabel))
+	c.mut.RUnlock()
+	if !ok {
+		return l, false
+	}
+
+	lb := labels.NewBuilder(l)
+	for k, v := range found {
+		lb.Set(k, v)
+	}
+	return lb.Labels(), true
+}
+
+// lookup returns the labels of value, and whether there are any. mut must
+// be held when called.
+func (c *Component) lookup(value string) (map[string]string, bool) {
+	if value == "" {
+		return nil, false
+	}
+	found, ok := c.table.Lookup(value)
+	return found, ok && len(found) > 0
+}
@@ -0,0 +1,155 @@
+package lookup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/grafana/river"
+	"github.com/grafana/river/rivertypes"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+const teams = `service,team,tier
+checkout,payments,1
+search,search,
+`
+
+func newTestComponent(t *testing.T, args Arguments) (*Component, *Exports) {
+	t.Helper()
+
+	var exports Exports
+	c, err := New(component.Options{
+		ID:            "enrich.lookup.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prom.NewRegistry(),
+		OnStateChange: func(e component.Exports) { exports = e.(Exports) },
+		GetServiceData: func(name string) (interface{}, error) {
+			return labelstore.New(nil, prom.DefaultRegisterer), nil
+		},
+	}, args)
+	require.NoError(t, err)
+	return c, &exports
+}
+
+func TestArguments_Validate(t *testing.T) {
+	cfg := `
+		content      = "service,team"
+		source_label = "service"
+		format       = "json"
+		key_column   = "service"
+	`
+	var args Arguments
+	err := river.Unmarshal([]byte(cfg), &args)
+	require.EqualError(t, err, `key_column can only be set for the "csv" format`)
+
+	cfg = `
+		content      = "service,team"
+		source_label = "service"
+		match        = "regex"
+	`
+	err = river.Unmarshal([]byte(cfg), &args)
+	require.EqualError(t, err, `match must be "exact" or "prefix", got "regex"`)
+}
+
+func TestTargets(t *testing.T) {
+	args := DefaultArguments
+	args.Content = rivertypes.OptionalSecret{Value: teams}
+	args.SourceLabel = "service"
+	args.Targets = []discovery.Target{
+		{"__address__": "checkout:8080", "service": "checkout", "team": "unknown"},
+		{"__address__": "search:8080", "service": "search"},
+		{"__address__": "cart:8080", "service": "cart"},
+	}
+	c, exports := newTestComponent(t, args)
+
+	require.Equal(t, []discovery.Target{
+		{"__address__": "checkout:8080", "service": "checkout", "team": "payments", "tier": "1"},
+		{"__address__": "search:8080", "service": "search", "team": "search"},
+		{"__address__": "cart:8080", "service": "cart"},
+	}, exports.Output)
+
+	// The targets are enriched again when the table changes.
+	args.Content = rivertypes.OptionalSecret{Value: "service,team\ncart,orders\n"}
+	require.NoError(t, c.Update(args))
+	require.Equal(t, []discovery.Target{
+		{"__address__": "checkout:8080", "service": "checkout", "team": "unknown"},
+		{"__address__": "search:8080", "service": "search"},
+		{"__address__": "cart:8080", "service": "cart", "team": "orders"},
+	}, exports.Output)
+
+	// The previous table is kept if the new one can't be parsed.
+	bad := args
+	bad.Content = rivertypes.OptionalSecret{Value: "service,not-a-label\n"}
+	require.Error(t, c.Update(bad))
+	found, ok := c.table.Lookup("cart")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"team": "orders"}, found)
+}
+
+func TestMetrics(t *testing.T) {
+	appended := make(chan labels.Labels, 10)
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	next := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		appended <- l
+		return ref, nil
+	}))
+
+	args := DefaultArguments
+	args.Content = rivertypes.OptionalSecret{Value: teams}
+	args.SourceLabel = "service"
+	args.MetricsForwardTo = []storage.Appendable{next}
+	_, exports := newTestComponent(t, args)
+
+	app := exports.MetricsReceiver.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up", "service", "checkout"), time.Now().UnixMilli(), 1)
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "service", "cart"), time.Now().UnixMilli(), 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, labels.FromStrings("__name__", "up", "service", "checkout", "team", "payments", "tier", "1"), <-appended)
+	require.Equal(t, labels.FromStrings("__name__", "up", "service", "cart"), <-appended)
+}
+
+func TestLogs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := loki.NewLogsReceiver()
+	args := DefaultArguments
+	args.Content = rivertypes.OptionalSecret{Value: `{"checkout": {"team": "payments"}}`}
+	args.Format = FormatJSON
+	args.SourceLabel = "service"
+	args.LogsForwardTo = []loki.LogsReceiver{out}
+	c, exports := newTestComponent(t, args)
+	go c.Run(ctx)
+
+	original := model.LabelSet{"service": "checkout"}
+	exports.LogsReceiver.Chan() <- loki.Entry{
+		Labels: original,
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "order placed"},
+	}
+
+	select {
+	case entry := <-out.Chan():
+		require.Equal(t, model.LabelSet{"service": "checkout", "team": "payments"}, entry.Labels)
+		require.Equal(t, "order placed", entry.Line)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "log entry was not forwarded")
+	}
+
+	// The labels of the received entry aren't modified.
+	require.Equal(t, model.LabelSet{"service": "checkout"}, original)
+}
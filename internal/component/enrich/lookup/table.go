@@ -0,0 +1,141 @@
+package lookup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// Formats of lookup tables.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Match modes of lookup keys.
+const (
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+)
+
+// table maps keys to the labels to add to the entries whose source label
+// matches the key.
+type table struct {
+	entries map[string]map[string]string
+	match   string
+}
+
+// parseTable parses content in the given format. For CSV tables, keyColumn
+// is the name of the column holding the keys, and defaults to the first
+// column.
+func parseTable(content, format, keyColumn, match string) (*table, error) {
+	var (
+		entries map[string]map[string]string
+		err     error
+	)
+	switch format {
+	case FormatCSV:
+		entries, err = parseCSV(content, keyColumn)
+	case FormatJSON:
+		entries, err = parseJSON(content)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &table{entries: entries, match: match}, nil
+}
+
+// parseCSV parses a CSV table whose first row is a header naming the labels
+// of each column. Empty cells don't set their label.
+func parseCSV(content, keyColumn string) (map[string]map[string]string, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV table has no header row")
+	} else if err != nil {
+		return nil, fmt.Errorf("parsing CSV table: %w", err)
+	}
+
+	keyIdx := 0
+	if keyColumn != "" {
+		keyIdx = -1
+		for i, name := range header {
+			if name == keyColumn {
+				keyIdx = i
+				break
+			}
+		}
+		if keyIdx < 0 {
+			return nil, fmt.Errorf("key column %q not found in CSV header", keyColumn)
+		}
+	}
+	for i, name := range header {
+		if i != keyIdx && !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("CSV column %q is not a valid label name", name)
+		}
+	}
+
+	entries := make(map[string]map[string]string)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing CSV table: %w", err)
+		}
+
+		labels := make(map[string]string, len(record)-1)
+		for i, value := range record {
+			if i != keyIdx && value != "" {
+				labels[header[i]] = value
+			}
+		}
+		entries[record[keyIdx]] = labels
+	}
+	return entries, nil
+}
+
+// parseJSON parses a JSON table holding an object which maps keys to objects
+// of labels.
+func parseJSON(content string) (map[string]map[string]string, error) {
+	var entries map[string]map[string]string
+	if err := json.Unmarshal([]byte(content), &entries); err != nil {
+		return nil, fmt.Errorf("parsing JSON table: %w", err)
+	}
+	for key, labels := range entries {
+		for name := range labels {
+			if !model.LabelName(name).IsValid() {
+				return nil, fmt.Errorf("label %q of key %q is not a valid label name", name, key)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Lookup returns the labels of value. In prefix mode, the entry with the
+// longest key which is a prefix of value is used.
+func (t *table) Lookup(value string) (map[string]string, bool) {
+	if t.match != MatchPrefix {
+		labels, ok := t.entries[value]
+		return labels, ok
+	}
+	for i := len(value); i > 0; i-- {
+		if labels, ok := t.entries[value[:i]]; ok {
+			return labels, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the number of entries of t.
+func (t *table) Len() int {
+	return len(t.entries)
+}
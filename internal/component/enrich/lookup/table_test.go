@@ -0,0 +1,91 @@
+package lookup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable_CSV(t *testing.T) {
+	content := `team,subnet,owner
+payments,10.1.,alice
+search,10.2.,
+`
+	tbl, err := parseTable(content, FormatCSV, "subnet", MatchExact)
+	require.NoError(t, err)
+	require.Equal(t, 2, tbl.Len())
+
+	labels, ok := tbl.Lookup("10.1.")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"team": "payments", "owner": "alice"}, labels)
+
+	// Empty cells don't set their label.
+	labels, ok = tbl.Lookup("10.2.")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"team": "search"}, labels)
+
+	_, ok = tbl.Lookup("10.1.0.4")
+	require.False(t, ok)
+
+	// The key column defaults to the first column.
+	tbl, err = parseTable(content, FormatCSV, "", MatchExact)
+	require.NoError(t, err)
+	labels, ok = tbl.Lookup("payments")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"subnet": "10.1.", "owner": "alice"}, labels)
+}
+
+func TestParseTable_JSON(t *testing.T) {
+	content := `{"checkout": {"team": "payments"}, "query": {"team": "search", "tier": "1"}}`
+	tbl, err := parseTable(content, FormatJSON, "", MatchExact)
+	require.NoError(t, err)
+	require.Equal(t, 2, tbl.Len())
+
+	labels, ok := tbl.Lookup("query")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"team": "search", "tier": "1"}, labels)
+}
+
+func TestParseTable_Errors(t *testing.T) {
+	tt := []struct {
+		name      string
+		content   string
+		format    string
+		keyColumn string
+		err       string
+	}{
+		{"empty CSV", "", FormatCSV, "", "CSV table has no header row"},
+		{"missing key column", "a,b\n1,2\n", FormatCSV, "c", `key column "c" not found in CSV header`},
+		{"invalid CSV label", "key,not-a-label\n1,2\n", FormatCSV, "", `CSV column "not-a-label" is not a valid label name`},
+		{"wrong number of fields", "a,b\n1,2,3\n", FormatCSV, "", "parsing CSV table: record on line 2: wrong number of fields"},
+		{"invalid JSON", `["a"]`, FormatJSON, "", "parsing JSON table: json: cannot unmarshal array into Go value of type map[string]map[string]string"},
+		{"invalid JSON label", `{"a": {"not-a-label": "1"}}`, FormatJSON, "", `label "not-a-label" of key "a" is not a valid label name`},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseTable(tc.content, tc.format, tc.keyColumn, MatchExact)
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestTable_LookupPrefix(t *testing.T) {
+	content := `prefix,zone
+10.,internal
+10.1.,payments
+`
+	tbl, err := parseTable(content, FormatCSV, "", MatchPrefix)
+	require.NoError(t, err)
+
+	// The longest matching prefix is used.
+	labels, ok := tbl.Lookup("10.1.0.4")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"zone": "payments"}, labels)
+
+	labels, ok = tbl.Lookup("10.2.0.4")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"zone": "internal"}, labels)
+
+	_, ok = tbl.Lookup("192.168.0.1")
+	require.False(t, ok)
+}
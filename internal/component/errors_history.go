@@ -0,0 +1,17 @@
+package component
+
+import "time"
+
+// ErrorRecord is a past error of a component, such as a failed evaluation or
+// a panic.
+type ErrorRecord struct {
+	// Time the error occurred.
+	Time time.Time `json:"time"`
+
+	// Message describes the error.
+	Message string `json:"message"`
+
+	// Stack holds the stack trace of the goroutine which panicked. Stack is
+	// empty for errors which aren't panics.
+	Stack string `json:"stack,omitempty"`
+}
@@ -0,0 +1,27 @@
+package component
+
+// ExportReference is an expression in the configuration which references the
+// exports of a component.
+type ExportReference struct {
+	// Component is the ID of the component or configuration block making the
+	// reference, such as "prometheus.scrape.default".
+	Component string `json:"component"`
+
+	// Attribute is the path of the attribute holding the reference, relative
+	// to the block making the reference, such as "endpoint.url".
+	Attribute string `json:"attribute"`
+
+	// Expression is the referencing expression, such as
+	// "prometheus.remote_write.default.receiver".
+	Expression string `json:"expression"`
+
+	// Field is the export field being referenced, such as "receiver". Field is
+	// empty for references to the component as a whole.
+	Field string `json:"field,omitempty"`
+
+	// File, Line, and Column hold the position of the expression in the
+	// configuration.
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
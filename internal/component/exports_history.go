@@ -0,0 +1,137 @@
+package component
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ExportsRecord is a past value of the exports of a component.
+type ExportsRecord struct {
+	// Time the exports were set.
+	Time time.Time `json:"time"`
+
+	// Exports holds the River JSON representation of the exports. Exports is
+	// empty if Truncated is set.
+	Exports json.RawMessage `json:"exports,omitempty"`
+
+	// Truncated is set when the exports were too large to be retained.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ExportsDiff describes what changed between two consecutive exports of a
+// component.
+type ExportsDiff struct {
+	// From and To are the times of the older and newer exports respectively.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	// Changes lists the exported fields which changed between the two exports.
+	// Changes is empty if either of the exports was truncated.
+	Changes []ExportsChange `json:"changes"`
+
+	// Truncated is set when either of the compared exports was truncated, in
+	// which case the changes are unknown.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ExportsChange is a change to a single exported field. Old is empty for
+// added fields, and New is empty for removed fields.
+type ExportsChange struct {
+	Name string          `json:"name"`
+	Old  json.RawMessage `json:"old,omitempty"`
+	New  json.RawMessage `json:"new,omitempty"`
+}
+
+// DiffExportsHistory compares consecutive records of history, which must be
+// ordered from oldest to newest, and returns the differences between them.
+func DiffExportsHistory(history []ExportsRecord) ([]ExportsDiff, error) {
+	diffs := make([]ExportsDiff, 0, len(history))
+	for i := 1; i < len(history); i++ {
+		prev, next := history[i-1], history[i]
+
+		diff := ExportsDiff{
+			From:    prev.Time,
+			To:      next.Time,
+			Changes: []ExportsChange{},
+		}
+		if prev.Truncated || next.Truncated {
+			diff.Truncated = true
+			diffs = append(diffs, diff)
+			continue
+		}
+
+		changes, err := diffExports(prev.Exports, next.Exports)
+		if err != nil {
+			return nil, err
+		}
+		diff.Changes = changes
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// exportsStatement is the subset of a River JSON statement used for diffing.
+type exportsStatement struct {
+	Name  string `json:"name"`
+	Label string `json:"label,omitempty"`
+}
+
+func diffExports(prev, next json.RawMessage) ([]ExportsChange, error) {
+	prevFields, prevOrder, err := decodeExportsFields(prev)
+	if err != nil {
+		return nil, err
+	}
+	nextFields, nextOrder, err := decodeExportsFields(next)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []ExportsChange{}
+	for _, name := range prevOrder {
+		newValue, ok := nextFields[name]
+		switch {
+		case !ok:
+			changes = append(changes, ExportsChange{Name: name, Old: prevFields[name]})
+		case !bytes.Equal(prevFields[name], newValue):
+			changes = append(changes, ExportsChange{Name: name, Old: prevFields[name], New: newValue})
+		}
+	}
+	for _, name := range nextOrder {
+		if _, ok := prevFields[name]; !ok {
+			changes = append(changes, ExportsChange{Name: name, New: nextFields[name]})
+		}
+	}
+	return changes, nil
+}
+
+// decodeExportsFields splits a River JSON body into its statements, keyed by
+// statement name and label.
+func decodeExportsFields(body json.RawMessage) (map[string]json.RawMessage, []string, error) {
+	if len(body) == 0 {
+		return map[string]json.RawMessage{}, nil, nil
+	}
+
+	var stmts []json.RawMessage
+	if err := json.Unmarshal(body, &stmts); err != nil {
+		return nil, nil, fmt.Errorf("decoding exports: %w", err)
+	}
+
+	fields := make(map[string]json.RawMessage, len(stmts))
+	order := make([]string, 0, len(stmts))
+	for _, raw := range stmts {
+		var stmt exportsStatement
+		if err := json.Unmarshal(raw, &stmt); err != nil {
+			return nil, nil, fmt.Errorf("decoding exports: %w", err)
+		}
+
+		name := stmt.Name
+		if stmt.Label != "" {
+			name = fmt.Sprintf("%s %q", stmt.Name, stmt.Label)
+		}
+		fields[name] = raw
+		order = append(order, name)
+	}
+	return fields, order, nil
+}
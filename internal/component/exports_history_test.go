@@ -0,0 +1,54 @@
+package component_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffExportsHistory(t *testing.T) {
+	var (
+		t1 = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+		t2 = t1.Add(time.Minute)
+		t3 = t2.Add(time.Minute)
+		t4 = t3.Add(time.Minute)
+	)
+
+	history := []component.ExportsRecord{
+		{Time: t1, Exports: json.RawMessage(`[
+			{"name":"a","type":"attr","value":{"type":"number","value":1}},
+			{"name":"b","type":"attr","value":{"type":"string","value":"x"}}
+		]`)},
+		{Time: t2, Exports: json.RawMessage(`[
+			{"name":"a","type":"attr","value":{"type":"number","value":2}},
+			{"name":"c","type":"attr","value":{"type":"bool","value":true}}
+		]`)},
+		{Time: t3, Truncated: true},
+		{Time: t4, Exports: json.RawMessage(`[]`)},
+	}
+
+	diffs, err := component.DiffExportsHistory(history)
+	require.NoError(t, err)
+	require.Len(t, diffs, 3)
+
+	require.Equal(t, t1, diffs[0].From)
+	require.Equal(t, t2, diffs[0].To)
+	require.False(t, diffs[0].Truncated)
+
+	var names []string
+	for _, change := range diffs[0].Changes {
+		names = append(names, change.Name)
+	}
+	require.Equal(t, []string{"a", "b", "c"}, names)
+	require.NotEmpty(t, diffs[0].Changes[0].Old)
+	require.NotEmpty(t, diffs[0].Changes[0].New)
+	require.Empty(t, diffs[0].Changes[1].New, "removed fields have no new value")
+	require.Empty(t, diffs[0].Changes[2].Old, "added fields have no old value")
+
+	require.True(t, diffs[1].Truncated)
+	require.True(t, diffs[2].Truncated)
+	require.Empty(t, diffs[2].Changes)
+}
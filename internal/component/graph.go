@@ -0,0 +1,68 @@
+package component
+
+// A GraphProvider is a system which exposes the dependency graph of the
+// blocks of its modules.
+type GraphProvider interface {
+	// GetGraph returns the dependency graph of the blocks of the given module.
+	// If recursive is true, the graphs of the modules created by the
+	// components of the module are included, at any depth.
+	//
+	// Returns ErrModuleNotFound if the provided moduleID doesn't exist.
+	GetGraph(moduleID string, recursive bool) (*Graph, error)
+}
+
+// Graph is the dependency graph of the blocks of one or more modules.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// Kinds of nodes of a Graph.
+const (
+	GraphNodeComponent       = "component"        // A builtin component.
+	GraphNodeCustomComponent = "custom_component" // An instance of a declare block.
+	GraphNodeDeclare         = "declare"          // A declare block.
+	GraphNodeImport          = "import"           // An import block.
+	GraphNodeService         = "service"          // A service.
+	GraphNodeConfig          = "config"           // Any other configuration block.
+)
+
+// GraphNode is a block of a module.
+type GraphNode struct {
+	// ID is the global ID of the block, such as "prometheus.scrape.default"
+	// or "custom.default/prometheus.scrape.default" for a block of a module.
+	ID       string `json:"id"`
+	ModuleID string `json:"moduleID"`
+	LocalID  string `json:"localID"`
+
+	Kind  string `json:"kind"`
+	Name  string `json:"name"` // Name of the block, such as "prometheus.scrape".
+	Label string `json:"label,omitempty"`
+
+	// Definition is the global ID of the declare or import block defining a
+	// custom component. Definition is empty for custom components defined by
+	// a declare block of a parent module, and for other kinds of nodes.
+	Definition string `json:"definition,omitempty"`
+
+	// CreatedModuleIDs are the IDs of the modules created by a component.
+	CreatedModuleIDs []string `json:"createdModuleIDs,omitempty"`
+}
+
+// Kinds of edges of a Graph.
+const (
+	// GraphEdgeReference is an edge from a block to a block it references or
+	// depends on.
+	GraphEdgeReference = "reference"
+
+	// GraphEdgeDefinition is an edge from a custom component or a declare
+	// block to a declare or import block defining the custom components it
+	// uses.
+	GraphEdgeDefinition = "definition"
+)
+
+// GraphEdge is a dependency between two blocks of the same module.
+type GraphEdge struct {
+	From string `json:"from"` // Global ID of the dependant block.
+	To   string `json:"to"`   // Global ID of the dependency.
+	Kind string `json:"kind"`
+}
@@ -0,0 +1,263 @@
+// Package assert implements the health.assert component.
+package assert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "health.assert",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the health.assert
+// component.
+type Arguments struct {
+	Condition   bool          `river:"condition,attr"`
+	Message     string        `river:"message,attr,optional"`
+	Interval    time.Duration `river:"interval,attr,optional"`
+	For         time.Duration `river:"for,attr,optional"`
+	LogFailures bool          `river:"log_failures,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval:    15 * time.Second,
+	LogFailures: true,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.For < 0 {
+		return fmt.Errorf("for must not be negative")
+	}
+	return nil
+}
+
+// Exports holds the values exported by the health.assert component.
+type Exports struct {
+	Passing bool `river:"passing,attr"`
+}
+
+// Component implements the health.assert component.
+type Component struct {
+	opts    component.Options
+	metrics *metrics
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+
+	mut          sync.Mutex
+	args         Arguments
+	failingSince time.Time // Zero while the condition is true.
+	passing      bool
+	checked      bool // Whether the assertion was checked at least once.
+
+	// updated is written to whenever args updates.
+	updated chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new health.assert component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	m, err := newMetrics(opts.Registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts:    opts,
+		metrics: m,
+		now:     time.Now,
+		updated: make(chan struct{}, 1),
+
+		health: component.Health{
+			Health:     component.HealthTypeUnknown,
+			Message:    "component started",
+			UpdateTime: time.Now(),
+		},
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		c.mut.Lock()
+		interval := c.args.Interval
+		c.mut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+			c.check()
+		case <-c.updated:
+			// no-op; force the interval to be reread.
+		}
+	}
+}
+
+// Update implements component.Component. The assertion is checked
+// immediately every time the condition is reevaluated.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	c.args = args.(Arguments)
+	c.mut.Unlock()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+
+	c.check()
+	return nil
+}
+
+// check checks the assertion, and updates the exports, metrics, and health of
+// the component with the result. The assertion only fails once the condition
+// has been false for the configured duration.
+func (c *Component) check() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.now()
+	if c.args.Condition {
+		c.failingSince = time.Time{}
+	} else if c.failingSince.IsZero() {
+		c.failingSince = now
+	}
+	passing := c.failingSince.IsZero() || now.Sub(c.failingSince) < c.args.For
+
+	if !c.checked || passing != c.passing {
+		c.logTransition(passing)
+		if !passing {
+			c.metrics.failures.Inc()
+		}
+		c.opts.OnStateChange(Exports{Passing: passing})
+	}
+	c.passing = passing
+	c.checked = true
+
+	if passing {
+		c.metrics.passing.Set(1)
+	} else {
+		c.metrics.passing.Set(0)
+	}
+	c.updateHealth(passing, now)
+}
+
+// logTransition logs the new state of the assertion. c.mut must be held when
+// calling.
+func (c *Component) logTransition(passing bool) {
+	if !c.args.LogFailures {
+		return
+	}
+	switch {
+	case !passing:
+		level.Warn(c.opts.Logger).Log("msg", "assertion failed", "message", c.failureMessage())
+	case c.checked:
+		level.Info(c.opts.Logger).Log("msg", "assertion is passing again")
+	}
+}
+
+// failureMessage returns the message describing a failed assertion. c.mut
+// must be held when calling.
+func (c *Component) failureMessage() string {
+	if c.args.Message != "" {
+		return c.args.Message
+	}
+	return "condition is false"
+}
+
+// updateHealth updates the health of the component. c.mut must be held when
+// calling.
+func (c *Component) updateHealth(passing bool, now time.Time) {
+	health := component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    "assertion passed",
+		UpdateTime: now,
+	}
+	switch {
+	case !passing:
+		health.Health = component.HealthTypeUnhealthy
+		health.Message = fmt.Sprintf("assertion failed: %s", c.failureMessage())
+	case !c.failingSince.IsZero():
+		health.Message = fmt.Sprintf("assertion pending: condition false since %s", c.failingSince.Format(time.RFC3339))
+	}
+
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+
+	// Only update the time of the health when the health changes.
+	if c.health.Health == health.Health && c.health.Message == health.Message {
+		return
+	}
+	c.health = health
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+type metrics struct {
+	passing  prometheus.Gauge
+	failures prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		passing: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_health_assert_passing",
+			Help: "Whether the assertion is passing (1) or failing (0).",
+		}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_health_assert_failures_total",
+			Help: "Total number of times the assertion started failing.",
+		}),
+	}
+	for _, c := range []prometheus.Collector{m.passing, m.failures} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
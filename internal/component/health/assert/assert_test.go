@@ -0,0 +1,78 @@
+package assert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		condition = [{"__address__" = "10.0.0.1:80"}] != []
+		message   = "discovery must return targets"
+		for       = "5m"
+	`), &args))
+	require.True(t, args.Condition)
+	require.Equal(t, 15*time.Second, args.Interval)
+	require.Equal(t, 5*time.Minute, args.For)
+	require.True(t, args.LogFailures)
+
+	require.EqualError(t, river.Unmarshal([]byte(`
+		condition = true
+		interval  = "0s"
+	`), &args), "interval must be greater than 0")
+}
+
+func TestAssert(t *testing.T) {
+	var exports []Exports
+	reg := prometheus.NewRegistry()
+	args := DefaultArguments
+	args.Message = "discovery must return targets"
+	args.For = time.Minute
+	args.Condition = true
+
+	c, err := New(component.Options{
+		ID:            "health.assert.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    reg,
+		OnStateChange: func(e component.Exports) { exports = append(exports, e.(Exports)) },
+	}, args)
+	require.NoError(t, err)
+	require.Equal(t, []Exports{{Passing: true}}, exports)
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	// The assertion is pending until the condition has been false for a
+	// minute.
+	args.Condition = false
+	require.NoError(t, c.Update(args))
+	require.Len(t, exports, 1)
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+	require.Contains(t, c.CurrentHealth().Message, "assertion pending")
+
+	now = now.Add(time.Minute)
+	c.check()
+	require.Equal(t, []Exports{{Passing: true}, {Passing: false}}, exports)
+	require.Equal(t, component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    "assertion failed: discovery must return targets",
+		UpdateTime: now,
+	}, c.CurrentHealth())
+	require.Equal(t, float64(0), testutil.ToFloat64(c.metrics.passing))
+	require.Equal(t, float64(1), testutil.ToFloat64(c.metrics.failures))
+
+	args.Condition = true
+	require.NoError(t, c.Update(args))
+	require.Equal(t, []Exports{{Passing: true}, {Passing: false}, {Passing: true}}, exports)
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+	require.Equal(t, float64(1), testutil.ToFloat64(c.metrics.passing))
+}
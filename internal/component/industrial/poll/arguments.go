@@ -0,0 +1,279 @@
+package poll
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+	"github.com/grafana/river/rivertypes"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Modbus tables which registers can be read from.
+const (
+	TableHolding       = "holding"
+	TableInput         = "input"
+	TableCoil          = "coil"
+	TableDiscreteInput = "discrete_input"
+)
+
+// OPC-UA security modes.
+const (
+	SecurityModeNone           = "None"
+	SecurityModeSign           = "Sign"
+	SecurityModeSignAndEncrypt = "SignAndEncrypt"
+)
+
+// TargetLabel is the label holding the name of the target a sample was read
+// from.
+const TargetLabel = "target"
+
+// Arguments holds values which are used to configure the industrial.poll
+// component.
+type Arguments struct {
+	Interval  time.Duration        `river:"interval,attr,optional"`
+	Timeout   time.Duration        `river:"timeout,attr,optional"`
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+
+	Modbus []ModbusArguments `river:"modbus,block,optional"`
+	OPCUA  []OPCUAArguments  `river:"opcua,block,optional"`
+}
+
+// ModbusArguments configures a Modbus TCP server to read registers from.
+type ModbusArguments struct {
+	Name      string              `river:",label"`
+	Address   string              `river:"address,attr"`
+	UnitID    int                 `river:"unit_id,attr,optional"`
+	Labels    map[string]string   `river:"labels,attr,optional"`
+	Registers []RegisterArguments `river:"register,block"`
+}
+
+// RegisterArguments configures a Modbus register to read as a gauge.
+type RegisterArguments struct {
+	Metric    string            `river:"metric,attr"`
+	Help      string            `river:"help,attr,optional"`
+	Unit      string            `river:"unit,attr,optional"`
+	Labels    map[string]string `river:"labels,attr,optional"`
+	Address   int               `river:"address,attr"`
+	Table     string            `river:"table,attr,optional"`
+	Type      string            `river:"type,attr,optional"`
+	WordOrder string            `river:"word_order,attr,optional"`
+	Scale     float64           `river:"scale,attr,optional"`
+	Offset    float64           `river:"offset,attr,optional"`
+}
+
+// OPCUAArguments configures an OPC-UA server to read nodes from.
+type OPCUAArguments struct {
+	Name           string            `river:",label"`
+	Endpoint       string            `river:"endpoint,attr"`
+	SecurityPolicy string            `river:"security_policy,attr,optional"`
+	SecurityMode   string            `river:"security_mode,attr,optional"`
+	CertFile       string            `river:"cert_file,attr,optional"`
+	KeyFile        string            `river:"key_file,attr,optional"`
+	Username       string            `river:"username,attr,optional"`
+	Password       rivertypes.Secret `river:"password,attr,optional"`
+	Labels         map[string]string `river:"labels,attr,optional"`
+	Nodes          []NodeArguments   `river:"node,block"`
+}
+
+// NodeArguments configures an OPC-UA node to read as a gauge.
+type NodeArguments struct {
+	Metric string            `river:"metric,attr"`
+	Help   string            `river:"help,attr,optional"`
+	Unit   string            `river:"unit,attr,optional"`
+	Labels map[string]string `river:"labels,attr,optional"`
+	NodeID string            `river:"node_id,attr"`
+	Scale  float64           `river:"scale,attr,optional"`
+	Offset float64           `river:"offset,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval: 15 * time.Second,
+	Timeout:  5 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if args.Timeout > args.Interval {
+		return fmt.Errorf("timeout must not be greater than interval")
+	}
+	if len(args.Modbus) == 0 && len(args.OPCUA) == 0 {
+		return fmt.Errorf("at least one modbus or opcua block must be set")
+	}
+
+	names := make(map[string]struct{}, len(args.Modbus)+len(args.OPCUA))
+	checkName := func(name string) error {
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("target %q is defined more than once", name)
+		}
+		names[name] = struct{}{}
+		return nil
+	}
+	for _, m := range args.Modbus {
+		if err := checkName(m.Name); err != nil {
+			return err
+		}
+	}
+	for _, o := range args.OPCUA {
+		if err := checkName(o.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *ModbusArguments) SetToDefault() {
+	*args = ModbusArguments{UnitID: 1}
+}
+
+// Validate implements river.Validator.
+func (args *ModbusArguments) Validate() error {
+	if _, _, err := net.SplitHostPort(args.Address); err != nil {
+		return fmt.Errorf("modbus %q: invalid address: %w", args.Name, err)
+	}
+	if args.UnitID < 0 || args.UnitID > 255 {
+		return fmt.Errorf("modbus %q: unit_id must be between 0 and 255", args.Name)
+	}
+	if err := validateLabels(args.Labels); err != nil {
+		return fmt.Errorf("modbus %q: %w", args.Name, err)
+	}
+	if len(args.Registers) == 0 {
+		return fmt.Errorf("modbus %q: at least one register block must be set", args.Name)
+	}
+	return nil
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *RegisterArguments) SetToDefault() {
+	*args = RegisterArguments{
+		Table:     TableHolding,
+		Type:      "uint16",
+		WordOrder: "big",
+		Scale:     1,
+	}
+}
+
+// Validate implements river.Validator.
+func (args *RegisterArguments) Validate() error {
+	if err := validateMetric(args.Metric, args.Labels); err != nil {
+		return err
+	}
+	if args.Address < 0 || args.Address > 0xFFFF {
+		return fmt.Errorf("register %q: address must be between 0 and 65535", args.Metric)
+	}
+	switch args.Table {
+	case TableHolding, TableInput:
+		if _, ok := registerCounts[args.Type]; !ok {
+			return fmt.Errorf("register %q: unsupported type %q", args.Metric, args.Type)
+		}
+	case TableCoil, TableDiscreteInput:
+	default:
+		return fmt.Errorf("register %q: table must be one of %s, %s, %s or %s, got %q",
+			args.Metric, TableHolding, TableInput, TableCoil, TableDiscreteInput, args.Table)
+	}
+	if args.WordOrder != "big" && args.WordOrder != "little" {
+		return fmt.Errorf("register %q: word_order must be \"big\" or \"little\", got %q", args.Metric, args.WordOrder)
+	}
+	return nil
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *OPCUAArguments) SetToDefault() {
+	*args = OPCUAArguments{
+		SecurityPolicy: "None",
+		SecurityMode:   SecurityModeNone,
+	}
+}
+
+// Validate implements river.Validator.
+func (args *OPCUAArguments) Validate() error {
+	u, err := url.Parse(args.Endpoint)
+	if err != nil {
+		return fmt.Errorf("opcua %q: invalid endpoint: %w", args.Name, err)
+	}
+	if u.Scheme != "opc.tcp" {
+		return fmt.Errorf("opcua %q: endpoint must use the opc.tcp scheme", args.Name)
+	}
+
+	switch args.SecurityPolicy {
+	case "None", "Basic128Rsa15", "Basic256", "Basic256Sha256", "Aes128_Sha256_RsaOaep", "Aes256_Sha256_RsaPss":
+	default:
+		return fmt.Errorf("opcua %q: unsupported security_policy %q", args.Name, args.SecurityPolicy)
+	}
+	switch args.SecurityMode {
+	case SecurityModeNone, SecurityModeSign, SecurityModeSignAndEncrypt:
+	default:
+		return fmt.Errorf("opcua %q: security_mode must be one of %s, %s or %s, got %q",
+			args.Name, SecurityModeNone, SecurityModeSign, SecurityModeSignAndEncrypt, args.SecurityMode)
+	}
+	if (args.SecurityPolicy == "None") != (args.SecurityMode == SecurityModeNone) {
+		return fmt.Errorf("opcua %q: security_policy and security_mode must both be None, or both be set", args.Name)
+	}
+	if (args.CertFile == "") != (args.KeyFile == "") {
+		return fmt.Errorf("opcua %q: cert_file and key_file must be set together", args.Name)
+	}
+	if args.SecurityMode != SecurityModeNone && args.CertFile == "" {
+		return fmt.Errorf("opcua %q: cert_file and key_file must be set when security_mode is %s", args.Name, args.SecurityMode)
+	}
+	if args.Password != "" && args.Username == "" {
+		return fmt.Errorf("opcua %q: username must be set when password is set", args.Name)
+	}
+	if err := validateLabels(args.Labels); err != nil {
+		return fmt.Errorf("opcua %q: %w", args.Name, err)
+	}
+	if len(args.Nodes) == 0 {
+		return fmt.Errorf("opcua %q: at least one node block must be set", args.Name)
+	}
+	return nil
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *NodeArguments) SetToDefault() {
+	*args = NodeArguments{Scale: 1}
+}
+
+// Validate implements river.Validator.
+func (args *NodeArguments) Validate() error {
+	if err := validateMetric(args.Metric, args.Labels); err != nil {
+		return err
+	}
+	if _, err := ua.ParseNodeID(args.NodeID); err != nil {
+		return fmt.Errorf("node %q: invalid node_id: %w", args.Metric, err)
+	}
+	return nil
+}
+
+func validateMetric(name string, labels map[string]string) error {
+	if !model.IsValidMetricName(model.LabelValue(name)) {
+		return fmt.Errorf("invalid metric name %q", name)
+	}
+	if err := validateLabels(labels); err != nil {
+		return fmt.Errorf("metric %q: %w", name, err)
+	}
+	return nil
+}
+
+func validateLabels(labels map[string]string) error {
+	for name := range labels {
+		if !model.LabelName(name).IsValid() || name == model.MetricNameLabel || name == TargetLabel {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return nil
+}
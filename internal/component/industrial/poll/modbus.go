@@ -0,0 +1,220 @@
+package poll
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+
+	"github.com/grafana/agent/internal/service/egress"
+)
+
+// registerCounts holds the number of 16-bit registers read for each type.
+var registerCounts = map[string]uint16{
+	"int16":   1,
+	"uint16":  1,
+	"int32":   2,
+	"uint32":  2,
+	"float32": 2,
+	"int64":   4,
+	"uint64":  4,
+	"float64": 4,
+}
+
+// Modbus function codes for reading each table.
+var functionCodes = map[string]byte{
+	TableCoil:          0x01,
+	TableDiscreteInput: 0x02,
+	TableHolding:       0x03,
+	TableInput:         0x04,
+}
+
+// modbusExceptions holds the descriptions of Modbus exception codes.
+var modbusExceptions = map[byte]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "server device failure",
+	0x06: "server device busy",
+	0x0A: "gateway path unavailable",
+	0x0B: "gateway target device failed to respond",
+}
+
+// modbusTarget reads registers from a Modbus TCP server. The connection is
+// kept open between polls, and reestablished on the next poll after an
+// error.
+type modbusTarget struct {
+	args ModbusArguments
+
+	conn          net.Conn
+	transactionID uint16
+}
+
+var _ target = (*modbusTarget)(nil)
+
+func newModbusTarget(args ModbusArguments) *modbusTarget {
+	return &modbusTarget{args: args}
+}
+
+// Read implements target. Registers are read one after the other on the
+// same connection, and decoded as their type.
+func (t *modbusTarget) Read(ctx context.Context) ([]reading, error) {
+	if t.conn == nil {
+		conn, err := egress.DialContext(ctx, "tcp", t.args.Address)
+		if err != nil {
+			return nil, err
+		}
+		t.conn = conn
+	}
+
+	readings := make([]reading, 0, len(t.args.Registers))
+	var errs []error
+	for _, r := range t.args.Registers {
+		value, err := t.readRegister(ctx, r)
+		if err != nil {
+			var exc modbusException
+			if !errors.As(err, &exc) {
+				// The connection may be in an unknown state after any error
+				// other than an exception response, such as a timeout.
+				t.Close()
+				return readings, fmt.Errorf("register %q: %w", r.Metric, err)
+			}
+			errs = append(errs, fmt.Errorf("register %q: %w", r.Metric, err))
+			continue
+		}
+		readings = append(readings, reading{
+			Metric: r.Metric,
+			Help:   r.Help,
+			Unit:   r.Unit,
+			Labels: r.Labels,
+			Value:  value*r.Scale + r.Offset,
+		})
+	}
+	return readings, errors.Join(errs...)
+}
+
+func (t *modbusTarget) readRegister(ctx context.Context, r RegisterArguments) (float64, error) {
+	quantity := uint16(1)
+	if r.Table == TableHolding || r.Table == TableInput {
+		quantity = registerCounts[r.Type]
+	}
+	data, err := t.request(ctx, functionCodes[r.Table], uint16(r.Address), quantity)
+	if err != nil {
+		return 0, err
+	}
+
+	if r.Table == TableCoil || r.Table == TableDiscreteInput {
+		if len(data) < 1 {
+			return 0, fmt.Errorf("empty response")
+		}
+		return float64(data[0] & 0x01), nil
+	}
+	if len(data) != int(quantity)*2 {
+		return 0, fmt.Errorf("expected %d bytes, got %d", quantity*2, len(data))
+	}
+	return decodeRegisters(data, r.Type, r.WordOrder), nil
+}
+
+// request sends a read request for quantity items starting at address, and
+// returns the data of the response.
+func (t *modbusTarget) request(ctx context.Context, function byte, address, quantity uint16) ([]byte, error) {
+	// The deadline is zero, and doesn't expire, if ctx has no deadline.
+	deadline, _ := ctx.Deadline()
+	if err := t.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	t.transactionID++
+	req := make([]byte, 12)
+	binary.BigEndian.PutUint16(req[0:], t.transactionID)
+	binary.BigEndian.PutUint16(req[2:], 0) // Protocol identifier.
+	binary.BigEndian.PutUint16(req[4:], 6) // Length of the unit ID and PDU.
+	req[6] = byte(t.args.UnitID)
+	req[7] = function
+	binary.BigEndian.PutUint16(req[8:], address)
+	binary.BigEndian.PutUint16(req[10:], quantity)
+	if _, err := t.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(t.conn, header); err != nil {
+		return nil, err
+	}
+	if id := binary.BigEndian.Uint16(header[0:]); id != t.transactionID {
+		return nil, fmt.Errorf("unexpected transaction ID %d, expected %d", id, t.transactionID)
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 3 || length > 254 {
+		return nil, fmt.Errorf("invalid response length %d", length)
+	}
+	pdu := make([]byte, length-1)
+	if _, err := io.ReadFull(t.conn, pdu); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case pdu[0] == function|0x80:
+		return nil, modbusException(pdu[1])
+	case pdu[0] != function:
+		return nil, fmt.Errorf("unexpected function code %d in response", pdu[0])
+	case int(pdu[1]) != len(pdu)-2:
+		return nil, fmt.Errorf("invalid byte count %d in response", pdu[1])
+	}
+	return pdu[2:], nil
+}
+
+// Close closes the connection to the server, if any.
+func (t *modbusTarget) Close() {
+	if t.conn != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// modbusException is an exception code returned by a Modbus server.
+type modbusException byte
+
+func (e modbusException) Error() string {
+	if desc, ok := modbusExceptions[byte(e)]; ok {
+		return fmt.Sprintf("modbus exception %d: %s", byte(e), desc)
+	}
+	return fmt.Sprintf("modbus exception %d", byte(e))
+}
+
+// decodeRegisters decodes the big-endian registers in data as typ. When
+// wordOrder is little, the least significant register comes first.
+func decodeRegisters(data []byte, typ, wordOrder string) float64 {
+	if wordOrder == "little" {
+		words := len(data) / 2
+		swapped := make([]byte, len(data))
+		for i := 0; i < words; i++ {
+			copy(swapped[i*2:], data[(words-1-i)*2:(words-i)*2])
+		}
+		data = swapped
+	}
+
+	switch typ {
+	case "int16":
+		return float64(int16(binary.BigEndian.Uint16(data)))
+	case "uint16":
+		return float64(binary.BigEndian.Uint16(data))
+	case "int32":
+		return float64(int32(binary.BigEndian.Uint32(data)))
+	case "uint32":
+		return float64(binary.BigEndian.Uint32(data))
+	case "float32":
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case "int64":
+		return float64(int64(binary.BigEndian.Uint64(data)))
+	case "uint64":
+		return float64(binary.BigEndian.Uint64(data))
+	case "float64":
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	default:
+		panic("unsupported register type " + typ)
+	}
+}
@@ -0,0 +1,224 @@
+package poll
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeModbusServer is a Modbus TCP server holding registers and coils in
+// memory.
+type fakeModbusServer struct {
+	t  *testing.T
+	ln net.Listener
+
+	mut       sync.Mutex
+	registers map[uint16]uint16 // Holding and input registers.
+	coils     map[uint16]bool   // Coils and discrete inputs.
+}
+
+func newFakeModbusServer(t *testing.T) *fakeModbusServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeModbusServer{
+		t:         t,
+		ln:        ln,
+		registers: make(map[uint16]uint16),
+		coils:     make(map[uint16]bool),
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeModbusServer) Addr() string { return s.ln.Addr().String() }
+
+func (s *fakeModbusServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeModbusServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		req := make([]byte, 12)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		function := req[7]
+		address := binary.BigEndian.Uint16(req[8:])
+		quantity := binary.BigEndian.Uint16(req[10:])
+
+		s.mut.Lock()
+		var pdu []byte
+		switch function {
+		case 0x01, 0x02:
+			pdu = []byte{function, 1, 0}
+			if s.coils[address] {
+				pdu[2] = 1
+			}
+		case 0x03, 0x04:
+			pdu = []byte{function, byte(quantity * 2)}
+			for i := uint16(0); i < quantity; i++ {
+				v, ok := s.registers[address+i]
+				if !ok {
+					pdu = []byte{function | 0x80, 0x02}
+					break
+				}
+				pdu = binary.BigEndian.AppendUint16(pdu, v)
+			}
+		default:
+			pdu = []byte{function | 0x80, 0x01}
+		}
+		s.mut.Unlock()
+
+		resp := make([]byte, 7, 7+len(pdu))
+		copy(resp, req[:4])
+		binary.BigEndian.PutUint16(resp[4:], uint16(len(pdu)+1))
+		resp[6] = req[6]
+		if _, err := conn.Write(append(resp, pdu...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeModbusServer) SetRegisters(address uint16, values ...uint16) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for i, v := range values {
+		s.registers[address+uint16(i)] = v
+	}
+}
+
+func (s *fakeModbusServer) SetCoil(address uint16, value bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.coils[address] = value
+}
+
+func TestModbusTarget(t *testing.T) {
+	srv := newFakeModbusServer(t)
+	srv.SetRegisters(0, 215)
+	srv.SetRegisters(10, uint16(math.Float32bits(-1.5)>>16), uint16(math.Float32bits(-1.5)))
+	// 70000 as a uint32 with the least significant word first.
+	srv.SetRegisters(20, 70000&0xFFFF, 70000>>16)
+	srv.SetCoil(5, true)
+
+	var args ModbusArguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		address = "`+srv.Addr()+`"
+
+		register {
+			metric = "boiler_temperature_celsius"
+			unit   = "celsius"
+			help   = "Temperature of the boiler."
+			address = 0
+			scale   = 0.1
+		}
+		register {
+			metric  = "flow_rate"
+			address = 10
+			table   = "input"
+			type    = "float32"
+		}
+		register {
+			metric     = "energy_total"
+			address    = 20
+			type       = "uint32"
+			word_order = "little"
+		}
+		register {
+			metric  = "pump_running"
+			address = 5
+			table   = "coil"
+			labels  = { pump = "1" }
+		}
+	`), &args))
+
+	target := newModbusTarget(args)
+	defer target.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	readings, err := target.Read(ctx)
+	require.NoError(t, err)
+	require.Len(t, readings, 4)
+	require.Equal(t, "boiler_temperature_celsius", readings[0].Metric)
+	require.Equal(t, "celsius", readings[0].Unit)
+	require.InDelta(t, 21.5, readings[0].Value, 1e-9)
+	require.Equal(t, -1.5, readings[1].Value)
+	require.Equal(t, 70000.0, readings[2].Value)
+	require.Equal(t, reading{Metric: "pump_running", Labels: map[string]string{"pump": "1"}, Value: 1}, readings[3])
+
+	// Exceptions fail single registers, and the connection is reused.
+	srv.SetRegisters(0)
+	args.Registers[0].Address = 100
+	target.args = args
+	readings, err = target.Read(ctx)
+	require.EqualError(t, err, `register "boiler_temperature_celsius": modbus exception 2: illegal data address`)
+	require.Len(t, readings, 3)
+	require.NotNil(t, target.conn)
+}
+
+func TestModbusTarget_Reconnect(t *testing.T) {
+	srv := newFakeModbusServer(t)
+	srv.SetRegisters(0, 1)
+
+	target := newModbusTarget(ModbusArguments{
+		Address:   srv.Addr(),
+		UnitID:    1,
+		Registers: []RegisterArguments{{Metric: "value", Table: TableHolding, Type: "uint16", WordOrder: "big", Scale: 1}},
+	})
+	defer target.Close()
+
+	_, err := target.Read(context.Background())
+	require.NoError(t, err)
+
+	// Break the connection; the next read fails and closes it, and the read
+	// after reconnects.
+	_ = target.conn.Close()
+	_, err = target.Read(context.Background())
+	require.Error(t, err)
+	require.Nil(t, target.conn)
+
+	readings, err := target.Read(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1.0, readings[0].Value)
+}
+
+func TestDecodeRegisters(t *testing.T) {
+	tt := []struct {
+		typ       string
+		wordOrder string
+		data      []byte
+		expect    float64
+	}{
+		{"int16", "big", []byte{0xFF, 0xFE}, -2},
+		{"uint16", "big", []byte{0xFF, 0xFE}, 65534},
+		{"int32", "big", []byte{0xFF, 0xFF, 0xFF, 0xFD}, -3},
+		{"int32", "little", []byte{0xFF, 0xFD, 0xFF, 0xFF}, -3},
+		{"uint64", "big", []byte{0, 0, 0, 0, 0, 0, 0x01, 0x00}, 256},
+		{"uint64", "little", []byte{0x01, 0x00, 0, 0, 0, 0, 0, 0}, 256},
+		{"float64", "big", binary.BigEndian.AppendUint64(nil, math.Float64bits(3.25)), 3.25},
+	}
+	for _, tc := range tt {
+		require.Equal(t, tc.expect, decodeRegisters(tc.data, tc.typ, tc.wordOrder), "%s, %s word order", tc.typ, tc.wordOrder)
+	}
+}
@@ -0,0 +1,171 @@
+package poll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+)
+
+// opcuaTarget reads nodes from an OPC-UA server. The session is kept open
+// between polls, and reestablished on the next poll after an error.
+type opcuaTarget struct {
+	args    OPCUAArguments
+	timeout time.Duration
+	nodes   []*ua.ReadValueID
+
+	client *opcua.Client
+}
+
+var _ target = (*opcuaTarget)(nil)
+
+func newOPCUATarget(args OPCUAArguments, timeout time.Duration) (*opcuaTarget, error) {
+	nodes := make([]*ua.ReadValueID, 0, len(args.Nodes))
+	for _, n := range args.Nodes {
+		id, err := ua.ParseNodeID(n.NodeID)
+		if err != nil {
+			return nil, fmt.Errorf("node %q: invalid node_id: %w", n.Metric, err)
+		}
+		nodes = append(nodes, &ua.ReadValueID{NodeID: id, AttributeID: ua.AttributeIDValue})
+	}
+	return &opcuaTarget{args: args, timeout: timeout, nodes: nodes}, nil
+}
+
+// Read implements target. Every node is read with a single request.
+func (t *opcuaTarget) Read(ctx context.Context) ([]reading, error) {
+	if t.client == nil {
+		client, err := t.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		t.client = client
+	}
+
+	resp, err := t.client.Read(ctx, &ua.ReadRequest{
+		NodesToRead:        t.nodes,
+		TimestampsToReturn: ua.TimestampsToReturnNeither,
+	})
+	if err == nil && len(resp.Results) != len(t.nodes) {
+		err = fmt.Errorf("expected %d results, got %d", len(t.nodes), len(resp.Results))
+	}
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	readings := make([]reading, 0, len(t.args.Nodes))
+	var errs []error
+	for i, n := range t.args.Nodes {
+		res := resp.Results[i]
+		if res.Status != ua.StatusOK {
+			errs = append(errs, fmt.Errorf("node %q: %w", n.Metric, res.Status))
+			continue
+		}
+		value, err := variantToFloat(res.Value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %q: %w", n.Metric, err))
+			continue
+		}
+		readings = append(readings, reading{
+			Metric: n.Metric,
+			Help:   n.Help,
+			Unit:   n.Unit,
+			Labels: n.Labels,
+			Value:  value*n.Scale + n.Offset,
+		})
+	}
+	return readings, errors.Join(errs...)
+}
+
+// connect selects the endpoint of the server matching the security settings
+// of the target, and opens a session with it.
+func (t *opcuaTarget) connect(ctx context.Context) (*opcua.Client, error) {
+	endpoints, err := opcua.GetEndpoints(ctx, t.args.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("getting endpoints: %w", err)
+	}
+	ep, err := opcua.SelectEndpoint(endpoints, t.args.SecurityPolicy, ua.MessageSecurityModeFromString(t.args.SecurityMode))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []opcua.Option{
+		opcua.AutoReconnect(false),
+		opcua.RequestTimeout(t.timeout),
+		opcua.DialTimeout(t.timeout),
+	}
+	if t.args.CertFile != "" {
+		opts = append(opts, opcua.CertificateFile(t.args.CertFile), opcua.PrivateKeyFile(t.args.KeyFile))
+	}
+	authType := ua.UserTokenTypeAnonymous
+	if t.args.Username != "" {
+		authType = ua.UserTokenTypeUserName
+		opts = append(opts, opcua.AuthUsername(t.args.Username, string(t.args.Password)))
+	} else {
+		opts = append(opts, opcua.AuthAnonymous())
+	}
+	opts = append(opts, opcua.SecurityFromEndpoint(ep, authType))
+
+	client, err := opcua.NewClient(t.args.Endpoint, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Close closes the session with the server, if any.
+func (t *opcuaTarget) Close() {
+	if t.client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+		defer cancel()
+		_ = t.client.Close(ctx)
+		t.client = nil
+	}
+}
+
+// variantToFloat converts the value of a node to a float. Booleans are
+// converted to 0 or 1, and strings are parsed as floats.
+func variantToFloat(v *ua.Variant) (float64, error) {
+	if v == nil {
+		return 0, fmt.Errorf("node has no value")
+	}
+	switch val := v.Value().(type) {
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	case int8:
+		return float64(val), nil
+	case uint8:
+		return float64(val), nil
+	case int16:
+		return float64(val), nil
+	case uint16:
+		return float64(val), nil
+	case int32:
+		return float64(val), nil
+	case uint32:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case uint64:
+		return float64(val), nil
+	case float32:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case string:
+		return strconv.ParseFloat(val, 64)
+	default:
+		return 0, fmt.Errorf("unsupported value type %s", strings.TrimPrefix(v.Type().String(), "TypeID"))
+	}
+}
@@ -0,0 +1,73 @@
+package poll
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/server"
+	"github.com/gopcua/opcua/ua"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOPCUATarget(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+
+	srv := server.New(
+		server.EndPoint("127.0.0.1", port),
+		server.EnableSecurity("None", ua.MessageSecurityModeNone),
+		server.EnableAuthMode(ua.UserTokenTypeAnonymous),
+	)
+	ns := server.NewMapNamespace(srv, "Plant")
+	ns.Data["Boiler.Temperature"] = 81.5
+	ns.Data["Pump.Running"] = true
+	ns.Data["Line.Name"] = "line-1"
+
+	// The server panics when the context it was started with is canceled, so
+	// it's only closed.
+	require.NoError(t, srv.Start(context.Background()))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	nodeID := func(name string) string { return fmt.Sprintf("ns=%d;s=%s", ns.ID(), name) }
+	target, err := newOPCUATarget(OPCUAArguments{
+		Endpoint:       fmt.Sprintf("opc.tcp://127.0.0.1:%d", port),
+		SecurityPolicy: "None",
+		SecurityMode:   SecurityModeNone,
+		Nodes: []NodeArguments{
+			{Metric: "boiler_temperature_fahrenheit", Unit: "fahrenheit", NodeID: nodeID("Boiler.Temperature"), Scale: 1.8, Offset: 32},
+			{Metric: "pump_running", NodeID: nodeID("Pump.Running"), Scale: 1},
+			{Metric: "line_name", NodeID: nodeID("Line.Name"), Scale: 1},
+		},
+	}, 5*time.Second)
+	require.NoError(t, err)
+	defer target.Close()
+
+	readings, err := target.Read(ctx)
+	require.ErrorContains(t, err, `node "line_name": strconv.ParseFloat: parsing "line-1": invalid syntax`)
+	require.Len(t, readings, 2)
+	require.Equal(t, "fahrenheit", readings[0].Unit)
+	require.InDelta(t, 178.7, readings[0].Value, 1e-9)
+	require.Equal(t, 1.0, readings[1].Value)
+}
+
+func TestVariantToFloat(t *testing.T) {
+	for _, v := range []any{int8(-3), uint16(3), int64(-3), float32(0.5), "0.5", false} {
+		variant, err := ua.NewVariant(v)
+		require.NoError(t, err)
+		_, err = variantToFloat(variant)
+		require.NoError(t, err, "%T", v)
+	}
+
+	variant, err := ua.NewVariant(time.Now())
+	require.NoError(t, err)
+	_, err = variantToFloat(variant)
+	require.EqualError(t, err, "unsupported value type DateTime")
+}
@@ -0,0 +1,320 @@
+// Package poll implements the industrial.poll component, which reads
+// registers of Modbus TCP servers and nodes of OPC-UA servers on an interval,
+// and forwards them as gauges.
+package poll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "industrial.poll",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// target is a server which values are read from.
+type target interface {
+	// Read reads every value configured for the target. Read may return
+	// readings along with an error when only some values couldn't be read.
+	Read(ctx context.Context) ([]reading, error)
+
+	// Close closes the connection to the server, if any.
+	Close()
+}
+
+// reading is a value read from a target.
+type reading struct {
+	Metric string
+	Help   string
+	Unit   string
+	Labels map[string]string
+	Value  float64
+}
+
+// namedTarget is a target along with the labels of its samples.
+type namedTarget struct {
+	name   string
+	labels map[string]string
+	target target
+}
+
+// Component implements the industrial.poll component.
+type Component struct {
+	log    log.Logger
+	opts   component.Options
+	fanout *agentprom.Fanout
+
+	mut     sync.Mutex
+	args    Arguments
+	targets []namedTarget
+	lastRun time.Time
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+
+	reads        *prometheus_client.CounterVec
+	readFailures *prometheus_client.CounterVec
+	readDuration *prometheus_client.GaugeVec
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new industrial.poll component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	data, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		log:     opts.Logger,
+		opts:    opts,
+		fanout:  agentprom.NewFanout(args.ForwardTo, opts.ID, opts.Registerer, ls),
+		updated: make(chan struct{}, 1),
+
+		health: component.Health{
+			Health:     component.HealthTypeUnknown,
+			Message:    "component started",
+			UpdateTime: time.Now(),
+		},
+
+		reads: prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+			Name: "agent_industrial_poll_reads_total",
+			Help: "Total number of times values were read from a target.",
+		}, []string{"target"}),
+		readFailures: prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+			Name: "agent_industrial_poll_read_failures_total",
+			Help: "Total number of times reading values from a target failed, at least partially.",
+		}, []string{"target"}),
+		readDuration: prometheus_client.NewGaugeVec(prometheus_client.GaugeOpts{
+			Name: "agent_industrial_poll_read_duration_seconds",
+			Help: "Duration of the last read of the values of a target.",
+		}, []string{"target"}),
+	}
+	for _, m := range []prometheus_client.Collector{c.reads, c.readFailures, c.readDuration} {
+		if err := opts.Registerer.Register(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+		closeTargets(c.targets)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.nextRun()):
+			c.poll(ctx)
+		case <-c.updated:
+			// no-op; force the next wait to be reread.
+		}
+	}
+}
+
+// nextRun returns how long to wait to poll targets given the last time they
+// were polled. nextRun returns 0 if targets should be polled immediately.
+func (c *Component) nextRun() time.Duration {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	nextRun := c.lastRun.Add(c.args.Interval)
+	now := time.Now()
+
+	if now.After(nextRun) {
+		return 0
+	}
+	return nextRun.Sub(now)
+}
+
+// poll reads the values of every target concurrently, and forwards them to
+// the receivers. c.mut must not be held when calling. After polling, the
+// component's health is updated with the failures, if any.
+func (c *Component) poll(ctx context.Context) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := time.Now()
+	c.lastRun = now
+
+	readCtx, cancel := context.WithTimeout(ctx, c.args.Timeout)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		readings = make([][]reading, len(c.targets))
+		errs     = make([]error, len(c.targets))
+	)
+	for i, t := range c.targets {
+		wg.Add(1)
+		go func(i int, t namedTarget) {
+			defer wg.Done()
+
+			start := time.Now()
+			readings[i], errs[i] = t.target.Read(readCtx)
+			c.readDuration.WithLabelValues(t.name).Set(time.Since(start).Seconds())
+			c.reads.WithLabelValues(t.name).Inc()
+			if errs[i] != nil {
+				c.readFailures.WithLabelValues(t.name).Inc()
+				level.Error(c.log).Log("msg", "failed to read values", "target", t.name, "err", errs[i])
+				errs[i] = fmt.Errorf("target %q: %w", t.name, errs[i])
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	var (
+		app = c.fanout.Appender(ctx)
+		ts  = timestamp.FromTime(now)
+	)
+	for i, t := range c.targets {
+		if err := appendReadings(app, t, readings[i], ts); err != nil {
+			level.Error(c.log).Log("msg", "failed to append values", "target", t.name, "err", err)
+			errs = append(errs, fmt.Errorf("target %q: appending values: %w", t.name, err))
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Error(c.log).Log("msg", "failed to forward values", "err", err)
+		errs = append(errs, fmt.Errorf("forwarding values: %w", err))
+	}
+
+	c.updateHealth(errors.Join(errs...))
+}
+
+// appendReadings appends readings as gauges, along with their metadata.
+// Labels of readings take precedence over labels of the target.
+func appendReadings(app storage.Appender, t namedTarget, readings []reading, ts int64) error {
+	for _, r := range readings {
+		lb := labels.NewBuilder(labels.EmptyLabels())
+		lb.Set(labels.MetricName, r.Metric)
+		lb.Set(TargetLabel, t.name)
+		for n, v := range t.labels {
+			lb.Set(n, v)
+		}
+		for n, v := range r.Labels {
+			lb.Set(n, v)
+		}
+		lset := lb.Labels()
+
+		ref, err := app.Append(0, lset, ts, r.Value)
+		if err != nil {
+			return err
+		}
+		_, err = app.UpdateMetadata(ref, lset, metadata.Metadata{
+			Type: textparse.MetricTypeGauge,
+			Unit: r.Unit,
+			Help: r.Help,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Component) updateHealth(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+
+	if err == nil {
+		c.health = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "polled targets",
+			UpdateTime: time.Now(),
+		}
+	} else {
+		c.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("polling targets failed: %s", err),
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+// Update implements component.Component. Connections to the targets are
+// reestablished on the next poll.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	targets := make([]namedTarget, 0, len(newArgs.Modbus)+len(newArgs.OPCUA))
+	for _, m := range newArgs.Modbus {
+		targets = append(targets, namedTarget{name: m.Name, labels: m.Labels, target: newModbusTarget(m)})
+	}
+	for _, o := range newArgs.OPCUA {
+		t, err := newOPCUATarget(o, newArgs.Timeout)
+		if err != nil {
+			return fmt.Errorf("opcua %q: %w", o.Name, err)
+		}
+		targets = append(targets, namedTarget{name: o.Name, labels: o.Labels, target: t})
+	}
+
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	closeTargets(c.targets)
+	c.args = newArgs
+	c.targets = targets
+
+	// Send an updated event if one wasn't already read.
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func closeTargets(targets []namedTarget) {
+	for _, t := range targets {
+		t.target.Close()
+	}
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
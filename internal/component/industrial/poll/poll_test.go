@@ -0,0 +1,220 @@
+package poll
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "valid",
+			config: `
+				forward_to = []
+				modbus "plc" {
+					address = "10.0.0.5:502"
+					register {
+						metric  = "pressure_bar"
+						address = 3
+						type    = "float32"
+					}
+				}
+				opcua "line" {
+					endpoint = "opc.tcp://10.0.0.6:4840"
+					node {
+						metric  = "speed_rpm"
+						node_id = "ns=2;s=Motor.Speed"
+					}
+				}
+			`,
+		},
+		{
+			name:   "no targets",
+			config: `forward_to = []`,
+			err:    "at least one modbus or opcua block must be set",
+		},
+		{
+			name: "duplicate target",
+			config: `
+				forward_to = []
+				modbus "a" {
+					address = "10.0.0.5:502"
+					register {
+						metric  = "value"
+						address = 0
+					}
+				}
+				opcua "a" {
+					endpoint = "opc.tcp://10.0.0.6:4840"
+					node {
+						metric  = "value"
+						node_id = "i=2258"
+					}
+				}
+			`,
+			err: `target "a" is defined more than once`,
+		},
+		{
+			name: "bad register type",
+			config: `
+				forward_to = []
+				modbus "plc" {
+					address = "10.0.0.5:502"
+					register {
+						metric  = "value"
+						address = 0
+						type    = "int8"
+					}
+				}
+			`,
+			err: `register "value": unsupported type "int8"`,
+		},
+		{
+			name: "reserved label",
+			config: `
+				forward_to = []
+				modbus "plc" {
+					address = "10.0.0.5:502"
+					labels  = { target = "other" }
+					register {
+						metric  = "value"
+						address = 0
+					}
+				}
+			`,
+			err: `modbus "plc": invalid label name "target"`,
+		},
+		{
+			name: "security without certificate",
+			config: `
+				forward_to = []
+				opcua "line" {
+					endpoint        = "opc.tcp://10.0.0.6:4840"
+					security_policy = "Basic256Sha256"
+					security_mode   = "SignAndEncrypt"
+					node {
+						metric  = "value"
+						node_id = "i=2258"
+					}
+				}
+			`,
+			err: `opcua "line": cert_file and key_file must be set when security_mode is SignAndEncrypt`,
+		},
+		{
+			name: "bad node id",
+			config: `
+				forward_to = []
+				opcua "line" {
+					endpoint = "opc.tcp://10.0.0.6:4840"
+					node {
+						metric  = "value"
+						node_id = "ns=x;s=Motor"
+					}
+				}
+			`,
+			err: `node "value": invalid node_id: opcua: invalid namespace id`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.config), &args)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPoll(t *testing.T) {
+	srv := newFakeModbusServer(t)
+	srv.SetRegisters(0, 215)
+
+	type sample struct {
+		labels labels.Labels
+		value  float64
+	}
+	var (
+		samples = make(chan sample, 10)
+		meta    = make(chan metadata.Metadata, 10)
+	)
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	receiver := prometheus.NewInterceptor(nil, ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+			samples <- sample{labels: l, value: v}
+			return ref, nil
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, _ labels.Labels, m metadata.Metadata, _ storage.Appender) (storage.SeriesRef, error) {
+			meta <- m
+			return ref, nil
+		}),
+	)
+
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		forward_to = []
+		modbus "boiler" {
+			address = "`+srv.Addr()+`"
+			labels  = { site = "plant-a" }
+			register {
+				metric  = "boiler_temperature_celsius"
+				help    = "Temperature of the boiler."
+				unit    = "celsius"
+				address = 0
+				scale   = 0.1
+			}
+		}
+	`), &args))
+	args.ForwardTo = []storage.Appendable{receiver}
+
+	c, err := New(component.Options{
+		ID:            "industrial.poll.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	select {
+	case s := <-samples:
+		require.Equal(t, labels.FromStrings("__name__", "boiler_temperature_celsius", "site", "plant-a", "target", "boiler"), s.labels)
+		require.InDelta(t, 21.5, s.value, 1e-9)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no sample received")
+	}
+	select {
+	case m := <-meta:
+		require.Equal(t, metadata.Metadata{Type: textparse.MetricTypeGauge, Unit: "celsius", Help: "Temperature of the boiler."}, m)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no metadata received")
+	}
+	require.Eventually(t, func() bool {
+		return c.CurrentHealth().Health == component.HealthTypeHealthy
+	}, 5*time.Second, 10*time.Millisecond)
+}
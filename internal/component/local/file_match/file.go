@@ -2,9 +2,14 @@ package file_match
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/bmatcuk/doublestar"
+	"github.com/fsnotify/fsnotify"
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/component/discovery"
 	"github.com/grafana/agent/internal/featuregate"
@@ -26,8 +31,12 @@ func init() {
 // Arguments holds values which are used to configure the local.file_match
 // component.
 type Arguments struct {
-	PathTargets []discovery.Target `river:"path_targets,attr"`
-	SyncPeriod  time.Duration      `river:"sync_period,attr,optional"`
+	PathTargets   []discovery.Target `river:"path_targets,attr"`
+	SyncPeriod    time.Duration      `river:"sync_period,attr,optional"`
+	Watch         bool               `river:"watch,attr,optional"`
+	WatchDebounce time.Duration      `river:"watch_debounce,attr,optional"`
+	MaxDepth      int                `river:"max_depth,attr,optional"`
+	Exclude       []string           `river:"exclude,attr,optional"`
 }
 
 var _ component.Component = (*Component)(nil)
@@ -40,6 +49,11 @@ type Component struct {
 	args     Arguments
 	watches  []watch
 	watchDog *time.Ticker
+	updated  chan struct{}
+
+	// rescanNeeded is true if the paths of the watches must be found by a full
+	// rescan instead of being updated by filesystem events.
+	rescanNeeded bool
 }
 
 // New creates a new local.file_match component.
@@ -50,6 +64,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		args:     args,
 		watches:  make([]watch, 0),
 		watchDog: time.NewTicker(args.SyncPeriod),
+		updated:  make(chan struct{}, 1),
 	}
 
 	if err := c.Update(args); err != nil {
@@ -59,7 +74,10 @@ func New(o component.Options, args Arguments) (*Component, error) {
 }
 
 func getDefault() Arguments {
-	return Arguments{SyncPeriod: 10 * time.Second}
+	return Arguments{
+		SyncPeriod:    10 * time.Second,
+		WatchDebounce: time.Second,
+	}
 }
 
 // SetToDefault implements river.Defaulter.
@@ -67,51 +85,176 @@ func (a *Arguments) SetToDefault() {
 	*a = getDefault()
 }
 
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.SyncPeriod <= 0 {
+		return fmt.Errorf("sync_period must be greater than 0")
+	}
+	if a.WatchDebounce < 0 {
+		return fmt.Errorf("watch_debounce must not be negative")
+	}
+	if a.MaxDepth < 0 {
+		return fmt.Errorf("max_depth must not be negative")
+	}
+	for _, exclude := range a.Exclude {
+		if _, err := doublestar.Match(exclude, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", exclude, err)
+		}
+	}
+	return nil
+}
+
 // Update satisfies the component interface.
 func (c *Component) Update(args component.Arguments) error {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
 	// Check to see if our ticker timer needs to be reset.
-	if args.(Arguments).SyncPeriod != c.args.SyncPeriod {
-		c.watchDog.Reset(c.args.SyncPeriod)
+	newArgs := args.(Arguments)
+	if newArgs.SyncPeriod != c.args.SyncPeriod {
+		c.watchDog.Reset(newArgs.SyncPeriod)
 	}
-	c.args = args.(Arguments)
+	c.args = newArgs
 	c.watches = c.watches[:0]
 	for _, v := range c.args.PathTargets {
 		c.watches = append(c.watches, watch{
-			target: v,
-			log:    c.opts.Logger,
+			target:   v,
+			exclude:  c.args.Exclude,
+			maxDepth: c.args.MaxDepth,
+			log:      c.opts.Logger,
+			paths:    make(map[string]discovery.Target),
 		})
 	}
+	c.rescanNeeded = true
 
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
 	return nil
 }
 
 // Run satisfies the component interface.
+//
+// Paths are found by a full rescan every sync_period. When watch is true,
+// paths are also updated from filesystem events in between rescans, and the
+// exports are updated once no event happened for watch_debounce.
 func (c *Component) Run(ctx context.Context) error {
-	update := func() {
+	n := newNotifier(c.opts.Logger)
+	defer n.close()
+
+	var debounce <-chan time.Time
+
+	rescan := func() {
 		c.mut.Lock()
 		defer c.mut.Unlock()
 
-		paths := c.getWatchedFiles()
-		// The component node checks to see if exports have actually changed.
-		c.opts.OnStateChange(discovery.Exports{Targets: paths})
+		for i := range c.watches {
+			w := &c.watches[i]
+			if err := w.rescan(); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "error getting paths", "path", w.getPath(), "excluded", w.getExcludePath(), "err", err)
+			}
+		}
+		c.rescanNeeded = false
+
+		dirs := make(map[string]struct{})
+		if c.args.Watch {
+			for i := range c.watches {
+				c.watches[i].dirs(func(dir string) { dirs[dir] = struct{}{} })
+			}
+		}
+		n.sync(c.args.Watch, dirs)
+		c.export()
 	}
 	// Trigger initial check
-	update()
+	rescan()
 	defer c.watchDog.Stop()
 	for {
 		select {
 		case <-c.watchDog.C:
 			// This triggers a check for any new paths, along with pushing new targets.
-			update()
+			rescan()
+		case <-c.updated:
+			rescan()
+		case ev := <-n.events():
+			c.mut.Lock()
+			changed := c.handleEvent(ev)
+			debounceDuration := c.args.WatchDebounce
+			c.mut.Unlock()
+
+			if changed && debounce == nil {
+				debounce = time.After(debounceDuration)
+			}
+		case err := <-n.errors():
+			// Events may have been dropped, so look for changes with a full rescan.
+			level.Warn(c.opts.Logger).Log("msg", "filesystem watcher error; rescanning paths", "err", err)
+			rescan()
+		case <-debounce:
+			debounce = nil
+
+			c.mut.Lock()
+			rescanNeeded := c.rescanNeeded
+			if !rescanNeeded {
+				c.export()
+			}
+			c.mut.Unlock()
+
+			if rescanNeeded {
+				rescan()
+			}
 		case <-ctx.Done():
 			return nil
 		}
 	}
 }
 
+// handleEvent updates the paths of the watches from a filesystem event, and
+// returns true if the exports may have changed. New directories require a
+// full rescan, since files may be created in them before they're watched.
+// mut must be held when calling handleEvent.
+func (c *Component) handleEvent(ev fsnotify.Event) bool {
+	switch {
+	case ev.Has(fsnotify.Create):
+		fi, err := os.Stat(ev.Name)
+		if err != nil {
+			// The path was removed since the event.
+			return false
+		}
+		if fi.IsDir() {
+			c.rescanNeeded = true
+			return true
+		}
+		for i := range c.watches {
+			c.watches[i].add(ev.Name)
+		}
+		return true
+	case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+		for i := range c.watches {
+			c.watches[i].remove(ev.Name)
+		}
+		return true
+	default:
+		// Writes to watched files don't change the set of paths.
+		return false
+	}
+}
+
+// export updates the exports of the component with the current paths of the
+// watches. mut must be held when calling export.
+func (c *Component) export() {
+	paths := make([]discovery.Target, 0)
+	for _, w := range c.watches {
+		start := len(paths)
+		for _, t := range w.paths {
+			paths = append(paths, t)
+		}
+		added := paths[start:]
+		sort.Slice(added, func(i, j int) bool { return added[i]["__path__"] < added[j]["__path__"] })
+	}
+	// The component node checks to see if exports have actually changed.
+	c.opts.OnStateChange(discovery.Exports{Targets: paths})
+}
+
 func (c *Component) getWatchedFiles() []discovery.Target {
 	paths := make([]discovery.Target, 0)
 	// See if there is anything new we need to check.
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -238,6 +239,86 @@ func TestMultiLabels(t *testing.T) {
 	require.True(t, contains([]discovery.Target{foundFiles[1]}, "t1.txt"))
 }
 
+func TestMaxDepthAndExclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "t1.txt")
+	writeFile(t, dir, "t1.tmp.txt")
+	require.NoError(t, os.MkdirAll(path.Join(dir, "a", "b"), 0755))
+	writeFile(t, path.Join(dir, "a"), "t2.txt")
+	writeFile(t, path.Join(dir, "a", "b"), "t3.txt")
+
+	c := createComponent(t, dir, []string{path.Join(dir, "**", "*.txt")}, nil)
+	require.NoError(t, c.Update(Arguments{
+		PathTargets: c.args.PathTargets,
+		SyncPeriod:  time.Second,
+		MaxDepth:    2,
+		Exclude:     []string{path.Join(dir, "**", "*.tmp.txt")},
+	}))
+	foundFiles := c.getWatchedFiles()
+	require.Len(t, foundFiles, 2)
+	require.True(t, contains(foundFiles, "t1.txt"))
+	require.True(t, contains(foundFiles, "t2.txt"))
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "t1.txt")
+
+	var (
+		mut     sync.Mutex
+		exports []discovery.Target
+	)
+	c, err := New(component.Options{
+		ID:     "test",
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			mut.Lock()
+			defer mut.Unlock()
+			exports = e.(discovery.Exports).Targets
+		},
+	}, Arguments{
+		PathTargets:   []discovery.Target{{"__path__": path.Join(dir, "**", "*.txt")}},
+		SyncPeriod:    time.Hour,
+		Watch:         true,
+		WatchDebounce: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	found := func(names ...string) func() bool {
+		return func() bool {
+			mut.Lock()
+			defer mut.Unlock()
+			if len(exports) != len(names) {
+				return false
+			}
+			for _, name := range names {
+				if !contains(exports, name) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	require.Eventually(t, found("t1.txt"), 5*time.Second, 10*time.Millisecond)
+
+	// With a sync_period of an hour, changes can only be found from events.
+	writeFile(t, dir, "t2.txt")
+	require.Eventually(t, found("t1.txt", "t2.txt"), 5*time.Second, 10*time.Millisecond)
+
+	subdir := path.Join(dir, "subdir")
+	require.NoError(t, os.Mkdir(subdir, 0755))
+	writeFile(t, subdir, "t3.txt")
+	require.Eventually(t, found("t1.txt", "t2.txt", "t3.txt"), 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.RemoveAll(subdir))
+	require.NoError(t, os.Remove(path.Join(dir, "t1.txt")))
+	require.Eventually(t, found("t2.txt"), 5*time.Second, 10*time.Millisecond)
+}
+
 // createComponent creates a component with the given paths and labels. The paths and excluded slices are zipped together
 // to create the set of targets to pass to the component.
 func createComponent(t *testing.T, dir string, paths []string, excluded []string) *Component {
@@ -0,0 +1,79 @@
+package file_match
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+// notifier watches directories for filesystem events. fsnotify doesn't watch
+// directories recursively, so every directory which may hold matching files
+// is watched.
+type notifier struct {
+	log     log.Logger
+	watcher *fsnotify.Watcher // nil when watching is disabled.
+	dirs    map[string]struct{}
+}
+
+func newNotifier(l log.Logger) *notifier {
+	return &notifier{log: l, dirs: make(map[string]struct{})}
+}
+
+// sync watches dirs and stops watching any other directory. If enabled is
+// false, all watches are removed.
+func (n *notifier) sync(enabled bool, dirs map[string]struct{}) {
+	if !enabled {
+		n.close()
+		return
+	}
+	if n.watcher == nil {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			level.Warn(n.log).Log("msg", "failed to create filesystem watcher; falling back to polling", "err", err)
+			return
+		}
+		n.watcher = w
+	}
+
+	for dir := range n.dirs {
+		if _, ok := dirs[dir]; !ok {
+			// Watches of deleted directories are already removed.
+			_ = n.watcher.Remove(dir)
+			delete(n.dirs, dir)
+		}
+	}
+	for dir := range dirs {
+		// Directories are added again even if they're already watched, in case
+		// they were deleted and recreated since the previous sync.
+		if err := n.watcher.Add(dir); err != nil {
+			level.Warn(n.log).Log("msg", "failed to watch directory; changes will be found by polling", "dir", dir, "err", err)
+			continue
+		}
+		n.dirs[dir] = struct{}{}
+	}
+}
+
+// events returns the channel of filesystem events, or nil if watching is
+// disabled.
+func (n *notifier) events() <-chan fsnotify.Event {
+	if n.watcher == nil {
+		return nil
+	}
+	return n.watcher.Events
+}
+
+// errors returns the channel of watch errors, or nil if watching is disabled.
+func (n *notifier) errors() <-chan error {
+	if n.watcher == nil {
+		return nil
+	}
+	return n.watcher.Errors
+}
+
+func (n *notifier) close() {
+	if n.watcher != nil {
+		_ = n.watcher.Close()
+		n.watcher = nil
+	}
+	n.dirs = make(map[string]struct{})
+}
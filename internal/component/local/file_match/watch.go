@@ -1,8 +1,10 @@
 package file_match
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-kit/log"
 
@@ -13,8 +15,14 @@ import (
 
 // watch handles a single discovery.target for file watching.
 type watch struct {
-	target discovery.Target
-	log    log.Logger
+	target   discovery.Target
+	exclude  []string
+	maxDepth int
+	log      log.Logger
+
+	// paths holds the targets currently matched by the watch, by path. It's
+	// rebuilt by full rescans and updated by filesystem events in between.
+	paths map[string]discovery.Target
 }
 
 func (w *watch) getPaths() ([]discovery.Target, error) {
@@ -24,18 +32,18 @@ func (w *watch) getPaths() ([]discovery.Target, error) {
 	if err != nil {
 		return nil, err
 	}
-	exclude := w.getExcludePath()
 	for _, m := range matches {
-		if exclude != "" {
-			if match, _ := doublestar.PathMatch(exclude, m); match {
-				continue
-			}
+		if w.excluded(m) {
+			continue
 		}
 		abs, err := filepath.Abs(m)
 		if err != nil {
 			level.Error(w.log).Log("msg", "error getting absolute path", "path", m, "err", err)
 			continue
 		}
+		if w.tooDeep(abs) {
+			continue
+		}
 		fi, err := os.Stat(abs)
 		if err != nil {
 			level.Error(w.log).Log("msg", "error getting os stat", "path", abs, "err", err)
@@ -44,17 +52,122 @@ func (w *watch) getPaths() ([]discovery.Target, error) {
 		if fi.IsDir() {
 			continue
 		}
-		dt := discovery.Target{}
-		for dk, v := range w.target {
-			dt[dk] = v
-		}
-		dt["__path__"] = abs
-		allMatchingPaths = append(allMatchingPaths, dt)
+		allMatchingPaths = append(allMatchingPaths, w.newTarget(abs))
 	}
 
 	return allMatchingPaths, nil
 }
 
+// rescan replaces the paths of the watch with the paths currently matching
+// its pattern.
+func (w *watch) rescan() error {
+	targets, err := w.getPaths()
+	w.paths = make(map[string]discovery.Target, len(targets))
+	for _, t := range targets {
+		w.paths[t["__path__"]] = t
+	}
+	return err
+}
+
+// add adds the file at the absolute path p if it matches the watch.
+func (w *watch) add(p string) {
+	match, _ := doublestar.PathMatch(absPattern(w.getPath()), p)
+	if !match || w.excluded(p) || w.tooDeep(p) {
+		return
+	}
+	w.paths[p] = w.newTarget(p)
+}
+
+// remove removes the absolute path p, and any path under p if p was a
+// directory.
+func (w *watch) remove(p string) {
+	delete(w.paths, p)
+	prefix := p + string(filepath.Separator)
+	for path := range w.paths {
+		if strings.HasPrefix(path, prefix) {
+			delete(w.paths, path)
+		}
+	}
+}
+
+func (w *watch) newTarget(abs string) discovery.Target {
+	dt := discovery.Target{}
+	for dk, v := range w.target {
+		dt[dk] = v
+	}
+	dt["__path__"] = abs
+	return dt
+}
+
+// excluded returns true if p matches the exclude pattern of the target or one
+// of the exclude patterns of the component.
+func (w *watch) excluded(p string) bool {
+	abs := filepath.IsAbs(p)
+	if exclude := w.getExcludePath(); exclude != "" {
+		if abs {
+			exclude = absPattern(exclude)
+		}
+		if match, _ := doublestar.PathMatch(exclude, p); match {
+			return true
+		}
+	}
+	for _, exclude := range w.exclude {
+		if abs {
+			exclude = absPattern(exclude)
+		}
+		if match, _ := doublestar.PathMatch(exclude, p); match {
+			return true
+		}
+	}
+	return false
+}
+
+// tooDeep returns true if the absolute path p is more than maxDepth levels
+// below the base directory of the pattern.
+func (w *watch) tooDeep(p string) bool {
+	if w.maxDepth <= 0 {
+		return false
+	}
+	rel, err := filepath.Rel(baseDir(absPattern(w.getPath())), p)
+	if err != nil {
+		return false
+	}
+	return len(strings.Split(rel, string(filepath.Separator))) > w.maxDepth
+}
+
+// dirs calls fn for every directory which may hold files matching the watch.
+func (w *watch) dirs(fn func(dir string)) {
+	pattern := absPattern(w.getPath())
+	base := baseDir(pattern)
+
+	// Files matched by the watch are at most maxDepth levels below the base
+	// directory, and so are in directories at most maxDepth-1 levels below it.
+	// Patterns without ** also limit how deep files can be.
+	limit := -1
+	if !strings.Contains(pattern, "**") {
+		rel, _ := filepath.Rel(base, pattern)
+		limit = len(strings.Split(rel, string(filepath.Separator))) - 1
+	}
+	if w.maxDepth > 0 && (limit < 0 || w.maxDepth-1 < limit) {
+		limit = w.maxDepth - 1
+	}
+
+	_ = filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			// Skip unreadable directories instead of failing the walk.
+			return nil
+		}
+		if limit >= 0 && p != base {
+			rel, _ := filepath.Rel(base, p)
+			if len(strings.Split(rel, string(filepath.Separator))) > limit {
+				return filepath.SkipDir
+			}
+		}
+		fn(p)
+		return nil
+	})
+}
+
 func (w *watch) getPath() string {
 	return w.target["__path__"]
 }
@@ -62,3 +175,25 @@ func (w *watch) getPath() string {
 func (w *watch) getExcludePath() string {
 	return w.target["__path_exclude__"]
 }
+
+// absPattern returns the absolute form of the glob pattern p. Absolute
+// patterns are returned unchanged.
+func absPattern(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+// baseDir returns the deepest directory of the pattern p which doesn't
+// contain wildcards.
+func baseDir(p string) string {
+	dir := filepath.Dir(p)
+	for strings.ContainsAny(dir, "*?[{") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component/common/geoip"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/jmespath/go-jmespath"
 	"github.com/oschwald/geoip2-golang"
@@ -60,6 +61,12 @@ type GeoIPConfig struct {
 	Source        *string           `river:"source,attr"`
 	DBType        string            `river:"db_type,attr,optional"`
 	CustomLookups map[string]string `river:"custom_lookups,attr,optional"`
+	MaxCacheSize  int               `river:"max_cache_size,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (c *GeoIPConfig) SetToDefault() {
+	*c = GeoIPConfig{MaxCacheSize: 10_000}
 }
 
 func validateGeoIPConfig(c GeoIPConfig) (map[string]*jmespath.JMESPath, error) {
@@ -69,6 +76,9 @@ func validateGeoIPConfig(c GeoIPConfig) (map[string]*jmespath.JMESPath, error) {
 	if c.Source != nil && *c.Source == "" {
 		return nil, ErrEmptySourceGeoIPStageConfig
 	}
+	if c.MaxCacheSize < 0 {
+		return nil, fmt.Errorf("max_cache_size must not be negative, got %d", c.MaxCacheSize)
+	}
 
 	if c.DBType == "" && c.CustomLookups == nil {
 		return nil, ErrEmptyDBTypeAndValuesGeoIPStageConfig
@@ -108,22 +118,29 @@ func newGeoIPStage(logger log.Logger, config GeoIPConfig) (Stage, error) {
 		return nil, err
 	}
 
-	mmdb, err := maxminddb.Open(config.DB)
-	if err != nil {
-		return nil, err
-	}
-
-	return &geoIPStage{
-		mmdb:              mmdb,
+	g := &geoIPStage{
 		logger:            logger,
 		cfgs:              config,
 		valuesExpressions: valuesExpressions,
-	}, nil
+	}
+
+	// The database is reloaded when its file changes, and the fields
+	// found for each IP address are cached until then.
+	g.db, err = geoip.Open(geoip.Options{
+		Logger:    logger,
+		Path:      config.DB,
+		CacheSize: config.MaxCacheSize,
+		Decode:    g.lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
 }
 
 type geoIPStage struct {
 	logger            log.Logger
-	mmdb              *maxminddb.Reader
+	db                *geoip.Database
 	cfgs              GeoIPConfig
 	valuesExpressions map[string]*jmespath.JMESPath
 }
@@ -170,43 +187,53 @@ func (g *geoIPStage) process(_ model.LabelSet, extracted map[string]interface{})
 			return
 		}
 	}
+
+	res, err := g.db.Lookup(ip)
+	if err != nil {
+		level.Error(g.logger).Log("msg", "unable to get record for the ip", "err", err, "ip", ip)
+		return
+	}
+	for k, v := range res.(map[string]interface{}) {
+		extracted[k] = v
+	}
+}
+
+// lookup returns the fields to extract for ip. It's used to decode the records
+// of the database, so that the fields are cached.
+func (g *geoIPStage) lookup(mmdb *maxminddb.Reader, ip net.IP) (any, error) {
+	fields := map[string]interface{}{}
 	if g.cfgs.DBType != "" {
 		switch g.cfgs.DBType {
 		case "city":
 			var record geoip2.City
-			err := g.mmdb.Lookup(ip, &record)
-			if err != nil {
-				level.Error(g.logger).Log("msg", "unable to get City record for the ip", "err", err, "ip", ip)
-				return
+			if err := mmdb.Lookup(ip, &record); err != nil {
+				return nil, fmt.Errorf("unable to get City record: %w", err)
 			}
-			g.populateExtractedWithCityData(extracted, &record)
+			g.populateExtractedWithCityData(fields, &record)
 		case "asn":
 			var record geoip2.ASN
-			err := g.mmdb.Lookup(ip, &record)
-			if err != nil {
-				level.Error(g.logger).Log("msg", "unable to get ASN record for the ip", "err", err, "ip", ip)
-				return
+			if err := mmdb.Lookup(ip, &record); err != nil {
+				return nil, fmt.Errorf("unable to get ASN record: %w", err)
 			}
-			g.populateExtractedWithASNData(extracted, &record)
+			g.populateExtractedWithASNData(fields, &record)
 		case "country":
 			var record geoip2.Country
-			err := g.mmdb.Lookup(ip, &record)
-			if err != nil {
-				level.Error(g.logger).Log("msg", "unable to get Country record for the ip", "err", err, "ip", ip)
-				return
+			if err := mmdb.Lookup(ip, &record); err != nil {
+				return nil, fmt.Errorf("unable to get Country record: %w", err)
 			}
-			g.populateExtractedWithCountryData(extracted, &record)
+			g.populateExtractedWithCountryData(fields, &record)
 		default:
 			level.Error(g.logger).Log("msg", "unknown database type")
 		}
 	}
 	if g.valuesExpressions != nil {
-		g.populateExtractedWithCustomFields(ip, extracted)
+		g.populateExtractedWithCustomFields(mmdb, ip, fields)
 	}
+	return fields, nil
 }
 
 func (g *geoIPStage) close() {
-	if err := g.mmdb.Close(); err != nil {
+	if err := g.db.Close(); err != nil {
 		level.Error(g.logger).Log("msg", "error while closing mmdb", "err", err)
 	}
 }
@@ -319,9 +346,9 @@ func (g *geoIPStage) populateExtractedWithCountryData(extracted map[string]inter
 	}
 }
 
-func (g *geoIPStage) populateExtractedWithCustomFields(ip net.IP, extracted map[string]interface{}) {
+func (g *geoIPStage) populateExtractedWithCustomFields(mmdb *maxminddb.Reader, ip net.IP, extracted map[string]interface{}) {
 	var record any
-	if err := g.mmdb.Lookup(ip, &record); err != nil {
+	if err := mmdb.Lookup(ip, &record); err != nil {
 		level.Error(g.logger).Log("msg", "unable to lookup record for the ip", "err", err, "ip", ip)
 		return
 	}
@@ -130,7 +130,6 @@ func Test_MaxmindAsn(t *testing.T) {
 		t.Errorf("Error validating test-config: %v", err)
 	}
 	testStage := &geoIPStage{
-		mmdb:              mmdb,
 		logger:            util_log.Logger,
 		valuesExpressions: valuesExpressions,
 		cfgs:              config,
@@ -174,7 +173,6 @@ func Test_MaxmindCity(t *testing.T) {
 		t.Errorf("Error validating test-config: %v", err)
 	}
 	testStage := &geoIPStage{
-		mmdb:              mmdb,
 		logger:            util_log.Logger,
 		valuesExpressions: valuesExpressions,
 		cfgs:              config,
@@ -228,7 +226,6 @@ func Test_MaxmindCountry(t *testing.T) {
 		t.Errorf("Error validating test-config: %v", err)
 	}
 	testStage := &geoIPStage{
-		mmdb:              mmdb,
 		logger:            util_log.Logger,
 		valuesExpressions: valuesExpressions,
 		cfgs:              config,
@@ -249,3 +246,25 @@ func Test_MaxmindCountry(t *testing.T) {
 		}
 	}
 }
+
+func Test_GeoIPStageProcess(t *testing.T) {
+	config := GeoIPConfig{
+		DB:           "testdata/geoip_maxmind_asn.mmdb",
+		Source:       &geoipTestSource,
+		DBType:       "asn",
+		MaxCacheSize: 10,
+	}
+	stage, err := newGeoIPStage(util_log.Logger, config)
+	require.NoError(t, err)
+	testStage := stage.(*geoIPStage)
+	defer testStage.close()
+
+	// Lookups of the same IP are served from the cache, and give the same
+	// fields.
+	for i := 0; i < 2; i++ {
+		extracted := map[string]interface{}{geoipTestSource: geoipTestIP}
+		testStage.process(nil, extracted)
+		require.Contains(t, extracted, fields[ASN])
+		require.Contains(t, extracted, fields[ASNORG])
+	}
+}
@@ -10,6 +10,7 @@ import (
 	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/drops"
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/model/labels"
@@ -62,6 +63,7 @@ type Exports struct {
 type Component struct {
 	opts    component.Options
 	metrics *metrics
+	dropped *drops.Recorder
 
 	mut      sync.RWMutex
 	rcs      []*relabel.Config
@@ -86,6 +88,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 	c := &Component{
 		opts:         o,
 		metrics:      newMetrics(o.Registerer),
+		dropped:      drops.NewRecorder(o, drops.SignalLogs),
 		cache:        cache,
 		maxCacheSize: args.MaxCacheSize,
 	}
@@ -114,6 +117,7 @@ func (c *Component) Run(ctx context.Context) error {
 			lbls := c.relabel(entry)
 			if len(lbls) == 0 {
 				level.Debug(c.opts.Logger).Log("msg", "dropping entry after relabeling", "labels", entry.Labels.String())
+				c.dropped.Add(drops.ReasonRelabel, 1)
 				continue
 			}
 
@@ -0,0 +1,40 @@
+package route
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	entriesProcessed prometheus.Counter
+	entriesRouted    *prometheus.CounterVec
+	entriesDropped   prometheus.Counter
+}
+
+// newMetrics creates a new set of metrics. If reg is non-nil, the metrics
+// will also be registered.
+func newMetrics(reg prometheus.Registerer) *metrics {
+	var m metrics
+
+	m.entriesProcessed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_route_entries_processed",
+		Help: "Total number of log entries processed",
+	})
+	m.entriesRouted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_route_entries_routed",
+		Help: "Total number of log entries forwarded by each route",
+	}, []string{"route"})
+	m.entriesDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_route_entries_dropped",
+		Help: "Total number of log entries dropped because they didn't match any route",
+	})
+
+	if reg != nil {
+		reg.MustRegister(
+			m.entriesProcessed,
+			m.entriesRouted,
+			m.entriesDropped,
+		)
+	}
+
+	return &m
+}
@@ -0,0 +1,236 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/drops"
+	"github.com/grafana/loki/clients/pkg/logentry/logql"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.route",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// defaultRouteName is the name used in metrics for entries which didn't
+// match any route.
+const defaultRouteName = "default"
+
+// Arguments holds values which are used to configure the loki.route
+// component.
+type Arguments struct {
+	// The routes to evaluate for each log entry, in order.
+	Routes []RouteArguments `river:"route,block,optional"`
+
+	// Where log entries which don't match any route should be forwarded to.
+	DefaultForwardTo []loki.LogsReceiver `river:"default_forward_to,attr,optional"`
+}
+
+// RouteArguments configures a single route of the loki.route component.
+type RouteArguments struct {
+	Name      string              `river:"name,attr"`
+	Selector  string              `river:"selector,attr"`
+	ForwardTo []loki.LogsReceiver `river:"forward_to,attr"`
+	Continue  bool                `river:"continue,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	names := make(map[string]struct{}, len(a.Routes))
+	for _, r := range a.Routes {
+		if r.Name == "" {
+			return fmt.Errorf("route name must not be empty")
+		}
+		if r.Name == defaultRouteName {
+			return fmt.Errorf("route name %q is reserved for the default route", defaultRouteName)
+		}
+		if _, ok := names[r.Name]; ok {
+			return fmt.Errorf("found duplicate route name %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if _, err := logql.ParseExpr(r.Selector); err != nil {
+			return fmt.Errorf("invalid selector for route %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the loki.route component.
+type Exports struct {
+	Receiver loki.LogsReceiver `river:"receiver,attr"`
+}
+
+// route is a compiled route.
+type route struct {
+	name      string
+	matchers  []*labels.Matcher
+	filter    logql.Filter
+	forwardTo []loki.LogsReceiver
+	cont      bool
+}
+
+func newRoute(args RouteArguments) (*route, error) {
+	selector, err := logql.ParseExpr(args.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector for route %q: %w", args.Name, err)
+	}
+	filter, err := selector.Filter()
+	if err != nil {
+		return nil, fmt.Errorf("invalid line filter for route %q: %w", args.Name, err)
+	}
+	return &route{
+		name:      args.Name,
+		matchers:  selector.Matchers(),
+		filter:    filter,
+		forwardTo: args.ForwardTo,
+		cont:      args.Continue,
+	}, nil
+}
+
+// Matches returns true if the entry matches the selector of the route.
+func (r *route) Matches(e loki.Entry) bool {
+	for _, m := range r.matchers {
+		if !m.Matches(string(e.Labels[model.LabelName(m.Name)])) {
+			return false
+		}
+	}
+	return r.filter == nil || r.filter([]byte(e.Line))
+}
+
+// Component implements the loki.route component.
+type Component struct {
+	opts     component.Options
+	metrics  *metrics
+	dropped  *drops.Recorder
+	receiver loki.LogsReceiver
+
+	mut              sync.RWMutex
+	routes           []*route
+	defaultForwardTo []loki.LogsReceiver
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new loki.route component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+		dropped: drops.NewRecorder(o, drops.SignalLogs),
+	}
+
+	// Create and immediately export the receiver which remains the same for
+	// the component's lifetime.
+	c.receiver = loki.NewLogsReceiver()
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			c.metrics.entriesProcessed.Inc()
+			if !c.route(ctx, entry) {
+				return nil
+			}
+		}
+	}
+}
+
+// route forwards entry to the receivers of every matching route, or to the
+// default receivers if no route matches. It returns false if ctx is canceled
+// while forwarding.
+func (c *Component) route(ctx context.Context, entry loki.Entry) bool {
+	// The lock isn't held while forwarding, so that a blocked receiver doesn't
+	// block Update.
+	c.mut.RLock()
+	var (
+		routes           = c.routes
+		defaultForwardTo = c.defaultForwardTo
+	)
+	c.mut.RUnlock()
+
+	matched := false
+	for _, r := range routes {
+		if !r.Matches(entry) {
+			continue
+		}
+		matched = true
+		c.metrics.entriesRouted.WithLabelValues(r.name).Inc()
+		if !forward(ctx, r.forwardTo, entry) {
+			return false
+		}
+		if !r.cont {
+			return true
+		}
+	}
+	if matched {
+		return true
+	}
+
+	if len(defaultForwardTo) == 0 {
+		level.Debug(c.opts.Logger).Log("msg", "dropping entry which didn't match any route", "labels", entry.Labels.String())
+		c.metrics.entriesDropped.Inc()
+		c.dropped.Add(drops.ReasonNoRoute, 1)
+		return true
+	}
+	c.metrics.entriesRouted.WithLabelValues(defaultRouteName).Inc()
+	return forward(ctx, defaultForwardTo, entry)
+}
+
+func forward(ctx context.Context, receivers []loki.LogsReceiver, entry loki.Entry) bool {
+	for _, f := range receivers {
+		select {
+		case <-ctx.Done():
+			return false
+		case f.Chan() <- entry:
+		}
+	}
+	return true
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	routes := make([]*route, 0, len(newArgs.Routes))
+	for _, ra := range newArgs.Routes {
+		r, err := newRoute(ra)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, r)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.routes = routes
+	c.defaultForwardTo = newArgs.DefaultForwardTo
+	return nil
+}
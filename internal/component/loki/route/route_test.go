@@ -0,0 +1,154 @@
+package route
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouting(t *testing.T) {
+	prod, errors, fallback := loki.NewLogsReceiver(), loki.NewLogsReceiver(), loki.NewLogsReceiver()
+
+	reg := prometheus.NewRegistry()
+	opts := component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    reg,
+		OnStateChange: func(e component.Exports) {},
+	}
+	args := Arguments{
+		Routes: []RouteArguments{
+			{Name: "errors", Selector: `{env=~".+"} |= "error"`, ForwardTo: []loki.LogsReceiver{errors}, Continue: true},
+			{Name: "prod", Selector: `{env="prod"}`, ForwardTo: []loki.LogsReceiver{prod}},
+		},
+		DefaultForwardTo: []loki.LogsReceiver{fallback},
+	}
+
+	c, err := New(opts, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	send := func(env, line string) {
+		c.receiver.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"env": model.LabelValue(env)},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+		}
+	}
+	expect := func(r loki.LogsReceiver, line string) {
+		select {
+		case e := <-r.Chan():
+			require.Equal(t, line, e.Line)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for log line", line)
+		}
+	}
+
+	// Matches both routes since the first route continues.
+	go send("prod", "an error happened")
+	expect(errors, "an error happened")
+	expect(prod, "an error happened")
+
+	// Only matches the prod route.
+	go send("prod", "all good")
+	expect(prod, "all good")
+
+	// Doesn't match any route.
+	go send("dev", "all good")
+	expect(fallback, "all good")
+
+	require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.entriesRouted.WithLabelValues("errors")))
+	require.Equal(t, 2.0, testutil.ToFloat64(c.metrics.entriesRouted.WithLabelValues("prod")))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.metrics.entriesRouted.WithLabelValues(defaultRouteName)))
+}
+
+func TestUpdate_BlockedReceiver(t *testing.T) {
+	blocked := loki.NewLogsReceiver()
+
+	opts := component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}
+	c, err := New(opts, Arguments{DefaultForwardTo: []loki.LogsReceiver{blocked}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	// Nothing reads from blocked, so Run blocks forwarding the entry.
+	c.receiver.Chan() <- loki.Entry{Labels: model.LabelSet{"env": "prod"}, Entry: logproto.Entry{Line: "stuck"}}
+
+	updated := make(chan error)
+	go func() { updated <- c.Update(Arguments{DefaultForwardTo: []loki.LogsReceiver{loki.NewLogsReceiver()}}) }()
+	select {
+	case err := <-updated:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Update blocked behind a blocked receiver")
+	}
+}
+
+func TestArguments_Validate(t *testing.T) {
+	tt := []struct {
+		name        string
+		cfg         string
+		expectedErr string
+	}{
+		{
+			name: "duplicate names",
+			cfg: `
+				route {
+					name       = "a"
+					selector   = "{env=\"prod\"}"
+					forward_to = []
+				}
+				route {
+					name       = "a"
+					selector   = "{env=\"dev\"}"
+					forward_to = []
+				}`,
+			expectedErr: `found duplicate route name "a"`,
+		},
+		{
+			name: "reserved name",
+			cfg: `
+				route {
+					name       = "default"
+					selector   = "{env=\"prod\"}"
+					forward_to = []
+				}`,
+			expectedErr: `route name "default" is reserved for the default route`,
+		},
+		{
+			name: "invalid selector",
+			cfg: `
+				route {
+					name       = "a"
+					selector   = "env"
+					forward_to = []
+				}`,
+			expectedErr: `invalid selector for route "a"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.cfg), &args)
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
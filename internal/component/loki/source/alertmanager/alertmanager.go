@@ -0,0 +1,264 @@
+// Package alertmanager implements the loki.source.alertmanager component.
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	fnet "github.com/grafana/agent/internal/component/common/net"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.alertmanager",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// webhookPath is the path Alertmanager webhook notifications are received on.
+const webhookPath = "/alertmanager/api/v1/webhook"
+
+// Arguments holds values which are used to configure the
+// loki.source.alertmanager component.
+type Arguments struct {
+	Server    *fnet.ServerConfig  `river:",squash"`
+	ForwardTo []loki.LogsReceiver `river:"forward_to,attr,optional"`
+	Labels    map[string]string   `river:"labels,attr,optional"`
+
+	MetricsForwardTo []storage.Appendable `river:"metrics_forward_to,attr,optional"`
+	MetricsInterval  time.Duration        `river:"metrics_interval,attr,optional"`
+	MetricsExpiry    time.Duration        `river:"metrics_expiry,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		Server:          fnet.DefaultServerConfig(),
+		MetricsInterval: time.Minute,
+		MetricsExpiry:   4 * time.Hour,
+	}
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.MetricsInterval <= 0 {
+		return fmt.Errorf("metrics_interval must be greater than 0")
+	}
+	if args.MetricsExpiry < args.MetricsInterval {
+		return fmt.Errorf("metrics_expiry must not be less than metrics_interval")
+	}
+	return nil
+}
+
+func (args *Arguments) labelSet() model.LabelSet {
+	labelSet := make(model.LabelSet, len(args.Labels))
+	for k, v := range args.Labels {
+		labelSet[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return labelSet
+}
+
+// Component implements the loki.source.alertmanager component.
+type Component struct {
+	opts               component.Options
+	metrics            *metrics
+	uncheckedCollector *util.UncheckedCollector
+	fanout             *agentprom.Fanout
+	alerts             *alertStates
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+
+	mut       sync.RWMutex
+	args      Arguments
+	receivers []loki.LogsReceiver
+	labels    model.LabelSet
+	server    *fnet.TargetServer
+
+	// updated is written to whenever metrics_interval may have changed.
+	updated chan struct{}
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new loki.source.alertmanager component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	data, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:               opts,
+		metrics:            newMetrics(opts.Registerer),
+		uncheckedCollector: util.NewUncheckedCollector(nil),
+		fanout:             agentprom.NewFanout(args.MetricsForwardTo, opts.ID, opts.Registerer, ls),
+		alerts:             newAlertStates(),
+		now:                time.Now,
+		updated:            make(chan struct{}, 1),
+	}
+	opts.Registerer.MustRegister(c.uncheckedCollector)
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+		c.shutdownServer()
+	}()
+
+	for {
+		c.mut.RLock()
+		interval := c.args.MetricsInterval
+		c.mut.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+			c.flushMetrics(ctx)
+		case <-c.updated:
+			// no-op; force the interval to be reread.
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+	c.fanout.UpdateChildren(newArgs.MetricsForwardTo)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.receivers = newArgs.ForwardTo
+	c.labels = newArgs.labelSet()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+
+	if c.server != nil && reflect.DeepEqual(c.args.Server, newArgs.Server) {
+		c.args = newArgs
+		return nil
+	}
+	c.shutdownServer()
+
+	// [fnet.TargetServer] registers new metrics every time it is created. To
+	// avoid issues with re-registering metrics with the same name, we create a
+	// new registry for the server every time we create one, and pass it to an
+	// unchecked collector to bypass uniqueness checking.
+	serverRegistry := prometheus.NewRegistry()
+	c.uncheckedCollector.SetCollector(serverRegistry)
+
+	s, err := fnet.NewTargetServer(c.opts.Logger, "loki_source_alertmanager", serverRegistry, newArgs.Server)
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	err = s.MountAndRun(func(router *mux.Router) {
+		router.Path(webhookPath).Methods("POST").HandlerFunc(c.handleWebhook)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.server = s
+	c.args = newArgs
+	return nil
+}
+
+// shutdownServer shuts down the current server. c.mut must be held when
+// calling.
+func (c *Component) shutdownServer() {
+	if c.server != nil {
+		c.server.StopAndShutdown()
+		c.server = nil
+	}
+}
+
+// flushMetrics writes a sample for every firing alert, and stale markers for
+// the alerts which expired.
+func (c *Component) flushMetrics(ctx context.Context) {
+	c.mut.RLock()
+	expiry := c.args.MetricsExpiry
+	c.mut.RUnlock()
+
+	app := c.fanout.Appender(ctx)
+	if err := c.alerts.flush(app, c.now(), expiry); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to write alert states", "err", err)
+		_ = app.Rollback()
+		return
+	}
+	if err := app.Commit(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to write alert states", "err", err)
+	}
+	c.metrics.firingAlerts.Set(float64(c.alerts.len()))
+}
+
+// DebugInfo returns information about the status of the listener.
+func (c *Component) DebugInfo() interface{} {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	var res debugInfo
+	if c.server != nil {
+		res.Address = c.server.HTTPListenAddr()
+	}
+	res.FiringAlerts = c.alerts.len()
+	return res
+}
+
+type debugInfo struct {
+	Address      string `river:"address,attr"`
+	FiringAlerts int    `river:"firing_alerts,attr"`
+}
+
+type metrics struct {
+	alertsReceived *prometheus.CounterVec
+	firingAlerts   prometheus.Gauge
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		alertsReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loki_source_alertmanager_alerts_received_total",
+			Help: "Total number of alerts received from Alertmanager webhook notifications.",
+		}, []string{"status"}),
+		firingAlerts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "loki_source_alertmanager_firing_alerts",
+			Help: "Number of firing alerts tracked to write alert state series.",
+		}),
+	}
+	if reg != nil {
+		m.alertsReceived = util.MustRegisterOrGet(reg, m.alertsReceived).(*prometheus.CounterVec)
+		m.firingAlerts = util.MustRegisterOrGet(reg, m.firingAlerts).(prometheus.Gauge)
+	}
+	return m
+}
@@ -0,0 +1,173 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/phayes/freeport"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+const testPayload = `{
+	"version": "4",
+	"groupKey": "{}:{alertname=\"HighLatency\"}",
+	"receiver": "agent",
+	"status": "firing",
+	"alerts": [
+		{
+			"status": "firing",
+			"labels": {"alertname": "HighLatency", "severity": "warning", "job": "api"},
+			"annotations": {"summary": "API latency is high"},
+			"startsAt": "2024-01-01T00:00:00Z",
+			"endsAt": "0001-01-01T00:00:00Z",
+			"generatorURL": "http://prometheus:9090/graph",
+			"fingerprint": "c6fe6b6f1b4c1f0d"
+		}
+	]
+}`
+
+func TestUnmarshalRiver(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		forward_to = []
+		labels     = {job = "alertmanager"}
+	`), &args))
+	require.Equal(t, time.Minute, args.MetricsInterval)
+	require.Equal(t, 4*time.Hour, args.MetricsExpiry)
+
+	require.EqualError(t, river.Unmarshal([]byte(`
+		metrics_interval = "5m"
+		metrics_expiry   = "1m"
+	`), &args), "metrics_expiry must not be less than metrics_interval")
+}
+
+type testSeries struct {
+	mut     sync.Mutex
+	samples map[string][]float64
+}
+
+func (s *testSeries) appendable(ls labelstore.LabelStore) storage.Appendable {
+	return prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		s.samples[l.String()] = append(s.samples[l.String()], v)
+		return ref, nil
+	}))
+}
+
+func (s *testSeries) get(name string) []float64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.samples[name]
+}
+
+func TestWebhook(t *testing.T) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	series := &testSeries{samples: map[string][]float64{}}
+	receiver := loki.NewLogsReceiverWithChannel(make(chan loki.Entry, 10))
+
+	ports, err := freeport.GetFreePorts(2)
+	require.NoError(t, err)
+
+	var args Arguments
+	args.SetToDefault()
+	args.Server.HTTP.ListenAddress = "127.0.0.1"
+	args.Server.HTTP.ListenPort = ports[0]
+	args.Server.GRPC.ListenAddress = "127.0.0.1"
+	args.Server.GRPC.ListenPort = ports[1]
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+	args.Labels = map[string]string{"job": "alertmanager"}
+	args.MetricsForwardTo = []storage.Appendable{series.appendable(ls)}
+
+	c, err := New(component.Options{
+		ID:            "loki.source.alertmanager.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prom.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+	defer c.shutdownServer()
+
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	post := func(payload string) {
+		url := fmt.Sprintf("http://%s%s", c.server.HTTPListenAddr(), webhookPath)
+		res, err := http.Post(url, "application/json", strings.NewReader(payload))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+	}
+	post(testPayload)
+
+	entry := <-receiver.Chan()
+	require.Equal(t, model.LabelSet{"job": "alertmanager", "alertname": "HighLatency", "status": "firing"}, entry.Labels)
+	require.True(t, now.Equal(entry.Timestamp))
+	require.JSONEq(t, `{
+		"receiver": "agent",
+		"groupKey": "{}:{alertname=\"HighLatency\"}",
+		"status": "firing",
+		"labels": {"alertname": "HighLatency", "severity": "warning", "job": "api"},
+		"annotations": {"summary": "API latency is high"},
+		"startsAt": "2024-01-01T00:00:00Z",
+		"endsAt": "0001-01-01T00:00:00Z",
+		"generatorURL": "http://prometheus:9090/graph",
+		"fingerprint": "c6fe6b6f1b4c1f0d"
+	}`, entry.Line)
+
+	const seriesName = `{__name__="ALERTS", alertname="HighLatency", alertstate="firing", job="api", severity="warning"}`
+	require.Equal(t, []float64{1}, series.get(seriesName))
+
+	// Firing alerts are written until they're resolved.
+	now = now.Add(time.Minute)
+	c.flushMetrics(context.Background())
+	require.Equal(t, []float64{1, 1}, series.get(seriesName))
+
+	post(strings.ReplaceAll(testPayload, `"firing"`, `"resolved"`))
+	<-receiver.Chan()
+	samples := series.get(seriesName)
+	require.Len(t, samples, 3)
+	require.True(t, value.IsStaleNaN(samples[2]))
+	require.Zero(t, c.alerts.len())
+}
+
+func TestAlertStatesExpiry(t *testing.T) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	series := &testSeries{samples: map[string][]float64{}}
+	app := series.appendable(ls).Appender(context.Background())
+
+	states := newAlertStates()
+	now := time.Unix(1000, 0)
+	require.NoError(t, states.update(app, []alert{{
+		Status: "firing",
+		Labels: map[string]string{"alertname": "Down", "invalid-label": "dropped"},
+	}}, now))
+
+	require.NoError(t, states.flush(app, now.Add(time.Hour), 2*time.Hour))
+	require.NoError(t, states.flush(app, now.Add(3*time.Hour), 2*time.Hour))
+	require.Zero(t, states.len())
+
+	samples := series.get(`{__name__="ALERTS", alertname="Down", alertstate="firing"}`)
+	require.Len(t, samples, 3)
+	require.Equal(t, []float64{1, 1}, samples[:2])
+	require.True(t, value.IsStaleNaN(samples[2]))
+}
@@ -0,0 +1,110 @@
+package alertmanager
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Metric and label names of the alert state series, named after the series of
+// the same shape written by Prometheus.
+const (
+	alertsMetricName = "ALERTS"
+	alertStateLabel  = "alertstate"
+)
+
+// alertStates tracks firing alerts to write an alert state series for each of
+// them until they're resolved.
+type alertStates struct {
+	mut    sync.Mutex
+	firing map[string]*alertState // Keyed by the labels of the series.
+}
+
+type alertState struct {
+	labels   labels.Labels
+	lastSeen time.Time
+}
+
+func newAlertStates() *alertStates {
+	return &alertStates{firing: make(map[string]*alertState)}
+}
+
+func (s *alertStates) len() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return len(s.firing)
+}
+
+// update tracks firing alerts and forgets resolved ones, writing a sample for
+// every firing alert and a stale marker for every resolved alert to app.
+func (s *alertStates) update(app storage.Appender, alerts []alert, now time.Time) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ts := timestamp.FromTime(now)
+	for _, a := range alerts {
+		lset := seriesLabels(a)
+		key := lset.String()
+
+		if a.Status == string(model.AlertResolved) {
+			if _, ok := s.firing[key]; !ok {
+				continue
+			}
+			delete(s.firing, key)
+			if _, err := app.Append(0, lset, ts, math.Float64frombits(value.StaleNaN)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s.firing[key] = &alertState{labels: lset, lastSeen: now}
+		if _, err := app.Append(0, lset, ts, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes a sample for every firing alert to app. Alerts which were last
+// notified more than expiry ago are forgotten and a stale marker is written
+// instead, so that alerts whose resolved notifications were lost don't fire
+// forever.
+func (s *alertStates) flush(app storage.Appender, now time.Time, expiry time.Duration) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ts := timestamp.FromTime(now)
+	for key, state := range s.firing {
+		v := float64(1)
+		if now.Sub(state.lastSeen) > expiry {
+			delete(s.firing, key)
+			v = math.Float64frombits(value.StaleNaN)
+		}
+		if _, err := app.Append(0, state.labels, ts, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seriesLabels returns the labels of the alert state series of a. Labels of a
+// whose names aren't valid label names are dropped.
+func seriesLabels(a alert) labels.Labels {
+	lb := labels.NewScratchBuilder(len(a.Labels) + 2)
+	lb.Add(labels.MetricName, alertsMetricName)
+	lb.Add(alertStateLabel, string(model.AlertFiring))
+	for name, value := range a.Labels {
+		if name == labels.MetricName || name == alertStateLabel || !model.LabelName(name).IsValid() {
+			continue
+		}
+		lb.Add(name, value)
+	}
+	lb.Sort()
+	return lb.Labels()
+}
@@ -0,0 +1,120 @@
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+)
+
+// maxMessageSize is the maximum size of the webhook notifications accepted.
+const maxMessageSize = 10 << 20
+
+// message is a webhook notification sent by Alertmanager.
+type message struct {
+	Version  string  `json:"version"`
+	GroupKey string  `json:"groupKey"`
+	Receiver string  `json:"receiver"`
+	Status   string  `json:"status"`
+	Alerts   []alert `json:"alerts"`
+}
+
+// alert is a single alert of a webhook notification.
+type alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// logLine is the content of the log lines written for each alert.
+type logLine struct {
+	Receiver string `json:"receiver"`
+	GroupKey string `json:"groupKey,omitempty"`
+	alert
+}
+
+func (c *Component) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	var msg message
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMessageSize)).Decode(&msg); err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to decode webhook notification", "err", err)
+		http.Error(w, fmt.Sprintf("failed to decode webhook notification: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	now := c.now()
+	entries := make([]loki.Entry, 0, len(msg.Alerts))
+	for _, a := range msg.Alerts {
+		c.metrics.alertsReceived.WithLabelValues(a.Status).Inc()
+
+		entry, err := c.toEntry(msg, a, now)
+		if err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to convert alert to log entry", "err", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	c.recordAlerts(r, msg.Alerts, now)
+
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	for _, entry := range entries {
+		for _, receiver := range c.receivers {
+			select {
+			case <-r.Context().Done():
+				http.Error(w, "request canceled", http.StatusServiceUnavailable)
+				return
+			case receiver.Chan() <- entry:
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// toEntry converts an alert to a log entry. Entries are labeled with the
+// alertname and status of alerts on top of the configured labels.
+func (c *Component) toEntry(msg message, a alert, now time.Time) (loki.Entry, error) {
+	line, err := json.Marshal(logLine{Receiver: msg.Receiver, GroupKey: msg.GroupKey, alert: a})
+	if err != nil {
+		return loki.Entry{}, err
+	}
+
+	c.mut.RLock()
+	lset := c.labels.Clone()
+	c.mut.RUnlock()
+	if name := a.Labels[model.AlertNameLabel]; name != "" {
+		lset[model.AlertNameLabel] = model.LabelValue(name)
+	}
+	if a.Status != "" {
+		lset["status"] = model.LabelValue(a.Status)
+	}
+
+	return loki.Entry{
+		Labels: lset,
+		Entry:  logproto.Entry{Timestamp: now, Line: string(line)},
+	}, nil
+}
+
+// recordAlerts updates the tracked alert states with alerts, and immediately
+// writes the series of the alerts which changed.
+func (c *Component) recordAlerts(r *http.Request, alerts []alert, now time.Time) {
+	app := c.fanout.Appender(r.Context())
+	if err := c.alerts.update(app, alerts, now); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to write alert states", "err", err)
+		_ = app.Rollback()
+		return
+	}
+	if err := app.Commit(); err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to write alert states", "err", err)
+	}
+	c.metrics.firingAlerts.Set(float64(c.alerts.len()))
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"reflect"
 	"sync"
 
@@ -12,7 +13,9 @@ import (
 	"github.com/grafana/agent/internal/component/common/relabel"
 	"github.com/grafana/agent/internal/component/loki/source/api/internal/lokipush"
 	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/ports"
 	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river/rivertypes"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 )
@@ -22,6 +25,7 @@ func init() {
 		Name:      "loki.source.api",
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
+		Exports:   Exports{},
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
 		},
@@ -34,15 +38,87 @@ type Arguments struct {
 	Labels               map[string]string   `river:"labels,attr,optional"`
 	RelabelRules         relabel.Rules       `river:"relabel_rules,attr,optional"`
 	UseIncomingTimestamp bool                `river:"use_incoming_timestamp,attr,optional"`
+	Tenants              []TenantConfig      `river:"tenant,block,optional"`
+	TenantLabel          string              `river:"tenant_label,attr,optional"`
 }
 
+// Exports holds the values exported by loki.source.api.
+type Exports struct {
+	HTTPListenAddress string `river:"http_listen_address,attr"`
+	GRPCListenAddress string `river:"grpc_listen_address,attr"`
+}
+
+// TenantConfig configures a tenant allowed to push logs to the component.
+type TenantConfig struct {
+	ID             string            `river:"id,attr"`
+	Token          rivertypes.Secret `river:"token,attr"`
+	Labels         map[string]string `river:"labels,attr,optional"`
+	RateLimit      float64           `river:"rate_limit,attr,optional"`
+	RateLimitBurst int               `river:"rate_limit_burst,attr,optional"`
+}
+
+// DefaultTenantLabel is the label holding the ID of the tenant pushing an
+// entry by default. loki.write sends entries with this label to the tenant
+// it holds.
+const DefaultTenantLabel = "__tenant_id__"
+
 // SetToDefault implements river.Defaulter.
 func (a *Arguments) SetToDefault() {
 	*a = Arguments{
-		Server: fnet.DefaultServerConfig(),
+		Server:      fnet.DefaultServerConfig(),
+		TenantLabel: DefaultTenantLabel,
 	}
 }
 
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	var (
+		ids    = make(map[string]struct{}, len(a.Tenants))
+		tokens = make(map[rivertypes.Secret]struct{}, len(a.Tenants))
+	)
+	for _, t := range a.Tenants {
+		switch {
+		case t.ID == "":
+			return fmt.Errorf("tenant id must not be empty")
+		case t.Token == "":
+			return fmt.Errorf("tenant %q: token must not be empty", t.ID)
+		case t.RateLimit < 0:
+			return fmt.Errorf("tenant %q: rate_limit must not be negative", t.ID)
+		case t.RateLimitBurst < 0:
+			return fmt.Errorf("tenant %q: rate_limit_burst must not be negative", t.ID)
+		case t.RateLimitBurst > 0 && t.RateLimit == 0:
+			return fmt.Errorf("tenant %q: rate_limit_burst requires rate_limit to be set", t.ID)
+		}
+		if _, ok := ids[t.ID]; ok {
+			return fmt.Errorf("tenant %q is defined more than once", t.ID)
+		}
+		ids[t.ID] = struct{}{}
+		if _, ok := tokens[t.Token]; ok {
+			return fmt.Errorf("tenant %q: token is shared with another tenant", t.ID)
+		}
+		tokens[t.Token] = struct{}{}
+	}
+	return nil
+}
+
+func (a *Arguments) tenants() []lokipush.Tenant {
+	tenants := make([]lokipush.Tenant, 0, len(a.Tenants))
+	for _, t := range a.Tenants {
+		labels := make(model.LabelSet, len(t.Labels))
+		for k, v := range t.Labels {
+			labels[model.LabelName(k)] = model.LabelValue(v)
+		}
+		tenants = append(tenants, lokipush.Tenant{
+			ID:             t.ID,
+			Token:          string(t.Token),
+			Labels:         labels,
+			RateLimit:      t.RateLimit,
+			RateLimitBurst: t.RateLimitBurst,
+		})
+	}
+	return tenants
+}
+
 func (a *Arguments) labelSet() model.LabelSet {
 	labelSet := make(model.LabelSet, len(a.Labels))
 	for k, v := range a.Labels {
@@ -56,8 +132,9 @@ type Component struct {
 	entriesChan        chan loki.Entry
 	uncheckedCollector *util.UncheckedCollector
 
-	serverMut sync.Mutex
-	server    *lokipush.PushAPIServer
+	serverMut    sync.Mutex
+	server       *lokipush.PushAPIServer
+	serverConfig fnet.ServerConfig // Server config before ports are allocated.
 
 	// Use separate receivers mutex to address potential deadlock when Update drains the current server.
 	// e.g. https://github.com/grafana/agent/issues/3391
@@ -128,7 +205,7 @@ func (c *Component) Update(args component.Arguments) error {
 
 	c.serverMut.Lock()
 	defer c.serverMut.Unlock()
-	serverNeedsRestarting := c.server == nil || !reflect.DeepEqual(c.server.ServerConfig(), *newArgs.Server)
+	serverNeedsRestarting := c.server == nil || !reflect.DeepEqual(c.serverConfig, *newArgs.Server)
 	if serverNeedsRestarting {
 		if c.server != nil {
 			c.server.Shutdown()
@@ -141,8 +218,11 @@ func (c *Component) Update(args component.Arguments) error {
 		serverRegistry := prometheus.NewRegistry()
 		c.uncheckedCollector.SetCollector(serverRegistry)
 
-		var err error
-		c.server, err = lokipush.NewPushAPIServer(c.opts.Logger, newArgs.Server, loki.NewEntryHandler(c.entriesChan, func() {}), serverRegistry)
+		serverConfig, err := c.allocatePorts(newArgs.Server)
+		if err != nil {
+			return fmt.Errorf("failed to allocate ports: %w", err)
+		}
+		c.server, err = lokipush.NewPushAPIServer(c.opts.Logger, serverConfig, loki.NewEntryHandler(c.entriesChan, func() {}), serverRegistry)
 		if err != nil {
 			return fmt.Errorf("failed to create embedded server: %v", err)
 		}
@@ -150,15 +230,38 @@ func (c *Component) Update(args component.Arguments) error {
 		if err != nil {
 			return fmt.Errorf("failed to run embedded server: %v", err)
 		}
+		c.serverConfig = *newArgs.Server
+
+		c.opts.OnStateChange(Exports{
+			HTTPListenAddress: c.server.HTTPListenAddr(),
+			GRPCListenAddress: c.server.GRPCListenAddr(),
+		})
 	}
 
 	c.server.SetLabels(newArgs.labelSet())
 	c.server.SetRelabelRules(newArgs.RelabelRules)
 	c.server.SetKeepTimestamp(newArgs.UseIncomingTimestamp)
+	c.server.SetTenants(newArgs.tenants(), newArgs.TenantLabel)
 
 	return nil
 }
 
+// Handler serves the push API from the HTTP server of the agent, such as for
+// connections routed to the component by the sni blocks of the http block.
+func (c *Component) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.serverMut.Lock()
+		server := c.server
+		c.serverMut.Unlock()
+
+		if server == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		server.Handler().ServeHTTP(w, r)
+	})
+}
+
 func (c *Component) stop() {
 	c.serverMut.Lock()
 	defer c.serverMut.Unlock()
@@ -166,4 +269,33 @@ func (c *Component) stop() {
 		c.server.Shutdown()
 		c.server = nil
 	}
+	if alloc := c.portAllocator(); alloc != nil {
+		alloc.Release(c.opts.ID)
+	}
+}
+
+// allocatePorts allocates the listen ports of config from its listen port
+// ranges. Ports allocated for previous configs stay allocated while config
+// has listen port ranges, so the server keeps its ports across restarts.
+func (c *Component) allocatePorts(config *fnet.ServerConfig) (*fnet.ServerConfig, error) {
+	alloc := c.portAllocator()
+	res, err := config.AllocatePorts(alloc, c.opts.ID)
+	if err == nil && res == config && alloc != nil {
+		alloc.Release(c.opts.ID)
+	}
+	return res, err
+}
+
+// portAllocator returns the allocator of the ports service, or nil if the
+// ports service isn't available.
+func (c *Component) portAllocator() *ports.Allocator {
+	if c.opts.GetServiceData == nil {
+		return nil
+	}
+	data, err := c.opts.GetServiceData(ports.ServiceName)
+	if err != nil {
+		return nil
+	}
+	alloc, _ := data.(*ports.Allocator)
+	return alloc
 }
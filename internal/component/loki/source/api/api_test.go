@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,10 +17,12 @@ import (
 	"github.com/grafana/agent/internal/component/common/loki/client/fake"
 	"github.com/grafana/agent/internal/component/common/net"
 	"github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/service/ports"
 	"github.com/grafana/agent/internal/util"
 	"github.com/grafana/dskit/flagext"
 	"github.com/grafana/loki/pkg/logproto"
 	"github.com/grafana/regexp"
+	"github.com/grafana/river"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
@@ -71,6 +75,33 @@ func TestLokiSourceAPI_Simple(t *testing.T) {
 	})
 }
 
+func TestLokiSourceAPI_Handler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receiver := fake.NewClient(func() {})
+	defer receiver.Stop()
+
+	args := testArgsWith(t, func(a *Arguments) {
+		a.ForwardTo = []loki.LogsReceiver{receiver.LogsReceiver()}
+	})
+	comp, shutdown := startTestComponent(t, defaultOptions(t), args, ctx)
+	defer shutdown()
+
+	// The push API is served by the handler of the component, without going
+	// through the server of the component.
+	srv := httptest.NewServer(comp.(*Component).Handler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/loki/api/v1/raw", "text/plain", strings.NewReader("hello world!"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.Eventually(t, func() bool { return len(receiver.Received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, "hello world!", receiver.Received()[0].Line)
+}
+
 func TestLokiSourceAPI_Update(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -300,6 +331,86 @@ func TestComponent_detectsWhenUpdateRequiresARestart(t *testing.T) {
 	}
 }
 
+func TestArguments_Tenants(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		forward_to = []
+
+		tenant {
+			id         = "team-a"
+			token      = "token-a"
+			labels     = {team = "a"}
+			rate_limit = 100
+		}
+	`), &args))
+	require.Equal(t, DefaultTenantLabel, args.TenantLabel)
+	require.Equal(t, []TenantConfig{{
+		ID:        "team-a",
+		Token:     "token-a",
+		Labels:    map[string]string{"team": "a"},
+		RateLimit: 100,
+	}}, args.Tenants)
+
+	tt := []struct {
+		name      string
+		cfg       string
+		expectErr string
+	}{
+		{
+			name: "empty id",
+			cfg: `
+				tenant {
+					id    = ""
+					token = "a"
+				}`,
+			expectErr: "tenant id must not be empty",
+		},
+		{
+			name: "duplicate tenant",
+			cfg: `
+				tenant {
+					id    = "a"
+					token = "a"
+				}
+				tenant {
+					id    = "a"
+					token = "b"
+				}`,
+			expectErr: `tenant "a" is defined more than once`,
+		},
+		{
+			name: "shared token",
+			cfg: `
+				tenant {
+					id    = "a"
+					token = "a"
+				}
+				tenant {
+					id    = "b"
+					token = "a"
+				}`,
+			expectErr: `tenant "b": token is shared with another tenant`,
+		},
+		{
+			name: "burst without rate limit",
+			cfg: `
+				tenant {
+					id               = "a"
+					token            = "a"
+					rate_limit_burst = 10
+				}`,
+			expectErr: `tenant "a": rate_limit_burst requires rate_limit to be set`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte("forward_to = []\n"+tc.cfg), &args)
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
+
 func TestDefaultServerConfig(t *testing.T) {
 	args := testArgs(t)
 	args.Server = nil // user did not define server options
@@ -322,6 +433,43 @@ func TestDefaultServerConfig(t *testing.T) {
 	comp.stop()
 }
 
+func TestLokiSourceAPI_PortRange(t *testing.T) {
+	port := getFreePort(t)
+	args := testArgsWith(t, func(a *Arguments) {
+		a.Server.HTTP.ListenPort = 0
+		a.Server.HTTP.ListenPortRange = fmt.Sprintf("%d-%d", port, port)
+	})
+
+	alloc := ports.New(nil).Data().(*ports.Allocator)
+	exports := make(chan Exports, 1)
+	opts := defaultOptions(t)
+	opts.OnStateChange = func(e component.Exports) { exports <- e.(Exports) }
+	opts.GetServiceData = func(name string) (interface{}, error) {
+		return alloc, nil
+	}
+
+	comp, err := New(opts, args)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("127.0.0.1:%d", port), (<-exports).HTTPListenAddress)
+	waitForServerToBeReady(t, comp)
+
+	// The port is allocated to the component until it stops.
+	_, err = alloc.Allocate("loki.source.api.other", "http", "127.0.0.1", ports.Range{Min: port, Max: port})
+	require.Error(t, err)
+	comp.stop()
+	_, err = alloc.Allocate("loki.source.api.other", "http", "127.0.0.1", ports.Range{Min: port, Max: port})
+	require.NoError(t, err)
+}
+
+func TestLokiSourceAPI_PortRangeWithoutService(t *testing.T) {
+	args := testArgsWith(t, func(a *Arguments) {
+		a.Server.HTTP.ListenPort = 0
+		a.Server.HTTP.ListenPortRange = "20000-20010"
+	})
+	_, err := New(defaultOptions(t), args)
+	require.ErrorContains(t, err, "listen_port_range requires the ports service")
+}
+
 func startTestComponent(
 	t *testing.T,
 	opts component.Options,
@@ -388,9 +536,10 @@ func newTestLokiClient(t *testing.T, args Arguments, opts component.Options) cli
 
 func defaultOptions(t *testing.T) component.Options {
 	return component.Options{
-		ID:         "loki.source.api.test",
-		Logger:     util.TestFlowLogger(t),
-		Registerer: prometheus.NewRegistry(),
+		ID:            "loki.source.api.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
 	}
 }
 
@@ -31,11 +31,16 @@ type PushAPIServer struct {
 	serverConfig *fnet.ServerConfig
 	server       *fnet.TargetServer
 	handler      loki.EntryHandler
+	router       *mux.Router // Serves the push API outside of server.
 
 	rwMutex       sync.RWMutex
 	labels        model.LabelSet
 	relabelRules  []*relabel.Config
 	keepTimestamp bool
+	tenants       []*tenantState
+	tenantLabel   model.LabelName
+
+	tenantMetrics *tenantMetrics
 }
 
 func NewPushAPIServer(logger log.Logger,
@@ -45,9 +50,10 @@ func NewPushAPIServer(logger log.Logger,
 ) (*PushAPIServer, error) {
 
 	s := &PushAPIServer{
-		logger:       logger,
-		serverConfig: serverConfig,
-		handler:      handler,
+		logger:        logger,
+		serverConfig:  serverConfig,
+		handler:       handler,
+		tenantMetrics: newTenantMetrics(registerer),
 	}
 
 	srv, err := fnet.NewTargetServer(logger, "loki_source_api", registerer, serverConfig)
@@ -56,36 +62,56 @@ func NewPushAPIServer(logger log.Logger,
 	}
 
 	s.server = srv
+	s.router = mux.NewRouter()
+	s.mount(s.router)
 	return s, nil
 }
 
 func (s *PushAPIServer) Run() error {
 	level.Info(s.logger).Log("msg", "starting push API server")
 
-	err := s.server.MountAndRun(func(router *mux.Router) {
-		// This redirecting is so we can avoid breaking changes where we originally implemented it with
-		// the loki prefix.
-		router.Path("/api/v1/push").Methods("POST").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			r.URL.Path = "/loki/api/v1/push"
-			r.RequestURI = "/loki/api/v1/push"
-			s.handleLoki(w, r)
-		}))
-		router.Path("/api/v1/raw").Methods("POST").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			r.URL.Path = "/loki/api/v1/raw"
-			r.RequestURI = "/loki/api/v1/raw"
-			s.handlePlaintext(w, r)
-		}))
-		router.Path("/ready").Methods("GET").Handler(http.HandlerFunc(s.ready))
-		router.Path("/loki/api/v1/push").Methods("POST").Handler(http.HandlerFunc(s.handleLoki))
-		router.Path("/loki/api/v1/raw").Methods("POST").Handler(http.HandlerFunc(s.handlePlaintext))
-	})
-	return err
+	return s.server.MountAndRun(s.mount)
+}
+
+// mount registers the routes of the push API on router.
+func (s *PushAPIServer) mount(router *mux.Router) {
+	// This redirecting is so we can avoid breaking changes where we originally implemented it with
+	// the loki prefix.
+	router.Path("/api/v1/push").Methods("POST").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "/loki/api/v1/push"
+		r.RequestURI = "/loki/api/v1/push"
+		s.handleLoki(w, r)
+	}))
+	router.Path("/api/v1/raw").Methods("POST").Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "/loki/api/v1/raw"
+		r.RequestURI = "/loki/api/v1/raw"
+		s.handlePlaintext(w, r)
+	}))
+	router.Path("/ready").Methods("GET").Handler(http.HandlerFunc(s.ready))
+	router.Path("/loki/api/v1/push").Methods("POST").Handler(http.HandlerFunc(s.handleLoki))
+	router.Path("/loki/api/v1/raw").Methods("POST").Handler(http.HandlerFunc(s.handlePlaintext))
+}
+
+// Handler returns the routes of the push API, for serving them from another
+// HTTP server than the one of s.
+func (s *PushAPIServer) Handler() http.Handler {
+	return s.router
 }
 
 func (s *PushAPIServer) ServerConfig() fnet.ServerConfig {
 	return *s.serverConfig
 }
 
+// HTTPListenAddr returns the listen address of the HTTP server.
+func (s *PushAPIServer) HTTPListenAddr() string {
+	return s.server.HTTPListenAddr()
+}
+
+// GRPCListenAddr returns the listen address of the gRPC server.
+func (s *PushAPIServer) GRPCListenAddr() string {
+	return s.server.GRPCListenAddr()
+}
+
 func (s *PushAPIServer) Shutdown() {
 	level.Info(s.logger).Log("msg", "stopping push API server")
 	s.server.StopAndShutdown()
@@ -135,6 +161,11 @@ func (s *PushAPIServer) getRelabelRules() []*relabel.Config {
 // NOTE: This code is copied from Promtail (https://github.com/grafana/loki/commit/47e2c5884f443667e64764f3fc3948f8f11abbb8) with changes kept to the minimum.
 // Only the HTTP handler functions are copied to allow for flow-specific server configuration and lifecycle management.
 func (s *PushAPIServer) handleLoki(w http.ResponseWriter, r *http.Request) {
+	pusher, ok := s.authenticateTenant(w, r)
+	if !ok {
+		return
+	}
+
 	logger := util_log.WithContext(r.Context(), util_log.Logger)
 	userID, _ := tenant.TenantID(r.Context())
 	req, err := push.ParseRequest(logger, userID, r, nil, nil, push.ParseLokiRequest)
@@ -144,8 +175,17 @@ func (s *PushAPIServer) handleLoki(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var entries int
+	for _, stream := range req.Streams {
+		entries += len(stream.Entries)
+	}
+	if !s.allowEntries(w, pusher, entries) {
+		return
+	}
+
 	// Take snapshot of current configs and apply consistently for the entire request.
 	addLabels := s.getLabels()
+	tenantLabels := s.tenantLabels(pusher)
 	relabelRules := s.getRelabelRules()
 	keepTimestamp := s.getKeepTimestamp()
 
@@ -180,6 +220,11 @@ func (s *PushAPIServer) handleLoki(w http.ResponseWriter, r *http.Request) {
 			}
 			filtered[model.LabelName(processed[i].Name)] = model.LabelValue(processed[i].Value)
 		}
+		// Tenant labels are added last, so they can't be overridden by the
+		// tenant and aren't dropped with other reserved labels.
+		for k, v := range tenantLabels {
+			filtered[k] = v
+		}
 
 		for _, entry := range stream.Entries {
 			e := loki.Entry{
@@ -211,10 +256,16 @@ func (s *PushAPIServer) handleLoki(w http.ResponseWriter, r *http.Request) {
 // NOTE: This code is copied from Promtail (https://github.com/grafana/loki/commit/47e2c5884f443667e64764f3fc3948f8f11abbb8) with changes kept to the minimum.
 // Only the HTTP handler functions are copied to allow for flow-specific server configuration and lifecycle management.
 func (s *PushAPIServer) handlePlaintext(w http.ResponseWriter, r *http.Request) {
-	entries := s.handler.Chan()
+	pusher, ok := s.authenticateTenant(w, r)
+	if !ok {
+		return
+	}
+
 	defer r.Body.Close()
 	body := bufio.NewReader(r.Body)
-	addLabels := s.getLabels()
+	// Lines are read before being sent, so that the rate limit of the tenant
+	// applies to the whole request.
+	var lines []string
 	for {
 		line, err := body.ReadString('\n')
 		if err != nil && err != io.EOF {
@@ -223,12 +274,23 @@ func (s *PushAPIServer) handlePlaintext(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		line = strings.TrimRight(line, "\r\n")
-		if line == "" {
-			if err == io.EOF {
-				break
-			}
-			continue
+		if line != "" {
+			lines = append(lines, line)
 		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if !s.allowEntries(w, pusher, len(lines)) {
+		return
+	}
+
+	addLabels := s.getLabels()
+	for k, v := range s.tenantLabels(pusher) {
+		addLabels[k] = v
+	}
+	entries := s.handler.Chan()
+	for _, line := range lines {
 		entries <- loki.Entry{
 			Labels: addLabels,
 			Entry: logproto.Entry{
@@ -236,9 +298,6 @@ func (s *PushAPIServer) handlePlaintext(w http.ResponseWriter, r *http.Request)
 				Line:      line,
 			},
 		}
-		if err == io.EOF {
-			break
-		}
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -266,6 +266,56 @@ func TestPlaintextPushTarget(t *testing.T) {
 	pt.Shutdown()
 }
 
+func TestTenants(t *testing.T) {
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	pt, port, eh := createPushServer(t, logger)
+	defer pt.Shutdown()
+
+	pt.SetLabels(model.LabelSet{"source": "gateway"})
+	pt.SetTenants([]Tenant{
+		{ID: "team-a", Token: "token-a", Labels: model.LabelSet{"team": "a"}},
+		{ID: "team-b", Token: "token-b", RateLimit: 1, RateLimitBurst: 2},
+	}, "__tenant_id__")
+
+	push := func(path, body string, auth func(r *http.Request)) int {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s:%d%s", localhost, port, path), bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if auth != nil {
+			auth(req)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	basic := func(username, password string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(username, password) }
+	}
+	stream := fmt.Sprintf(`{"streams": [{"stream": {"app": "x", "__tenant_id__": "forged"}, "values": [["%d", "hello"]]}]}`, time.Now().UnixNano())
+
+	// Requests of unknown tenants are rejected.
+	require.Equal(t, http.StatusUnauthorized, push("/loki/api/v1/push", stream, nil))
+	require.Equal(t, http.StatusUnauthorized, push("/loki/api/v1/push", stream, bearer("token-c")))
+	require.Equal(t, http.StatusUnauthorized, push("/api/v1/raw", "hello", basic("team-b", "token-a")))
+
+	// Tenant labels are injected, and override the labels sent by tenants.
+	require.Equal(t, http.StatusNoContent, push("/loki/api/v1/push", stream, bearer("token-a")))
+	require.Equal(t, http.StatusNoContent, push("/api/v1/raw", "hello\nworld", basic("team-b", "token-b")))
+	require.Eventually(t, func() bool { return len(eh.Received()) == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	received := eh.Received()
+	require.Equal(t, model.LabelSet{"app": "x", "source": "gateway", "team": "a", "__tenant_id__": "team-a"}, received[0].Labels)
+	require.Equal(t, model.LabelSet{"source": "gateway", "__tenant_id__": "team-b"}, received[1].Labels)
+
+	// team-b used its burst of 2 entries.
+	require.Equal(t, http.StatusTooManyRequests, push("/api/v1/raw", "hello", bearer("token-b")))
+	require.Equal(t, http.StatusNoContent, push("/api/v1/raw", "hello", bearer("token-a")))
+}
+
 func TestReady(t *testing.T) {
 	w := log.NewSyncWriter(os.Stderr)
 	logger := log.NewLogfmtLogger(w)
@@ -0,0 +1,194 @@
+package lokipush
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/time/rate"
+)
+
+// Tenant is a tenant allowed to push logs to the server.
+type Tenant struct {
+	// ID of the tenant, injected into the labels of the entries it pushes.
+	ID string
+
+	// Token authenticates the requests of the tenant, either as a bearer token
+	// or as the password of basic authentication with ID as the username.
+	Token string
+
+	// Labels are added to the entries pushed by the tenant.
+	Labels model.LabelSet
+
+	// RateLimit is the number of entries per second accepted from the tenant,
+	// with bursts of up to RateLimitBurst entries. A zero RateLimit disables
+	// rate limiting.
+	RateLimit      float64
+	RateLimitBurst int
+}
+
+// Reasons for rejecting the requests of tenants, used as values of the reason
+// label.
+const (
+	rejectUnauthorized = "unauthorized"
+	rejectRateLimit    = "rate_limit"
+)
+
+// tenantState is a Tenant along with the rate limiter enforcing its limits.
+type tenantState struct {
+	Tenant
+	limiter *rate.Limiter // nil if the tenant isn't rate limited.
+}
+
+// tenantMetrics holds the metrics of the tenants of a server.
+type tenantMetrics struct {
+	entries  *prometheus.CounterVec
+	rejected *prometheus.CounterVec
+}
+
+func newTenantMetrics(reg prometheus.Registerer) *tenantMetrics {
+	m := &tenantMetrics{
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loki_source_api_tenant_entries_total",
+			Help: "Total number of entries accepted from each tenant.",
+		}, []string{"tenant"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "loki_source_api_tenant_rejected_requests_total",
+			Help: "Total number of push requests rejected for each tenant, either because they couldn't be authenticated or because the tenant exceeded its rate limit.",
+		}, []string{"tenant", "reason"}),
+	}
+	if reg != nil {
+		reg.MustRegister(m.entries, m.rejected)
+	}
+	return m
+}
+
+// SetTenants replaces the tenants allowed to push logs to the server. When
+// tenants is empty, requests aren't authenticated. The ID of the tenant
+// pushing an entry is added to its labels as tenantLabel. Tenants keep their
+// rate limiters across updates, so updating doesn't reset their limits.
+func (s *PushAPIServer) SetTenants(tenants []Tenant, tenantLabel string) {
+	s.rwMutex.Lock()
+	defer s.rwMutex.Unlock()
+
+	newTenants := make([]*tenantState, 0, len(tenants))
+	for _, t := range tenants {
+		state := &tenantState{Tenant: t}
+		if t.RateLimit > 0 {
+			burst := t.RateLimitBurst
+			if burst == 0 {
+				burst = max(1, int(t.RateLimit))
+			}
+			state.limiter = s.tenantLimiter(t.ID)
+			if state.limiter == nil {
+				state.limiter = rate.NewLimiter(rate.Limit(t.RateLimit), burst)
+			} else {
+				now := time.Now()
+				state.limiter.SetLimitAt(now, rate.Limit(t.RateLimit))
+				state.limiter.SetBurstAt(now, burst)
+			}
+		}
+		newTenants = append(newTenants, state)
+	}
+	s.tenants = newTenants
+	s.tenantLabel = model.LabelName(tenantLabel)
+}
+
+// tenantLimiter returns the rate limiter of the current tenant called id, if
+// any. The caller must hold rwMutex.
+func (s *PushAPIServer) tenantLimiter(id string) *rate.Limiter {
+	for _, t := range s.tenants {
+		if t.ID == id {
+			return t.limiter
+		}
+	}
+	return nil
+}
+
+// authenticate returns the tenant making the request r. authenticate returns
+// true with a nil tenant when no tenants are configured, and false when the
+// request couldn't be authenticated.
+func (s *PushAPIServer) authenticate(r *http.Request) (*tenantState, bool) {
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+
+	if len(s.tenants) == 0 {
+		return nil, true
+	}
+
+	if username, password, ok := r.BasicAuth(); ok {
+		for _, t := range s.tenants {
+			if t.ID == username && tokenEqual(t.Token, password) {
+				return t, true
+			}
+		}
+		return nil, false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, false
+	}
+	for _, t := range s.tenants {
+		if tokenEqual(t.Token, token) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func tokenEqual(expect, actual string) bool {
+	return subtle.ConstantTimeCompare([]byte(expect), []byte(actual)) == 1
+}
+
+// authenticateTenant returns the tenant making the request r. It writes an
+// error response and returns false when the request can't be authenticated.
+func (s *PushAPIServer) authenticateTenant(w http.ResponseWriter, r *http.Request) (*tenantState, bool) {
+	tenant, ok := s.authenticate(r)
+	if !ok {
+		s.tenantMetrics.rejected.WithLabelValues("", rejectUnauthorized).Inc()
+		w.Header().Set("WWW-Authenticate", `Bearer realm="loki.source.api"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return nil, false
+	}
+	return tenant, true
+}
+
+// allowEntries checks that tenant may push n more entries. It writes an
+// error response and returns false when the tenant exceeded its rate limit.
+// Entries of requests which aren't authenticated are always allowed.
+func (s *PushAPIServer) allowEntries(w http.ResponseWriter, tenant *tenantState, n int) bool {
+	if tenant == nil {
+		return true
+	}
+	if tenant.limiter != nil && !tenant.limiter.AllowN(time.Now(), n) {
+		s.tenantMetrics.rejected.WithLabelValues(tenant.ID, rejectRateLimit).Inc()
+		http.Error(w, "tenant rate limit exceeded", http.StatusTooManyRequests)
+		return false
+	}
+	s.tenantMetrics.entries.WithLabelValues(tenant.ID).Add(float64(n))
+	return true
+}
+
+// tenantLabels returns the labels to add to the entries pushed by tenant,
+// which may be nil.
+func (s *PushAPIServer) tenantLabels(tenant *tenantState) model.LabelSet {
+	if tenant == nil {
+		return nil
+	}
+
+	s.rwMutex.RLock()
+	defer s.rwMutex.RUnlock()
+
+	res := tenant.Labels.Clone()
+	if res == nil {
+		res = make(model.LabelSet, 1)
+	}
+	if s.tenantLabel != "" {
+		res[s.tenantLabel] = model.LabelValue(tenant.ID)
+	}
+	return res
+}
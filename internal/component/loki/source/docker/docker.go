@@ -24,6 +24,7 @@ import (
 	dt "github.com/grafana/agent/internal/component/loki/source/docker/internal/dockertarget"
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/egress"
 	"github.com/grafana/agent/internal/useragent"
 	"github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
@@ -282,7 +283,7 @@ func (c *Component) getManagerOptions(args Arguments) (*options, error) {
 	// unix, which are not supported by the HTTP client. Passing HTTP client
 	// options to the Docker client makes those non-HTTP requests fail.
 	if hostURL.Scheme == "http" || hostURL.Scheme == "https" {
-		rt, err := config.NewRoundTripperFromConfig(*args.HTTPClientConfig.Convert(), "docker_sd")
+		rt, err := config.NewRoundTripperFromConfig(*args.HTTPClientConfig.Convert(), "docker_sd", egress.HTTPClientOption())
 		if err != nil {
 			return c.lastOptions, err
 		}
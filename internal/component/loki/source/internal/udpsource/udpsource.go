@@ -0,0 +1,191 @@
+// Package udpsource implements the parts shared by loki.source components
+// which convert packets received over UDP into log entries: restarting their
+// listener on updates, reporting its health, and forwarding entries to
+// receivers.
+package udpsource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+// ListenFunc starts a listener which handles packets until stop is closed.
+// It returns a function closing the listener, and a function which blocks
+// until the listener stopped and returns the error it stopped with.
+type ListenFunc func(stop <-chan struct{}) (closeFn func(), wait func() error, err error)
+
+// Source runs the listener of a component and forwards the entries created
+// by its packet handlers to the receivers of the component.
+type Source struct {
+	logger  log.Logger
+	noun    string // What the listener receives, such as "flow" or "trap".
+	entries chan loki.Entry
+
+	mut       sync.RWMutex
+	fanout    []loki.LogsReceiver
+	closeFn   func()
+	stop      chan struct{} // Closed to stop handling packets.
+	done      chan struct{} // Closed when the listener stopped.
+	listenErr error
+}
+
+// New returns a Source which doesn't listen yet. noun describes what packets
+// are received in logs and health messages.
+func New(logger log.Logger, noun string) *Source {
+	return &Source{
+		logger:  logger,
+		noun:    noun,
+		entries: make(chan loki.Entry),
+	}
+}
+
+// SetReceivers sets the receivers which entries are forwarded to.
+func (s *Source) SetReceivers(fanout []loki.LogsReceiver) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.fanout = fanout
+}
+
+// Run forwards entries to the receivers until ctx is canceled, and then stops
+// the listener.
+func (s *Source) Run(ctx context.Context) error {
+	defer s.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-s.entries:
+			s.mut.RLock()
+			for _, receiver := range s.fanout {
+				select {
+				case <-ctx.Done():
+					s.mut.RUnlock()
+					return nil
+				case receiver.Chan() <- entry:
+				}
+			}
+			s.mut.RUnlock()
+		}
+	}
+}
+
+// Start stops the current listener, if any, and starts a new one with listen.
+func (s *Source) Start(listen ListenFunc) error {
+	s.Stop()
+
+	stop := make(chan struct{})
+	closeFn, wait, err := listen(stop)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+
+	s.mut.Lock()
+	s.closeFn, s.stop, s.done = closeFn, stop, done
+	s.listenErr = nil
+	s.mut.Unlock()
+
+	go func() {
+		defer close(done)
+		err := wait()
+
+		// Report the listener as unhealthy if it stops without being asked to.
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		level.Error(s.logger).Log("msg", s.noun+" listener stopped", "err", err)
+
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		if s.done == done {
+			s.listenErr = err
+		}
+	}()
+	return nil
+}
+
+// Stop stops the current listener, if any, and waits for it to exit.
+func (s *Source) Stop() {
+	s.mut.Lock()
+	closeFn, stop, done := s.closeFn, s.stop, s.done
+	s.closeFn, s.stop, s.done = nil, nil, nil
+	s.mut.Unlock()
+
+	if closeFn == nil {
+		return
+	}
+	close(stop)
+	closeFn()
+	<-done
+}
+
+// Send queues entry to be forwarded, until stop is closed. It returns false
+// if stop was closed first.
+func (s *Source) Send(entry loki.Entry, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case s.entries <- entry:
+		return true
+	}
+}
+
+// CurrentHealth reports the source as unhealthy if its listener stopped
+// without being asked to.
+func (s *Source) CurrentHealth() component.Health {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	if s.listenErr != nil {
+		return component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("%s listener stopped: %s", s.noun, s.listenErr),
+			UpdateTime: time.Now(),
+		}
+	}
+	return component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    fmt.Sprintf("listening for %ss", s.noun),
+		UpdateTime: time.Now(),
+	}
+}
+
+// EntryLabels returns the labels of an entry: the static labels, overridden
+// by the labels which don't start with "__" after relabeling discovered with
+// rcs, and job set to the component ID if it's missing. It returns false if
+// the entry is dropped by rcs.
+func EntryLabels(discovered labels.Labels, rcs []*relabel.Config, static map[string]string, componentID string) (model.LabelSet, bool) {
+	processed, keep := relabel.Process(discovered, rcs...)
+	if !keep {
+		return nil, false
+	}
+
+	entryLabels := make(model.LabelSet, len(static)+len(processed))
+	for k, v := range static {
+		entryLabels[model.LabelName(k)] = model.LabelValue(v)
+	}
+	for _, lbl := range processed {
+		if strings.HasPrefix(lbl.Name, "__") {
+			continue
+		}
+		entryLabels[model.LabelName(lbl.Name)] = model.LabelValue(lbl.Value)
+	}
+	if _, ok := entryLabels["job"]; !ok {
+		entryLabels["job"] = model.LabelValue(componentID)
+	}
+	return entryLabels, true
+}
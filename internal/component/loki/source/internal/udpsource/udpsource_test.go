@@ -0,0 +1,73 @@
+package udpsource
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource_Health(t *testing.T) {
+	s := New(util.TestLogger(t), "flow")
+
+	// The listener fails once it's closed by failed.
+	failed := make(chan struct{})
+	require.NoError(t, s.Start(func(stop <-chan struct{}) (func(), func() error, error) {
+		return func() {}, func() error {
+			select {
+			case <-stop:
+				return nil
+			case <-failed:
+				return errors.New("connection reset")
+			}
+		}, nil
+	}))
+	require.Equal(t, component.HealthTypeHealthy, s.CurrentHealth().Health)
+	require.Equal(t, "listening for flows", s.CurrentHealth().Message)
+
+	close(failed)
+	require.Eventually(t, func() bool {
+		return s.CurrentHealth().Health == component.HealthTypeUnhealthy
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "flow listener stopped: connection reset", s.CurrentHealth().Message)
+
+	// Stopping the listener doesn't report it as unhealthy.
+	require.NoError(t, s.Start(func(stop <-chan struct{}) (func(), func() error, error) {
+		return func() {}, func() error { <-stop; return errors.New("closed") }, nil
+	}))
+	s.Stop()
+	require.Equal(t, component.HealthTypeHealthy, s.CurrentHealth().Health)
+}
+
+func TestEntryLabels(t *testing.T) {
+	discovered := labels.FromStrings("__source", "192.0.2.1", "__version", "9")
+	rcs := []*relabel.Config{{
+		SourceLabels: model.LabelNames{"__source"},
+		Regex:        relabel.MustNewRegexp("(.*)"),
+		Replacement:  "$1",
+		TargetLabel:  "source",
+		Action:       relabel.Replace,
+	}}
+
+	entryLabels, keep := EntryLabels(discovered, rcs, map[string]string{"source": "static", "env": "prod"}, "loki.source.netflow.default")
+	require.True(t, keep)
+	require.Equal(t, model.LabelSet{
+		"source": "192.0.2.1",
+		"env":    "prod",
+		"job":    "loki.source.netflow.default",
+	}, entryLabels)
+
+	drop := []*relabel.Config{{
+		SourceLabels: model.LabelNames{"__version"},
+		Regex:        relabel.MustNewRegexp("9"),
+		Action:       relabel.Drop,
+	}}
+	_, keep = EntryLabels(discovered, drop, nil, "loki.source.netflow.default")
+	require.False(t, keep)
+}
@@ -38,6 +38,9 @@ type eventControllerTask struct {
 	Receiver     loki.LogsReceiver
 	Positions    positions.Positions
 	LogFormat    string
+
+	DeduplicationWindow  time.Duration // Window to drop repeated events in; 0 disables deduplication.
+	InvolvedObjectLabels bool          // Whether to add the kind and name of involved objects as labels.
 }
 
 // Hash implements [runner.Task].
@@ -58,6 +61,18 @@ type eventController struct {
 
 	positionsKey  string
 	initTimestamp time.Time
+
+	// Repeated events seen within the deduplication window. Event handlers
+	// are called sequentially, so these don't need to be guarded by a mutex.
+	seen      map[string]*seenEvent
+	lastPrune time.Time
+}
+
+// seenEvent tracks an event which has been logged, and how many times it has
+// been repeated since.
+type seenEvent struct {
+	loggedAt   time.Time
+	duplicates int
 }
 
 func newEventController(task eventControllerTask) *eventController {
@@ -76,6 +91,7 @@ func newEventController(task eventControllerTask) *eventController {
 		handler:       loki.NewEntryHandler(task.Receiver.Chan(), func() {}),
 		positionsKey:  key,
 		initTimestamp: time.UnixMicro(lastTimestamp),
+		seen:          make(map[string]*seenEvent),
 	}
 }
 
@@ -216,7 +232,13 @@ func (ctrl *eventController) handleEvent(ctx context.Context, event *corev1.Even
 		return nil
 	}
 
-	lset, msg, err := ctrl.parseEvent(event)
+	duplicates, drop := ctrl.deduplicate(event, eventTs)
+	if drop {
+		ctrl.task.Positions.Put(ctrl.positionsKey, "", eventTs.UnixMicro())
+		return nil
+	}
+
+	lset, msg, err := ctrl.parseEvent(event, duplicates)
 	if err != nil {
 		return err
 	}
@@ -240,7 +262,47 @@ func (ctrl *eventController) handleEvent(ctx context.Context, event *corev1.Even
 	}
 }
 
-func (ctrl *eventController) parseEvent(event *corev1.Event) (model.LabelSet, string, error) {
+// deduplicate reports whether event repeats an event which has been logged
+// within the deduplication window and must be dropped. Otherwise, it returns
+// how many repetitions of the event have been dropped since it was last
+// logged.
+func (ctrl *eventController) deduplicate(event *corev1.Event, eventTs time.Time) (duplicates int, drop bool) {
+	window := ctrl.task.DeduplicationWindow
+	if window == 0 {
+		return 0, false
+	}
+
+	// Repeated events are usually reported as updates of a single Event
+	// object with an increasing count, but may also be reported as distinct
+	// objects, so they're identified by their content.
+	obj := event.InvolvedObject
+	key := strings.Join([]string{obj.Namespace, obj.Kind, obj.Name, event.Type, event.Reason, event.Message}, "\x00")
+
+	seen, ok := ctrl.seen[key]
+	if ok && eventTs.Sub(seen.loggedAt) < window {
+		seen.duplicates++
+		return 0, true
+	}
+	if ok {
+		duplicates = seen.duplicates
+	}
+
+	// Forget events which have been logged before the window, at most once
+	// per window.
+	if eventTs.Sub(ctrl.lastPrune) >= window {
+		for k, seen := range ctrl.seen {
+			if eventTs.Sub(seen.loggedAt) >= window {
+				delete(ctrl.seen, k)
+			}
+		}
+		ctrl.lastPrune = eventTs
+	}
+
+	ctrl.seen[key] = &seenEvent{loggedAt: eventTs}
+	return duplicates, false
+}
+
+func (ctrl *eventController) parseEvent(event *corev1.Event, duplicates int) (model.LabelSet, string, error) {
 	var (
 		msg      strings.Builder
 		lset     = make(model.LabelSet)
@@ -256,6 +318,12 @@ func (ctrl *eventController) parseEvent(event *corev1.Event) (model.LabelSet, st
 	lset[model.LabelName("namespace")] = model.LabelValue(obj.Namespace)
 	lset[model.LabelName("job")] = model.LabelValue(ctrl.task.JobName)
 	lset[model.LabelName("instance")] = model.LabelValue(ctrl.task.InstanceName)
+	if ctrl.task.InvolvedObjectLabels {
+		if obj.Kind != "" {
+			lset[model.LabelName("kind")] = model.LabelValue(obj.Kind)
+		}
+		lset[model.LabelName("name")] = model.LabelValue(obj.Name)
+	}
 
 	if ctrl.task.LogFormat == logFormatJson {
 		appender = appendJsonMsg
@@ -298,6 +366,9 @@ func (ctrl *eventController) parseEvent(event *corev1.Event) (model.LabelSet, st
 	if event.Count != 0 {
 		appender(&msg, fields, "count", event.Count, "%d")
 	}
+	if duplicates != 0 {
+		appender(&msg, fields, "duplicates", duplicates, "%d")
+	}
 
 	appender(&msg, fields, "msg", event.Message, "%q")
 
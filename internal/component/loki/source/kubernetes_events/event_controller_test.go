@@ -0,0 +1,98 @@
+package kubernetes_events
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/common/loki/positions"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestController(t *testing.T, task eventControllerTask) (*eventController, chan loki.Entry) {
+	pos, err := positions.New(util.TestLogger(t), positions.Config{
+		SyncPeriod:    time.Minute,
+		PositionsFile: filepath.Join(t.TempDir(), "positions.yml"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(pos.Stop)
+
+	entries := make(chan loki.Entry, 10)
+	task.Log = util.TestLogger(t)
+	task.JobName = "events"
+	task.InstanceName = "loki.source.kubernetes_events.test"
+	task.LogFormat = logFormatFmt
+	task.Receiver = loki.NewLogsReceiverWithChannel(entries)
+	task.Positions = pos
+
+	ctrl := newEventController(task)
+	t.Cleanup(ctrl.handler.Stop)
+	return ctrl, entries
+}
+
+func newTestEvent(ts time.Time, count int32) *corev1.Event {
+	return &corev1.Event{
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Pod",
+			Namespace: "default",
+			Name:      "web-0",
+		},
+		Reason:        "BackOff",
+		Type:          "Warning",
+		Message:       "Back-off restarting failed container",
+		Count:         count,
+		LastTimestamp: metav1.NewTime(ts),
+	}
+}
+
+func receiveLines(t *testing.T, entries chan loki.Entry) []string {
+	var lines []string
+	for {
+		select {
+		case entry := <-entries:
+			lines = append(lines, entry.Line)
+		case <-time.After(100 * time.Millisecond):
+			return lines
+		}
+	}
+}
+
+func TestDeduplication(t *testing.T) {
+	ctrl, entries := newTestController(t, eventControllerTask{DeduplicationWindow: time.Minute})
+
+	start := time.Now()
+	for i, offset := range []time.Duration{0, 10 * time.Second, 20 * time.Second, 70 * time.Second} {
+		require.NoError(t, ctrl.handleEvent(context.Background(), newTestEvent(start.Add(offset), int32(i+1))))
+	}
+
+	require.Equal(t, []string{
+		`name=web-0 kind=Pod reason=BackOff type=Warning count=1 msg="Back-off restarting failed container" `,
+		`name=web-0 kind=Pod reason=BackOff type=Warning count=4 duplicates=2 msg="Back-off restarting failed container" `,
+	}, receiveLines(t, entries))
+
+	// Events with other messages aren't duplicates.
+	other := newTestEvent(start.Add(80*time.Second), 1)
+	other.Message = "Readiness probe failed"
+	require.NoError(t, ctrl.handleEvent(context.Background(), other))
+	require.Len(t, receiveLines(t, entries), 1)
+}
+
+func TestInvolvedObjectLabels(t *testing.T) {
+	ctrl, _ := newTestController(t, eventControllerTask{InvolvedObjectLabels: true})
+
+	lset, _, err := ctrl.parseEvent(newTestEvent(time.Now(), 1), 0)
+	require.NoError(t, err)
+	require.Equal(t, model.LabelSet{
+		"namespace": "default",
+		"job":       "events",
+		"instance":  "loki.source.kubernetes_events.test",
+		"kind":      "Pod",
+		"name":      "web-0",
+	}, lset)
+}
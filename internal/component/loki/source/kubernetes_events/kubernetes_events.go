@@ -47,6 +47,9 @@ type Arguments struct {
 	Namespaces []string `river:"namespaces,attr,optional"`
 	LogFormat  string   `river:"log_format,attr,optional"`
 
+	DeduplicationWindow  time.Duration `river:"deduplication_window,attr,optional"`
+	InvolvedObjectLabels bool          `river:"involved_object_labels,attr,optional"`
+
 	// Client settings to connect to Kubernetes.
 	Client kubernetes.ClientArguments `river:"client,block,optional"`
 }
@@ -72,6 +75,9 @@ func (args *Arguments) Validate() error {
 	if args.LogFormat != logFormatFmt && args.LogFormat != logFormatJson {
 		return fmt.Errorf("supported values of log_format are %s and %s", logFormatFmt, logFormatJson)
 	}
+	if args.DeduplicationWindow < 0 {
+		return fmt.Errorf("deduplication_window must not be negative")
+	}
 	return nil
 }
 
@@ -219,6 +225,9 @@ func (c *Component) Update(args component.Arguments) error {
 			Receiver:     c.handler,
 			Positions:    c.positions,
 			LogFormat:    newArgs.LogFormat,
+
+			DeduplicationWindow:  newArgs.DeduplicationWindow,
+			InvolvedObjectLabels: newArgs.InvolvedObjectLabels,
 		})
 	}
 
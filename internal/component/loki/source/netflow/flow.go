@@ -0,0 +1,348 @@
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/go-logfmt/logfmt"
+	lru "github.com/hashicorp/golang-lru/v2"
+	nf "github.com/netsampler/goflow2/decoders/netflow"
+	"github.com/netsampler/goflow2/decoders/netflowlegacy"
+)
+
+// Field types shared by NetFlow v9 and IPFIX which are decoded into flows.
+const (
+	fieldOctetDelta             = 1
+	fieldPacketDelta            = 2
+	fieldProtocol               = 4
+	fieldTos                    = 5
+	fieldTCPFlags               = 6
+	fieldSrcPort                = 7
+	fieldSrcIPv4                = 8
+	fieldInputInterface         = 10
+	fieldDstPort                = 11
+	fieldDstIPv4                = 12
+	fieldOutputInterface        = 14
+	fieldNextHopIPv4            = 15
+	fieldSrcAS                  = 16
+	fieldDstAS                  = 17
+	fieldSrcIPv6                = 27
+	fieldDstIPv6                = 28
+	fieldSamplingInterval       = 34
+	fieldNextHopIPv6            = 62
+	fieldSamplingPacketInterval = 305
+)
+
+// flow is the structured representation of a received flow record which is
+// written as the log line.
+//
+// Bytes and Packets are corrected for sampling: they are the values reported
+// by the exporter multiplied by SamplingRate.
+type flow struct {
+	Exporter     string `json:"exporter"`
+	Version      string `json:"version"`
+	SrcAddr      string `json:"src_addr,omitempty"`
+	DstAddr      string `json:"dst_addr,omitempty"`
+	NextHop      string `json:"next_hop,omitempty"`
+	SrcPort      uint64 `json:"src_port"`
+	DstPort      uint64 `json:"dst_port"`
+	Protocol     uint64 `json:"protocol"`
+	TCPFlags     uint64 `json:"tcp_flags"`
+	Tos          uint64 `json:"tos"`
+	InIf         uint64 `json:"in_if"`
+	OutIf        uint64 `json:"out_if"`
+	SrcAS        uint64 `json:"src_as"`
+	DstAS        uint64 `json:"dst_as"`
+	Bytes        uint64 `json:"bytes"`
+	Packets      uint64 `json:"packets"`
+	SamplingRate uint64 `json:"sampling_rate"`
+}
+
+// format returns the log line for f in the given format.
+func (f flow) format(format string) (string, error) {
+	switch format {
+	case FormatLogfmt:
+		var buf bytes.Buffer
+		enc := logfmt.NewEncoder(&buf)
+		keyvals := []interface{}{"exporter", f.Exporter, "version", f.Version}
+		for _, kv := range [][2]string{{"src_addr", f.SrcAddr}, {"dst_addr", f.DstAddr}, {"next_hop", f.NextHop}} {
+			if kv[1] != "" {
+				keyvals = append(keyvals, kv[0], kv[1])
+			}
+		}
+		keyvals = append(keyvals,
+			"src_port", f.SrcPort,
+			"dst_port", f.DstPort,
+			"protocol", f.Protocol,
+			"tcp_flags", f.TCPFlags,
+			"tos", f.Tos,
+			"in_if", f.InIf,
+			"out_if", f.OutIf,
+			"src_as", f.SrcAS,
+			"dst_as", f.DstAS,
+			"bytes", f.Bytes,
+			"packets", f.Packets,
+			"sampling_rate", f.SamplingRate,
+		)
+		if err := enc.EncodeKeyvals(keyvals...); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	default:
+		line, err := json.Marshal(f)
+		return string(line), err
+	}
+}
+
+// decoder decodes NetFlow v5, v9 and IPFIX packets into flows.
+//
+// NetFlow v9 and IPFIX data records can only be decoded once the template
+// describing them has been received, so decoder keeps the templates and
+// sampling rates announced by exporters. The state of the least recently seen
+// exporters is evicted once too many exporters are kept.
+type decoder struct {
+	// samplingRate is used for flows whose exporter doesn't report a sampling
+	// rate.
+	samplingRate uint64
+
+	mut       sync.Mutex
+	exporters *lru.Cache[string, *exporterState] // State by exporter address.
+}
+
+// exporterState holds the templates and sampling rates announced by an
+// exporter.
+type exporterState struct {
+	templates *nf.BasicTemplateSystem
+	sampling  map[samplingKey]uint64 // Sampling rates announced in options records.
+}
+
+type samplingKey struct {
+	exporter    string
+	version     uint16
+	obsDomainID uint32
+}
+
+// newDecoder returns a decoder which keeps the state of up to maxExporters
+// exporters. onEvict is called with the address of evicted exporters.
+func newDecoder(samplingRate uint64, maxExporters int, onEvict func(exporter string)) *decoder {
+	exporters, _ := lru.NewWithEvict[string, *exporterState](maxExporters, func(exporter string, _ *exporterState) {
+		if onEvict != nil {
+			onEvict(exporter)
+		}
+	})
+	return &decoder{
+		samplingRate: samplingRate,
+		exporters:    exporters,
+	}
+}
+
+// Resize changes the number of exporters whose state is kept, evicting the
+// least recently seen exporters if there are too many.
+func (d *decoder) Resize(maxExporters int) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	d.exporters.Resize(maxExporters)
+}
+
+// Decode decodes a packet received from exporter into flows.
+func (d *decoder) Decode(payload []byte, exporter net.IP) ([]flow, string, error) {
+	if len(payload) < 2 {
+		return nil, "", fmt.Errorf("packet too short")
+	}
+
+	switch version := binary.BigEndian.Uint16(payload); version {
+	case 5:
+		packet, err := netflowlegacy.DecodeMessage(bytes.NewBuffer(payload))
+		if err != nil {
+			return nil, "5", err
+		}
+		return d.flowsV5(packet.(netflowlegacy.PacketNetFlowV5), exporter), "5", nil
+	case 9, 10:
+		versionName := "9"
+		if version == 10 {
+			versionName = "ipfix"
+		}
+
+		state := d.exporterState(exporter.String())
+		packet, err := nf.DecodeMessage(bytes.NewBuffer(payload), state.templates)
+		if err != nil {
+			return nil, versionName, err
+		}
+
+		var (
+			obsDomainID uint32
+			flowSets    []interface{}
+		)
+		switch p := packet.(type) {
+		case nf.NFv9Packet:
+			obsDomainID, flowSets = p.SourceId, p.FlowSets
+		case nf.IPFIXPacket:
+			obsDomainID, flowSets = p.ObservationDomainId, p.FlowSets
+		}
+		key := samplingKey{exporter: exporter.String(), version: version, obsDomainID: obsDomainID}
+		return d.flowsTemplated(state, flowSets, key, versionName), versionName, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported NetFlow version %d", version)
+	}
+}
+
+// exporterState returns the state of exporter, creating it if it's not kept
+// yet.
+func (d *decoder) exporterState(exporter string) *exporterState {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	state, ok := d.exporters.Get(exporter)
+	if !ok {
+		state = &exporterState{
+			templates: nf.CreateTemplateSystem(),
+			sampling:  make(map[samplingKey]uint64),
+		}
+		d.exporters.Add(exporter, state)
+	}
+	return state
+}
+
+func (d *decoder) flowsV5(packet netflowlegacy.PacketNetFlowV5, exporter net.IP) []flow {
+	samplingRate := uint64(packet.SamplingInterval)
+	if samplingRate == 0 {
+		samplingRate = d.samplingRate
+	}
+
+	flows := make([]flow, 0, len(packet.Records))
+	for _, r := range packet.Records {
+		flows = append(flows, flow{
+			Exporter:     exporter.String(),
+			Version:      "5",
+			SrcAddr:      ipv4(r.SrcAddr).String(),
+			DstAddr:      ipv4(r.DstAddr).String(),
+			NextHop:      ipv4(r.NextHop).String(),
+			SrcPort:      uint64(r.SrcPort),
+			DstPort:      uint64(r.DstPort),
+			Protocol:     uint64(r.Proto),
+			TCPFlags:     uint64(r.TCPFlags),
+			Tos:          uint64(r.Tos),
+			InIf:         uint64(r.Input),
+			OutIf:        uint64(r.Output),
+			SrcAS:        uint64(r.SrcAS),
+			DstAS:        uint64(r.DstAS),
+			Bytes:        uint64(r.DOctets) * samplingRate,
+			Packets:      uint64(r.DPkts) * samplingRate,
+			SamplingRate: samplingRate,
+		})
+	}
+	return flows
+}
+
+// flowsTemplated converts the flow sets of a NetFlow v9 or IPFIX packet into
+// flows. Options records announcing a sampling rate apply to the data records
+// which follow them.
+func (d *decoder) flowsTemplated(state *exporterState, flowSets []interface{}, key samplingKey, version string) []flow {
+	var flows []flow
+	for _, fs := range flowSets {
+		switch fs := fs.(type) {
+		case nf.OptionsDataFlowSet:
+			for _, r := range fs.Records {
+				if rate := samplingRateOf(r.OptionsValues); rate > 0 {
+					d.mut.Lock()
+					state.sampling[key] = rate
+					d.mut.Unlock()
+				}
+			}
+		case nf.DataFlowSet:
+			d.mut.Lock()
+			announced := state.sampling[key]
+			d.mut.Unlock()
+
+			for _, r := range fs.Records {
+				flows = append(flows, d.flowTemplated(r.Values, key.exporter, version, announced))
+			}
+		}
+	}
+	return flows
+}
+
+func (d *decoder) flowTemplated(values []nf.DataField, exporter, version string, announced uint64) flow {
+	f := flow{Exporter: exporter, Version: version}
+	for _, v := range values {
+		b, ok := v.Value.([]byte)
+		if !ok || v.PenProvided {
+			continue
+		}
+		switch v.Type {
+		case fieldOctetDelta:
+			f.Bytes = uintValue(b)
+		case fieldPacketDelta:
+			f.Packets = uintValue(b)
+		case fieldProtocol:
+			f.Protocol = uintValue(b)
+		case fieldTos:
+			f.Tos = uintValue(b)
+		case fieldTCPFlags:
+			f.TCPFlags = uintValue(b)
+		case fieldSrcPort:
+			f.SrcPort = uintValue(b)
+		case fieldDstPort:
+			f.DstPort = uintValue(b)
+		case fieldInputInterface:
+			f.InIf = uintValue(b)
+		case fieldOutputInterface:
+			f.OutIf = uintValue(b)
+		case fieldSrcAS:
+			f.SrcAS = uintValue(b)
+		case fieldDstAS:
+			f.DstAS = uintValue(b)
+		case fieldSrcIPv4, fieldSrcIPv6:
+			f.SrcAddr = net.IP(b).String()
+		case fieldDstIPv4, fieldDstIPv6:
+			f.DstAddr = net.IP(b).String()
+		case fieldNextHopIPv4, fieldNextHopIPv6:
+			f.NextHop = net.IP(b).String()
+		}
+	}
+
+	f.SamplingRate = samplingRateOf(values)
+	if f.SamplingRate == 0 {
+		f.SamplingRate = announced
+	}
+	if f.SamplingRate == 0 {
+		f.SamplingRate = d.samplingRate
+	}
+	f.Bytes *= f.SamplingRate
+	f.Packets *= f.SamplingRate
+	return f
+}
+
+// samplingRateOf returns the sampling rate reported in values, or 0 if values
+// don't report one.
+func samplingRateOf(values []nf.DataField) uint64 {
+	for _, v := range values {
+		b, ok := v.Value.([]byte)
+		if !ok || v.PenProvided {
+			continue
+		}
+		if v.Type == fieldSamplingInterval || v.Type == fieldSamplingPacketInterval {
+			return uintValue(b)
+		}
+	}
+	return 0
+}
+
+// uintValue decodes a big-endian unsigned integer of up to 8 bytes.
+func uintValue(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func ipv4(addr uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, addr)
+	return ip
+}
@@ -0,0 +1,64 @@
+package netflow
+
+import (
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons for dropping packets and flows.
+const (
+	dropReasonDecode   = "decode"
+	dropReasonTemplate = "template"
+	dropReasonFormat   = "format"
+	dropReasonRelabel  = "relabel"
+)
+
+type metrics struct {
+	packetsReceived *prometheus.CounterVec
+	packetsDropped  *prometheus.CounterVec
+	flowsDropped    *prometheus.CounterVec
+	flowBytes       *prometheus.CounterVec
+	flowPackets     *prometheus.CounterVec
+
+	exportersEvicted prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	var m metrics
+
+	m.packetsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_netflow_packets_received_total",
+		Help: "Total number of NetFlow and IPFIX packets received.",
+	}, []string{"version"})
+	m.packetsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_netflow_packets_dropped_total",
+		Help: "Total number of NetFlow and IPFIX packets which couldn't be decoded.",
+	}, []string{"reason"})
+	m.flowsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_netflow_flows_dropped_total",
+		Help: "Total number of decoded flow records dropped.",
+	}, []string{"reason"})
+	m.flowBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_netflow_flow_bytes_total",
+		Help: "Total number of bytes reported in flow records, corrected for sampling.",
+	}, []string{"exporter"})
+	m.flowPackets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_netflow_flow_packets_total",
+		Help: "Total number of packets reported in flow records, corrected for sampling.",
+	}, []string{"exporter"})
+
+	m.exportersEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "loki_source_netflow_exporters_evicted_total",
+		Help: "Total number of exporters whose templates and sampling rates were evicted to keep at most max_exporters.",
+	})
+
+	if reg != nil {
+		m.packetsReceived = util.MustRegisterOrGet(reg, m.packetsReceived).(*prometheus.CounterVec)
+		m.packetsDropped = util.MustRegisterOrGet(reg, m.packetsDropped).(*prometheus.CounterVec)
+		m.flowsDropped = util.MustRegisterOrGet(reg, m.flowsDropped).(*prometheus.CounterVec)
+		m.flowBytes = util.MustRegisterOrGet(reg, m.flowBytes).(*prometheus.CounterVec)
+		m.flowPackets = util.MustRegisterOrGet(reg, m.flowPackets).(*prometheus.CounterVec)
+		m.exportersEvicted = util.MustRegisterOrGet(reg, m.exportersEvicted).(prometheus.Counter)
+	}
+	return &m
+}
@@ -0,0 +1,249 @@
+package netflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/component/loki/source/internal/udpsource"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/loki/pkg/logproto"
+	nf "github.com/netsampler/goflow2/decoders/netflow"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.netflow",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the loki.source.netflow
+// component.
+type Arguments struct {
+	ListenAddress string              `river:"listen_address,attr,optional"`
+	SamplingRate  uint64              `river:"sampling_rate,attr,optional"`
+	MaxExporters  int                 `river:"max_exporters,attr,optional"`
+	Format        string              `river:"format,attr,optional"`
+	Labels        map[string]string   `river:"labels,attr,optional"`
+	RelabelRules  flow_relabel.Rules  `river:"relabel_rules,attr,optional"`
+	ForwardTo     []loki.LogsReceiver `river:"forward_to,attr"`
+}
+
+// Supported line formats.
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// maxPacketSize is the size of the buffer packets are read into. NetFlow and
+// IPFIX packets are limited to the size of a UDP datagram.
+const maxPacketSize = 65535
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	ListenAddress: "0.0.0.0:2055",
+	SamplingRate:  1,
+	MaxExporters:  1000,
+	Format:        FormatJSON,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if _, _, err := net.SplitHostPort(args.ListenAddress); err != nil {
+		return fmt.Errorf("invalid listen_address %q: %w", args.ListenAddress, err)
+	}
+	if args.SamplingRate == 0 {
+		return fmt.Errorf("sampling_rate must be greater than zero")
+	}
+	if args.MaxExporters <= 0 {
+		return fmt.Errorf("max_exporters must be greater than zero")
+	}
+	switch args.Format {
+	case FormatJSON, FormatLogfmt:
+	default:
+		return fmt.Errorf("unsupported format %q, must be one of %s or %s", args.Format, FormatJSON, FormatLogfmt)
+	}
+	return nil
+}
+
+// Component implements the loki.source.netflow component.
+type Component struct {
+	opts    component.Options
+	metrics *metrics
+	source  *udpsource.Source
+
+	mut     sync.RWMutex
+	args    Arguments
+	decoder *decoder
+	relabel []*relabel.Config
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new loki.source.netflow component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+		source:  udpsource.New(o.Logger, "flow"),
+	}
+
+	// Call to Update() to start the listener and set receivers once at the
+	// start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	return c.source.Run(ctx)
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	var rcs []*relabel.Config
+	if len(newArgs.RelabelRules) > 0 {
+		rcs = flow_relabel.ComponentToPromRelabelConfigs(newArgs.RelabelRules)
+	}
+
+	c.source.Stop()
+
+	c.mut.Lock()
+	// Keep the templates received so far unless the decoder settings changed.
+	if c.decoder == nil || c.decoder.samplingRate != newArgs.SamplingRate {
+		c.decoder = newDecoder(newArgs.SamplingRate, newArgs.MaxExporters, c.evictExporter)
+	} else {
+		c.decoder.Resize(newArgs.MaxExporters)
+	}
+	c.args = newArgs
+	c.relabel = rcs
+	c.mut.Unlock()
+
+	c.source.SetReceivers(newArgs.ForwardTo)
+	return c.source.Start(func(stop <-chan struct{}) (func(), func() error, error) {
+		conn, err := net.ListenPacket("udp", newArgs.ListenAddress)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listening for flows on %s: %w", newArgs.ListenAddress, err)
+		}
+		closeFn := func() { conn.Close() }
+		return closeFn, func() error { return c.receive(conn, stop) }, nil
+	})
+}
+
+// receive reads packets from conn until it is closed.
+func (c *Component) receive(conn net.PacketConn, stop <-chan struct{}) error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		c.handlePacket(buf[:n], udpAddr.IP, stop)
+	}
+}
+
+// evictExporter is called when the templates and sampling rates of exporter
+// are evicted from the decoder.
+func (c *Component) evictExporter(exporter string) {
+	c.metrics.exportersEvicted.Inc()
+	c.metrics.flowBytes.DeleteLabelValues(exporter)
+	c.metrics.flowPackets.DeleteLabelValues(exporter)
+	level.Debug(c.opts.Logger).Log("msg", "evicted templates of exporter", "exporter", exporter)
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	return c.source.CurrentHealth()
+}
+
+// handlePacket decodes a received packet into log entries, one per flow
+// record, and queues them to be forwarded until stop is closed.
+func (c *Component) handlePacket(payload []byte, exporter net.IP, stop <-chan struct{}) {
+	c.mut.RLock()
+	var (
+		args    = c.args
+		decoder = c.decoder
+		rcs     = c.relabel
+	)
+	c.mut.RUnlock()
+
+	flows, version, err := decoder.Decode(payload, exporter)
+	if err != nil {
+		var templateErr *nf.ErrorTemplateNotFound
+		if errors.As(err, &templateErr) {
+			c.metrics.packetsDropped.WithLabelValues(dropReasonTemplate).Inc()
+			level.Debug(c.opts.Logger).Log("msg", "dropping packet received before its template", "exporter", exporter.String(), "err", err)
+			return
+		}
+		c.metrics.packetsDropped.WithLabelValues(dropReasonDecode).Inc()
+		level.Warn(c.opts.Logger).Log("msg", "failed to decode packet", "exporter", exporter.String(), "err", err)
+		return
+	}
+	c.metrics.packetsReceived.WithLabelValues(version).Inc()
+
+	for _, f := range flows {
+		c.metrics.flowBytes.WithLabelValues(f.Exporter).Add(float64(f.Bytes))
+		c.metrics.flowPackets.WithLabelValues(f.Exporter).Add(float64(f.Packets))
+
+		line, err := f.format(args.Format)
+		if err != nil {
+			c.metrics.flowsDropped.WithLabelValues(dropReasonFormat).Inc()
+			level.Warn(c.opts.Logger).Log("msg", "failed to format flow", "exporter", f.Exporter, "err", err)
+			continue
+		}
+
+		lb := labels.NewBuilder(nil)
+		lb.Set("__netflow_exporter_address", f.Exporter)
+		lb.Set("__netflow_version", f.Version)
+		lb.Set("__netflow_src_addr", f.SrcAddr)
+		lb.Set("__netflow_dst_addr", f.DstAddr)
+		lb.Set("__netflow_protocol", fmt.Sprint(f.Protocol))
+		entryLabels, keep := udpsource.EntryLabels(lb.Labels(), rcs, args.Labels, c.opts.ID)
+		if !keep {
+			c.metrics.flowsDropped.WithLabelValues(dropReasonRelabel).Inc()
+			continue
+		}
+
+		entry := loki.Entry{
+			Labels: entryLabels,
+			Entry: logproto.Entry{
+				Timestamp: time.Now(),
+				Line:      line,
+			},
+		}
+		if !c.source.Send(entry, stop) {
+			return
+		}
+	}
+}
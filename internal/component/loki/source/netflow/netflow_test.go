@@ -0,0 +1,308 @@
+package netflow
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetflow(t *testing.T) {
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	receiver := loki.NewLogsReceiver()
+	args := DefaultArguments
+	args.ListenAddress = fmt.Sprintf("127.0.0.1:%d", port)
+	args.Labels = map[string]string{"source": "netflow"}
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+
+	c, err := New(component.Options{
+		ID:            "loki.source.netflow.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go c.Run(ctx)
+
+	conn, err := net.Dial("udp", args.ListenAddress)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write(netflowV5Packet(10))
+	require.NoError(t, err)
+
+	select {
+	case <-ctx.Done():
+		require.FailNow(t, "no flow received")
+	case entry := <-receiver.Chan():
+		require.Equal(t, model.LabelSet{
+			"job":    "loki.source.netflow.test",
+			"source": "netflow",
+		}, entry.Labels)
+
+		var received flow
+		require.NoError(t, json.Unmarshal([]byte(entry.Line), &received))
+		require.Equal(t, flow{
+			Exporter:     "127.0.0.1",
+			Version:      "5",
+			SrcAddr:      "10.0.0.1",
+			DstAddr:      "10.0.0.2",
+			NextHop:      "0.0.0.0",
+			SrcPort:      51234,
+			DstPort:      443,
+			Protocol:     6,
+			TCPFlags:     0x18,
+			InIf:         1,
+			OutIf:        2,
+			SrcAS:        64512,
+			DstAS:        64513,
+			Bytes:        15000,
+			Packets:      100,
+			SamplingRate: 10,
+		}, received)
+	}
+}
+
+func TestDecoder_Templated(t *testing.T) {
+	for _, version := range []uint16{9, 10} {
+		t.Run(fmt.Sprint(version), func(t *testing.T) {
+			d := newDecoder(1, 10, nil)
+			exporter := net.ParseIP("192.0.2.1")
+
+			// Data records can't be decoded until their template is received.
+			_, _, err := d.Decode(templatedPacket(version, dataSet(1500, 3)), exporter)
+			require.Error(t, err)
+
+			flows, _, err := d.Decode(templatedPacket(version, templateSet(version), dataSet(1500, 3)), exporter)
+			require.NoError(t, err)
+			require.Len(t, flows, 1)
+			require.Equal(t, "198.51.100.1", flows[0].SrcAddr)
+			require.Equal(t, "198.51.100.2", flows[0].DstAddr)
+			require.Equal(t, uint64(17), flows[0].Protocol)
+			require.Equal(t, uint64(1500), flows[0].Bytes)
+			require.Equal(t, uint64(3), flows[0].Packets)
+			require.Equal(t, uint64(1), flows[0].SamplingRate)
+
+			// A sampling rate announced in an options record applies to later
+			// flows of the exporter.
+			flows, _, err = d.Decode(templatedPacket(version, optionsTemplateSet(version), optionsDataSet(100), dataSet(1500, 3)), exporter)
+			require.NoError(t, err)
+			require.Len(t, flows, 1)
+			require.Equal(t, uint64(150000), flows[0].Bytes)
+			require.Equal(t, uint64(300), flows[0].Packets)
+			require.Equal(t, uint64(100), flows[0].SamplingRate)
+
+			// Templates are kept by exporter.
+			_, _, err = d.Decode(templatedPacket(version, dataSet(1500, 3)), net.ParseIP("192.0.2.2"))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDecoder_EvictsExporters(t *testing.T) {
+	var evicted []string
+	d := newDecoder(1, 1, func(exporter string) { evicted = append(evicted, exporter) })
+
+	packet := templatedPacket(9, templateSet(9), dataSet(1500, 3))
+	_, _, err := d.Decode(packet, net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	_, _, err = d.Decode(packet, net.ParseIP("192.0.2.2"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.1"}, evicted)
+
+	// The templates of the evicted exporter must be announced again.
+	_, _, err = d.Decode(templatedPacket(9, dataSet(1500, 3)), net.ParseIP("192.0.2.1"))
+	require.Error(t, err)
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, evicted)
+}
+
+func TestArguments_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    string
+		expect string
+	}{
+		{
+			name: "defaults",
+			cfg:  `forward_to = []`,
+		},
+		{
+			name: "zero sampling rate",
+			cfg: `
+				sampling_rate = 0
+				forward_to    = []
+			`,
+			expect: "sampling_rate must be greater than zero",
+		},
+		{
+			name: "zero max exporters",
+			cfg: `
+				max_exporters = 0
+				forward_to    = []
+			`,
+			expect: "max_exporters must be greater than zero",
+		},
+		{
+			name: "invalid listen address",
+			cfg: `
+				listen_address = "2055"
+				forward_to     = []
+			`,
+			expect: `invalid listen_address "2055"`,
+		},
+		{
+			name: "invalid format",
+			cfg: `
+				format     = "xml"
+				forward_to = []
+			`,
+			expect: `unsupported format "xml", must be one of json or logfmt`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.cfg), &args)
+			if tc.expect == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.expect)
+			}
+		})
+	}
+}
+
+func TestFlow_FormatLogfmt(t *testing.T) {
+	line, err := flow{
+		Exporter:     "192.0.2.1",
+		Version:      "ipfix",
+		SrcAddr:      "2001:db8::1",
+		DstAddr:      "2001:db8::2",
+		SrcPort:      53,
+		DstPort:      40000,
+		Protocol:     17,
+		Bytes:        512,
+		Packets:      4,
+		SamplingRate: 4,
+	}.format(FormatLogfmt)
+	require.NoError(t, err)
+	require.Equal(t, `exporter=192.0.2.1 version=ipfix src_addr=2001:db8::1 dst_addr=2001:db8::2 src_port=53 dst_port=40000 protocol=17 tcp_flags=0 tos=0 in_if=0 out_if=0 src_as=0 dst_as=0 bytes=512 packets=4 sampling_rate=4`, line)
+}
+
+// netflowV5Packet returns a NetFlow v5 packet with a single record and the
+// given sampling interval.
+func netflowV5Packet(samplingInterval uint16) []byte {
+	b := make([]byte, 24+48)
+	binary.BigEndian.PutUint16(b[0:], 5)
+	binary.BigEndian.PutUint16(b[2:], 1)
+	binary.BigEndian.PutUint16(b[22:], samplingInterval)
+
+	r := b[24:]
+	copy(r[0:], net.ParseIP("10.0.0.1").To4())
+	copy(r[4:], net.ParseIP("10.0.0.2").To4())
+	binary.BigEndian.PutUint16(r[12:], 1)
+	binary.BigEndian.PutUint16(r[14:], 2)
+	binary.BigEndian.PutUint32(r[16:], 10)
+	binary.BigEndian.PutUint32(r[20:], 1500)
+	binary.BigEndian.PutUint16(r[32:], 51234)
+	binary.BigEndian.PutUint16(r[34:], 443)
+	r[37] = 0x18
+	r[38] = 6
+	binary.BigEndian.PutUint16(r[40:], 64512)
+	binary.BigEndian.PutUint16(r[42:], 64513)
+	return b
+}
+
+const (
+	testTemplateID        = 256
+	testOptionsTemplateID = 257
+)
+
+// templatedPacket returns a NetFlow v9 or IPFIX packet holding sets.
+func templatedPacket(version uint16, sets ...[]byte) []byte {
+	b := make([]byte, 16, 20)
+	binary.BigEndian.PutUint16(b[0:], version)
+	if version == 9 {
+		b = b[:20]
+		binary.BigEndian.PutUint16(b[2:], uint16(len(sets)))
+	}
+	for _, s := range sets {
+		b = append(b, s...)
+	}
+	if version == 10 {
+		binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	}
+	return b
+}
+
+// set returns a flow set with the given ID. Template and options template
+// sets have the IDs 0 and 1 in NetFlow v9, and 2 and 3 in IPFIX.
+func set(id uint16, body ...uint16) []byte {
+	b := make([]byte, 4+2*len(body))
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	for i, v := range body {
+		binary.BigEndian.PutUint16(b[4+2*i:], v)
+	}
+	return b
+}
+
+func templateSet(version uint16) []byte {
+	id := uint16(0)
+	if version == 10 {
+		id = 2
+	}
+	return set(id, testTemplateID, 5,
+		fieldSrcIPv4, 4,
+		fieldDstIPv4, 4,
+		fieldProtocol, 1,
+		fieldOctetDelta, 4,
+		fieldPacketDelta, 4,
+	)
+}
+
+func optionsTemplateSet(version uint16) []byte {
+	if version == 9 {
+		// Scope and option lengths are in bytes. The scope is the system.
+		return set(1, testOptionsTemplateID, 4, 4, 1, 4, fieldSamplingInterval, 4)
+	}
+	return set(3, testOptionsTemplateID, 2, 1, 149, 4, fieldSamplingInterval, 4)
+}
+
+func dataSet(bytes, packets uint32) []byte {
+	b := make([]byte, 4, 4+17)
+	binary.BigEndian.PutUint16(b[0:], testTemplateID)
+	b = append(b, net.ParseIP("198.51.100.1").To4()...)
+	b = append(b, net.ParseIP("198.51.100.2").To4()...)
+	b = append(b, 17)
+	b = binary.BigEndian.AppendUint32(b, bytes)
+	b = binary.BigEndian.AppendUint32(b, packets)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+func optionsDataSet(samplingInterval uint32) []byte {
+	b := make([]byte, 4, 12)
+	binary.BigEndian.PutUint16(b[0:], testOptionsTemplateID)
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint32(b, samplingInterval)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
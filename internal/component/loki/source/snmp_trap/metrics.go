@@ -0,0 +1,37 @@
+package snmp_trap
+
+import (
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons for dropping traps.
+const (
+	dropReasonCommunity = "community"
+	dropReasonFormat    = "format"
+	dropReasonRelabel   = "relabel"
+)
+
+type metrics struct {
+	trapsReceived *prometheus.CounterVec
+	trapsDropped  *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	var m metrics
+
+	m.trapsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_snmp_trap_traps_received_total",
+		Help: "Total number of SNMP traps received.",
+	}, []string{"version"})
+	m.trapsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_source_snmp_trap_traps_dropped_total",
+		Help: "Total number of SNMP traps dropped.",
+	}, []string{"reason"})
+
+	if reg != nil {
+		m.trapsReceived = util.MustRegisterOrGet(reg, m.trapsReceived).(*prometheus.CounterVec)
+		m.trapsDropped = util.MustRegisterOrGet(reg, m.trapsDropped).(*prometheus.CounterVec)
+	}
+	return &m
+}
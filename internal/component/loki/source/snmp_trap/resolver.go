@@ -0,0 +1,83 @@
+package snmp_trap
+
+import (
+	"fmt"
+	"strings"
+
+	snmp_config "github.com/prometheus/snmp_exporter/config"
+)
+
+// defaultOIDNames holds the names of the system group and of the OIDs which
+// are part of every SNMPv2 trap.
+var defaultOIDNames = map[string]string{
+	"1.3.6.1.2.1.1.1":     "sysDescr",
+	"1.3.6.1.2.1.1.2":     "sysObjectID",
+	"1.3.6.1.2.1.1.3":     "sysUpTime",
+	"1.3.6.1.2.1.1.4":     "sysContact",
+	"1.3.6.1.2.1.1.5":     "sysName",
+	"1.3.6.1.2.1.1.6":     "sysLocation",
+	"1.3.6.1.6.3.1.1.4.1": "snmpTrapOID",
+	"1.3.6.1.6.3.1.1.4.3": "snmpTrapEnterprise",
+	"1.3.6.1.6.3.1.1.5.1": "coldStart",
+	"1.3.6.1.6.3.1.1.5.2": "warmStart",
+	"1.3.6.1.6.3.1.1.5.3": "linkDown",
+	"1.3.6.1.6.3.1.1.5.4": "linkUp",
+	"1.3.6.1.6.3.1.1.5.5": "authenticationFailure",
+}
+
+// resolver resolves OIDs to names.
+type resolver struct {
+	names map[string]string
+}
+
+// newResolver creates a resolver from the names of the metrics in the given
+// MIB bundles and the additional OID names. MIB bundles use the snmp.yml
+// format generated by the snmp_exporter generator. Additional names take
+// precedence over names from MIB bundles.
+func newResolver(bundleFiles []string, oidNames map[string]string) (*resolver, error) {
+	names := make(map[string]string, len(defaultOIDNames))
+	for oid, name := range defaultOIDNames {
+		names[oid] = name
+	}
+
+	if len(bundleFiles) > 0 {
+		cfg, err := snmp_config.LoadFile(bundleFiles)
+		if err != nil {
+			return nil, fmt.Errorf("loading MIB bundles: %w", err)
+		}
+		for _, module := range cfg.Modules {
+			for _, metric := range module.Metrics {
+				names[normalizeOID(metric.Oid)] = metric.Name
+			}
+		}
+	}
+
+	for oid, name := range oidNames {
+		names[normalizeOID(oid)] = name
+	}
+	return &resolver{names: names}, nil
+}
+
+// Resolve returns the name of oid. When oid has no name of its own, the name
+// of its longest known prefix is used, followed by the remaining
+// sub-identifiers; for example, an ifIndex instance is resolved to
+// "ifIndex.5". Resolve returns oid without its leading period if no prefix
+// is known.
+func (r *resolver) Resolve(oid string) string {
+	oid = normalizeOID(oid)
+	if name, ok := r.names[oid]; ok {
+		return name
+	}
+
+	for i := strings.LastIndexByte(oid, '.'); i > 0; i = strings.LastIndexByte(oid[:i], '.') {
+		if name, ok := r.names[oid[:i]]; ok {
+			return name + oid[i:]
+		}
+	}
+	return oid
+}
+
+// normalizeOID removes the leading period of oid, if any.
+func normalizeOID(oid string) string {
+	return strings.TrimPrefix(oid, ".")
+}
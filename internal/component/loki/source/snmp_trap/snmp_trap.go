@@ -0,0 +1,378 @@
+package snmp_trap
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gosnmp/gosnmp"
+	"github.com/grafana/agent/internal/boringcrypto"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/component/loki/source/internal/udpsource"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/grafana/river/rivertypes"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.source.snmp_trap",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// loki.source.snmp_trap component.
+type Arguments struct {
+	ListenAddress  string              `river:"listen_address,attr,optional"`
+	Version        string              `river:"version,attr,optional"`
+	Community      rivertypes.Secret   `river:"community,attr,optional"`
+	USM            *USMArguments       `river:"usm,block,optional"`
+	MIBBundleFiles []string            `river:"mib_bundle_files,attr,optional"`
+	OIDNames       map[string]string   `river:"oid_names,attr,optional"`
+	Format         string              `river:"format,attr,optional"`
+	Labels         map[string]string   `river:"labels,attr,optional"`
+	RelabelRules   flow_relabel.Rules  `river:"relabel_rules,attr,optional"`
+	ForwardTo      []loki.LogsReceiver `river:"forward_to,attr"`
+}
+
+// USMArguments configures the SNMPv3 user-based security model used to
+// authenticate and decrypt received traps.
+type USMArguments struct {
+	EngineID      string            `river:"engine_id,attr"`
+	Username      string            `river:"username,attr"`
+	SecurityLevel string            `river:"security_level,attr,optional"`
+	AuthProtocol  string            `river:"auth_protocol,attr,optional"`
+	AuthPassword  rivertypes.Secret `river:"auth_password,attr,optional"`
+	PrivProtocol  string            `river:"priv_protocol,attr,optional"`
+	PrivPassword  rivertypes.Secret `river:"priv_password,attr,optional"`
+}
+
+// Supported line formats.
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	ListenAddress: "0.0.0.0:9162",
+	Version:       "2c",
+	Format:        FormatJSON,
+}
+
+// DefaultUSMArguments holds default settings for USMArguments.
+var DefaultUSMArguments = USMArguments{
+	SecurityLevel: "authPriv",
+	AuthProtocol:  "SHA",
+	PrivProtocol:  "AES",
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if _, _, err := net.SplitHostPort(args.ListenAddress); err != nil {
+		return fmt.Errorf("invalid listen_address %q: %w", args.ListenAddress, err)
+	}
+	switch args.Version {
+	case "2c":
+		if args.USM != nil {
+			return fmt.Errorf("the usm block can only be used with version 3")
+		}
+	case "3":
+		if args.USM == nil {
+			return fmt.Errorf("the usm block is required with version 3")
+		}
+	default:
+		return fmt.Errorf("unsupported version %q, must be one of 2c or 3", args.Version)
+	}
+	switch args.Format {
+	case FormatJSON, FormatLogfmt:
+	default:
+		return fmt.Errorf("unsupported format %q, must be one of %s or %s", args.Format, FormatJSON, FormatLogfmt)
+	}
+	return nil
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *USMArguments) SetToDefault() {
+	*args = DefaultUSMArguments
+}
+
+// Validate implements river.Validator.
+func (args *USMArguments) Validate() error {
+	engineID, err := hex.DecodeString(strings.TrimPrefix(args.EngineID, "0x"))
+	if err != nil {
+		return fmt.Errorf("engine_id must be hex encoded: %w", err)
+	}
+	if len(engineID) < 5 || len(engineID) > 32 {
+		return fmt.Errorf("engine_id must be between 5 and 32 bytes long")
+	}
+	if args.Username == "" {
+		return fmt.Errorf("username must not be empty")
+	}
+
+	switch args.SecurityLevel {
+	case "noAuthNoPriv":
+	case "authNoPriv":
+		if args.AuthPassword == "" {
+			return fmt.Errorf("auth_password is required with security level %s", args.SecurityLevel)
+		}
+	case "authPriv":
+		if args.AuthPassword == "" || args.PrivPassword == "" {
+			return fmt.Errorf("auth_password and priv_password are required with security level %s", args.SecurityLevel)
+		}
+	default:
+		return fmt.Errorf("unsupported security_level %q, must be one of noAuthNoPriv, authNoPriv or authPriv", args.SecurityLevel)
+	}
+	if _, ok := authProtocols[args.AuthProtocol]; !ok {
+		return fmt.Errorf("unsupported auth_protocol %q", args.AuthProtocol)
+	}
+	if _, ok := privProtocols[args.PrivProtocol]; !ok {
+		return fmt.Errorf("unsupported priv_protocol %q", args.PrivProtocol)
+	}
+
+	if args.SecurityLevel != "noAuthNoPriv" {
+		if err := boringcrypto.CheckAlgorithm("auth_protocol", args.AuthProtocol, "SHA", "SHA224", "SHA256", "SHA384", "SHA512"); err != nil {
+			return err
+		}
+	}
+	if args.SecurityLevel == "authPriv" {
+		if err := boringcrypto.CheckAlgorithm("priv_protocol", args.PrivProtocol, "AES", "AES192", "AES192C", "AES256", "AES256C"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":     gosnmp.DES,
+	"AES":     gosnmp.AES,
+	"AES192":  gosnmp.AES192,
+	"AES192C": gosnmp.AES192C,
+	"AES256":  gosnmp.AES256,
+	"AES256C": gosnmp.AES256C,
+}
+
+// params returns the gosnmp parameters used to decode received traps.
+func (args Arguments) params(l log.Logger) *gosnmp.GoSNMP {
+	params := &gosnmp.GoSNMP{
+		Version:   gosnmp.Version2c,
+		Community: string(args.Community),
+		Logger:    gosnmp.NewLogger(&gosnmpLogger{l: l}),
+	}
+	if args.Version != "3" {
+		return params
+	}
+
+	usm := args.USM
+	engineID, _ := hex.DecodeString(strings.TrimPrefix(usm.EngineID, "0x"))
+	sp := &gosnmp.UsmSecurityParameters{
+		UserName:              usm.Username,
+		AuthoritativeEngineID: string(engineID),
+	}
+
+	params.Version = gosnmp.Version3
+	params.SecurityModel = gosnmp.UserSecurityModel
+	switch usm.SecurityLevel {
+	case "noAuthNoPriv":
+		params.MsgFlags = gosnmp.NoAuthNoPriv
+	case "authNoPriv":
+		params.MsgFlags = gosnmp.AuthNoPriv
+		sp.AuthenticationProtocol = authProtocols[usm.AuthProtocol]
+		sp.AuthenticationPassphrase = string(usm.AuthPassword)
+	case "authPriv":
+		params.MsgFlags = gosnmp.AuthPriv
+		sp.AuthenticationProtocol = authProtocols[usm.AuthProtocol]
+		sp.AuthenticationPassphrase = string(usm.AuthPassword)
+		sp.PrivacyProtocol = privProtocols[usm.PrivProtocol]
+		sp.PrivacyPassphrase = string(usm.PrivPassword)
+	}
+	params.SecurityParameters = sp
+	return params
+}
+
+// Component implements the loki.source.snmp_trap component.
+type Component struct {
+	opts    component.Options
+	metrics *metrics
+	source  *udpsource.Source
+
+	mut      sync.RWMutex
+	args     Arguments
+	resolver *resolver
+	relabel  []*relabel.Config
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new loki.source.snmp_trap component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+		source:  udpsource.New(o.Logger, "trap"),
+	}
+
+	// Call to Update() to start the listener and set receivers once at the
+	// start.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	return c.source.Run(ctx)
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	resolver, err := newResolver(newArgs.MIBBundleFiles, newArgs.OIDNames)
+	if err != nil {
+		return err
+	}
+
+	var rcs []*relabel.Config
+	if len(newArgs.RelabelRules) > 0 {
+		rcs = flow_relabel.ComponentToPromRelabelConfigs(newArgs.RelabelRules)
+	}
+
+	c.source.Stop()
+
+	c.mut.Lock()
+	c.args = newArgs
+	c.resolver = resolver
+	c.relabel = rcs
+	c.mut.Unlock()
+
+	c.source.SetReceivers(newArgs.ForwardTo)
+	return c.source.Start(func(stop <-chan struct{}) (func(), func() error, error) {
+		return c.listen(newArgs, stop)
+	})
+}
+
+// listen starts listening for traps, which are handled until stop is closed.
+func (c *Component) listen(args Arguments, stop <-chan struct{}) (func(), func() error, error) {
+	var (
+		listener = gosnmp.NewTrapListener()
+		errCh    = make(chan error, 1)
+	)
+	listener.Params = args.params(c.opts.Logger)
+	listener.OnNewTrap = func(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+		c.handleTrap(packet, addr, stop)
+	}
+
+	go func() {
+		errCh <- listener.Listen("udp://" + args.ListenAddress)
+	}()
+
+	select {
+	case <-listener.Listening():
+	case err := <-errCh:
+		return nil, nil, fmt.Errorf("listening for traps on %s: %w", args.ListenAddress, err)
+	}
+	return listener.Close, func() error { return <-errCh }, nil
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	return c.source.CurrentHealth()
+}
+
+// handleTrap converts a received trap into a log entry and queues it to be
+// forwarded, until stop is closed.
+func (c *Component) handleTrap(packet *gosnmp.SnmpPacket, addr *net.UDPAddr, stop <-chan struct{}) {
+	c.mut.RLock()
+	var (
+		args     = c.args
+		resolver = c.resolver
+		rcs      = c.relabel
+	)
+	c.mut.RUnlock()
+
+	if args.Version == "2c" && args.Community != "" && packet.Community != string(args.Community) {
+		c.metrics.trapsDropped.WithLabelValues(dropReasonCommunity).Inc()
+		level.Debug(c.opts.Logger).Log("msg", "dropping trap with unexpected community", "source", addr.String())
+		return
+	}
+	c.metrics.trapsReceived.WithLabelValues(versionString(packet.Version)).Inc()
+
+	trap := newTrap(packet, addr, resolver)
+	line, err := trap.format(args.Format)
+	if err != nil {
+		c.metrics.trapsDropped.WithLabelValues(dropReasonFormat).Inc()
+		level.Warn(c.opts.Logger).Log("msg", "failed to format trap", "source", addr.String(), "err", err)
+		return
+	}
+
+	lb := labels.NewBuilder(nil)
+	lb.Set("__snmp_trap_source_address", addr.IP.String())
+	lb.Set("__snmp_trap_version", trap.Version)
+	lb.Set("__snmp_trap_oid", trap.TrapOID)
+	lb.Set("__snmp_trap_name", trap.TrapName)
+	if packet.Community != "" {
+		lb.Set("__snmp_trap_community", packet.Community)
+	}
+	entryLabels, keep := udpsource.EntryLabels(lb.Labels(), rcs, args.Labels, c.opts.ID)
+	if !keep {
+		c.metrics.trapsDropped.WithLabelValues(dropReasonRelabel).Inc()
+		return
+	}
+
+	entry := loki.Entry{
+		Labels: entryLabels,
+		Entry: logproto.Entry{
+			Timestamp: time.Now(),
+			Line:      line,
+		},
+	}
+	c.source.Send(entry, stop)
+}
+
+// gosnmpLogger adapts a go-kit logger to the logger interface of gosnmp.
+type gosnmpLogger struct {
+	l log.Logger
+}
+
+func (g *gosnmpLogger) Print(v ...interface{}) {
+	level.Debug(g.l).Log("msg", strings.TrimSpace(fmt.Sprint(v...)))
+}
+
+func (g *gosnmpLogger) Printf(format string, v ...interface{}) {
+	level.Debug(g.l).Log("msg", strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
@@ -0,0 +1,256 @@
+package snmp_trap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSNMPTrap(t *testing.T) {
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	receiver := loki.NewLogsReceiver()
+	args := DefaultArguments
+	args.ListenAddress = fmt.Sprintf("127.0.0.1:%d", port)
+	args.Community = "secret"
+	args.OIDNames = map[string]string{"1.3.6.1.4.1.8072.2.3.0.1": "netSnmpExampleHeartbeatNotification"}
+	args.Labels = map[string]string{"source": "snmp"}
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+
+	c, err := New(component.Options{
+		ID:            "loki.source.snmp_trap.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go c.Run(ctx)
+
+	// A trap with the wrong community must be dropped.
+	sendTrap(t, port, "wrong")
+	sendTrap(t, port, "secret")
+
+	select {
+	case <-ctx.Done():
+		require.FailNow(t, "no trap received")
+	case entry := <-receiver.Chan():
+		require.Equal(t, model.LabelSet{
+			"job":    "loki.source.snmp_trap.test",
+			"source": "snmp",
+		}, entry.Labels)
+
+		var received trap
+		require.NoError(t, json.Unmarshal([]byte(entry.Line), &received))
+		require.Equal(t, "127.0.0.1", received.Source)
+		require.Equal(t, "2c", received.Version)
+		require.Equal(t, "1.3.6.1.4.1.8072.2.3.0.1", received.TrapOID)
+		require.Equal(t, "netSnmpExampleHeartbeatNotification", received.TrapName)
+		require.Equal(t, "1234", received.Uptime)
+		require.Contains(t, received.Variables, variable{
+			OID:   "1.3.6.1.2.1.1.5.0",
+			Name:  "sysName.0",
+			Type:  "OctetString",
+			Value: "router-1",
+		})
+	}
+
+	select {
+	case entry := <-receiver.Chan():
+		require.FailNow(t, "unexpected trap received", entry.Line)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func sendTrap(t *testing.T, port int, community string) {
+	t.Helper()
+
+	client := &gosnmp.GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(port),
+		Community: community,
+		Version:   gosnmp.Version2c,
+		Timeout:   time.Second,
+	}
+	require.NoError(t, client.Connect())
+	defer client.Conn.Close()
+
+	_, err := client.SendTrap(gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1234)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.8072.2.3.0.1"},
+			{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: "router-1"},
+		},
+	})
+	require.NoError(t, err)
+}
+
+func TestResolver(t *testing.T) {
+	bundle := `
+modules:
+  if_mib:
+    walk: [1.3.6.1.2.1.2]
+    metrics:
+    - name: ifIndex
+      oid: 1.3.6.1.2.1.2.2.1.1
+      type: gauge
+      help: A unique value for each interface
+    - name: sysName
+      oid: 1.3.6.1.2.1.1.5
+      type: DisplayString
+      help: An administratively-assigned name
+`
+	bundlePath := filepath.Join(t.TempDir(), "snmp.yml")
+	require.NoError(t, os.WriteFile(bundlePath, []byte(bundle), 0644))
+
+	r, err := newResolver([]string{bundlePath}, map[string]string{".1.3.6.1.2.1.1.5": "hostname"})
+	require.NoError(t, err)
+
+	tt := []struct {
+		oid, expect string
+	}{
+		{oid: ".1.3.6.1.2.1.2.2.1.1", expect: "ifIndex"},
+		{oid: ".1.3.6.1.2.1.2.2.1.1.5", expect: "ifIndex.5"},
+		{oid: "1.3.6.1.2.1.1.5.0", expect: "hostname.0"},
+		{oid: ".1.3.6.1.6.3.1.1.5.3", expect: "linkDown"},
+		{oid: ".1.3.6.1.2.1.1.3.0", expect: "sysUpTime.0"},
+		{oid: ".1.3.6.1.4.1.99999.1", expect: "1.3.6.1.4.1.99999.1"},
+	}
+	for _, tc := range tt {
+		require.Equal(t, tc.expect, r.Resolve(tc.oid), tc.oid)
+	}
+
+	_, err = newResolver([]string{filepath.Join(t.TempDir(), "missing.yml")}, nil)
+	require.NoError(t, err, "globs which match no files are ignored")
+}
+
+func TestArguments_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    string
+		expect string
+	}{
+		{
+			name: "v2c",
+			cfg: `
+				community  = "public"
+				forward_to = []
+			`,
+		},
+		{
+			name: "v3",
+			cfg: `
+				version    = "3"
+				forward_to = []
+				usm {
+					engine_id     = "0x8000000001020304"
+					username      = "agent"
+					auth_password = "auth-secret"
+					priv_password = "priv-secret"
+				}
+			`,
+		},
+		{
+			name: "v3 without usm",
+			cfg: `
+				version    = "3"
+				forward_to = []
+			`,
+			expect: "the usm block is required with version 3",
+		},
+		{
+			name: "usm with v2c",
+			cfg: `
+				forward_to = []
+				usm {
+					engine_id = "0x8000000001020304"
+					username  = "agent"
+					security_level = "noAuthNoPriv"
+				}
+			`,
+			expect: "the usm block can only be used with version 3",
+		},
+		{
+			name: "missing passwords",
+			cfg: `
+				version    = "3"
+				forward_to = []
+				usm {
+					engine_id = "0x8000000001020304"
+					username  = "agent"
+				}
+			`,
+			expect: "auth_password and priv_password are required with security level authPriv",
+		},
+		{
+			name: "short engine ID",
+			cfg: `
+				version    = "3"
+				forward_to = []
+				usm {
+					engine_id      = "0x0102"
+					username       = "agent"
+					security_level = "noAuthNoPriv"
+				}
+			`,
+			expect: "engine_id must be between 5 and 32 bytes long",
+		},
+		{
+			name: "invalid format",
+			cfg: `
+				format     = "xml"
+				forward_to = []
+			`,
+			expect: `unsupported format "xml", must be one of json or logfmt`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.cfg), &args)
+			if tc.expect == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.expect)
+			}
+		})
+	}
+}
+
+func TestTrap_FormatLogfmt(t *testing.T) {
+	r, err := newResolver(nil, nil)
+	require.NoError(t, err)
+
+	packet := &gosnmp.SnmpPacket{
+		Version: gosnmp.Version2c,
+		PDUType: gosnmp.SNMPv2Trap,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(42)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+			{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: 2},
+			{Name: ".1.3.6.1.2.1.2.2.1.6.2", Type: gosnmp.OctetString, Value: []byte{0x00, 0x1a, 0x2b}},
+		},
+	}
+	line, err := newTrap(packet, &net.UDPAddr{IP: net.ParseIP("10.0.0.1")}, r).format(FormatLogfmt)
+	require.NoError(t, err)
+	require.Equal(t, "source=10.0.0.1 version=2c pdu_type=SNMPv2Trap trap_oid=1.3.6.1.6.3.1.1.5.3 trap_name=linkDown uptime=42 1.3.6.1.2.1.2.2.1.1.2=2 1.3.6.1.2.1.2.2.1.6.2=0x001a2b", line)
+}
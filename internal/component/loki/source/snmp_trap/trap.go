@@ -0,0 +1,150 @@
+package snmp_trap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"unicode/utf8"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// trap is the structured representation of a received trap which is written
+// as the log line.
+type trap struct {
+	Source    string     `json:"source"`
+	Version   string     `json:"version"`
+	PDUType   string     `json:"pdu_type"`
+	TrapOID   string     `json:"trap_oid,omitempty"`
+	TrapName  string     `json:"trap_name,omitempty"`
+	Uptime    string     `json:"uptime,omitempty"`
+	Variables []variable `json:"variables"`
+}
+
+// variable is a variable binding of a trap.
+type variable struct {
+	OID       string `json:"oid"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	ValueName string `json:"value_name,omitempty"` // Resolved name of OID values.
+}
+
+// newTrap converts a received packet into a trap, resolving the names of
+// OIDs with r.
+func newTrap(packet *gosnmp.SnmpPacket, addr *net.UDPAddr, r *resolver) trap {
+	t := trap{
+		Source:    addr.IP.String(),
+		Version:   versionString(packet.Version),
+		PDUType:   packet.PDUType.String(),
+		Variables: make([]variable, 0, len(packet.Variables)),
+	}
+
+	for _, pdu := range packet.Variables {
+		v := variable{
+			OID:   normalizeOID(pdu.Name),
+			Name:  r.Resolve(pdu.Name),
+			Type:  pdu.Type.String(),
+			Value: formatValue(pdu),
+		}
+		if pdu.Type == gosnmp.ObjectIdentifier {
+			v.Value = normalizeOID(v.Value)
+			v.ValueName = r.Resolve(v.Value)
+		}
+
+		switch v.OID {
+		case oidSysUpTime:
+			t.Uptime = v.Value
+		case oidSnmpTrapOID:
+			t.TrapOID = v.Value
+			t.TrapName = v.ValueName
+		}
+		t.Variables = append(t.Variables, v)
+	}
+	return t
+}
+
+// format returns the log line for t in the given format.
+func (t trap) format(format string) (string, error) {
+	switch format {
+	case FormatLogfmt:
+		var buf bytes.Buffer
+		enc := logfmt.NewEncoder(&buf)
+		keyvals := []interface{}{
+			"source", t.Source,
+			"version", t.Version,
+			"pdu_type", t.PDUType,
+		}
+		if t.TrapOID != "" {
+			keyvals = append(keyvals, "trap_oid", t.TrapOID, "trap_name", t.TrapName)
+		}
+		if t.Uptime != "" {
+			keyvals = append(keyvals, "uptime", t.Uptime)
+		}
+		for _, v := range t.Variables {
+			if v.OID == oidSysUpTime || v.OID == oidSnmpTrapOID {
+				continue
+			}
+			value := v.Value
+			if v.ValueName != "" {
+				value = v.ValueName
+			}
+			keyvals = append(keyvals, v.Name, value)
+		}
+		if err := enc.EncodeKeyvals(keyvals...); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	default:
+		line, err := json.Marshal(t)
+		return string(line), err
+	}
+}
+
+// formatValue returns the string representation of the value of pdu.
+// Non-printable octet strings are hex encoded.
+func formatValue(pdu gosnmp.SnmpPDU) string {
+	switch value := pdu.Value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		if utf8.Valid(value) && isPrintable(value) {
+			return string(value)
+		}
+		return "0x" + hex.EncodeToString(value)
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func isPrintable(b []byte) bool {
+	for _, r := range string(b) {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+func versionString(v gosnmp.SnmpVersion) string {
+	switch v {
+	case gosnmp.Version1:
+		return "1"
+	case gosnmp.Version2c:
+		return "2c"
+	case gosnmp.Version3:
+		return "3"
+	default:
+		return v.String()
+	}
+}
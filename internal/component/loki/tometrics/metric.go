@@ -0,0 +1,330 @@
+package tometrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/jmespath/go-jmespath"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// Types of metrics which can be derived from log lines.
+const (
+	TypeCounter   = "counter"
+	TypeHistogram = "histogram"
+)
+
+// windowSlots is the number of slots sliding windows are divided into. Slots
+// expire one at a time as the window slides.
+const windowSlots = 6
+
+// MetricArguments configures a metric derived from log lines.
+type MetricArguments struct {
+	Name       string            `river:"name,attr"`
+	Type       string            `river:"type,attr,optional"`
+	Regex      string            `river:"regex,attr,optional"`
+	JSONFields map[string]string `river:"json_fields,attr,optional"`
+	Value      string            `river:"value,attr,optional"`
+	Labels     []string          `river:"labels,attr,optional"`
+	Buckets    []float64         `river:"buckets,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (m *MetricArguments) SetToDefault() {
+	*m = MetricArguments{
+		Type:    TypeCounter,
+		Buckets: prometheus.DefBuckets,
+	}
+}
+
+// Validate implements river.Validator.
+func (m *MetricArguments) Validate() error {
+	_, err := newMetric(*m)
+	return err
+}
+
+// metric derives the series of a metric from log lines.
+type metric struct {
+	name       string
+	typ        string
+	regex      *regexp.Regexp
+	jsonFields map[string]*jmespath.JMESPath
+	value      string
+	labels     []string
+	buckets    []float64
+}
+
+func newMetric(args MetricArguments) (*metric, error) {
+	if !model.IsValidMetricName(model.LabelValue(args.Name)) {
+		return nil, fmt.Errorf("invalid metric name %q", args.Name)
+	}
+	m := &metric{
+		name:   args.Name,
+		typ:    args.Type,
+		value:  args.Value,
+		labels: args.Labels,
+	}
+
+	switch args.Type {
+	case TypeCounter:
+	case TypeHistogram:
+		if args.Value == "" {
+			return nil, fmt.Errorf("metric %q: value must be set for histograms", args.Name)
+		}
+		if len(args.Buckets) == 0 {
+			return nil, fmt.Errorf("metric %q: buckets must not be empty", args.Name)
+		}
+		m.buckets = append([]float64{}, args.Buckets...)
+		sort.Float64s(m.buckets)
+	default:
+		return nil, fmt.Errorf("metric %q: type must be %q or %q", args.Name, TypeCounter, TypeHistogram)
+	}
+
+	if args.Regex != "" {
+		re, err := regexp.Compile(args.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("metric %q: invalid regex: %w", args.Name, err)
+		}
+		m.regex = re
+	}
+	if len(args.JSONFields) > 0 {
+		m.jsonFields = make(map[string]*jmespath.JMESPath, len(args.JSONFields))
+		for field, expr := range args.JSONFields {
+			if expr == "" {
+				expr = field
+			}
+			jp, err := jmespath.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("metric %q: invalid JMESPath expression for field %q: %w", args.Name, field, err)
+			}
+			m.jsonFields[field] = jp
+		}
+	}
+
+	for _, l := range args.Labels {
+		if !model.LabelName(l).IsValid() || l == model.MetricNameLabel || l == model.BucketLabel {
+			return nil, fmt.Errorf("metric %q: invalid label name %q", args.Name, l)
+		}
+	}
+	return m, nil
+}
+
+// extract returns the fields extracted from the line of entry, and whether
+// the entry matches the metric.
+func (m *metric) extract(entry loki.Entry) (map[string]string, bool) {
+	fields := make(map[string]string)
+
+	if m.regex != nil {
+		match := m.regex.FindStringSubmatch(entry.Line)
+		if match == nil {
+			return nil, false
+		}
+		for i, name := range m.regex.SubexpNames() {
+			if name != "" && i < len(match) {
+				fields[name] = match[i]
+			}
+		}
+	}
+
+	if m.jsonFields != nil {
+		var data interface{}
+		if err := json.Unmarshal([]byte(entry.Line), &data); err != nil {
+			return nil, false
+		}
+		for field, jp := range m.jsonFields {
+			v, err := jp.Search(data)
+			if err != nil || v == nil {
+				continue
+			}
+			switch v := v.(type) {
+			case string:
+				fields[field] = v
+			case float64:
+				fields[field] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				fields[field] = strconv.FormatBool(v)
+			default:
+				b, err := json.Marshal(v)
+				if err == nil {
+					fields[field] = string(b)
+				}
+			}
+		}
+	}
+	return fields, true
+}
+
+// observation is what a matching log line contributes to a series.
+type observation struct {
+	labels labels.Labels
+	value  float64
+}
+
+// observe returns the observation of entry, or false if the entry doesn't
+// contribute to the metric.
+func (m *metric) observe(entry loki.Entry) (observation, bool) {
+	fields, ok := m.extract(entry)
+	if !ok {
+		return observation{}, false
+	}
+
+	value := 1.0
+	if m.value != "" {
+		raw, ok := fields[m.value]
+		if !ok {
+			return observation{}, false
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) {
+			return observation{}, false
+		}
+		if m.typ == TypeCounter && v < 0 {
+			return observation{}, false
+		}
+		value = v
+	}
+
+	lb := labels.NewScratchBuilder(len(m.labels) + 1)
+	lb.Add(labels.MetricName, m.name)
+	for _, name := range m.labels {
+		// Extracted fields take precedence over the labels of the log stream.
+		v, ok := fields[name]
+		if !ok {
+			v = string(entry.Labels[model.LabelName(name)])
+		}
+		if v != "" {
+			lb.Add(name, v)
+		}
+	}
+	lb.Sort()
+	return observation{labels: lb.Labels(), value: value}, true
+}
+
+// aggregate holds the observations of a series over a period of time.
+type aggregate struct {
+	count   float64
+	sum     float64
+	buckets []float64 // Non-cumulative count per bucket; the last bucket is +Inf.
+}
+
+func (a *aggregate) add(m *metric, v float64) {
+	a.count++
+	a.sum += v
+	if m.typ != TypeHistogram {
+		return
+	}
+	if a.buckets == nil {
+		a.buckets = make([]float64, len(m.buckets)+1)
+	}
+	a.buckets[sort.SearchFloat64s(m.buckets, v)]++
+}
+
+func (a *aggregate) merge(other *aggregate) {
+	a.count += other.count
+	a.sum += other.sum
+	if other.buckets == nil {
+		return
+	}
+	if a.buckets == nil {
+		a.buckets = make([]float64, len(other.buckets))
+	}
+	for i, c := range other.buckets {
+		a.buckets[i] += c
+	}
+}
+
+// slot holds the observations of a series made during a slot of a sliding
+// window.
+type slot struct {
+	start time.Time
+	agg   aggregate
+}
+
+// series holds the state of a series derived from log lines.
+type series struct {
+	metric      *metric
+	labels      labels.Labels
+	lastUpdated time.Time
+
+	total aggregate // Observations since the series was created.
+	slots []slot    // Observations in the sliding window, if any.
+}
+
+func (s *series) add(v float64, now time.Time, window time.Duration) {
+	s.lastUpdated = now
+	if window <= 0 {
+		s.total.add(s.metric, v)
+		return
+	}
+
+	if s.slots == nil {
+		s.slots = make([]slot, windowSlots)
+	}
+	slotSize := window / windowSlots
+	start := now.Truncate(slotSize)
+	sl := &s.slots[(start.UnixNano()/int64(slotSize))%windowSlots]
+	if !sl.start.Equal(start) {
+		*sl = slot{start: start}
+	}
+	sl.agg.add(s.metric, v)
+}
+
+// current returns the observations of the series, which are the
+// observations in the sliding window ending at now if window is set.
+func (s *series) current(now time.Time, window time.Duration) aggregate {
+	if window <= 0 {
+		return s.total
+	}
+
+	var res aggregate
+	cutoff := now.Add(-window)
+	for i := range s.slots {
+		if s.slots[i].start.After(cutoff) {
+			res.merge(&s.slots[i].agg)
+		}
+	}
+	if s.metric.typ == TypeHistogram && res.buckets == nil {
+		res.buckets = make([]float64, len(s.metric.buckets)+1)
+	}
+	return res
+}
+
+// samples calls fn for every sample of the series given its current
+// observations.
+func (s *series) samples(agg aggregate, fn func(lset labels.Labels, v float64)) {
+	if s.metric.typ == TypeCounter {
+		if s.metric.value == "" {
+			fn(s.labels, agg.count)
+		} else {
+			fn(s.labels, agg.sum)
+		}
+		return
+	}
+
+	name := s.metric.name
+	withName := func(suffix string, extra ...string) labels.Labels {
+		lb := labels.NewBuilder(s.labels)
+		lb.Set(labels.MetricName, name+suffix)
+		for i := 0; i+1 < len(extra); i += 2 {
+			lb.Set(extra[i], extra[i+1])
+		}
+		return lb.Labels()
+	}
+
+	var cumulative float64
+	for i, upper := range s.metric.buckets {
+		cumulative += agg.buckets[i]
+		fn(withName("_bucket", model.BucketLabel, strconv.FormatFloat(upper, 'g', -1, 64)), cumulative)
+	}
+	fn(withName("_bucket", model.BucketLabel, "+Inf"), agg.count)
+	fn(withName("_sum"), agg.sum)
+	fn(withName("_count"), agg.count)
+}
@@ -0,0 +1,258 @@
+package tometrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.tometrics",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the loki.tometrics
+// component.
+type Arguments struct {
+	ForwardTo       []storage.Appendable `river:"forward_to,attr"`
+	Window          time.Duration        `river:"window,attr,optional"`
+	FlushInterval   time.Duration        `river:"flush_interval,attr,optional"`
+	MaxIdleDuration time.Duration        `river:"max_idle_duration,attr,optional"`
+	Metrics         []MetricArguments    `river:"metric,block"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	FlushInterval:   15 * time.Second,
+	MaxIdleDuration: 5 * time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Window < 0 {
+		return fmt.Errorf("window must not be negative")
+	}
+	if args.Window > 0 && args.Window < windowSlots*time.Second {
+		return fmt.Errorf("window must be at least %s", windowSlots*time.Second)
+	}
+	if args.FlushInterval <= 0 {
+		return fmt.Errorf("flush_interval must be greater than zero")
+	}
+	if args.MaxIdleDuration <= 0 {
+		return fmt.Errorf("max_idle_duration must be greater than zero")
+	}
+	if args.Window > 0 && args.MaxIdleDuration < args.Window {
+		return fmt.Errorf("max_idle_duration must not be shorter than window")
+	}
+
+	names := make(map[string]struct{}, len(args.Metrics))
+	for _, m := range args.Metrics {
+		if _, ok := names[m.Name]; ok {
+			return fmt.Errorf("metric %q is defined more than once", m.Name)
+		}
+		names[m.Name] = struct{}{}
+	}
+	return nil
+}
+
+// Exports holds the values exported by the loki.tometrics component.
+type Exports struct {
+	Receiver loki.LogsReceiver `river:"receiver,attr"`
+}
+
+// Component implements the loki.tometrics component, which derives metrics
+// from log lines and forwards them to Prometheus components.
+type Component struct {
+	opts     component.Options
+	fanout   *agentprom.Fanout
+	receiver loki.LogsReceiver
+	now      func() time.Time
+
+	mut     sync.Mutex
+	args    Arguments
+	metrics []*metric
+	series  map[uint64]*series
+	stale   []*series     // Series to mark as stale on the next flush.
+	flush   chan struct{} // Notifies Run that the flush interval changed.
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new loki.tometrics component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:     o,
+		fanout:   agentprom.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls),
+		receiver: loki.NewLogsReceiver(),
+		now:      time.Now,
+		series:   make(map[uint64]*series),
+		flush:    make(chan struct{}, 1),
+	}
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	c.mut.Lock()
+	ticker := time.NewTicker(c.args.FlushInterval)
+	c.mut.Unlock()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			c.handleEntry(entry)
+		case <-c.flush:
+			c.mut.Lock()
+			ticker.Reset(c.args.FlushInterval)
+			c.mut.Unlock()
+		case <-ticker.C:
+			if err := c.flushSeries(ctx); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to forward metrics", "err", err)
+			}
+		}
+	}
+}
+
+// handleEntry updates the series of every metric the entry matches.
+func (c *Component) handleEntry(entry loki.Entry) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.now()
+	for _, m := range c.metrics {
+		obs, ok := m.observe(entry)
+		if !ok {
+			continue
+		}
+		hash := obs.labels.Hash()
+		s, ok := c.series[hash]
+		if !ok {
+			s = &series{metric: m, labels: obs.labels}
+			c.series[hash] = s
+		}
+		s.add(obs.value, now, c.args.Window)
+	}
+}
+
+// flushSeries appends the current samples of every series to the receivers,
+// and removes series which haven't been updated for longer than
+// max_idle_duration after marking them as stale.
+func (c *Component) flushSeries(ctx context.Context) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.now()
+	ts := timestamp.FromTime(now)
+
+	app := c.fanout.Appender(ctx)
+	var err error
+	appendSample := func(lset labels.Labels, v float64) {
+		if err == nil {
+			_, err = app.Append(0, lset, ts, v)
+		}
+	}
+	appendStale := func(lset labels.Labels, _ float64) {
+		appendSample(lset, math.Float64frombits(value.StaleNaN))
+	}
+
+	for _, s := range c.stale {
+		s.samples(s.current(now, c.args.Window), appendStale)
+	}
+	for hash, s := range c.series {
+		agg := s.current(now, c.args.Window)
+		if now.Sub(s.lastUpdated) > c.args.MaxIdleDuration {
+			s.samples(agg, appendStale)
+			delete(c.series, hash)
+			continue
+		}
+		s.samples(agg, appendSample)
+	}
+	if err != nil {
+		_ = app.Rollback()
+		return err
+	}
+	c.stale = nil
+	return app.Commit()
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	metrics := make([]*metric, 0, len(newArgs.Metrics))
+	for _, m := range newArgs.Metrics {
+		compiled, err := newMetric(m)
+		if err != nil {
+			return err
+		}
+		metrics = append(metrics, compiled)
+	}
+
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// Series computed with the previous configuration are discarded when the
+	// metrics or the window change.
+	if !reflect.DeepEqual(c.args.Metrics, newArgs.Metrics) || c.args.Window != newArgs.Window {
+		for _, s := range c.series {
+			c.stale = append(c.stale, s)
+		}
+		c.series = make(map[uint64]*series)
+	}
+	c.metrics = metrics
+
+	if c.args.FlushInterval != 0 && c.args.FlushInterval != newArgs.FlushInterval {
+		select {
+		case c.flush <- struct{}{}:
+		default:
+			// no-op: a ticker reset is already queued.
+		}
+	}
+	c.args = newArgs
+	return nil
+}
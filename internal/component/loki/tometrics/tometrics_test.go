@@ -0,0 +1,175 @@
+package tometrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	riverCfg := `
+		forward_to = []
+		window     = "1m"
+
+		metric {
+			name   = "http_requests_total"
+			regex  = "status=(?P<status>\\d+)"
+			labels = ["status", "app"]
+		}
+		metric {
+			name        = "http_request_duration_seconds"
+			type        = "histogram"
+			json_fields = { duration = "", method = "request.method" }
+			value       = "duration"
+			buckets     = [0.1, 1]
+		}
+`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(riverCfg), &args))
+	require.Equal(t, time.Minute, args.Window)
+	require.Equal(t, 5*time.Minute, args.MaxIdleDuration)
+	require.Len(t, args.Metrics, 2)
+	require.Equal(t, TypeCounter, args.Metrics[0].Type)
+	require.Equal(t, []float64{0.1, 1}, args.Metrics[1].Buckets)
+
+	require.EqualError(t, river.Unmarshal([]byte(`
+		forward_to = []
+		metric {
+			name = "latency"
+			type = "histogram"
+		}
+	`), &args), `metric "latency": value must be set for histograms`)
+}
+
+type testComponent struct {
+	*Component
+	samples map[string]float64
+	clock   time.Time
+}
+
+func newTestComponent(t *testing.T, args Arguments) *testComponent {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	tc := &testComponent{clock: time.Unix(1000, 0)}
+
+	receiver := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+		tc.samples[l.String()] = v
+		return ref, nil
+	}))
+	args.ForwardTo = []storage.Appendable{receiver}
+
+	c, err := New(component.Options{
+		ID:            "loki.tometrics.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+	c.now = func() time.Time { return tc.clock }
+	tc.Component = c
+	return tc
+}
+
+func (tc *testComponent) send(line string, lbls model.LabelSet) {
+	tc.handleEntry(loki.Entry{Labels: lbls, Entry: logproto.Entry{Timestamp: tc.clock, Line: line}})
+}
+
+func (tc *testComponent) collect(t *testing.T) map[string]float64 {
+	tc.samples = make(map[string]float64)
+	require.NoError(t, tc.flushSeries(context.Background()))
+	return tc.samples
+}
+
+func TestCounterAndHistogram(t *testing.T) {
+	args := DefaultArguments
+	args.Metrics = []MetricArguments{
+		{Name: "http_requests_total", Type: TypeCounter, Regex: `status=(?P<status>\d+)`, Labels: []string{"status", "app"}},
+		{Name: "http_bytes_total", Type: TypeCounter, Regex: `bytes=(?P<bytes>\d+)`, Value: "bytes"},
+		{
+			Name:       "http_request_duration_seconds",
+			Type:       TypeHistogram,
+			JSONFields: map[string]string{"duration": "", "method": "request.method"},
+			Value:      "duration",
+			Labels:     []string{"method"},
+			Buckets:    []float64{0.1, 1},
+		},
+	}
+	tc := newTestComponent(t, args)
+
+	app := model.LabelSet{"app": "web"}
+	tc.send("status=200 bytes=100", app)
+	tc.send("status=200 bytes=50", app)
+	tc.send("status=500", app)
+	tc.send("no status", app)
+	tc.send(`{"duration": 0.05, "request": {"method": "GET"}}`, nil)
+	tc.send(`{"duration": 0.5, "request": {"method": "GET"}}`, nil)
+	tc.send(`{"duration": 2, "request": {"method": "GET"}}`, nil)
+
+	require.Equal(t, map[string]float64{
+		`{__name__="http_requests_total", app="web", status="200"}`:                  2,
+		`{__name__="http_requests_total", app="web", status="500"}`:                  1,
+		`{__name__="http_bytes_total"}`:                                              150,
+		`{__name__="http_request_duration_seconds_bucket", le="0.1", method="GET"}`:  1,
+		`{__name__="http_request_duration_seconds_bucket", le="1", method="GET"}`:    2,
+		`{__name__="http_request_duration_seconds_bucket", le="+Inf", method="GET"}`: 3,
+		`{__name__="http_request_duration_seconds_sum", method="GET"}`:               2.55,
+		`{__name__="http_request_duration_seconds_count", method="GET"}`:             3,
+	}, tc.collect(t))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	args := DefaultArguments
+	args.Window = time.Minute
+	args.Metrics = []MetricArguments{{Name: "errors", Type: TypeCounter, Regex: "error"}}
+	tc := newTestComponent(t, args)
+
+	tc.send("error", nil)
+	tc.clock = tc.clock.Add(30 * time.Second)
+	tc.send("error", nil)
+	require.Equal(t, map[string]float64{`{__name__="errors"}`: 2}, tc.collect(t))
+
+	// The first error leaves the window.
+	tc.clock = tc.clock.Add(40 * time.Second)
+	require.Equal(t, map[string]float64{`{__name__="errors"}`: 1}, tc.collect(t))
+
+	tc.clock = tc.clock.Add(time.Minute)
+	require.Equal(t, map[string]float64{`{__name__="errors"}`: 0}, tc.collect(t))
+}
+
+func TestStaleSeries(t *testing.T) {
+	args := DefaultArguments
+	args.MaxIdleDuration = time.Minute
+	args.Metrics = []MetricArguments{{Name: "errors", Type: TypeCounter, Regex: "error"}}
+	tc := newTestComponent(t, args)
+
+	tc.send("error", nil)
+	require.Equal(t, map[string]float64{`{__name__="errors"}`: 1}, tc.collect(t))
+
+	tc.clock = tc.clock.Add(2 * time.Minute)
+	samples := tc.collect(t)
+	require.Len(t, samples, 1)
+	require.True(t, value.IsStaleNaN(samples[`{__name__="errors"}`]))
+
+	// Stale series are removed.
+	require.Empty(t, tc.collect(t))
+
+	// Series are created again when matching lines are received.
+	tc.send("error", nil)
+	require.Equal(t, map[string]float64{`{__name__="errors"}`: 1}, tc.collect(t))
+}
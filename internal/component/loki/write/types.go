@@ -17,19 +17,20 @@ import (
 
 // EndpointOptions describes an individual location to send logs to.
 type EndpointOptions struct {
-	Name              string                  `river:"name,attr,optional"`
-	URL               string                  `river:"url,attr"`
-	BatchWait         time.Duration           `river:"batch_wait,attr,optional"`
-	BatchSize         units.Base2Bytes        `river:"batch_size,attr,optional"`
-	RemoteTimeout     time.Duration           `river:"remote_timeout,attr,optional"`
-	Headers           map[string]string       `river:"headers,attr,optional"`
-	MinBackoff        time.Duration           `river:"min_backoff_period,attr,optional"`  // start backoff at this level
-	MaxBackoff        time.Duration           `river:"max_backoff_period,attr,optional"`  // increase exponentially to this level
-	MaxBackoffRetries int                     `river:"max_backoff_retries,attr,optional"` // give up after this many; zero means infinite retries
-	TenantID          string                  `river:"tenant_id,attr,optional"`
-	RetryOnHTTP429    bool                    `river:"retry_on_http_429,attr,optional"`
-	HTTPClientConfig  *types.HTTPClientConfig `river:",squash"`
-	QueueConfig       QueueConfig             `river:"queue_config,block,optional"`
+	Name              string                    `river:"name,attr,optional"`
+	URL               string                    `river:"url,attr"`
+	BatchWait         time.Duration             `river:"batch_wait,attr,optional"`
+	BatchSize         units.Base2Bytes          `river:"batch_size,attr,optional"`
+	RemoteTimeout     time.Duration             `river:"remote_timeout,attr,optional"`
+	Headers           map[string]string         `river:"headers,attr,optional"`
+	MinBackoff        time.Duration             `river:"min_backoff_period,attr,optional"`  // start backoff at this level
+	MaxBackoff        time.Duration             `river:"max_backoff_period,attr,optional"`  // increase exponentially to this level
+	MaxBackoffRetries int                       `river:"max_backoff_retries,attr,optional"` // give up after this many; zero means infinite retries
+	TenantID          string                    `river:"tenant_id,attr,optional"`
+	RetryOnHTTP429    bool                      `river:"retry_on_http_429,attr,optional"`
+	HTTPClientConfig  *types.HTTPClientConfig   `river:",squash"`
+	QueueConfig       QueueConfig               `river:"queue_config,block,optional"`
+	Transport         *types.TransportArguments `river:"transport,block,optional"`
 }
 
 // GetDefaultEndpointOptions defines the default settings for sending logs to a
@@ -63,6 +64,11 @@ func (r *EndpointOptions) Validate() error {
 		return fmt.Errorf("failed to parse remote url %q: %w", r.URL, err)
 	}
 
+	// loki.write disables HTTP/2, and the size of its connection pool is fixed.
+	if err := r.Transport.CheckSupported("loki.write", types.TransportIdleConnTimeout, types.TransportTCPKeepAlive); err != nil {
+		return err
+	}
+
 	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
 	if r.HTTPClientConfig != nil {
 		return r.HTTPClientConfig.Validate()
@@ -110,6 +116,7 @@ func (args Arguments) convertClientConfigs() []client.Config {
 				Capacity:     int(cfg.QueueConfig.Capacity),
 				DrainTimeout: cfg.QueueConfig.DrainTimeout,
 			},
+			Transport: cfg.Transport,
 		}
 		res = append(res, cc)
 	}
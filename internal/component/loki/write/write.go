@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"time"
 
@@ -12,8 +13,10 @@ import (
 	"github.com/grafana/agent/internal/component/common/loki"
 	"github.com/grafana/agent/internal/component/common/loki/client"
 	"github.com/grafana/agent/internal/component/common/loki/limit"
+	"github.com/grafana/agent/internal/component/common/loki/utils"
 	"github.com/grafana/agent/internal/component/common/loki/wal"
 	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/drops"
 )
 
 func init() {
@@ -34,14 +37,33 @@ type Arguments struct {
 	Endpoints      []EndpointOptions `river:"endpoint,block,optional"`
 	ExternalLabels map[string]string `river:"external_labels,attr,optional"`
 	MaxStreams     int               `river:"max_streams,attr,optional"`
+	StopTimeout    time.Duration     `river:"stop_timeout,attr,optional"`
 	WAL            WalArguments      `river:"wal,block,optional"`
 }
 
+// DefaultStopTimeout is how long stopping the clients waits for in-flight
+// requests before cancelling them.
+const DefaultStopTimeout = time.Minute
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = Arguments{StopTimeout: DefaultStopTimeout}
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.StopTimeout < 0 {
+		return fmt.Errorf("stop_timeout must not be negative")
+	}
+	return nil
+}
+
 // WalArguments holds the settings for configuring the Write-Ahead Log (WAL) used
 // by the underlying remote write client.
 type WalArguments struct {
 	Enabled          bool          `river:"enabled,attr,optional"`
 	MaxSegmentAge    time.Duration `river:"max_segment_age,attr,optional"`
+	MaxUnsentAge     time.Duration `river:"max_unsent_segment_age,attr,optional"`
 	MinReadFrequency time.Duration `river:"min_read_frequency,attr,optional"`
 	MaxReadFrequency time.Duration `river:"max_read_frequency,attr,optional"`
 	DrainTimeout     time.Duration `river:"drain_timeout,attr,optional"`
@@ -51,6 +73,9 @@ func (wa *WalArguments) Validate() error {
 	if wa.MinReadFrequency >= wa.MaxReadFrequency {
 		return fmt.Errorf("WAL min read frequency should be lower than max read frequency")
 	}
+	if wa.MaxUnsentAge != 0 && wa.MaxUnsentAge < wa.MaxSegmentAge {
+		return fmt.Errorf("WAL max unsent segment age should not be lower than max segment age")
+	}
 	return nil
 }
 
@@ -100,6 +125,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		opts:    o,
 		metrics: client.NewMetrics(o.Registerer),
 	}
+	c.metrics.RecordDrops(drops.NewRecorder(o, drops.SignalLogs))
 
 	// Create and immediately export the receiver which remains the same for
 	// the component's lifetime.
@@ -123,8 +149,11 @@ func (c *Component) Run(ctx context.Context) error {
 			c.walWriter.Stop()
 		}
 		if c.clientManger != nil {
+			c.mut.RLock()
+			stopTimeout := c.args.StopTimeout
+			c.mut.RUnlock()
 			// drain, since the component is shutting down. That means the agent is shutting down as well
-			c.clientManger.StopWithDrain(true)
+			c.clientManger.StopWithTimeout(true, stopTimeout)
 		}
 	}()
 
@@ -151,6 +180,14 @@ func (c *Component) Update(args component.Arguments) error {
 
 	c.mut.Lock()
 	defer c.mut.Unlock()
+
+	// External labels are usually set from the exports of other components,
+	// and can change often. They're updated without restarting the clients.
+	if c.clientManger != nil && onlyExternalLabelsChanged(c.args, newArgs) {
+		c.clientManger.SetExternalLabels(utils.ToLabelSet(newArgs.ExternalLabels))
+		c.args = newArgs
+		return nil
+	}
 	c.args = newArgs
 
 	if c.walWriter != nil {
@@ -158,7 +195,7 @@ func (c *Component) Update(args component.Arguments) error {
 	}
 	if c.clientManger != nil {
 		// only drain on component shutdown
-		c.clientManger.Stop()
+		c.clientManger.StopWithTimeout(false, newArgs.StopTimeout)
 	}
 
 	cfgs := newArgs.convertClientConfigs()
@@ -172,8 +209,9 @@ func (c *Component) Update(args component.Arguments) error {
 		cfgs[i].Headers[agentseed.HeaderName] = uid
 	}
 	walCfg := wal.Config{
-		Enabled:       newArgs.WAL.Enabled,
-		MaxSegmentAge: newArgs.WAL.MaxSegmentAge,
+		Enabled:             newArgs.WAL.Enabled,
+		MaxSegmentAge:       newArgs.WAL.MaxSegmentAge,
+		MaxUnsentSegmentAge: newArgs.WAL.MaxUnsentAge,
 		WatchConfig: wal.WatchConfig{
 			MinReadFrequency: newArgs.WAL.MinReadFrequency,
 			MaxReadFrequency: newArgs.WAL.MaxReadFrequency,
@@ -213,3 +251,10 @@ func (c *Component) Update(args component.Arguments) error {
 
 	return err
 }
+
+// onlyExternalLabelsChanged returns whether prev and next only differ by
+// their external labels.
+func onlyExternalLabelsChanged(prev, next Arguments) bool {
+	prev.ExternalLabels, next.ExternalLabels = nil, nil
+	return reflect.DeepEqual(prev, next)
+}
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/component/common/loki"
 	"github.com/grafana/agent/internal/component/common/loki/wal"
 	"github.com/grafana/agent/internal/component/discovery"
@@ -17,6 +18,7 @@ import (
 	"github.com/grafana/agent/internal/flow/componenttest"
 	"github.com/grafana/agent/internal/util"
 	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
@@ -37,6 +39,7 @@ func TestRiverConfig(t *testing.T) {
 	var args Arguments
 	err := river.Unmarshal([]byte(exampleRiverConfig), &args)
 	require.NoError(t, err)
+	require.Equal(t, DefaultStopTimeout, args.StopTimeout)
 }
 
 func TestBadRiverConfig(t *testing.T) {
@@ -201,6 +204,71 @@ func testSingleEndpoint(t *testing.T, alterConfig func(arguments *Arguments)) {
 	}
 }
 
+func TestUpdateExternalLabels(t *testing.T) {
+	t.Run("wal disabled", func(t *testing.T) {
+		testUpdateExternalLabels(t, func(args *Arguments) {})
+	})
+
+	t.Run("wal enabled", func(t *testing.T) {
+		testUpdateExternalLabels(t, func(args *Arguments) {
+			args.WAL.Enabled = true
+		})
+	})
+}
+
+func testUpdateExternalLabels(t *testing.T, alterConfig func(arguments *Arguments)) {
+	ch := make(chan logproto.PushRequest)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pushReq logproto.PushRequest
+		require.NoError(t, loki_util.ParseProtoReader(context.Background(), r.Body, int(r.ContentLength), math.MaxInt32, &pushReq, loki_util.RawSnappy))
+		ch <- pushReq
+	}))
+	defer srv.Close()
+
+	cfg := fmt.Sprintf(`
+		endpoint {
+			url        = "%s"
+			batch_wait = "10ms"
+		}
+		external_labels = { "region" = "eu-west-1" }
+	`, srv.URL)
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+	alterConfig(&args)
+
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		DataPath:      t.TempDir(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+	go c.Run(componenttest.TestContext(t))
+
+	send := func(wantLabels string) {
+		t.Helper()
+		c.receiver.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"foo": "bar"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: "very important log"},
+		}
+		select {
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for logs")
+		case req := <-ch:
+			require.Len(t, req.Streams, 1)
+			require.Equal(t, wantLabels, req.Streams[0].Labels)
+		}
+	}
+	send(`{foo="bar", region="eu-west-1"}`)
+
+	// Changing the external labels doesn't restart the clients.
+	manager := c.clientManger
+	args.ExternalLabels = map[string]string{"region": "eu-west-2"}
+	require.NoError(t, c.Update(args))
+	require.Same(t, manager, c.clientManger)
+	send(`{foo="bar", region="eu-west-2"}`)
+}
+
 func TestEntrySentToTwoWriteComponents(t *testing.T) {
 	t.Run("wal disabled", func(t *testing.T) {
 		testMultipleEndpoint(t, func(arguments *Arguments) {})
@@ -404,3 +472,34 @@ func benchSingleEndpoint(b *testing.B, tc testCase, alterConfig func(arguments *
 		}, time.Minute, time.Second, "haven't seen expected number of lines")
 	}
 }
+
+func TestTransportRiverConfig(t *testing.T) {
+	var args Arguments
+	err := river.Unmarshal([]byte(`
+	endpoint {
+		url = "http://0.0.0.0:11111/loki/api/v1/push"
+
+		transport {
+			idle_conn_timeout = "30s"
+			tcp_keepalive     = "15s"
+		}
+	}
+	`), &args)
+	require.NoError(t, err)
+
+	cfgs := args.convertClientConfigs()
+	require.Len(t, cfgs, 1)
+	require.Equal(t, 30*time.Second, cfgs[0].Transport.IdleConnTimeout)
+	require.Equal(t, 15*time.Second, cfgs[0].Transport.TCPKeepAlive)
+
+	err = river.Unmarshal([]byte(`
+	endpoint {
+		url = "http://0.0.0.0:11111/loki/api/v1/push"
+
+		transport {
+			http2_ping_interval = "1m"
+		}
+	}
+	`), &args)
+	require.EqualError(t, err, "transport: http2_ping_interval is not supported by loki.write")
+}
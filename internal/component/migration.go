@@ -0,0 +1,217 @@
+package component
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/river/ast"
+)
+
+// Migration upgrades the body of a component block written for the previous
+// version of the arguments of the component, such as after an argument was
+// renamed.
+//
+// The version of a block isn't written in the configuration, so every
+// migration of a component is applied in order to every block of the
+// component. Migrate must leave bodies which were already upgraded unchanged.
+type Migration struct {
+	// Version is the version of the arguments the migration upgrades to. The
+	// first version of the arguments of every component is 1, so the first
+	// migration of a component upgrades to version 2.
+	Version int
+
+	// Description explains what the migration changes, such as
+	// `the "url" argument was renamed to "address"`.
+	Description string
+
+	// Migrate returns the upgraded body and whether body was changed. Migrate
+	// must not modify body in place, as body may still be in use.
+	Migrate func(body ast.Body) (ast.Body, bool, error)
+}
+
+// Version returns the current version of the arguments of the component,
+// which is the version of its last migration.
+func (r Registration) Version() int {
+	if len(r.Migrations) == 0 {
+		return 1
+	}
+	return r.Migrations[len(r.Migrations)-1].Version
+}
+
+// Migrate applies the migrations of the component to block. It returns a
+// copy of block with the upgraded body, along with the migrations which
+// changed it. Migrate returns block itself when no migration changed it.
+func (r Registration) Migrate(block *ast.BlockStmt) (*ast.BlockStmt, []Migration, error) {
+	var (
+		body    = block.Body
+		applied []Migration
+	)
+	for _, m := range r.Migrations {
+		newBody, changed, err := m.Migrate(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrating to version %d: %w", m.Version, err)
+		}
+		if changed {
+			body = newBody
+			applied = append(applied, m)
+		}
+	}
+	if len(applied) == 0 {
+		return block, nil, nil
+	}
+
+	migrated := *block
+	migrated.Body = body
+	return &migrated, applied, nil
+}
+
+// validateMigrations returns an error if the versions of migrations aren't
+// consecutive, starting from version 2, or if a migration can't be applied.
+func validateMigrations(migrations []Migration) error {
+	for i, m := range migrations {
+		if expect := i + 2; m.Version != expect {
+			return fmt.Errorf("migration %d must upgrade to version %d, got %d", i, expect, m.Version)
+		}
+		if m.Migrate == nil {
+			return fmt.Errorf("migration to version %d must have a Migrate function", m.Version)
+		}
+	}
+	return nil
+}
+
+// RenameAttribute returns a Migration which renames the attribute from of a
+// component block to to. Migrating fails if both attributes are set.
+func RenameAttribute(version int, from, to string) Migration {
+	return Migration{
+		Version:     version,
+		Description: fmt.Sprintf("the %q argument was renamed to %q", from, to),
+		Migrate: func(body ast.Body) (ast.Body, bool, error) {
+			return renameStmt(body, from, to, func(stmt ast.Stmt) (string, ast.Stmt, bool) {
+				attr, ok := stmt.(*ast.AttributeStmt)
+				if !ok {
+					return "", nil, false
+				}
+				renamed := &ast.AttributeStmt{
+					Name:  &ast.Ident{Name: to, NamePos: attr.Name.NamePos},
+					Value: attr.Value,
+				}
+				return attr.Name.Name, renamed, true
+			})
+		},
+	}
+}
+
+// RenameBlock returns a Migration which renames the blocks from of a
+// component block to to. Migrating fails if blocks with both names are set.
+func RenameBlock(version int, from, to string) Migration {
+	return Migration{
+		Version:     version,
+		Description: fmt.Sprintf("the %q block was renamed to %q", from, to),
+		Migrate: func(body ast.Body) (ast.Body, bool, error) {
+			return renameStmt(body, from, to, func(stmt ast.Stmt) (string, ast.Stmt, bool) {
+				block, ok := stmt.(*ast.BlockStmt)
+				if !ok {
+					return "", nil, false
+				}
+				renamed := *block
+				renamed.Name = strings.Split(to, ".")
+				return block.GetBlockName(), &renamed, true
+			})
+		},
+	}
+}
+
+// renameStmt renames the statements of body called from to to. rename
+// returns the name of a statement and its renamed copy, and false for
+// statements of the wrong kind.
+func renameStmt(body ast.Body, from, to string, rename func(ast.Stmt) (string, ast.Stmt, bool)) (ast.Body, bool, error) {
+	var (
+		res              = make(ast.Body, len(body))
+		fromStmt, toStmt ast.Stmt
+	)
+	for i, stmt := range body {
+		res[i] = stmt
+
+		name, renamed, ok := rename(stmt)
+		switch {
+		case !ok:
+		case name == from:
+			res[i], fromStmt = renamed, stmt
+		case name == to:
+			toStmt = stmt
+		}
+	}
+
+	switch {
+	case fromStmt == nil:
+		return body, false, nil
+	case toStmt != nil:
+		return nil, false, fmt.Errorf("%s: %q can't be set along with %q, which replaces it, at %s",
+			ast.StartPos(fromStmt).Position(), from, to, ast.StartPos(toStmt).Position())
+	}
+	return res, true, nil
+}
+
+// NestAttributes returns a Migration which moves the attributes called names
+// of a component block into a new block called block. Migrating fails if the
+// block is already set along with any of the attributes.
+func NestAttributes(version int, block string, names ...string) Migration {
+	return Migration{
+		Version:     version,
+		Description: fmt.Sprintf("the %s arguments were moved into the %q block", strings.Join(quoteAll(names), ", "), block),
+		Migrate: func(body ast.Body) (ast.Body, bool, error) {
+			move := make(map[string]struct{}, len(names))
+			for _, name := range names {
+				move[name] = struct{}{}
+			}
+
+			var (
+				res    = make(ast.Body, 0, len(body))
+				nested *ast.BlockStmt
+				exist  ast.Stmt
+			)
+			for _, stmt := range body {
+				switch stmt := stmt.(type) {
+				case *ast.AttributeStmt:
+					if _, ok := move[stmt.Name.Name]; ok {
+						if nested == nil {
+							// The new block takes the place of the first attribute
+							// being moved.
+							nested = &ast.BlockStmt{
+								Name:      strings.Split(block, "."),
+								NamePos:   stmt.Name.NamePos,
+								LCurlyPos: stmt.Name.NamePos,
+							}
+							res = append(res, nested)
+						}
+						nested.Body = append(nested.Body, stmt)
+						nested.RCurlyPos = ast.EndPos(stmt)
+						continue
+					}
+				case *ast.BlockStmt:
+					if stmt.GetBlockName() == block {
+						exist = stmt
+					}
+				}
+				res = append(res, stmt)
+			}
+
+			switch {
+			case nested == nil:
+				return body, false, nil
+			case exist != nil:
+				return nil, false, fmt.Errorf("%s: %s can't be set along with the %q block at %s",
+					ast.StartPos(nested).Position(), strings.Join(quoteAll(names), ", "), block, ast.StartPos(exist).Position())
+			}
+			return res, true, nil
+		},
+	}
+}
+
+func quoteAll(ss []string) []string {
+	res := make([]string, len(ss))
+	for i, s := range ss {
+		res[i] = fmt.Sprintf("%q", s)
+	}
+	return res
+}
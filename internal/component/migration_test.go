@@ -0,0 +1,143 @@
+package component
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/printer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistration_Migrate(t *testing.T) {
+	reg := Registration{
+		Name: "remote.example",
+		Migrations: []Migration{
+			RenameAttribute(2, "url", "address"),
+			NestAttributes(3, "auth", "username", "password"),
+			RenameBlock(4, "tls", "tls_config"),
+		},
+	}
+	require.Equal(t, 4, reg.Version())
+
+	tt := []struct {
+		name      string
+		input     string
+		expect    string
+		versions  []int
+		expectErr string
+	}{
+		{
+			name: "outdated",
+			input: `remote.example "default" {
+	url      = "http://localhost"
+	username = "user"
+	password = "pass"
+	timeout  = "10s"
+
+	tls {
+		insecure_skip_verify = true
+	}
+}`,
+			expect: `remote.example "default" {
+	address = "http://localhost"
+
+	auth {
+		username = "user"
+		password = "pass"
+	}
+	timeout = "10s"
+
+	tls_config {
+		insecure_skip_verify = true
+	}
+}`,
+			versions: []int{2, 3, 4},
+		},
+		{
+			name: "partially outdated",
+			input: `remote.example "default" {
+	address = "http://localhost"
+
+	tls {
+		insecure_skip_verify = true
+	}
+}`,
+			expect: `remote.example "default" {
+	address = "http://localhost"
+
+	tls_config {
+		insecure_skip_verify = true
+	}
+}`,
+			versions: []int{4},
+		},
+		{
+			name: "up to date",
+			input: `remote.example "default" {
+	address = "http://localhost"
+}`,
+			expect: `remote.example "default" {
+	address = "http://localhost"
+}`,
+		},
+		{
+			name: "renamed attribute set twice",
+			input: `remote.example "default" {
+	url     = "http://localhost"
+	address = "http://localhost"
+}`,
+			expectErr: `migrating to version 2: 2:2: "url" can't be set along with "address", which replaces it, at 3:2`,
+		},
+		{
+			name: "nested attributes set twice",
+			input: `remote.example "default" {
+	username = "user"
+
+	auth {
+		password = "pass"
+	}
+}`,
+			expectErr: `migrating to version 3: 2:2: "username", "password" can't be set along with the "auth" block at 4:2`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := parser.ParseFile("", []byte(tc.input))
+			require.NoError(t, err)
+			block := f.Body[0].(*ast.BlockStmt)
+
+			migrated, applied, err := reg.Migrate(block)
+			if tc.expectErr != "" {
+				require.EqualError(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var versions []int
+			for _, m := range applied {
+				versions = append(versions, m.Version)
+			}
+			require.Equal(t, tc.versions, versions)
+
+			var buf bytes.Buffer
+			require.NoError(t, printer.Fprint(&buf, migrated))
+			require.Equal(t, tc.expect, buf.String())
+
+			// The block being migrated is left unchanged.
+			buf.Reset()
+			require.NoError(t, printer.Fprint(&buf, block))
+			require.Equal(t, tc.input, buf.String())
+		})
+	}
+}
+
+func Test_validateMigrations(t *testing.T) {
+	require.NoError(t, validateMigrations(nil))
+	require.NoError(t, validateMigrations([]Migration{RenameAttribute(2, "a", "b"), RenameAttribute(3, "b", "c")}))
+
+	require.EqualError(t, validateMigrations([]Migration{RenameAttribute(3, "a", "b")}), "migration 0 must upgrade to version 2, got 3")
+	require.EqualError(t, validateMigrations([]Migration{{Version: 2}}), "migration to version 2 must have a Migrate function")
+}
@@ -0,0 +1,240 @@
+package source
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/river/rivertypes"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Protocol versions supported by the component.
+const (
+	ProtocolV311 = "3.1.1"
+	ProtocolV5   = "5"
+)
+
+// Payload formats supported by the component.
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatBinary = "binary"
+)
+
+// Timestamp formats supported by the component.
+const (
+	TimestampUnix    = "unix"
+	TimestampUnixMs  = "unix_ms"
+	TimestampRFC3339 = "rfc3339"
+)
+
+// Arguments holds values which are used to configure the mqtt.source
+// component.
+type Arguments struct {
+	BrokerURL       string            `river:"broker_url,attr"`
+	ProtocolVersion string            `river:"protocol_version,attr,optional"`
+	ClientID        string            `river:"client_id,attr,optional"`
+	Username        string            `river:"username,attr,optional"`
+	Password        rivertypes.Secret `river:"password,attr,optional"`
+	KeepAlive       time.Duration     `river:"keep_alive,attr,optional"`
+	ConnectTimeout  time.Duration     `river:"connect_timeout,attr,optional"`
+	CleanSession    bool              `river:"clean_session,attr,optional"`
+	TLSConfig       config.TLSConfig  `river:"tls_config,block,optional"`
+
+	Subscriptions []SubscriptionArguments `river:"subscription,block"`
+	Output        OutputArguments         `river:"output,block"`
+}
+
+// SubscriptionArguments configures a topic to subscribe to, and how to map
+// its messages to metrics and log entries.
+type SubscriptionArguments struct {
+	Topic           string            `river:"topic,attr"`
+	QoS             int               `river:"qos,attr,optional"`
+	Format          string            `river:"format,attr,optional"`
+	ByteOrder       string            `river:"byte_order,attr,optional"`
+	TopicLabels     map[string]int    `river:"topic_labels,attr,optional"`
+	Timestamp       string            `river:"timestamp,attr,optional"`
+	TimestampFormat string            `river:"timestamp_format,attr,optional"`
+	Fields          []FieldArguments  `river:"field,block,optional"`
+	Metrics         []MetricArguments `river:"metric,block,optional"`
+	Log             *LogArguments     `river:"log,block,optional"`
+}
+
+// FieldArguments decodes a field of a binary payload.
+type FieldArguments struct {
+	Name   string  `river:"name,attr"`
+	Offset int     `river:"offset,attr"`
+	Type   string  `river:"type,attr"`
+	Scale  float64 `river:"scale,attr,optional"`
+}
+
+// MetricArguments maps the messages of a subscription to samples of a
+// metric.
+type MetricArguments struct {
+	Name   string            `river:"name,attr"`
+	Value  string            `river:"value,attr,optional"`
+	Labels map[string]string `river:"labels,attr,optional"`
+}
+
+// LogArguments maps the messages of a subscription to log entries.
+type LogArguments struct {
+	Line   string            `river:"line,attr,optional"`
+	Labels map[string]string `river:"labels,attr,optional"`
+}
+
+// OutputArguments holds where metrics and log entries are sent to.
+type OutputArguments struct {
+	Metrics []storage.Appendable `river:"metrics,attr,optional"`
+	Logs    []loki.LogsReceiver  `river:"logs,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		ProtocolVersion: ProtocolV311,
+		KeepAlive:       30 * time.Second,
+		ConnectTimeout:  10 * time.Second,
+		CleanSession:    true,
+	}
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	u, err := url.Parse(args.BrokerURL)
+	if err != nil {
+		return fmt.Errorf("invalid broker_url: %w", err)
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return fmt.Errorf("unsupported broker_url scheme %q, must be one of tcp, mqtt, ssl, tls, mqtts, ws or wss", u.Scheme)
+	}
+	if args.ProtocolVersion != ProtocolV311 && args.ProtocolVersion != ProtocolV5 {
+		return fmt.Errorf("protocol_version must be %q or %q, got %q", ProtocolV311, ProtocolV5, args.ProtocolVersion)
+	}
+	if args.KeepAlive < time.Second {
+		return fmt.Errorf("keep_alive must be at least 1s")
+	}
+	if args.ConnectTimeout <= 0 {
+		return fmt.Errorf("connect_timeout must be greater than 0")
+	}
+	if args.Password != "" && args.Username == "" {
+		return fmt.Errorf("username must be set when password is set")
+	}
+	if len(args.Subscriptions) == 0 {
+		return fmt.Errorf("at least one subscription block must be set")
+	}
+	return args.TLSConfig.Validate()
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *SubscriptionArguments) SetToDefault() {
+	*args = SubscriptionArguments{
+		Format:          FormatJSON,
+		ByteOrder:       "big",
+		TimestampFormat: TimestampUnix,
+	}
+}
+
+// Validate implements river.Validator.
+func (args *SubscriptionArguments) Validate() error {
+	if args.Topic == "" {
+		return fmt.Errorf("topic must not be empty")
+	}
+	if args.QoS < 0 || args.QoS > 2 {
+		return fmt.Errorf("qos must be 0, 1 or 2, got %d", args.QoS)
+	}
+	switch args.Format {
+	case FormatJSON, FormatText:
+		if len(args.Fields) > 0 {
+			return fmt.Errorf("field blocks can only be set for the %s format", FormatBinary)
+		}
+	case FormatBinary:
+		if len(args.Fields) == 0 {
+			return fmt.Errorf("at least one field block must be set for the %s format", FormatBinary)
+		}
+	default:
+		return fmt.Errorf("format must be one of %s, %s or %s, got %q", FormatJSON, FormatText, FormatBinary, args.Format)
+	}
+	if args.ByteOrder != "big" && args.ByteOrder != "little" {
+		return fmt.Errorf("byte_order must be \"big\" or \"little\", got %q", args.ByteOrder)
+	}
+	switch args.TimestampFormat {
+	case TimestampUnix, TimestampUnixMs, TimestampRFC3339:
+	default:
+		return fmt.Errorf("timestamp_format must be one of %s, %s or %s, got %q", TimestampUnix, TimestampUnixMs, TimestampRFC3339, args.TimestampFormat)
+	}
+	for name, level := range args.TopicLabels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid topic label name %q", name)
+		}
+		if level < 0 {
+			return fmt.Errorf("the level of topic label %q must not be negative", name)
+		}
+	}
+	mappedLabels := make([]map[string]string, 0, len(args.Metrics)+1)
+	for _, m := range args.Metrics {
+		mappedLabels = append(mappedLabels, m.Labels)
+	}
+	if args.Log != nil {
+		mappedLabels = append(mappedLabels, args.Log.Labels)
+	}
+	for _, lbls := range mappedLabels {
+		for name := range lbls {
+			if _, ok := args.TopicLabels[name]; ok {
+				return fmt.Errorf("label %q is set both by topic_labels and by a mapping", name)
+			}
+		}
+	}
+	if len(args.Metrics) == 0 && args.Log == nil {
+		return fmt.Errorf("at least one metric or log block must be set for topic %q", args.Topic)
+	}
+	return nil
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *FieldArguments) SetToDefault() {
+	*args = FieldArguments{Scale: 1}
+}
+
+// Validate implements river.Validator.
+func (args *FieldArguments) Validate() error {
+	if args.Offset < 0 {
+		return fmt.Errorf("the offset of field %q must not be negative", args.Name)
+	}
+	if _, ok := fieldSizes[args.Type]; !ok {
+		return fmt.Errorf("unsupported type %q for field %q", args.Type, args.Name)
+	}
+	return nil
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *MetricArguments) SetToDefault() {
+	*args = MetricArguments{Value: "@"}
+}
+
+// Validate implements river.Validator.
+func (args *MetricArguments) Validate() error {
+	if !model.IsValidMetricName(model.LabelValue(args.Name)) {
+		return fmt.Errorf("invalid metric name %q", args.Name)
+	}
+	return validateLabelNames(args.Labels)
+}
+
+// Validate implements river.Validator.
+func (args *LogArguments) Validate() error {
+	return validateLabelNames(args.Labels)
+}
+
+func validateLabelNames(labels map[string]string) error {
+	for name := range labels {
+		if !model.LabelName(name).IsValid() || name == model.MetricNameLabel {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+// disconnectTimeout is how long clients wait for the broker to acknowledge
+// disconnecting.
+const disconnectTimeout = 5 * time.Second
+
+// sessionExpiry is how long an MQTT v5 broker keeps the session of the
+// component when clean_session is false.
+const sessionExpiry = 24 * time.Hour
+
+// clientConfig configures a client.
+type clientConfig struct {
+	BrokerURL      *url.URL
+	ClientID       string
+	Username       string
+	Password       string
+	KeepAlive      time.Duration
+	ConnectTimeout time.Duration
+	CleanSession   bool
+	TLS            *tls.Config
+
+	// Subscriptions holds the topic filters to subscribe to and their QoS.
+	Subscriptions map[string]byte
+
+	// OnMessage is called for every message received on the subscriptions.
+	OnMessage func(topic string, payload []byte)
+	// OnConnectionChange is called when the client connects or loses its
+	// connection. err is nil when the client is connected.
+	OnConnectionChange func(err error)
+
+	Logger log.Logger
+}
+
+// client subscribes to topics of an MQTT broker.
+type client interface {
+	// Run connects to the broker and subscribes to the topics, reconnecting
+	// and subscribing again whenever the connection is lost, until ctx is
+	// canceled.
+	Run(ctx context.Context)
+}
+
+func newClient(protocolVersion string, cfg clientConfig) client {
+	if protocolVersion == ProtocolV5 {
+		return &v5Client{cfg: cfg}
+	}
+	return &v3Client{cfg: cfg}
+}
+
+// v3Client is a client for MQTT v3.1.1 brokers.
+type v3Client struct {
+	cfg clientConfig
+}
+
+// Run implements client.
+func (c *v3Client) Run(ctx context.Context) {
+	handler := func(_ mqtt.Client, msg mqtt.Message) {
+		c.cfg.OnMessage(msg.Topic(), msg.Payload())
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(c.cfg.BrokerURL.String()).
+		SetProtocolVersion(4). // 3.1.1, without falling back to 3.1.
+		SetClientID(c.cfg.ClientID).
+		SetUsername(c.cfg.Username).
+		SetPassword(c.cfg.Password).
+		SetKeepAlive(c.cfg.KeepAlive).
+		SetConnectTimeout(c.cfg.ConnectTimeout).
+		SetCleanSession(c.cfg.CleanSession).
+		SetTLSConfig(c.cfg.TLS).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(cli mqtt.Client) {
+			// Subscriptions are made again on every connection, as the broker
+			// may not have kept the session.
+			token := cli.SubscribeMultiple(c.cfg.Subscriptions, handler)
+			if token.WaitTimeout(c.cfg.ConnectTimeout) && token.Error() != nil {
+				level.Error(c.cfg.Logger).Log("msg", "failed to subscribe", "err", token.Error())
+				c.cfg.OnConnectionChange(fmt.Errorf("failed to subscribe: %w", token.Error()))
+				return
+			}
+			c.cfg.OnConnectionChange(nil)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			level.Warn(c.cfg.Logger).Log("msg", "lost connection to the broker", "err", err)
+			c.cfg.OnConnectionChange(fmt.Errorf("lost connection to the broker: %w", err))
+		})
+
+	cli := mqtt.NewClient(opts)
+	// With ConnectRetry, the token only completes once connected, so it
+	// isn't waited on.
+	cli.Connect()
+
+	<-ctx.Done()
+	cli.Disconnect(uint(disconnectTimeout.Milliseconds()))
+}
+
+// v5Client is a client for MQTT v5 brokers.
+type v5Client struct {
+	cfg clientConfig
+}
+
+// Run implements client.
+func (c *v5Client) Run(ctx context.Context) {
+	subscribe := &paho.Subscribe{}
+	for topic, qos := range c.cfg.Subscriptions {
+		subscribe.Subscriptions = append(subscribe.Subscriptions, paho.SubscribeOptions{Topic: topic, QoS: qos})
+	}
+
+	cfg := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{c.cfg.BrokerURL},
+		TlsCfg:                        c.cfg.TLS,
+		KeepAlive:                     uint16(c.cfg.KeepAlive.Seconds()),
+		CleanStartOnInitialConnection: c.cfg.CleanSession,
+		ConnectTimeout:                c.cfg.ConnectTimeout,
+		ConnectUsername:               c.cfg.Username,
+		ConnectPassword:               []byte(c.cfg.Password),
+
+		OnConnectionUp: func(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+			subCtx, cancel := context.WithTimeout(ctx, c.cfg.ConnectTimeout)
+			defer cancel()
+			if _, err := cm.Subscribe(subCtx, subscribe); err != nil {
+				level.Error(c.cfg.Logger).Log("msg", "failed to subscribe", "err", err)
+				c.cfg.OnConnectionChange(fmt.Errorf("failed to subscribe: %w", err))
+				return
+			}
+			c.cfg.OnConnectionChange(nil)
+		},
+		OnConnectError: func(err error) {
+			level.Warn(c.cfg.Logger).Log("msg", "failed to connect to the broker", "err", err)
+			c.cfg.OnConnectionChange(fmt.Errorf("failed to connect to the broker: %w", err))
+		},
+
+		ClientConfig: paho.ClientConfig{
+			ClientID: c.cfg.ClientID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				func(pr paho.PublishReceived) (bool, error) {
+					c.cfg.OnMessage(pr.Packet.Topic, pr.Packet.Payload)
+					return true, nil
+				},
+			},
+			OnClientError: func(err error) {
+				level.Warn(c.cfg.Logger).Log("msg", "lost connection to the broker", "err", err)
+				c.cfg.OnConnectionChange(fmt.Errorf("lost connection to the broker: %w", err))
+			},
+			OnServerDisconnect: func(d *paho.Disconnect) {
+				level.Warn(c.cfg.Logger).Log("msg", "broker closed the connection", "reason_code", d.ReasonCode)
+				c.cfg.OnConnectionChange(fmt.Errorf("broker closed the connection with reason code %d", d.ReasonCode))
+			},
+		},
+	}
+	if !c.cfg.CleanSession {
+		cfg.SessionExpiryInterval = uint32(sessionExpiry.Seconds())
+	}
+
+	// The connection has its own context, so that it's still open when
+	// disconnecting once ctx is canceled.
+	connCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cm, err := autopaho.NewConnection(connCtx, cfg)
+	if err != nil {
+		// Only returned for invalid configurations, which are validated
+		// beforehand.
+		level.Error(c.cfg.Logger).Log("msg", "failed to create the MQTT client", "err", err)
+		c.cfg.OnConnectionChange(err)
+		<-ctx.Done()
+		return
+	}
+
+	<-ctx.Done()
+	disconnectCtx, disconnectCancel := context.WithTimeout(context.Background(), disconnectTimeout)
+	defer disconnectCancel()
+	_ = cm.Disconnect(disconnectCtx)
+}
@@ -0,0 +1,356 @@
+package source
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// fieldSizes holds the size in bytes of the types of binary fields.
+var fieldSizes = map[string]int{
+	"int8": 1, "uint8": 1,
+	"int16": 2, "uint16": 2,
+	"int32": 4, "uint32": 4, "float32": 4,
+	"int64": 8, "uint64": 8, "float64": 8,
+}
+
+// subscription is a compiled subscription block, which maps messages to
+// samples and log entries.
+type subscription struct {
+	args      SubscriptionArguments
+	filter    string
+	byteOrder binary.ByteOrder
+	timestamp *jmespath.JMESPath
+	metrics   []metricMapping
+	log       *logMapping
+}
+
+type metricMapping struct {
+	name   string
+	value  *jmespath.JMESPath
+	labels map[string]*jmespath.JMESPath
+}
+
+type logMapping struct {
+	line   *jmespath.JMESPath // nil to use the raw payload
+	labels map[string]*jmespath.JMESPath
+}
+
+// sample is a sample extracted from a message.
+type sample struct {
+	Labels labels.Labels
+	Value  float64
+}
+
+func compileSubscription(args SubscriptionArguments) (*subscription, error) {
+	s := &subscription{
+		args:      args,
+		filter:    matchFilter(args.Topic),
+		byteOrder: binary.BigEndian,
+	}
+	if args.ByteOrder == "little" {
+		s.byteOrder = binary.LittleEndian
+	}
+
+	var err error
+	if args.Timestamp != "" {
+		if s.timestamp, err = jmespath.Compile(args.Timestamp); err != nil {
+			return nil, fmt.Errorf("invalid timestamp expression %q: %w", args.Timestamp, err)
+		}
+	}
+	for _, m := range args.Metrics {
+		mm := metricMapping{name: m.Name}
+		if mm.value, err = jmespath.Compile(m.Value); err != nil {
+			return nil, fmt.Errorf("invalid value expression %q for metric %q: %w", m.Value, m.Name, err)
+		}
+		if mm.labels, err = compileLabels(m.Labels); err != nil {
+			return nil, err
+		}
+		s.metrics = append(s.metrics, mm)
+	}
+	if args.Log != nil {
+		s.log = &logMapping{}
+		if args.Log.Line != "" {
+			if s.log.line, err = jmespath.Compile(args.Log.Line); err != nil {
+				return nil, fmt.Errorf("invalid line expression %q: %w", args.Log.Line, err)
+			}
+		}
+		if s.log.labels, err = compileLabels(args.Log.Labels); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func compileLabels(exprs map[string]string) (map[string]*jmespath.JMESPath, error) {
+	res := make(map[string]*jmespath.JMESPath, len(exprs))
+	for name, expr := range exprs {
+		compiled, err := jmespath.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q for label %q: %w", expr, name, err)
+		}
+		res[name] = compiled
+	}
+	return res, nil
+}
+
+// matchFilter returns the topic filter messages are matched against. Shared
+// subscriptions, such as $share/group/topic, receive messages of the topic
+// after the group name.
+func matchFilter(filter string) string {
+	if !strings.HasPrefix(filter, "$share/") {
+		return filter
+	}
+	parts := strings.SplitN(filter, "/", 3)
+	if len(parts) < 3 {
+		return filter
+	}
+	return parts[2]
+}
+
+// Matches returns true if topic matches the topic filter of s.
+func (s *subscription) Matches(topic string) bool {
+	return topicMatches(s.filter, topic)
+}
+
+// topicMatches returns true if topic matches an MQTT topic filter, which may
+// hold the + (single level) and # (multiple levels) wildcards.
+func topicMatches(filter, topic string) bool {
+	// Wildcards at the first level don't match topics starting with $, which
+	// are reserved by brokers.
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, f := range filterLevels {
+		if f == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if f != "+" && f != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// Decode decodes payload into the document expressions are evaluated
+// against.
+func (s *subscription) Decode(payload []byte) (interface{}, error) {
+	switch s.args.Format {
+	case FormatText:
+		return string(payload), nil
+	case FormatBinary:
+		return s.decodeBinary(payload)
+	default:
+		var doc interface{}
+		if err := json.Unmarshal(payload, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON payload: %w", err)
+		}
+		return doc, nil
+	}
+}
+
+func (s *subscription) decodeBinary(payload []byte) (interface{}, error) {
+	doc := make(map[string]interface{}, len(s.args.Fields))
+	for _, f := range s.args.Fields {
+		end := f.Offset + fieldSizes[f.Type]
+		if end > len(payload) {
+			return nil, fmt.Errorf("payload of %d bytes is too short for field %q", len(payload), f.Name)
+		}
+		b := payload[f.Offset:end]
+
+		var v float64
+		switch f.Type {
+		case "int8":
+			v = float64(int8(b[0]))
+		case "uint8":
+			v = float64(b[0])
+		case "int16":
+			v = float64(int16(s.byteOrder.Uint16(b)))
+		case "uint16":
+			v = float64(s.byteOrder.Uint16(b))
+		case "int32":
+			v = float64(int32(s.byteOrder.Uint32(b)))
+		case "uint32":
+			v = float64(s.byteOrder.Uint32(b))
+		case "float32":
+			v = float64(math.Float32frombits(s.byteOrder.Uint32(b)))
+		case "int64":
+			v = float64(int64(s.byteOrder.Uint64(b)))
+		case "uint64":
+			v = float64(s.byteOrder.Uint64(b))
+		case "float64":
+			v = math.Float64frombits(s.byteOrder.Uint64(b))
+		}
+		doc[f.Name] = v * f.Scale
+	}
+	return doc, nil
+}
+
+// Timestamp returns the timestamp of a message, which defaults to now.
+func (s *subscription) Timestamp(doc interface{}, now time.Time) (time.Time, error) {
+	if s.timestamp == nil {
+		return now, nil
+	}
+	v, err := s.timestamp.Search(doc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch s.args.TimestampFormat {
+	case TimestampRFC3339:
+		str, ok := v.(string)
+		if !ok {
+			return time.Time{}, fmt.Errorf("timestamp isn't a string: %v", v)
+		}
+		return time.Parse(time.RFC3339Nano, str)
+	default:
+		f, err := toFloat(v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp: %w", err)
+		}
+		if s.args.TimestampFormat == TimestampUnixMs {
+			return time.UnixMilli(int64(f)), nil
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	}
+}
+
+// Samples returns the samples of doc, a message received on topic. Metrics
+// whose value isn't found in doc are skipped.
+func (s *subscription) Samples(topic string, doc interface{}) ([]sample, error) {
+	var res []sample
+	for _, m := range s.metrics {
+		v, err := m.value.Search(doc)
+		if err != nil {
+			return nil, fmt.Errorf("evaluating the value of metric %q: %w", m.name, err)
+		}
+		if v == nil {
+			continue
+		}
+		value, err := toFloat(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for metric %q: %w", m.name, err)
+		}
+
+		b := labels.NewScratchBuilder(len(s.args.TopicLabels) + len(m.labels) + 1)
+		b.Add(model.MetricNameLabel, m.name)
+		if err := s.addLabels(&b, topic, m.labels, doc); err != nil {
+			return nil, err
+		}
+		b.Sort()
+		res = append(res, sample{Labels: b.Labels(), Value: value})
+	}
+	return res, nil
+}
+
+// Entry returns the line and labels of the log entry of doc, a message
+// received on topic. ok is false if s doesn't map messages to log entries.
+func (s *subscription) Entry(topic string, payload []byte, doc interface{}) (line string, lbls model.LabelSet, ok bool, err error) {
+	if s.log == nil {
+		return "", nil, false, nil
+	}
+
+	line = string(payload)
+	if s.log.line != nil {
+		v, err := s.log.line.Search(doc)
+		if err != nil {
+			return "", nil, false, fmt.Errorf("evaluating the log line: %w", err)
+		}
+		if line, err = toLine(v); err != nil {
+			return "", nil, false, err
+		}
+	} else if s.args.Format == FormatBinary {
+		// Raw binary payloads make for unreadable lines, so the decoded
+		// fields are used instead.
+		if line, err = toLine(doc); err != nil {
+			return "", nil, false, err
+		}
+	}
+
+	b := labels.NewScratchBuilder(len(s.args.TopicLabels) + len(s.log.labels))
+	if err := s.addLabels(&b, topic, s.log.labels, doc); err != nil {
+		return "", nil, false, err
+	}
+	lbls = make(model.LabelSet, len(s.args.TopicLabels)+len(s.log.labels))
+	b.Labels().Range(func(l labels.Label) {
+		lbls[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	})
+	return line, lbls, true, nil
+}
+
+// addLabels adds the topic labels and the labels of exprs to b. Labels whose
+// value isn't found are skipped.
+func (s *subscription) addLabels(b *labels.ScratchBuilder, topic string, exprs map[string]*jmespath.JMESPath, doc interface{}) error {
+	if len(s.args.TopicLabels) > 0 {
+		levels := strings.Split(topic, "/")
+		for name, level := range s.args.TopicLabels {
+			if level < len(levels) && levels[level] != "" {
+				b.Add(name, levels[level])
+			}
+		}
+	}
+	for name, expr := range exprs {
+		v, err := expr.Search(doc)
+		if err != nil {
+			return fmt.Errorf("evaluating label %q: %w", name, err)
+		}
+		if v == nil {
+			continue
+		}
+		b.Add(name, toLabelValue(v))
+	}
+	return nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	default:
+		return 0, fmt.Errorf("%v isn't a number", v)
+	}
+}
+
+func toLabelValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toLine(v interface{}) (string, error) {
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encoding the log line: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,218 @@
+package source
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/river"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tt := []struct {
+		filter, topic string
+		expect        bool
+	}{
+		{"sensors/temp", "sensors/temp", true},
+		{"sensors/temp", "sensors/humidity", false},
+		{"sensors/+/temp", "sensors/a/temp", true},
+		{"sensors/+/temp", "sensors/a/b/temp", false},
+		{"sensors/#", "sensors", true},
+		{"sensors/#", "sensors/a/b", true},
+		{"sensors/+", "sensors", false},
+		{"#", "sensors/a", true},
+		{"#", "$SYS/uptime", false},
+		{"+/uptime", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+	}
+	for _, tc := range tt {
+		require.Equal(t, tc.expect, topicMatches(tc.filter, tc.topic), "filter %q, topic %q", tc.filter, tc.topic)
+	}
+
+	require.Equal(t, "sensors/+", matchFilter("$share/agents/sensors/+"))
+	require.Equal(t, "sensors/+", matchFilter("sensors/+"))
+}
+
+func compile(t *testing.T, config string) *subscription {
+	t.Helper()
+
+	var args SubscriptionArguments
+	require.NoError(t, river.Unmarshal([]byte(config), &args))
+	s, err := compileSubscription(args)
+	require.NoError(t, err)
+	return s
+}
+
+func TestJSON(t *testing.T) {
+	s := compile(t, `
+		topic        = "factory/+/telemetry"
+		topic_labels = { line = 1 }
+		timestamp    = "ts"
+
+		metric {
+			name   = "temperature_celsius"
+			value  = "temp"
+			labels = { sensor = "sensor.id" }
+		}
+		metric {
+			name  = "running"
+			value = "running"
+		}
+		metric {
+			name  = "missing"
+			value = "missing"
+		}
+		log {
+			line   = "message"
+			labels = { level = "level" }
+		}
+	`)
+
+	payload := []byte(`{"ts": 1700000000.5, "temp": 21.5, "running": true, "sensor": {"id": 7}, "message": "ok", "level": "info"}`)
+	doc, err := s.Decode(payload)
+	require.NoError(t, err)
+
+	ts, err := s.Timestamp(doc, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1700000000, 5e8), ts)
+
+	samples, err := s.Samples("factory/line-1/telemetry", doc)
+	require.NoError(t, err)
+	require.Equal(t, []sample{
+		{Labels: labels.FromStrings("__name__", "temperature_celsius", "line", "line-1", "sensor", "7"), Value: 21.5},
+		{Labels: labels.FromStrings("__name__", "running", "line", "line-1"), Value: 1},
+	}, samples)
+
+	line, lbls, ok, err := s.Entry("factory/line-1/telemetry", payload, doc)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "ok", line)
+	require.Equal(t, model.LabelSet{"line": "line-1", "level": "info"}, lbls)
+
+	_, err = s.Decode([]byte(`{`))
+	require.Error(t, err)
+
+	doc, err = s.Decode([]byte(`{"temp": "hot"}`))
+	require.NoError(t, err)
+	_, err = s.Samples("factory/line-1/telemetry", doc)
+	require.EqualError(t, err, `invalid value for metric "temperature_celsius": strconv.ParseFloat: parsing "hot": invalid syntax`)
+}
+
+func TestText(t *testing.T) {
+	s := compile(t, `
+		topic  = "home/+/temperature"
+		format = "text"
+		topic_labels = { room = 1 }
+
+		metric {
+			name = "temperature_celsius"
+		}
+		log { }
+	`)
+
+	payload := []byte(" 19.25\n")
+	doc, err := s.Decode(payload)
+	require.NoError(t, err)
+
+	samples, err := s.Samples("home/kitchen/temperature", doc)
+	require.NoError(t, err)
+	require.Equal(t, []sample{
+		{Labels: labels.FromStrings("__name__", "temperature_celsius", "room", "kitchen"), Value: 19.25},
+	}, samples)
+
+	line, lbls, ok, err := s.Entry("home/kitchen/temperature", payload, doc)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, " 19.25\n", line)
+	require.Equal(t, model.LabelSet{"room": "kitchen"}, lbls)
+}
+
+func TestBinary(t *testing.T) {
+	s := compile(t, `
+		topic      = "plc/status"
+		format     = "binary"
+		byte_order = "little"
+
+		field {
+			name   = "pressure"
+			offset = 0
+			type   = "uint16"
+			scale  = 0.1
+		}
+		field {
+			name   = "flow"
+			offset = 2
+			type   = "float32"
+		}
+		field {
+			name   = "state"
+			offset = 6
+			type   = "int8"
+		}
+
+		metric {
+			name  = "pressure_bar"
+			value = "pressure"
+		}
+		metric {
+			name   = "flow_rate"
+			value  = "flow"
+			labels = { state = "state" }
+		}
+		log { }
+	`)
+
+	payload := make([]byte, 7)
+	binary.LittleEndian.PutUint16(payload[0:], 1013)
+	binary.LittleEndian.PutUint32(payload[2:], math.Float32bits(2.5))
+	payload[6] = 0xff // -1
+
+	doc, err := s.Decode(payload)
+	require.NoError(t, err)
+
+	samples, err := s.Samples("plc/status", doc)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	require.InDelta(t, 101.3, samples[0].Value, 1e-9)
+	require.Equal(t, labels.FromStrings("__name__", "flow_rate", "state", "-1"), samples[1].Labels)
+	require.Equal(t, 2.5, samples[1].Value)
+
+	// Binary payloads are logged as their decoded fields.
+	line, _, ok, err := s.Entry("plc/status", payload, doc)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.JSONEq(t, `{"pressure": 101.30000000000001, "flow": 2.5, "state": -1}`, line)
+
+	_, err = s.Decode(payload[:5])
+	require.EqualError(t, err, `payload of 5 bytes is too short for field "flow"`)
+}
+
+func TestTimestampFormats(t *testing.T) {
+	s := compile(t, `
+		topic            = "a"
+		timestamp        = "ts"
+		timestamp_format = "unix_ms"
+		log { }
+	`)
+	doc, err := s.Decode([]byte(`{"ts": 1700000000123}`))
+	require.NoError(t, err)
+	ts, err := s.Timestamp(doc, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, time.UnixMilli(1700000000123), ts)
+
+	s = compile(t, `
+		topic            = "a"
+		timestamp        = "ts"
+		timestamp_format = "rfc3339"
+		log { }
+	`)
+	doc, err = s.Decode([]byte(`{"ts": "2024-01-02T15:04:05Z"}`))
+	require.NoError(t, err)
+	ts, err = s.Timestamp(doc, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), ts.UTC())
+}
@@ -0,0 +1,390 @@
+// Package source implements the mqtt.source component, which subscribes to
+// topics of an MQTT broker and maps their messages to metrics and log
+// entries.
+package source
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/agentseed"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/drops"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/loki/pkg/logproto"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	promconfig "github.com/prometheus/common/config"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "mqtt.source",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// sendTimeout is how long log entries wait to be accepted by a receiver
+// before being dropped.
+const sendTimeout = 2 * time.Second
+
+// Component implements the mqtt.source component.
+type Component struct {
+	log  log.Logger
+	opts component.Options
+
+	fanout *prometheus.Fanout
+
+	mut           sync.RWMutex
+	args          Arguments
+	clientCfg     clientConfig
+	subscriptions []*subscription
+	logs          []loki.LogsReceiver
+	health        component.Health
+
+	// restartCh is notified when the client must be restarted for new
+	// arguments.
+	restartCh chan struct{}
+
+	messagesReceived  prometheus_client.Counter
+	messagesUnmatched prometheus_client.Counter
+	decodeErrors      *prometheus_client.CounterVec
+	samplesEmitted    prometheus_client.Counter
+	entriesEmitted    prometheus_client.Counter
+	entriesDropped    prometheus_client.Counter
+	connected         prometheus_client.Gauge
+	dropped           *drops.Recorder
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new mqtt.source component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	service, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := service.(labelstore.LabelStore)
+
+	c := &Component{
+		log:       o.Logger,
+		opts:      o,
+		fanout:    prometheus.NewFanout(nil, o.ID, o.Registerer, ls),
+		restartCh: make(chan struct{}, 1),
+
+		messagesReceived: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_mqtt_source_messages_received_total",
+			Help: "Total number of messages received from the broker.",
+		}),
+		messagesUnmatched: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_mqtt_source_messages_unmatched_total",
+			Help: "Total number of messages received on a topic which doesn't match any subscription.",
+		}),
+		decodeErrors: prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+			Name: "agent_mqtt_source_decode_errors_total",
+			Help: "Total number of messages which couldn't be mapped to metrics or log entries, by subscription.",
+		}, []string{"subscription"}),
+		samplesEmitted: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_mqtt_source_samples_total",
+			Help: "Total number of samples sent to the metrics receivers.",
+		}),
+		entriesEmitted: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_mqtt_source_entries_total",
+			Help: "Total number of log entries sent to the logs receivers.",
+		}),
+		entriesDropped: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_mqtt_source_entries_dropped_total",
+			Help: "Total number of log entries dropped because a logs receiver didn't accept them in time.",
+		}),
+		connected: prometheus_client.NewGauge(prometheus_client.GaugeOpts{
+			Name: "agent_mqtt_source_connected",
+			Help: "Whether the component is connected to the broker and subscribed to its topics.",
+		}),
+		dropped: drops.NewRecorder(o, drops.SignalLogs),
+	}
+	for _, m := range []prometheus_client.Collector{
+		c.messagesReceived, c.messagesUnmatched, c.decodeErrors,
+		c.samplesEmitted, c.entriesEmitted, c.entriesDropped, c.connected,
+	} {
+		if err := o.Registerer.Register(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		c.mut.RLock()
+		cli := newClient(c.args.ProtocolVersion, c.clientCfg)
+		c.mut.RUnlock()
+
+		clientCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			cli.Run(clientCtx)
+		}()
+
+		select {
+		case <-ctx.Done():
+		case <-c.restartCh:
+			level.Info(c.log).Log("msg", "reconnecting to the broker with new arguments")
+		}
+		cancel()
+		<-done
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	subscriptions := make([]*subscription, 0, len(newArgs.Subscriptions))
+	for _, s := range newArgs.Subscriptions {
+		compiled, err := compileSubscription(s)
+		if err != nil {
+			return fmt.Errorf("subscription %q: %w", s.Topic, err)
+		}
+		subscriptions = append(subscriptions, compiled)
+	}
+
+	clientCfg, err := c.newClientConfig(newArgs)
+	if err != nil {
+		return err
+	}
+
+	c.fanout.UpdateChildren(newArgs.Output.Metrics)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	restart := c.args.BrokerURL != "" && !sameConnection(c.args, newArgs)
+	c.args = newArgs
+	c.clientCfg = clientCfg
+	c.subscriptions = subscriptions
+	c.logs = newArgs.Output.Logs
+
+	if restart {
+		select {
+		case c.restartCh <- struct{}{}:
+		default:
+			// A restart is already pending.
+		}
+	}
+	return nil
+}
+
+func (c *Component) newClientConfig(args Arguments) (clientConfig, error) {
+	u, err := url.Parse(args.BrokerURL)
+	if err != nil {
+		return clientConfig{}, err
+	}
+	tlsConfig, err := promconfig.NewTLSConfig(args.TLSConfig.Convert())
+	if err != nil {
+		return clientConfig{}, fmt.Errorf("invalid tls_config: %w", err)
+	}
+
+	clientID := args.ClientID
+	if clientID == "" {
+		clientID = defaultClientID(c.opts.ID)
+	}
+
+	subscriptions := make(map[string]byte, len(args.Subscriptions))
+	for _, s := range args.Subscriptions {
+		subscriptions[s.Topic] = max(subscriptions[s.Topic], byte(s.QoS))
+	}
+
+	return clientConfig{
+		BrokerURL:      u,
+		ClientID:       clientID,
+		Username:       args.Username,
+		Password:       string(args.Password),
+		KeepAlive:      args.KeepAlive,
+		ConnectTimeout: args.ConnectTimeout,
+		CleanSession:   args.CleanSession,
+		TLS:            tlsConfig,
+		Subscriptions:  subscriptions,
+
+		OnMessage:          c.handleMessage,
+		OnConnectionChange: c.setConnectionState,
+		Logger:             c.log,
+	}, nil
+}
+
+// defaultClientID returns a client ID which is unique to the component and
+// the agent, and short enough to be accepted by every broker.
+func defaultClientID(componentID string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(agentseed.Get().UID))
+	_, _ = h.Write([]byte(componentID))
+	return fmt.Sprintf("grafana-agent-%08x", h.Sum32())
+}
+
+// sameConnection returns true if a and b connect to the broker and subscribe
+// to topics the same way. Changes to how messages are mapped and to the
+// receivers don't require reconnecting.
+func sameConnection(a, b Arguments) bool {
+	topics := func(args Arguments) map[string]int {
+		res := make(map[string]int, len(args.Subscriptions))
+		for _, s := range args.Subscriptions {
+			res[s.Topic] = max(res[s.Topic], s.QoS)
+		}
+		return res
+	}
+	if !reflect.DeepEqual(topics(a), topics(b)) {
+		return false
+	}
+
+	a.Subscriptions, a.Output = nil, OutputArguments{}
+	b.Subscriptions, b.Output = nil, OutputArguments{}
+	return reflect.DeepEqual(a, b)
+}
+
+// setConnectionState updates the health of the component when the client
+// connects or loses its connection.
+func (c *Component) setConnectionState(err error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if err != nil {
+		c.connected.Set(0)
+		c.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    err.Error(),
+			UpdateTime: time.Now(),
+		}
+		return
+	}
+	c.connected.Set(1)
+	c.health = component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    "connected to the broker",
+		UpdateTime: time.Now(),
+	}
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.health
+}
+
+// handleMessage maps a message to samples and log entries with every
+// subscription whose topic filter matches the topic of the message.
+func (c *Component) handleMessage(topic string, payload []byte) {
+	c.messagesReceived.Inc()
+
+	c.mut.RLock()
+	var (
+		subscriptions = c.subscriptions
+		logs          = c.logs
+	)
+	c.mut.RUnlock()
+
+	now := time.Now()
+	matched := false
+	for _, s := range subscriptions {
+		if !s.Matches(topic) {
+			continue
+		}
+		matched = true
+		if err := c.handleSubscription(s, topic, payload, logs, now); err != nil {
+			level.Debug(c.log).Log("msg", "failed to decode message", "topic", topic, "err", err)
+			c.decodeErrors.WithLabelValues(s.args.Topic).Inc()
+		}
+	}
+	if !matched {
+		c.messagesUnmatched.Inc()
+	}
+}
+
+func (c *Component) handleSubscription(s *subscription, topic string, payload []byte, logs []loki.LogsReceiver, now time.Time) error {
+	doc, err := s.Decode(payload)
+	if err != nil {
+		return err
+	}
+	ts, err := s.Timestamp(doc, now)
+	if err != nil {
+		return err
+	}
+
+	samples, err := s.Samples(topic, doc)
+	if err != nil {
+		return err
+	}
+	line, lbls, hasEntry, err := s.Entry(topic, payload, doc)
+	if err != nil {
+		return err
+	}
+
+	if len(samples) > 0 {
+		if err := c.appendSamples(samples, ts); err != nil {
+			level.Warn(c.log).Log("msg", "failed to send samples", "topic", topic, "err", err)
+		}
+	}
+	if hasEntry {
+		c.sendEntry(logs, loki.Entry{
+			Labels: lbls,
+			Entry:  logproto.Entry{Timestamp: ts, Line: line},
+		})
+	}
+	return nil
+}
+
+func (c *Component) appendSamples(samples []sample, ts time.Time) error {
+	app := c.fanout.Appender(context.Background())
+	for _, s := range samples {
+		if _, err := app.Append(0, s.Labels, ts.UnixMilli(), s.Value); err != nil {
+			_ = app.Rollback()
+			return err
+		}
+	}
+	if err := app.Commit(); err != nil {
+		return err
+	}
+	c.samplesEmitted.Add(float64(len(samples)))
+	return nil
+}
+
+func (c *Component) sendEntry(logs []loki.LogsReceiver, entry loki.Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	for _, receiver := range logs {
+		select {
+		case <-ctx.Done():
+			c.entriesDropped.Inc()
+			c.dropped.Add(drops.ReasonSendFailed, 1)
+		case receiver.Chan() <- entry:
+			c.entriesEmitted.Inc()
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "valid",
+			config: `
+				broker_url       = "tcp://localhost:1883"
+				protocol_version = "5"
+				subscription {
+					topic = "sensors/#"
+					qos   = 1
+					metric { name = "reading" }
+				}
+				output { }
+			`,
+		},
+		{
+			name: "bad scheme",
+			config: `
+				broker_url = "http://localhost:1883"
+				subscription {
+					topic = "sensors/#"
+					log { }
+				}
+				output { }
+			`,
+			err: `unsupported broker_url scheme "http", must be one of tcp, mqtt, ssl, tls, mqtts, ws or wss`,
+		},
+		{
+			name: "bad protocol version",
+			config: `
+				broker_url       = "tcp://localhost:1883"
+				protocol_version = "4"
+				subscription {
+					topic = "sensors/#"
+					log { }
+				}
+				output { }
+			`,
+			err: `protocol_version must be "3.1.1" or "5", got "4"`,
+		},
+		{
+			name: "no mapping",
+			config: `
+				broker_url = "tcp://localhost:1883"
+				subscription {
+					topic = "sensors/#"
+				}
+				output { }
+			`,
+			err: `at least one metric or log block must be set for topic "sensors/#"`,
+		},
+		{
+			name: "binary without fields",
+			config: `
+				broker_url = "tcp://localhost:1883"
+				subscription {
+					topic  = "sensors/#"
+					format = "binary"
+					log { }
+				}
+				output { }
+			`,
+			err: `at least one field block must be set for the binary format`,
+		},
+		{
+			name: "conflicting labels",
+			config: `
+				broker_url = "tcp://localhost:1883"
+				subscription {
+					topic        = "sensors/+"
+					topic_labels = { sensor = 1 }
+					metric {
+						name   = "reading"
+						labels = { sensor = "id" }
+					}
+				}
+				output { }
+			`,
+			err: `label "sensor" is set both by topic_labels and by a mapping`,
+		},
+		{
+			name: "bad metric name",
+			config: `
+				broker_url = "tcp://localhost:1883"
+				subscription {
+					topic = "sensors/#"
+					metric { name = "1reading" }
+				}
+				output { }
+			`,
+			err: `invalid metric name "1reading"`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.config), &args)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestHandleMessage(t *testing.T) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+
+	var samples []labels.Labels
+	metrics := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		samples = append(samples, l)
+		return ref, nil
+	}))
+	logs := loki.NewLogsReceiverWithChannel(make(chan loki.Entry, 10))
+
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		broker_url = "tcp://localhost:1883"
+		subscription {
+			topic        = "sensors/+"
+			topic_labels = { sensor = 1 }
+			metric {
+				name  = "temperature_celsius"
+				value = "temp"
+			}
+		}
+		subscription {
+			topic = "sensors/#"
+			log { }
+		}
+		output { }
+	`), &args))
+	args.Output = OutputArguments{
+		Metrics: []storage.Appendable{metrics},
+		Logs:    []loki.LogsReceiver{logs},
+	}
+
+	c, err := New(component.Options{
+		ID:            "mqtt.source.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	// The message is mapped by both subscriptions.
+	c.handleMessage("sensors/a", []byte(`{"temp": 20}`))
+	require.Equal(t, []labels.Labels{labels.FromStrings("__name__", "temperature_celsius", "sensor", "a")}, samples)
+	select {
+	case entry := <-logs.Chan():
+		require.Equal(t, `{"temp": 20}`, entry.Line)
+		require.Equal(t, model.LabelSet{}, entry.Labels)
+	case <-time.After(time.Second):
+		t.Fatal("no log entry received")
+	}
+
+	// Invalid messages are counted for every subscription which matched.
+	c.handleMessage("sensors/b", []byte(`not json`))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.decodeErrors.WithLabelValues("sensors/+")))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.decodeErrors.WithLabelValues("sensors/#")))
+
+	c.handleMessage("other", []byte(`{}`))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.messagesUnmatched))
+	require.Equal(t, 3.0, testutil.ToFloat64(c.messagesReceived))
+}
+
+func TestSameConnection(t *testing.T) {
+	var a Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		broker_url = "tcp://localhost:1883"
+		subscription {
+			topic = "sensors/#"
+			metric { name = "reading" }
+		}
+		output { }
+	`), &a))
+
+	b := a
+	b.Subscriptions = []SubscriptionArguments{a.Subscriptions[0]}
+	b.Subscriptions[0].Metrics = []MetricArguments{{Name: "other", Value: "@"}}
+	require.True(t, sameConnection(a, b), "changing a mapping doesn't require reconnecting")
+
+	b.Subscriptions[0].QoS = 1
+	require.False(t, sameConnection(a, b), "changing the QoS of a topic requires reconnecting")
+
+	b = a
+	b.Username = "agent"
+	require.False(t, sameConnection(a, b), "changing the credentials requires reconnecting")
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/alecthomas/units"
+	"github.com/grafana/agent/internal/component/common/config"
 	"github.com/grafana/agent/internal/component/otelcol/auth"
 	otelcomponent "go.opentelemetry.io/collector/component"
 	otelconfigauth "go.opentelemetry.io/collector/config/configauth"
@@ -148,11 +149,20 @@ type GRPCClientArguments struct {
 	BalancerName    string            `river:"balancer_name,attr,optional"`
 	Authority       string            `river:"authority,attr,optional"`
 
+	// Transport overrides the keepalive settings above when set.
+	Transport *config.TransportArguments `river:"transport,block,optional"`
+
 	// Auth is a binding to an otelcol.auth.* component extension which handles
 	// authentication.
 	Auth *auth.Handler `river:"auth,attr,optional"`
 }
 
+// Validate returns an error if args sets transport settings which gRPC
+// clients don't support.
+func (args *GRPCClientArguments) Validate() error {
+	return args.Transport.CheckSupported("otelcol gRPC clients", config.TransportHTTP2Ping)
+}
+
 // Convert converts args into the upstream type.
 func (args *GRPCClientArguments) Convert() *otelconfiggrpc.GRPCClientSettings {
 	if args == nil {
@@ -176,13 +186,24 @@ func (args *GRPCClientArguments) Convert() *otelconfiggrpc.GRPCClientSettings {
 		balancerName = DefaultBalancerName
 	}
 
+	keepalive := args.Keepalive.Convert()
+	if t := args.Transport; t != nil && t.HTTP2PingInterval > 0 {
+		keepalive = &otelconfiggrpc.KeepaliveClientConfig{
+			Time:    t.HTTP2PingInterval,
+			Timeout: t.HTTP2PingTimeout,
+		}
+		if args.Keepalive != nil {
+			keepalive.PermitWithoutStream = args.Keepalive.PingWithoutStream
+		}
+	}
+
 	return &otelconfiggrpc.GRPCClientSettings{
 		Endpoint: args.Endpoint,
 
 		Compression: args.Compression.Convert(),
 
 		TLSSetting: *args.TLS.Convert(),
-		Keepalive:  args.Keepalive.Convert(),
+		Keepalive:  keepalive,
 
 		ReadBufferSize:  int(args.ReadBufferSize),
 		WriteBufferSize: int(args.WriteBufferSize),
@@ -0,0 +1,32 @@
+package otelcol_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/component/otelcol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCClientArguments_Transport(t *testing.T) {
+	args := otelcol.GRPCClientArguments{
+		Keepalive: &otelcol.KeepaliveClientArguments{
+			PingWait:          time.Hour,
+			PingWithoutStream: true,
+		},
+		Transport: &config.TransportArguments{
+			HTTP2PingInterval: time.Minute,
+			HTTP2PingTimeout:  10 * time.Second,
+		},
+	}
+	require.NoError(t, args.Validate())
+
+	keepalive := args.Convert().Keepalive
+	require.Equal(t, time.Minute, keepalive.Time)
+	require.Equal(t, 10*time.Second, keepalive.Timeout)
+	require.True(t, keepalive.PermitWithoutStream)
+
+	args.Transport.MaxIdleConns = 10
+	require.EqualError(t, args.Validate(), "transport: max_idle_conns is not supported by otelcol gRPC clients")
+}
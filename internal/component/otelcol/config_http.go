@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/alecthomas/units"
+	"github.com/grafana/agent/internal/component/common/config"
 	"github.com/grafana/agent/internal/component/otelcol/auth"
 	otelcomponent "go.opentelemetry.io/collector/component"
 	otelconfigauth "go.opentelemetry.io/collector/config/configauth"
@@ -91,11 +92,25 @@ type HTTPClientArguments struct {
 	IdleConnTimeout     *time.Duration `river:"idle_conn_timeout,attr,optional"`
 	DisableKeepAlives   bool           `river:"disable_keep_alives,attr,optional"`
 
+	// Transport overrides the connection pool settings above when set.
+	Transport *config.TransportArguments `river:"transport,block,optional"`
+
 	// Auth is a binding to an otelcol.auth.* component extension which handles
 	// authentication.
 	Auth *auth.Handler `river:"auth,attr,optional"`
 }
 
+// Validate returns an error if args sets transport settings which HTTP
+// clients don't support.
+func (args *HTTPClientArguments) Validate() error {
+	return args.Transport.CheckSupported(
+		"otelcol HTTP clients",
+		config.TransportMaxIdleConns,
+		config.TransportMaxIdleConnsPerHost,
+		config.TransportIdleConnTimeout,
+	)
+}
+
 // Convert converts args into the upstream type.
 func (args *HTTPClientArguments) Convert() *otelconfighttp.HTTPClientSettings {
 	if args == nil {
@@ -113,6 +128,23 @@ func (args *HTTPClientArguments) Convert() *otelconfighttp.HTTPClientSettings {
 		opaqueHeaders[headerName] = configopaque.String(headerVal)
 	}
 
+	var (
+		maxIdleConns        = args.MaxIdleConns
+		maxIdleConnsPerHost = args.MaxIdleConnsPerHost
+		idleConnTimeout     = args.IdleConnTimeout
+	)
+	if t := args.Transport; t != nil {
+		if t.MaxIdleConns > 0 {
+			maxIdleConns = &t.MaxIdleConns
+		}
+		if t.MaxIdleConnsPerHost > 0 {
+			maxIdleConnsPerHost = &t.MaxIdleConnsPerHost
+		}
+		if t.IdleConnTimeout > 0 {
+			idleConnTimeout = &t.IdleConnTimeout
+		}
+	}
+
 	return &otelconfighttp.HTTPClientSettings{
 		Endpoint: args.Endpoint,
 
@@ -125,10 +157,10 @@ func (args *HTTPClientArguments) Convert() *otelconfighttp.HTTPClientSettings {
 		Timeout:         args.Timeout,
 		Headers:         opaqueHeaders,
 		// CustomRoundTripper: func(http.RoundTripper) (http.RoundTripper, error) { panic("not implemented") }, TODO (@tpaschalis)
-		MaxIdleConns:        args.MaxIdleConns,
-		MaxIdleConnsPerHost: args.MaxIdleConnsPerHost,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
 		MaxConnsPerHost:     args.MaxConnsPerHost,
-		IdleConnTimeout:     args.IdleConnTimeout,
+		IdleConnTimeout:     idleConnTimeout,
 
 		Auth: auth,
 	}
@@ -0,0 +1,30 @@
+package otelcol_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/component/otelcol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientArguments_Transport(t *testing.T) {
+	maxIdleConns := 100
+	args := otelcol.HTTPClientArguments{
+		MaxIdleConns: &maxIdleConns,
+		Transport: &config.TransportArguments{
+			MaxIdleConnsPerHost: 5,
+			IdleConnTimeout:     time.Minute,
+		},
+	}
+	require.NoError(t, args.Validate())
+
+	settings := args.Convert()
+	require.Equal(t, 100, *settings.MaxIdleConns)
+	require.Equal(t, 5, *settings.MaxIdleConnsPerHost)
+	require.Equal(t, time.Minute, *settings.IdleConnTimeout)
+
+	args.Transport.TCPKeepAlive = time.Minute
+	require.EqualError(t, args.Validate(), "transport: tcp_keepalive is not supported by otelcol HTTP clients")
+}
@@ -3,7 +3,10 @@ package otelcol
 import (
 	"fmt"
 
+	"github.com/grafana/agent/internal/component/otelcol/storage"
+	otelcomponent "go.opentelemetry.io/collector/component"
 	otelexporterhelper "go.opentelemetry.io/collector/exporter/exporterhelper"
+	otelextension "go.opentelemetry.io/collector/extension"
 )
 
 // QueueArguments holds shared settings for components which can queue
@@ -13,7 +16,13 @@ type QueueArguments struct {
 	NumConsumers int  `river:"num_consumers,attr,optional"`
 	QueueSize    int  `river:"queue_size,attr,optional"`
 
-	// TODO(rfratto): queues can send to persistent storage through an extension.
+	// Storage is a binding to an otelcol.storage.* component extension which
+	// persists queued requests so they survive restarts and outages of the
+	// destination.
+	//
+	// Both in memory and persisted, the upstream queue drops new requests once
+	// it's full. Dropping old requests or blocking isn't supported upstream.
+	Storage *storage.Handler `river:"storage,attr,optional"`
 }
 
 // SetToDefault implements river.Defaulter.
@@ -37,11 +46,27 @@ func (args *QueueArguments) Convert() *otelexporterhelper.QueueSettings {
 		return nil
 	}
 
+	// Configure the persistent queue if args.Storage is set.
+	var storageID *otelcomponent.ID
+	if args.Storage != nil {
+		storageID = &args.Storage.ID
+	}
+
 	return &otelexporterhelper.QueueSettings{
 		Enabled:      args.Enabled,
 		NumConsumers: args.NumConsumers,
 		QueueSize:    args.QueueSize,
+		StorageID:    storageID,
+	}
+}
+
+// Extensions exposes extensions used by args.
+func (args *QueueArguments) Extensions() map[otelcomponent.ID]otelextension.Extension {
+	m := make(map[otelcomponent.ID]otelextension.Extension)
+	if args != nil && args.Storage != nil {
+		m[args.Storage.ID] = args.Storage.Extension
 	}
+	return m
 }
 
 // Validate returns an error if args is invalid.
@@ -1,9 +1,11 @@
 package otelcol
 
 import (
+	"crypto/tls"
 	"fmt"
 	"time"
 
+	"github.com/grafana/agent/internal/boringcrypto"
 	"github.com/grafana/river/rivertypes"
 	"go.opentelemetry.io/collector/config/configopaque"
 	otelconfigtls "go.opentelemetry.io/collector/config/configtls"
@@ -106,5 +108,18 @@ func (t *TLSSetting) Validate() error {
 		return fmt.Errorf("exactly one of cert_pem or cert_file must be configured when a client key is configured")
 	}
 
-	return nil
+	if err := boringcrypto.CheckTLSVersion("min_version", tlsVersions[t.MinVersion]); err != nil {
+		return err
+	}
+	return boringcrypto.CheckTLSVersion("max_version", tlsVersions[t.MaxVersion])
+}
+
+// tlsVersions maps the TLS versions supported by the collector to their
+// crypto/tls values. Unsupported versions are rejected when the settings are
+// converted.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
 }
@@ -15,9 +15,12 @@ import (
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/internal/component/common/loki"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/loki/pkg/push"
 	loki_translator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/loki"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 )
 
@@ -29,18 +32,45 @@ type Converter struct {
 
 	mut  sync.RWMutex
 	next []loki.LogsReceiver // Location to write converted logs.
+
+	optsMut sync.RWMutex
+	opts    Options
+	guard   *labelGuard // Distinct values of the labels of opts.
 }
 
 var _ consumer.Logs = (*Converter)(nil)
 
 // New returns a new Converter. Converted logs are passed to the provided list
 // of LogsReceivers.
-func New(l log.Logger, r prometheus.Registerer, next []loki.LogsReceiver) *Converter {
+func New(l log.Logger, r prometheus.Registerer, next []loki.LogsReceiver, opts Options) *Converter {
 	if l == nil {
 		l = log.NewNopLogger()
 	}
 	m := newMetrics(r)
-	return &Converter{log: l, metrics: m, next: next}
+	return &Converter{
+		log:     l,
+		metrics: m,
+		next:    next,
+		opts:    opts,
+		guard:   newLabelGuard(opts.MaxLabelValues),
+	}
+}
+
+// UpdateOptions updates how the Converter maps attributes. The distinct values
+// counted towards MaxLabelValues are reset.
+func (conv *Converter) UpdateOptions(opts Options) {
+	conv.optsMut.Lock()
+	defer conv.optsMut.Unlock()
+	conv.opts = opts
+	conv.guard = newLabelGuard(opts.MaxLabelValues)
+}
+
+// getOpts gets a copy of the current options and label guard of the
+// Converter.
+func (conv *Converter) getOpts() (Options, *labelGuard) {
+	conv.optsMut.RLock()
+	defer conv.optsMut.RUnlock()
+	return conv.opts, conv.guard
 }
 
 // Capabilities implements consumer.Logs.
@@ -57,6 +87,8 @@ func (conv *Converter) Capabilities() consumer.Capabilities {
 // distribution and its LogsToLokiRequests function.
 func (conv *Converter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
 	var entries []loki.Entry
+	opts, guard := conv.getOpts()
+	hasMappings := len(opts.Labels) > 0 || len(opts.StructuredMetadata) > 0
 
 	rls := ld.ResourceLogs()
 	for i := 0; i < rls.Len(); i++ {
@@ -67,10 +99,24 @@ func (conv *Converter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
 			for k := 0; k < logs.Len(); k++ {
 				conv.metrics.entriesTotal.Inc()
 
+				record, resource := logs.At(k), rls.At(i).Resource()
+				var (
+					labels   model.LabelSet
+					metadata []push.LabelAdapter
+				)
+				if hasMappings {
+					// Mapped attributes are removed from the log line, so the
+					// mappings are applied to copies.
+					record, resource = plog.NewLogRecord(), pcommon.NewResource()
+					logs.At(k).CopyTo(record)
+					rls.At(i).Resource().CopyTo(resource)
+					labels, metadata = conv.applyMappings(opts, guard, resource.Attributes(), record.Attributes())
+				}
+
 				// TODO: loki added a parameter `defaultLabelsEnabled` to this function to add the possibility to disable default labels (exporter, job, instance, level)
 				// Is this interesting for us in any ways? (@wildum)
 				// https://github.com/open-telemetry/opentelemetry-collector-contrib/pull/23863/files#diff-ef7831fcba373f6e8aa7f799b5b89f4e113b2064cd7ef1688286ce193d2256a8
-				entry, err := loki_translator.LogToLokiEntry(logs.At(k), rls.At(i).Resource(), scope, nil)
+				entry, err := loki_translator.LogToLokiEntry(record, resource, scope, nil)
 				if err != nil {
 					level.Error(conv.log).Log("msg", "failed to convert log to loki entry", "err", err)
 					conv.metrics.entriesFailed.Inc()
@@ -78,6 +124,12 @@ func (conv *Converter) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
 				}
 
 				conv.metrics.entriesProcessed.Inc()
+				if len(labels) > 0 {
+					entry.Labels = entry.Labels.Merge(labels)
+				}
+				if len(metadata) > 0 {
+					entry.Entry.StructuredMetadata = append(entry.Entry.StructuredMetadata, metadata...)
+				}
 				entries = append(entries, loki.Entry{
 					Labels: entry.Labels,
 					Entry:  *entry.Entry,
@@ -316,7 +316,7 @@ func TestConsumeLogs(t *testing.T) {
 			promReg := prometheus.NewRegistry()
 			receiver := loki.NewLogsReceiverWithChannel(make(chan loki.Entry, maxTestedLogEntries))
 
-			converter := convert.New(logger, promReg, []loki.LogsReceiver{receiver})
+			converter := convert.New(logger, promReg, []loki.LogsReceiver{receiver}, convert.Options{})
 
 			ctx := context.Background()
 
@@ -361,3 +361,98 @@ func entryToStream(entry *loki.Entry) push.Stream {
 		},
 	}
 }
+
+func TestConsumeLogs_Mappings(t *testing.T) {
+	inputLogJson := `{
+		"resourceLogs": [{
+			"resource": {
+				"attributes": [{
+					"key": "service.name",
+					"value": { "stringValue": "auth" }
+				}]
+			},
+			"scopeLogs": [{
+				"log_records": [{
+					"timeUnixNano": "1581452773000000111",
+					"severityNumber": 9,
+					"severityText": "Info",
+					"body": { "stringValue": "first" },
+					"attributes": [{
+						"key": "user.id",
+						"value": { "stringValue": "a" }
+					},
+					{
+						"key": "trace.id",
+						"value": { "stringValue": "1234" }
+					}]
+				},
+				{
+					"timeUnixNano": "1581452773000000211",
+					"severityNumber": 9,
+					"severityText": "Info",
+					"body": { "stringValue": "second" },
+					"attributes": [{
+						"key": "user.id",
+						"value": { "stringValue": "b" }
+					}]
+				}]
+			}]
+		}]
+	}`
+
+	expectedEntries := []loki.Entry{
+		{
+			Labels: map[model.LabelName]model.LabelValue{
+				"exporter": model.LabelValue("OTLP"),
+				"level":    model.LabelValue("INFO"),
+				"service":  model.LabelValue("auth"),
+				"user_id":  model.LabelValue("a"),
+			},
+			Entry: push.Entry{
+				Timestamp:          time.Unix(0, int64(1581452773000000111)),
+				Line:               `{"body":"first","severity":"Info"}`,
+				StructuredMetadata: push.LabelsAdapter{{Name: "trace_id", Value: "1234"}},
+			},
+		},
+		{
+			// The second value of user.id exceeds max_label_values, so it's
+			// sent as structured metadata instead.
+			Labels: map[model.LabelName]model.LabelValue{
+				"exporter": model.LabelValue("OTLP"),
+				"level":    model.LabelValue("INFO"),
+				"service":  model.LabelValue("auth"),
+			},
+			Entry: push.Entry{
+				Timestamp:          time.Unix(0, int64(1581452773000000211)),
+				Line:               `{"body":"second","severity":"Info"}`,
+				StructuredMetadata: push.LabelsAdapter{{Name: "user_id", Value: "b"}},
+			},
+		},
+	}
+
+	receiver := loki.NewLogsReceiverWithChannel(make(chan loki.Entry, len(expectedEntries)))
+	converter := convert.New(util.TestFlowLogger(t), prometheus.NewRegistry(), []loki.LogsReceiver{receiver}, convert.Options{
+		Labels: []convert.Mapping{
+			{From: convert.SourceResource, Attribute: "service.name", Name: "service"},
+			{From: convert.SourceRecord, Attribute: "user.id", Name: "user_id"},
+		},
+		StructuredMetadata: []convert.Mapping{
+			{From: convert.SourceRecord, Attribute: "trace.id", Name: "trace_id"},
+		},
+		MaxLabelValues: 1,
+	})
+
+	logs := processortest.CreateTestLogs(inputLogJson)
+	require.NoError(t, converter.ConsumeLogs(context.Background(), logs))
+	close(receiver.Chan())
+
+	for _, expectedEntry := range expectedEntries {
+		entry, ok := <-receiver.Chan()
+		require.True(t, ok)
+		compareLokiEntries(t, &expectedEntry, &entry)
+	}
+
+	// The mappings don't modify the consumed logs.
+	_, ok := logs.ResourceLogs().At(0).Resource().Attributes().Get("service.name")
+	require.True(t, ok)
+}
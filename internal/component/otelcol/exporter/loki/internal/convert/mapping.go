@@ -0,0 +1,143 @@
+package convert
+
+import (
+	"sync"
+
+	"github.com/grafana/loki/pkg/push"
+	prometheustranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/prometheus"
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// Source is where the attribute of a Mapping is read from.
+type Source string
+
+const (
+	// SourceResource reads the attribute from the resource of the log record.
+	SourceResource Source = "resource"
+	// SourceRecord reads the attribute from the log record.
+	SourceRecord Source = "record"
+)
+
+// Mapping maps an OTLP attribute to a Loki label or structured metadata.
+type Mapping struct {
+	// From is where the attribute is read from.
+	From Source
+	// Attribute is the key of the attribute.
+	Attribute string
+	// Name is the name of the label or structured metadata.
+	Name string
+}
+
+// MappingName returns name if it's set, or else the attribute key translated
+// to the Prometheus format.
+func MappingName(attribute, name string) string {
+	if name != "" {
+		return name
+	}
+	return prometheustranslator.NormalizeLabel(attribute)
+}
+
+// Options configures how attributes of log records are mapped.
+type Options struct {
+	// Labels are the attributes to convert to Loki labels.
+	Labels []Mapping
+	// StructuredMetadata are the attributes to convert to structured metadata.
+	StructuredMetadata []Mapping
+	// MaxLabelValues is the maximum number of distinct values of each label of
+	// Labels. Values past the limit are sent as structured metadata instead. 0
+	// means no limit.
+	MaxLabelValues int
+}
+
+// labelGuard tracks the distinct values of the labels created by mappings.
+type labelGuard struct {
+	max int
+
+	mut    sync.Mutex
+	values map[model.LabelName]map[model.LabelValue]struct{}
+}
+
+func newLabelGuard(max int) *labelGuard {
+	return &labelGuard{
+		max:    max,
+		values: make(map[model.LabelName]map[model.LabelValue]struct{}),
+	}
+}
+
+// Allow reports whether value may be used for the label name without
+// exceeding the limit of distinct values of the label.
+func (g *labelGuard) Allow(name model.LabelName, value model.LabelValue) bool {
+	if g.max <= 0 {
+		return true
+	}
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	seen, ok := g.values[name]
+	if !ok {
+		seen = make(map[model.LabelValue]struct{})
+		g.values[name] = seen
+	}
+	if _, ok := seen[value]; ok {
+		return true
+	}
+	if len(seen) >= g.max {
+		return false
+	}
+	seen[value] = struct{}{}
+	return true
+}
+
+// takeAttribute removes the attribute of m from the resource or log record
+// attributes and returns its value.
+func takeAttribute(m Mapping, resAttrs, logAttrs pcommon.Map) (string, bool) {
+	attrs := logAttrs
+	if m.From == SourceResource {
+		attrs = resAttrs
+	}
+	v, ok := attrs.Get(m.Attribute)
+	if !ok {
+		return "", false
+	}
+	value := v.AsString()
+	attrs.Remove(m.Attribute)
+	return value, true
+}
+
+// applyMappings removes the attributes of the mappings from resAttrs and
+// logAttrs, and returns the labels and structured metadata they map to.
+func (conv *Converter) applyMappings(opts Options, guard *labelGuard, resAttrs, logAttrs pcommon.Map) (model.LabelSet, []push.LabelAdapter) {
+	var (
+		labels   model.LabelSet
+		metadata []push.LabelAdapter
+	)
+
+	for _, m := range opts.Labels {
+		value, ok := takeAttribute(m, resAttrs, logAttrs)
+		if !ok {
+			continue
+		}
+		name := m.Name
+		if !guard.Allow(model.LabelName(name), model.LabelValue(value)) {
+			conv.metrics.labelValuesLimited.WithLabelValues(name).Inc()
+			metadata = append(metadata, push.LabelAdapter{Name: name, Value: value})
+			continue
+		}
+		if labels == nil {
+			labels = make(model.LabelSet)
+		}
+		labels[model.LabelName(name)] = model.LabelValue(value)
+	}
+
+	for _, m := range opts.StructuredMetadata {
+		value, ok := takeAttribute(m, resAttrs, logAttrs)
+		if !ok {
+			continue
+		}
+		metadata = append(metadata, push.LabelAdapter{Name: m.Name, Value: value})
+	}
+
+	return labels, metadata
+}
@@ -9,6 +9,8 @@ type metrics struct {
 	entriesTotal     prometheus_client.Counter
 	entriesFailed    prometheus_client.Counter
 	entriesProcessed prometheus_client.Counter
+
+	labelValuesLimited *prometheus_client.CounterVec
 }
 
 func newMetrics(reg prometheus.Registerer) *metrics {
@@ -26,12 +28,17 @@ func newMetrics(reg prometheus.Registerer) *metrics {
 		Name: "otelcol_exporter_loki_entries_processed",
 		Help: "Total number of log entries successfully converted",
 	})
+	m.labelValuesLimited = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "otelcol_exporter_loki_label_values_limited_total",
+		Help: "Total number of label values sent as structured metadata because the label reached max_label_values",
+	}, []string{"label"})
 
 	if reg != nil {
 		reg.MustRegister(
 			m.entriesTotal,
 			m.entriesFailed,
 			m.entriesProcessed,
+			m.labelValuesLimited,
 		)
 	}
 
@@ -3,6 +3,7 @@ package loki
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/internal/component"
@@ -11,6 +12,7 @@ import (
 	"github.com/grafana/agent/internal/component/otelcol/exporter/loki/internal/convert"
 	"github.com/grafana/agent/internal/component/otelcol/internal/lazyconsumer"
 	"github.com/grafana/agent/internal/featuregate"
+	"github.com/prometheus/common/model"
 )
 
 func init() {
@@ -29,6 +31,90 @@ func init() {
 // Arguments configures the otelcol.exporter.loki component.
 type Arguments struct {
 	ForwardTo []loki.LogsReceiver `river:"forward_to,attr"`
+
+	Labels             []AttributeMapping `river:"label,block,optional"`
+	StructuredMetadata []AttributeMapping `river:"structured_metadata,block,optional"`
+	MaxLabelValues     int                `river:"max_label_values,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	MaxLabelValues: 1000,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.MaxLabelValues < 0 {
+		return fmt.Errorf("max_label_values must not be negative")
+	}
+
+	labels := make(map[string]struct{}, len(args.Labels))
+	for _, m := range args.Labels {
+		if err := m.validate("label"); err != nil {
+			return err
+		}
+		name := m.convert().Name
+		if _, ok := labels[name]; ok {
+			return fmt.Errorf("label %q is mapped more than once", name)
+		}
+		labels[name] = struct{}{}
+	}
+	for _, m := range args.StructuredMetadata {
+		if err := m.validate("structured_metadata"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertOptions converts args to the options of the converter.
+func (args *Arguments) convertOptions() convert.Options {
+	opts := convert.Options{MaxLabelValues: args.MaxLabelValues}
+	for _, m := range args.Labels {
+		opts.Labels = append(opts.Labels, m.convert())
+	}
+	for _, m := range args.StructuredMetadata {
+		opts.StructuredMetadata = append(opts.StructuredMetadata, m.convert())
+	}
+	return opts
+}
+
+// AttributeMapping maps an OTLP attribute to a Loki label or to structured
+// metadata.
+type AttributeMapping struct {
+	From      string `river:"from,attr"`
+	Attribute string `river:"attribute,attr"`
+	Name      string `river:"name,attr,optional"`
+}
+
+func (m AttributeMapping) validate(block string) error {
+	switch convert.Source(m.From) {
+	case convert.SourceResource, convert.SourceRecord:
+	default:
+		return fmt.Errorf("%s: unknown from %q, must be one of %s, %s", block, m.From, convert.SourceResource, convert.SourceRecord)
+	}
+	if m.Attribute == "" {
+		return fmt.Errorf("%s: attribute must not be empty", block)
+	}
+	if name := m.convert().Name; !model.LabelName(name).IsValid() {
+		return fmt.Errorf("%s: %q is not a valid name", block, name)
+	}
+	return nil
+}
+
+// convert converts m to a mapping of the converter, defaulting its name to the
+// attribute key translated to the Prometheus format.
+func (m AttributeMapping) convert() convert.Mapping {
+	return convert.Mapping{
+		From:      convert.Source(m.From),
+		Attribute: m.Attribute,
+		Name:      convert.MappingName(m.Attribute, m.Name),
+	}
 }
 
 // Component is the otelcol.exporter.loki component.
@@ -43,7 +129,7 @@ var _ component.Component = (*Component)(nil)
 
 // New creates a new otelcol.exporter.loki component.
 func New(o component.Options, c Arguments) (*Component, error) {
-	converter := convert.New(o.Logger, o.Registerer, c.ForwardTo)
+	converter := convert.New(o.Logger, o.Registerer, c.ForwardTo, c.convertOptions())
 
 	res := &Component{
 		log:  o.Logger,
@@ -75,5 +161,6 @@ func (c *Component) Run(ctx context.Context) error {
 func (c *Component) Update(newConfig component.Arguments) error {
 	cfg := newConfig.(Arguments)
 	c.converter.UpdateFanout(cfg.ForwardTo)
+	c.converter.UpdateOptions(cfg.convertOptions())
 	return nil
 }
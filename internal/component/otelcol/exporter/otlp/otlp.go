@@ -69,7 +69,11 @@ func (args Arguments) Convert() (otelcomponent.Config, error) {
 
 // Extensions implements exporter.Arguments.
 func (args Arguments) Extensions() map[otelcomponent.ID]otelextension.Extension {
-	return (*otelcol.GRPCClientArguments)(&args.Client).Extensions()
+	m := (*otelcol.GRPCClientArguments)(&args.Client).Extensions()
+	for id, ext := range args.Queue.Extensions() {
+		m[id] = ext
+	}
+	return m
 }
 
 // Exporters implements exporter.Arguments.
@@ -86,6 +90,11 @@ func (args Arguments) DebugMetricsConfig() otelcol.DebugMetricsArguments {
 // component-specific defaults.
 type GRPCClientArguments otelcol.GRPCClientArguments
 
+// Validate implements river.Validator.
+func (args *GRPCClientArguments) Validate() error {
+	return (*otelcol.GRPCClientArguments)(args).Validate()
+}
+
 // SetToDefault implements river.Defaulter.
 func (args *GRPCClientArguments) SetToDefault() {
 	*args = GRPCClientArguments{
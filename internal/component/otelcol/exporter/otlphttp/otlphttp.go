@@ -71,7 +71,11 @@ func (args Arguments) Convert() (otelcomponent.Config, error) {
 
 // Extensions implements exporter.Arguments.
 func (args Arguments) Extensions() map[otelcomponent.ID]otelextension.Extension {
-	return (*otelcol.HTTPClientArguments)(&args.Client).Extensions()
+	m := (*otelcol.HTTPClientArguments)(&args.Client).Extensions()
+	for id, ext := range args.Queue.Extensions() {
+		m[id] = ext
+	}
+	return m
 }
 
 // Exporters implements exporter.Arguments.
@@ -96,6 +100,11 @@ func (args *Arguments) Validate() error {
 // component-specific defaults.
 type HTTPClientArguments otelcol.HTTPClientArguments
 
+// Validate implements river.Validator.
+func (args *HTTPClientArguments) Validate() error {
+	return (*otelcol.HTTPClientArguments)(args).Validate()
+}
+
 // Default server settings.
 var (
 	DefaultMaxIdleConns    = 100
@@ -0,0 +1,249 @@
+package spanevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-logfmt/logfmt"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	otelconsumer "go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.5.0"
+)
+
+// Reasons for which a span is logged.
+const (
+	reasonError = "error"
+	reasonSlow  = "slow"
+)
+
+// consumer implements consumer.Traces and logs the spans matching the
+// configured filters.
+type consumer struct {
+	log log.Logger
+
+	mut         sync.RWMutex
+	args        Arguments
+	spanKinds   map[ptrace.SpanKind]struct{} // Empty to match every kind.
+	labelNames  map[string]model.LabelName   // Attribute name to label name.
+	receiversCh []chan<- loki.Entry
+}
+
+var _ otelconsumer.Traces = (*consumer)(nil)
+
+func newConsumer(l log.Logger) *consumer {
+	if l == nil {
+		l = log.NewNopLogger()
+	}
+	return &consumer{log: l}
+}
+
+func (c *consumer) update(args Arguments) {
+	spanKindSet := make(map[ptrace.SpanKind]struct{}, len(args.SpanKinds))
+	for _, kind := range args.SpanKinds {
+		spanKindSet[spanKinds[strings.ToLower(kind)]] = struct{}{}
+	}
+	labelNames := make(map[string]model.LabelName, len(args.Labels))
+	for _, attr := range args.Labels {
+		labelNames[attr] = model.LabelName(sanitizeLabelName(attr))
+	}
+	receivers := make([]chan<- loki.Entry, 0, len(args.ForwardTo))
+	for _, r := range args.ForwardTo {
+		receivers = append(receivers, r.Chan())
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = args
+	c.spanKinds = spanKindSet
+	c.labelNames = labelNames
+	c.receiversCh = receivers
+}
+
+// Capabilities implements consumer.Traces.
+func (c *consumer) Capabilities() otelconsumer.Capabilities {
+	return otelconsumer.Capabilities{MutatesData: false}
+}
+
+// ConsumeTraces implements consumer.Traces.
+func (c *consumer) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	var entries []loki.Entry
+
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		resource := rss.At(i).Resource()
+		sss := rss.At(i).ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			spans := sss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				reason, ok := c.match(span)
+				if !ok {
+					continue
+				}
+				entry, err := c.toEntry(resource, span, reason)
+				if err != nil {
+					level.Error(c.log).Log("msg", "failed to convert span to log entry", "err", err)
+					continue
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		for _, ch := range c.receiversCh {
+			select {
+			case <-ctx.Done():
+				return nil
+			case ch <- entry:
+				// no-op, send the entry along
+			}
+		}
+	}
+	return nil
+}
+
+// match returns why span must be logged, or false if it doesn't match the
+// filters.
+func (c *consumer) match(span ptrace.Span) (string, bool) {
+	if len(c.spanKinds) > 0 {
+		if _, ok := c.spanKinds[span.Kind()]; !ok {
+			return "", false
+		}
+	}
+
+	switch {
+	case c.args.Errors && span.Status().Code() == ptrace.StatusCodeError:
+		return reasonError, true
+	case c.args.MinDuration > 0 && spanDuration(span) >= c.args.MinDuration:
+		return reasonSlow, true
+	default:
+		return "", false
+	}
+}
+
+func (c *consumer) toEntry(resource pcommon.Resource, span ptrace.Span, reason string) (loki.Entry, error) {
+	lset := make(model.LabelSet)
+	var service string
+	if v, ok := resource.Attributes().Get(semconv.AttributeServiceName); ok {
+		service = v.AsString()
+		lset["service_name"] = model.LabelValue(service)
+	}
+	for attr, name := range c.labelNames {
+		if v, ok := lookupAttribute(resource, span, attr); ok && v != "" {
+			lset[name] = model.LabelValue(v)
+		}
+	}
+
+	fields := []keyValue{
+		{"trace_id", span.TraceID().String()},
+		{"span_id", span.SpanID().String()},
+	}
+	if !span.ParentSpanID().IsEmpty() {
+		fields = append(fields, keyValue{"parent_span_id", span.ParentSpanID().String()})
+	}
+	if service != "" {
+		fields = append(fields, keyValue{"service", service})
+	}
+	fields = append(fields,
+		keyValue{"name", span.Name()},
+		keyValue{"kind", strings.ToLower(span.Kind().String())},
+		keyValue{"duration", spanDuration(span).String()},
+		keyValue{"status", strings.ToLower(span.Status().Code().String())},
+	)
+	if msg := span.Status().Message(); msg != "" {
+		fields = append(fields, keyValue{"status_message", msg})
+	}
+	fields = append(fields, keyValue{"reason", reason})
+	for _, attr := range c.args.Attributes {
+		if v, ok := span.Attributes().Get(attr); ok {
+			fields = append(fields, keyValue{attr, v.AsString()})
+		}
+	}
+
+	line, err := formatLine(c.args.LogFormat, fields)
+	if err != nil {
+		return loki.Entry{}, err
+	}
+
+	ts := span.EndTimestamp().AsTime()
+	if span.EndTimestamp() == 0 {
+		ts = time.Now()
+	}
+	return loki.Entry{
+		Labels: lset,
+		Entry:  logproto.Entry{Timestamp: ts, Line: line},
+	}, nil
+}
+
+type keyValue struct {
+	key, value string
+}
+
+func formatLine(format string, fields []keyValue) (string, error) {
+	if format == FormatJSON {
+		m := make(map[string]string, len(fields))
+		for _, f := range fields {
+			m[f.key] = f.value
+		}
+		b, err := json.Marshal(m)
+		return string(b), err
+	}
+
+	var buf bytes.Buffer
+	enc := logfmt.NewEncoder(&buf)
+	for _, f := range fields {
+		if err := enc.EncodeKeyval(f.key, f.value); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+func spanDuration(span ptrace.Span) time.Duration {
+	if span.EndTimestamp() < span.StartTimestamp() {
+		return 0
+	}
+	return span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime())
+}
+
+// lookupAttribute returns the value of the span attribute attr, or of the
+// resource attribute attr if the span doesn't have it.
+func lookupAttribute(resource pcommon.Resource, span ptrace.Span, attr string) (string, bool) {
+	if v, ok := span.Attributes().Get(attr); ok {
+		return v.AsString(), true
+	}
+	if v, ok := resource.Attributes().Get(attr); ok {
+		return v.AsString(), true
+	}
+	return "", false
+}
+
+// sanitizeLabelName replaces the characters of an attribute name which aren't
+// valid in label names with underscores, such as the dots of
+// "k8s.namespace.name".
+func sanitizeLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
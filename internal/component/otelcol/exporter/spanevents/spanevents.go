@@ -0,0 +1,126 @@
+// Package spanevents provides an otelcol.exporter.spanevents component.
+package spanevents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/otelcol"
+	"github.com/grafana/agent/internal/component/otelcol/internal/lazyconsumer"
+	"github.com/grafana/agent/internal/featuregate"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "otelcol.exporter.spanevents",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   otelcol.ConsumerExports{},
+
+		Build: func(o component.Options, a component.Arguments) (component.Component, error) {
+			return New(o, a.(Arguments))
+		},
+	})
+}
+
+// Formats of the generated log lines.
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
+
+// Arguments configures the otelcol.exporter.spanevents component.
+type Arguments struct {
+	ForwardTo []loki.LogsReceiver `river:"forward_to,attr"`
+
+	Errors      bool          `river:"errors,attr,optional"`
+	MinDuration time.Duration `river:"min_duration,attr,optional"`
+	SpanKinds   []string      `river:"span_kinds,attr,optional"`
+
+	Attributes []string `river:"attributes,attr,optional"`
+	Labels     []string `river:"labels,attr,optional"`
+	LogFormat  string   `river:"log_format,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Errors:    true,
+	LogFormat: FormatLogfmt,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if !args.Errors && args.MinDuration <= 0 {
+		return fmt.Errorf("at least one of errors or min_duration must be set")
+	}
+	if args.MinDuration < 0 {
+		return fmt.Errorf("min_duration must not be negative")
+	}
+	for _, kind := range args.SpanKinds {
+		if _, ok := spanKinds[strings.ToLower(kind)]; !ok {
+			return fmt.Errorf("unsupported span kind %q: supported kinds are internal, server, client, producer and consumer", kind)
+		}
+	}
+	if args.LogFormat != FormatLogfmt && args.LogFormat != FormatJSON {
+		return fmt.Errorf("supported values of log_format are %s and %s", FormatLogfmt, FormatJSON)
+	}
+	return nil
+}
+
+var spanKinds = map[string]ptrace.SpanKind{
+	"internal": ptrace.SpanKindInternal,
+	"server":   ptrace.SpanKindServer,
+	"client":   ptrace.SpanKindClient,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+}
+
+// Component is the otelcol.exporter.spanevents component.
+type Component struct {
+	opts     component.Options
+	consumer *consumer
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new otelcol.exporter.spanevents component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	res := &Component{
+		opts:     o,
+		consumer: newConsumer(o.Logger),
+	}
+	if err := res.Update(args); err != nil {
+		return nil, err
+	}
+
+	// Construct a consumer based on our converter and export it. This will
+	// remain the same throughout the component's lifetime, so we do this
+	// during component construction.
+	export := lazyconsumer.New(context.Background())
+	export.SetConsumers(res.consumer, nil, nil)
+	o.OnStateChange(otelcol.ConsumerExports{Input: export})
+
+	return res, nil
+}
+
+// Run implements Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements Component.
+func (c *Component) Update(newConfig component.Arguments) error {
+	c.consumer.update(newConfig.(Arguments))
+	return nil
+}
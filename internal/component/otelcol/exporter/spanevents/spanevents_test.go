@@ -0,0 +1,111 @@
+package spanevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		forward_to   = []
+		min_duration = "500ms"
+		span_kinds   = ["server"]
+	`), &args))
+	require.True(t, args.Errors)
+	require.Equal(t, 500*time.Millisecond, args.MinDuration)
+	require.Equal(t, FormatLogfmt, args.LogFormat)
+
+	require.EqualError(t, river.Unmarshal([]byte(`
+		forward_to = []
+		errors     = false
+	`), &args), "at least one of errors or min_duration must be set")
+}
+
+func newTestTraces() ptrace.Traces {
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "checkout")
+	rs.Resource().Attributes().PutStr("k8s.namespace.name", "shop")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+
+	start := time.Unix(1000, 0)
+	addSpan := func(name string, kind ptrace.SpanKind, duration time.Duration, code ptrace.StatusCode) ptrace.Span {
+		span := spans.AppendEmpty()
+		span.SetName(name)
+		span.SetKind(kind)
+		span.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+		span.SetSpanID(pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, byte(spans.Len())})
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(duration)))
+		span.Status().SetCode(code)
+		return span
+	}
+
+	failed := addSpan("POST /cart", ptrace.SpanKindServer, 20*time.Millisecond, ptrace.StatusCodeError)
+	failed.Status().SetMessage("payment declined")
+	failed.Attributes().PutInt("http.status_code", 502)
+	addSpan("GET /cart", ptrace.SpanKindServer, 10*time.Millisecond, ptrace.StatusCodeOk)
+	addSpan("GET /products", ptrace.SpanKindServer, 2*time.Second, ptrace.StatusCodeUnset)
+	addSpan("SELECT products", ptrace.SpanKindClient, 3*time.Second, ptrace.StatusCodeUnset)
+	return td
+}
+
+func consume(t *testing.T, args Arguments) []loki.Entry {
+	receiver := loki.NewLogsReceiverWithChannel(make(chan loki.Entry, 10))
+	args.ForwardTo = []loki.LogsReceiver{receiver}
+
+	c := newConsumer(util.TestLogger(t))
+	c.update(args)
+	require.NoError(t, c.ConsumeTraces(context.Background(), newTestTraces()))
+
+	var entries []loki.Entry
+	for len(receiver.Chan()) > 0 {
+		entries = append(entries, <-receiver.Chan())
+	}
+	return entries
+}
+
+func TestErrorSpans(t *testing.T) {
+	args := DefaultArguments
+	args.Attributes = []string{"http.status_code"}
+	args.Labels = []string{"k8s.namespace.name"}
+
+	entries := consume(t, args)
+	require.Len(t, entries, 1)
+	require.Equal(t, model.LabelSet{"service_name": "checkout", "k8s_namespace_name": "shop"}, entries[0].Labels)
+	require.True(t, time.Unix(1000, 0).Add(20*time.Millisecond).Equal(entries[0].Timestamp))
+	require.Equal(t,
+		`trace_id=0102030405060708090a0b0c0d0e0f10 span_id=0102030405060701 service=checkout name="POST /cart" kind=server duration=20ms status=error status_message="payment declined" reason=error http.status_code=502`,
+		entries[0].Line)
+}
+
+func TestSlowSpans(t *testing.T) {
+	args := DefaultArguments
+	args.MinDuration = time.Second
+	args.SpanKinds = []string{"server"}
+	args.LogFormat = FormatJSON
+
+	entries := consume(t, args)
+	require.Len(t, entries, 2)
+	require.Contains(t, entries[0].Line, `"reason":"error"`)
+	require.JSONEq(t, `{
+		"trace_id": "0102030405060708090a0b0c0d0e0f10",
+		"span_id": "0102030405060703",
+		"service": "checkout",
+		"name": "GET /products",
+		"kind": "server",
+		"duration": "2s",
+		"status": "unset",
+		"reason": "slow"
+	}`, entries[1].Line)
+}
@@ -133,6 +133,11 @@ func (args *HTTPServerArguments) SetToDefault() {
 // component-specific defaults.
 type GRPCClientArguments otelcol.GRPCClientArguments
 
+// Validate implements river.Validator.
+func (args *GRPCClientArguments) Validate() error {
+	return (*otelcol.GRPCClientArguments)(args).Validate()
+}
+
 // SetToDefault implements river.Defaulter.
 func (args *GRPCClientArguments) SetToDefault() {
 	*args = GRPCClientArguments{
@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	otelconsumer "go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// The processors of a Flow component acquire a worker from the executor of
+// the component when they receive data, and release it as soon as they hand
+// data over to the next consumers. This bounds the processing done by the
+// processor itself, without holding a worker while downstream components
+// process or send data, which could otherwise deadlock pipelines where
+// several processors share an execution pool.
+
+type releaseKey struct{}
+
+// acquireWorker acquires a worker from e. The returned context carries the
+// release function so that it can be called by releaseWorker.
+func acquireWorker(ctx context.Context, e component.Executor) (context.Context, func(), error) {
+	release, err := component.Acquire(ctx, e)
+	if err != nil {
+		return ctx, nil, err
+	}
+	var once sync.Once
+	releaseOnce := func() { once.Do(release) }
+	return context.WithValue(ctx, releaseKey{}, releaseOnce), releaseOnce, nil
+}
+
+// releaseWorker releases the worker acquired for ctx, if any.
+func releaseWorker(ctx context.Context) {
+	if release, ok := ctx.Value(releaseKey{}).(func()); ok {
+		release()
+	}
+}
+
+// executorTraces acquires a worker before passing traces to a processor.
+type executorTraces struct {
+	executor component.Executor
+	otelconsumer.Traces
+}
+
+func (c *executorTraces) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	ctx, release, err := acquireWorker(ctx, c.executor)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.Traces.ConsumeTraces(ctx, td)
+}
+
+// executorMetrics acquires a worker before passing metrics to a processor.
+type executorMetrics struct {
+	executor component.Executor
+	otelconsumer.Metrics
+}
+
+func (c *executorMetrics) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	ctx, release, err := acquireWorker(ctx, c.executor)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.Metrics.ConsumeMetrics(ctx, md)
+}
+
+// executorLogs acquires a worker before passing logs to a processor.
+type executorLogs struct {
+	executor component.Executor
+	otelconsumer.Logs
+}
+
+func (c *executorLogs) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	ctx, release, err := acquireWorker(ctx, c.executor)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.Logs.ConsumeLogs(ctx, ld)
+}
+
+// releasingTraces releases the worker of the processor before passing traces
+// to the next consumers.
+type releasingTraces struct{ otelconsumer.Traces }
+
+func (c releasingTraces) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	releaseWorker(ctx)
+	return c.Traces.ConsumeTraces(ctx, td)
+}
+
+// releasingMetrics releases the worker of the processor before passing
+// metrics to the next consumers.
+type releasingMetrics struct{ otelconsumer.Metrics }
+
+func (c releasingMetrics) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	releaseWorker(ctx)
+	return c.Metrics.ConsumeMetrics(ctx, md)
+}
+
+// releasingLogs releases the worker of the processor before passing logs to
+// the next consumers.
+type releasingLogs struct{ otelconsumer.Logs }
+
+func (c releasingLogs) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	releaseWorker(ctx)
+	return c.Logs.ConsumeLogs(ctx, ld)
+}
+
+// withExecutor wraps the processors so that they acquire a worker from e
+// before processing data. nil processors are kept nil.
+func withExecutor(e component.Executor, t otelconsumer.Traces, m otelconsumer.Metrics, l otelconsumer.Logs) (otelconsumer.Traces, otelconsumer.Metrics, otelconsumer.Logs) {
+	if t != nil {
+		t = &executorTraces{executor: e, Traces: t}
+	}
+	if m != nil {
+		m = &executorMetrics{executor: e, Metrics: m}
+	}
+	if l != nil {
+		l = &executorLogs{executor: e, Logs: l}
+	}
+	return t, m, l
+}
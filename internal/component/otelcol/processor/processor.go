@@ -155,9 +155,9 @@ func (p *Processor) Update(args component.Arguments) error {
 
 	var (
 		next        = pargs.NextConsumers()
-		nextTraces  = fanoutconsumer.Traces(next.Traces)
-		nextMetrics = fanoutconsumer.Metrics(next.Metrics)
-		nextLogs    = fanoutconsumer.Logs(next.Logs)
+		nextTraces  = releasingTraces{fanoutconsumer.Traces(next.Traces)}
+		nextMetrics = releasingMetrics{fanoutconsumer.Metrics(next.Metrics)}
+		nextLogs    = releasingLogs{fanoutconsumer.Logs(next.Logs)}
 	)
 
 	// Create instances of the processor from our factory for each of our
@@ -196,7 +196,7 @@ func (p *Processor) Update(args component.Arguments) error {
 
 	// Schedule the components to run once our component is running.
 	p.sched.Schedule(host, components...)
-	p.consumer.SetConsumers(tracesProcessor, metricsProcessor, logsProcessor)
+	p.consumer.SetConsumers(withExecutor(p.opts.Executor, tracesProcessor, metricsProcessor, logsProcessor))
 	return nil
 }
 
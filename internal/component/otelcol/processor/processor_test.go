@@ -3,6 +3,7 @@ package processor_test
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -55,7 +56,7 @@ func TestProcessor(t *testing.T) {
 
 	// Create and start our Flow component. We then wait for it to export a
 	// consumer that we can send data to.
-	te := newTestEnvironment(t, innerProcessor, onTracesConsumer)
+	te := newTestEnvironment(t, innerProcessor, onTracesConsumer, nil)
 	te.Start(fakeProcessorArgs{
 		Output: &otelcol.ConsumerArguments{
 			Metrics: []otelcol.Consumer{nextConsumer},
@@ -91,6 +92,62 @@ func TestProcessor(t *testing.T) {
 	require.NoError(t, waitTracesTrigger.Wait(time.Second), "consumer did not get invoked")
 }
 
+func TestProcessor_Executor(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+
+	// The executor tracks how many workers are held, so that the test can check
+	// that the worker is only held while the processor itself processes data.
+	var (
+		held     atomic.Int32
+		executor = component.ExecutorFunc(func(context.Context) (func(), error) {
+			held.Add(1)
+			return func() { held.Add(-1) }, nil
+		})
+
+		consumer otelconsumer.Traces
+
+		waitConsumerTrigger = util.NewWaitTrigger()
+		onTracesConsumer    = func(t otelconsumer.Traces) {
+			consumer = t
+			waitConsumerTrigger.Trigger()
+		}
+
+		heldByProcessor, heldByNext = make(chan int32, 1), make(chan int32, 1)
+		nextConsumer                = &fakeconsumer.Consumer{
+			ConsumeTracesFunc: func(context.Context, ptrace.Traces) error {
+				heldByNext <- held.Load()
+				return nil
+			},
+		}
+
+		innerProcessor = &fakeProcessor{
+			ConsumeTracesFunc: func(ctx context.Context, td ptrace.Traces) error {
+				require.NoError(t, waitConsumerTrigger.Wait(time.Second), "no next consumer registered")
+				heldByProcessor <- held.Load()
+				return consumer.ConsumeTraces(ctx, td)
+			},
+		}
+	)
+
+	te := newTestEnvironment(t, innerProcessor, onTracesConsumer, executor)
+	te.Start(fakeProcessorArgs{
+		Output: &otelcol.ConsumerArguments{
+			Traces: []otelcol.Consumer{nextConsumer},
+		},
+	})
+
+	require.NoError(t, te.Controller.WaitExports(1*time.Second), "test component did not generate exports")
+	ce := te.Controller.Exports().(otelcol.ConsumerExports)
+
+	require.Eventually(t, func() bool {
+		return !errors.Is(ce.Input.ConsumeTraces(ctx, ptrace.NewTraces()), otelcomponent.ErrDataTypeIsNotSupported)
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, int32(1), <-heldByProcessor)
+	require.Equal(t, int32(0), <-heldByNext)
+	require.Equal(t, int32(0), held.Load())
+}
+
 type testEnvironment struct {
 	t *testing.T
 
@@ -101,6 +158,7 @@ func newTestEnvironment(
 	t *testing.T,
 	fp otelprocessor.Traces,
 	onTracesConsumer func(t otelconsumer.Traces),
+	executor component.Executor,
 ) *testEnvironment {
 
 	t.Helper()
@@ -131,6 +189,7 @@ func newTestEnvironment(
 				}, otelcomponent.StabilityLevelUndefined),
 			)
 
+			opts.Executor = executor
 			return processor.New(opts, factory, args.(processor.Arguments))
 		},
 	}
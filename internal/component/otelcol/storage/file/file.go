@@ -0,0 +1,166 @@
+// Package file provides an otelcol.storage.file component.
+package file
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/otelcol/storage"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/storage/filestorage"
+	otelcomponent "go.opentelemetry.io/collector/component"
+	otelextension "go.opentelemetry.io/collector/extension"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "otelcol.storage.file",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   storage.Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			c := &Component{dataPath: opts.DataPath}
+
+			fargs, err := c.withDefaults(args.(Arguments))
+			if err != nil {
+				return nil, err
+			}
+			factory := newLimitFactory(filestorage.NewFactory(), newLimitMetrics(opts.Registerer))
+			s, err := storage.New(opts, factory, fargs)
+			if err != nil {
+				return nil, err
+			}
+			c.Storage = s
+			return c, nil
+		},
+	})
+}
+
+// Arguments configures the otelcol.storage.file component.
+type Arguments struct {
+	// Directory is where data is persisted. Defaults to the data directory of
+	// the component.
+	Directory string        `river:"directory,attr,optional"`
+	Timeout   time.Duration `river:"timeout,attr,optional"`
+
+	// MaxSize limits the bytes stored by the clients of the storage. 0 means
+	// no limit.
+	MaxSize units.Base2Bytes `river:"max_size,attr,optional"`
+
+	Compaction CompactionArguments `river:"compaction,block,optional"`
+}
+
+var _ storage.Arguments = Arguments{}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Timeout: time.Second,
+
+	Compaction: CompactionArguments{
+		ReboundNeededThresholdMiB:  100,
+		ReboundTriggerThresholdMiB: 10,
+		MaxTransactionSize:         65536,
+		CheckInterval:              5 * time.Second,
+	},
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than zero")
+	}
+	if args.MaxSize < 0 {
+		return fmt.Errorf("max_size must not be negative")
+	}
+	if args.Compaction.MaxTransactionSize < 0 {
+		return fmt.Errorf("compaction max_transaction_size must not be negative")
+	}
+	if args.Compaction.OnRebound && args.Compaction.CheckInterval <= 0 {
+		return fmt.Errorf("compaction check_interval must be greater than zero when on_rebound is set")
+	}
+	return nil
+}
+
+// Convert implements storage.Arguments.
+func (args Arguments) Convert() (otelcomponent.Config, error) {
+	compactionDir := args.Compaction.Directory
+	if compactionDir == "" {
+		compactionDir = args.Directory
+	}
+
+	return &limitConfig{
+		Config: &filestorage.Config{
+			Directory: args.Directory,
+			Timeout:   args.Timeout,
+
+			Compaction: &filestorage.CompactionConfig{
+				OnStart:                    args.Compaction.OnStart,
+				OnRebound:                  args.Compaction.OnRebound,
+				Directory:                  compactionDir,
+				ReboundNeededThresholdMiB:  args.Compaction.ReboundNeededThresholdMiB,
+				ReboundTriggerThresholdMiB: args.Compaction.ReboundTriggerThresholdMiB,
+				MaxTransactionSize:         args.Compaction.MaxTransactionSize,
+				CheckInterval:              args.Compaction.CheckInterval,
+			},
+		},
+		MaxSize: int64(args.MaxSize),
+	}, nil
+}
+
+// Extensions implements storage.Arguments.
+func (args Arguments) Extensions() map[otelcomponent.ID]otelextension.Extension {
+	return nil
+}
+
+// Exporters implements storage.Arguments.
+func (args Arguments) Exporters() map[otelcomponent.DataType]map[otelcomponent.ID]otelcomponent.Component {
+	return nil
+}
+
+// CompactionArguments configures compaction of the storage files.
+type CompactionArguments struct {
+	OnStart                    bool          `river:"on_start,attr,optional"`
+	OnRebound                  bool          `river:"on_rebound,attr,optional"`
+	Directory                  string        `river:"directory,attr,optional"`
+	ReboundNeededThresholdMiB  int64         `river:"rebound_needed_threshold_mib,attr,optional"`
+	ReboundTriggerThresholdMiB int64         `river:"rebound_trigger_threshold_mib,attr,optional"`
+	MaxTransactionSize         int64         `river:"max_transaction_size,attr,optional"`
+	CheckInterval              time.Duration `river:"check_interval,attr,optional"`
+}
+
+// Component wraps storage.Storage to default the storage directory to the
+// data directory of the component.
+type Component struct {
+	*storage.Storage
+
+	dataPath string
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	fargs, err := c.withDefaults(args.(Arguments))
+	if err != nil {
+		return err
+	}
+	return c.Storage.Update(fargs)
+}
+
+// withDefaults fills in the directory of args and ensures it exists.
+func (c *Component) withDefaults(args Arguments) (Arguments, error) {
+	if args.Directory == "" {
+		args.Directory = c.dataPath
+	}
+	if err := os.MkdirAll(args.Directory, 0770); err != nil {
+		return args, fmt.Errorf("creating storage directory: %w", err)
+	}
+	return args, nil
+}
@@ -0,0 +1,175 @@
+package file_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/otelcol/storage"
+	"github.com/grafana/agent/internal/component/otelcol/storage/file"
+	"github.com/grafana/agent/internal/flow/componenttest"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+	otelcomponent "go.opentelemetry.io/collector/component"
+	extstorage "go.opentelemetry.io/collector/extension/experimental/storage"
+)
+
+// Test performs a basic integration test which runs the otelcol.storage.file
+// component and ensures that data written through it is persisted.
+func Test(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	l := util.TestLogger(t)
+
+	ctrl, err := componenttest.NewControllerFromID(l, "otelcol.storage.file")
+	require.NoError(t, err)
+
+	cfg := `
+		timeout = "5s"
+	`
+	var args file.Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+
+	go func() {
+		err := ctrl.Run(ctx, args)
+		require.NoError(t, err)
+	}()
+
+	require.NoError(t, ctrl.WaitRunning(time.Second), "component never started")
+	require.NoError(t, ctrl.WaitExports(time.Second), "component never exported anything")
+
+	exports := ctrl.Exports().(storage.Exports)
+	require.NotNil(t, exports.Handler.Extension, "handler extension is nil")
+
+	ext, ok := exports.Handler.Extension.(extstorage.Extension)
+	require.True(t, ok, "handler does not implement storage.Extension")
+
+	// Wait for the extension to be started by the scheduler before requesting a
+	// client.
+	var client extstorage.Client
+	require.Eventually(t, func() bool {
+		client, err = ext.GetClient(ctx, otelcomponent.KindExporter, otelcomponent.NewID("otlp"), "traces")
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	defer client.Close(ctx)
+
+	require.NoError(t, client.Set(ctx, "key", []byte("value")))
+	value, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+}
+
+// TestMaxSize ensures that writes of new keys are rejected once the storage
+// reached max_size, and that the stored size is kept across clients.
+func TestMaxSize(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "otelcol.storage.file")
+	require.NoError(t, err)
+
+	var args file.Arguments
+	require.NoError(t, river.Unmarshal([]byte(`max_size = "1KiB"`), &args))
+
+	go func() {
+		err := ctrl.Run(ctx, args)
+		require.NoError(t, err)
+	}()
+	require.NoError(t, ctrl.WaitRunning(time.Second), "component never started")
+	require.NoError(t, ctrl.WaitExports(time.Second), "component never exported anything")
+
+	ext := ctrl.Exports().(storage.Exports).Handler.Extension.(extstorage.Extension)
+	getClient := func() extstorage.Client {
+		var client extstorage.Client
+		require.Eventually(t, func() bool {
+			client, err = ext.GetClient(ctx, otelcomponent.KindExporter, otelcomponent.NewID("otlp"), "traces")
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+		return client
+	}
+	value := func(n int) []byte { return make([]byte, n) }
+
+	client := getClient()
+	require.NoError(t, client.Set(ctx, "a", value(500)))
+	require.NoError(t, client.Set(ctx, "b", value(500)))
+	require.Error(t, client.Set(ctx, "c", value(500)))
+
+	// Existing keys can be replaced, even past the limit.
+	require.NoError(t, client.Set(ctx, "a", value(600)))
+	require.NoError(t, client.Delete(ctx, "b"))
+	require.NoError(t, client.Set(ctx, "c", value(100)))
+	require.NoError(t, client.Close(ctx))
+
+	// The size of the data stored by the previous client, 700 bytes, is
+	// restored.
+	client = getClient()
+	defer client.Close(ctx)
+	require.Error(t, client.Set(ctx, "d", value(400)))
+	require.NoError(t, client.Set(ctx, "d", value(300)))
+
+	stored, err := client.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Len(t, stored, 600)
+}
+
+// TestMaxSize_Batch ensures that keys written more than once in a batch are
+// only counted once.
+func TestMaxSize_Batch(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "otelcol.storage.file")
+	require.NoError(t, err)
+
+	var args file.Arguments
+	require.NoError(t, river.Unmarshal([]byte(`max_size = "1KiB"`), &args))
+
+	go func() {
+		err := ctrl.Run(ctx, args)
+		require.NoError(t, err)
+	}()
+	require.NoError(t, ctrl.WaitRunning(time.Second), "component never started")
+	require.NoError(t, ctrl.WaitExports(time.Second), "component never exported anything")
+
+	ext := ctrl.Exports().(storage.Exports).Handler.Extension.(extstorage.Extension)
+	var client extstorage.Client
+	require.Eventually(t, func() bool {
+		client, err = ext.GetClient(ctx, otelcomponent.KindExporter, otelcomponent.NewID("otlp"), "traces")
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	defer client.Close(ctx)
+	value := func(n int) []byte { return make([]byte, n) }
+
+	require.NoError(t, client.Set(ctx, "a", value(500)))
+	require.NoError(t, client.Set(ctx, "b", value(400)))
+
+	// Deleting a twice only subtracts its size once, so 400 bytes are left.
+	require.NoError(t, client.Batch(ctx, extstorage.DeleteOperation("a"), extstorage.DeleteOperation("a")))
+	require.Error(t, client.Set(ctx, "x", value(625)))
+
+	// A key set then deleted in the same batch isn't counted.
+	require.NoError(t, client.Batch(ctx, extstorage.SetOperation("c", value(300)), extstorage.DeleteOperation("c")))
+	require.NoError(t, client.Set(ctx, "x", value(624)))
+	require.NoError(t, client.Delete(ctx, "x"))
+
+	// A key set twice in the same batch is counted with its last value.
+	require.NoError(t, client.Batch(ctx, extstorage.SetOperation("d", value(100)), extstorage.SetOperation("d", value(200))))
+	require.NoError(t, client.Set(ctx, "x", value(424)))
+	require.Error(t, client.Set(ctx, "y", value(1)))
+}
+
+func TestArguments_Defaults(t *testing.T) {
+	var args file.Arguments
+	require.NoError(t, river.Unmarshal([]byte(``), &args))
+	require.Equal(t, file.DefaultArguments, args)
+
+	cfg := `
+		timeout = "0s"
+	`
+	require.EqualError(t, river.Unmarshal([]byte(cfg), &args), "timeout must be greater than zero")
+}
@@ -0,0 +1,213 @@
+package file
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/storage/filestorage"
+	"github.com/prometheus/client_golang/prometheus"
+	otelcomponent "go.opentelemetry.io/collector/component"
+	otelextension "go.opentelemetry.io/collector/extension"
+	extstorage "go.opentelemetry.io/collector/extension/experimental/storage"
+)
+
+// sizeKey is the key where clients persist the number of bytes they store, so
+// that the size is known after restarts.
+const sizeKey = "agent_stored_bytes"
+
+// errStorageFull is returned for writes which would exceed max_size.
+var errStorageFull = errors.New("otelcol.storage.file: max_size reached")
+
+// limitConfig is the configuration of the extensions of limitFactory.
+type limitConfig struct {
+	*filestorage.Config
+
+	// MaxSize is the maximum number of bytes stored by the clients of the
+	// extension. 0 means no limit.
+	MaxSize int64
+}
+
+// limitMetrics holds the metrics of the size limit.
+type limitMetrics struct {
+	storedBytes   prometheus.Gauge
+	rejectedTotal prometheus.Counter
+}
+
+func newLimitMetrics(r prometheus.Registerer) *limitMetrics {
+	m := &limitMetrics{
+		storedBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "otelcol_storage_file_stored_bytes",
+			Help: "Number of bytes stored by the clients of the storage, not including storage overhead.",
+		}),
+		rejectedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "otelcol_storage_file_rejected_writes_total",
+			Help: "Number of writes rejected because the storage reached max_size.",
+		}),
+	}
+	r.MustRegister(m.storedBytes, m.rejectedTotal)
+	return m
+}
+
+// newLimitFactory returns a factory which creates the extensions of inner,
+// limiting the bytes stored by their clients to the MaxSize of limitConfig.
+func newLimitFactory(inner otelextension.Factory, m *limitMetrics) otelextension.Factory {
+	return otelextension.NewFactory(
+		inner.Type(),
+		inner.CreateDefaultConfig,
+		func(ctx context.Context, set otelextension.CreateSettings, cfg otelcomponent.Config) (otelextension.Extension, error) {
+			lcfg := cfg.(*limitConfig)
+			ext, err := inner.CreateExtension(ctx, set, lcfg.Config)
+			if err != nil {
+				return nil, err
+			}
+			m.storedBytes.Set(0)
+			return &limitExtension{
+				Extension: ext.(extstorage.Extension),
+				maxSize:   lcfg.MaxSize,
+				metrics:   m,
+			}, nil
+		},
+		inner.ExtensionStability(),
+	)
+}
+
+// limitExtension limits the bytes stored by the clients of a storage
+// extension.
+type limitExtension struct {
+	extstorage.Extension
+
+	maxSize int64
+	metrics *limitMetrics
+	stored  atomic.Int64 // Bytes stored by the open clients.
+}
+
+// GetClient implements extstorage.Extension.
+func (e *limitExtension) GetClient(ctx context.Context, kind otelcomponent.Kind, id otelcomponent.ID, storageName string) (extstorage.Client, error) {
+	client, err := e.Extension.GetClient(ctx, kind, id, storageName)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := client.Get(ctx, sizeKey)
+	if err != nil {
+		_ = client.Close(ctx)
+		return nil, fmt.Errorf("reading stored size: %w", err)
+	}
+	var size int64
+	if len(raw) == 8 {
+		size = int64(binary.BigEndian.Uint64(raw))
+	}
+	e.add(size)
+
+	return &limitClient{Client: client, ext: e, size: size}, nil
+}
+
+func (e *limitExtension) add(delta int64) {
+	e.metrics.storedBytes.Set(float64(e.stored.Add(delta)))
+}
+
+// limitClient tracks the bytes stored by a client and rejects writes of new
+// keys once the extension reached its limit.
+//
+// Writes which only replace or delete existing keys are never rejected, so
+// that the bookkeeping of users of the client, such as the read index of a
+// persistent queue, can always be updated.
+type limitClient struct {
+	extstorage.Client
+	ext *limitExtension
+
+	mut  sync.Mutex
+	size int64 // Bytes stored by the client.
+}
+
+// Set implements extstorage.Client.
+func (c *limitClient) Set(ctx context.Context, key string, value []byte) error {
+	return c.Batch(ctx, extstorage.SetOperation(key, value))
+}
+
+// Delete implements extstorage.Client.
+func (c *limitClient) Delete(ctx context.Context, key string) error {
+	return c.Batch(ctx, extstorage.DeleteOperation(key))
+}
+
+// Batch implements extstorage.Client.
+func (c *limitClient) Batch(ctx context.Context, ops ...extstorage.Operation) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	var (
+		delta   int64
+		newKeys bool
+		writes  bool
+
+		// Size of the keys written by previous operations of the batch, or -1
+		// if they were deleted, so that keys written more than once are only
+		// counted once.
+		written = make(map[string]int)
+	)
+	for _, op := range ops {
+		if op.Type == extstorage.Get {
+			continue
+		}
+		writes = true
+
+		// The size of the previous value is needed to know the size of the
+		// client after the write.
+		old, ok := written[op.Key]
+		if !ok {
+			value, err := c.Client.Get(ctx, op.Key)
+			if err != nil {
+				return err
+			}
+			old = len(value)
+			if value == nil {
+				old = -1
+			}
+		}
+		delta -= int64(max(old, 0))
+		written[op.Key] = -1
+		if op.Type == extstorage.Set {
+			delta += int64(len(op.Value))
+			newKeys = newKeys || old < 0
+			written[op.Key] = len(op.Value)
+		}
+	}
+	if !writes {
+		return c.Client.Batch(ctx, ops...)
+	}
+
+	// Data stored before the size was tracked isn't counted, so deleting it
+	// mustn't make the size negative.
+	size := max(c.size+delta, 0)
+	delta = size - c.size
+
+	stored := c.ext.stored.Add(delta)
+	if newKeys && delta > 0 && c.ext.maxSize > 0 && stored > c.ext.maxSize {
+		c.ext.stored.Add(-delta)
+		c.ext.metrics.rejectedTotal.Inc()
+		return errStorageFull
+	}
+
+	raw := binary.BigEndian.AppendUint64(nil, uint64(size))
+	if err := c.Client.Batch(ctx, append(ops, extstorage.SetOperation(sizeKey, raw))...); err != nil {
+		c.ext.stored.Add(-delta)
+		return err
+	}
+	c.size = size
+	c.ext.metrics.storedBytes.Set(float64(c.ext.stored.Load()))
+	return nil
+}
+
+// Close implements extstorage.Client.
+func (c *limitClient) Close(ctx context.Context) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.ext.add(-c.size)
+	c.size = 0
+	return c.Client.Close(ctx)
+}
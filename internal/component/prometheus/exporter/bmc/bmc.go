@@ -0,0 +1,142 @@
+// Package bmc implements the prometheus.exporter.bmc component, which
+// collects hardware telemetry from baseboard management controllers using
+// Redfish or IPMI.
+package bmc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/prometheus/exporter"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/static/integrations"
+	"github.com/grafana/river/rivertypes"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.bmc",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.NewWithTargetBuilder(createExporter, "bmc", buildBMCTargets),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	i, err := newIntegration(opts.Logger, a)
+	return i, defaultInstanceKey, err
+}
+
+// buildBMCTargets creates the exporter's discovery targets based on the
+// defined BMC targets.
+func buildBMCTargets(baseTarget discovery.Target, args component.Arguments) []discovery.Target {
+	var targets []discovery.Target
+
+	a := args.(Arguments)
+	for _, tgt := range a.Targets {
+		target := make(discovery.Target)
+		// Set extra labels first, meaning that any other labels will override
+		for k, v := range tgt.Labels {
+			target[k] = v
+		}
+		for k, v := range baseTarget {
+			target[k] = v
+		}
+
+		target["job"] = target["job"] + "/" + tgt.Name
+		target["__param_target"] = tgt.Name
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// Protocols which can be used to collect telemetry from a BMC.
+const (
+	ProtocolAuto    = "auto"
+	ProtocolRedfish = "redfish"
+	ProtocolIPMI    = "ipmi"
+)
+
+// DefaultArguments holds non-zero default options for Arguments when it is
+// unmarshaled from river.
+var DefaultArguments = Arguments{
+	Timeout:         10 * time.Second,
+	IPMISensorsPath: "ipmi-sensors",
+}
+
+// Arguments configures the prometheus.exporter.bmc component.
+type Arguments struct {
+	Targets         TargetBlock      `river:"target,block"`
+	Timeout         time.Duration    `river:"timeout,attr,optional"`
+	IPMISensorsPath string           `river:"ipmi_sensors_path,attr,optional"`
+	TLSConfig       config.TLSConfig `river:"tls_config,block,optional"`
+}
+
+// Target defines a BMC to collect telemetry from.
+type Target struct {
+	Name string `river:",label"`
+
+	// Address is the URL of the Redfish service of the BMC. The host of the
+	// URL is also used to connect to the BMC with IPMI.
+	Address  string            `river:"address,attr"`
+	Protocol string            `river:"protocol,attr,optional"`
+	Username string            `river:"username,attr,optional"`
+	Password rivertypes.Secret `river:"password,attr,optional"`
+	Labels   map[string]string `river:"labels,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (t *Target) SetToDefault() {
+	*t = Target{Protocol: ProtocolAuto}
+}
+
+// TargetBlock is a list of BMCs to collect telemetry from.
+type TargetBlock []Target
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Targets) == 0 {
+		return errors.New("at least one target block must be set")
+	}
+	if a.Timeout <= 0 {
+		return errors.New("timeout must be greater than 0")
+	}
+
+	names := make(map[string]struct{}, len(a.Targets))
+	for _, t := range a.Targets {
+		if _, ok := names[t.Name]; ok {
+			return fmt.Errorf("duplicate target name %q", t.Name)
+		}
+		names[t.Name] = struct{}{}
+
+		u, err := url.Parse(t.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target %q: address must be an http or https URL", t.Name)
+		}
+		switch t.Protocol {
+		case ProtocolAuto, ProtocolRedfish:
+		case ProtocolIPMI:
+			if a.IPMISensorsPath == "" {
+				return fmt.Errorf("target %q: ipmi_sensors_path must be set to use the %s protocol", t.Name, ProtocolIPMI)
+			}
+		default:
+			return fmt.Errorf("target %q: unsupported protocol %q: supported protocols are %s, %s and %s", t.Name, t.Protocol, ProtocolAuto, ProtocolRedfish, ProtocolIPMI)
+		}
+	}
+	return nil
+}
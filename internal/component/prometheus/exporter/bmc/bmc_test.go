@@ -0,0 +1,250 @@
+package bmc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	riverCfg := `
+		target "node_1" {
+			address  = "https://10.0.0.10"
+			username = "monitor"
+			password = "secret"
+			labels   = { "rack" = "a1" }
+		}
+		target "node_2" {
+			address  = "https://10.0.0.11"
+			protocol = "ipmi"
+		}
+
+		timeout = "20s"
+		tls_config {
+			insecure_skip_verify = true
+		}
+`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(riverCfg), &args))
+	require.Len(t, args.Targets, 2)
+	require.Equal(t, ProtocolAuto, args.Targets[0].Protocol)
+	require.Equal(t, "monitor", args.Targets[0].Username)
+	require.Equal(t, ProtocolIPMI, args.Targets[1].Protocol)
+	require.Equal(t, "ipmi-sensors", args.IPMISensorsPath)
+	require.True(t, args.TLSConfig.InsecureSkipVerify)
+
+	targets := buildBMCTargets(discovery.Target{"job": "integrations/bmc", "instance": "agent"}, args)
+	require.Equal(t, []discovery.Target{
+		{"job": "integrations/bmc/node_1", "instance": "agent", "rack": "a1", "__param_target": "node_1"},
+		{"job": "integrations/bmc/node_2", "instance": "agent", "__param_target": "node_2"},
+	}, targets)
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "no targets",
+			config: `timeout = "10s"`,
+			err:    `missing required block "target"`,
+		},
+		{
+			name: "duplicate target",
+			config: `
+				target "a" { address = "https://10.0.0.10" }
+				target "a" { address = "https://10.0.0.11" }`,
+			err: `duplicate target name "a"`,
+		},
+		{
+			name:   "invalid address",
+			config: `target "a" { address = "10.0.0.10" }`,
+			err:    `target "a": address must be an http or https URL`,
+		},
+		{
+			name: "invalid protocol",
+			config: `
+				target "a" {
+					address  = "https://10.0.0.10"
+					protocol = "snmp"
+				}`,
+			err: `target "a": unsupported protocol "snmp": supported protocols are auto, redfish and ipmi`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			require.ErrorContains(t, river.Unmarshal([]byte(tc.config), &args), tc.err)
+		})
+	}
+}
+
+// redfishResources are the resources served by the fake Redfish service.
+var redfishResources = map[string]string{
+	"/redfish/v1/": `{"Chassis": {"@odata.id": "/redfish/v1/Chassis"}}`,
+	"/redfish/v1/Chassis": `{"Members": [
+		{"@odata.id": "/redfish/v1/Chassis/1"},
+		{"@odata.id": "/redfish/v1/Chassis/missing"}
+	]}`,
+	"/redfish/v1/Chassis/1": `{
+		"Id": "1",
+		"Name": "Computer System Chassis",
+		"Manufacturer": "Contoso",
+		"Model": "3500",
+		"SerialNumber": "2M220100SL",
+		"Status": {"State": "Enabled", "Health": "Warning"},
+		"Thermal": {"@odata.id": "/redfish/v1/Chassis/1/Thermal"},
+		"Power": {"@odata.id": "/redfish/v1/Chassis/1/Power"}
+	}`,
+	"/redfish/v1/Chassis/1/Thermal": `{
+		"Temperatures": [
+			{"Name": "CPU1 Temp", "ReadingCelsius": 41, "Status": {"State": "Enabled", "Health": "OK"}},
+			{"Name": "CPU2 Temp", "ReadingCelsius": null, "Status": {"State": "Absent"}}
+		],
+		"Fans": [
+			{"FanName": "Fan 1", "Reading": 2100, "ReadingUnits": "RPM", "Status": {"State": "Enabled", "Health": "OK"}},
+			{"MemberId": "2", "Reading": 40, "ReadingUnits": "Percent", "Status": {"State": "Enabled", "Health": "Critical"}}
+		]
+	}`,
+	"/redfish/v1/Chassis/1/Power": `{
+		"PowerControl": [{"Name": "System Power Control", "PowerConsumedWatts": 344}],
+		"Voltages": [{"Name": "VRM1 Voltage", "ReadingVolts": 12.1, "Status": {"State": "Enabled", "Health": "OK"}}],
+		"PowerSupplies": [
+			{"Name": "Power Supply 1", "Status": {"State": "Enabled", "Health": "OK"}},
+			{"Name": "Power Supply 2", "Status": {"State": "Absent"}}
+		]
+	}`,
+}
+
+func newFakeRedfishService(t *testing.T) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "monitor" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, ok := redfishResources[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func scrape(t *testing.T, i *Integration, target string) string {
+	t.Helper()
+
+	h, err := i.MetricsHandler()
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?target="+target, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestCollect_Redfish(t *testing.T) {
+	srv := newFakeRedfishService(t)
+
+	args := DefaultArguments
+	args.TLSConfig.InsecureSkipVerify = true
+	args.Targets = TargetBlock{{
+		Name:     "node",
+		Address:  srv.URL,
+		Protocol: ProtocolRedfish,
+		Username: "monitor",
+		Password: "secret",
+	}}
+	i, err := newIntegration(util.TestLogger(t), args)
+	require.NoError(t, err)
+
+	body := scrape(t, i, "node")
+	for _, line := range []string{
+		`bmc_up{protocol="redfish"} 1`,
+		`bmc_collection_errors 1`,
+		`bmc_chassis_info{chassis="1",manufacturer="Contoso",model="3500",name="Computer System Chassis",serial_number="2M220100SL"} 1`,
+		`bmc_chassis_health{chassis="1"} 1`,
+		`bmc_temperature_celsius{chassis="1",sensor="CPU1 Temp"} 41`,
+		`bmc_fan_speed_rpm{chassis="1",sensor="Fan 1"} 2100`,
+		`bmc_fan_speed_percent{chassis="1",sensor="2"} 40`,
+		`bmc_power_watts{chassis="1",sensor="System Power Control"} 344`,
+		`bmc_voltage_volts{chassis="1",sensor="VRM1 Voltage"} 12.1`,
+		`bmc_sensor_health{chassis="1",sensor="2",type="fan"} 2`,
+		`bmc_sensor_health{chassis="1",sensor="CPU1 Temp",type="temperature"} 0`,
+		`bmc_sensor_health{chassis="1",sensor="Power Supply 1",type="power_supply"} 0`,
+	} {
+		require.Contains(t, body, line+"\n")
+	}
+	require.NotContains(t, body, "CPU2 Temp")
+	require.NotContains(t, body, "Power Supply 2")
+
+	// Wrong credentials fail the scrape.
+	i.targets["node"] = Target{Name: "node", Address: srv.URL, Protocol: ProtocolRedfish}
+	require.Contains(t, scrape(t, i, "node"), `bmc_up{protocol="redfish"} 0`+"\n")
+}
+
+func TestCollect_AutoFallback(t *testing.T) {
+	// The BMC doesn't provide Redfish.
+	var redfishRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redfishRequests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	args := DefaultArguments
+	args.Targets = TargetBlock{{
+		Name:     "node",
+		Address:  srv.URL,
+		Protocol: ProtocolAuto,
+		Username: "admin",
+		Password: "secret",
+	}}
+	i, err := newIntegration(util.TestLogger(t), args)
+	require.NoError(t, err)
+
+	var (
+		ipmiArgs []string
+		ipmiConf []byte
+		ipmiErr  error
+	)
+	i.ipmi = func(_ context.Context, path string, args ...string) ([]byte, error) {
+		require.Equal(t, "ipmi-sensors", path)
+		ipmiArgs = args
+		conf, err := os.ReadFile(args[1])
+		require.NoError(t, err)
+		ipmiConf = conf
+		return []byte("4,CPU Temp,Temperature,Nominal,38.00,C,'OK'\n"), ipmiErr
+	}
+
+	body := scrape(t, i, "node")
+	require.Contains(t, body, `bmc_up{protocol="ipmi"} 1`+"\n")
+	require.Contains(t, body, `bmc_temperature_celsius{chassis="",sensor="CPU Temp"} 38`+"\n")
+	require.Equal(t, "127.0.0.1", ipmiArgs[3])
+	require.Equal(t, "username admin\npassword secret\n", string(ipmiConf))
+	require.NoFileExists(t, ipmiArgs[1])
+	require.Equal(t, int32(1), redfishRequests.Load())
+
+	// The target keeps using IPMI, until it fails.
+	scrape(t, i, "node")
+	require.Equal(t, int32(1), redfishRequests.Load())
+
+	ipmiErr = errors.New("connection timeout")
+	require.Contains(t, scrape(t, i, "node"), `bmc_up{protocol="ipmi"} 0`+"\n")
+	scrape(t, i, "node")
+	require.Equal(t, int32(2), redfishRequests.Load())
+}
@@ -0,0 +1,307 @@
+package bmc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/egress"
+	"github.com/grafana/agent/internal/static/integrations/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	common_config "github.com/prometheus/common/config"
+)
+
+// Integration collects hardware telemetry from BMCs. All targets share the
+// same HTTP client, so connections to Redfish services are reused across
+// scrapes.
+type Integration struct {
+	log     log.Logger
+	args    Arguments
+	targets map[string]Target
+	client  *http.Client
+	ipmi    ipmiRunner
+
+	mut sync.Mutex
+	// detected holds the targets using the auto protocol which fell back to
+	// IPMI. They keep using IPMI until collecting with it fails.
+	detected map[string]string
+}
+
+func newIntegration(l log.Logger, args Arguments) (*Integration, error) {
+	client, err := common_config.NewClientFromConfig(
+		common_config.HTTPClientConfig{TLSConfig: *args.TLSConfig.Convert()},
+		"bmc",
+		egress.HTTPClientOption(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Integration{
+		log:      l,
+		args:     args,
+		targets:  make(map[string]Target, len(args.Targets)),
+		client:   client,
+		ipmi:     runIPMISensors,
+		detected: make(map[string]string),
+	}
+	for _, t := range args.Targets {
+		i.targets[t.Name] = t
+	}
+	return i, nil
+}
+
+// MetricsHandler implements Integration. The target to collect metrics from
+// is selected with the target query parameter.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("target")
+		t, ok := i.targets[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown target %q", name), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), i.args.Timeout)
+		defer cancel()
+
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(&collector{ctx: ctx, integration: i, target: t})
+		promhttp.HandlerFor(reg, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, r)
+	}), nil
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	var res []config.ScrapeConfig
+	for _, t := range i.args.Targets {
+		res = append(res, config.ScrapeConfig{
+			JobName:     "bmc/" + t.Name,
+			MetricsPath: "/metrics",
+			QueryParams: url.Values{"target": []string{t.Name}},
+		})
+	}
+	return res
+}
+
+// Run satisfies Integration.Run.
+func (i *Integration) Run(ctx context.Context) error {
+	<-ctx.Done()
+	i.client.CloseIdleConnections()
+	return ctx.Err()
+}
+
+// collect collects the telemetry of a target, and returns it along with the
+// protocol which was used.
+//
+// Targets using the auto protocol are collected with Redfish, unless their
+// BMC doesn't provide a Redfish service, in which case IPMI is used instead.
+// Half of the timeout is left to IPMI by limiting how long to wait for the
+// Redfish service.
+func (i *Integration) collect(ctx context.Context, t Target) (*telemetry, string, error) {
+	switch t.Protocol {
+	case ProtocolRedfish:
+		tel, err := i.collectRedfish(ctx, ctx, t)
+		return tel, ProtocolRedfish, err
+	case ProtocolIPMI:
+		tel, err := i.collectIPMI(ctx, t)
+		return tel, ProtocolIPMI, err
+	}
+
+	i.mut.Lock()
+	protocol := i.detected[t.Name]
+	i.mut.Unlock()
+
+	if protocol == ProtocolIPMI {
+		tel, err := i.collectIPMI(ctx, t)
+		if err != nil {
+			i.setDetected(t.Name, "")
+		}
+		return tel, ProtocolIPMI, err
+	}
+
+	rootCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		rootCtx, cancel = context.WithTimeout(ctx, time.Until(deadline)/2)
+		defer cancel()
+	}
+	tel, err := i.collectRedfish(ctx, rootCtx, t)
+	var unavailable *redfishUnavailableError
+	if !errors.As(err, &unavailable) {
+		return tel, ProtocolRedfish, err
+	}
+
+	level.Debug(i.log).Log("msg", "Redfish service unavailable, falling back to IPMI", "target", t.Name, "err", err)
+	tel, ipmiErr := i.collectIPMI(ctx, t)
+	if ipmiErr != nil {
+		return nil, ProtocolIPMI, fmt.Errorf("%w; ipmi: %w", err, ipmiErr)
+	}
+	i.setDetected(t.Name, ProtocolIPMI)
+	return tel, ProtocolIPMI, nil
+}
+
+func (i *Integration) setDetected(name, protocol string) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	if protocol == "" {
+		delete(i.detected, name)
+	} else {
+		i.detected[name] = protocol
+	}
+}
+
+var (
+	sensorLabels = []string{"chassis", "sensor"}
+
+	upDesc = prometheus.NewDesc(
+		"bmc_up",
+		"Whether telemetry could be collected from the BMC.",
+		[]string{"protocol"}, nil,
+	)
+	collectionErrorsDesc = prometheus.NewDesc(
+		"bmc_collection_errors",
+		"Number of resources which couldn't be read during the last scrape.",
+		nil, nil,
+	)
+	scrapeDurationDesc = prometheus.NewDesc(
+		"bmc_scrape_duration_seconds",
+		"Duration of the last scrape of the BMC.",
+		nil, nil,
+	)
+	chassisInfoDesc = prometheus.NewDesc(
+		"bmc_chassis_info",
+		"Information about a chassis.",
+		[]string{"chassis", "name", "manufacturer", "model", "serial_number"}, nil,
+	)
+	chassisHealthDesc = prometheus.NewDesc(
+		"bmc_chassis_health",
+		"Health of a chassis: 0 for OK, 1 for warning, 2 for critical.",
+		[]string{"chassis"}, nil,
+	)
+	sensorHealthDesc = prometheus.NewDesc(
+		"bmc_sensor_health",
+		"Health of a sensor or device: 0 for OK, 1 for warning, 2 for critical.",
+		append(sensorLabels, "type"), nil,
+	)
+	temperatureDesc = prometheus.NewDesc(
+		"bmc_temperature_celsius",
+		"Temperature reported by a sensor, in degrees Celsius.",
+		sensorLabels, nil,
+	)
+	fanSpeedRPMDesc = prometheus.NewDesc(
+		"bmc_fan_speed_rpm",
+		"Speed of a fan, in revolutions per minute.",
+		sensorLabels, nil,
+	)
+	fanSpeedPercentDesc = prometheus.NewDesc(
+		"bmc_fan_speed_percent",
+		"Speed of a fan, in percent of its maximum speed.",
+		sensorLabels, nil,
+	)
+	powerDesc = prometheus.NewDesc(
+		"bmc_power_watts",
+		"Power reported by a sensor, in watts.",
+		sensorLabels, nil,
+	)
+	voltageDesc = prometheus.NewDesc(
+		"bmc_voltage_volts",
+		"Voltage reported by a sensor, in volts.",
+		sensorLabels, nil,
+	)
+)
+
+// Sensor types of bmc_sensor_health.
+const (
+	sensorTemperature = "temperature"
+	sensorFan         = "fan"
+	sensorVoltage     = "voltage"
+	sensorPowerSupply = "power_supply"
+)
+
+// telemetry is the telemetry collected from a BMC. Samples which would
+// duplicate an existing series are dropped, since they would make the scrape
+// fail.
+type telemetry struct {
+	samples []sample
+	seen    map[string]struct{}
+
+	// errors is the number of resources which couldn't be read.
+	errors int
+}
+
+type sample struct {
+	desc        *prometheus.Desc
+	labelValues []string
+	value       float64
+}
+
+func newTelemetry() *telemetry {
+	return &telemetry{seen: make(map[string]struct{})}
+}
+
+func (t *telemetry) add(desc *prometheus.Desc, value float64, labelValues ...string) {
+	key := fmt.Sprintf("%p\xff%s", desc, strings.Join(labelValues, "\xff"))
+	if _, ok := t.seen[key]; ok {
+		return
+	}
+	t.seen[key] = struct{}{}
+	t.samples = append(t.samples, sample{desc: desc, labelValues: labelValues, value: value})
+}
+
+// addHealth adds a health sample if health is a known health state.
+func (t *telemetry) addHealth(desc *prometheus.Desc, health string, labelValues ...string) {
+	var value float64
+	switch strings.ToLower(health) {
+	case "ok", "nominal":
+		value = 0
+	case "warning":
+		value = 1
+	case "critical":
+		value = 2
+	default:
+		return
+	}
+	t.add(desc, value, labelValues...)
+}
+
+// collector collects the metrics of a single target. A new collector is
+// created for every scrape.
+type collector struct {
+	ctx         context.Context
+	integration *Integration
+	target      Target
+}
+
+// Describe implements prometheus.Collector. The collector is unchecked, as
+// the sensors it collects depend on the hardware of the target.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+	defer func() {
+		ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
+	}()
+
+	tel, protocol, err := c.integration.collect(c.ctx, c.target)
+	if err != nil {
+		level.Warn(c.integration.log).Log("msg", "failed to collect BMC telemetry", "target", c.target.Name, "protocol", protocol, "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0, protocol)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1, protocol)
+	ch <- prometheus.MustNewConstMetric(collectionErrorsDesc, prometheus.GaugeValue, float64(tel.errors))
+
+	for _, s := range tel.samples {
+		ch <- prometheus.MustNewConstMetric(s.desc, prometheus.GaugeValue, s.value, s.labelValues...)
+	}
+}
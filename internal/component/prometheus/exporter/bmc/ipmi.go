@@ -0,0 +1,138 @@
+package bmc
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ipmiRunner runs ipmi-sensors with args and returns its output.
+type ipmiRunner func(ctx context.Context, path string, args ...string) ([]byte, error)
+
+func runIPMISensors(ctx context.Context, path string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// collectIPMI collects the sensors of a target with the ipmi-sensors command
+// of FreeIPMI, connecting to the host of the address of the target with IPMI
+// over LAN. The credentials are passed in a temporary configuration file, so
+// they don't show up in the arguments of the process.
+//
+// IPMI doesn't group sensors by chassis, so the chassis label of the sensors
+// is empty.
+func (i *Integration) collectIPMI(ctx context.Context, t Target) (*telemetry, error) {
+	u, err := url.Parse(t.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "agent-bmc-ipmi-*.conf")
+	if err != nil {
+		return nil, fmt.Errorf("creating FreeIPMI configuration file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	var conf strings.Builder
+	if t.Username != "" {
+		fmt.Fprintf(&conf, "username %s\n", t.Username)
+	}
+	if t.Password != "" {
+		fmt.Fprintf(&conf, "password %s\n", t.Password)
+	}
+	_, err = f.WriteString(conf.String())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("writing FreeIPMI configuration file: %w", err)
+	}
+
+	out, err := i.ipmi(ctx, i.args.IPMISensorsPath,
+		"--config-file", f.Name(),
+		"--hostname", u.Hostname(),
+		"--driver-type", "LAN_2_0",
+		"--quiet-cache",
+		"--sdr-cache-recreate",
+		"--comma-separated-output",
+		"--no-header-output",
+		"--output-sensor-state",
+		"--ignore-not-available-sensors",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("running ipmi-sensors: %w", err)
+	}
+	return parseIPMISensors(out)
+}
+
+// parseIPMISensors parses the comma-separated output of ipmi-sensors, whose
+// columns are the ID, name, type, state, reading, units, and event of the
+// sensors.
+func parseIPMISensors(out []byte) (*telemetry, error) {
+	r := csv.NewReader(bytes.NewReader(out))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	tel := newTelemetry()
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing ipmi-sensors output: %w", err)
+		}
+		if len(record) < 6 {
+			return nil, fmt.Errorf("parsing ipmi-sensors output: expected at least 6 columns, got %d", len(record))
+		}
+		var (
+			name  = record[1]
+			typ   = record[2]
+			state = record[3]
+			units = record[5]
+		)
+
+		if value, err := strconv.ParseFloat(record[4], 64); err == nil {
+			switch units {
+			case "C":
+				tel.add(temperatureDesc, value, "", name)
+			case "F":
+				tel.add(temperatureDesc, (value-32)*5/9, "", name)
+			case "RPM":
+				tel.add(fanSpeedRPMDesc, value, "", name)
+			case "%":
+				if typ == "Fan" {
+					tel.add(fanSpeedPercentDesc, value, "", name)
+				}
+			case "W":
+				tel.add(powerDesc, value, "", name)
+			case "V":
+				tel.add(voltageDesc, value, "", name)
+			}
+		}
+		tel.addHealth(sensorHealthDesc, state, "", name, ipmiSensorType(typ))
+	}
+	return tel, nil
+}
+
+// ipmiSensorType converts the type of an IPMI sensor, such as Power Supply,
+// to a sensor type of bmc_sensor_health, such as power_supply.
+func ipmiSensorType(typ string) string {
+	return strings.ReplaceAll(strings.ToLower(typ), " ", "_")
+}
@@ -0,0 +1,55 @@
+package bmc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/agent/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect_IPMI(t *testing.T) {
+	args := DefaultArguments
+	args.IPMISensorsPath = "/usr/sbin/ipmi-sensors"
+	args.Targets = TargetBlock{{Name: "node", Address: "https://bmc-1:8443", Protocol: ProtocolIPMI}}
+	i, err := newIntegration(util.TestLogger(t), args)
+	require.NoError(t, err)
+
+	var ipmiArgs []string
+	i.ipmi = func(_ context.Context, path string, args ...string) ([]byte, error) {
+		require.Equal(t, "/usr/sbin/ipmi-sensors", path)
+		ipmiArgs = args
+		return []byte(`4,CPU Temp,Temperature,Nominal,38.00,C,'OK'
+5,Inlet Temp,Temperature,Warning,104.00,F,'Upper Non-critical - going high'
+30,Fan1,Fan,Nominal,5400.00,RPM,'OK'
+31,Fan2,Fan,Critical,12.00,%,'Lower Critical - going low'
+40,Pwr Consumption,Current,Nominal,210.00,W,'OK'
+50,12V,Voltage,Nominal,12.06,V,'OK'
+60,PS1 Status,Power Supply,Nominal,N/A,N/A,'Presence detected'
+70,Chassis Intru,Physical Security,N/A,N/A,N/A,N/A
+`), nil
+	}
+
+	body := scrape(t, i, "node")
+	require.Equal(t, "bmc-1", ipmiArgs[3])
+	for _, line := range []string{
+		`bmc_up{protocol="ipmi"} 1`,
+		`bmc_temperature_celsius{chassis="",sensor="CPU Temp"} 38`,
+		`bmc_temperature_celsius{chassis="",sensor="Inlet Temp"} 40`,
+		`bmc_fan_speed_rpm{chassis="",sensor="Fan1"} 5400`,
+		`bmc_fan_speed_percent{chassis="",sensor="Fan2"} 12`,
+		`bmc_power_watts{chassis="",sensor="Pwr Consumption"} 210`,
+		`bmc_voltage_volts{chassis="",sensor="12V"} 12.06`,
+		`bmc_sensor_health{chassis="",sensor="Inlet Temp",type="temperature"} 1`,
+		`bmc_sensor_health{chassis="",sensor="Fan2",type="fan"} 2`,
+		`bmc_sensor_health{chassis="",sensor="PS1 Status",type="power_supply"} 0`,
+	} {
+		require.Contains(t, body, line+"\n")
+	}
+	require.NotContains(t, body, "Chassis Intru")
+}
+
+func TestParseIPMISensors_Invalid(t *testing.T) {
+	_, err := parseIPMISensors([]byte("4,CPU Temp,Temperature\n"))
+	require.EqualError(t, err, "parsing ipmi-sensors output: expected at least 6 columns, got 3")
+}
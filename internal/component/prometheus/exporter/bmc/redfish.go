@@ -0,0 +1,257 @@
+package bmc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+// redfishServiceRoot is the path of the service root of Redfish services.
+const redfishServiceRoot = "/redfish/v1/"
+
+// redfishUnavailableError is returned when the Redfish service root of a BMC
+// can't be read, meaning that the BMC likely doesn't provide Redfish.
+type redfishUnavailableError struct {
+	err error
+}
+
+func (e *redfishUnavailableError) Error() string {
+	return fmt.Sprintf("redfish service unavailable: %s", e.err)
+}
+
+func (e *redfishUnavailableError) Unwrap() error { return e.err }
+
+// The following types hold the subset of Redfish resources read by the
+// component.
+
+type odataRef struct {
+	ID string `json:"@odata.id"`
+}
+
+type redfishStatus struct {
+	State  string `json:"State"`
+	Health string `json:"Health"`
+}
+
+// absent returns true if the resource isn't installed.
+func (s redfishStatus) absent() bool { return s.State == "Absent" }
+
+type redfishRoot struct {
+	Chassis odataRef `json:"Chassis"`
+}
+
+type redfishCollection struct {
+	Members []odataRef `json:"Members"`
+}
+
+type redfishChassis struct {
+	ID           string        `json:"Id"`
+	Name         string        `json:"Name"`
+	Manufacturer string        `json:"Manufacturer"`
+	Model        string        `json:"Model"`
+	SerialNumber string        `json:"SerialNumber"`
+	Status       redfishStatus `json:"Status"`
+	Thermal      odataRef      `json:"Thermal"`
+	Power        odataRef      `json:"Power"`
+}
+
+type redfishThermal struct {
+	Temperatures []struct {
+		Name           string        `json:"Name"`
+		MemberID       string        `json:"MemberId"`
+		ReadingCelsius *float64      `json:"ReadingCelsius"`
+		Status         redfishStatus `json:"Status"`
+	} `json:"Temperatures"`
+	Fans []struct {
+		Name         string        `json:"Name"`
+		FanName      string        `json:"FanName"`
+		MemberID     string        `json:"MemberId"`
+		Reading      *float64      `json:"Reading"`
+		ReadingUnits string        `json:"ReadingUnits"`
+		Status       redfishStatus `json:"Status"`
+	} `json:"Fans"`
+}
+
+type redfishPower struct {
+	PowerControl []struct {
+		Name               string   `json:"Name"`
+		MemberID           string   `json:"MemberId"`
+		PowerConsumedWatts *float64 `json:"PowerConsumedWatts"`
+	} `json:"PowerControl"`
+	Voltages []struct {
+		Name         string        `json:"Name"`
+		MemberID     string        `json:"MemberId"`
+		ReadingVolts *float64      `json:"ReadingVolts"`
+		Status       redfishStatus `json:"Status"`
+	} `json:"Voltages"`
+	PowerSupplies []struct {
+		Name     string        `json:"Name"`
+		MemberID string        `json:"MemberId"`
+		Status   redfishStatus `json:"Status"`
+	} `json:"PowerSupplies"`
+}
+
+// collectRedfish collects the telemetry of the chassis of a target from its
+// Redfish service. The service root is read with rootCtx, and the other
+// resources with ctx.
+//
+// A chassis whose thermal or power resources can't be read is counted as a
+// collection error, and the other chassis are still collected.
+func (i *Integration) collectRedfish(ctx, rootCtx context.Context, t Target) (*telemetry, error) {
+	base, err := url.Parse(t.Address)
+	if err != nil {
+		return nil, err
+	}
+	get := func(ctx context.Context, path string, v interface{}) error {
+		return i.getRedfish(ctx, base, t, path, v)
+	}
+
+	var root redfishRoot
+	if err := get(rootCtx, redfishServiceRoot, &root); err != nil {
+		return nil, &redfishUnavailableError{err: err}
+	}
+	if root.Chassis.ID == "" {
+		return nil, fmt.Errorf("redfish service doesn't provide chassis")
+	}
+	var chassisCollection redfishCollection
+	if err := get(ctx, root.Chassis.ID, &chassisCollection); err != nil {
+		return nil, err
+	}
+
+	tel := newTelemetry()
+	for _, member := range chassisCollection.Members {
+		var ch redfishChassis
+		if err := get(ctx, member.ID, &ch); err != nil {
+			level.Debug(i.log).Log("msg", "failed to read chassis", "target", t.Name, "chassis", member.ID, "err", err)
+			tel.errors++
+			continue
+		}
+		tel.add(chassisInfoDesc, 1, ch.ID, ch.Name, ch.Manufacturer, ch.Model, ch.SerialNumber)
+		tel.addHealth(chassisHealthDesc, ch.Status.Health, ch.ID)
+
+		if ch.Thermal.ID != "" {
+			var thermal redfishThermal
+			if err := get(ctx, ch.Thermal.ID, &thermal); err != nil {
+				level.Debug(i.log).Log("msg", "failed to read chassis thermal", "target", t.Name, "chassis", ch.ID, "err", err)
+				tel.errors++
+			} else {
+				addRedfishThermal(tel, ch.ID, thermal)
+			}
+		}
+		if ch.Power.ID != "" {
+			var power redfishPower
+			if err := get(ctx, ch.Power.ID, &power); err != nil {
+				level.Debug(i.log).Log("msg", "failed to read chassis power", "target", t.Name, "chassis", ch.ID, "err", err)
+				tel.errors++
+			} else {
+				addRedfishPower(tel, ch.ID, power)
+			}
+		}
+	}
+	return tel, nil
+}
+
+func addRedfishThermal(tel *telemetry, chassis string, thermal redfishThermal) {
+	for _, temp := range thermal.Temperatures {
+		if temp.Status.absent() {
+			continue
+		}
+		sensor := sensorName(temp.Name, temp.MemberID)
+		if temp.ReadingCelsius != nil {
+			tel.add(temperatureDesc, *temp.ReadingCelsius, chassis, sensor)
+		}
+		tel.addHealth(sensorHealthDesc, temp.Status.Health, chassis, sensor, sensorTemperature)
+	}
+	for _, fan := range thermal.Fans {
+		if fan.Status.absent() {
+			continue
+		}
+		// Fans are named with FanName before version 1.1.0 of the Thermal
+		// schema.
+		name := fan.Name
+		if name == "" {
+			name = fan.FanName
+		}
+		sensor := sensorName(name, fan.MemberID)
+		if fan.Reading != nil {
+			switch fan.ReadingUnits {
+			case "RPM":
+				tel.add(fanSpeedRPMDesc, *fan.Reading, chassis, sensor)
+			case "Percent":
+				tel.add(fanSpeedPercentDesc, *fan.Reading, chassis, sensor)
+			}
+		}
+		tel.addHealth(sensorHealthDesc, fan.Status.Health, chassis, sensor, sensorFan)
+	}
+}
+
+func addRedfishPower(tel *telemetry, chassis string, power redfishPower) {
+	for _, pc := range power.PowerControl {
+		if pc.PowerConsumedWatts != nil {
+			tel.add(powerDesc, *pc.PowerConsumedWatts, chassis, sensorName(pc.Name, pc.MemberID))
+		}
+	}
+	for _, v := range power.Voltages {
+		if v.Status.absent() {
+			continue
+		}
+		sensor := sensorName(v.Name, v.MemberID)
+		if v.ReadingVolts != nil {
+			tel.add(voltageDesc, *v.ReadingVolts, chassis, sensor)
+		}
+		tel.addHealth(sensorHealthDesc, v.Status.Health, chassis, sensor, sensorVoltage)
+	}
+	for _, ps := range power.PowerSupplies {
+		if ps.Status.absent() {
+			continue
+		}
+		tel.addHealth(sensorHealthDesc, ps.Status.Health, chassis, sensorName(ps.Name, ps.MemberID), sensorPowerSupply)
+	}
+}
+
+// sensorName returns the name of a sensor, or its member ID if it doesn't
+// have a name.
+func sensorName(name, memberID string) string {
+	if name != "" {
+		return name
+	}
+	return memberID
+}
+
+// getRedfish reads the Redfish resource at path into v, authenticating with
+// the credentials of the target.
+func (i *Integration) getRedfish(ctx context.Context, base *url.URL, t Target, path string, v interface{}) error {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return err
+	}
+	u := base.ResolveReference(ref)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("OData-Version", "4.0")
+	if t.Username != "" {
+		req.SetBasicAuth(t.Username, string(t.Password))
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("reading %s: unexpected status %s", u.Path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("reading %s: %w", u.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cgroup
+
+import (
+	"errors"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/prometheus/exporter"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/static/integrations"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.cgroup",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "cgroup"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	c := newCollector(opts.Logger, a)
+	return integrations.NewCollectorIntegration("cgroup", integrations.WithCollectors(c)), defaultInstanceKey, nil
+}
+
+// DefaultArguments holds non-zero default options for Arguments when it is
+// unmarshaled from river.
+var DefaultArguments = Arguments{
+	CgroupRoot:     "/sys/fs/cgroup",
+	ProcRoot:       "/proc",
+	NetworkMetrics: true,
+}
+
+// Arguments configures the prometheus.exporter.cgroup component.
+type Arguments struct {
+	CgroupRoot     string             `river:"cgroup_root,attr,optional"`
+	ProcRoot       string             `river:"proc_root,attr,optional"`
+	Pods           []discovery.Target `river:"pods,attr,optional"`
+	NetworkMetrics bool               `river:"network_metrics,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.CgroupRoot == "" {
+		return errors.New("cgroup_root must not be empty")
+	}
+	if a.ProcRoot == "" {
+		return errors.New("proc_root must not be empty")
+	}
+	return nil
+}
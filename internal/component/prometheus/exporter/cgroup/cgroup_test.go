@@ -0,0 +1,169 @@
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	appID     = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	sandboxID = "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	podUID    = "1b2c3d4e-5f60-7182-93a4-b5c6d7e8f901"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+// withLabel adds a label to a comma-separated list of labels, keeping them
+// sorted by name.
+func withLabel(labels, name, value string) string {
+	list := append(strings.Split(labels, ","), name+`="`+value+`"`)
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func newTestCollector(t *testing.T, cgroupRoot, procRoot string) *prometheus.Registry {
+	args := DefaultArguments
+	args.CgroupRoot = cgroupRoot
+	args.ProcRoot = procRoot
+	args.Pods = []discovery.Target{{
+		"__meta_kubernetes_namespace":           "default",
+		"__meta_kubernetes_pod_name":            "web",
+		"__meta_kubernetes_pod_uid":             podUID,
+		"__meta_kubernetes_pod_container_name":  "app",
+		"__meta_kubernetes_pod_container_id":    "containerd://" + appID,
+		"__meta_kubernetes_pod_container_image": "nginx:1.25",
+	}}
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(newCollector(util.TestLogger(t), args)))
+	return reg
+}
+
+func TestCollectV2(t *testing.T) {
+	var (
+		cgroupRoot = t.TempDir()
+		procRoot   = t.TempDir()
+		podDir     = "kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod" + strings.ReplaceAll(podUID, "-", "_") + ".slice"
+		appDir     = podDir + "/cri-containerd-" + appID + ".scope"
+		sandboxDir = podDir + "/cri-containerd-" + sandboxID + ".scope"
+	)
+	writeFiles(t, cgroupRoot, map[string]string{
+		"cgroup.controllers":                 "cpu io memory",
+		appDir + "/cpu.stat":                 "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\nnr_periods 10\nnr_throttled 2\nthrottled_usec 250000\n",
+		appDir + "/memory.current":           "1048576\n",
+		appDir + "/memory.max":               "2097152\n",
+		appDir + "/memory.stat":              "anon 524288\nfile 262144\ninactive_file 131072\n",
+		appDir + "/io.stat":                  "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n",
+		appDir + "/cgroup.procs":             "42\n",
+		sandboxDir + "/cpu.stat":             "usage_usec 1000\nuser_usec 0\nsystem_usec 1000\nnr_throttled 0\nthrottled_usec 0\n",
+		sandboxDir + "/memory.current":       "4096\n",
+		sandboxDir + "/memory.max":           "max\n",
+		"system.slice/cron.service/cpu.stat": "usage_usec 1\n",
+	})
+	writeFiles(t, procRoot, map[string]string{
+		"partitions": "major minor  #blocks  name\n\n   8        0  488386584 sda\n",
+		"42/net/dev": strings.Join([]string{
+			"Inter-|   Receive                                                |  Transmit",
+			" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed",
+			"    lo:     100       1    0    0    0     0          0         0      100       1    0    0    0     0       0          0",
+			"  eth0:    2048      16    1    0    0     0          0         0     1024       8    0    0    0     0       0          0",
+		}, "\n"),
+	})
+
+	reg := newTestCollector(t, cgroupRoot, procRoot)
+
+	appLabels := `container="app",container_id="` + appID + `",id="/` + appDir + `",image="nginx:1.25",namespace="default",pod="web"`
+	sandboxLabels := `container="",container_id="` + sandboxID + `",id="/` + sandboxDir + `",image="",namespace="default",pod="web"`
+	expect := `
+# HELP container_cpu_usage_seconds_total Cumulative CPU time consumed by the container.
+# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{` + appLabels + `} 1.5
+container_cpu_usage_seconds_total{` + sandboxLabels + `} 0.001
+# HELP container_cpu_cfs_throttled_seconds_total Total time duration the container has been throttled.
+# TYPE container_cpu_cfs_throttled_seconds_total counter
+container_cpu_cfs_throttled_seconds_total{` + appLabels + `} 0.25
+container_cpu_cfs_throttled_seconds_total{` + sandboxLabels + `} 0
+# HELP container_memory_working_set_bytes Current working set of the container.
+# TYPE container_memory_working_set_bytes gauge
+container_memory_working_set_bytes{` + appLabels + `} 917504
+container_memory_working_set_bytes{` + sandboxLabels + `} 4096
+# HELP container_spec_memory_limit_bytes Memory limit of the container.
+# TYPE container_spec_memory_limit_bytes gauge
+container_spec_memory_limit_bytes{` + appLabels + `} 2.097152e+06
+container_spec_memory_limit_bytes{` + sandboxLabels + `} 0
+# HELP container_fs_writes_bytes_total Cumulative count of bytes written by the container.
+# TYPE container_fs_writes_bytes_total counter
+container_fs_writes_bytes_total{` + withLabel(appLabels, "device", "/dev/sda") + `} 8192
+# HELP container_network_receive_bytes_total Cumulative count of bytes received.
+# TYPE container_network_receive_bytes_total counter
+container_network_receive_bytes_total{` + withLabel(appLabels, "interface", "eth0") + `} 2048
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect),
+		"container_cpu_usage_seconds_total",
+		"container_cpu_cfs_throttled_seconds_total",
+		"container_memory_working_set_bytes",
+		"container_spec_memory_limit_bytes",
+		"container_fs_writes_bytes_total",
+		"container_network_receive_bytes_total",
+	))
+}
+
+func TestCollectV1(t *testing.T) {
+	var (
+		cgroupRoot = t.TempDir()
+		appDir     = "kubepods/burstable/pod" + podUID + "/" + appID
+	)
+	writeFiles(t, cgroupRoot, map[string]string{
+		"cpuacct/" + appDir + "/cpuacct.usage":                 "2000000000\n",
+		"cpuacct/" + appDir + "/cpuacct.stat":                  "user 150\nsystem 50\n",
+		"cpu/" + appDir + "/cpu.stat":                          "nr_periods 10\nnr_throttled 3\nthrottled_time 500000000\n",
+		"memory/" + appDir + "/memory.usage_in_bytes":          "1048576\n",
+		"memory/" + appDir + "/memory.limit_in_bytes":          "9223372036854771712\n",
+		"memory/" + appDir + "/memory.stat":                    "total_rss 524288\ntotal_cache 262144\ntotal_inactive_file 65536\n",
+		"blkio/" + appDir + "/blkio.throttle.io_service_bytes": "8:0 Read 4096\n8:0 Write 8192\nTotal 12288\n",
+		"blkio/" + appDir + "/blkio.throttle.io_serviced":      "8:0 Read 1\n8:0 Write 2\nTotal 3\n",
+	})
+
+	reg := newTestCollector(t, cgroupRoot, t.TempDir())
+
+	labels := `container="app",container_id="` + appID + `",id="/` + appDir + `",image="nginx:1.25",namespace="default",pod="web"`
+	expect := `
+# HELP container_cpu_user_seconds_total Cumulative user CPU time consumed by the container.
+# TYPE container_cpu_user_seconds_total counter
+container_cpu_user_seconds_total{` + labels + `} 1.5
+# HELP container_cpu_cfs_throttled_periods_total Number of throttled CPU period intervals.
+# TYPE container_cpu_cfs_throttled_periods_total counter
+container_cpu_cfs_throttled_periods_total{` + labels + `} 3
+# HELP container_memory_working_set_bytes Current working set of the container.
+# TYPE container_memory_working_set_bytes gauge
+container_memory_working_set_bytes{` + labels + `} 983040
+# HELP container_spec_memory_limit_bytes Memory limit of the container.
+# TYPE container_spec_memory_limit_bytes gauge
+container_spec_memory_limit_bytes{` + labels + `} 0
+# HELP container_fs_reads_total Cumulative count of reads completed by the container.
+# TYPE container_fs_reads_total counter
+container_fs_reads_total{` + withLabel(labels, "device", "8:0") + `} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect),
+		"container_cpu_user_seconds_total",
+		"container_cpu_cfs_throttled_periods_total",
+		"container_memory_working_set_bytes",
+		"container_spec_memory_limit_bytes",
+		"container_fs_reads_total",
+	))
+}
@@ -0,0 +1,207 @@
+package cgroup
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// containerIDRegexp matches the cgroup directories of containers created
+	// by Docker, containerd, CRI-O and Podman, with either the systemd or the
+	// cgroupfs cgroup driver.
+	containerIDRegexp = regexp.MustCompile(`^(?:(?:docker|cri-containerd|crio|libpod)-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+	// podUIDRegexp matches the cgroup directories of Kubernetes pods. The
+	// systemd cgroup driver replaces the dashes of pod UIDs with underscores.
+	podUIDRegexp = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})(?:\.slice)?$`)
+)
+
+// Labels of Kubernetes pod targets which are used as metadata.
+const (
+	namespaceLabel      = "__meta_kubernetes_namespace"
+	podNameLabel        = "__meta_kubernetes_pod_name"
+	podUIDLabel         = "__meta_kubernetes_pod_uid"
+	containerNameLabel  = "__meta_kubernetes_pod_container_name"
+	containerIDLabel    = "__meta_kubernetes_pod_container_id"
+	containerImageLabel = "__meta_kubernetes_pod_container_image"
+)
+
+// metadata holds the Kubernetes metadata of a container.
+type metadata struct {
+	namespace, pod, container, image string
+}
+
+// collector collects the resource usage of containers from cgroups.
+type collector struct {
+	log            log.Logger
+	cgroupRoot     string
+	procRoot       string
+	networkMetrics bool
+
+	// Metadata of containers keyed by container ID, and of pods keyed by pod
+	// UID. Pod metadata is used for containers which aren't known, such as
+	// sandbox containers.
+	containers map[string]metadata
+	pods       map[string]metadata
+}
+
+func newCollector(l log.Logger, args Arguments) *collector {
+	c := &collector{
+		log:            l,
+		cgroupRoot:     args.CgroupRoot,
+		procRoot:       args.ProcRoot,
+		networkMetrics: args.NetworkMetrics,
+		containers:     make(map[string]metadata),
+		pods:           make(map[string]metadata),
+	}
+	for _, t := range args.Pods {
+		pod := metadata{namespace: t[namespaceLabel], pod: t[podNameLabel]}
+		if uid := t[podUIDLabel]; uid != "" {
+			c.pods[uid] = pod
+		}
+		// Container IDs are prefixed with the runtime, such as
+		// containerd://<id>.
+		if _, id, ok := strings.Cut(t[containerIDLabel], "://"); ok {
+			pod.container = t[containerNameLabel]
+			pod.image = t[containerImageLabel]
+			c.containers[id] = pod
+		}
+	}
+	return c
+}
+
+var (
+	containerLabels = []string{"id", "container_id", "namespace", "pod", "container", "image"}
+
+	cpuUsageDesc         = newDesc("container_cpu_usage_seconds_total", "Cumulative CPU time consumed by the container.")
+	cpuUserDesc          = newDesc("container_cpu_user_seconds_total", "Cumulative user CPU time consumed by the container.")
+	cpuSystemDesc        = newDesc("container_cpu_system_seconds_total", "Cumulative system CPU time consumed by the container.")
+	throttledPeriodsDesc = newDesc("container_cpu_cfs_throttled_periods_total", "Number of throttled CPU period intervals.")
+	throttledTimeDesc    = newDesc("container_cpu_cfs_throttled_seconds_total", "Total time duration the container has been throttled.")
+
+	memUsageDesc      = newDesc("container_memory_usage_bytes", "Current memory usage of the container, including the page cache.")
+	memWorkingSetDesc = newDesc("container_memory_working_set_bytes", "Current working set of the container.")
+	memRSSDesc        = newDesc("container_memory_rss", "Size of the anonymous memory of the container.")
+	memCacheDesc      = newDesc("container_memory_cache", "Size of the page cache of the container.")
+	memLimitDesc      = newDesc("container_spec_memory_limit_bytes", "Memory limit of the container.")
+
+	fsReadBytesDesc  = newDesc("container_fs_reads_bytes_total", "Cumulative count of bytes read by the container.", "device")
+	fsWriteBytesDesc = newDesc("container_fs_writes_bytes_total", "Cumulative count of bytes written by the container.", "device")
+	fsReadsDesc      = newDesc("container_fs_reads_total", "Cumulative count of reads completed by the container.", "device")
+	fsWritesDesc     = newDesc("container_fs_writes_total", "Cumulative count of writes completed by the container.", "device")
+
+	netRxBytesDesc   = newDesc("container_network_receive_bytes_total", "Cumulative count of bytes received.", "interface")
+	netRxPacketsDesc = newDesc("container_network_receive_packets_total", "Cumulative count of packets received.", "interface")
+	netRxErrorsDesc  = newDesc("container_network_receive_errors_total", "Cumulative count of errors encountered while receiving.", "interface")
+	netTxBytesDesc   = newDesc("container_network_transmit_bytes_total", "Cumulative count of bytes transmitted.", "interface")
+	netTxPacketsDesc = newDesc("container_network_transmit_packets_total", "Cumulative count of packets transmitted.", "interface")
+	netTxErrorsDesc  = newDesc("container_network_transmit_errors_total", "Cumulative count of errors encountered while transmitting.", "interface")
+)
+
+func newDesc(name, help string, extraLabels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(name, help, append(append([]string{}, containerLabels...), extraLabels...), nil)
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		cpuUsageDesc, cpuUserDesc, cpuSystemDesc, throttledPeriodsDesc, throttledTimeDesc,
+		memUsageDesc, memWorkingSetDesc, memRSSDesc, memCacheDesc, memLimitDesc,
+		fsReadBytesDesc, fsWriteBytesDesc, fsReadsDesc, fsWritesDesc,
+		netRxBytesDesc, netRxPacketsDesc, netRxErrorsDesc, netTxBytesDesc, netTxPacketsDesc, netTxErrorsDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	h := newHierarchy(c.cgroupRoot)
+	root := h.walkRoot()
+	devices := readDevices(filepath.Join(c.procRoot, "partitions"))
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Cgroups are removed when containers exit, which may happen while
+			// walking the hierarchy.
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		m := containerIDRegexp.FindStringSubmatch(d.Name())
+		if m == nil {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		c.collectContainer(ch, h, "/"+filepath.ToSlash(rel), m[1], devices)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to read cgroups", "root", root, "err", err)
+	}
+}
+
+func (c *collector) collectContainer(ch chan<- prometheus.Metric, h hierarchy, path, containerID string, devices map[string]string) {
+	md, ok := c.containers[containerID]
+	if !ok {
+		for _, dir := range strings.Split(path, "/") {
+			if m := podUIDRegexp.FindStringSubmatch(dir); m != nil {
+				md = c.pods[strings.ReplaceAll(m[1], "_", "-")]
+			}
+		}
+	}
+	labels := []string{path, containerID, md.namespace, md.pod, md.container, md.image}
+
+	emit := func(desc *prometheus.Desc, valueType prometheus.ValueType, v float64, extraLabels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, valueType, v, append(append([]string{}, labels...), extraLabels...)...)
+	}
+
+	s := h.read(path)
+	if s.hasCPU {
+		emit(cpuUsageDesc, prometheus.CounterValue, s.cpuUsage)
+		emit(cpuUserDesc, prometheus.CounterValue, s.cpuUser)
+		emit(cpuSystemDesc, prometheus.CounterValue, s.cpuSystem)
+		emit(throttledPeriodsDesc, prometheus.CounterValue, s.throttledPeriods)
+		emit(throttledTimeDesc, prometheus.CounterValue, s.throttledTime)
+	}
+	if s.hasMemory {
+		emit(memUsageDesc, prometheus.GaugeValue, s.memUsage)
+		emit(memWorkingSetDesc, prometheus.GaugeValue, s.memWorkingSet)
+		emit(memRSSDesc, prometheus.GaugeValue, s.memRSS)
+		emit(memCacheDesc, prometheus.GaugeValue, s.memCache)
+		emit(memLimitDesc, prometheus.GaugeValue, s.memLimit)
+	}
+	for dev, st := range s.io {
+		if name, ok := devices[dev]; ok {
+			dev = name
+		}
+		emit(fsReadBytesDesc, prometheus.CounterValue, st.readBytes, dev)
+		emit(fsWriteBytesDesc, prometheus.CounterValue, st.writeBytes, dev)
+		emit(fsReadsDesc, prometheus.CounterValue, st.reads, dev)
+		emit(fsWritesDesc, prometheus.CounterValue, st.writes, dev)
+	}
+
+	if !c.networkMetrics || s.pid == 0 {
+		return
+	}
+	for iface, st := range readNetDev(filepath.Join(c.procRoot, strconv.Itoa(s.pid), "net", "dev")) {
+		emit(netRxBytesDesc, prometheus.CounterValue, st.rxBytes, iface)
+		emit(netRxPacketsDesc, prometheus.CounterValue, st.rxPackets, iface)
+		emit(netRxErrorsDesc, prometheus.CounterValue, st.rxErrors, iface)
+		emit(netTxBytesDesc, prometheus.CounterValue, st.txBytes, iface)
+		emit(netTxPacketsDesc, prometheus.CounterValue, st.txPackets, iface)
+		emit(netTxErrorsDesc, prometheus.CounterValue, st.txErrors, iface)
+	}
+}
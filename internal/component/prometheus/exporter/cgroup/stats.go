@@ -0,0 +1,356 @@
+package cgroup
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// userHZ is the unit of the CPU times reported by cgroup v1, which is
+// always 100 on Linux.
+const userHZ = 100
+
+// unlimited is the lowest value which cgroup v1 reports for memory limits
+// when no limit is set.
+const unlimited = 1 << 62
+
+// stats holds the resource usage of a container.
+type stats struct {
+	hasCPU           bool
+	cpuUsage         float64 // Seconds.
+	cpuUser          float64 // Seconds.
+	cpuSystem        float64 // Seconds.
+	throttledPeriods float64
+	throttledTime    float64 // Seconds.
+
+	hasMemory     bool
+	memUsage      float64
+	memWorkingSet float64
+	memRSS        float64
+	memCache      float64
+	memLimit      float64 // 0 if there is no limit.
+
+	io map[string]*ioStats // Keyed by major:minor device number.
+
+	// pid is a process of the container, or 0 if there is none.
+	pid int
+}
+
+type ioStats struct {
+	readBytes, writeBytes float64
+	reads, writes         float64
+}
+
+// hierarchy reads the stats of containers from a cgroup hierarchy.
+type hierarchy interface {
+	// walkRoot returns the directory to walk to find containers.
+	walkRoot() string
+	// read reads the stats of the container with the given cgroup path,
+	// relative to the root of the hierarchy.
+	read(path string) stats
+}
+
+// newHierarchy returns the hierarchy mounted at root. The unified hierarchy
+// of cgroup v2 is used if it is mounted, and the per-controller hierarchies
+// of cgroup v1 otherwise.
+func newHierarchy(root string) hierarchy {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return v2{root: root}
+	}
+	return v1{root: root}
+}
+
+// v2 reads stats from the unified cgroup v2 hierarchy.
+type v2 struct{ root string }
+
+func (h v2) walkRoot() string { return h.root }
+
+func (h v2) read(path string) stats {
+	var (
+		s   stats
+		dir = filepath.Join(h.root, path)
+	)
+
+	if cpu, err := readKeyValues(filepath.Join(dir, "cpu.stat")); err == nil {
+		s.hasCPU = true
+		s.cpuUsage = cpu["usage_usec"] / 1e6
+		s.cpuUser = cpu["user_usec"] / 1e6
+		s.cpuSystem = cpu["system_usec"] / 1e6
+		s.throttledPeriods = cpu["nr_throttled"]
+		s.throttledTime = cpu["throttled_usec"] / 1e6
+	}
+
+	if usage, err := readValue(filepath.Join(dir, "memory.current")); err == nil {
+		s.hasMemory = true
+		s.memUsage = usage
+		mem, _ := readKeyValues(filepath.Join(dir, "memory.stat"))
+		s.memRSS = mem["anon"]
+		s.memCache = mem["file"]
+		s.memWorkingSet = workingSet(usage, mem["inactive_file"])
+		if limit, err := readValue(filepath.Join(dir, "memory.max")); err == nil {
+			s.memLimit = limit
+		}
+	}
+
+	s.io = readIOStatV2(filepath.Join(dir, "io.stat"))
+	s.pid = readFirstPid(filepath.Join(dir, "cgroup.procs"))
+	return s
+}
+
+// v1 reads stats from the per-controller cgroup v1 hierarchies.
+type v1 struct{ root string }
+
+func (h v1) walkRoot() string { return filepath.Join(h.root, "cpuacct") }
+
+func (h v1) read(path string) stats {
+	var (
+		s      stats
+		cpuDir = filepath.Join(h.root, "cpuacct", path)
+		memDir = filepath.Join(h.root, "memory", path)
+		ioDir  = filepath.Join(h.root, "blkio", path)
+	)
+
+	if usage, err := readValue(filepath.Join(cpuDir, "cpuacct.usage")); err == nil {
+		s.hasCPU = true
+		s.cpuUsage = usage / 1e9
+		cpu, _ := readKeyValues(filepath.Join(cpuDir, "cpuacct.stat"))
+		s.cpuUser = cpu["user"] / userHZ
+		s.cpuSystem = cpu["system"] / userHZ
+		throttling, _ := readKeyValues(filepath.Join(h.root, "cpu", path, "cpu.stat"))
+		s.throttledPeriods = throttling["nr_throttled"]
+		s.throttledTime = throttling["throttled_time"] / 1e9
+	}
+
+	if usage, err := readValue(filepath.Join(memDir, "memory.usage_in_bytes")); err == nil {
+		s.hasMemory = true
+		s.memUsage = usage
+		mem, _ := readKeyValues(filepath.Join(memDir, "memory.stat"))
+		s.memRSS = mem["total_rss"]
+		s.memCache = mem["total_cache"]
+		s.memWorkingSet = workingSet(usage, mem["total_inactive_file"])
+		if limit, err := readValue(filepath.Join(memDir, "memory.limit_in_bytes")); err == nil && limit < unlimited {
+			s.memLimit = limit
+		}
+	}
+
+	s.io = make(map[string]*ioStats)
+	readBlkioV1(filepath.Join(ioDir, "blkio.throttle.io_service_bytes"), s.io, func(st *ioStats, op string, v float64) {
+		switch op {
+		case "Read":
+			st.readBytes = v
+		case "Write":
+			st.writeBytes = v
+		}
+	})
+	readBlkioV1(filepath.Join(ioDir, "blkio.throttle.io_serviced"), s.io, func(st *ioStats, op string, v float64) {
+		switch op {
+		case "Read":
+			st.reads = v
+		case "Write":
+			st.writes = v
+		}
+	})
+
+	s.pid = readFirstPid(filepath.Join(cpuDir, "cgroup.procs"))
+	return s
+}
+
+// workingSet returns the working set of a container, which is its memory
+// usage without the inactive page cache, like the kubelet computes it.
+func workingSet(usage, inactiveFile float64) float64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return usage - inactiveFile
+}
+
+// readValue reads a file containing a single number. "max" is read as 0,
+// meaning that there is no limit.
+func readValue(path string) (float64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// readKeyValues reads a file made of "key value" lines.
+func readKeyValues(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res := make(map[string]float64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			res[fields[0]] = v
+		}
+	}
+	return res, scanner.Err()
+}
+
+// readIOStatV2 reads a cgroup v2 io.stat file, made of lines such as
+// "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0".
+func readIOStatV2(path string) map[string]*ioStats {
+	res := make(map[string]*ioStats)
+	f, err := os.Open(path)
+	if err != nil {
+		return res
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		st := &ioStats{}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				st.readBytes = v
+			case "wbytes":
+				st.writeBytes = v
+			case "rios":
+				st.reads = v
+			case "wios":
+				st.writes = v
+			}
+		}
+		res[fields[0]] = st
+	}
+	return res
+}
+
+// readBlkioV1 reads a cgroup v1 blkio file, made of lines such as
+// "8:0 Read 1024", and calls set for every value.
+func readBlkioV1(path string, res map[string]*ioStats, set func(st *ioStats, op string, v float64)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			continue
+		}
+		st, ok := res[fields[0]]
+		if !ok {
+			st = &ioStats{}
+			res[fields[0]] = st
+		}
+		set(st, fields[1], v)
+	}
+}
+
+// readFirstPid returns the first process listed in a cgroup.procs file, or 0
+// if there is none.
+func readFirstPid(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+	return pid
+}
+
+// netStats holds the network usage of an interface.
+type netStats struct {
+	rxBytes, rxPackets, rxErrors float64
+	txBytes, txPackets, txErrors float64
+}
+
+// readNetDev reads the network usage of every interface other than the
+// loopback from a /proc/<pid>/net/dev file.
+func readNetDev(path string) map[string]netStats {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	res := make(map[string]netStats)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		iface, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			// Header lines don't contain colons.
+			continue
+		}
+		iface = strings.TrimSpace(iface)
+		fields := strings.Fields(counters)
+		if iface == "lo" || len(fields) < 11 {
+			continue
+		}
+
+		values := make([]float64, 11)
+		for i := range values {
+			values[i], _ = strconv.ParseFloat(fields[i], 64)
+		}
+		res[iface] = netStats{
+			rxBytes:   values[0],
+			rxPackets: values[1],
+			rxErrors:  values[2],
+			txBytes:   values[8],
+			txPackets: values[9],
+			txErrors:  values[10],
+		}
+	}
+	return res
+}
+
+// readDevices returns the names of block devices keyed by major:minor device
+// number, read from /proc/partitions.
+func readDevices(path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	res := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+		res[fields[0]+":"+fields[1]] = "/dev/" + fields[3]
+	}
+	return res
+}
@@ -0,0 +1,243 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatch_types "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	costexplorer_types "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+)
+
+// costExplorerRegion is the region of the endpoint of the Cost Explorer API.
+const costExplorerRegion = "us-east-1"
+
+// maxMetricDataQueries is the maximum number of queries of a GetMetricData
+// request.
+const maxMetricDataQueries = 500
+
+type costExplorerAPI interface {
+	GetCostAndUsage(ctx context.Context, params *costexplorer.GetCostAndUsageInput, optFns ...func(*costexplorer.Options)) (*costexplorer.GetCostAndUsageOutput, error)
+}
+
+// awsRegion holds the clients used to read the quotas of a region.
+type awsRegion struct {
+	name       string
+	quotas     servicequotas.ListServiceQuotasAPIClient
+	cloudwatch cloudwatch.GetMetricDataAPIClient
+}
+
+// awsAccount collects the costs of an AWS account with the Cost Explorer
+// API, and its quotas with the Service Quotas API. The usage of quotas is
+// read from the CloudWatch usage metrics of the quotas.
+type awsAccount struct {
+	args    AWSArguments
+	costs   costExplorerAPI
+	regions []awsRegion
+	now     func() time.Time
+}
+
+func newAWSAccount(ctx context.Context, args AWSArguments, client *http.Client) (*awsAccount, error) {
+	cfg, err := aws_config.LoadDefaultConfig(ctx, aws_config.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("aws %q: loading AWS config: %w", args.Name, err)
+	}
+
+	a := &awsAccount{
+		args: args,
+		costs: costexplorer.NewFromConfig(cfg, func(o *costexplorer.Options) {
+			o.Region = costExplorerRegion
+		}),
+		now: time.Now,
+	}
+	for _, region := range args.Regions {
+		region := region
+		a.regions = append(a.regions, awsRegion{
+			name:       region,
+			quotas:     servicequotas.NewFromConfig(cfg, func(o *servicequotas.Options) { o.Region = region }),
+			cloudwatch: cloudwatch.NewFromConfig(cfg, func(o *cloudwatch.Options) { o.Region = region }),
+		})
+	}
+	return a, nil
+}
+
+// Costs implements account. Costs are the unblended costs since the start of
+// the current month, in UTC.
+func (a *awsAccount) Costs(ctx context.Context) ([]cost, error) {
+	now := a.now().UTC()
+	var (
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		end   = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	)
+
+	type key struct{ service, currency string }
+	amounts := make(map[key]float64)
+
+	input := &costexplorer.GetCostAndUsageInput{
+		Granularity: costexplorer_types.GranularityMonthly,
+		Metrics:     []string{"UnblendedCost"},
+		GroupBy: []costexplorer_types.GroupDefinition{{
+			Type: costexplorer_types.GroupDefinitionTypeDimension,
+			Key:  aws.String("SERVICE"),
+		}},
+		TimePeriod: &costexplorer_types.DateInterval{
+			Start: aws.String(start.Format(time.DateOnly)),
+			End:   aws.String(end.Format(time.DateOnly)),
+		},
+	}
+	for {
+		out, err := a.costs.GetCostAndUsage(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range out.ResultsByTime {
+			for _, group := range result.Groups {
+				if len(group.Keys) == 0 {
+					continue
+				}
+				m, ok := group.Metrics["UnblendedCost"]
+				if !ok || m.Amount == nil {
+					continue
+				}
+				amount, err := strconv.ParseFloat(*m.Amount, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid cost of service %q: %w", group.Keys[0], err)
+				}
+				amounts[key{service: group.Keys[0], currency: aws.ToString(m.Unit)}] += amount
+			}
+		}
+		if out.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = out.NextPageToken
+	}
+
+	costs := make([]cost, 0, len(amounts))
+	for k, amount := range amounts {
+		costs = append(costs, cost{Service: k.service, Currency: k.currency, Amount: amount})
+	}
+	sort.Slice(costs, func(i, j int) bool { return costs[i].Service < costs[j].Service })
+	return costs, nil
+}
+
+// Quotas implements account. Quotas are read for every service of
+// quota_services in every region. Quotas without a usage metric don't have
+// a usage.
+func (a *awsAccount) Quotas(ctx context.Context) ([]quota, error) {
+	var (
+		quotas []quota
+		errs   []error
+	)
+	for _, region := range a.regions {
+		q, err := a.regionQuotas(ctx, region)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("region %s: %w", region.name, err))
+			continue
+		}
+		quotas = append(quotas, q...)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return quotas, nil
+}
+
+func (a *awsAccount) regionQuotas(ctx context.Context, region awsRegion) ([]quota, error) {
+	var (
+		quotas  []quota
+		queries []cloudwatch_types.MetricDataQuery
+		// usageIndex maps the IDs of queries to the index of their quota.
+		usageIndex = make(map[string]int)
+	)
+	for _, service := range a.args.QuotaServices {
+		p := servicequotas.NewListServiceQuotasPaginator(region.quotas, &servicequotas.ListServiceQuotasInput{
+			ServiceCode: aws.String(service),
+		})
+		for p.HasMorePages() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("listing quotas of service %s: %w", service, err)
+			}
+			for _, q := range page.Quotas {
+				if q.Value == nil {
+					continue
+				}
+				quotas = append(quotas, quota{
+					Region:  region.name,
+					Service: service,
+					Name:    aws.ToString(q.QuotaName),
+					Limit:   *q.Value,
+				})
+
+				m := q.UsageMetric
+				if m == nil || m.MetricName == nil || m.MetricNamespace == nil {
+					continue
+				}
+				stat := aws.ToString(m.MetricStatisticRecommendation)
+				if stat == "" {
+					stat = "Maximum"
+				}
+				dims := make([]cloudwatch_types.Dimension, 0, len(m.MetricDimensions))
+				for n, v := range m.MetricDimensions {
+					dims = append(dims, cloudwatch_types.Dimension{Name: aws.String(n), Value: aws.String(v)})
+				}
+				id := fmt.Sprintf("q%d", len(queries))
+				usageIndex[id] = len(quotas) - 1
+				queries = append(queries, cloudwatch_types.MetricDataQuery{
+					Id: aws.String(id),
+					MetricStat: &cloudwatch_types.MetricStat{
+						Metric: &cloudwatch_types.Metric{
+							Namespace:  m.MetricNamespace,
+							MetricName: m.MetricName,
+							Dimensions: dims,
+						},
+						Period: aws.Int32(300),
+						Stat:   aws.String(stat),
+					},
+				})
+			}
+		}
+	}
+
+	// The latest value of the usage metrics of the last hour is used as the
+	// usage of the quotas.
+	now := a.now()
+	for len(queries) > 0 {
+		batch := queries
+		if len(batch) > maxMetricDataQueries {
+			batch = batch[:maxMetricDataQueries]
+		}
+		queries = queries[len(batch):]
+
+		p := cloudwatch.NewGetMetricDataPaginator(region.cloudwatch, &cloudwatch.GetMetricDataInput{
+			MetricDataQueries: batch,
+			StartTime:         aws.Time(now.Add(-time.Hour)),
+			EndTime:           aws.Time(now),
+			ScanBy:            cloudwatch_types.ScanByTimestampDescending,
+		})
+		for p.HasMorePages() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("reading usage of quotas: %w", err)
+			}
+			for _, result := range page.MetricDataResults {
+				i, ok := usageIndex[aws.ToString(result.Id)]
+				if !ok || len(result.Values) == 0 || quotas[i].Usage != nil {
+					continue
+				}
+				usage := result.Values[0]
+				quotas[i].Usage = &usage
+			}
+		}
+	}
+	return quotas, nil
+}
@@ -0,0 +1,129 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cloudwatch_types "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	costexplorer_types "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	servicequotas_types "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCostExplorer struct {
+	inputs []*costexplorer.GetCostAndUsageInput
+	pages  []*costexplorer.GetCostAndUsageOutput
+}
+
+func (f *fakeCostExplorer) GetCostAndUsage(_ context.Context, in *costexplorer.GetCostAndUsageInput, _ ...func(*costexplorer.Options)) (*costexplorer.GetCostAndUsageOutput, error) {
+	input := *in
+	f.inputs = append(f.inputs, &input)
+	return f.pages[len(f.inputs)-1], nil
+}
+
+type fakeServiceQuotas map[string][]servicequotas_types.ServiceQuota
+
+func (f fakeServiceQuotas) ListServiceQuotas(_ context.Context, in *servicequotas.ListServiceQuotasInput, _ ...func(*servicequotas.Options)) (*servicequotas.ListServiceQuotasOutput, error) {
+	return &servicequotas.ListServiceQuotasOutput{Quotas: f[aws.ToString(in.ServiceCode)]}, nil
+}
+
+type fakeCloudWatch struct {
+	values map[string][]float64
+}
+
+func (f fakeCloudWatch) GetMetricData(_ context.Context, in *cloudwatch.GetMetricDataInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	var out cloudwatch.GetMetricDataOutput
+	for _, q := range in.MetricDataQueries {
+		out.MetricDataResults = append(out.MetricDataResults, cloudwatch_types.MetricDataResult{
+			Id:     q.Id,
+			Values: f.values[aws.ToString(q.MetricStat.Metric.MetricName)],
+		})
+	}
+	return &out, nil
+}
+
+func costGroup(service, amount string) costexplorer_types.Group {
+	return costexplorer_types.Group{
+		Keys: []string{service},
+		Metrics: map[string]costexplorer_types.MetricValue{
+			"UnblendedCost": {Amount: aws.String(amount), Unit: aws.String("USD")},
+		},
+	}
+}
+
+func TestAWSAccount_Costs(t *testing.T) {
+	costs := &fakeCostExplorer{pages: []*costexplorer.GetCostAndUsageOutput{
+		{
+			ResultsByTime: []costexplorer_types.ResultByTime{{Groups: []costexplorer_types.Group{
+				costGroup("Amazon Simple Storage Service", "10.5"),
+				costGroup("AWS Lambda", "0.25"),
+			}}},
+			NextPageToken: aws.String("page-2"),
+		},
+		{
+			ResultsByTime: []costexplorer_types.ResultByTime{{Groups: []costexplorer_types.Group{
+				costGroup("Amazon Simple Storage Service", "2"),
+			}}},
+		},
+	}}
+	a := &awsAccount{
+		costs: costs,
+		now:   func() time.Time { return time.Date(2024, time.March, 31, 23, 30, 0, 0, time.UTC) },
+	}
+
+	actual, err := a.Costs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []cost{
+		{Service: "AWS Lambda", Currency: "USD", Amount: 0.25},
+		{Service: "Amazon Simple Storage Service", Currency: "USD", Amount: 12.5},
+	}, actual)
+
+	require.Len(t, costs.inputs, 2)
+	require.Equal(t, "2024-03-01", aws.ToString(costs.inputs[0].TimePeriod.Start))
+	require.Equal(t, "2024-04-01", aws.ToString(costs.inputs[0].TimePeriod.End))
+	require.Nil(t, costs.inputs[0].NextPageToken)
+	require.Equal(t, "page-2", aws.ToString(costs.inputs[1].NextPageToken))
+}
+
+func TestAWSAccount_Quotas(t *testing.T) {
+	a := &awsAccount{
+		args: AWSArguments{QuotaServices: []string{"ec2"}},
+		regions: []awsRegion{{
+			name: "eu-west-1",
+			quotas: fakeServiceQuotas{"ec2": {
+				{
+					QuotaName: aws.String("Running On-Demand Standard instances"),
+					Value:     aws.Float64(64),
+					UsageMetric: &servicequotas_types.MetricInfo{
+						MetricName:      aws.String("ResourceCount"),
+						MetricNamespace: aws.String("AWS/Usage"),
+						MetricDimensions: map[string]string{
+							"Service": "EC2",
+							"Type":    "Resource",
+						},
+					},
+				},
+				{
+					QuotaName: aws.String("Number of EBS snapshots"),
+					Value:     aws.Float64(100000),
+				},
+			}},
+			cloudwatch: fakeCloudWatch{values: map[string][]float64{"ResourceCount": {16, 12}}},
+		}},
+		now: time.Now,
+	}
+
+	actual, err := a.Quotas(context.Background())
+	require.NoError(t, err)
+
+	usage := 16.0
+	require.Equal(t, []quota{
+		{Region: "eu-west-1", Service: "ec2", Name: "Running On-Demand Standard instances", Limit: 64, Usage: &usage},
+		{Region: "eu-west-1", Service: "ec2", Name: "Number of EBS snapshots", Limit: 100000},
+	}, actual)
+}
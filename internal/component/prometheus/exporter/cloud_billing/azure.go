@@ -0,0 +1,168 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v4"
+)
+
+// azureCostQueryAPIVersion is the version of the Cost Management query API.
+const azureCostQueryAPIVersion = "2023-03-01"
+
+// azureCostQuery queries the actual costs of the current billing month by
+// service.
+var azureCostQuery = map[string]interface{}{
+	"type":      "ActualCost",
+	"timeframe": "MonthToDate",
+	"dataset": map[string]interface{}{
+		"granularity": "None",
+		"aggregation": map[string]interface{}{
+			"totalCost": map[string]interface{}{"name": "Cost", "function": "Sum"},
+		},
+		"grouping": []map[string]interface{}{
+			{"type": "Dimension", "name": "ServiceName"},
+		},
+	},
+}
+
+// azureQueryResult is the result of a Cost Management query.
+type azureQueryResult struct {
+	Properties struct {
+		NextLink string `json:"nextLink"`
+		Columns  []struct {
+			Name string `json:"name"`
+		} `json:"columns"`
+		Rows [][]interface{} `json:"rows"`
+	} `json:"properties"`
+}
+
+// azureAccount collects the costs of an Azure subscription with the Cost
+// Management query API, and its Compute quotas with the Compute usage API.
+type azureAccount struct {
+	args   AzureArguments
+	client *arm.Client
+	usages *armcompute.UsageClient
+}
+
+// newAzureAccount creates the clients of an Azure subscription,
+// authenticating with the default Azure credential.
+func newAzureAccount(args AzureArguments, client *http.Client) (*azureAccount, error) {
+	clientOptions := policy.ClientOptions{Transport: client}
+	cred, err := azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: clientOptions})
+	if err != nil {
+		return nil, fmt.Errorf("azure %q: creating credential: %w", args.Name, err)
+	}
+	return newAzureAccountWithCredential(args, cred, &arm.ClientOptions{ClientOptions: clientOptions})
+}
+
+func newAzureAccountWithCredential(args AzureArguments, cred azcore.TokenCredential, opts *arm.ClientOptions) (*azureAccount, error) {
+	client, err := arm.NewClient("cloudbilling.CostClient", "v1.0.0", cred, opts)
+	if err != nil {
+		return nil, fmt.Errorf("azure %q: creating Resource Manager client: %w", args.Name, err)
+	}
+	usages, err := armcompute.NewUsageClient(args.SubscriptionID, cred, opts)
+	if err != nil {
+		return nil, fmt.Errorf("azure %q: creating Compute usage client: %w", args.Name, err)
+	}
+	return &azureAccount{args: args, client: client, usages: usages}, nil
+}
+
+// Costs implements account.
+func (a *azureAccount) Costs(ctx context.Context) ([]cost, error) {
+	endpoint := fmt.Sprintf("%s/subscriptions/%s/providers/Microsoft.CostManagement/query?api-version=%s",
+		a.client.Endpoint(), url.PathEscape(a.args.SubscriptionID), azureCostQueryAPIVersion)
+
+	var costs []cost
+	for endpoint != "" {
+		var result azureQueryResult
+		if err := a.post(ctx, endpoint, azureCostQuery, &result); err != nil {
+			return nil, fmt.Errorf("querying costs: %w", err)
+		}
+
+		costIndex, serviceIndex, currencyIndex := -1, -1, -1
+		for i, c := range result.Properties.Columns {
+			switch c.Name {
+			case "Cost", "PreTaxCost", "totalCost":
+				costIndex = i
+			case "ServiceName":
+				serviceIndex = i
+			case "Currency":
+				currencyIndex = i
+			}
+		}
+		if costIndex < 0 || serviceIndex < 0 || currencyIndex < 0 {
+			return nil, errors.New("querying costs: missing columns in query result")
+		}
+
+		for _, row := range result.Properties.Rows {
+			if len(row) != len(result.Properties.Columns) {
+				return nil, errors.New("querying costs: unexpected number of columns in query result")
+			}
+			amount, ok := row[costIndex].(float64)
+			if !ok {
+				return nil, fmt.Errorf("querying costs: invalid cost %v", row[costIndex])
+			}
+			service, _ := row[serviceIndex].(string)
+			currency, _ := row[currencyIndex].(string)
+			costs = append(costs, cost{Service: service, Currency: currency, Amount: amount})
+		}
+		endpoint = result.Properties.NextLink
+	}
+	return costs, nil
+}
+
+func (a *azureAccount) post(ctx context.Context, endpoint string, body, v interface{}) error {
+	req, err := runtime.NewRequest(ctx, http.MethodPost, endpoint)
+	if err != nil {
+		return err
+	}
+	if err := runtime.MarshalAsJSON(req, body); err != nil {
+		return err
+	}
+	resp, err := a.client.Pipeline().Do(req)
+	if err != nil {
+		return err
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return runtime.NewResponseError(resp)
+	}
+	return runtime.UnmarshalAsJSON(resp, v)
+}
+
+// Quotas implements account. Quotas are read for every location of
+// locations.
+func (a *azureAccount) Quotas(ctx context.Context) ([]quota, error) {
+	var quotas []quota
+	for _, location := range a.args.Locations {
+		p := a.usages.NewListPager(location, nil)
+		for p.More() {
+			page, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("reading usages of location %s: %w", location, err)
+			}
+			for _, u := range page.Value {
+				if u == nil || u.Name == nil || u.Name.Value == nil || u.Limit == nil || u.CurrentValue == nil {
+					continue
+				}
+				usage := float64(*u.CurrentValue)
+				quotas = append(quotas, quota{
+					Region:  location,
+					Service: "compute",
+					Name:    *u.Name.Value,
+					Limit:   float64(*u.Limit),
+					Usage:   &usage,
+				})
+			}
+		}
+	}
+	return quotas, nil
+}
@@ -0,0 +1,96 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTokenCredential struct{}
+
+func (fakeTokenCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestAzureAccount(t *testing.T) {
+	const subscription = "00000000-0000-0000-0000-000000000000"
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var resp interface{}
+		switch r.URL.Path {
+		case "/subscriptions/" + subscription + "/providers/Microsoft.CostManagement/query":
+			require.Equal(t, http.MethodPost, r.Method)
+			var req struct {
+				Type      string `json:"type"`
+				Timeframe string `json:"timeframe"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, "ActualCost", req.Type)
+			require.Equal(t, "MonthToDate", req.Timeframe)
+
+			columns := []map[string]string{{"name": "totalCost"}, {"name": "ServiceName"}, {"name": "Currency"}}
+			if r.URL.Query().Get("$skiptoken") == "" {
+				resp = map[string]interface{}{"properties": map[string]interface{}{
+					"nextLink": srv.URL + r.URL.Path + "?api-version=" + azureCostQueryAPIVersion + "&$skiptoken=page-2",
+					"columns":  columns,
+					"rows":     [][]interface{}{{812.4, "Virtual Machines", "EUR"}},
+				}}
+			} else {
+				resp = map[string]interface{}{"properties": map[string]interface{}{
+					"columns": columns,
+					"rows":    [][]interface{}{{20.1, "Storage", "EUR"}},
+				}}
+			}
+		case "/subscriptions/" + subscription + "/providers/Microsoft.Compute/locations/westeurope/usages":
+			resp = map[string]interface{}{"value": []map[string]interface{}{
+				{"name": map[string]string{"value": "cores", "localizedValue": "Total Regional vCPUs"}, "currentValue": 40, "limit": 100, "unit": "Count"},
+			}}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	a, err := newAzureAccountWithCredential(AzureArguments{
+		Name:           "prod",
+		SubscriptionID: subscription,
+		CollectCosts:   true,
+		Locations:      []string{"westeurope"},
+	}, fakeTokenCredential{}, &arm.ClientOptions{ClientOptions: policy.ClientOptions{
+		Cloud: cloud.Configuration{Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+			cloud.ResourceManager: {Endpoint: srv.URL, Audience: "https://management.azure.com"},
+		}},
+		Retry:     policy.RetryOptions{MaxRetries: -1},
+		Transport: srv.Client(),
+	}})
+	require.NoError(t, err)
+
+	costs, err := a.Costs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []cost{
+		{Service: "Virtual Machines", Currency: "EUR", Amount: 812.4},
+		{Service: "Storage", Currency: "EUR", Amount: 20.1},
+	}, costs)
+
+	quotas, err := a.Quotas(context.Background())
+	require.NoError(t, err)
+	usage := 40.0
+	require.Equal(t, []quota{
+		{Region: "westeurope", Service: "compute", Name: "cores", Limit: 100, Usage: &usage},
+	}, quotas)
+}
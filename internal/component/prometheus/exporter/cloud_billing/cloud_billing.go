@@ -0,0 +1,181 @@
+// Package cloud_billing implements the prometheus.exporter.cloud_billing
+// component, which exports the costs and quotas of AWS, GCP, and Azure
+// accounts.
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus/exporter"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/static/integrations"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.cloud_billing",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "cloud_billing"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+
+	targets, err := buildTargets(context.Background(), a)
+	if err != nil {
+		return nil, "", err
+	}
+
+	c := newCollector(opts.Logger, a, targets)
+	return integrations.NewCollectorIntegration(
+		"cloud_billing",
+		integrations.WithCollectors(c),
+		integrations.WithRunner(c.Run),
+	), defaultInstanceKey, nil
+}
+
+// DefaultArguments holds non-zero default options for Arguments when it is
+// unmarshaled from river.
+var DefaultArguments = Arguments{
+	CostRefreshInterval:  time.Hour,
+	QuotaRefreshInterval: 15 * time.Minute,
+	Timeout:              time.Minute,
+}
+
+// Arguments configures the prometheus.exporter.cloud_billing component.
+type Arguments struct {
+	CostRefreshInterval  time.Duration `river:"cost_refresh_interval,attr,optional"`
+	QuotaRefreshInterval time.Duration `river:"quota_refresh_interval,attr,optional"`
+	Timeout              time.Duration `river:"timeout,attr,optional"`
+
+	AWS   []AWSArguments   `river:"aws,block,optional"`
+	GCP   []GCPArguments   `river:"gcp,block,optional"`
+	Azure []AzureArguments `river:"azure,block,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.AWS)+len(a.GCP)+len(a.Azure) == 0 {
+		return errors.New("at least one aws, gcp, or azure block must be set")
+	}
+	if a.CostRefreshInterval <= 0 {
+		return errors.New("cost_refresh_interval must be greater than 0")
+	}
+	if a.QuotaRefreshInterval <= 0 {
+		return errors.New("quota_refresh_interval must be greater than 0")
+	}
+	if a.Timeout <= 0 {
+		return errors.New("timeout must be greater than 0")
+	}
+
+	names := make(map[string]struct{}, len(a.AWS)+len(a.GCP)+len(a.Azure))
+	checkName := func(name string) error {
+		if _, ok := names[name]; ok {
+			return fmt.Errorf("account %q is defined more than once", name)
+		}
+		names[name] = struct{}{}
+		return nil
+	}
+	for _, aws := range a.AWS {
+		if err := checkName(aws.Name); err != nil {
+			return err
+		}
+	}
+	for _, gcp := range a.GCP {
+		if err := checkName(gcp.Name); err != nil {
+			return err
+		}
+	}
+	for _, azure := range a.Azure {
+		if err := checkName(azure.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AWSArguments configures an AWS account to collect costs and quotas from.
+type AWSArguments struct {
+	Name          string   `river:",label"`
+	CollectCosts  bool     `river:"collect_costs,attr,optional"`
+	QuotaServices []string `river:"quota_services,attr,optional"`
+	Regions       []string `river:"regions,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *AWSArguments) SetToDefault() {
+	*args = AWSArguments{CollectCosts: true}
+}
+
+// Validate implements river.Validator.
+func (args *AWSArguments) Validate() error {
+	if !args.CollectCosts && len(args.QuotaServices) == 0 {
+		return fmt.Errorf("aws %q: collect_costs must be true or quota_services must be set", args.Name)
+	}
+	if len(args.QuotaServices) > 0 && len(args.Regions) == 0 {
+		return fmt.Errorf("aws %q: regions must be set to collect quotas", args.Name)
+	}
+	return nil
+}
+
+// bigQueryTableRegexp matches the IDs of BigQuery tables, as
+// PROJECT.DATASET.TABLE.
+var bigQueryTableRegexp = regexp.MustCompile(`^[a-zA-Z0-9.:_-]+\.[a-zA-Z0-9_]+\.[a-zA-Z0-9_]+$`)
+
+// GCPArguments configures a GCP project to collect costs and quotas from.
+type GCPArguments struct {
+	Name               string   `river:",label"`
+	ProjectID          string   `river:"project_id,attr"`
+	BillingExportTable string   `river:"billing_export_table,attr,optional"`
+	Regions            []string `river:"regions,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (args *GCPArguments) Validate() error {
+	if args.ProjectID == "" {
+		return fmt.Errorf("gcp %q: project_id must not be empty", args.Name)
+	}
+	if args.BillingExportTable != "" && !bigQueryTableRegexp.MatchString(args.BillingExportTable) {
+		return fmt.Errorf("gcp %q: billing_export_table must be a table ID as PROJECT.DATASET.TABLE", args.Name)
+	}
+	return nil
+}
+
+// AzureArguments configures an Azure subscription to collect costs and
+// quotas from.
+type AzureArguments struct {
+	Name           string   `river:",label"`
+	SubscriptionID string   `river:"subscription_id,attr"`
+	CollectCosts   bool     `river:"collect_costs,attr,optional"`
+	Locations      []string `river:"locations,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *AzureArguments) SetToDefault() {
+	*args = AzureArguments{CollectCosts: true}
+}
+
+// Validate implements river.Validator.
+func (args *AzureArguments) Validate() error {
+	if args.SubscriptionID == "" {
+		return fmt.Errorf("azure %q: subscription_id must not be empty", args.Name)
+	}
+	if !args.CollectCosts && len(args.Locations) == 0 {
+		return fmt.Errorf("azure %q: collect_costs must be true or locations must be set", args.Name)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "valid",
+			config: `
+				cost_refresh_interval = "6h"
+				aws "payer" {
+					quota_services = ["ec2", "vpc"]
+					regions        = ["us-east-1", "eu-west-1"]
+				}
+				gcp "main" {
+					project_id           = "my-project"
+					billing_export_table = "billing-project.billing.gcp_billing_export_v1_0123AB_4567CD_89EF01"
+					regions              = ["us-central1"]
+				}
+				azure "prod" {
+					subscription_id = "00000000-0000-0000-0000-000000000000"
+					locations       = ["westeurope"]
+				}
+			`,
+		},
+		{
+			name:   "no accounts",
+			config: `timeout = "1m"`,
+			err:    "at least one aws, gcp, or azure block must be set",
+		},
+		{
+			name: "duplicate account",
+			config: `
+				aws "main" {}
+				gcp "main" { project_id = "my-project" }
+			`,
+			err: `account "main" is defined more than once`,
+		},
+		{
+			name:   "nothing to collect",
+			config: `aws "main" { collect_costs = false }`,
+			err:    `aws "main": collect_costs must be true or quota_services must be set`,
+		},
+		{
+			name:   "quotas without regions",
+			config: `aws "main" { quota_services = ["ec2"] }`,
+			err:    `aws "main": regions must be set to collect quotas`,
+		},
+		{
+			name: "invalid billing export table",
+			config: `
+				gcp "main" {
+					project_id           = "my-project"
+					billing_export_table = "billing.export; DROP TABLE x"
+				}
+			`,
+			err: `gcp "main": billing_export_table must be a table ID as PROJECT.DATASET.TABLE`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.config), &args)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, args.AWS[0].CollectCosts)
+			require.Equal(t, 15*time.Minute, args.QuotaRefreshInterval)
+		})
+	}
+}
+
+// fakeAccount is an account returning fixed costs and quotas.
+type fakeAccount struct {
+	mut    sync.Mutex
+	costs  []cost
+	quotas []quota
+	err    error
+	calls  int
+}
+
+func (a *fakeAccount) Costs(context.Context) ([]cost, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.calls++
+	return a.costs, a.err
+}
+
+func (a *fakeAccount) Quotas(context.Context) ([]quota, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.calls++
+	return a.quotas, a.err
+}
+
+func (a *fakeAccount) Refreshes() int {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.calls
+}
+
+func TestCollector(t *testing.T) {
+	usage := 24.0
+	acc := &fakeAccount{
+		costs: []cost{{Service: "Amazon Elastic Compute Cloud - Compute", Currency: "USD", Amount: 1520.5}},
+		quotas: []quota{
+			{Region: "us-east-1", Service: "ec2", Name: "Running On-Demand Standard instances", Limit: 32, Usage: &usage},
+			{Region: "us-east-1", Service: "ec2", Name: "EC2-VPC Elastic IPs", Limit: 5},
+		},
+	}
+
+	args := DefaultArguments
+	args.CostRefreshInterval = time.Hour
+	args.QuotaRefreshInterval = 50 * time.Millisecond
+	c := newCollector(util.TestLogger(t), args, []target{
+		{provider: providerAWS, name: "payer", costs: true, quotas: true, account: acc},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = c.Run(ctx) }()
+	require.Eventually(t, func() bool { return acc.Refreshes() >= 2 }, 5*time.Second, 10*time.Millisecond)
+
+	expect := `
+		# HELP cloud_billing_cost_month_to_date Cost of a service since the start of the month.
+		# TYPE cloud_billing_cost_month_to_date gauge
+		cloud_billing_cost_month_to_date{account="payer",currency="USD",provider="aws",service="Amazon Elastic Compute Cloud - Compute"} 1520.5
+		# HELP cloud_billing_quota_limit Limit of a quota.
+		# TYPE cloud_billing_quota_limit gauge
+		cloud_billing_quota_limit{account="payer",provider="aws",quota="EC2-VPC Elastic IPs",region="us-east-1",service="ec2"} 5
+		cloud_billing_quota_limit{account="payer",provider="aws",quota="Running On-Demand Standard instances",region="us-east-1",service="ec2"} 32
+		# HELP cloud_billing_quota_usage Usage of a quota.
+		# TYPE cloud_billing_quota_usage gauge
+		cloud_billing_quota_usage{account="payer",provider="aws",quota="Running On-Demand Standard instances",region="us-east-1",service="ec2"} 24
+		# HELP cloud_billing_quota_utilization_ratio Usage of a quota divided by its limit.
+		# TYPE cloud_billing_quota_utilization_ratio gauge
+		cloud_billing_quota_utilization_ratio{account="payer",provider="aws",quota="Running On-Demand Standard instances",region="us-east-1",service="ec2"} 0.75
+		# HELP cloud_billing_refresh_success Whether the last refresh of the data of an account succeeded.
+		# TYPE cloud_billing_refresh_success gauge
+		cloud_billing_refresh_success{account="payer",data="cost",provider="aws"} 1
+		cloud_billing_refresh_success{account="payer",data="quota",provider="aws"} 1
+	`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"cloud_billing_cost_month_to_date",
+		"cloud_billing_quota_limit",
+		"cloud_billing_quota_usage",
+		"cloud_billing_quota_utilization_ratio",
+		"cloud_billing_refresh_success",
+	))
+	require.Equal(t, 2, testutil.CollectAndCount(c, "cloud_billing_last_refresh_timestamp_seconds"))
+
+	// Failed refreshes keep the last values. Only quotas are refreshed again
+	// within the test.
+	acc.mut.Lock()
+	acc.err = errors.New("throttled")
+	acc.mut.Unlock()
+	refreshes := acc.Refreshes()
+	require.Eventually(t, func() bool { return acc.Refreshes() > refreshes+1 }, 5*time.Second, 10*time.Millisecond)
+
+	expect = `
+		# HELP cloud_billing_refresh_success Whether the last refresh of the data of an account succeeded.
+		# TYPE cloud_billing_refresh_success gauge
+		cloud_billing_refresh_success{account="payer",data="cost",provider="aws"} 1
+		cloud_billing_refresh_success{account="payer",data="quota",provider="aws"} 0
+	`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect), "cloud_billing_refresh_success"))
+	require.Equal(t, 2, testutil.CollectAndCount(c, "cloud_billing_quota_limit"))
+}
@@ -0,0 +1,314 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/egress"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Providers of accounts.
+const (
+	providerAWS   = "aws"
+	providerGCP   = "gcp"
+	providerAzure = "azure"
+)
+
+// Kinds of data collected from accounts.
+const (
+	dataCost  = "cost"
+	dataQuota = "quota"
+)
+
+// account is a cloud account which costs and quotas are collected from.
+type account interface {
+	// Costs returns the month-to-date costs of the account, by service.
+	Costs(ctx context.Context) ([]cost, error)
+
+	// Quotas returns the quotas of the account, along with their usage.
+	Quotas(ctx context.Context) ([]quota, error)
+}
+
+// cost is the month-to-date cost of a service.
+type cost struct {
+	Service  string
+	Currency string
+	Amount   float64
+}
+
+// quota is the limit of a quota, along with its usage. Usage is nil when
+// the usage of the quota isn't known.
+type quota struct {
+	Region  string
+	Service string
+	Name    string
+	Limit   float64
+	Usage   *float64
+}
+
+// target is an account along with the data to collect from it.
+type target struct {
+	provider string
+	name     string
+	costs    bool
+	quotas   bool
+	account  account
+}
+
+// buildTargets creates the accounts configured in args.
+func buildTargets(ctx context.Context, args Arguments) ([]target, error) {
+	client := newHTTPClient()
+
+	var targets []target
+	for _, a := range args.AWS {
+		acc, err := newAWSAccount(ctx, a, client)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target{provider: providerAWS, name: a.Name, costs: a.CollectCosts, quotas: len(a.QuotaServices) > 0, account: acc})
+	}
+	for _, g := range args.GCP {
+		acc, err := newGCPAccount(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target{provider: providerGCP, name: g.Name, costs: g.BillingExportTable != "", quotas: true, account: acc})
+	}
+	for _, a := range args.Azure {
+		acc, err := newAzureAccount(a, client)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target{provider: providerAzure, name: a.Name, costs: a.CollectCosts, quotas: len(a.Locations) > 0, account: acc})
+	}
+	return targets, nil
+}
+
+// newHTTPClient returns the HTTP client used by the AWS and Azure SDKs,
+// which don't use http.DefaultTransport. The Google API clients use a copy of
+// http.DefaultTransport, which already connects with egress.DialContext.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = egress.DialContext
+	return &http.Client{Transport: transport}
+}
+
+var (
+	accountLabels = []string{"provider", "account"}
+
+	costDesc = prometheus.NewDesc(
+		"cloud_billing_cost_month_to_date",
+		"Cost of a service since the start of the month.",
+		append(accountLabels, "service", "currency"), nil,
+	)
+	quotaLimitDesc = prometheus.NewDesc(
+		"cloud_billing_quota_limit",
+		"Limit of a quota.",
+		append(accountLabels, "region", "service", "quota"), nil,
+	)
+	quotaUsageDesc = prometheus.NewDesc(
+		"cloud_billing_quota_usage",
+		"Usage of a quota.",
+		append(accountLabels, "region", "service", "quota"), nil,
+	)
+	quotaUtilizationDesc = prometheus.NewDesc(
+		"cloud_billing_quota_utilization_ratio",
+		"Usage of a quota divided by its limit.",
+		append(accountLabels, "region", "service", "quota"), nil,
+	)
+	refreshSuccessDesc = prometheus.NewDesc(
+		"cloud_billing_refresh_success",
+		"Whether the last refresh of the data of an account succeeded.",
+		append(accountLabels, "data"), nil,
+	)
+	lastRefreshDesc = prometheus.NewDesc(
+		"cloud_billing_last_refresh_timestamp_seconds",
+		"Time the data of an account was last refreshed successfully, as a Unix timestamp.",
+		append(accountLabels, "data"), nil,
+	)
+)
+
+// cache holds the values of the last successful refresh of the data of an
+// account.
+type cache[T any] struct {
+	values      []T
+	refreshed   bool // Whether a refresh finished, successfully or not.
+	success     bool
+	lastSuccess time.Time
+}
+
+func (c *cache[T]) update(values []T, err error) {
+	c.refreshed = true
+	c.success = err == nil
+	if err == nil {
+		c.values = values
+		c.lastSuccess = time.Now()
+	}
+}
+
+// collector refreshes the costs and quotas of accounts on an interval, and
+// exports the values of their last successful refresh, so collecting
+// metrics doesn't call the APIs of the cloud providers.
+type collector struct {
+	log     log.Logger
+	args    Arguments
+	targets []target
+
+	mut    sync.RWMutex
+	costs  map[string]*cache[cost]
+	quotas map[string]*cache[quota]
+}
+
+func newCollector(l log.Logger, args Arguments, targets []target) *collector {
+	c := &collector{
+		log:     l,
+		args:    args,
+		targets: targets,
+		costs:   make(map[string]*cache[cost]),
+		quotas:  make(map[string]*cache[quota]),
+	}
+	for _, t := range targets {
+		if t.costs {
+			c.costs[t.name] = &cache[cost]{}
+		}
+		if t.quotas {
+			c.quotas[t.name] = &cache[quota]{}
+		}
+	}
+	return c
+}
+
+// Run refreshes the data of every account immediately, and then on the
+// refresh interval of the data, until ctx is canceled.
+func (c *collector) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, t := range c.targets {
+		t := t
+		if t.costs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.refreshLoop(ctx, c.args.CostRefreshInterval, func(ctx context.Context) { c.refreshCosts(ctx, t) })
+			}()
+		}
+		if t.quotas {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.refreshLoop(ctx, c.args.QuotaRefreshInterval, func(ctx context.Context) { c.refreshQuotas(ctx, t) })
+			}()
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
+func (c *collector) refreshLoop(ctx context.Context, interval time.Duration, refresh func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		refreshCtx, cancel := context.WithTimeout(ctx, c.args.Timeout)
+		refresh(refreshCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *collector) refreshCosts(ctx context.Context, t target) {
+	costs, err := t.account.Costs(ctx)
+	if err != nil && ctx.Err() != context.Canceled {
+		level.Warn(c.log).Log("msg", "failed to refresh costs", "provider", t.provider, "account", t.name, "err", err)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.costs[t.name].update(costs, err)
+}
+
+func (c *collector) refreshQuotas(ctx context.Context, t target) {
+	quotas, err := t.account.Quotas(ctx)
+	if err != nil && ctx.Err() != context.Canceled {
+		level.Warn(c.log).Log("msg", "failed to refresh quotas", "provider", t.provider, "account", t.name, "err", err)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.quotas[t.name].update(quotas, err)
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		costDesc, quotaLimitDesc, quotaUsageDesc, quotaUtilizationDesc, refreshSuccessDesc, lastRefreshDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector. Values which would duplicate an
+// existing series are dropped, since they would make the scrape fail.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	seen := make(map[string]struct{})
+	emit := func(desc *prometheus.Desc, value float64, labelValues ...string) {
+		key := desc.String() + "\xff" + strings.Join(labelValues, "\xff")
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+	}
+	emitRefresh := func(t target, data string, refreshed, success bool, lastSuccess time.Time) {
+		if !refreshed {
+			return
+		}
+		emit(refreshSuccessDesc, boolToFloat(success), t.provider, t.name, data)
+		if !lastSuccess.IsZero() {
+			emit(lastRefreshDesc, float64(lastSuccess.UnixMilli())/1000, t.provider, t.name, data)
+		}
+	}
+
+	for _, t := range c.targets {
+		if costs, ok := c.costs[t.name]; ok {
+			emitRefresh(t, dataCost, costs.refreshed, costs.success, costs.lastSuccess)
+			for _, v := range costs.values {
+				emit(costDesc, v.Amount, t.provider, t.name, v.Service, v.Currency)
+			}
+		}
+		if quotas, ok := c.quotas[t.name]; ok {
+			emitRefresh(t, dataQuota, quotas.refreshed, quotas.success, quotas.lastSuccess)
+			for _, q := range quotas.values {
+				labelValues := []string{t.provider, t.name, q.Region, q.Service, q.Name}
+				emit(quotaLimitDesc, q.Limit, labelValues...)
+				if q.Usage == nil {
+					continue
+				}
+				emit(quotaUsageDesc, *q.Usage, labelValues...)
+				if q.Limit > 0 {
+					emit(quotaUtilizationDesc, *q.Usage/q.Limit, labelValues...)
+				}
+			}
+		}
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	bigquery "google.golang.org/api/bigquery/v2"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// gcpCostQuery sums the costs of the current invoice month by service,
+// including credits. Invoice months start at midnight Pacific time.
+const gcpCostQuery = "SELECT service.description, currency, " +
+	"SUM(cost) + SUM(IFNULL((SELECT SUM(c.amount) FROM UNNEST(credits) c), 0)) " +
+	"FROM `%s` " +
+	"WHERE invoice.month = FORMAT_DATE('%%Y%%m', CURRENT_DATE('America/Los_Angeles')) " +
+	"GROUP BY 1, 2"
+
+// gcpAccount collects the costs of a GCP project from its Cloud Billing
+// export to BigQuery, and its Compute Engine quotas with the Compute Engine
+// API.
+type gcpAccount struct {
+	args     GCPArguments
+	compute  *compute.Service
+	bigquery *bigquery.Service
+}
+
+// newGCPAccount creates the clients of a GCP project, authenticating with
+// the application default credentials unless opts configures otherwise.
+func newGCPAccount(ctx context.Context, args GCPArguments, opts ...option.ClientOption) (*gcpAccount, error) {
+	computeService, err := compute.NewService(ctx, append([]option.ClientOption{option.WithScopes(compute.ComputeReadonlyScope)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("gcp %q: creating Compute Engine client: %w", args.Name, err)
+	}
+	a := &gcpAccount{args: args, compute: computeService}
+
+	if args.BillingExportTable != "" {
+		a.bigquery, err = bigquery.NewService(ctx, append([]option.ClientOption{option.WithScopes(bigquery.BigqueryScope)}, opts...)...)
+		if err != nil {
+			return nil, fmt.Errorf("gcp %q: creating BigQuery client: %w", args.Name, err)
+		}
+	}
+	return a, nil
+}
+
+// Costs implements account. The query runs as a job of the project of the
+// account.
+func (a *gcpAccount) Costs(ctx context.Context) ([]cost, error) {
+	useLegacySQL := false
+	resp, err := a.bigquery.Jobs.Query(a.args.ProjectID, &bigquery.QueryRequest{
+		Query:        fmt.Sprintf(gcpCostQuery, a.args.BillingExportTable),
+		UseLegacySql: &useLegacySQL,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("querying billing export: %w", err)
+	}
+
+	var (
+		rows      = resp.Rows
+		complete  = resp.JobComplete
+		pageToken = resp.PageToken
+	)
+	for !complete || pageToken != "" {
+		if resp.JobReference == nil {
+			return nil, errors.New("querying billing export: missing job reference")
+		}
+		call := a.bigquery.Jobs.GetQueryResults(resp.JobReference.ProjectId, resp.JobReference.JobId).
+			Location(resp.JobReference.Location).
+			Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		results, err := call.Do()
+		if err != nil {
+			return nil, fmt.Errorf("reading results of billing export query: %w", err)
+		}
+		if !results.JobComplete {
+			continue
+		}
+		complete = true
+		rows = append(rows, results.Rows...)
+		pageToken = results.PageToken
+	}
+
+	costs := make([]cost, 0, len(rows))
+	for _, row := range rows {
+		if len(row.F) != 3 {
+			return nil, fmt.Errorf("unexpected number of columns %d in billing export query results", len(row.F))
+		}
+		service, _ := row.F[0].V.(string)
+		currency, _ := row.F[1].V.(string)
+		value, _ := row.F[2].V.(string)
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cost of service %q: %w", service, err)
+		}
+		costs = append(costs, cost{Service: service, Currency: currency, Amount: amount})
+	}
+	return costs, nil
+}
+
+// Quotas implements account. The quotas of the project are reported in the
+// global region, along with the quotas of every region of regions.
+func (a *gcpAccount) Quotas(ctx context.Context) ([]quota, error) {
+	project, err := a.compute.Projects.Get(a.args.ProjectID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("reading project quotas: %w", err)
+	}
+	quotas := convertGCPQuotas("global", project.Quotas)
+
+	for _, name := range a.args.Regions {
+		region, err := a.compute.Regions.Get(a.args.ProjectID, name).Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("reading quotas of region %s: %w", name, err)
+		}
+		quotas = append(quotas, convertGCPQuotas(name, region.Quotas)...)
+	}
+	return quotas, nil
+}
+
+func convertGCPQuotas(region string, in []*compute.Quota) []quota {
+	out := make([]quota, 0, len(in))
+	for _, q := range in {
+		usage := q.Usage
+		out = append(out, quota{
+			Region:  region,
+			Service: "compute",
+			Name:    q.Metric,
+			Limit:   q.Limit,
+			Usage:   &usage,
+		})
+	}
+	return out
+}
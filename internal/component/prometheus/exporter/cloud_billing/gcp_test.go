@@ -0,0 +1,79 @@
+package cloud_billing //nolint:golint
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestGCPAccount(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/my-project":
+			resp = map[string]interface{}{"quotas": []map[string]interface{}{
+				{"metric": "NETWORKS", "limit": 15, "usage": 3},
+			}}
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/my-project/regions/us-central1":
+			resp = map[string]interface{}{"quotas": []map[string]interface{}{
+				{"metric": "CPUS", "limit": 24, "usage": 6},
+			}}
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/my-project/queries":
+			var req struct {
+				Query string `json:"query"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			query = req.Query
+			resp = map[string]interface{}{
+				"jobComplete":  false,
+				"jobReference": map[string]string{"projectId": "my-project", "jobId": "job-1", "location": "US"},
+			}
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/my-project/queries/job-1":
+			require.Equal(t, "US", r.URL.Query().Get("location"))
+			resp = map[string]interface{}{
+				"jobComplete": true,
+				"rows": []map[string]interface{}{
+					{"f": []map[string]string{{"v": "Compute Engine"}, {"v": "USD"}, {"v": "120.75"}}},
+					{"f": []map[string]string{{"v": "Cloud Storage"}, {"v": "USD"}, {"v": "3.5"}}},
+				},
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	a, err := newGCPAccount(context.Background(), GCPArguments{
+		Name:               "main",
+		ProjectID:          "my-project",
+		BillingExportTable: "billing-project.billing.gcp_billing_export_v1_0123AB_4567CD_89EF01",
+		Regions:            []string{"us-central1"},
+	}, option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+
+	costs, err := a.Costs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []cost{
+		{Service: "Compute Engine", Currency: "USD", Amount: 120.75},
+		{Service: "Cloud Storage", Currency: "USD", Amount: 3.5},
+	}, costs)
+	require.Contains(t, query, "FROM `billing-project.billing.gcp_billing_export_v1_0123AB_4567CD_89EF01`")
+	require.Contains(t, query, "FORMAT_DATE('%Y%m', ")
+
+	quotas, err := a.Quotas(context.Background())
+	require.NoError(t, err)
+	networks, cpus := 3.0, 6.0
+	require.Equal(t, []quota{
+		{Region: "global", Service: "compute", Name: "NETWORKS", Limit: 15, Usage: &networks},
+		{Region: "us-central1", Service: "compute", Name: "CPUS", Limit: 24, Usage: &cpus},
+	}, quotas)
+}
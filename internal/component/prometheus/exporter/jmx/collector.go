@@ -0,0 +1,386 @@
+package jmx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/egress"
+	"github.com/grafana/agent/internal/static/integrations/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	common_config "github.com/prometheus/common/config"
+)
+
+// Integration collects metrics from JVMs using Jolokia. All targets share the
+// same HTTP client, so connections to Jolokia agents and proxies are pooled
+// and reused across scrapes.
+type Integration struct {
+	log     log.Logger
+	args    Arguments
+	targets map[string]Target
+	client  *http.Client
+
+	// sem limits the number of concurrent requests to Jolokia. It is nil if
+	// there is no limit.
+	sem chan struct{}
+}
+
+func newIntegration(l log.Logger, args Arguments) (*Integration, error) {
+	client, err := common_config.NewClientFromConfig(
+		*args.HTTPClientConfig.Convert(),
+		"jmx",
+		common_config.WithIdleConnTimeout(args.IdleConnectionTimeout),
+		egress.HTTPClientOption(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Integration{
+		log:     l,
+		args:    args,
+		targets: make(map[string]Target, len(args.Targets)),
+		client:  client,
+	}
+	for _, t := range args.Targets {
+		i.targets[t.Name] = t
+	}
+	if args.MaxConcurrentRequests > 0 {
+		i.sem = make(chan struct{}, args.MaxConcurrentRequests)
+	}
+	return i, nil
+}
+
+// MetricsHandler implements Integration. The target to collect metrics from
+// is selected with the target query parameter.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("target")
+		t, ok := i.targets[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown target %q", name), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), i.args.Timeout)
+		defer cancel()
+
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(&collector{ctx: ctx, integration: i, target: t})
+		promhttp.HandlerFor(reg, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, r)
+	}), nil
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	var res []config.ScrapeConfig
+	for _, t := range i.args.Targets {
+		res = append(res, config.ScrapeConfig{
+			JobName:     "jmx/" + t.Name,
+			MetricsPath: "/metrics",
+			QueryParams: url.Values{"target": []string{t.Name}},
+		})
+	}
+	return res
+}
+
+// Run satisfies Integration.Run.
+func (i *Integration) Run(ctx context.Context) error {
+	<-ctx.Done()
+	i.client.CloseIdleConnections()
+	return ctx.Err()
+}
+
+var (
+	upDesc = prometheus.NewDesc(
+		"jmx_up",
+		"Whether the Jolokia agent of the target could be reached.",
+		nil, nil,
+	)
+	queryErrorsDesc = prometheus.NewDesc(
+		"jmx_query_errors",
+		"Number of queries which failed during the last scrape.",
+		nil, nil,
+	)
+	scrapeDurationDesc = prometheus.NewDesc(
+		"jmx_scrape_duration_seconds",
+		"Duration of the last scrape of the target.",
+		nil, nil,
+	)
+)
+
+// collector collects the metrics of a single target. A new collector is
+// created for every scrape.
+type collector struct {
+	ctx         context.Context
+	integration *Integration
+	target      Target
+}
+
+// Describe implements prometheus.Collector. The collector is unchecked, as
+// the metrics it collects depend on the MBeans exposed by the target.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+	defer func() {
+		ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
+	}()
+
+	resps, err := c.read()
+	if err != nil {
+		level.Warn(c.integration.log).Log("msg", "failed to read MBeans", "target", c.target.Name, "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	var (
+		failed int
+		m      = newMapper(c.integration.args.LowercaseNames)
+	)
+	for idx, resp := range resps {
+		q := c.integration.args.Queries[idx]
+		if resp.Status != http.StatusOK {
+			failed++
+			level.Debug(c.integration.log).Log("msg", "MBean query failed", "target", c.target.Name, "mbean", q.MBean, "status", resp.Status, "err", resp.Error)
+			continue
+		}
+		for mbean, attrs := range mbeanValues(q, resp.Value) {
+			m.mapMBean(q, mbean, attrs)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(queryErrorsDesc, prometheus.GaugeValue, float64(failed))
+
+	for _, s := range m.samples {
+		metric, err := prometheus.NewConstMetric(
+			prometheus.NewDesc(s.name, m.help[s.name], s.labelNames, nil),
+			s.valueType, s.value, s.labelValues...,
+		)
+		if err != nil {
+			level.Debug(c.integration.log).Log("msg", "invalid JMX metric", "target", c.target.Name, "metric", s.name, "err", err)
+			continue
+		}
+		ch <- metric
+	}
+}
+
+// read sends the queries to the target, waiting for a free slot if the
+// number of concurrent requests is limited.
+func (c *collector) read() ([]readResponse, error) {
+	if sem := c.integration.sem; sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-c.ctx.Done():
+			return nil, c.ctx.Err()
+		}
+	}
+	reqs := buildRequests(c.target, c.integration.args.Queries)
+	return read(c.ctx, c.integration.client, c.target.Address, reqs)
+}
+
+// sample is a single metric value mapped from an MBean attribute.
+type sample struct {
+	name        string
+	labelNames  []string
+	labelValues []string
+	valueType   prometheus.ValueType
+	value       float64
+}
+
+// mapper maps MBean attributes to samples. Samples which would duplicate an
+// existing series are dropped, and every metric name keeps the help text it
+// was first mapped with, since both would make the scrape fail.
+type mapper struct {
+	lowercase bool
+	samples   []sample
+	help      map[string]string
+	seen      map[string]struct{}
+}
+
+func newMapper(lowercase bool) *mapper {
+	return &mapper{
+		lowercase: lowercase,
+		help:      make(map[string]string),
+		seen:      make(map[string]struct{}),
+	}
+}
+
+// mapMBean maps the attributes of an MBean returned by a query.
+//
+// By default, attributes are named domain_type_attribute, and the other key
+// properties of the MBean are used as labels. Composite values produce a
+// metric per key, with the key appended to the metric name.
+func (m *mapper) mapMBean(q Query, mbean string, attrs map[string]interface{}) {
+	domain, props, err := parseObjectName(mbean)
+	if err != nil {
+		return
+	}
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		if k != "type" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	labelNames := make([]string, 0, len(keys))
+	labelValues := make([]string, 0, len(keys))
+	for _, k := range keys {
+		labelNames = append(labelNames, m.sanitize(k))
+		labelValues = append(labelValues, props[k])
+	}
+
+	if len(q.Attributes) == 0 {
+		names := make([]string, 0, len(attrs))
+		for name := range attrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m.mapValue(Attribute{Name: name}, domain, props["type"], attrs[name], labelNames, labelValues)
+		}
+		return
+	}
+
+	for _, attr := range q.Attributes {
+		value, ok := attrs[attr.Name]
+		if !ok {
+			continue
+		}
+		if attr.Path != "" {
+			value, ok = lookupPath(value, attr.Path)
+			if !ok {
+				continue
+			}
+		}
+		m.mapValue(attr, domain, props["type"], value, labelNames, labelValues)
+	}
+}
+
+func (m *mapper) mapValue(attr Attribute, domain, mbeanType string, value interface{}, labelNames, labelValues []string) {
+	var (
+		name      = attr.MetricName
+		help      = attr.Help
+		valueType = prometheus.UntypedValue
+	)
+	if name == "" {
+		parts := []string{domain}
+		if mbeanType != "" {
+			parts = append(parts, mbeanType)
+		}
+		parts = append(parts, attr.Name)
+		if attr.Path != "" {
+			parts = append(parts, strings.Split(attr.Path, "/")...)
+		}
+		name = m.sanitize(strings.Join(parts, "_"))
+	}
+	if help == "" {
+		objectType := domain
+		if mbeanType != "" {
+			objectType += ":type=" + mbeanType
+		}
+		help = fmt.Sprintf("Value of the JMX attribute %s of %s.", attr.Name, objectType)
+	}
+	switch attr.Type {
+	case TypeGauge:
+		valueType = prometheus.GaugeValue
+	case TypeCounter:
+		valueType = prometheus.CounterValue
+	}
+
+	base := name
+	flatten(name, value, func(name string, v float64) {
+		// Keys of composite values may contain invalid characters.
+		name = base + m.sanitize(name[len(base):])
+		key := name + "\xff" + strings.Join(labelNames, "\xff") + "\xff" + strings.Join(labelValues, "\xff")
+		if _, ok := m.seen[key]; ok {
+			return
+		}
+		m.seen[key] = struct{}{}
+		if _, ok := m.help[name]; !ok {
+			m.help[name] = help
+		}
+		m.samples = append(m.samples, sample{
+			name:        name,
+			labelNames:  labelNames,
+			labelValues: labelValues,
+			valueType:   valueType,
+			value:       v,
+		})
+	})
+}
+
+// flatten calls fn for every numeric or boolean value in v. Keys of
+// composite values are appended to the name with an underscore. Other values,
+// such as strings and arrays, are ignored.
+func flatten(name string, v interface{}, fn func(name string, v float64)) {
+	switch v := v.(type) {
+	case float64:
+		fn(name, v)
+	case bool:
+		if v {
+			fn(name, 1)
+		} else {
+			fn(name, 0)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(name+"_"+k, v[k], fn)
+		}
+	}
+}
+
+// lookupPath returns the value of a composite value at a path of keys
+// separated by slashes.
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, "/") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// sanitize replaces the characters of s which aren't valid in metric and
+// label names with underscores, and lowercases it if lowercase names are
+// enabled.
+func (m *mapper) sanitize(s string) string {
+	if m.lowercase {
+		s = strings.ToLower(s)
+	}
+	var sb strings.Builder
+	sb.Grow(len(s) + 1)
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
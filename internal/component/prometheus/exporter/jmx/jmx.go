@@ -0,0 +1,178 @@
+package jmx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/prometheus/exporter"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/static/integrations"
+	"github.com/grafana/river/rivertypes"
+	"github.com/prometheus/common/model"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.jmx",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.NewWithTargetBuilder(createExporter, "jmx", buildJMXTargets),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	i, err := newIntegration(opts.Logger, a)
+	return i, defaultInstanceKey, err
+}
+
+// buildJMXTargets creates the exporter's discovery targets based on the defined JMX targets.
+func buildJMXTargets(baseTarget discovery.Target, args component.Arguments) []discovery.Target {
+	var targets []discovery.Target
+
+	a := args.(Arguments)
+	for _, tgt := range a.Targets {
+		target := make(discovery.Target)
+		// Set extra labels first, meaning that any other labels will override
+		for k, v := range tgt.Labels {
+			target[k] = v
+		}
+		for k, v := range baseTarget {
+			target[k] = v
+		}
+
+		target["job"] = target["job"] + "/" + tgt.Name
+		target["__param_target"] = tgt.Name
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// Metric types which can be assigned to attributes.
+const (
+	TypeUntyped = "untyped"
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// DefaultArguments holds non-zero default options for Arguments when it is
+// unmarshaled from river.
+var DefaultArguments = Arguments{
+	Timeout:               10 * time.Second,
+	IdleConnectionTimeout: 5 * time.Minute,
+	HTTPClientConfig:      config.DefaultHTTPClientConfig,
+}
+
+// Arguments configures the prometheus.exporter.jmx component.
+type Arguments struct {
+	Targets               TargetBlock             `river:"target,block"`
+	Queries               []Query                 `river:"query,block"`
+	Timeout               time.Duration           `river:"timeout,attr,optional"`
+	IdleConnectionTimeout time.Duration           `river:"idle_connection_timeout,attr,optional"`
+	MaxConcurrentRequests int                     `river:"max_concurrent_requests,attr,optional"`
+	LowercaseNames        bool                    `river:"lowercase_names,attr,optional"`
+	HTTPClientConfig      config.HTTPClientConfig `river:",squash"`
+}
+
+// Target defines a JVM to collect metrics from.
+type Target struct {
+	Name string `river:",label"`
+
+	// Address is the URL of the Jolokia agent of the JVM, or of a Jolokia
+	// proxy if JMXURL is set.
+	Address     string            `river:"address,attr"`
+	JMXURL      string            `river:"jmx_url,attr,optional"`
+	JMXUsername string            `river:"jmx_username,attr,optional"`
+	JMXPassword rivertypes.Secret `river:"jmx_password,attr,optional"`
+	Labels      map[string]string `river:"labels,attr,optional"`
+}
+
+// TargetBlock is a list of JVMs to collect metrics from.
+type TargetBlock []Target
+
+// Query selects the MBeans to read attributes from.
+type Query struct {
+	MBean      string      `river:"mbean,attr"`
+	Attributes []Attribute `river:"attribute,block,optional"`
+}
+
+// Attribute maps an MBean attribute to a metric.
+type Attribute struct {
+	Name       string `river:"name,attr"`
+	Path       string `river:"path,attr,optional"`
+	MetricName string `river:"metric_name,attr,optional"`
+	Type       string `river:"type,attr,optional"`
+	Help       string `river:"help,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Targets) == 0 {
+		return errors.New("at least one target block must be set")
+	}
+	if len(a.Queries) == 0 {
+		return errors.New("at least one query block must be set")
+	}
+	if a.Timeout <= 0 {
+		return errors.New("timeout must be greater than 0")
+	}
+	if a.MaxConcurrentRequests < 0 {
+		return errors.New("max_concurrent_requests must not be negative")
+	}
+
+	names := make(map[string]struct{}, len(a.Targets))
+	for _, t := range a.Targets {
+		if _, ok := names[t.Name]; ok {
+			return fmt.Errorf("duplicate target name %q", t.Name)
+		}
+		names[t.Name] = struct{}{}
+
+		u, err := url.Parse(t.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target %q: address must be an http or https URL", t.Name)
+		}
+		if t.JMXURL == "" && (t.JMXUsername != "" || t.JMXPassword != "") {
+			return fmt.Errorf("target %q: jmx_username and jmx_password require jmx_url to be set", t.Name)
+		}
+	}
+
+	for _, q := range a.Queries {
+		if _, _, err := parseObjectName(q.MBean); err != nil {
+			return err
+		}
+		for _, attr := range q.Attributes {
+			if attr.Name == "" {
+				return fmt.Errorf("query %q: attribute name must not be empty", q.MBean)
+			}
+			switch attr.Type {
+			case "", TypeUntyped, TypeGauge, TypeCounter:
+			default:
+				return fmt.Errorf("query %q: attribute %q has invalid type %q", q.MBean, attr.Name, attr.Type)
+			}
+			if attr.MetricName != "" && !model.IsValidMetricName(model.LabelValue(attr.MetricName)) {
+				return fmt.Errorf("query %q: attribute %q has invalid metric name %q", q.MBean, attr.Name, attr.MetricName)
+			}
+			if strings.HasPrefix(attr.Path, "/") || strings.HasSuffix(attr.Path, "/") {
+				return fmt.Errorf("query %q: attribute %q has invalid path %q", q.MBean, attr.Name, attr.Path)
+			}
+		}
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
+	return a.HTTPClientConfig.Validate()
+}
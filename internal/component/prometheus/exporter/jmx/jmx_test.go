@@ -0,0 +1,218 @@
+package jmx
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	riverCfg := `
+		target "kafka" {
+			address      = "http://kafka:8778/jolokia"
+			labels       = { "env" = "prod" }
+		}
+		target "cassandra" {
+			address      = "http://jolokia-proxy:8080/jolokia"
+			jmx_url      = "service:jmx:rmi:///jndi/rmi://cassandra:7199/jmxrmi"
+			jmx_username = "monitor"
+			jmx_password = "secret"
+		}
+
+		query {
+			mbean = "java.lang:type=Memory"
+
+			attribute {
+				name        = "HeapMemoryUsage"
+				path        = "used"
+				metric_name = "jvm_memory_heap_used_bytes"
+				type        = "gauge"
+			}
+		}
+		query {
+			mbean = "java.lang:type=GarbageCollector,*"
+		}
+
+		max_concurrent_requests = 4
+		basic_auth {
+			username = "jolokia"
+			password = "jolokia"
+		}
+`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(riverCfg), &args))
+	require.Len(t, args.Targets, 2)
+	require.Equal(t, "cassandra", args.Targets[1].Name)
+	require.Equal(t, "monitor", args.Targets[1].JMXUsername)
+	require.Len(t, args.Queries, 2)
+	require.Equal(t, "jvm_memory_heap_used_bytes", args.Queries[0].Attributes[0].MetricName)
+	require.Equal(t, DefaultArguments.Timeout, args.Timeout)
+	require.Equal(t, 4, args.MaxConcurrentRequests)
+	require.Equal(t, "jolokia", args.HTTPClientConfig.BasicAuth.Username)
+
+	targets := buildJMXTargets(discovery.Target{"job": "integrations/jmx", "instance": "agent"}, args)
+	require.Equal(t, []discovery.Target{
+		{"job": "integrations/jmx/kafka", "instance": "agent", "env": "prod", "__param_target": "kafka"},
+		{"job": "integrations/jmx/cassandra", "instance": "agent", "__param_target": "cassandra"},
+	}, targets)
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "invalid mbean",
+			config: `
+				target "a" { address = "http://a:8778/jolokia" }
+				query { mbean = "java.lang" }`,
+			err: `invalid MBean name "java.lang": expected domain:key=value[,key=value...]`,
+		},
+		{
+			name: "duplicate target",
+			config: `
+				target "a" { address = "http://a:8778/jolokia" }
+				target "a" { address = "http://b:8778/jolokia" }
+				query { mbean = "java.lang:type=Memory" }`,
+			err: `duplicate target name "a"`,
+		},
+		{
+			name: "credentials without jmx_url",
+			config: `
+				target "a" {
+					address      = "http://a:8778/jolokia"
+					jmx_username = "monitor"
+				}
+				query { mbean = "java.lang:type=Memory" }`,
+			err: `target "a": jmx_username and jmx_password require jmx_url to be set`,
+		},
+		{
+			name: "invalid type",
+			config: `
+				target "a" { address = "http://a:8778/jolokia" }
+				query {
+					mbean = "java.lang:type=Memory"
+					attribute {
+						name = "HeapMemoryUsage"
+						type = "histogram"
+					}
+				}`,
+			err: `query "java.lang:type=Memory": attribute "HeapMemoryUsage" has invalid type "histogram"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			require.EqualError(t, river.Unmarshal([]byte(tc.config), &args), tc.err)
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	var received []readRequest
+	jolokia := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_, _ = io.WriteString(w, `[
+			{"status": 200, "value": {"used": 1024, "max": 4096}},
+			{"status": 200, "value": {
+				"java.lang:name=G1 Young Generation,type=GarbageCollector": {"CollectionCount": 12, "Valid": true, "Name": "G1 Young Generation"},
+				"java.lang:name=G1 Old Generation,type=GarbageCollector": {"CollectionCount": 1, "Valid": true, "Name": "G1 Old Generation"}
+			}},
+			{"status": 404, "error": "javax.management.InstanceNotFoundException"}
+		]`)
+	}))
+	defer jolokia.Close()
+
+	args := DefaultArguments
+	args.Targets = TargetBlock{{
+		Name:        "app",
+		Address:     jolokia.URL,
+		JMXURL:      "service:jmx:rmi:///jndi/rmi://app:9999/jmxrmi",
+		JMXUsername: "monitor",
+		JMXPassword: "secret",
+	}}
+	args.Queries = []Query{
+		{
+			MBean: "java.lang:type=Memory",
+			Attributes: []Attribute{{
+				Name:       "HeapMemoryUsage",
+				Path:       "used",
+				MetricName: "jvm_memory_heap_used_bytes",
+				Type:       TypeGauge,
+			}},
+		},
+		{MBean: "java.lang:type=GarbageCollector,*"},
+		{MBean: "kafka.server:type=BrokerTopicMetrics,name=MessagesInPerSec"},
+	}
+
+	i, err := newIntegration(util.TestLogger(t), args)
+	require.NoError(t, err)
+	h, err := i.MetricsHandler()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?target=app", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, received, 3)
+	require.Equal(t, readRequest{
+		Type:      "read",
+		MBean:     "java.lang:type=Memory",
+		Attribute: []string{"HeapMemoryUsage"},
+		Config:    map[string]interface{}{"ignoreErrors": true},
+		Target: &proxyTarget{
+			URL:      "service:jmx:rmi:///jndi/rmi://app:9999/jmxrmi",
+			User:     "monitor",
+			Password: "secret",
+		},
+	}, received[0])
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE jvm_memory_heap_used_bytes gauge",
+		"jvm_memory_heap_used_bytes 1024",
+		`java_lang_GarbageCollector_CollectionCount{name="G1 Old Generation"} 1`,
+		`java_lang_GarbageCollector_CollectionCount{name="G1 Young Generation"} 12`,
+		`java_lang_GarbageCollector_Valid{name="G1 Young Generation"} 1`,
+		"jmx_up 1",
+		"jmx_query_errors 1",
+	} {
+		require.Contains(t, body, line+"\n")
+	}
+	require.NotContains(t, body, "java_lang_GarbageCollector_Name")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?target=unknown", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestParseObjectName(t *testing.T) {
+	domain, props, err := parseObjectName(`kafka.server:type=BrokerTopicMetrics,name="Messages,In",*`)
+	require.NoError(t, err)
+	require.Equal(t, "kafka.server", domain)
+	require.Equal(t, map[string]string{"type": "BrokerTopicMetrics", "name": "Messages,In"}, props)
+
+	_, _, err = parseObjectName("kafka.server:type")
+	require.EqualError(t, err, `invalid MBean name "kafka.server:type": invalid key property "type"`)
+}
+
+func TestLowercaseNames(t *testing.T) {
+	m := newMapper(true)
+	m.mapMBean(Query{MBean: "java.lang:type=Threading"}, "java.lang:type=Threading", map[string]interface{}{
+		"ThreadCount": float64(42),
+	})
+	require.Len(t, m.samples, 1)
+	require.Equal(t, "java_lang_threading_threadcount", m.samples[0].name)
+	require.True(t, strings.HasPrefix(m.help[m.samples[0].name], "Value of the JMX attribute ThreadCount"))
+}
@@ -0,0 +1,193 @@
+package jmx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// readRequest is a Jolokia read request.
+type readRequest struct {
+	Type      string                 `json:"type"`
+	MBean     string                 `json:"mbean"`
+	Attribute []string               `json:"attribute,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+	Target    *proxyTarget           `json:"target,omitempty"`
+}
+
+// proxyTarget is the remote JMX endpoint a Jolokia proxy forwards a request
+// to.
+type proxyTarget struct {
+	URL      string `json:"url"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// readResponse is the response of a Jolokia agent to a read request.
+type readResponse struct {
+	Status int         `json:"status"`
+	Error  string      `json:"error,omitempty"`
+	Value  interface{} `json:"value"`
+}
+
+// isPattern returns true if an MBean name is a pattern matching multiple
+// MBeans.
+func isPattern(mbean string) bool {
+	return strings.ContainsAny(mbean, "*?")
+}
+
+// buildRequests returns the Jolokia read requests for the queries of a
+// target. Requests are sent as a single bulk request, in the order of the
+// queries.
+func buildRequests(t Target, queries []Query) []readRequest {
+	var target *proxyTarget
+	if t.JMXURL != "" {
+		target = &proxyTarget{
+			URL:      t.JMXURL,
+			User:     t.JMXUsername,
+			Password: string(t.JMXPassword),
+		}
+	}
+
+	reqs := make([]readRequest, 0, len(queries))
+	for _, q := range queries {
+		req := readRequest{
+			Type:   "read",
+			MBean:  q.MBean,
+			Target: target,
+			// Attributes which can't be read, for example because they aren't
+			// supported by the JVM, don't fail the whole query.
+			Config: map[string]interface{}{"ignoreErrors": true},
+		}
+		seen := make(map[string]struct{}, len(q.Attributes))
+		for _, attr := range q.Attributes {
+			if _, ok := seen[attr.Name]; ok {
+				continue
+			}
+			seen[attr.Name] = struct{}{}
+			req.Attribute = append(req.Attribute, attr.Name)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// read sends a bulk read request to a Jolokia agent and returns the
+// responses, in the order of the requests.
+func read(ctx context.Context, client *http.Client, address string, reqs []readRequest) ([]readResponse, error) {
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var resps []readResponse
+	if err := json.NewDecoder(resp.Body).Decode(&resps); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(resps) != len(reqs) {
+		return nil, fmt.Errorf("expected %d responses, got %d", len(reqs), len(resps))
+	}
+	return resps, nil
+}
+
+// mbeanValues returns the attribute values of every MBean in the value of a
+// read response, keyed by MBean name.
+func mbeanValues(q Query, value interface{}) map[string]map[string]interface{} {
+	if isPattern(q.MBean) {
+		// Pattern reads return the attributes of every matching MBean.
+		mbeans, _ := value.(map[string]interface{})
+		res := make(map[string]map[string]interface{}, len(mbeans))
+		for name, attrs := range mbeans {
+			if attrs, ok := attrs.(map[string]interface{}); ok {
+				res[name] = attrs
+			}
+		}
+		return res
+	}
+
+	// Reads of a single attribute may return the value of the attribute
+	// directly rather than a map of attribute names to values.
+	attrs, ok := value.(map[string]interface{})
+	if len(q.Attributes) > 0 {
+		single := true
+		for _, attr := range q.Attributes {
+			single = single && attr.Name == q.Attributes[0].Name
+		}
+		if _, isAttr := attrs[q.Attributes[0].Name]; single && (!ok || !isAttr) {
+			attrs, ok = map[string]interface{}{q.Attributes[0].Name: value}, true
+		}
+	}
+	if !ok {
+		return nil
+	}
+	return map[string]map[string]interface{}{q.MBean: attrs}
+}
+
+// parseObjectName parses an MBean object name of the form
+// domain:key=value[,key=value...] into its domain and key properties. Quoted
+// values are unquoted.
+func parseObjectName(name string) (string, map[string]string, error) {
+	domain, rest, ok := strings.Cut(name, ":")
+	if !ok || domain == "" || rest == "" {
+		return "", nil, fmt.Errorf("invalid MBean name %q: expected domain:key=value[,key=value...]", name)
+	}
+
+	props := make(map[string]string)
+	for rest != "" {
+		var prop string
+		prop, rest = cutProperty(rest)
+		if prop == "*" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(prop, "=")
+		if !ok || key == "" {
+			return "", nil, fmt.Errorf("invalid MBean name %q: invalid key property %q", name, prop)
+		}
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = quotedValueReplacer.Replace(value[1 : len(value)-1])
+		}
+		props[key] = value
+	}
+	return domain, props, nil
+}
+
+var quotedValueReplacer = strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n", `\*`, "*", `\?`, "?")
+
+// cutProperty splits a list of key properties at the first comma which isn't
+// within a quoted value.
+func cutProperty(s string) (prop, rest string) {
+	var inQuote bool
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && inQuote:
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case c == ',' && !inQuote:
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
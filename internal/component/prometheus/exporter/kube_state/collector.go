@@ -0,0 +1,241 @@
+package kube_state //nolint:golint
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// resyncPeriod is how often informers resync their caches. Metrics are
+// computed from the caches when collected, so resyncs aren't needed.
+const resyncPeriod = 0
+
+var (
+	deploymentLabels = []string{"namespace", "deployment"}
+	podLabels        = []string{"namespace", "pod", "uid"}
+	containerLabels  = []string{"namespace", "pod", "uid", "container"}
+
+	deploymentSpecReplicasDesc        = prometheus.NewDesc("kube_deployment_spec_replicas", "Number of desired pods for a deployment.", deploymentLabels, nil)
+	deploymentReplicasDesc            = prometheus.NewDesc("kube_deployment_status_replicas", "The number of replicas per deployment.", deploymentLabels, nil)
+	deploymentReplicasReadyDesc       = prometheus.NewDesc("kube_deployment_status_replicas_ready", "The number of ready replicas per deployment.", deploymentLabels, nil)
+	deploymentReplicasAvailableDesc   = prometheus.NewDesc("kube_deployment_status_replicas_available", "The number of available replicas per deployment.", deploymentLabels, nil)
+	deploymentReplicasUnavailableDesc = prometheus.NewDesc("kube_deployment_status_replicas_unavailable", "The number of unavailable replicas per deployment.", deploymentLabels, nil)
+	deploymentReplicasUpdatedDesc     = prometheus.NewDesc("kube_deployment_status_replicas_updated", "The number of updated replicas per deployment.", deploymentLabels, nil)
+	deploymentGenerationDesc          = prometheus.NewDesc("kube_deployment_metadata_generation", "Sequence number representing a specific generation of the desired state.", deploymentLabels, nil)
+	deploymentObservedGenerationDesc  = prometheus.NewDesc("kube_deployment_status_observed_generation", "The generation observed by the deployment controller.", deploymentLabels, nil)
+
+	podPhaseDesc            = prometheus.NewDesc("kube_pod_status_phase", "The pods current phase.", append(podLabels, "phase"), nil)
+	podReadyDesc            = prometheus.NewDesc("kube_pod_status_ready", "Describes whether the pod is ready to serve requests.", append(podLabels, "condition"), nil)
+	podInfoDesc             = prometheus.NewDesc("kube_pod_info", "Information about pod.", append(podLabels, "node", "host_ip", "pod_ip"), nil)
+	containerReadyDesc      = prometheus.NewDesc("kube_pod_container_status_ready", "Describes whether the containers readiness check succeeded.", containerLabels, nil)
+	containerRestartsDesc   = prometheus.NewDesc("kube_pod_container_status_restarts_total", "The number of container restarts per container.", containerLabels, nil)
+	containerWaitingDesc    = prometheus.NewDesc("kube_pod_container_status_waiting_reason", "Describes the reason the container is currently in waiting state.", append(containerLabels, "reason"), nil)
+	containerTerminatedDesc = prometheus.NewDesc("kube_pod_container_status_last_terminated_reason", "Describes the last reason the container was in terminated state.", append(containerLabels, "reason"), nil)
+
+	nodeConditionDesc     = prometheus.NewDesc("kube_node_status_condition", "The condition of a cluster node.", []string{"node", "condition", "status"}, nil)
+	nodeUnschedulableDesc = prometheus.NewDesc("kube_node_spec_unschedulable", "Whether a node can schedule new pods.", []string{"node"}, nil)
+	nodeAllocatableDesc   = prometheus.NewDesc("kube_node_status_allocatable", "The allocatable for different resources of a node that are available for scheduling.", []string{"node", "resource", "unit"}, nil)
+)
+
+// podPhases are the phases reported by kube_pod_status_phase.
+var podPhases = []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown}
+
+// conditionStatuses are the statuses reported by condition metrics.
+var conditionStatuses = []corev1.ConditionStatus{corev1.ConditionTrue, corev1.ConditionFalse, corev1.ConditionUnknown}
+
+// collector computes the state metrics of Kubernetes objects from the caches
+// of shared informers.
+type collector struct {
+	log       log.Logger
+	factories []informers.SharedInformerFactory
+
+	deployments []appslisters.DeploymentLister
+	pods        []corelisters.PodLister
+	nodes       corelisters.NodeLister
+}
+
+func newCollector(l log.Logger, client kubernetes.Interface, args Arguments) *collector {
+	c := &collector{log: l}
+
+	enabled := make(map[string]bool, len(args.Resources))
+	for _, r := range args.Resources {
+		enabled[r] = true
+	}
+
+	// Namespaced objects are watched with one factory per namespace, while
+	// nodes are watched with a cluster-wide factory.
+	if enabled[ResourceDeployments] || enabled[ResourcePods] {
+		for _, ns := range args.namespaces() {
+			factory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod, informers.WithNamespace(ns))
+			if enabled[ResourceDeployments] {
+				c.deployments = append(c.deployments, factory.Apps().V1().Deployments().Lister())
+			}
+			if enabled[ResourcePods] {
+				c.pods = append(c.pods, factory.Core().V1().Pods().Lister())
+			}
+			c.factories = append(c.factories, factory)
+		}
+	}
+	if enabled[ResourceNodes] {
+		factory := informers.NewSharedInformerFactory(client, resyncPeriod)
+		c.nodes = factory.Core().V1().Nodes().Lister()
+		c.factories = append(c.factories, factory)
+	}
+	return c
+}
+
+// Run starts the informers, and runs until ctx is canceled.
+func (c *collector) Run(ctx context.Context) error {
+	for _, factory := range c.factories {
+		factory.Start(ctx.Done())
+	}
+	for _, factory := range c.factories {
+		for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+			if !synced && ctx.Err() == nil {
+				level.Warn(c.log).Log("msg", "informer cache failed to sync", "type", typ.String())
+			}
+		}
+	}
+
+	<-ctx.Done()
+	for _, factory := range c.factories {
+		factory.Shutdown()
+	}
+	return nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		deploymentSpecReplicasDesc, deploymentReplicasDesc, deploymentReplicasReadyDesc, deploymentReplicasAvailableDesc,
+		deploymentReplicasUnavailableDesc, deploymentReplicasUpdatedDesc, deploymentGenerationDesc, deploymentObservedGenerationDesc,
+		podPhaseDesc, podReadyDesc, podInfoDesc, containerReadyDesc, containerRestartsDesc, containerWaitingDesc, containerTerminatedDesc,
+		nodeConditionDesc, nodeUnschedulableDesc, nodeAllocatableDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for _, lister := range c.deployments {
+		deployments, err := lister.List(labels.Everything())
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to list deployments", "err", err)
+			continue
+		}
+		for _, d := range deployments {
+			collectDeployment(ch, d)
+		}
+	}
+
+	for _, lister := range c.pods {
+		pods, err := lister.List(labels.Everything())
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to list pods", "err", err)
+			continue
+		}
+		for _, p := range pods {
+			collectPod(ch, p)
+		}
+	}
+
+	if c.nodes != nil {
+		nodes, err := c.nodes.List(labels.Everything())
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to list nodes", "err", err)
+			return
+		}
+		for _, n := range nodes {
+			collectNode(ch, n)
+		}
+	}
+}
+
+func collectDeployment(ch chan<- prometheus.Metric, d *appsv1.Deployment) {
+	gauge := func(desc *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, d.Namespace, d.Name)
+	}
+
+	// Deployments default to a single replica when replicas isn't set.
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	gauge(deploymentSpecReplicasDesc, float64(replicas))
+	gauge(deploymentReplicasDesc, float64(d.Status.Replicas))
+	gauge(deploymentReplicasReadyDesc, float64(d.Status.ReadyReplicas))
+	gauge(deploymentReplicasAvailableDesc, float64(d.Status.AvailableReplicas))
+	gauge(deploymentReplicasUnavailableDesc, float64(d.Status.UnavailableReplicas))
+	gauge(deploymentReplicasUpdatedDesc, float64(d.Status.UpdatedReplicas))
+	gauge(deploymentGenerationDesc, float64(d.Generation))
+	gauge(deploymentObservedGenerationDesc, float64(d.Status.ObservedGeneration))
+}
+
+func collectPod(ch chan<- prometheus.Metric, p *corev1.Pod) {
+	uid := string(p.UID)
+
+	ch <- prometheus.MustNewConstMetric(podInfoDesc, prometheus.GaugeValue, 1, p.Namespace, p.Name, uid, p.Spec.NodeName, p.Status.HostIP, p.Status.PodIP)
+
+	for _, phase := range podPhases {
+		ch <- prometheus.MustNewConstMetric(podPhaseDesc, prometheus.GaugeValue, boolValue(p.Status.Phase == phase), p.Namespace, p.Name, uid, string(phase))
+	}
+
+	for _, cond := range p.Status.Conditions {
+		if cond.Type != corev1.PodReady {
+			continue
+		}
+		for _, status := range conditionStatuses {
+			ch <- prometheus.MustNewConstMetric(podReadyDesc, prometheus.GaugeValue, boolValue(cond.Status == status), p.Namespace, p.Name, uid, strings.ToLower(string(status)))
+		}
+	}
+
+	for _, cs := range p.Status.ContainerStatuses {
+		ch <- prometheus.MustNewConstMetric(containerReadyDesc, prometheus.GaugeValue, boolValue(cs.Ready), p.Namespace, p.Name, uid, cs.Name)
+		ch <- prometheus.MustNewConstMetric(containerRestartsDesc, prometheus.CounterValue, float64(cs.RestartCount), p.Namespace, p.Name, uid, cs.Name)
+		if w := cs.State.Waiting; w != nil && w.Reason != "" {
+			ch <- prometheus.MustNewConstMetric(containerWaitingDesc, prometheus.GaugeValue, 1, p.Namespace, p.Name, uid, cs.Name, w.Reason)
+		}
+		if t := cs.LastTerminationState.Terminated; t != nil && t.Reason != "" {
+			ch <- prometheus.MustNewConstMetric(containerTerminatedDesc, prometheus.GaugeValue, 1, p.Namespace, p.Name, uid, cs.Name, t.Reason)
+		}
+	}
+}
+
+func collectNode(ch chan<- prometheus.Metric, n *corev1.Node) {
+	for _, cond := range n.Status.Conditions {
+		for _, status := range conditionStatuses {
+			ch <- prometheus.MustNewConstMetric(nodeConditionDesc, prometheus.GaugeValue, boolValue(cond.Status == status), n.Name, string(cond.Type), strings.ToLower(string(status)))
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(nodeUnschedulableDesc, prometheus.GaugeValue, boolValue(n.Spec.Unschedulable), n.Name)
+
+	for name, quantity := range n.Status.Allocatable {
+		switch name {
+		case corev1.ResourceCPU:
+			ch <- prometheus.MustNewConstMetric(nodeAllocatableDesc, prometheus.GaugeValue, float64(quantity.MilliValue())/1000, n.Name, "cpu", "core")
+		case corev1.ResourceMemory:
+			ch <- prometheus.MustNewConstMetric(nodeAllocatableDesc, prometheus.GaugeValue, float64(quantity.Value()), n.Name, "memory", "byte")
+		case corev1.ResourcePods:
+			ch <- prometheus.MustNewConstMetric(nodeAllocatableDesc, prometheus.GaugeValue, float64(quantity.Value()), n.Name, "pods", "integer")
+		}
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+// Package kube_state implements the prometheus.exporter.kube_state
+// component.
+package kube_state //nolint:golint
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/internal/component"
+	commonk8s "github.com/grafana/agent/internal/component/common/kubernetes"
+	"github.com/grafana/agent/internal/component/prometheus/exporter"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/static/integrations"
+	"k8s.io/client-go/kubernetes"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.kube_state",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "kube_state"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+
+	restConfig, err := a.Client.BuildRESTConfig(opts.Logger)
+	if err != nil {
+		return nil, "", fmt.Errorf("building Kubernetes client config: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+
+	c := newCollector(opts.Logger, clientset, a)
+	return integrations.NewCollectorIntegration(
+		"kube_state",
+		integrations.WithCollectors(c),
+		integrations.WithRunner(c.Run),
+	), defaultInstanceKey, nil
+}
+
+// Resources which metrics can be generated for.
+const (
+	ResourceDeployments = "deployments"
+	ResourcePods        = "pods"
+	ResourceNodes       = "nodes"
+)
+
+// DefaultArguments holds non-zero default options for Arguments when it is
+// unmarshaled from river.
+var DefaultArguments = Arguments{
+	Resources: []string{ResourceDeployments, ResourcePods, ResourceNodes},
+	Client:    commonk8s.DefaultClientArguments,
+}
+
+// Arguments configures the prometheus.exporter.kube_state component.
+type Arguments struct {
+	Resources  []string `river:"resources,attr,optional"`
+	Namespaces []string `river:"namespaces,attr,optional"`
+
+	// Client settings to connect to Kubernetes.
+	Client commonk8s.ClientArguments `river:"client,block,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+	a.Resources = append([]string(nil), DefaultArguments.Resources...)
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Resources) == 0 {
+		return fmt.Errorf("resources must not be empty")
+	}
+	for _, r := range a.Resources {
+		switch r {
+		case ResourceDeployments, ResourcePods, ResourceNodes:
+		default:
+			return fmt.Errorf("unsupported resource %q: supported resources are %s, %s and %s", r, ResourceDeployments, ResourcePods, ResourceNodes)
+		}
+	}
+	for _, ns := range a.Namespaces {
+		if ns == "" {
+			return fmt.Errorf("namespaces must not contain empty strings")
+		}
+	}
+	return nil
+}
+
+// namespaces returns the namespaces to watch namespaced objects in. An empty
+// string means to watch all namespaces.
+func (a *Arguments) namespaces() []string {
+	if len(a.Namespaces) == 0 {
+		return []string{""}
+	}
+	return a.Namespaces
+}
@@ -0,0 +1,138 @@
+package kube_state //nolint:golint
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`namespaces = ["default"]`), &args))
+	require.Equal(t, []string{ResourceDeployments, ResourcePods, ResourceNodes}, args.Resources)
+	require.Equal(t, []string{"default"}, args.Namespaces)
+
+	require.EqualError(t, river.Unmarshal([]byte(`resources = ["services"]`), &args),
+		`unsupported resource "services": supported resources are deployments, pods and nodes`)
+}
+
+func TestCollect(t *testing.T) {
+	replicas := int32(3)
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{Replicas: 3, ReadyReplicas: 2, UnavailableReplicas: 1, ObservedGeneration: 2},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "ignored"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-0", UID: "1234"},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:                 "app",
+					RestartCount:         4,
+					State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled"}},
+				}},
+			},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1500m"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	)
+
+	args := DefaultArguments
+	args.Namespaces = []string{"default"}
+	c := newCollector(util.TestLogger(t), client, args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = c.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(c))
+
+	expect := `
+# HELP kube_deployment_spec_replicas Number of desired pods for a deployment.
+# TYPE kube_deployment_spec_replicas gauge
+kube_deployment_spec_replicas{deployment="web",namespace="default"} 3
+# HELP kube_deployment_status_replicas_unavailable The number of unavailable replicas per deployment.
+# TYPE kube_deployment_status_replicas_unavailable gauge
+kube_deployment_status_replicas_unavailable{deployment="web",namespace="default"} 1
+# HELP kube_pod_status_phase The pods current phase.
+# TYPE kube_pod_status_phase gauge
+kube_pod_status_phase{namespace="default",phase="Failed",pod="web-0",uid="1234"} 0
+kube_pod_status_phase{namespace="default",phase="Pending",pod="web-0",uid="1234"} 0
+kube_pod_status_phase{namespace="default",phase="Running",pod="web-0",uid="1234"} 1
+kube_pod_status_phase{namespace="default",phase="Succeeded",pod="web-0",uid="1234"} 0
+kube_pod_status_phase{namespace="default",phase="Unknown",pod="web-0",uid="1234"} 0
+# HELP kube_pod_status_ready Describes whether the pod is ready to serve requests.
+# TYPE kube_pod_status_ready gauge
+kube_pod_status_ready{condition="false",namespace="default",pod="web-0",uid="1234"} 1
+kube_pod_status_ready{condition="true",namespace="default",pod="web-0",uid="1234"} 0
+kube_pod_status_ready{condition="unknown",namespace="default",pod="web-0",uid="1234"} 0
+# HELP kube_pod_container_status_restarts_total The number of container restarts per container.
+# TYPE kube_pod_container_status_restarts_total counter
+kube_pod_container_status_restarts_total{container="app",namespace="default",pod="web-0",uid="1234"} 4
+# HELP kube_pod_container_status_waiting_reason Describes the reason the container is currently in waiting state.
+# TYPE kube_pod_container_status_waiting_reason gauge
+kube_pod_container_status_waiting_reason{container="app",namespace="default",pod="web-0",reason="CrashLoopBackOff",uid="1234"} 1
+# HELP kube_pod_container_status_last_terminated_reason Describes the last reason the container was in terminated state.
+# TYPE kube_pod_container_status_last_terminated_reason gauge
+kube_pod_container_status_last_terminated_reason{container="app",namespace="default",pod="web-0",reason="OOMKilled",uid="1234"} 1
+# HELP kube_node_status_condition The condition of a cluster node.
+# TYPE kube_node_status_condition gauge
+kube_node_status_condition{condition="Ready",node="node-1",status="false"} 0
+kube_node_status_condition{condition="Ready",node="node-1",status="true"} 1
+kube_node_status_condition{condition="Ready",node="node-1",status="unknown"} 0
+# HELP kube_node_status_allocatable The allocatable for different resources of a node that are available for scheduling.
+# TYPE kube_node_status_allocatable gauge
+kube_node_status_allocatable{node="node-1",resource="cpu",unit="core"} 1.5
+kube_node_status_allocatable{node="node-1",resource="memory",unit="byte"} 1.073741824e+09
+`
+	metrics := []string{
+		"kube_deployment_spec_replicas",
+		"kube_deployment_status_replicas_unavailable",
+		"kube_pod_status_phase",
+		"kube_pod_status_ready",
+		"kube_pod_container_status_restarts_total",
+		"kube_pod_container_status_waiting_reason",
+		"kube_pod_container_status_last_terminated_reason",
+		"kube_node_status_condition",
+		"kube_node_status_allocatable",
+	}
+	require.Eventually(t, func() bool {
+		return testutil.GatherAndCompare(reg, strings.NewReader(expect), metrics...) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
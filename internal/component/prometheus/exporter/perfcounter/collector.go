@@ -0,0 +1,223 @@
+package perfcounter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// object is a snapshot of the counters of a performance object.
+type object struct {
+	name string
+
+	// instances of the object. Objects without instances have a single
+	// instance with an empty name.
+	instances []instance
+}
+
+type instance struct {
+	name     string
+	counters []counterValue
+}
+
+type counterValue struct {
+	name string
+	// value is the raw value of the counter. Timers, for example, are
+	// reported in ticks of 100 nanoseconds.
+	value float64
+	// isCounter is true if the value is monotonically increasing.
+	isCounter bool
+}
+
+// snapshotFunc returns a snapshot of the given performance objects, keyed by
+// lowercased object name. Objects which don't exist are omitted.
+type snapshotFunc func(objects []string) (map[string]*object, error)
+
+// compiledCounter is a Counter with its path parsed.
+type compiledCounter struct {
+	Counter
+	path    counterPath
+	exclude []*pattern
+}
+
+// collector collects performance counters.
+type collector struct {
+	log           log.Logger
+	prefix        string
+	instanceLabel string
+	counters      []compiledCounter
+	objects       []string
+	query         snapshotFunc
+}
+
+func newCollector(l log.Logger, args Arguments, query snapshotFunc) (*collector, error) {
+	c := &collector{
+		log:           l,
+		prefix:        args.MetricPrefix,
+		instanceLabel: args.InstanceLabel,
+		query:         query,
+	}
+
+	seen := make(map[string]struct{})
+	for _, counter := range args.Counters {
+		p, err := parsePath(counter.Path)
+		if err != nil {
+			return nil, err
+		}
+		cc := compiledCounter{Counter: counter, path: p}
+		for _, e := range counter.ExcludeInstances {
+			cc.exclude = append(cc.exclude, newPattern(e))
+		}
+		c.counters = append(c.counters, cc)
+
+		if _, ok := seen[strings.ToLower(p.object)]; !ok {
+			seen[strings.ToLower(p.object)] = struct{}{}
+			c.objects = append(c.objects, p.object)
+		}
+	}
+	return c, nil
+}
+
+// Describe implements prometheus.Collector. The collector is unchecked, as
+// the metrics it collects depend on the instances of the objects.
+func (c *collector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	snapshot, err := c.query(c.objects)
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to query performance counters", "err", err)
+		return
+	}
+
+	var (
+		seen = make(map[string]struct{})
+		help = make(map[string]string)
+	)
+	for _, cc := range c.counters {
+		obj, ok := snapshot[strings.ToLower(cc.path.object)]
+		if !ok {
+			level.Debug(c.log).Log("msg", "performance object not found", "object", cc.path.object)
+			continue
+		}
+
+		names := instanceNames(obj.instances)
+		for i, inst := range obj.instances {
+			if !cc.matchInstance(names[i]) {
+				continue
+			}
+
+			for _, cv := range inst.counters {
+				if !cc.path.counter.match(cv.name) {
+					continue
+				}
+
+				name := c.metricName(cc, obj.name, cv.name)
+				var labelNames, labelValues []string
+				if names[i] != "" {
+					labelNames, labelValues = []string{c.instanceLabel}, []string{names[i]}
+				}
+
+				key := name + "\xff" + strings.Join(labelValues, "\xff")
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = struct{}{}
+				if _, ok := help[name]; !ok {
+					help[name] = cc.Help
+					if help[name] == "" {
+						help[name] = fmt.Sprintf(`Performance counter \%s\%s.`, obj.name, cv.name)
+					}
+				}
+
+				valueType := prometheus.GaugeValue
+				switch {
+				case cc.Type == TypeCounter, cc.Type == "" && cv.isCounter:
+					valueType = prometheus.CounterValue
+				}
+				metric, err := prometheus.NewConstMetric(
+					prometheus.NewDesc(name, help[name], labelNames, nil),
+					valueType, cv.value*cc.Scale, labelValues...,
+				)
+				if err != nil {
+					level.Debug(c.log).Log("msg", "invalid performance counter metric", "metric", name, "err", err)
+					continue
+				}
+				ch <- metric
+			}
+		}
+	}
+}
+
+// matchInstance returns true if the instance with the given name is selected
+// by the counter.
+func (cc *compiledCounter) matchInstance(name string) bool {
+	if cc.path.instance == nil {
+		return name == ""
+	}
+	if !cc.path.instance.match(name) {
+		return false
+	}
+	for _, e := range cc.exclude {
+		if e.match(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// metricName returns the name of the metric of a counter. By default, it is
+// made of the metric prefix, object name and counter name.
+func (c *collector) metricName(cc compiledCounter, object, counter string) string {
+	if cc.MetricName != "" {
+		return cc.MetricName
+	}
+	parts := []string{sanitize(object), sanitize(counter)}
+	if c.prefix != "" {
+		parts = append([]string{c.prefix}, parts...)
+	}
+	return strings.Join(parts, "_")
+}
+
+// instanceNames returns unique names for instances. Like the Windows
+// Performance Data Helper, instances which share a name with a previous
+// instance get a #N suffix.
+func instanceNames(instances []instance) []string {
+	var (
+		names  = make([]string, len(instances))
+		counts = make(map[string]int, len(instances))
+	)
+	for i, inst := range instances {
+		n := counts[inst.name]
+		counts[inst.name]++
+		if n == 0 || inst.name == "" {
+			names[i] = inst.name
+			continue
+		}
+		names[i] = fmt.Sprintf("%s#%d", inst.name, n)
+	}
+	return names
+}
+
+// sanitize converts an object or counter name into a metric name fragment.
+// Names are lowercased, and sequences of characters which aren't valid in
+// metric names are replaced with a single underscore.
+func sanitize(s string) string {
+	var sb strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if underscore && sb.Len() > 0 {
+				sb.WriteRune('_')
+			}
+			underscore = false
+			sb.WriteRune(r)
+			continue
+		}
+		underscore = true
+	}
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+package perfcounter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// counterPath is a parsed performance counter path of the form
+// \Object(Instance)\Counter. Instance and counter names may contain the *
+// and ? wildcards.
+type counterPath struct {
+	object string
+
+	// instance is nil if the path doesn't select any instance, which is the
+	// case for objects without instances such as Memory.
+	instance *pattern
+	counter  *pattern
+}
+
+// parsePath parses a performance counter path.
+func parsePath(path string) (counterPath, error) {
+	if !strings.HasPrefix(path, `\`) || strings.HasPrefix(path, `\\`) {
+		return counterPath{}, fmt.Errorf(`invalid counter path %q: expected \Object(Instance)\Counter`, path)
+	}
+
+	idx := strings.LastIndex(path, `\`)
+	object, counter := path[1:idx], path[idx+1:]
+	if object == "" || counter == "" {
+		return counterPath{}, fmt.Errorf(`invalid counter path %q: expected \Object(Instance)\Counter`, path)
+	}
+
+	var p counterPath
+	if open := strings.Index(object, "("); open >= 0 {
+		if !strings.HasSuffix(object, ")") {
+			return counterPath{}, fmt.Errorf("invalid counter path %q: unterminated instance name", path)
+		}
+		instance := object[open+1 : len(object)-1]
+		object = object[:open]
+		if instance == "" || object == "" {
+			return counterPath{}, fmt.Errorf(`invalid counter path %q: expected \Object(Instance)\Counter`, path)
+		}
+		p.instance = newPattern(instance)
+	}
+	if strings.ContainsAny(object, "*?") {
+		return counterPath{}, fmt.Errorf("invalid counter path %q: object names can't contain wildcards", path)
+	}
+
+	p.object = object
+	p.counter = newPattern(counter)
+	return p, nil
+}
+
+// pattern matches names case-insensitively, where * matches any sequence of
+// characters and ? matches any single character.
+type pattern struct {
+	wildcard bool
+	literal  string
+	re       *regexp.Regexp
+}
+
+func newPattern(s string) *pattern {
+	if !strings.ContainsAny(s, "*?") {
+		return &pattern{literal: s}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	for _, r := range s {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return &pattern{wildcard: true, re: regexp.MustCompile(sb.String())}
+}
+
+func (p *pattern) match(s string) bool {
+	if !p.wildcard {
+		return strings.EqualFold(p.literal, s)
+	}
+	return p.re.MatchString(s)
+}
@@ -0,0 +1,121 @@
+package perfcounter
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus/exporter"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/static/integrations"
+	"github.com/prometheus/common/model"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.exporter.perfcounter",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   exporter.Exports{},
+
+		Build: exporter.New(createExporter, "perfcounter"),
+	})
+}
+
+func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
+	a := args.(Arguments)
+	if runtime.GOOS != "windows" {
+		level.Warn(opts.Logger).Log("msg", "the perfcounter exporter only works on Windows; enabling it otherwise will do nothing")
+	}
+
+	c, err := newCollector(opts.Logger, a, querySnapshot)
+	if err != nil {
+		return nil, "", err
+	}
+	return integrations.NewCollectorIntegration("perfcounter", integrations.WithCollectors(c)), defaultInstanceKey, nil
+}
+
+// Metric types which can be assigned to counters.
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// DefaultArguments holds non-zero default options for Arguments when it is
+// unmarshaled from river.
+var DefaultArguments = Arguments{
+	MetricPrefix:  "windows_perf",
+	InstanceLabel: "instance_name",
+}
+
+// Arguments configures the prometheus.exporter.perfcounter component.
+type Arguments struct {
+	Counters      []Counter `river:"counter,block"`
+	MetricPrefix  string    `river:"metric_prefix,attr,optional"`
+	InstanceLabel string    `river:"instance_label,attr,optional"`
+}
+
+// Counter selects performance counters to collect.
+type Counter struct {
+	Path             string   `river:"path,attr"`
+	MetricName       string   `river:"metric_name,attr,optional"`
+	Scale            float64  `river:"scale,attr,optional"`
+	Type             string   `river:"type,attr,optional"`
+	Help             string   `river:"help,attr,optional"`
+	ExcludeInstances []string `river:"exclude_instances,attr,optional"`
+}
+
+// DefaultCounter holds non-zero default options for Counter when it is
+// unmarshaled from river.
+var DefaultCounter = Counter{
+	Scale: 1,
+}
+
+// SetToDefault implements river.Defaulter.
+func (c *Counter) SetToDefault() {
+	*c = DefaultCounter
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Counters) == 0 {
+		return errors.New("at least one counter block must be set")
+	}
+	if a.MetricPrefix != "" && !model.IsValidMetricName(model.LabelValue(a.MetricPrefix)) {
+		return fmt.Errorf("invalid metric_prefix %q", a.MetricPrefix)
+	}
+	if !model.LabelName(a.InstanceLabel).IsValid() {
+		return fmt.Errorf("invalid instance_label %q", a.InstanceLabel)
+	}
+
+	for _, c := range a.Counters {
+		p, err := parsePath(c.Path)
+		if err != nil {
+			return err
+		}
+		if c.MetricName != "" {
+			if !model.IsValidMetricName(model.LabelValue(c.MetricName)) {
+				return fmt.Errorf("counter %q: invalid metric_name %q", c.Path, c.MetricName)
+			}
+			if p.counter.wildcard {
+				return fmt.Errorf("counter %q: metric_name can't be set when the counter name contains wildcards", c.Path)
+			}
+		}
+		switch c.Type {
+		case "", TypeGauge, TypeCounter:
+		default:
+			return fmt.Errorf("counter %q: invalid type %q", c.Path, c.Type)
+		}
+		if c.Scale == 0 {
+			return fmt.Errorf("counter %q: scale must not be 0", c.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package perfcounter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	riverCfg := `
+		instance_label = "core"
+
+		counter {
+			path              = "\\Processor(*)\\% Processor Time"
+			metric_name       = "cpu_time_seconds_total"
+			scale             = 0.0000001
+			type              = "counter"
+			exclude_instances = ["_Total"]
+		}
+		counter {
+			path = "\\Memory\\Available Bytes"
+		}
+`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(riverCfg), &args))
+	require.Equal(t, "windows_perf", args.MetricPrefix)
+	require.Equal(t, "core", args.InstanceLabel)
+	require.Len(t, args.Counters, 2)
+	require.Equal(t, 0.0000001, args.Counters[0].Scale)
+	require.Equal(t, []string{"_Total"}, args.Counters[0].ExcludeInstances)
+	require.Equal(t, 1.0, args.Counters[1].Scale)
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		path, metricName, err string
+	}{
+		{path: `Memory\Available Bytes`, err: `invalid counter path "Memory\\Available Bytes": expected \Object(Instance)\Counter`},
+		{path: `\\host\Memory\Available Bytes`, err: `invalid counter path "\\\\host\\Memory\\Available Bytes": expected \Object(Instance)\Counter`},
+		{path: `\Process(sql*\Working Set`, err: `invalid counter path "\\Process(sql*\\Working Set": unterminated instance name`},
+		{path: `\Proc*(*)\Working Set`, err: `invalid counter path "\\Proc*(*)\\Working Set": object names can't contain wildcards`},
+		{path: `\Processor(*)\*`, metricName: "cpu", err: `counter "\\Processor(*)\\*": metric_name can't be set when the counter name contains wildcards`},
+	}
+	for _, tc := range tt {
+		args := DefaultArguments
+		args.Counters = []Counter{{Path: tc.path, MetricName: tc.metricName, Scale: 1}}
+		require.EqualError(t, args.Validate(), tc.err)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	snapshot := map[string]*object{
+		"processor": {
+			name: "Processor",
+			instances: []instance{
+				{name: "0", counters: []counterValue{{name: "% Processor Time", value: 2e7, isCounter: true}, {name: "Interrupts/sec", value: 10, isCounter: true}}},
+				{name: "1", counters: []counterValue{{name: "% Processor Time", value: 3e7, isCounter: true}, {name: "Interrupts/sec", value: 20, isCounter: true}}},
+				{name: "_Total", counters: []counterValue{{name: "% Processor Time", value: 5e7, isCounter: true}, {name: "Interrupts/sec", value: 30, isCounter: true}}},
+			},
+		},
+		"process": {
+			name: "Process",
+			instances: []instance{
+				{name: "sqlservr", counters: []counterValue{{name: "Working Set", value: 100}}},
+				{name: "sqlservr", counters: []counterValue{{name: "Working Set", value: 200}}},
+				{name: "explorer", counters: []counterValue{{name: "Working Set", value: 300}}},
+			},
+		},
+		"memory": {
+			name:      "Memory",
+			instances: []instance{{counters: []counterValue{{name: "Available Bytes", value: 4096}}}},
+		},
+	}
+
+	args := DefaultArguments
+	args.Counters = []Counter{
+		{Path: `\Processor(*)\% Processor Time`, MetricName: "cpu_time_seconds_total", Scale: 1e-7, ExcludeInstances: []string{"_total"}},
+		{Path: `\Processor(_Total)\*`, Scale: 1, Type: TypeGauge},
+		{Path: `\Process(sql*)\Working Set`, Scale: 1, Help: "Working set of SQL Server."},
+		{Path: `\Memory\Available Bytes`, Scale: 1},
+		{Path: `\MSSQL$SQLEXPRESS:Buffer Manager\Page life expectancy`, Scale: 1},
+	}
+
+	var queried []string
+	c, err := newCollector(util.TestLogger(t), args, func(objects []string) (map[string]*object, error) {
+		queried = objects
+		return snapshot, nil
+	})
+	require.NoError(t, err)
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(c))
+
+	expect := `
+# HELP cpu_time_seconds_total Performance counter \\Processor\\% Processor Time.
+# TYPE cpu_time_seconds_total counter
+cpu_time_seconds_total{instance_name="0"} 2
+cpu_time_seconds_total{instance_name="1"} 3
+# HELP windows_perf_memory_available_bytes Performance counter \\Memory\\Available Bytes.
+# TYPE windows_perf_memory_available_bytes gauge
+windows_perf_memory_available_bytes 4096
+# HELP windows_perf_process_working_set Working set of SQL Server.
+# TYPE windows_perf_process_working_set gauge
+windows_perf_process_working_set{instance_name="sqlservr"} 100
+windows_perf_process_working_set{instance_name="sqlservr#1"} 200
+# HELP windows_perf_processor_interrupts_sec Performance counter \\Processor\\Interrupts/sec.
+# TYPE windows_perf_processor_interrupts_sec gauge
+windows_perf_processor_interrupts_sec{instance_name="_Total"} 30
+# HELP windows_perf_processor_processor_time Performance counter \\Processor\\% Processor Time.
+# TYPE windows_perf_processor_processor_time gauge
+windows_perf_processor_processor_time{instance_name="_Total"} 5e+07
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect)))
+	require.Equal(t, []string{"Processor", "Process", "Memory", "MSSQL$SQLEXPRESS:Buffer Manager"}, queried)
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package perfcounter
+
+import "errors"
+
+// querySnapshot always fails, since performance counters are only available
+// on Windows.
+func querySnapshot([]string) (map[string]*object, error) {
+	return nil, errors.New("performance counters are only available on Windows")
+}
@@ -0,0 +1,46 @@
+package perfcounter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus-community/windows_exporter/pkg/perflib"
+)
+
+// querySnapshot reads the given performance objects from the registry.
+func querySnapshot(objects []string) (map[string]*object, error) {
+	indices := make([]string, 0, len(objects))
+	for _, name := range objects {
+		// Objects which aren't registered have no index, and are omitted from
+		// the snapshot.
+		if idx := perflib.CounterNameTable.LookupIndex(name); idx != 0 {
+			indices = append(indices, strconv.Itoa(int(idx)))
+		}
+	}
+	if len(indices) == 0 {
+		return nil, nil
+	}
+
+	perfObjects, err := perflib.QueryPerformanceData(strings.Join(indices, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(map[string]*object, len(perfObjects))
+	for _, po := range perfObjects {
+		obj := &object{name: po.Name}
+		for _, pi := range po.Instances {
+			inst := instance{name: pi.Name}
+			for _, pc := range pi.Counters {
+				inst.counters = append(inst.counters, counterValue{
+					name:      pc.Def.Name,
+					value:     float64(pc.Value),
+					isCounter: pc.Def.IsCounter,
+				})
+			}
+			obj.instances = append(obj.instances, inst)
+		}
+		snapshot[strings.ToLower(po.Name)] = obj
+	}
+	return snapshot, nil
+}
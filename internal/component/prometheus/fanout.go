@@ -30,8 +30,17 @@ type Fanout struct {
 	ls             labelstore.LabelStore
 }
 
-// NewFanout creates a fanout appendable.
-func NewFanout(children []storage.Appendable, componentID string, register prometheus.Registerer, ls labelstore.LabelStore) *Fanout {
+// FanoutMetrics are the metrics of a fanout. Components with several fanouts
+// share one FanoutMetrics between them, since the metrics can only be
+// registered once.
+type FanoutMetrics struct {
+	writeLatency   prometheus.Histogram
+	samplesCounter prometheus.Counter
+}
+
+// NewFanoutMetrics creates the metrics of a fanout and registers them to
+// register.
+func NewFanoutMetrics(register prometheus.Registerer) *FanoutMetrics {
 	wl := prometheus.NewHistogram(prometheus.HistogramOpts{
 		Name: "agent_prometheus_fanout_latency",
 		Help: "Write latency for sending to direct and indirect components",
@@ -44,11 +53,22 @@ func NewFanout(children []storage.Appendable, componentID string, register prome
 	})
 	_ = register.Register(s)
 
+	return &FanoutMetrics{writeLatency: wl, samplesCounter: s}
+}
+
+// NewFanout creates a fanout appendable.
+func NewFanout(children []storage.Appendable, componentID string, register prometheus.Registerer, ls labelstore.LabelStore) *Fanout {
+	return NewFanoutWithMetrics(children, componentID, NewFanoutMetrics(register), ls)
+}
+
+// NewFanoutWithMetrics creates a fanout appendable which records to the
+// existing metrics m.
+func NewFanoutWithMetrics(children []storage.Appendable, componentID string, m *FanoutMetrics, ls labelstore.LabelStore) *Fanout {
 	return &Fanout{
 		children:       children,
 		componentID:    componentID,
-		writeLatency:   wl,
-		samplesCounter: s,
+		writeLatency:   m.writeLatency,
+		samplesCounter: m.samplesCounter,
 		ls:             ls,
 	}
 }
@@ -0,0 +1,378 @@
+// Package query implements the prometheus.query component.
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	common_config "github.com/grafana/agent/internal/component/common/config"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/egress"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/useragent"
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	prom_config "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+)
+
+var userAgent = useragent.Get()
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.query",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the prometheus.query
+// component.
+type Arguments struct {
+	URL          string               `river:"url,attr"`
+	Interval     time.Duration        `river:"interval,attr,optional"`
+	Timeout      time.Duration        `river:"timeout,attr,optional"`
+	Headers      map[string]string    `river:"headers,attr,optional"`
+	ForwardTo    []storage.Appendable `river:"forward_to,attr,optional"`
+	MetricPrefix string               `river:"metric_prefix,attr,optional"`
+	Queries      []QueryArguments     `river:"query,block"`
+
+	Client common_config.HTTPClientConfig `river:"client,block,optional"`
+}
+
+// QueryArguments configures a query run by the prometheus.query component.
+type QueryArguments struct {
+	Name string `river:",label"`
+	Expr string `river:"expr,attr"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval: 1 * time.Minute,
+	Timeout:  30 * time.Second,
+	Client:   common_config.DefaultHTTPClientConfig,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if _, err := url.Parse(args.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if args.Timeout > args.Interval {
+		return fmt.Errorf("timeout must not be greater than interval")
+	}
+
+	names := make(map[string]struct{}, len(args.Queries))
+	for _, q := range args.Queries {
+		if _, ok := names[q.Name]; ok {
+			return fmt.Errorf("query %q is defined more than once", q.Name)
+		}
+		names[q.Name] = struct{}{}
+
+		if q.Expr == "" {
+			return fmt.Errorf("query %q: expr must not be empty", q.Name)
+		}
+		if !model.IsValidMetricName(model.LabelValue(args.MetricPrefix + q.Name)) {
+			return fmt.Errorf("query %q: %q is not a valid metric name", q.Name, args.MetricPrefix+q.Name)
+		}
+	}
+	return nil
+}
+
+// Exports holds the values exported by the prometheus.query component.
+type Exports struct {
+	Results map[string][]Sample `river:"results,attr"`
+}
+
+// Sample is a single sample of the result of a query.
+type Sample struct {
+	Labels map[string]string `river:"labels,attr"`
+	Value  float64           `river:"value,attr"`
+}
+
+// Component implements the prometheus.query component.
+type Component struct {
+	log    log.Logger
+	opts   component.Options
+	fanout *agentprom.Fanout
+
+	mut         sync.Mutex
+	args        Arguments
+	api         promv1.API
+	lastRun     time.Time
+	lastExports Exports // Used for determining whether exports should be updated
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new prometheus.query component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	data, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		log:     opts.Logger,
+		opts:    opts,
+		fanout:  agentprom.NewFanout(args.ForwardTo, opts.ID, opts.Registerer, ls),
+		updated: make(chan struct{}, 1),
+
+		health: component.Health{
+			Health:     component.HealthTypeUnknown,
+			Message:    "component started",
+			UpdateTime: time.Now(),
+		},
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.nextRun()):
+			c.runQueries(ctx)
+		case <-c.updated:
+			// no-op; force the next wait to be reread.
+		}
+	}
+}
+
+// nextRun returns how long to wait to run queries given the last time they
+// were run. nextRun returns 0 if queries should be run immediately.
+func (c *Component) nextRun() time.Duration {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	nextRun := c.lastRun.Add(c.args.Interval)
+	now := time.Now()
+
+	if now.After(nextRun) {
+		return 0
+	}
+	return nextRun.Sub(now)
+}
+
+// runQueries runs every query, updates the exports with their results, and
+// forwards the results to the receivers. c.mut must not be held when
+// calling. After running queries, the component's health is updated with the
+// failures, if any.
+func (c *Component) runQueries(ctx context.Context) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := time.Now()
+	c.lastRun = now
+
+	var (
+		errs    []error
+		results = make(map[string][]Sample, len(c.args.Queries))
+		app     = c.fanout.Appender(ctx)
+		ts      = timestamp.FromTime(now)
+	)
+	for _, q := range c.args.Queries {
+		samples, err := c.runQuery(ctx, q, now)
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to run query", "query", q.Name, "err", err)
+			errs = append(errs, fmt.Errorf("query %q: %w", q.Name, err))
+			continue
+		}
+		results[q.Name] = samples
+
+		if err := appendSamples(app, c.args.MetricPrefix+q.Name, samples, ts); err != nil {
+			level.Error(c.log).Log("msg", "failed to append query results", "query", q.Name, "err", err)
+			errs = append(errs, fmt.Errorf("query %q: appending results: %w", q.Name, err))
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Error(c.log).Log("msg", "failed to forward query results", "err", err)
+		errs = append(errs, fmt.Errorf("forwarding results: %w", err))
+	}
+
+	newExports := Exports{Results: results}
+	if !reflect.DeepEqual(c.lastExports, newExports) {
+		c.opts.OnStateChange(newExports)
+	}
+	c.lastExports = newExports
+
+	c.updateHealth(errors.Join(errs...))
+}
+
+// runQuery runs a single instant query at ts.
+func (c *Component) runQuery(ctx context.Context, q QueryArguments, ts time.Time) ([]Sample, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.args.Timeout)
+	defer cancel()
+
+	res, warnings, err := c.api.Query(ctx, q.Expr, ts)
+	if err != nil {
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		level.Warn(c.log).Log("msg", "query returned warnings", "query", q.Name, "warnings", strings.Join(warnings, "; "))
+	}
+
+	switch res := res.(type) {
+	case model.Vector:
+		// Sort samples so that exports only change when results change.
+		sort.Slice(res, func(i, j int) bool {
+			return res[i].Metric.String() < res[j].Metric.String()
+		})
+		samples := make([]Sample, 0, len(res))
+		for _, s := range res {
+			lset := make(map[string]string, len(s.Metric))
+			for name, value := range s.Metric {
+				lset[string(name)] = string(value)
+			}
+			samples = append(samples, Sample{Labels: lset, Value: float64(s.Value)})
+		}
+		return samples, nil
+	case *model.Scalar:
+		return []Sample{{Labels: map[string]string{}, Value: float64(res.Value)}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported result type %s: queries must return an instant vector or a scalar", res.Type())
+	}
+}
+
+// appendSamples appends samples as series of the metric name, replacing the
+// metric names of samples.
+func appendSamples(app storage.Appender, name string, samples []Sample, ts int64) error {
+	for _, s := range samples {
+		lb := labels.NewScratchBuilder(len(s.Labels) + 1)
+		lb.Add(labels.MetricName, name)
+		for n, v := range s.Labels {
+			if n != labels.MetricName {
+				lb.Add(n, v)
+			}
+		}
+		lb.Sort()
+		if _, err := app.Append(0, lb.Labels(), ts, s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Component) updateHealth(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+
+	if err == nil {
+		c.health = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "ran queries",
+			UpdateTime: time.Now(),
+		}
+	} else {
+		c.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("running queries failed: %s", err),
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	cli, err := prom_config.NewClientFromConfig(
+		*newArgs.Client.Convert(),
+		c.opts.ID,
+		prom_config.WithUserAgent(userAgent),
+		egress.HTTPClientOption(),
+	)
+	if err != nil {
+		return err
+	}
+	if len(newArgs.Headers) > 0 {
+		cli.Transport = &headersRoundTripper{headers: newArgs.Headers, next: cli.Transport}
+	}
+	queryAPI, err := api.NewClient(api.Config{Address: newArgs.URL, Client: cli})
+	if err != nil {
+		return err
+	}
+
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = newArgs
+	c.api = promv1.NewAPI(queryAPI)
+
+	// Send an updated event if one wasn't already read.
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+// headersRoundTripper sets headers on every request, such as the
+// X-Scope-OrgID header used to select the tenant of Mimir.
+type headersRoundTripper struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (rt *headersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range rt.headers {
+		req.Header.Set(name, value)
+	}
+	return rt.next.RoundTrip(req)
+}
@@ -0,0 +1,123 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	riverCfg := `
+		url           = "http://mimir:8080/prometheus"
+		metric_prefix = "agent:"
+
+		query "availability" {
+			expr = "sum(rate(http_requests_total{code!~\"5..\"}[5m])) / sum(rate(http_requests_total[5m]))"
+		}
+`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(riverCfg), &args))
+	require.Equal(t, time.Minute, args.Interval)
+	require.Len(t, args.Queries, 1)
+	require.Equal(t, "availability", args.Queries[0].Name)
+
+	require.EqualError(t, river.Unmarshal([]byte(`
+		url           = "http://mimir:8080/prometheus"
+		metric_prefix = "agent-"
+
+		query "availability" {
+			expr = "up"
+		}
+	`), &args), `query "availability": "agent-availability" is not a valid metric name`)
+}
+
+func TestRunQueries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/prometheus/api/v1/query", r.URL.Path)
+		require.Equal(t, "tenant-1", r.Header.Get("X-Scope-OrgID"))
+		require.NoError(t, r.ParseForm())
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Form.Get("query") {
+		case "up":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"up","job":"b"},"value":[1000,"0"]},
+				{"metric":{"__name__":"up","job":"a"},"value":[1000,"1"]}
+			]}}`)
+		case "1 + 1":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"scalar","result":[1000,"2"]}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		}
+	}))
+	defer srv.Close()
+
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+	var (
+		mut      sync.Mutex
+		received = map[string]float64{}
+	)
+	receiver := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		received[l.String()] = v
+		return ref, nil
+	}))
+
+	args := DefaultArguments
+	args.URL = srv.URL + "/prometheus"
+	args.Headers = map[string]string{"X-Scope-OrgID": "tenant-1"}
+	args.ForwardTo = []storage.Appendable{receiver}
+	args.MetricPrefix = "agent:"
+	args.Queries = []QueryArguments{
+		{Name: "up", Expr: "up"},
+		{Name: "two", Expr: "1 + 1"},
+		{Name: "invalid", Expr: "sum("},
+	}
+
+	var exports Exports
+	c, err := New(component.Options{
+		ID:            "prometheus.query.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) { exports = e.(Exports) },
+		Registerer:    prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	c.runQueries(context.Background())
+
+	require.Equal(t, map[string][]Sample{
+		"up": {
+			{Labels: map[string]string{"__name__": "up", "job": "a"}, Value: 1},
+			{Labels: map[string]string{"__name__": "up", "job": "b"}, Value: 0},
+		},
+		"two": {{Labels: map[string]string{}, Value: 2}},
+	}, exports.Results)
+	require.Equal(t, map[string]float64{
+		`{__name__="agent:up", job="a"}`: 1,
+		`{__name__="agent:up", job="b"}`: 0,
+		`{__name__="agent:two"}`:         2,
+	}, received)
+
+	health := c.CurrentHealth()
+	require.Equal(t, component.HealthTypeUnhealthy, health.Health)
+	require.Contains(t, health.Message, `query "invalid"`)
+}
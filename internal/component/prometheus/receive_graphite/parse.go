@@ -0,0 +1,96 @@
+package receive_graphite
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// point is a single point received using the Graphite plaintext protocol.
+type point struct {
+	path  string
+	tags  map[string]string
+	value float64
+	ts    time.Time
+}
+
+// parseLine parses a line of the Graphite plaintext protocol:
+//
+//	<path>[;<tag>=<value>...] <value> [<timestamp>]
+//
+// The timestamp is in seconds since the epoch. now is used if the timestamp
+// is missing or is -1.
+func parseLine(line string, now time.Time) (point, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return point{}, fmt.Errorf("expected 2 or 3 fields, got %d", len(fields))
+	}
+
+	p := point{ts: now}
+
+	parts := strings.Split(fields[0], ";")
+	p.path = parts[0]
+	if p.path == "" {
+		return point{}, fmt.Errorf("empty metric path")
+	}
+	for _, tag := range parts[1:] {
+		name, value, ok := strings.Cut(tag, "=")
+		if !ok || name == "" || value == "" {
+			return point{}, fmt.Errorf("invalid tag %q", tag)
+		}
+		if p.tags == nil {
+			p.tags = make(map[string]string, len(parts)-1)
+		}
+		p.tags[name] = value
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return point{}, fmt.Errorf("invalid value %q", fields[1])
+	}
+	p.value = value
+
+	if len(fields) == 3 && fields[2] != "-1" {
+		ts, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return point{}, fmt.Errorf("invalid timestamp %q", fields[2])
+		}
+		sec, frac := math.Modf(ts)
+		p.ts = time.Unix(int64(sec), int64(frac*float64(time.Second)))
+	}
+	return p, nil
+}
+
+// labels returns the labels of the series of p. The metric name is the path
+// of p with every character which isn't valid in metric names replaced with
+// an underscore. Tags are converted into labels using tagLabels, and tags in
+// dropTags are ignored.
+func (p point) labels(tagLabels map[string]string, dropTags map[string]struct{}) labels.Labels {
+	tags := make([]string, 0, len(p.tags))
+	for tag := range p.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	// Tags are set in order so that the last one wins if several tags are
+	// converted into the same label.
+	lb := labels.NewBuilder(labels.EmptyLabels())
+	for _, tag := range tags {
+		if _, drop := dropTags[tag]; drop {
+			continue
+		}
+		name, ok := tagLabels[tag]
+		if !ok {
+			name = agentprom.SanitizeLabelName(tag)
+		}
+		lb.Set(name, p.tags[tag])
+	}
+	lb.Set(labels.MetricName, agentprom.SanitizeMetricName(p.path))
+	return lb.Labels()
+}
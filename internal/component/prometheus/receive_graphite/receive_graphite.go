@@ -0,0 +1,331 @@
+package receive_graphite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/grafana/agent/internal/component"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.receive_graphite",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.receive_graphite component.
+type Arguments struct {
+	ListenTCP   string               `river:"listen_tcp,attr,optional"`
+	ListenUDP   string               `river:"listen_udp,attr,optional"`
+	TagLabels   map[string]string    `river:"tag_labels,attr,optional"`
+	DropTags    []string             `river:"drop_tags,attr,optional"`
+	MaxLineSize units.Base2Bytes     `river:"max_line_size,attr,optional"`
+	ForwardTo   []storage.Appendable `river:"forward_to,attr"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	ListenTCP:   "0.0.0.0:2003",
+	MaxLineSize: 64 * units.KiB,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.ListenTCP == "" && args.ListenUDP == "" {
+		return fmt.Errorf("at least one of listen_tcp or listen_udp must be set")
+	}
+	if args.MaxLineSize <= 0 {
+		return fmt.Errorf("max_line_size must be greater than 0")
+	}
+	for tag, label := range args.TagLabels {
+		if agentprom.SanitizeLabelName(label) != label || strings.HasPrefix(label, "__") {
+			return fmt.Errorf("tag %q is mapped to invalid label name %q", tag, label)
+		}
+	}
+	return nil
+}
+
+// Component implements the prometheus.receive_graphite component.
+type Component struct {
+	opts            component.Options
+	fanout          *agentprom.Fanout
+	samplesReceived prometheus_client.Counter
+	parseErrors     prometheus_client.Counter
+	oversizedLines  prometheus_client.Counter
+
+	mut       sync.RWMutex
+	args      Arguments
+	dropTags  map[string]struct{}
+	tcpLis    net.Listener
+	udpConn   net.PacketConn
+	listeners sync.WaitGroup // Tracks the goroutines reading from listeners.
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.receive_graphite component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:   o,
+		fanout: agentprom.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls),
+	}
+	c.samplesReceived = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_receive_graphite_samples_received_total",
+		Help: "Total number of Graphite samples received",
+	})
+	c.parseErrors = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_receive_graphite_parse_errors_total",
+		Help: "Total number of Graphite lines which couldn't be parsed",
+	})
+	c.oversizedLines = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_receive_graphite_oversized_lines_total",
+		Help: "Total number of Graphite lines dropped because they were longer than max_line_size",
+	})
+	for _, metric := range []prometheus_client.Collector{c.samplesReceived, c.parseErrors, c.oversizedLines} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.stopListeners()
+
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	dropTags := make(map[string]struct{}, len(newArgs.DropTags))
+	for _, tag := range newArgs.DropTags {
+		dropTags[tag] = struct{}{}
+	}
+
+	c.mut.RLock()
+	listenersChanged := c.args.ListenTCP != newArgs.ListenTCP || c.args.ListenUDP != newArgs.ListenUDP ||
+		(c.tcpLis == nil && c.udpConn == nil)
+	c.mut.RUnlock()
+
+	if listenersChanged {
+		c.stopListeners()
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = newArgs
+	c.dropTags = dropTags
+
+	if !listenersChanged {
+		return nil
+	}
+	return c.startListeners()
+}
+
+// startListeners starts the TCP and UDP listeners. mut must be held when
+// calling startListeners.
+func (c *Component) startListeners() error {
+	if c.args.ListenTCP != "" {
+		lis, err := net.Listen("tcp", c.args.ListenTCP)
+		if err != nil {
+			return fmt.Errorf("failed to start TCP listener: %w", err)
+		}
+		c.tcpLis = lis
+
+		c.listeners.Add(1)
+		go func() {
+			defer c.listeners.Done()
+			c.acceptTCP(lis)
+		}()
+	}
+
+	if c.args.ListenUDP != "" {
+		conn, err := net.ListenPacket("udp", c.args.ListenUDP)
+		if err != nil {
+			return fmt.Errorf("failed to start UDP listener: %w", err)
+		}
+		c.udpConn = conn
+
+		c.listeners.Add(1)
+		go func() {
+			defer c.listeners.Done()
+			c.readUDP(conn)
+		}()
+	}
+	return nil
+}
+
+// stopListeners closes the current listeners, if any, and waits for the
+// goroutines reading from them to exit.
+func (c *Component) stopListeners() {
+	c.mut.Lock()
+	if c.tcpLis != nil {
+		c.tcpLis.Close()
+		c.tcpLis = nil
+	}
+	if c.udpConn != nil {
+		c.udpConn.Close()
+		c.udpConn = nil
+	}
+	c.mut.Unlock()
+
+	c.listeners.Wait()
+}
+
+func (c *Component) acceptTCP(lis net.Listener) {
+	var conns sync.WaitGroup
+	defer conns.Wait()
+
+	// Open connections are closed once the listener is closed.
+	var (
+		connsMut sync.Mutex
+		open     = make(map[net.Conn]struct{})
+	)
+	defer func() {
+		connsMut.Lock()
+		defer connsMut.Unlock()
+		for conn := range open {
+			conn.Close()
+		}
+	}()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				level.Error(c.opts.Logger).Log("msg", "failed to accept TCP connection", "err", err)
+			}
+			return
+		}
+
+		connsMut.Lock()
+		open[conn] = struct{}{}
+		connsMut.Unlock()
+
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			defer func() {
+				connsMut.Lock()
+				delete(open, conn)
+				connsMut.Unlock()
+				conn.Close()
+			}()
+
+			if err := c.handleLines(conn); err != nil && !errors.Is(err, net.ErrClosed) {
+				level.Warn(c.opts.Logger).Log("msg", "failed to read from TCP connection", "remote", conn.RemoteAddr(), "err", err)
+			}
+		}()
+	}
+}
+
+func (c *Component) readUDP(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				level.Error(c.opts.Logger).Log("msg", "failed to read UDP packet", "err", err)
+			}
+			return
+		}
+		_ = c.handleLines(bytes.NewReader(buf[:n]))
+	}
+}
+
+// handleLines parses the lines read from r and appends the resulting samples
+// to the receivers. Lines longer than max_line_size are skipped.
+func (c *Component) handleLines(r io.Reader) error {
+	c.mut.RLock()
+	maxLineSize := int(c.args.MaxLineSize)
+	c.mut.RUnlock()
+
+	// The buffer holds the newline ending the line in addition to the line.
+	br := bufio.NewReaderSize(r, maxLineSize+1)
+	for {
+		line, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			c.oversizedLines.Inc()
+			level.Debug(c.opts.Logger).Log("msg", "dropping Graphite line longer than max_line_size", "max_line_size", maxLineSize)
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = br.ReadSlice('\n')
+			}
+		} else if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+			c.handleLine(trimmed)
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Component) handleLine(line string) {
+	p, err := parseLine(line, time.Now())
+	if err != nil {
+		c.parseErrors.Inc()
+		level.Debug(c.opts.Logger).Log("msg", "failed to parse Graphite line", "line", line, "err", err)
+		return
+	}
+
+	c.mut.RLock()
+	lbls := p.labels(c.args.TagLabels, c.dropTags)
+	c.mut.RUnlock()
+
+	app := c.fanout.Appender(context.Background())
+	if _, err := app.Append(0, lbls, timestamp.FromTime(p.ts), p.value); err != nil {
+		_ = app.Rollback()
+		level.Warn(c.opts.Logger).Log("msg", "failed to append Graphite sample", "err", err)
+		return
+	}
+	if err := app.Commit(); err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to commit Graphite sample", "err", err)
+		return
+	}
+	c.samplesReceived.Inc()
+}
@@ -0,0 +1,191 @@
+package receive_graphite
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiveGraphite(t *testing.T) {
+	ls := labelstore.New(nil, prometheus.DefaultRegisterer)
+
+	var (
+		mut      sync.Mutex
+		received = map[string]float64{}
+	)
+	receiver := agentprom.NewInterceptor(nil, ls, agentprom.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, ts int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		received[fmt.Sprintf("%s %d", l, ts)] = v
+		return ref, nil
+	}))
+
+	tcpPort, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	udpPort, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	args := Arguments{
+		ListenTCP:   fmt.Sprintf("127.0.0.1:%d", tcpPort),
+		ListenUDP:   fmt.Sprintf("127.0.0.1:%d", udpPort),
+		TagLabels:   map[string]string{"dc": "datacenter"},
+		DropTags:    []string{"rack"},
+		MaxLineSize: DefaultArguments.MaxLineSize,
+		ForwardTo:   []storage.Appendable{receiver},
+	}
+	c, err := New(component.Options{
+		ID:         "prometheus.receive_graphite.test",
+		Logger:     util.TestFlowLogger(t),
+		Registerer: prometheus.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go c.Run(ctx)
+
+	tcpConn, err := net.Dial("tcp", args.ListenTCP)
+	require.NoError(t, err)
+	_, err = fmt.Fprint(tcpConn, "servers.web-1.cpu.load 0.5 1700000000\nnot a valid line at all\n")
+	require.NoError(t, err)
+	require.NoError(t, tcpConn.Close())
+
+	udpConn, err := net.Dial("udp", args.ListenUDP)
+	require.NoError(t, err)
+	_, err = fmt.Fprint(udpConn, "disk.used;dc=eu1;rack=r7;host=db-1 1024 1700000001.5\n")
+	require.NoError(t, err)
+	require.NoError(t, udpConn.Close())
+
+	require.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(received) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	mut.Lock()
+	defer mut.Unlock()
+	require.Equal(t, map[string]float64{
+		`{__name__="servers_web_1_cpu_load"} 1700000000000`:                   0.5,
+		`{__name__="disk_used", datacenter="eu1", host="db-1"} 1700000001500`: 1024,
+	}, received)
+}
+
+func TestReceiveGraphite_MaxLineSize(t *testing.T) {
+	ls := labelstore.New(nil, prometheus.DefaultRegisterer)
+
+	var (
+		mut      sync.Mutex
+		received []string
+	)
+	receiver := agentprom.NewInterceptor(nil, ls, agentprom.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		received = append(received, l.Get(labels.MetricName))
+		return ref, nil
+	}))
+
+	tcpPort, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	args := DefaultArguments
+	args.ListenTCP = fmt.Sprintf("127.0.0.1:%d", tcpPort)
+	args.MaxLineSize = 32
+	args.ForwardTo = []storage.Appendable{receiver}
+	c, err := New(component.Options{
+		ID:         "prometheus.receive_graphite.test",
+		Logger:     util.TestFlowLogger(t),
+		Registerer: prometheus.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go c.Run(ctx)
+
+	// The line which is too long is dropped, and the following lines of the
+	// connection are still read.
+	tcpConn, err := net.Dial("tcp", args.ListenTCP)
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(tcpConn, "before 1\n%s 1\nafter.exactly.at.the.limit.xyz 1\n", strings.Repeat("a", 64))
+	require.NoError(t, err)
+	require.NoError(t, tcpConn.Close())
+
+	require.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(received) == 2
+	}, 5*time.Second, 20*time.Millisecond)
+
+	mut.Lock()
+	defer mut.Unlock()
+	require.Equal(t, []string{"before", "after_exactly_at_the_limit_xyz"}, received)
+	require.Equal(t, 1.0, testutil.ToFloat64(c.oversizedLines))
+}
+
+func TestArguments_Validate(t *testing.T) {
+	args := DefaultArguments
+	require.NoError(t, args.Validate())
+
+	args.MaxLineSize = 0
+	require.EqualError(t, args.Validate(), "max_line_size must be greater than 0")
+}
+
+func TestParseLine(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tt := []struct {
+		line   string
+		expect point
+		err    string
+	}{
+		{
+			line:   "foo.bar 12.5 1600000000",
+			expect: point{path: "foo.bar", value: 12.5, ts: time.Unix(1600000000, 0)},
+		},
+		{
+			line:   "foo.bar;env=prod 1 -1",
+			expect: point{path: "foo.bar", tags: map[string]string{"env": "prod"}, value: 1, ts: now},
+		},
+		{
+			line:   "foo.bar 3",
+			expect: point{path: "foo.bar", value: 3, ts: now},
+		},
+		{line: "foo.bar", err: "expected 2 or 3 fields, got 1"},
+		{line: "foo.bar abc", err: `invalid value "abc"`},
+		{line: "foo.bar;env 1", err: `invalid tag "env"`},
+		{line: "foo.bar 1 soon", err: `invalid timestamp "soon"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.line, func(t *testing.T) {
+			p, err := parseLine(tc.line, now)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, p)
+		})
+	}
+}
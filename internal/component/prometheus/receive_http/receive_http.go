@@ -47,6 +47,7 @@ func (args *Arguments) SetToDefault() {
 type Component struct {
 	opts               component.Options
 	handler            http.Handler
+	router             *mux.Router // Serves the handler outside of server.
 	fanout             *agentprom.Fanout
 	uncheckedCollector *util.UncheckedCollector
 
@@ -72,6 +73,8 @@ func New(opts component.Options, args Arguments) (*Component, error) {
 		fanout:             fanout,
 		uncheckedCollector: uncheckedCollector,
 	}
+	c.router = mux.NewRouter()
+	c.mount(c.router)
 
 	if err := c.Update(args); err != nil {
 		return nil, err
@@ -113,9 +116,7 @@ func (c *Component) Update(args component.Arguments) error {
 	}
 	c.server = s
 
-	err = c.server.MountAndRun(func(router *mux.Router) {
-		router.Path("/api/v1/metrics/write").Methods("POST").Handler(c.handler)
-	})
+	err = c.server.MountAndRun(c.mount)
 	if err != nil {
 		return err
 	}
@@ -124,6 +125,18 @@ func (c *Component) Update(args component.Arguments) error {
 	return nil
 }
 
+// mount registers the routes of the component on router.
+func (c *Component) mount(router *mux.Router) {
+	router.Path("/api/v1/metrics/write").Methods("POST").Handler(c.handler)
+}
+
+// Handler serves the remote write endpoint from the HTTP server of the agent,
+// such as for connections routed to the component by the sni blocks of the
+// http block.
+func (c *Component) Handler() http.Handler {
+	return c.router
+}
+
 func (c *Component) createNewServer(args Arguments) (error, *fnet.TargetServer) {
 	// [server.Server] registers new metrics every time it is created. To
 	// avoid issues with re-registering metrics with the same name, we create a
@@ -0,0 +1,174 @@
+package receive_influxdb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// point is a single point received using the InfluxDB line protocol.
+type point struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]float64 // Numeric and boolean fields. String fields are ignored.
+	ts          time.Time
+}
+
+// parseLine parses a line of the InfluxDB line protocol:
+//
+//	<measurement>[,<tag>=<value>...] <field>=<value>[,<field>=<value>...] [<timestamp>]
+//
+// The timestamp is interpreted with the given precision. now is used if the
+// timestamp is missing.
+func parseLine(line string, precision time.Duration, now time.Time) (point, error) {
+	key, rest := splitUnescaped(line, ' ', false)
+	fields, ts := splitUnescaped(rest, ' ', true)
+	if key == "" {
+		return point{}, fmt.Errorf("missing measurement")
+	}
+	if fields == "" {
+		return point{}, fmt.Errorf("missing fields")
+	}
+
+	p := point{ts: now}
+
+	measurement, tags := splitUnescaped(key, ',', false)
+	p.measurement = unescape(measurement)
+	if p.measurement == "" {
+		return point{}, fmt.Errorf("missing measurement")
+	}
+	for tags != "" {
+		var tag string
+		tag, tags = splitUnescaped(tags, ',', false)
+		name, value := splitUnescaped(tag, '=', false)
+		if name == "" || value == "" {
+			return point{}, fmt.Errorf("invalid tag %q", tag)
+		}
+		if p.tags == nil {
+			p.tags = make(map[string]string)
+		}
+		p.tags[unescape(name)] = unescape(value)
+	}
+
+	p.fields = make(map[string]float64)
+	for fields != "" {
+		var field string
+		field, fields = splitUnescaped(fields, ',', true)
+		name, value := splitUnescaped(field, '=', true)
+		if name == "" || value == "" {
+			return point{}, fmt.Errorf("invalid field %q", field)
+		}
+		v, ok, err := parseFieldValue(value)
+		if err != nil {
+			return point{}, fmt.Errorf("invalid value of field %q: %w", unescape(name), err)
+		}
+		if ok {
+			p.fields[unescape(name)] = v
+		}
+	}
+
+	if ts = strings.TrimSpace(ts); ts != "" {
+		n, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return point{}, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		p.ts = time.Unix(0, n*int64(precision))
+	}
+	return p, nil
+}
+
+// parseFieldValue parses the value of a field. ok is false for string values,
+// which can't be converted into samples.
+func parseFieldValue(s string) (v float64, ok bool, err error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+			return 0, false, fmt.Errorf("unterminated string")
+		}
+		return 0, false, nil
+	case strings.HasSuffix(s, "i"):
+		n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+		return float64(n), err == nil, err
+	case strings.HasSuffix(s, "u"):
+		n, err := strconv.ParseUint(s[:len(s)-1], 10, 64)
+		return float64(n), err == nil, err
+	}
+
+	switch s {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, true, nil
+	}
+	v, err = strconv.ParseFloat(s, 64)
+	return v, err == nil, err
+}
+
+// splitUnescaped splits s at the first occurrence of sep which isn't escaped
+// with a backslash. If quoted is true, occurrences of sep within double
+// quotes are ignored too.
+func splitUnescaped(s string, sep byte, quoted bool) (before, after string) {
+	var inQuote bool
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case c == '"' && quoted:
+			inQuote = !inQuote
+		case c == sep && !inQuote:
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// unescape removes the backslashes escaping commas, equal signs and spaces.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	r := strings.NewReplacer(`\,`, ",", `\=`, "=", `\ `, " ", `\\`, `\`)
+	return r.Replace(s)
+}
+
+// series returns the name and labels of the series of every field of p. The
+// metric name is made of the measurement and field names, separated by an
+// underscore. Fields named value only use the measurement name. Tags are
+// converted into labels using tagLabels, and tags in dropTags are ignored.
+func (p point) series(tagLabels map[string]string, dropTags map[string]struct{}) map[string]labels.Labels {
+	tags := make([]string, 0, len(p.tags))
+	for tag := range p.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	// Tags are set in order so that the last one wins if several tags are
+	// converted into the same label.
+	lb := labels.NewBuilder(labels.EmptyLabels())
+	for _, tag := range tags {
+		if _, drop := dropTags[tag]; drop {
+			continue
+		}
+		name, ok := tagLabels[tag]
+		if !ok {
+			name = agentprom.SanitizeLabelName(tag)
+		}
+		lb.Set(name, p.tags[tag])
+	}
+
+	series := make(map[string]labels.Labels, len(p.fields))
+	for field := range p.fields {
+		name := p.measurement
+		if field != "value" {
+			name += "_" + field
+		}
+		lb.Set(labels.MetricName, agentprom.SanitizeMetricName(name))
+		series[field] = lb.Labels()
+	}
+	return series
+}
@@ -0,0 +1,275 @@
+package receive_influxdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/component"
+	fnet "github.com/grafana/agent/internal/component/common/net"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.receive_influxdb",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.receive_influxdb component.
+type Arguments struct {
+	Server    *fnet.ServerConfig   `river:",squash"`
+	TagLabels map[string]string    `river:"tag_labels,attr,optional"`
+	DropTags  []string             `river:"drop_tags,attr,optional"`
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		Server: fnet.DefaultServerConfig(),
+	}
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	for tag, label := range args.TagLabels {
+		if agentprom.SanitizeLabelName(label) != label || strings.HasPrefix(label, "__") {
+			return fmt.Errorf("tag %q is mapped to invalid label name %q", tag, label)
+		}
+	}
+	return nil
+}
+
+// Component implements the prometheus.receive_influxdb component.
+type Component struct {
+	opts               component.Options
+	fanout             *agentprom.Fanout
+	uncheckedCollector *util.UncheckedCollector
+	samplesReceived    prometheus.Counter
+	parseErrors        prometheus.Counter
+
+	updateMut sync.RWMutex
+	args      Arguments
+	dropTags  map[string]struct{}
+	server    *fnet.TargetServer
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.receive_influxdb component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	service, err := opts.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := service.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:               opts,
+		fanout:             agentprom.NewFanout(args.ForwardTo, opts.ID, opts.Registerer, ls),
+		uncheckedCollector: util.NewUncheckedCollector(nil),
+	}
+	c.samplesReceived = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_prometheus_receive_influxdb_samples_received_total",
+		Help: "Total number of samples received from InfluxDB line protocol points",
+	})
+	c.parseErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_prometheus_receive_influxdb_parse_errors_total",
+		Help: "Total number of InfluxDB line protocol lines which couldn't be parsed",
+	})
+	for _, metric := range []prometheus.Collector{c.uncheckedCollector, c.samplesReceived, c.parseErrors} {
+		if err := opts.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run satisfies the Component interface.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.updateMut.Lock()
+		defer c.updateMut.Unlock()
+		c.shutdownServer()
+	}()
+
+	<-ctx.Done()
+	level.Info(c.opts.Logger).Log("msg", "terminating due to context done")
+	return nil
+}
+
+// Update satisfies the Component interface.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	dropTags := make(map[string]struct{}, len(newArgs.DropTags))
+	for _, tag := range newArgs.DropTags {
+		dropTags[tag] = struct{}{}
+	}
+
+	c.updateMut.Lock()
+	defer c.updateMut.Unlock()
+
+	serverNeedsUpdate := c.server == nil || !reflect.DeepEqual(c.args.Server, newArgs.Server)
+	c.args = newArgs
+	c.dropTags = dropTags
+	if !serverNeedsUpdate {
+		return nil
+	}
+	c.shutdownServer()
+
+	// [fnet.TargetServer] registers new metrics every time it is created. To
+	// avoid issues with re-registering metrics with the same name, we create a
+	// new registry for the server every time we create one, and pass it to an
+	// unchecked collector to bypass uniqueness checking.
+	serverRegistry := prometheus.NewRegistry()
+	c.uncheckedCollector.SetCollector(serverRegistry)
+
+	s, err := fnet.NewTargetServer(c.opts.Logger, "prometheus_receive_influxdb", serverRegistry, newArgs.Server)
+	if err != nil {
+		return fmt.Errorf("failed to create server: %v", err)
+	}
+	c.server = s
+
+	return c.server.MountAndRun(func(router *mux.Router) {
+		router.Path("/write").Methods("POST").HandlerFunc(c.handleWrite(precisionsV1))
+		router.Path("/api/v2/write").Methods("POST").HandlerFunc(c.handleWrite(precisionsV2))
+		router.Path("/ping").Methods("GET", "HEAD").HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+}
+
+// shutdownServer will shut down the currently used server.
+// It is not goroutine-safe and an updateMut write lock must be held when it's called.
+func (c *Component) shutdownServer() {
+	if c.server != nil {
+		c.server.StopAndShutdown()
+		c.server = nil
+	}
+}
+
+// Timestamp precisions supported by the v1 and v2 write APIs.
+var (
+	precisionsV1 = map[string]time.Duration{
+		"":   time.Nanosecond,
+		"n":  time.Nanosecond,
+		"ns": time.Nanosecond,
+		"u":  time.Microsecond,
+		"us": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+	}
+	precisionsV2 = map[string]time.Duration{
+		"":   time.Nanosecond,
+		"ns": time.Nanosecond,
+		"us": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+	}
+)
+
+// handleWrite returns a handler for write requests. Lines which can't be
+// parsed are reported in the response, but don't prevent the other lines of
+// the request from being written.
+func (c *Component) handleWrite(precisions map[string]time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		precision, ok := precisions[r.URL.Query().Get("precision")]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported precision %q", r.URL.Query().Get("precision")), http.StatusBadRequest)
+			return
+		}
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid gzip body: %s", err), http.StatusBadRequest)
+				return
+			}
+			defer gz.Close()
+			body = gz
+		}
+
+		c.updateMut.RLock()
+		tagLabels, dropTags := c.args.TagLabels, c.dropTags
+		c.updateMut.RUnlock()
+
+		var (
+			now       = time.Now()
+			app       = c.fanout.Appender(r.Context())
+			parseErrs []error
+			samples   int
+		)
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(nil, 1<<20)
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			p, err := parseLine(line, precision, now)
+			if err != nil {
+				c.parseErrors.Inc()
+				parseErrs = append(parseErrs, fmt.Errorf("line %d: %w", lineNum, err))
+				continue
+			}
+			ts := timestamp.FromTime(p.ts)
+			for field, lbls := range p.series(tagLabels, dropTags) {
+				if _, err := app.Append(0, lbls, ts, p.fields[field]); err != nil {
+					_ = app.Rollback()
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				samples++
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			_ = app.Rollback()
+			http.Error(w, fmt.Sprintf("failed to read request body: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := app.Commit(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		c.samplesReceived.Add(float64(samples))
+
+		if len(parseErrs) > 0 {
+			http.Error(w, errors.Join(parseErrs...).Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
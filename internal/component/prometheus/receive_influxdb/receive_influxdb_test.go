@@ -0,0 +1,144 @@
+package receive_influxdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	fnet "github.com/grafana/agent/internal/component/common/net"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	ls := labelstore.New(nil, prometheus.DefaultRegisterer)
+
+	var (
+		mut      sync.Mutex
+		received = map[string]float64{}
+	)
+	receiver := agentprom.NewInterceptor(nil, ls, agentprom.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, ts int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		received[fmt.Sprintf("%s %d", l, ts)] = v
+		return ref, nil
+	}))
+
+	args := Arguments{
+		Server: &fnet.ServerConfig{
+			HTTP: &fnet.HTTPConfig{
+				ListenAddress: "127.0.0.1",
+				ListenPort:    getFreePort(t),
+			},
+			GRPC: &fnet.GRPCConfig{ListenAddress: "127.0.0.1", ListenPort: getFreePort(t)},
+		},
+		TagLabels: map[string]string{"host": "instance"},
+		DropTags:  []string{"region"},
+		ForwardTo: []storage.Appendable{receiver},
+	}
+	c, err := New(component.Options{
+		ID:         "prometheus.receive_influxdb.test",
+		Logger:     util.TestFlowLogger(t),
+		Registerer: prometheus.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go c.Run(ctx)
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", args.Server.HTTP.ListenPort)
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(baseURL + "/ping")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusNoContent
+	}, 5*time.Second, 20*time.Millisecond)
+
+	body := strings.Join([]string{
+		`cpu,host=server01,region=eu usage_idle=92.5,usage_user=3i,state="ok" 1700000000`,
+		`temperature,room=kitchen value=21 1700000001`,
+		`invalid line`,
+	}, "\n")
+	resp, err := http.Post(baseURL+"/write?precision=s", "text/plain", strings.NewReader(body))
+	require.NoError(t, err)
+	msg, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, string(msg), "line 3")
+
+	mut.Lock()
+	defer mut.Unlock()
+	require.Equal(t, map[string]float64{
+		`{__name__="cpu_usage_idle", instance="server01"} 1700000000000`: 92.5,
+		`{__name__="cpu_usage_user", instance="server01"} 1700000000000`: 3,
+		`{__name__="temperature", room="kitchen"} 1700000001000`:         21,
+	}, received)
+}
+
+func TestParseLine(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tt := []struct {
+		line   string
+		expect point
+		err    string
+	}{
+		{
+			line: `weather,location=us\,midwest,station\ id=42 temperature=82,raining=true,note="hot, dry" 1465839830100400200`,
+			expect: point{
+				measurement: "weather",
+				tags:        map[string]string{"location": "us,midwest", "station id": "42"},
+				fields:      map[string]float64{"temperature": 82, "raining": 1},
+				ts:          time.Unix(0, 1465839830100400200),
+			},
+		},
+		{
+			line: `disk\ io reads=12u`,
+			expect: point{
+				measurement: "disk io",
+				fields:      map[string]float64{"reads": 12},
+				ts:          now,
+			},
+		},
+		{line: `cpu`, err: "missing fields"},
+		{line: `cpu usage=abc`, err: `invalid value of field "usage"`},
+		{line: `cpu,host usage=1`, err: `invalid tag "host"`},
+		{line: `cpu usage=1 yesterday`, err: `invalid timestamp "yesterday"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.line, func(t *testing.T) {
+			p, err := parseLine(tc.line, time.Nanosecond, now)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, p)
+		})
+	}
+}
+
+func getFreePort(t *testing.T) int {
+	p, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	return p
+}
@@ -0,0 +1,93 @@
+package receive_statsd
+
+import (
+	"math"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// appendFamilies appends a sample at timestamp ts for every series of mfs.
+// Summaries and histograms are appended as their classic series, the same
+// way they would be ingested when scraped.
+func appendFamilies(app storage.Appender, mfs []*dto.MetricFamily, ts int64) error {
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			var err error
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				err = appendSample(app, name, m, ts, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				err = appendSample(app, name, m, ts, m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				err = appendSample(app, name, m, ts, m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				err = appendSummary(app, name, m, ts)
+			case dto.MetricType_HISTOGRAM:
+				err = appendHistogram(app, name, m, ts)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func appendSummary(app storage.Appender, name string, m *dto.Metric, ts int64) error {
+	s := m.GetSummary()
+	for _, q := range s.GetQuantile() {
+		quantile := strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64)
+		if err := appendSample(app, name, m, ts, q.GetValue(), model.QuantileLabel, quantile); err != nil {
+			return err
+		}
+	}
+	if err := appendSample(app, name+"_sum", m, ts, s.GetSampleSum()); err != nil {
+		return err
+	}
+	return appendSample(app, name+"_count", m, ts, float64(s.GetSampleCount()))
+}
+
+func appendHistogram(app storage.Appender, name string, m *dto.Metric, ts int64) error {
+	h := m.GetHistogram()
+	var sawInf bool
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), +1) {
+			sawInf = true
+		}
+		le := strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64)
+		if err := appendSample(app, name+"_bucket", m, ts, float64(b.GetCumulativeCount()), model.BucketLabel, le); err != nil {
+			return err
+		}
+	}
+	if !sawInf {
+		if err := appendSample(app, name+"_bucket", m, ts, float64(h.GetSampleCount()), model.BucketLabel, "+Inf"); err != nil {
+			return err
+		}
+	}
+	if err := appendSample(app, name+"_sum", m, ts, h.GetSampleSum()); err != nil {
+		return err
+	}
+	return appendSample(app, name+"_count", m, ts, float64(h.GetSampleCount()))
+}
+
+// appendSample appends a single sample of the series name with the labels of
+// m and the additional extra label name-value pairs.
+func appendSample(app storage.Appender, name string, m *dto.Metric, ts int64, v float64, extra ...string) error {
+	lb := labels.NewScratchBuilder(len(m.GetLabel()) + 1 + len(extra)/2)
+	lb.Add(labels.MetricName, name)
+	for _, lp := range m.GetLabel() {
+		lb.Add(lp.GetName(), lp.GetValue())
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		lb.Add(extra[i], extra[i+1])
+	}
+	lb.Sort()
+
+	_, err := app.Append(0, lb.Labels(), ts, v)
+	return err
+}
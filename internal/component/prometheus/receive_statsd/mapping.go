@@ -0,0 +1,135 @@
+package receive_statsd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/statsd_exporter/pkg/mapper"
+	"gopkg.in/yaml.v2"
+)
+
+// MappingArguments configures a mapping rule which translates statsd metric
+// names into Prometheus metric names and labels.
+type MappingArguments struct {
+	Match           string            `river:"match,attr"`
+	MatchType       string            `river:"match_type,attr,optional"`
+	MatchMetricType string            `river:"match_metric_type,attr,optional"`
+	Name            string            `river:"name,attr"`
+	Labels          map[string]string `river:"labels,attr,optional"`
+	Help            string            `river:"help,attr,optional"`
+	Action          string            `river:"action,attr,optional"`
+	ObserverType    string            `river:"observer_type,attr,optional"`
+	Buckets         []float64         `river:"buckets,attr,optional"`
+	TTL             time.Duration     `river:"ttl,attr,optional"`
+}
+
+// DefaultMappingArguments holds default settings for MappingArguments.
+var DefaultMappingArguments = MappingArguments{
+	MatchType: "glob",
+	Action:    "map",
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *MappingArguments) SetToDefault() {
+	*args = DefaultMappingArguments
+}
+
+// Validate implements river.Validator.
+func (args *MappingArguments) Validate() error {
+	switch args.MatchType {
+	case "glob", "regex":
+	default:
+		return fmt.Errorf("unsupported match_type %q, must be one of glob or regex", args.MatchType)
+	}
+	switch args.MatchMetricType {
+	case "", "counter", "gauge", "observer":
+	default:
+		return fmt.Errorf("unsupported match_metric_type %q, must be one of counter, gauge or observer", args.MatchMetricType)
+	}
+	switch args.Action {
+	case "map", "drop":
+	default:
+		return fmt.Errorf("unsupported action %q, must be one of map or drop", args.Action)
+	}
+	switch args.ObserverType {
+	case "", "summary", "histogram":
+	default:
+		return fmt.Errorf("unsupported observer_type %q, must be one of summary or histogram", args.ObserverType)
+	}
+	if len(args.Buckets) > 0 && args.ObserverType != "histogram" {
+		return fmt.Errorf("buckets can only be set with observer_type histogram")
+	}
+	return nil
+}
+
+// mapperConfig is the statsd_exporter mapping configuration generated from
+// the mapping blocks.
+type mapperConfig struct {
+	Mappings []mappingConfig `yaml:"mappings"`
+}
+
+type mappingConfig struct {
+	Match            string            `yaml:"match"`
+	MatchType        string            `yaml:"match_type,omitempty"`
+	MatchMetricType  string            `yaml:"match_metric_type,omitempty"`
+	Name             string            `yaml:"name"`
+	Labels           map[string]string `yaml:"labels,omitempty"`
+	Help             string            `yaml:"help,omitempty"`
+	Action           string            `yaml:"action,omitempty"`
+	ObserverType     string            `yaml:"observer_type,omitempty"`
+	HistogramOptions *histogramOptions `yaml:"histogram_options,omitempty"`
+	TTL              string            `yaml:"ttl,omitempty"`
+}
+
+type histogramOptions struct {
+	Buckets []float64 `yaml:"buckets"`
+}
+
+// newMapper returns a statsd_exporter mapper which applies the given
+// mappings in order.
+func newMapper(mappings []MappingArguments, reg prometheus.Registerer, mappingsCount prometheus.Gauge, l log.Logger) (*mapper.MetricMapper, error) {
+	m := &mapper.MetricMapper{
+		Registerer:    reg,
+		MappingsCount: mappingsCount,
+		Logger:        l,
+	}
+	if err := loadMappings(m, mappings); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// loadMappings replaces the mappings of m.
+func loadMappings(m *mapper.MetricMapper, mappings []MappingArguments) error {
+	cfg := mapperConfig{Mappings: make([]mappingConfig, 0, len(mappings))}
+	for _, mapping := range mappings {
+		mc := mappingConfig{
+			Match:           mapping.Match,
+			MatchType:       mapping.MatchType,
+			MatchMetricType: mapping.MatchMetricType,
+			Name:            mapping.Name,
+			Labels:          mapping.Labels,
+			Help:            mapping.Help,
+			Action:          mapping.Action,
+			ObserverType:    mapping.ObserverType,
+		}
+		if len(mapping.Buckets) > 0 {
+			mc.HistogramOptions = &histogramOptions{Buckets: mapping.Buckets}
+		}
+		if mapping.TTL > 0 {
+			mc.TTL = mapping.TTL.String()
+		}
+		cfg.Mappings = append(cfg.Mappings, mc)
+	}
+
+	bb, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := m.InitFromYAMLString(string(bb)); err != nil {
+		return fmt.Errorf("invalid mapping: %w", err)
+	}
+	return nil
+}
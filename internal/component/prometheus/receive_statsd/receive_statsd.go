@@ -0,0 +1,315 @@
+package receive_statsd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	agentprom "github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/static/integrations/statsd_exporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/statsd_exporter/pkg/address"
+	"github.com/prometheus/statsd_exporter/pkg/event"
+	"github.com/prometheus/statsd_exporter/pkg/exporter"
+	"github.com/prometheus/statsd_exporter/pkg/line"
+	"github.com/prometheus/statsd_exporter/pkg/listener"
+	"github.com/prometheus/statsd_exporter/pkg/mapper"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.receive_statsd",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.receive_statsd component.
+type Arguments struct {
+	ListenUDP      string               `river:"listen_udp,attr,optional"`
+	ListenTCP      string               `river:"listen_tcp,attr,optional"`
+	ParseDogStatsd bool                 `river:"parse_dogstatsd_tags,attr,optional"`
+	ParseInfluxDB  bool                 `river:"parse_influxdb_tags,attr,optional"`
+	ParseLibrato   bool                 `river:"parse_librato_tags,attr,optional"`
+	ParseSignalFX  bool                 `river:"parse_signalfx_tags,attr,optional"`
+	FlushInterval  time.Duration        `river:"flush_interval,attr,optional"`
+	Mappings       []MappingArguments   `river:"mapping,block,optional"`
+	ForwardTo      []storage.Appendable `river:"forward_to,attr"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	ListenUDP:      "0.0.0.0:9125",
+	ParseDogStatsd: true,
+	ParseInfluxDB:  true,
+	ParseLibrato:   true,
+	ParseSignalFX:  true,
+	FlushInterval:  15 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.ListenUDP == "" && args.ListenTCP == "" {
+		return fmt.Errorf("at least one of listen_udp or listen_tcp must be set")
+	}
+	if args.FlushInterval <= 0 {
+		return fmt.Errorf("flush_interval must be greater than zero")
+	}
+	// Load the mappings into a throwaway mapper to report invalid mappings
+	// before the component is updated.
+	if _, err := newMapper(args.Mappings, nil, nil, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parser returns the line parser for the enabled tag formats.
+func (args Arguments) parser() *line.Parser {
+	parser := line.NewParser()
+	if args.ParseDogStatsd {
+		parser.EnableDogstatsdParsing()
+	}
+	if args.ParseInfluxDB {
+		parser.EnableInfluxdbParsing()
+	}
+	if args.ParseLibrato {
+		parser.EnableLibratoParsing()
+	}
+	if args.ParseSignalFX {
+		parser.EnableSignalFXParsing()
+	}
+	return parser
+}
+
+// Event queue settings, matching the defaults of statsd_exporter.
+const (
+	eventQueueSize      = 10000
+	eventFlushThreshold = 1000
+	eventFlushInterval  = 200 * time.Millisecond
+)
+
+// Component implements the prometheus.receive_statsd component.
+type Component struct {
+	opts     component.Options
+	fanout   *agentprom.Fanout
+	metrics  *statsd_exporter.Metrics
+	registry *prometheus.Registry // Holds the metrics mapped from statsd events.
+	mapper   *mapper.MetricMapper
+	exporter *exporter.Exporter
+	events   chan event.Events
+	queue    *event.EventQueue
+
+	mut     sync.Mutex
+	args    Arguments
+	udpConn *net.UDPConn
+	tcpConn *net.TCPListener
+	flush   chan time.Duration // Receives the new flush interval on updates.
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.receive_statsd component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	m, err := statsd_exporter.NewMetrics(o.Registerer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics for network listeners: %w", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	statsdMapper, err := newMapper(args.Mappings, registry, m.MappingsCount, o.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan event.Events, eventQueueSize)
+	c := &Component{
+		opts:     o,
+		fanout:   agentprom.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls),
+		metrics:  m,
+		registry: registry,
+		mapper:   statsdMapper,
+		exporter: exporter.NewExporter(registry, statsdMapper, o.Logger, m.EventsActions, m.EventsUnmapped, m.ErrorEventStats, m.EventStats, m.ConflictingEventStats, m.MetricsCount),
+		events:   events,
+		queue:    event.NewEventQueue(events, eventFlushThreshold, eventFlushInterval, m.EventsFlushed),
+		flush:    make(chan time.Duration, 1),
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.stopListeners()
+
+	go c.exporter.Listen(c.events)
+
+	c.mut.Lock()
+	ticker := time.NewTicker(c.args.FlushInterval)
+	c.mut.Unlock()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case interval := <-c.flush:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			if err := c.flushMetrics(ctx); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to forward statsd metrics", "err", err)
+			}
+		}
+	}
+}
+
+// flushMetrics appends the current value of all metrics mapped from statsd
+// events to the receivers.
+func (c *Component) flushMetrics(ctx context.Context) error {
+	mfs, err := c.registry.Gather()
+	if err != nil {
+		return err
+	}
+
+	app := c.fanout.Appender(ctx)
+	if err := appendFamilies(app, mfs, timestamp.FromTime(time.Now())); err != nil {
+		_ = app.Rollback()
+		return err
+	}
+	return app.Commit()
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	if err := loadMappings(c.mapper, newArgs.Mappings); err != nil {
+		return err
+	}
+
+	c.stopListeners()
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if newArgs.FlushInterval != c.args.FlushInterval && c.args.FlushInterval != 0 {
+		// Drop a pending interval which wasn't picked up yet.
+		select {
+		case <-c.flush:
+		default:
+		}
+		c.flush <- newArgs.FlushInterval
+	}
+	c.args = newArgs
+
+	return c.startListeners()
+}
+
+// startListeners starts the UDP and TCP listeners. mut must be held when
+// calling startListeners.
+func (c *Component) startListeners() error {
+	parser := c.args.parser()
+
+	if c.args.ListenUDP != "" {
+		addr, err := address.UDPAddrFromString(c.args.ListenUDP)
+		if err != nil {
+			return fmt.Errorf("invalid UDP listen address %s: %w", c.args.ListenUDP, err)
+		}
+		conn, err := net.ListenUDP("udp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to start UDP listener: %w", err)
+		}
+		c.udpConn = conn
+
+		ul := &listener.StatsDUDPListener{
+			Conn:            conn,
+			EventHandler:    c.queue,
+			Logger:          c.opts.Logger,
+			LineParser:      parser,
+			UDPPackets:      c.metrics.UDPPackets,
+			LinesReceived:   c.metrics.LinesReceived,
+			EventsFlushed:   c.metrics.EventsFlushed,
+			SampleErrors:    *c.metrics.SampleErrors,
+			SamplesReceived: c.metrics.SamplesReceived,
+			TagErrors:       c.metrics.TagErrors,
+			TagsReceived:    c.metrics.TagsReceived,
+		}
+		go ul.Listen()
+	}
+
+	if c.args.ListenTCP != "" {
+		addr, err := address.TCPAddrFromString(c.args.ListenTCP)
+		if err != nil {
+			return fmt.Errorf("invalid TCP listen address %s: %w", c.args.ListenTCP, err)
+		}
+		conn, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to start TCP listener: %w", err)
+		}
+		c.tcpConn = conn
+
+		tl := &listener.StatsDTCPListener{
+			Conn:            conn,
+			EventHandler:    c.queue,
+			Logger:          c.opts.Logger,
+			LineParser:      parser,
+			LinesReceived:   c.metrics.LinesReceived,
+			EventsFlushed:   c.metrics.EventsFlushed,
+			SampleErrors:    *c.metrics.SampleErrors,
+			SamplesReceived: c.metrics.SamplesReceived,
+			TagErrors:       c.metrics.TagErrors,
+			TagsReceived:    c.metrics.TagsReceived,
+			TCPConnections:  c.metrics.TCPConnections,
+			TCPErrors:       c.metrics.TCPErrors,
+			TCPLineTooLong:  c.metrics.TCPLineTooLong,
+		}
+		go tl.Listen()
+	}
+	return nil
+}
+
+// stopListeners closes the current listeners, if any.
+func (c *Component) stopListeners() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.udpConn != nil {
+		if err := c.udpConn.Close(); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to close UDP listener", "err", err)
+		}
+		c.udpConn = nil
+	}
+	if c.tcpConn != nil {
+		if err := c.tcpConn.Close(); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to close TCP listener", "err", err)
+		}
+		c.tcpConn = nil
+	}
+}
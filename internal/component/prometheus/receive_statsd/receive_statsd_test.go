@@ -0,0 +1,175 @@
+package receive_statsd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/phayes/freeport"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiveStatsd(t *testing.T) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+
+	var (
+		mut      sync.Mutex
+		received = map[string]float64{}
+	)
+	receiver := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		received[l.String()] = v
+		return ref, nil
+	}))
+
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	args := DefaultArguments
+	args.ListenUDP = fmt.Sprintf("127.0.0.1:%d", port)
+	args.FlushInterval = 50 * time.Millisecond
+	args.Mappings = []MappingArguments{
+		{
+			Match:     "api.*.requests",
+			MatchType: "glob",
+			Action:    "map",
+			Name:      "api_requests_total",
+			Labels:    map[string]string{"endpoint": "$1"},
+		},
+		{
+			Match:     "debug.*",
+			MatchType: "glob",
+			Action:    "drop",
+			Name:      "dropped",
+		},
+	}
+	args.ForwardTo = []storage.Appendable{receiver}
+
+	c, err := New(component.Options{
+		ID:            "prometheus.receive_statsd.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	conn, err := net.Dial("udp", args.ListenUDP)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("api.users.requests:3|c|#env:prod\napi.users.requests:2|c|#env:prod\ndebug.value:1|g\nqueue_depth:7|g"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return received[`{__name__="api_requests_total", endpoint="users", env="prod"}`] == 5 &&
+			received[`{__name__="queue_depth"}`] == 7
+	}, 5*time.Second, 25*time.Millisecond)
+
+	mut.Lock()
+	defer mut.Unlock()
+	for series := range received {
+		require.NotContains(t, series, "dropped")
+		require.NotContains(t, series, "debug")
+	}
+}
+
+func TestArguments_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    string
+		expect string
+	}{
+		{
+			name: "valid",
+			cfg: `
+				forward_to = []
+				mapping {
+					match  = "api.*.requests"
+					name   = "api_requests_total"
+					labels = { endpoint = "$1" }
+				}
+				mapping {
+					match         = "latency.*"
+					name          = "latency_seconds"
+					observer_type = "histogram"
+					buckets       = [0.1, 0.5, 1]
+				}
+			`,
+		},
+		{
+			name: "no listeners",
+			cfg: `
+				listen_udp = ""
+				forward_to = []
+			`,
+			expect: "at least one of listen_udp or listen_tcp must be set",
+		},
+		{
+			name: "invalid metric name",
+			cfg: `
+				forward_to = []
+				mapping {
+					match = "api.*"
+					name  = "api-requests"
+				}
+			`,
+			expect: "invalid mapping",
+		},
+		{
+			name: "invalid action",
+			cfg: `
+				forward_to = []
+				mapping {
+					match  = "api.*"
+					name   = "api"
+					action = "keep"
+				}
+			`,
+			expect: `unsupported action "keep", must be one of map or drop`,
+		},
+		{
+			name: "buckets without histogram",
+			cfg: `
+				forward_to = []
+				mapping {
+					match   = "api.*"
+					name    = "api"
+					buckets = [1]
+				}
+			`,
+			expect: "buckets can only be set with observer_type histogram",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.cfg), &args)
+			if tc.expect == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.expect)
+			}
+		})
+	}
+}
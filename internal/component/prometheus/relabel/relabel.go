@@ -9,6 +9,7 @@ import (
 	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
 	"github.com/grafana/agent/internal/component/prometheus"
 	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/drops"
 	"github.com/grafana/agent/internal/service/labelstore"
 	lru "github.com/hashicorp/golang-lru/v2"
 	prometheus_client "github.com/prometheus/client_golang/prometheus"
@@ -77,6 +78,7 @@ type Component struct {
 	receiver         *prometheus.Interceptor
 	metricsProcessed prometheus_client.Counter
 	metricsOutgoing  prometheus_client.Counter
+	dropped          *drops.Recorder
 	cacheHits        prometheus_client.Counter
 	cacheMisses      prometheus_client.Counter
 	cacheSize        prometheus_client.Gauge
@@ -140,6 +142,8 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		}
 	}
 
+	c.dropped = drops.NewRecorder(o, drops.SignalMetrics)
+
 	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, c.ls)
 	c.receiver = prometheus.NewInterceptor(
 		c.fanout,
@@ -151,6 +155,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 			newLbl := c.relabel(v, l)
 			if newLbl.IsEmpty() {
+				c.dropped.Add(drops.ReasonRelabel, 1)
 				return 0, nil
 			}
 			c.metricsOutgoing.Inc()
@@ -185,6 +190,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 			newLbl := c.relabel(0, l)
 			if newLbl.IsEmpty() {
+				c.dropped.Add(drops.ReasonRelabel, 1)
 				return 0, nil
 			}
 			return next.AppendHistogram(0, newLbl, t, h, fh)
@@ -243,9 +249,14 @@ func (c *Component) relabel(val float64, lbls labels.Labels) labels.Labels {
 			relabelled = newLbls.labels
 		}
 	} else {
+		// Relabeling is bounded by the execution pool of the component, if any.
+		// Acquiring a worker only fails when its context is canceled.
+		release, _ := component.Acquire(context.Background(), c.opts.Executor)
+
 		// Relabel against a copy of the labels to prevent modifying the original
 		// slice.
 		relabelled, keep = relabel.Process(lbls.Copy(), c.mrc...)
+		release()
 		c.cacheMisses.Inc()
 		c.addToCache(globalRef, relabelled, keep)
 	}
@@ -0,0 +1,294 @@
+package remotewrite
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/axiomhq/hyperloglog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/storage"
+	"gopkg.in/yaml.v2"
+)
+
+// activeSeriesWindows are the windows over which the active series sent to
+// each endpoint are counted. Each window is split in buckets, which are
+// dropped as the window slides. The bucket size of each window must be a
+// multiple of the bucket size of the first one.
+var activeSeriesWindows = []struct {
+	name       string
+	bucketSize time.Duration
+	buckets    int
+}{
+	{name: "1h", bucketSize: 10 * time.Minute, buckets: 6},
+	{name: "24h", bucketSize: time.Hour, buckets: 24},
+}
+
+var activeSeriesDesc = prometheus.NewDesc(
+	"agent_prometheus_remote_write_active_series",
+	"Approximate number of distinct series sent to the endpoint over the window.",
+	[]string{"remote_name", "url", "window"}, nil,
+)
+
+// activeSeriesTracker approximates the number of distinct series sent to each
+// endpoint with HyperLogLog sketches.
+//
+// The series sent to an endpoint are the series appended to the component,
+// with the external labels and the write relabeling rules of the endpoint
+// applied.
+//
+// A series is only inserted in the sketches the first time it's observed in
+// each bucket of the first window, since inserting it again in the same
+// buckets doesn't change the counts. The write relabeling rules are thus
+// applied to each series once per bucket rather than on every append.
+type activeSeriesTracker struct {
+	now func() time.Time
+
+	mut            sync.RWMutex
+	endpoints      []*endpointSeries
+	externalLabels labels.Labels
+
+	// seen holds the refs of the series observed in the bucket of the first
+	// window with the index seenEpoch. It's reset under mut when the
+	// endpoints change.
+	seenMut   sync.Mutex
+	seenEpoch int64
+	seen      map[storage.SeriesRef]struct{}
+}
+
+var _ prometheus.Collector = (*activeSeriesTracker)(nil)
+
+func newActiveSeriesTracker() *activeSeriesTracker {
+	return &activeSeriesTracker{
+		now:  time.Now,
+		seen: make(map[storage.SeriesRef]struct{}),
+	}
+}
+
+// endpointSeries counts the series sent to one endpoint.
+type endpointSeries struct {
+	name, url string
+	rules     []*relabel.Config
+
+	mut     sync.Mutex
+	windows []*windowedSketch
+}
+
+// SetConfig sets the endpoints to track from cfg. The counts of endpoints
+// with the same name and URL as before are kept.
+func (t *activeSeriesTracker) SetConfig(cfg *config.Config) error {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	prev := make(map[[2]string]*endpointSeries, len(t.endpoints))
+	for _, e := range t.endpoints {
+		prev[[2]string{e.name, e.url}] = e
+	}
+
+	endpoints := make([]*endpointSeries, 0, len(cfg.RemoteWriteConfigs))
+	for _, rwConf := range cfg.RemoteWriteConfigs {
+		name, err := queueName(rwConf)
+		if err != nil {
+			return err
+		}
+		url := rwConf.URL.Redacted()
+
+		e, ok := prev[[2]string{name, url}]
+		if !ok {
+			e = &endpointSeries{name: name, url: url}
+			for _, w := range activeSeriesWindows {
+				e.windows = append(e.windows, newWindowedSketch(w.bucketSize, w.buckets))
+			}
+		}
+		e.rules = rwConf.WriteRelabelConfigs
+		endpoints = append(endpoints, e)
+	}
+
+	t.endpoints = endpoints
+	t.externalLabels = cfg.GlobalConfig.ExternalLabels
+	// New endpoints or rules must see the series again.
+	t.seen = make(map[storage.SeriesRef]struct{})
+	return nil
+}
+
+// queueName returns the name which the remote storage gives to the queue of
+// rwConf, which is used as the remote_name label of its metrics.
+func queueName(rwConf *config.RemoteWriteConfig) (string, error) {
+	if rwConf.Name != "" {
+		return rwConf.Name, nil
+	}
+	bb, err := yaml.Marshal(rwConf)
+	if err != nil {
+		return "", err
+	}
+	hash := md5.Sum(bb)
+	return hex.EncodeToString(hash[:])[:6], nil
+}
+
+// Observe records that a sample of the series l, with the global ref ref,
+// was appended.
+func (t *activeSeriesTracker) Observe(ref storage.SeriesRef, l labels.Labels) {
+	now := t.now()
+
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	if len(t.endpoints) == 0 || !t.markSeen(ref, now) {
+		return
+	}
+
+	// The hash of l is shared by the endpoints without write relabeling rules.
+	hash := l.Hash()
+
+	for _, e := range t.endpoints {
+		h := hash
+		if len(e.rules) > 0 {
+			relabeled, keep := relabel.Process(t.withExternalLabels(l), e.rules...)
+			if !keep {
+				continue
+			}
+			h = relabeled.Hash()
+		}
+
+		e.mut.Lock()
+		for _, w := range e.windows {
+			w.Insert(h, now)
+		}
+		e.mut.Unlock()
+	}
+}
+
+// markSeen marks the series with the global ref ref as observed in the bucket
+// of the first window at now. It returns false if the series was already
+// observed in that bucket. t.mut must be held.
+func (t *activeSeriesTracker) markSeen(ref storage.SeriesRef, now time.Time) bool {
+	epoch := now.UnixNano() / int64(activeSeriesWindows[0].bucketSize)
+
+	t.seenMut.Lock()
+	defer t.seenMut.Unlock()
+
+	if epoch != t.seenEpoch {
+		t.seen = make(map[storage.SeriesRef]struct{}, len(t.seen))
+		t.seenEpoch = epoch
+	}
+	if _, ok := t.seen[ref]; ok {
+		return false
+	}
+	t.seen[ref] = struct{}{}
+	return true
+}
+
+// withExternalLabels adds the external labels which l doesn't have to l, as
+// the remote storage does before applying the write relabeling rules.
+func (t *activeSeriesTracker) withExternalLabels(l labels.Labels) labels.Labels {
+	if t.externalLabels.IsEmpty() {
+		return l
+	}
+	lb := labels.NewBuilder(l)
+	t.externalLabels.Range(func(ext labels.Label) {
+		if !l.Has(ext.Name) {
+			lb.Set(ext.Name, ext.Value)
+		}
+	})
+	return lb.Labels()
+}
+
+// activeSeries holds the approximate number of series sent to an endpoint.
+type activeSeries struct {
+	Name    string            `river:"remote_name,attr"`
+	URL     string            `river:"url,attr"`
+	Windows map[string]uint64 `river:"active_series,attr"`
+}
+
+// ActiveSeries returns the approximate number of series sent to each
+// endpoint over each window.
+func (t *activeSeriesTracker) ActiveSeries() []activeSeries {
+	now := t.now()
+
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+
+	res := make([]activeSeries, 0, len(t.endpoints))
+	for _, e := range t.endpoints {
+		as := activeSeries{Name: e.name, URL: e.url, Windows: make(map[string]uint64, len(e.windows))}
+		e.mut.Lock()
+		for i, w := range e.windows {
+			as.Windows[activeSeriesWindows[i].name] = w.Estimate(now)
+		}
+		e.mut.Unlock()
+		res = append(res, as)
+	}
+	return res
+}
+
+// Describe implements prometheus.Collector.
+func (t *activeSeriesTracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeSeriesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (t *activeSeriesTracker) Collect(ch chan<- prometheus.Metric) {
+	for _, as := range t.ActiveSeries() {
+		for _, w := range activeSeriesWindows {
+			ch <- prometheus.MustNewConstMetric(activeSeriesDesc, prometheus.GaugeValue, float64(as.Windows[w.name]), as.Name, as.URL, w.name)
+		}
+	}
+}
+
+// windowedSketch approximates the number of distinct hashes inserted over a
+// sliding window, made of buckets of bucketSize.
+type windowedSketch struct {
+	bucketSize time.Duration
+	sketches   []*hyperloglog.Sketch
+	epochs     []int64 // Index since the Unix epoch of the bucket held by each sketch.
+}
+
+func newWindowedSketch(bucketSize time.Duration, buckets int) *windowedSketch {
+	return &windowedSketch{
+		bucketSize: bucketSize,
+		sketches:   make([]*hyperloglog.Sketch, buckets),
+		epochs:     make([]int64, buckets),
+	}
+}
+
+func (w *windowedSketch) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(w.bucketSize)
+}
+
+// Insert inserts hash in the bucket of now.
+func (w *windowedSketch) Insert(hash uint64, now time.Time) {
+	epoch := w.epoch(now)
+	i := int(epoch % int64(len(w.sketches)))
+	if w.sketches[i] == nil || w.epochs[i] != epoch {
+		w.sketches[i] = hyperloglog.New14()
+		w.epochs[i] = epoch
+	}
+	w.sketches[i].InsertHash(hash)
+}
+
+// Estimate returns the approximate number of distinct hashes inserted in the
+// buckets of the window ending at now.
+func (w *windowedSketch) Estimate(now time.Time) uint64 {
+	epoch := w.epoch(now)
+
+	var merged *hyperloglog.Sketch
+	for i, sk := range w.sketches {
+		if sk == nil || epoch-w.epochs[i] >= int64(len(w.sketches)) {
+			continue
+		}
+		if merged == nil {
+			merged = sk.Clone()
+			continue
+		}
+		// Sketches have the same precision, so merging them can't fail.
+		_ = merged.Merge(sk)
+	}
+	if merged == nil {
+		return 0
+	}
+	return merged.Estimate()
+}
@@ -0,0 +1,171 @@
+package remotewrite
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	promconfig "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowedSketch(t *testing.T) {
+	now := time.Unix(0, 0)
+	w := newWindowedSketch(10*time.Minute, 6)
+
+	for i := 0; i < 100; i++ {
+		w.Insert(uint64(i)*0x9E3779B97F4A7C15, now)
+	}
+	require.Equal(t, uint64(100), w.Estimate(now))
+
+	// Series inserted again in a later bucket are only counted once.
+	now = now.Add(30 * time.Minute)
+	for i := 50; i < 150; i++ {
+		w.Insert(uint64(i)*0x9E3779B97F4A7C15, now)
+	}
+	require.Equal(t, uint64(150), w.Estimate(now))
+
+	// The first bucket leaves the window after an hour.
+	now = now.Add(31 * time.Minute)
+	require.Equal(t, uint64(100), w.Estimate(now))
+
+	now = now.Add(time.Hour)
+	require.Equal(t, uint64(0), w.Estimate(now))
+}
+
+func TestActiveSeriesTracker(t *testing.T) {
+	now := time.Unix(0, 0)
+	tracker := newActiveSeriesTracker()
+	tracker.now = func() time.Time { return now }
+
+	dropDebug := &relabel.Config{
+		SourceLabels: model.LabelNames{"level"},
+		Regex:        relabel.MustNewRegexp("debug"),
+		Action:       relabel.Drop,
+	}
+	// Only the external label tells the series apart once instance is dropped.
+	dropInstance := &relabel.Config{
+		Regex:  relabel.MustNewRegexp("instance"),
+		Action: relabel.LabelDrop,
+	}
+	require.NoError(t, tracker.SetConfig(&config.Config{
+		GlobalConfig: config.GlobalConfig{ExternalLabels: labels.FromStrings("cluster", "prod")},
+		RemoteWriteConfigs: []*config.RemoteWriteConfig{
+			{Name: "all", URL: testURL(t, "http://all/push")},
+			{Name: "filtered", URL: testURL(t, "http://filtered/push"), WriteRelabelConfigs: []*relabel.Config{dropDebug, dropInstance}},
+		},
+	}))
+
+	for i := 0; i < 10; i++ {
+		level := "info"
+		if i%2 == 0 {
+			level = "debug"
+		}
+		tracker.Observe(storage.SeriesRef(i+1), labels.FromStrings("__name__", "up", "instance", fmt.Sprint(i), "level", level))
+	}
+
+	require.Equal(t, []activeSeries{
+		{Name: "all", URL: "http://all/push", Windows: map[string]uint64{"1h": 10, "24h": 10}},
+		{Name: "filtered", URL: "http://filtered/push", Windows: map[string]uint64{"1h": 1, "24h": 1}},
+	}, tracker.ActiveSeries())
+
+	// Counts are kept for endpoints which aren't changed.
+	require.NoError(t, tracker.SetConfig(&config.Config{
+		RemoteWriteConfigs: []*config.RemoteWriteConfig{
+			{Name: "all", URL: testURL(t, "http://all/push")},
+			{Name: "other", URL: testURL(t, "http://other/push")},
+		},
+	}))
+	now = now.Add(2 * time.Hour)
+	require.Equal(t, []activeSeries{
+		{Name: "all", URL: "http://all/push", Windows: map[string]uint64{"1h": 0, "24h": 10}},
+		{Name: "other", URL: "http://other/push", Windows: map[string]uint64{"1h": 0, "24h": 0}},
+	}, tracker.ActiveSeries())
+}
+
+func TestActiveSeriesTracker_Seen(t *testing.T) {
+	now := time.Unix(0, 0)
+	tracker := newActiveSeriesTracker()
+	tracker.now = func() time.Time { return now }
+
+	require.NoError(t, tracker.SetConfig(&config.Config{
+		RemoteWriteConfigs: []*config.RemoteWriteConfig{
+			{Name: "all", URL: testURL(t, "http://all/push")},
+		},
+	}))
+	series := labels.FromStrings("__name__", "up")
+	tracker.Observe(1, series)
+	require.False(t, tracker.markSeen(1, now), "series should be seen in the current bucket")
+
+	// A series observed again in a later bucket is inserted again, so it stays
+	// in the 1h window after the first bucket leaves it.
+	now = now.Add(50 * time.Minute)
+	tracker.Observe(1, series)
+	now = now.Add(20 * time.Minute)
+	require.Equal(t, uint64(1), tracker.ActiveSeries()[0].Windows["1h"])
+
+	// Endpoints added in the middle of a bucket see the series already observed
+	// in it.
+	tracker.Observe(1, series)
+	require.NoError(t, tracker.SetConfig(&config.Config{
+		RemoteWriteConfigs: []*config.RemoteWriteConfig{
+			{Name: "all", URL: testURL(t, "http://all/push")},
+			{Name: "other", URL: testURL(t, "http://other/push")},
+		},
+	}))
+	tracker.Observe(1, series)
+	require.Equal(t, []activeSeries{
+		{Name: "all", URL: "http://all/push", Windows: map[string]uint64{"1h": 1, "24h": 1}},
+		{Name: "other", URL: "http://other/push", Windows: map[string]uint64{"1h": 1, "24h": 1}},
+	}, tracker.ActiveSeries())
+}
+
+func BenchmarkActiveSeriesTracker_Observe(b *testing.B) {
+	dropDebug := &relabel.Config{
+		SourceLabels: model.LabelNames{"level"},
+		Regex:        relabel.MustNewRegexp("debug"),
+		Action:       relabel.Drop,
+	}
+	series := make([]labels.Labels, 1000)
+	for i := range series {
+		series[i] = labels.FromStrings("__name__", "up", "instance", fmt.Sprint(i), "job", "test", "level", "info")
+	}
+
+	for _, tc := range []struct {
+		name  string
+		rules []*relabel.Config
+	}{
+		{name: "no_rules"},
+		{name: "write_relabel", rules: []*relabel.Config{dropDebug}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			tracker := newActiveSeriesTracker()
+			require.NoError(b, tracker.SetConfig(&config.Config{
+				GlobalConfig: config.GlobalConfig{ExternalLabels: labels.FromStrings("cluster", "prod")},
+				RemoteWriteConfigs: []*config.RemoteWriteConfig{
+					{Name: "endpoint", URL: testURL(b, "http://endpoint/push"), WriteRelabelConfigs: tc.rules},
+				},
+			}))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ref := i % len(series)
+				tracker.Observe(storage.SeriesRef(ref+1), series[ref])
+			}
+		})
+	}
+}
+
+func testURL(t testing.TB, s string) *promconfig.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	return &promconfig.URL{URL: u}
+}
@@ -3,9 +3,11 @@ package remotewrite
 import (
 	"context"
 	"fmt"
+	"maps"
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"time"
 
@@ -33,6 +35,12 @@ import (
 // TODO(rfratto): This should be exposed. How do we want to expose this?
 var remoteFlushDeadline = 1 * time.Minute
 
+// externalLabelsBatchPeriod is how long changes to the external labels are
+// batched before they're applied. Applying new external labels restarts the
+// remote write queues, so labels which flap during the period don't restart
+// them more than once.
+var externalLabelsBatchPeriod = 5 * time.Second
+
 func init() {
 	remote.UserAgent = useragent.Get()
 
@@ -57,11 +65,18 @@ type Component struct {
 	remoteStore *remote.Storage
 	storage     storage.Storage
 	exited      atomic.Bool
+	readOnly    atomic.Bool
 
 	mut sync.RWMutex
 	cfg Arguments
 
-	receiver *prometheus.Interceptor
+	// applied holds the arguments the remote storage was last configured with.
+	// Its external labels lag behind cfg while a change is batched.
+	applied          *Arguments
+	externalLabelsCh chan struct{}
+
+	receiver     *prometheus.Interceptor
+	activeSeries *activeSeriesTracker
 }
 
 // New creates a new prometheus.remote_write component.
@@ -96,6 +111,12 @@ func New(o component.Options, c Arguments) (*Component, error) {
 		walStore:    walStorage,
 		remoteStore: remoteStore,
 		storage:     storage.NewFanout(o.Logger, walStorage, remoteStore),
+
+		externalLabelsCh: make(chan struct{}, 1),
+		activeSeries:     newActiveSeriesTracker(),
+	}
+	if err := o.Registerer.Register(res.activeSeries); err != nil {
+		return nil, err
 	}
 	res.receiver = prometheus.NewInterceptor(
 		res.storage,
@@ -108,10 +129,11 @@ func New(o component.Options, c Arguments) (*Component, error) {
 		// ID" to ensure Flow compatibility.
 
 		prometheus.WithAppendHook(func(globalRef storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
-			if res.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
+			if err := res.checkWritable(); err != nil {
+				return 0, err
 			}
 
+			res.activeSeries.Observe(globalRef, l)
 			localID := ls.GetLocalRefID(res.opts.ID, uint64(globalRef))
 			newRef, nextErr := next.Append(storage.SeriesRef(localID), l, t, v)
 			if localID == 0 {
@@ -120,10 +142,11 @@ func New(o component.Options, c Arguments) (*Component, error) {
 			return globalRef, nextErr
 		}),
 		prometheus.WithHistogramHook(func(globalRef storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
-			if res.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
+			if err := res.checkWritable(); err != nil {
+				return 0, err
 			}
 
+			res.activeSeries.Observe(globalRef, l)
 			localID := ls.GetLocalRefID(res.opts.ID, uint64(globalRef))
 			newRef, nextErr := next.AppendHistogram(storage.SeriesRef(localID), l, t, h, fh)
 			if localID == 0 {
@@ -132,8 +155,8 @@ func New(o component.Options, c Arguments) (*Component, error) {
 			return globalRef, nextErr
 		}),
 		prometheus.WithMetadataHook(func(globalRef storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
-			if res.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
+			if err := res.checkWritable(); err != nil {
+				return 0, err
 			}
 
 			localID := ls.GetLocalRefID(res.opts.ID, uint64(globalRef))
@@ -144,8 +167,8 @@ func New(o component.Options, c Arguments) (*Component, error) {
 			return globalRef, nextErr
 		}),
 		prometheus.WithExemplarHook(func(globalRef storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
-			if res.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
+			if err := res.checkWritable(); err != nil {
+				return 0, err
 			}
 
 			localID := ls.GetLocalRefID(res.opts.ID, uint64(globalRef))
@@ -169,7 +192,21 @@ func New(o component.Options, c Arguments) (*Component, error) {
 
 func startTime() (int64, error) { return 0, nil }
 
-var _ component.Component = (*Component)(nil)
+// checkWritable returns an error if new data can't be written to the WAL.
+func (c *Component) checkWritable() error {
+	switch {
+	case c.exited.Load():
+		return fmt.Errorf("%s has exited", c.opts.ID)
+	case c.readOnly.Load():
+		return fmt.Errorf("%s is read-only", c.opts.ID)
+	}
+	return nil
+}
+
+var (
+	_ component.Component      = (*Component)(nil)
+	_ component.DebugComponent = (*Component)(nil)
+)
 
 // Run implements Component.
 func (c *Component) Run(ctx context.Context) error {
@@ -188,58 +225,94 @@ func (c *Component) Run(ctx context.Context) error {
 	// deleted until at least some new data has been sent.
 	var lastTs = int64(math.MinInt64)
 
+	truncateTimer := time.NewTimer(c.truncateFrequency())
+	defer truncateTimer.Stop()
+
+	// externalLabelsTimer fires at the end of the period during which changes
+	// to the external labels are batched.
+	var externalLabelsTimer <-chan time.Time
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case <-time.After(c.truncateFrequency()):
-			// We retrieve the current min/max keepalive time at once, since
-			// retrieving them separately could lead to issues where we have an older
-			// value for min which is now larger than max.
-			c.mut.RLock()
-			var (
-				minWALTime = c.cfg.WALOptions.MinKeepaliveTime
-				maxWALTime = c.cfg.WALOptions.MaxKeepaliveTime
-			)
-			c.mut.RUnlock()
-
-			// The timestamp ts is used to determine which series are not receiving
-			// samples and may be deleted from the WAL. Their most recent append
-			// timestamp is compared to ts, and if that timestamp is older than ts,
-			// they are considered inactive and may be deleted.
-			//
-			// Subtracting a duration from ts will delay when it will be considered
-			// inactive and scheduled for deletion.
-			ts := c.remoteStore.LowestSentTimestamp() - minWALTime.Milliseconds()
-			if ts < 0 {
-				ts = 0
+		case <-c.externalLabelsCh:
+			if externalLabelsTimer == nil {
+				externalLabelsTimer = time.After(externalLabelsBatchPeriod)
 			}
+		case <-externalLabelsTimer:
+			externalLabelsTimer = nil
+			c.applyExternalLabels()
+		case <-truncateTimer.C:
+			c.truncateWAL(&lastTs)
+			truncateTimer.Reset(c.truncateFrequency())
+		}
+	}
+}
 
-			// Network issues can prevent the result of LowestSentTimestamp from
-			// changing. We don't want data in the WAL to grow forever, so we set a cap
-			// on the maximum age data can be. If our ts is older than this cutoff point,
-			// we'll shift it forward to start deleting very stale data.
-			if maxTS := timestamp.FromTime(time.Now().Add(-maxWALTime)); ts < maxTS {
-				ts = maxTS
-			}
+// truncateWAL deletes the data which has been sent or is too old from the
+// WAL. lastTs is the timestamp of the previous truncation.
+func (c *Component) truncateWAL(lastTs *int64) {
+	// We retrieve the current min/max keepalive time at once, since
+	// retrieving them separately could lead to issues where we have an older
+	// value for min which is now larger than max.
+	c.mut.RLock()
+	var (
+		minWALTime = c.cfg.WALOptions.MinKeepaliveTime
+		maxWALTime = c.cfg.WALOptions.MaxKeepaliveTime
+	)
+	c.mut.RUnlock()
 
-			if ts == lastTs {
-				level.Debug(c.log).Log("msg", "not truncating the WAL, remote_write timestamp is unchanged", "ts", ts)
-				continue
-			}
-			lastTs = ts
-
-			level.Debug(c.log).Log("msg", "truncating the WAL", "ts", ts)
-			err := c.walStore.Truncate(ts)
-			if err != nil {
-				// The only issue here is larger disk usage and a greater replay time,
-				// so we'll only log this as a warning.
-				level.Warn(c.log).Log("msg", "could not truncate WAL", "err", err)
-			}
-		}
+	// The timestamp ts is used to determine which series are not receiving
+	// samples and may be deleted from the WAL. Their most recent append
+	// timestamp is compared to ts, and if that timestamp is older than ts,
+	// they are considered inactive and may be deleted.
+	//
+	// Subtracting a duration from ts will delay when it will be considered
+	// inactive and scheduled for deletion.
+	ts := c.remoteStore.LowestSentTimestamp() - minWALTime.Milliseconds()
+	if ts < 0 {
+		ts = 0
+	}
+
+	// Network issues can prevent the result of LowestSentTimestamp from
+	// changing. We don't want data in the WAL to grow forever, so we set a cap
+	// on the maximum age data can be. If our ts is older than this cutoff point,
+	// we'll shift it forward to start deleting very stale data.
+	if maxTS := timestamp.FromTime(time.Now().Add(-maxWALTime)); ts < maxTS {
+		ts = maxTS
+	}
+
+	if ts == *lastTs {
+		level.Debug(c.log).Log("msg", "not truncating the WAL, remote_write timestamp is unchanged", "ts", ts)
+		return
+	}
+	*lastTs = ts
+
+	level.Debug(c.log).Log("msg", "truncating the WAL", "ts", ts)
+	err := c.walStore.Truncate(ts)
+	if err != nil {
+		// The only issue here is larger disk usage and a greater replay time,
+		// so we'll only log this as a warning.
+		level.Warn(c.log).Log("msg", "could not truncate WAL", "err", err)
 	}
 }
 
+// applyExternalLabels applies the batched changes to the external labels.
+func (c *Component) applyExternalLabels() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.applied == nil || maps.Equal(c.applied.ExternalLabels, c.cfg.ExternalLabels) {
+		return
+	}
+	if err := c.applyConfig(c.cfg); err != nil {
+		level.Error(c.log).Log("msg", "failed to apply new external labels", "err", err)
+		return
+	}
+	level.Debug(c.log).Log("msg", "applied new external labels")
+}
+
 func (c *Component) truncateFrequency() time.Duration {
 	c.mut.RLock()
 	defer c.mut.RUnlock()
@@ -253,6 +326,40 @@ func (c *Component) Update(newConfig component.Arguments) error {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
+	if cfg.WALOptions.ReadOnly != c.readOnly.Load() {
+		level.Info(c.log).Log("msg", "changed the WAL mode", "read_only", cfg.WALOptions.ReadOnly)
+	}
+	c.readOnly.Store(cfg.WALOptions.ReadOnly)
+
+	// External labels are usually set from the exports of other components,
+	// and can change often. Their changes are batched and applied by Run.
+	if c.applied != nil && onlyExternalLabelsChanged(*c.applied, cfg) {
+		c.cfg = cfg
+		select {
+		case c.externalLabelsCh <- struct{}{}:
+		default:
+			// A change is already batched.
+		}
+		return nil
+	}
+
+	if err := c.applyConfig(cfg); err != nil {
+		return err
+	}
+	c.cfg = cfg
+	return nil
+}
+
+// onlyExternalLabelsChanged returns whether prev and next only differ by
+// their external labels.
+func onlyExternalLabelsChanged(prev, next Arguments) bool {
+	prev.ExternalLabels, next.ExternalLabels = nil, nil
+	return reflect.DeepEqual(prev, next)
+}
+
+// applyConfig configures the remote storage with cfg. mut must be held when
+// called.
+func (c *Component) applyConfig(cfg Arguments) error {
 	convertedConfig, err := convertConfigs(cfg)
 	if err != nil {
 		return err
@@ -268,7 +375,19 @@ func (c *Component) Update(newConfig component.Arguments) error {
 	if err != nil {
 		return err
 	}
+	if err := c.activeSeries.SetConfig(convertedConfig); err != nil {
+		return err
+	}
 
-	c.cfg = cfg
+	c.applied = &cfg
 	return nil
 }
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	return debugInfo{Endpoints: c.activeSeries.ActiveSeries()}
+}
+
+type debugInfo struct {
+	Endpoints []activeSeries `river:"endpoint,block"`
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -181,6 +182,133 @@ func TestUpdate(t *testing.T) {
 	}})
 }
 
+// TestUpdateExternalLabels ensures that changes to only the external labels
+// are batched before being applied.
+func TestUpdateExternalLabels(t *testing.T) {
+	writeResult := make(chan *prompb.WriteRequest, 10)
+	srv := newTestServer(t, writeResult)
+	defer srv.Close()
+
+	configWithLabels := func(cluster string) remotewrite.Arguments {
+		return testArgsForConfig(t, fmt.Sprintf(`
+			external_labels = {
+				cluster = %q,
+			}
+			endpoint {
+				name           = "test-url"
+				url            = "%s/api/v1/write"
+				remote_timeout = "100ms"
+
+				queue_config {
+					batch_send_deadline = "100ms"
+				}
+			}
+		`, cluster, srv.URL))
+	}
+
+	tc, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.remote_write")
+	require.NoError(t, err)
+	go func() {
+		err = tc.Run(componenttest.TestContext(t), configWithLabels("local"))
+		require.NoError(t, err)
+	}()
+	require.NoError(t, tc.WaitRunning(5*time.Second))
+
+	sample1Time := time.Now().Add(time.Minute).UnixMilli()
+	sendMetric(t, tc, labels.FromStrings("foo", "bar"), sample1Time, 12)
+	assertReceived(t, writeResult, []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "cluster", Value: "local"}, {Name: "foo", Value: "bar"}},
+		Samples: []prompb.Sample{{Timestamp: sample1Time, Value: 12}},
+	}})
+
+	// The changes are batched, so only the latest one is applied.
+	require.NoError(t, tc.Update(configWithLabels("flapping")))
+	require.NoError(t, tc.Update(configWithLabels("remote")))
+
+	sample2Time := time.Now().Add(2 * time.Minute).UnixMilli()
+	sendMetric(t, tc, labels.FromStrings("fizz", "buzz"), sample2Time, 34)
+
+	timeout := time.After(time.Minute)
+	for applied := false; !applied; {
+		select {
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for metrics with the new external labels")
+		case res := <-writeResult:
+			for _, ts := range res.Timeseries {
+				for _, l := range ts.Labels {
+					if l.Name != "cluster" {
+						continue
+					}
+					require.NotEqual(t, "flapping", l.Value)
+					applied = applied || l.Value == "remote"
+				}
+			}
+		}
+	}
+}
+
+// TestReadOnly ensures that a read-only WAL rejects new samples while the
+// samples already in the WAL are still sent.
+func TestReadOnly(t *testing.T) {
+	var (
+		writeResult = make(chan *prompb.WriteRequest, 10)
+		available   atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		req, err := remote.DecodeWriteRequest(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeResult <- req
+	}))
+	defer srv.Close()
+
+	configWithReadOnly := func(readOnly bool) remotewrite.Arguments {
+		return testArgsForConfig(t, fmt.Sprintf(`
+			endpoint {
+				name           = "test-url"
+				url            = "%s/api/v1/write"
+				remote_timeout = "100ms"
+
+				queue_config {
+					batch_send_deadline = "100ms"
+					max_backoff         = "100ms"
+				}
+			}
+			wal {
+				read_only = %t
+			}
+		`, srv.URL, readOnly))
+	}
+
+	tc, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.remote_write")
+	require.NoError(t, err)
+	go func() {
+		err = tc.Run(componenttest.TestContext(t), configWithReadOnly(false))
+		require.NoError(t, err)
+	}()
+	require.NoError(t, tc.WaitRunning(5*time.Second))
+
+	sampleTime := time.Now().Add(time.Minute).UnixMilli()
+	sendMetric(t, tc, labels.FromStrings("foo", "bar"), sampleTime, 12)
+
+	require.NoError(t, tc.Update(configWithReadOnly(true)))
+	appender := tc.Exports().(remotewrite.Exports).Receiver.Appender(context.Background())
+	_, err = appender.Append(0, labels.FromStrings("fizz", "buzz"), sampleTime, 34)
+	require.ErrorContains(t, err, "is read-only")
+
+	available.Store(true)
+	assertReceived(t, writeResult, []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "foo", Value: "bar"}},
+		Samples: []prompb.Sample{{Timestamp: sampleTime, Value: 12}},
+	}})
+}
+
 func assertReceived(t *testing.T, writeResult chan *prompb.WriteRequest, expect []prompb.TimeSeries) {
 	select {
 	case <-time.After(time.Minute):
@@ -202,6 +202,8 @@ type WALOptions struct {
 	TruncateFrequency time.Duration `river:"truncate_frequency,attr,optional"`
 	MinKeepaliveTime  time.Duration `river:"min_keepalive_time,attr,optional"`
 	MaxKeepaliveTime  time.Duration `river:"max_keepalive_time,attr,optional"`
+	// ReadOnly rejects new data while the data in the WAL keeps being sent.
+	ReadOnly bool `river:"read_only,attr,optional"`
 }
 
 // SetToDefault implements river.Defaulter.
@@ -0,0 +1,377 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/drops"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/hashicorp/go-multierror"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.route",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// defaultRouteName is the name used in metrics for samples which didn't match
+// any route.
+const defaultRouteName = "default"
+
+// Arguments holds values which are used to configure the prometheus.route
+// component.
+type Arguments struct {
+	// The routes to evaluate for each series, in order.
+	Routes []RouteArguments `river:"route,block,optional"`
+
+	// Where samples of series which don't match any route should be forwarded
+	// to.
+	DefaultForwardTo []storage.Appendable `river:"default_forward_to,attr,optional"`
+}
+
+// RouteArguments configures a single route of the prometheus.route component.
+type RouteArguments struct {
+	Name      string               `river:"name,attr"`
+	Selector  string               `river:"selector,attr"`
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+	Continue  bool                 `river:"continue,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (arg *Arguments) Validate() error {
+	names := make(map[string]struct{}, len(arg.Routes))
+	for _, r := range arg.Routes {
+		if r.Name == "" {
+			return fmt.Errorf("route name must not be empty")
+		}
+		if r.Name == defaultRouteName {
+			return fmt.Errorf("route name %q is reserved for the default route", defaultRouteName)
+		}
+		if _, ok := names[r.Name]; ok {
+			return fmt.Errorf("found duplicate route name %q", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if _, err := parser.ParseMetricSelector(r.Selector); err != nil {
+			return fmt.Errorf("invalid selector for route %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.route component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// route is a compiled route.
+type route struct {
+	name     string
+	matchers []*labels.Matcher
+	fanout   *prometheus.Fanout
+	cont     bool
+	samples  prometheus_client.Counter
+}
+
+// Matches returns true if lbls match the selector of the route.
+func (r *route) Matches(lbls labels.Labels) bool {
+	for _, m := range r.matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Component implements the prometheus.route component.
+type Component struct {
+	opts           component.Options
+	ls             labelstore.LabelStore
+	samplesRouted  *prometheus_client.CounterVec
+	samplesDropped prometheus_client.Counter
+	dropped        *drops.Recorder
+	fanoutMetrics  *prometheus.FanoutMetrics
+
+	mut          sync.RWMutex
+	routes       []*route
+	defaultRoute *route
+	fanouts      map[string]*prometheus.Fanout
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+	_ storage.Appendable  = (*Component)(nil)
+)
+
+// New creates a new prometheus.route component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	c := &Component{
+		opts:    o,
+		ls:      data.(labelstore.LabelStore),
+		fanouts: make(map[string]*prometheus.Fanout),
+		dropped: drops.NewRecorder(o, drops.SignalMetrics),
+
+		fanoutMetrics: prometheus.NewFanoutMetrics(o.Registerer),
+	}
+	c.samplesRouted = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_route_samples_routed_total",
+		Help: "Total number of samples forwarded by each route",
+	}, []string{"route"})
+	c.samplesDropped = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_route_samples_dropped_total",
+		Help: "Total number of samples dropped because they didn't match any route",
+	})
+	for _, metric := range []prometheus_client.Collector{c.samplesRouted, c.samplesDropped} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	routes := make([]*route, 0, len(newArgs.Routes))
+	fanouts := make(map[string]*prometheus.Fanout, len(newArgs.Routes)+1)
+	for _, ra := range newArgs.Routes {
+		matchers, err := parser.ParseMetricSelector(ra.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector for route %q: %w", ra.Name, err)
+		}
+		routes = append(routes, &route{
+			name:     ra.Name,
+			matchers: matchers,
+			fanout:   c.fanoutFor(fanouts, ra.Name, ra.ForwardTo),
+			cont:     ra.Continue,
+			samples:  c.samplesRouted.WithLabelValues(ra.Name),
+		})
+	}
+
+	c.routes = routes
+	c.defaultRoute = nil
+	if len(newArgs.DefaultForwardTo) > 0 {
+		c.defaultRoute = &route{
+			name:    defaultRouteName,
+			fanout:  c.fanoutFor(fanouts, defaultRouteName, newArgs.DefaultForwardTo),
+			samples: c.samplesRouted.WithLabelValues(defaultRouteName),
+		}
+	}
+	c.fanouts = fanouts
+	return nil
+}
+
+// fanoutFor returns the fanout of the route with the given name, reusing the
+// existing one if there is any. The fanout is added to fanouts. c.mut must be
+// held when calling fanoutFor.
+func (c *Component) fanoutFor(fanouts map[string]*prometheus.Fanout, name string, children []storage.Appendable) *prometheus.Fanout {
+	f, ok := c.fanouts[name]
+	if ok {
+		f.UpdateChildren(children)
+	} else {
+		f = prometheus.NewFanoutWithMetrics(children, c.opts.ID, c.fanoutMetrics, c.ls)
+	}
+	fanouts[name] = f
+	return f
+}
+
+// Appender implements storage.Appendable.
+func (c *Component) Appender(ctx context.Context) storage.Appender {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	app := &appender{
+		routes:         make([]*routeAppender, 0, len(c.routes)),
+		ls:             c.ls,
+		samplesDropped: c.samplesDropped,
+		dropped:        c.dropped,
+	}
+	for _, r := range c.routes {
+		app.routes = append(app.routes, &routeAppender{route: r, ctx: ctx})
+	}
+	if c.defaultRoute != nil {
+		app.defaultRoute = &routeAppender{route: c.defaultRoute, ctx: ctx}
+	}
+	return app
+}
+
+// routeAppender lazily creates the appender of a route the first time a
+// series is routed through it.
+type routeAppender struct {
+	*route
+	ctx context.Context
+	app storage.Appender
+}
+
+func (ra *routeAppender) Appender() storage.Appender {
+	if ra.app == nil {
+		ra.app = ra.fanout.Appender(ra.ctx)
+	}
+	return ra.app
+}
+
+// appender forwards each series to the appenders of its matching routes.
+type appender struct {
+	routes         []*routeAppender
+	defaultRoute   *routeAppender
+	ls             labelstore.LabelStore
+	samplesDropped prometheus_client.Counter
+	dropped        *drops.Recorder
+}
+
+var _ storage.Appender = (*appender)(nil)
+
+// forEach calls fn with the appender of every route l should be forwarded to.
+// isSample is set when a sample is forwarded, to update the route metrics.
+func (a *appender) forEach(l labels.Labels, isSample bool, fn func(storage.Appender) error) error {
+	var (
+		multiErr error
+		matched  bool
+	)
+	for _, r := range a.routes {
+		if !r.Matches(l) {
+			continue
+		}
+		matched = true
+		if isSample {
+			r.samples.Inc()
+		}
+		if err := fn(r.Appender()); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+		if !r.cont {
+			return multiErr
+		}
+	}
+	if matched {
+		return multiErr
+	}
+
+	if a.defaultRoute == nil {
+		if isSample {
+			a.samplesDropped.Inc()
+			a.dropped.Add(drops.ReasonNoRoute, 1)
+		}
+		return nil
+	}
+	if isSample {
+		a.defaultRoute.samples.Inc()
+	}
+	return fn(a.defaultRoute.Appender())
+}
+
+// globalRef returns the global ref of l, so that children of the routes see
+// the same refs as children of a fanout.
+func (a *appender) globalRef(ref storage.SeriesRef, l labels.Labels) storage.SeriesRef {
+	if ref == 0 {
+		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
+	}
+	return ref
+}
+
+// Append satisfies the Appender interface.
+func (a *appender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref = a.globalRef(ref, l)
+	return ref, a.forEach(l, true, func(app storage.Appender) error {
+		_, err := app.Append(ref, l, t, v)
+		return err
+	})
+}
+
+// AppendExemplar satisfies the Appender interface.
+func (a *appender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	ref = a.globalRef(ref, l)
+	return ref, a.forEach(l, false, func(app storage.Appender) error {
+		_, err := app.AppendExemplar(ref, l, e)
+		return err
+	})
+}
+
+// AppendHistogram satisfies the Appender interface.
+func (a *appender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	ref = a.globalRef(ref, l)
+	return ref, a.forEach(l, true, func(app storage.Appender) error {
+		_, err := app.AppendHistogram(ref, l, t, h, fh)
+		return err
+	})
+}
+
+// UpdateMetadata satisfies the Appender interface.
+func (a *appender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	ref = a.globalRef(ref, l)
+	return ref, a.forEach(l, false, func(app storage.Appender) error {
+		_, err := app.UpdateMetadata(ref, l, m)
+		return err
+	})
+}
+
+// Commit satisfies the Appender interface.
+func (a *appender) Commit() error {
+	return a.finish(storage.Appender.Commit)
+}
+
+// Rollback satisfies the Appender interface.
+func (a *appender) Rollback() error {
+	return a.finish(storage.Appender.Rollback)
+}
+
+func (a *appender) finish(fn func(storage.Appender) error) error {
+	var multiErr error
+	for _, r := range a.allRoutes() {
+		if r.app == nil {
+			continue
+		}
+		if err := fn(r.app); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+	}
+	return multiErr
+}
+
+func (a *appender) allRoutes() []*routeAppender {
+	if a.defaultRoute == nil {
+		return a.routes
+	}
+	return append(a.routes[:len(a.routes):len(a.routes)], a.defaultRoute)
+}
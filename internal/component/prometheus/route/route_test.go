@@ -0,0 +1,171 @@
+package route
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouting(t *testing.T) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+
+	received := map[string][]string{}
+	receiver := func(name string) storage.Appendable {
+		return prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+			received[name] = append(received[name], l.Get("__name__"))
+			return ref, nil
+		}))
+	}
+
+	c, err := New(component.Options{
+		ID:            "1",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, Arguments{
+		Routes: []RouteArguments{
+			{Name: "infra", Selector: `{__name__=~"node_.+"}`, ForwardTo: []storage.Appendable{receiver("infra")}, Continue: true},
+			{Name: "prod", Selector: `{env="prod"}`, ForwardTo: []storage.Appendable{receiver("prod")}},
+		},
+		DefaultForwardTo: []storage.Appendable{receiver("default")},
+	})
+	require.NoError(t, err)
+
+	app := c.Appender(context.Background())
+	ts := time.Now().UnixMilli()
+	for _, lbls := range []labels.Labels{
+		labels.FromStrings("__name__", "node_cpu", "env", "prod"),
+		labels.FromStrings("__name__", "node_memory", "env", "dev"),
+		labels.FromStrings("__name__", "http_requests", "env", "prod"),
+		labels.FromStrings("__name__", "http_requests", "env", "dev"),
+	} {
+		_, err := app.Append(0, lbls, ts, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, map[string][]string{
+		"infra":   {"node_cpu", "node_memory"},
+		"prod":    {"node_cpu", "http_requests"},
+		"default": {"http_requests"},
+	}, received)
+	require.Equal(t, 2.0, testutil.ToFloat64(c.samplesRouted.WithLabelValues("infra")))
+	require.Equal(t, 2.0, testutil.ToFloat64(c.samplesRouted.WithLabelValues("prod")))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.samplesRouted.WithLabelValues(defaultRouteName)))
+}
+
+func TestRouting_RefsAndMetrics(t *testing.T) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+
+	var refs []storage.SeriesRef
+	receiver := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, _ labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		refs = append(refs, ref)
+		return ref, nil
+	}))
+
+	reg := prom.NewRegistry()
+	c, err := New(component.Options{
+		ID:            "1",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    reg,
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, Arguments{
+		Routes: []RouteArguments{
+			{Name: "prod", Selector: `{env="prod"}`, ForwardTo: []storage.Appendable{receiver}},
+		},
+		DefaultForwardTo: []storage.Appendable{receiver},
+	})
+	require.NoError(t, err)
+
+	app := c.Appender(context.Background())
+	ts := time.Now().UnixMilli()
+	prod := labels.FromStrings("__name__", "up", "env", "prod")
+	ref, err := app.Append(0, prod, ts, 1)
+	require.NoError(t, err)
+	require.Equal(t, storage.SeriesRef(ls.GetOrAddGlobalRefID(prod)), ref)
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "env", "dev"), ts, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	// Children get the global refs of the series, like with a fanout.
+	require.Len(t, refs, 2)
+	require.Equal(t, ref, refs[0])
+	require.NotZero(t, refs[1])
+
+	// The fanouts of all routes share their metrics.
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+		# HELP agent_prometheus_forwarded_samples_total Total number of samples sent to downstream components.
+		# TYPE agent_prometheus_forwarded_samples_total counter
+		agent_prometheus_forwarded_samples_total 2
+	`), "agent_prometheus_forwarded_samples_total"))
+}
+
+func TestValidator(t *testing.T) {
+	tt := []struct {
+		name        string
+		cfg         string
+		expectedErr string
+	}{
+		{
+			name: "duplicate names",
+			cfg: `
+				route {
+					name       = "a"
+					selector   = "{env=\"prod\"}"
+					forward_to = []
+				}
+				route {
+					name       = "a"
+					selector   = "{env=\"dev\"}"
+					forward_to = []
+				}`,
+			expectedErr: `found duplicate route name "a"`,
+		},
+		{
+			name: "reserved name",
+			cfg: `
+				route {
+					name       = "default"
+					selector   = "up"
+					forward_to = []
+				}`,
+			expectedErr: `route name "default" is reserved for the default route`,
+		},
+		{
+			name: "invalid selector",
+			cfg: `
+				route {
+					name       = "a"
+					selector   = "{"
+					forward_to = []
+				}`,
+			expectedErr: `invalid selector for route "a"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.cfg), &args)
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package prometheus
+
+import "strings"
+
+// SanitizeMetricName replaces the characters of s which aren't valid in a
+// metric name with underscores, and prefixes names starting with a digit with
+// an underscore.
+func SanitizeMetricName(s string) string {
+	return sanitizeName(s, true)
+}
+
+// SanitizeLabelName replaces the characters of s which aren't valid in a
+// label name with underscores, and prefixes names starting with a digit with
+// an underscore.
+func SanitizeLabelName(s string) string {
+	return sanitizeName(s, false)
+}
+
+func sanitizeName(s string, metricName bool) string {
+	var sb strings.Builder
+	sb.Grow(len(s) + 1)
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		case r == ':' && metricName:
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
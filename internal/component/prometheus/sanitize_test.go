@@ -0,0 +1,24 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitize(t *testing.T) {
+	tt := []struct {
+		in, metricName, labelName string
+	}{
+		{in: "http_requests_total", metricName: "http_requests_total", labelName: "http_requests_total"},
+		{in: "servers.web-1.cpu", metricName: "servers_web_1_cpu", labelName: "servers_web_1_cpu"},
+		{in: "job:rate5m", metricName: "job:rate5m", labelName: "job_rate5m"},
+		{in: "5xx", metricName: "_5xx", labelName: "_5xx"},
+		{in: "héllo", metricName: "h_llo", labelName: "h_llo"},
+		{in: "", metricName: "", labelName: ""},
+	}
+	for _, tc := range tt {
+		require.Equal(t, tc.metricName, SanitizeMetricName(tc.in), "metric name of %q", tc.in)
+		require.Equal(t, tc.labelName, SanitizeLabelName(tc.in), "label name of %q", tc.in)
+	}
+}
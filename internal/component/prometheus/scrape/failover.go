@@ -0,0 +1,252 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component/discovery"
+	client_prometheus "github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+)
+
+const (
+	// AlternateAddressesLabel is the label of a target holding a
+	// comma-separated list of addresses to scrape when __address__ can't be
+	// reached.
+	AlternateAddressesLabel = "__alternate_addresses__"
+
+	// EndpointLabel is added to the samples of targets with alternate
+	// addresses. Its value is the address which served the scrape.
+	EndpointLabel = "scrape_endpoint"
+)
+
+// hasAlternates reports whether any of targets has alternate addresses.
+func hasAlternates(targets []discovery.Target) bool {
+	for _, t := range targets {
+		if t[AlternateAddressesLabel] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// failover dials the alternate addresses of targets when their primary
+// address can't be reached, and records which address served each scrape.
+//
+// Addresses are compared with their port, as dialed by the scrape client.
+type failover struct {
+	dial      config_util.DialContextFunc
+	failovers client_prometheus.Counter
+
+	mut        sync.RWMutex
+	alternates map[string][]string // Primary address -> alternate addresses.
+	served     map[string]string   // Primary address -> address which served the latest scrape.
+}
+
+func newFailover(dial config_util.DialContextFunc, failovers client_prometheus.Counter) *failover {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return &failover{
+		dial:       dial,
+		failovers:  failovers,
+		alternates: make(map[string][]string),
+		served:     make(map[string]string),
+	}
+}
+
+// SetTargets sets the targets to fail over for. scheme is used for targets
+// which don't set __scheme__.
+func (f *failover) SetTargets(targets []discovery.Target, scheme string) {
+	alternates := make(map[string][]string)
+	for _, t := range targets {
+		if t[AlternateAddressesLabel] == "" {
+			continue
+		}
+		targetScheme := scheme
+		if s := t[model.SchemeLabel]; s != "" {
+			targetScheme = s
+		}
+
+		primary := withDefaultPort(t[model.AddressLabel], targetScheme)
+		for _, addr := range strings.Split(t[AlternateAddressesLabel], ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				alternates[primary] = append(alternates[primary], withDefaultPort(addr, targetScheme))
+			}
+		}
+	}
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.alternates = alternates
+	for primary := range f.served {
+		if _, ok := alternates[primary]; !ok {
+			delete(f.served, primary)
+		}
+	}
+}
+
+// DialContext dials addr, falling back to its alternate addresses in order
+// if it fails. Every attempt gets an equal share of the time left before the
+// deadline of ctx, so that an unresponsive address doesn't use the whole
+// scrape timeout.
+func (f *failover) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	f.mut.RLock()
+	alternates, ok := f.alternates[addr]
+	f.mut.RUnlock()
+	if !ok {
+		return f.dial(ctx, network, addr)
+	}
+
+	var (
+		endpoints = append([]string{addr}, alternates...)
+		errs      []error
+	)
+	for i, endpoint := range endpoints {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline, ok := ctx.Deadline(); ok {
+			attemptCtx, cancel = context.WithTimeout(ctx, time.Until(deadline)/time.Duration(len(endpoints)-i))
+		}
+		conn, err := f.dial(attemptCtx, network, endpoint)
+		cancel()
+		if err == nil {
+			if i > 0 {
+				f.failovers.Inc()
+			}
+			f.setServed(addr, endpoint)
+			return conn, nil
+		}
+
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	// The primary address is reported when no address could be reached.
+	f.setServed(addr, addr)
+	return nil, errors.Join(errs...)
+}
+
+func (f *failover) setServed(primary, endpoint string) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if _, ok := f.alternates[primary]; ok {
+		f.served[primary] = endpoint
+	}
+}
+
+// endpoint returns the address which served the latest scrape of primary, and
+// whether primary has alternate addresses.
+func (f *failover) endpoint(primary string) (string, bool) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	if _, ok := f.alternates[primary]; !ok {
+		return "", false
+	}
+	if endpoint, ok := f.served[primary]; ok {
+		return endpoint, true
+	}
+	return primary, true
+}
+
+// Appendable returns an Appendable which adds EndpointLabel to the samples of
+// targets with alternate addresses before passing them to next.
+func (f *failover) Appendable(next storage.Appendable) storage.Appendable {
+	return &failoverAppendable{f: f, next: next}
+}
+
+type failoverAppendable struct {
+	f    *failover
+	next storage.Appendable
+}
+
+// Appender satisfies the Appendable interface.
+func (a *failoverAppendable) Appender(ctx context.Context) storage.Appender {
+	app := a.next.Appender(ctx)
+
+	// The target is only passed in the context if the scrape manager is
+	// created with PassMetadataInContext.
+	t, ok := scrape.TargetFromContext(ctx)
+	if !ok {
+		return app
+	}
+	u := t.URL()
+	primary := withDefaultPort(u.Host, u.Scheme)
+	if _, ok := a.f.endpoint(primary); !ok {
+		return app
+	}
+	return &failoverAppender{Appender: app, f: a.f, primary: primary}
+}
+
+// failoverAppender adds EndpointLabel to the samples of one target.
+//
+// The appender is created before the target is scraped, so the endpoint is
+// looked up when the first sample is appended. References returned by
+// previous scrapes are ignored, as they may point to the series of another
+// endpoint.
+type failoverAppender struct {
+	storage.Appender
+	f       *failover
+	primary string
+
+	builder  *labels.Builder
+	endpoint string
+	resolved bool
+}
+
+var _ storage.Appender = (*failoverAppender)(nil)
+
+func (a *failoverAppender) withEndpoint(l labels.Labels) labels.Labels {
+	if !a.resolved {
+		a.endpoint, _ = a.f.endpoint(a.primary)
+		a.builder = labels.NewBuilder(labels.EmptyLabels())
+		a.resolved = true
+	}
+	a.builder.Reset(l)
+	a.builder.Set(EndpointLabel, a.endpoint)
+	return a.builder.Labels()
+}
+
+// Append satisfies the Appender interface.
+func (a *failoverAppender) Append(_ storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	return a.Appender.Append(0, a.withEndpoint(l), t, v)
+}
+
+// AppendExemplar satisfies the Appender interface.
+func (a *failoverAppender) AppendExemplar(_ storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	return a.Appender.AppendExemplar(0, a.withEndpoint(l), e)
+}
+
+// AppendHistogram satisfies the Appender interface.
+func (a *failoverAppender) AppendHistogram(_ storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	return a.Appender.AppendHistogram(0, a.withEndpoint(l), t, h, fh)
+}
+
+// UpdateMetadata satisfies the Appender interface.
+func (a *failoverAppender) UpdateMetadata(_ storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	return a.Appender.UpdateMetadata(0, a.withEndpoint(l), m)
+}
+
+// withDefaultPort adds the default port of scheme to addr if it has no port.
+func withDefaultPort(addr, scheme string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	port := "80"
+	if scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), port)
+}
@@ -0,0 +1,154 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/cluster"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/ckit/memconn"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailover_DialContext(t *testing.T) {
+	var dialed []string
+	dial := func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		if addr == "replica-2:9100" {
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+		return nil, fmt.Errorf("dial %s: connection refused", addr)
+	}
+	failovers := prometheus_client.NewCounter(prometheus_client.CounterOpts{Name: "failovers"})
+	f := newFailover(dial, failovers)
+	f.SetTargets([]discovery.Target{
+		{"__address__": "replica-1:9100", AlternateAddressesLabel: "replica-2:9100, replica-3"},
+		{"__address__": "standalone:9100"},
+	}, "http")
+
+	// Targets without alternate addresses are dialed directly.
+	_, err := f.DialContext(context.Background(), "tcp", "standalone:9100")
+	require.EqualError(t, err, "dial standalone:9100: connection refused")
+	_, ok := f.endpoint("standalone:9100")
+	require.False(t, ok)
+
+	dialed = nil
+	conn, err := f.DialContext(context.Background(), "tcp", "replica-1:9100")
+	require.NoError(t, err)
+	conn.Close()
+	require.Equal(t, []string{"replica-1:9100", "replica-2:9100"}, dialed)
+	endpoint, ok := f.endpoint("replica-1:9100")
+	require.True(t, ok)
+	require.Equal(t, "replica-2:9100", endpoint)
+	require.Equal(t, 1.0, testutil.ToFloat64(failovers))
+
+	// Every address is tried before failing, and the primary address is
+	// reported if none could be reached.
+	f.SetTargets([]discovery.Target{
+		{"__address__": "replica-1:9100", AlternateAddressesLabel: "replica-3"},
+	}, "http")
+	dialed = nil
+	_, err = f.DialContext(context.Background(), "tcp", "replica-1:9100")
+	require.Error(t, err)
+	require.Equal(t, []string{"replica-1:9100", "replica-3:80"}, dialed)
+	endpoint, _ = f.endpoint("replica-1:9100")
+	require.Equal(t, "replica-1:9100", endpoint)
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	require.Equal(t, "example:9100", withDefaultPort("example:9100", "http"))
+	require.Equal(t, "example:80", withDefaultPort("example", "http"))
+	require.Equal(t, "example:443", withDefaultPort("example", "https"))
+	require.Equal(t, "[::1]:80", withDefaultPort("[::1]", "http"))
+}
+
+// TestFailover ensures that targets are scraped from an alternate address
+// when their primary address can't be reached.
+func TestFailover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		reg    = prometheus_client.NewRegistry()
+		memLis = memconn.NewListener(util.TestLogger(t))
+		srv    = &http.Server{Handler: promhttp.HandlerFor(reg, promhttp.HandlerOpts{})}
+	)
+	reg.MustRegister(prometheus_client.NewGauge(prometheus_client.GaugeOpts{Name: "replica_metric"}))
+
+	go srv.Serve(memLis)
+	defer srv.Shutdown(ctx)
+
+	opts := component.Options{
+		Logger:     util.TestFlowLogger(t),
+		Registerer: prometheus_client.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			switch name {
+			case http_service.ServiceName:
+				return http_service.Data{
+					HTTPListenAddr:   "localhost:12345",
+					MemoryListenAddr: "agent.internal:1245",
+					BaseHTTPPath:     "/",
+					DialFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+						if address != "replica-2:80" {
+							return nil, fmt.Errorf("dial %s: connection refused", address)
+						}
+						return memLis.DialContext(ctx)
+					},
+				}, nil
+
+			case cluster.ServiceName:
+				return cluster.Mock(), nil
+			case labelstore.ServiceName:
+				return labelstore.New(nil, prometheus_client.DefaultRegisterer), nil
+
+			default:
+				return nil, fmt.Errorf("service %q does not exist", name)
+			}
+		},
+	}
+
+	endpoints := make(chan string, 100)
+	receiver := prometheus.NewInterceptor(nil, labelstore.New(nil, prometheus_client.DefaultRegisterer), prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		if l.Get("__name__") == "replica_metric" {
+			select {
+			case endpoints <- l.Get(EndpointLabel):
+			default:
+			}
+		}
+		return ref, nil
+	}))
+
+	var args Arguments
+	args.SetToDefault()
+	args.Targets = []discovery.Target{{"__address__": "replica-1", AlternateAddressesLabel: "replica-2"}}
+	args.ForwardTo = []storage.Appendable{receiver}
+	args.ScrapeInterval = 100 * time.Millisecond
+	args.ScrapeTimeout = 85 * time.Millisecond
+
+	s, err := New(opts, args)
+	require.NoError(t, err)
+	go s.Run(ctx)
+
+	select {
+	case endpoint := <-endpoints:
+		require.Equal(t, "replica-2:80", endpoint)
+	case <-time.After(time.Minute):
+		require.FailNow(t, "target was not scraped from its alternate address")
+	}
+}
@@ -124,8 +124,12 @@ type Component struct {
 	opts    component.Options
 	cluster cluster.Cluster
 
+	failover      *failover
 	reloadTargets chan struct{}
 
+	// scraperUpdated is written to whenever the scrape manager is recreated.
+	scraperUpdated chan struct{}
+
 	mut          sync.RWMutex
 	args         Arguments
 	scraper      *scrape.Manager
@@ -158,14 +162,6 @@ func New(o component.Options, args Arguments) (*Component, error) {
 	ls := service.(labelstore.LabelStore)
 
 	flowAppendable := prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
-	scrapeOptions := &scrape.Options{
-		ExtraMetrics: args.ExtraMetrics,
-		HTTPClientOptions: []config_util.HTTPClientOption{
-			config_util.WithDialContextFunc(httpData.DialFunc),
-		},
-		EnableProtobufNegotiation: args.EnableProtobufNegotiation,
-	}
-	scraper := scrape.NewManager(scrapeOptions, o.Logger, flowAppendable)
 
 	targetsGauge := client_prometheus.NewGauge(client_prometheus.GaugeOpts{
 		Name: "agent_prometheus_scrape_targets_gauge",
@@ -175,13 +171,22 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		return nil, err
 	}
 
+	failovers := client_prometheus.NewCounter(client_prometheus.CounterOpts{
+		Name: "agent_prometheus_scrape_failovers_total",
+		Help: "Total number of scrapes served by an alternate address of their target."})
+	err = o.Registerer.Register(failovers)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Component{
-		opts:          o,
-		cluster:       clusterData,
-		reloadTargets: make(chan struct{}, 1),
-		scraper:       scraper,
-		appendable:    flowAppendable,
-		targetsGauge:  targetsGauge,
+		opts:           o,
+		cluster:        clusterData,
+		failover:       newFailover(httpData.DialFunc, failovers),
+		reloadTargets:  make(chan struct{}, 1),
+		scraperUpdated: make(chan struct{}, 1),
+		appendable:     flowAppendable,
+		targetsGauge:   targetsGauge,
 	}
 
 	// Call to Update() to set the receivers and targets once at the start.
@@ -194,26 +199,55 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 // Run implements component.Component.
 func (c *Component) Run(ctx context.Context) error {
-	defer c.scraper.Stop()
-
-	targetSetsChan := make(chan map[string][]*targetgroup.Group)
-
-	go func() {
-		err := c.scraper.Run(targetSetsChan)
-		level.Info(c.opts.Logger).Log("msg", "scrape manager stopped")
-		if err != nil {
-			level.Error(c.opts.Logger).Log("msg", "scrape manager failed", "err", err)
+	var (
+		scraper        *scrape.Manager
+		targetSetsChan chan map[string][]*targetgroup.Group
+	)
+
+	// runScraper runs the current scrape manager if it's not running already,
+	// and stops the previous one.
+	runScraper := func() {
+		c.mut.RLock()
+		current := c.scraper
+		c.mut.RUnlock()
+		if current == scraper {
+			return
 		}
-	}()
+		if scraper != nil {
+			scraper.Stop()
+		}
+
+		// Every scrape manager gets its own channel so that stopped managers
+		// can't receive targets.
+		scraper, targetSetsChan = current, make(chan map[string][]*targetgroup.Group)
+		go func(scraper *scrape.Manager, targetSetsChan chan map[string][]*targetgroup.Group) {
+			err := scraper.Run(targetSetsChan)
+			level.Info(c.opts.Logger).Log("msg", "scrape manager stopped")
+			if err != nil {
+				level.Error(c.opts.Logger).Log("msg", "scrape manager failed", "err", err)
+			}
+		}(scraper, targetSetsChan)
+	}
+	runScraper()
+	defer func() { scraper.Stop() }()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
+		case <-c.scraperUpdated:
+			runScraper()
+
+			// Pass the targets to the new scrape manager.
+			select {
+			case c.reloadTargets <- struct{}{}:
+			default:
+			}
 		case <-c.reloadTargets:
 			c.mut.RLock()
 			var (
 				targets           = c.args.Targets
+				scheme            = c.args.Scheme
 				jobName           = c.opts.ID
 				clusteringEnabled = c.args.Clustering.Enabled
 			)
@@ -222,6 +256,8 @@ func (c *Component) Run(ctx context.Context) error {
 			}
 			c.mut.RUnlock()
 
+			c.failover.SetTargets(targets, scheme)
+
 			promTargets := c.distTargets(targets, jobName, clusteringEnabled)
 
 			select {
@@ -239,6 +275,18 @@ func (c *Component) Update(args component.Arguments) error {
 
 	c.mut.Lock()
 	defer c.mut.Unlock()
+
+	// Options of the scrape manager can't be changed once it's created, so the
+	// scrape manager is recreated when they change.
+	if c.scraper == nil || scraperOptionsChanged(c.args, newArgs) {
+		c.scraper = c.newScraper(newArgs)
+		level.Debug(c.opts.Logger).Log("msg", "scrape manager was recreated")
+
+		select {
+		case c.scraperUpdated <- struct{}{}:
+		default:
+		}
+	}
 	c.args = newArgs
 
 	c.appendable.UpdateChildren(newArgs.ForwardTo)
@@ -260,6 +308,33 @@ func (c *Component) Update(args component.Arguments) error {
 	return nil
 }
 
+// newScraper creates a new scrape manager for args.
+func (c *Component) newScraper(args Arguments) *scrape.Manager {
+	scrapeOptions := &scrape.Options{
+		ExtraMetrics: args.ExtraMetrics,
+		HTTPClientOptions: []config_util.HTTPClientOption{
+			config_util.WithDialContextFunc(c.failover.DialContext),
+		},
+		EnableProtobufNegotiation: args.EnableProtobufNegotiation,
+	}
+	if hasAlternates(args.Targets) {
+		// Connections are dialed for every scrape so that the primary address
+		// is tried first again once it recovers. The target is passed to the
+		// appender to label samples with the endpoint which served them.
+		scrapeOptions.HTTPClientOptions = append(scrapeOptions.HTTPClientOptions, config_util.WithKeepAlivesDisabled())
+		scrapeOptions.PassMetadataInContext = true
+	}
+	return scrape.NewManager(scrapeOptions, c.opts.Logger, c.failover.Appendable(c.appendable))
+}
+
+// scraperOptionsChanged returns whether the arguments used to create scrape
+// managers differ between prev and next.
+func scraperOptionsChanged(prev, next Arguments) bool {
+	return prev.ExtraMetrics != next.ExtraMetrics ||
+		prev.EnableProtobufNegotiation != next.EnableProtobufNegotiation ||
+		hasAlternates(prev.Targets) != hasAlternates(next.Targets)
+}
+
 // NotifyClusterChange implements component.ClusterComponent.
 func (c *Component) NotifyClusterChange() {
 	c.mut.RLock()
@@ -369,8 +444,12 @@ func BuildTargetStatuses(targets map[string][]*scrape.Target) []TargetStatus {
 
 // DebugInfo implements component.DebugComponent
 func (c *Component) DebugInfo() interface{} {
+	c.mut.RLock()
+	scraper := c.scraper
+	c.mut.RUnlock()
+
 	return ScraperStatus{
-		TargetStatus: BuildTargetStatuses(c.scraper.TargetsActive()),
+		TargetStatus: BuildTargetStatuses(scraper.TargetsActive()),
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -211,6 +212,102 @@ func TestCustomDialer(t *testing.T) {
 	require.NoError(t, err, "custom dialer was not used")
 }
 
+func TestUpdateScrapeManagerOptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		reg        = prometheus_client.NewRegistry()
+		regHandler = promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+
+		acceptHeaders = make(chan string, 100)
+
+		srv = &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case acceptHeaders <- r.Header.Get("Accept"):
+				default:
+				}
+				regHandler.ServeHTTP(w, r)
+			}),
+		}
+
+		memLis = memconn.NewListener(util.TestLogger(t))
+	)
+
+	go srv.Serve(memLis)
+	defer srv.Shutdown(ctx)
+
+	var config = `
+	targets         = [{ __address__ = "inmemory:80" }]
+	forward_to      = []
+	scrape_interval = "100ms"
+	scrape_timeout  = "85ms"
+	`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(config), &args))
+
+	opts := component.Options{
+		Logger:     util.TestFlowLogger(t),
+		Registerer: prometheus_client.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			switch name {
+			case http_service.ServiceName:
+				return http_service.Data{
+					HTTPListenAddr:   "inmemory:80",
+					MemoryListenAddr: "inmemory:80",
+					BaseHTTPPath:     "/",
+					DialFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+						return memLis.DialContext(ctx)
+					},
+				}, nil
+
+			case cluster.ServiceName:
+				return cluster.Mock(), nil
+			case labelstore.ServiceName:
+				return labelstore.New(nil, prometheus_client.DefaultRegisterer), nil
+
+			default:
+				return nil, fmt.Errorf("service %q does not exist", name)
+			}
+		},
+	}
+
+	s, err := New(opts, args)
+	require.NoError(t, err)
+	go s.Run(ctx)
+
+	waitForAccept := func(protobuf bool) {
+		t.Helper()
+		timeout := time.After(1 * time.Minute)
+		for {
+			select {
+			case accept := <-acceptHeaders:
+				if strings.Contains(accept, "application/vnd.google.protobuf") == protobuf {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("no scrape with protobuf negotiation set to %t", protobuf)
+			}
+		}
+	}
+	waitForAccept(false)
+
+	// Enabling protobuf negotiation recreates the scrape manager, which then
+	// scrapes the same targets.
+	oldScraper := s.scraper
+	args.EnableProtobufNegotiation = true
+	require.NoError(t, s.Update(args))
+	require.NotSame(t, oldScraper, s.scraper)
+	waitForAccept(true)
+
+	// Other arguments are applied to the running scrape manager.
+	scraper := s.scraper
+	args.ScrapeInterval = 200 * time.Millisecond
+	require.NoError(t, s.Update(args))
+	require.Same(t, scraper, s.scraper)
+}
+
 func TestValidateScrapeConfig(t *testing.T) {
 	var exampleRiverConfig = `
 	targets         = [{ "target1" = "target1" }]
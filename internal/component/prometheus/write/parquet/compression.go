@@ -0,0 +1,115 @@
+package parquet
+
+import (
+	"math"
+	"sync"
+
+	"github.com/apache/arrow/go/v12/parquet/compress"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// CompressionAuto selects the codec of every file from the compressibility of
+// the recent batches.
+const CompressionAuto = "auto"
+
+const (
+	// autoMinSamples is the number of samples under which batches are written
+	// uncompressed, as compressing small files saves little.
+	autoMinSamples = 1000
+	// autoProbeSamples is the number of samples of a batch measured to estimate
+	// its compressibility.
+	autoProbeSamples = 1000
+	// autoSmoothing is the weight of the last batch in the compressibility of
+	// the recent batches.
+	autoSmoothing = 0.2
+
+	// Batches whose estimated ratio is under autoZstdRatio are compressed with
+	// snappy, as zstd gains little on data which doesn't compress well.
+	autoZstdRatio = 2
+	// Batches whose estimated ratio is at least autoFastZstdRatio are
+	// compressed with the fastest level of zstd, which already shrinks highly
+	// redundant data well.
+	autoFastZstdRatio = 6
+)
+
+var codecZstdFastest = fileCodec{Name: "zstd-1", Codec: compress.Codecs.Zstd, Level: 1}
+
+// codecSelector chooses the codec of the files written with CompressionAuto.
+type codecSelector struct {
+	mut   sync.Mutex
+	ratio float64 // Compressibility of the recent batches, 0 until a batch is measured.
+}
+
+// choose returns the codec to write samples with.
+func (s *codecSelector) choose(samples []sample) fileCodec {
+	if len(samples) < autoMinSamples {
+		return codecNone
+	}
+
+	ratio := estimateRatio(samples)
+	s.mut.Lock()
+	if s.ratio == 0 {
+		s.ratio = ratio
+	} else {
+		s.ratio = autoSmoothing*ratio + (1-autoSmoothing)*s.ratio
+	}
+	ratio = s.ratio
+	s.mut.Unlock()
+
+	switch {
+	case ratio < autoZstdRatio:
+		return codecSnappy
+	case ratio < autoFastZstdRatio:
+		return codecZstd
+	default:
+		return codecZstdFastest
+	}
+}
+
+// estimateRatio estimates the compression ratio of samples by compressing up
+// to autoProbeSamples of them, spread across the batch, with snappy.
+func estimateRatio(samples []sample) float64 {
+	stride := 1
+	if len(samples) > autoProbeSamples {
+		stride = len(samples) / autoProbeSamples
+	}
+
+	var raw []byte
+	for i := 0; i < len(samples); i += stride {
+		raw = appendSample(raw, samples[i])
+	}
+	if len(raw) == 0 {
+		return 1
+	}
+	return float64(len(raw)) / float64(len(snappy.Encode(nil, raw)))
+}
+
+// appendSample appends the uncompressed content of s to b.
+func appendSample(b []byte, s sample) []byte {
+	s.Labels.Range(func(l labels.Label) {
+		b = append(b, l.Name...)
+		b = append(b, l.Value...)
+	})
+	for shift := 0; shift < 64; shift += 8 {
+		b = append(b, byte(s.Timestamp>>shift))
+	}
+	bits := math.Float64bits(s.Value)
+	for shift := 0; shift < 64; shift += 8 {
+		b = append(b, byte(bits>>shift))
+	}
+	return b
+}
+
+// rawSize returns the size of the uncompressed content of samples, which the
+// achieved compression ratio is computed against.
+func rawSize(samples []sample) int {
+	var size int
+	for _, s := range samples {
+		s.Labels.Range(func(l labels.Label) {
+			size += len(l.Name) + len(l.Value)
+		})
+		size += 16 // Timestamp and value.
+	}
+	return size
+}
@@ -0,0 +1,149 @@
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/apache/arrow/go/v12/parquet"
+	"github.com/apache/arrow/go/v12/parquet/compress"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// sample is a float sample buffered until it's written.
+type sample struct {
+	Labels    labels.Labels
+	Timestamp int64
+	Value     float64
+}
+
+// schema is the schema of the files. The metric name is a column of its own,
+// so that it can be filtered on without reading the labels.
+var schema = arrow.NewSchema([]arrow.Field{
+	{Name: "timestamp", Type: arrow.FixedWidthTypes.Timestamp_ms},
+	{Name: "metric_name", Type: arrow.BinaryTypes.String},
+	{Name: "labels", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.BinaryTypes.String)},
+	{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+// fileCodec is the codec a file is compressed with.
+type fileCodec struct {
+	// Name identifies the codec and its level in metrics, such as zstd-1.
+	Name  string
+	Codec compress.Compression
+	Level int
+}
+
+var (
+	codecNone   = fileCodec{Name: "none", Codec: compress.Codecs.Uncompressed, Level: compress.DefaultCompressionLevel}
+	codecSnappy = fileCodec{Name: "snappy", Codec: compress.Codecs.Snappy, Level: compress.DefaultCompressionLevel}
+	codecGzip   = fileCodec{Name: "gzip", Codec: compress.Codecs.Gzip, Level: compress.DefaultCompressionLevel}
+	codecZstd   = fileCodec{Name: "zstd", Codec: compress.Codecs.Zstd, Level: compress.DefaultCompressionLevel}
+)
+
+func compressionCodec(name string) (fileCodec, error) {
+	switch name {
+	case "none":
+		return codecNone, nil
+	case "snappy":
+		return codecSnappy, nil
+	case "gzip":
+		return codecGzip, nil
+	case "zstd":
+		return codecZstd, nil
+	default:
+		return fileCodec{}, fmt.Errorf("unsupported compression %q, must be one of %s, none, snappy, gzip or zstd", name, CompressionAuto)
+	}
+}
+
+// filePartition holds the samples written to a single file.
+type filePartition struct {
+	// Dir is the Hive-style directory of the partition, such as
+	// dt=2024-01-02/hour=15.
+	Dir     string
+	Samples []sample
+}
+
+// partition groups samples by the UTC hour or day of their timestamp. The
+// partitions are sorted by directory.
+func partition(samples []sample, by string) []filePartition {
+	var (
+		byDir = make(map[string][]sample)
+		dirs  []string
+	)
+	for _, s := range samples {
+		dir := partitionDir(time.UnixMilli(s.Timestamp).UTC(), by)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], s)
+	}
+	sort.Strings(dirs)
+
+	res := make([]filePartition, 0, len(dirs))
+	for _, dir := range dirs {
+		res = append(res, filePartition{Dir: dir, Samples: byDir[dir]})
+	}
+	return res
+}
+
+func partitionDir(t time.Time, by string) string {
+	if by == PartitionByDay {
+		return "dt=" + t.Format("2006-01-02")
+	}
+	return "dt=" + t.Format("2006-01-02") + "/hour=" + t.Format("15")
+}
+
+// encode returns samples encoded as a Parquet file.
+func encode(samples []sample, codec fileCodec) ([]byte, error) {
+	rec := buildRecord(samples)
+	defer rec.Release()
+
+	var buf bytes.Buffer
+	props := parquet.NewWriterProperties(parquet.WithCompression(codec.Codec), parquet.WithCompressionLevel(codec.Level))
+	w, err := pqarrow.NewFileWriter(schema, &buf, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Write(rec); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func buildRecord(samples []sample) arrow.Record {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+
+	var (
+		timestamps = b.Field(0).(*array.TimestampBuilder)
+		names      = b.Field(1).(*array.StringBuilder)
+		lbls       = b.Field(2).(*array.MapBuilder)
+		keys       = lbls.KeyBuilder().(*array.StringBuilder)
+		values     = lbls.ItemBuilder().(*array.StringBuilder)
+		floats     = b.Field(3).(*array.Float64Builder)
+	)
+	for _, s := range samples {
+		timestamps.Append(arrow.Timestamp(s.Timestamp))
+		names.Append(s.Labels.Get(labels.MetricName))
+		lbls.Append(true)
+		s.Labels.Range(func(l labels.Label) {
+			if l.Name == labels.MetricName {
+				return
+			}
+			keys.Append(l.Name)
+			values.Append(l.Value)
+		})
+		floats.Append(s.Value)
+	}
+	return b.NewRecord()
+}
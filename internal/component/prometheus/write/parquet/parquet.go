@@ -0,0 +1,375 @@
+// Package parquet implements the prometheus.write.parquet component, which
+// writes batches of samples as Parquet files.
+package parquet
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/agentseed"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/remote/s3"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/drops"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.write.parquet",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Partitioning of the files.
+const (
+	PartitionByHour = "hour"
+	PartitionByDay  = "day"
+)
+
+// Arguments holds values which are used to configure the
+// prometheus.write.parquet component.
+type Arguments struct {
+	// Local directory to write the files to.
+	Path string `river:"path,attr,optional"`
+	// S3 bucket to write the files to, instead of a local directory.
+	S3 *S3Arguments `river:"s3,block,optional"`
+
+	PartitionBy     string        `river:"partition_by,attr,optional"`
+	Compression     string        `river:"compression,attr,optional"`
+	FlushInterval   time.Duration `river:"flush_interval,attr,optional"`
+	MaxBatchSamples int           `river:"max_batch_samples,attr,optional"`
+}
+
+// S3Arguments configures the S3 bucket files are written to.
+type S3Arguments struct {
+	Bucket string    `river:"bucket,attr"`
+	Prefix string    `river:"prefix,attr,optional"`
+	Client s3.Client `river:"client,block,optional"`
+}
+
+// DefaultArguments holds the default arguments of the component.
+var DefaultArguments = Arguments{
+	PartitionBy:     PartitionByHour,
+	Compression:     "snappy",
+	FlushInterval:   5 * time.Minute,
+	MaxBatchSamples: 1_000_000,
+}
+
+// SetToDefault implements river.Defaulter.
+func (arg *Arguments) SetToDefault() {
+	*arg = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (arg *Arguments) Validate() error {
+	switch {
+	case arg.Path == "" && arg.S3 == nil:
+		return fmt.Errorf("one of path or the s3 block must be set")
+	case arg.Path != "" && arg.S3 != nil:
+		return fmt.Errorf("path and the s3 block can't both be set")
+	case arg.S3 != nil && arg.S3.Bucket == "":
+		return fmt.Errorf("the s3 bucket must not be empty")
+	}
+	if arg.PartitionBy != PartitionByHour && arg.PartitionBy != PartitionByDay {
+		return fmt.Errorf("partition_by must be %q or %q, got %q", PartitionByHour, PartitionByDay, arg.PartitionBy)
+	}
+	if arg.Compression != CompressionAuto {
+		if _, err := compressionCodec(arg.Compression); err != nil {
+			return err
+		}
+	}
+	if arg.FlushInterval <= 0 {
+		return fmt.Errorf("flush_interval must be greater than 0")
+	}
+	if arg.MaxBatchSamples <= 0 {
+		return fmt.Errorf("max_batch_samples must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.write.parquet
+// component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// Component implements the prometheus.write.parquet component.
+type Component struct {
+	log  log.Logger
+	opts component.Options
+
+	mut    sync.Mutex
+	args   Arguments
+	sink   sink
+	buffer []sample
+
+	// flushCh is notified when the buffer holds a full batch.
+	flushCh chan struct{}
+
+	// selector chooses the codec of the files when compression is auto.
+	selector codecSelector
+
+	samplesWritten    prometheus_client.Counter
+	filesWritten      prometheus_client.Counter
+	bytesWritten      prometheus_client.Counter
+	filesFailed       prometheus_client.Counter
+	histogramsDropped prometheus_client.Counter
+	codec             *prometheus_client.GaugeVec
+	compressionRatio  prometheus_client.Gauge
+	dropped           *drops.Recorder
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+	_ storage.Appendable  = (*Component)(nil)
+)
+
+// New creates a new prometheus.write.parquet component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		log:     o.Logger,
+		opts:    o,
+		flushCh: make(chan struct{}, 1),
+
+		samplesWritten: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_prometheus_write_parquet_samples_total",
+			Help: "Total number of samples written to Parquet files.",
+		}),
+		filesWritten: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_prometheus_write_parquet_files_total",
+			Help: "Total number of Parquet files written.",
+		}),
+		bytesWritten: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_prometheus_write_parquet_bytes_total",
+			Help: "Total number of bytes of the Parquet files written.",
+		}),
+		filesFailed: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_prometheus_write_parquet_failed_files_total",
+			Help: "Total number of Parquet files which couldn't be written.",
+		}),
+		histogramsDropped: prometheus_client.NewCounter(prometheus_client.CounterOpts{
+			Name: "agent_prometheus_write_parquet_histograms_dropped_total",
+			Help: "Total number of native histogram samples dropped, as they can't be written to Parquet files.",
+		}),
+		codec: prometheus_client.NewGaugeVec(prometheus_client.GaugeOpts{
+			Name: "agent_prometheus_write_parquet_compression_codec",
+			Help: "Codec of the last Parquet file written, set to 1 for the codec in use.",
+		}, []string{"codec"}),
+		compressionRatio: prometheus_client.NewGauge(prometheus_client.GaugeOpts{
+			Name: "agent_prometheus_write_parquet_compression_ratio",
+			Help: "Ratio of the size of the samples to the size of the last Parquet file written.",
+		}),
+		dropped: drops.NewRecorder(o, drops.SignalMetrics),
+	}
+	for _, m := range []prometheus_client.Collector{c.samplesWritten, c.filesWritten, c.bytesWritten, c.filesFailed, c.histogramsDropped, c.codec, c.compressionRatio} {
+		if err := o.Registerer.Register(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	// The component is its own receiver, which remains the same for the
+	// component lifetime.
+	o.OnStateChange(Exports{Receiver: c})
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	c.mut.Lock()
+	interval := c.args.FlushInterval
+	c.mut.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Samples still buffered are written with a fresh context, as ctx
+			// is already canceled.
+			flushCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			c.flush(flushCtx)
+			cancel()
+			return nil
+		case <-ticker.C:
+			c.flush(ctx)
+		case <-c.flushCh:
+			c.flush(ctx)
+		}
+
+		c.mut.Lock()
+		if c.args.FlushInterval != interval {
+			interval = c.args.FlushInterval
+			ticker.Reset(interval)
+		}
+		c.mut.Unlock()
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	var (
+		s   sink
+		err error
+	)
+	if newArgs.S3 != nil {
+		s, err = newS3Sink(*newArgs.S3)
+	} else {
+		s, err = newLocalSink(newArgs.Path)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = newArgs
+	c.sink = s
+	return nil
+}
+
+// flush writes the buffered samples, one file per partition. Files which
+// can't be written are dropped rather than retried, so that a failing sink
+// doesn't make the buffer grow without bound.
+func (c *Component) flush(ctx context.Context) {
+	c.mut.Lock()
+	var (
+		samples = c.buffer
+		args    = c.args
+		sink    = c.sink
+	)
+	c.buffer = nil
+	c.mut.Unlock()
+
+	if len(samples) == 0 {
+		return
+	}
+
+	now := time.Now()
+	for _, p := range partition(samples, args.PartitionBy) {
+		key := path.Join(p.Dir, fileName(now))
+
+		codec, _ := compressionCodec(args.Compression)
+		if args.Compression == CompressionAuto {
+			codec = c.selector.choose(p.Samples)
+		}
+		data, err := encode(p.Samples, codec)
+		if err == nil {
+			err = sink.Put(ctx, key, data)
+		}
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to write parquet file", "file", key, "samples", len(p.Samples), "err", err)
+			c.filesFailed.Inc()
+			c.dropped.Add(drops.ReasonSendFailed, len(p.Samples))
+			continue
+		}
+
+		level.Debug(c.log).Log("msg", "wrote parquet file", "file", key, "samples", len(p.Samples), "bytes", len(data), "codec", codec.Name)
+		c.filesWritten.Inc()
+		c.samplesWritten.Add(float64(len(p.Samples)))
+		c.bytesWritten.Add(float64(len(data)))
+		c.codec.Reset()
+		c.codec.WithLabelValues(codec.Name).Set(1)
+		c.compressionRatio.Set(float64(rawSize(p.Samples)) / float64(len(data)))
+	}
+}
+
+// fileName returns the name of a file written at t. The agent seed is part of
+// the name so that agents writing to the same bucket don't overwrite the
+// files of each other.
+func fileName(t time.Time) string {
+	return fmt.Sprintf("%d-%s.parquet", t.UnixNano(), agentseed.Get().UID)
+}
+
+// commit adds the samples of a committed transaction to the buffer.
+func (c *Component) commit(samples []sample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	c.mut.Lock()
+	c.buffer = append(c.buffer, samples...)
+	full := len(c.buffer) >= c.args.MaxBatchSamples
+	c.mut.Unlock()
+
+	if full {
+		select {
+		case c.flushCh <- struct{}{}:
+		default:
+			// A flush is already pending.
+		}
+	}
+}
+
+// Appender implements storage.Appendable.
+func (c *Component) Appender(_ context.Context) storage.Appender {
+	return &appender{c: c}
+}
+
+// appender buffers the samples of a transaction until it's committed.
+type appender struct {
+	c       *Component
+	samples []sample
+}
+
+var _ storage.Appender = (*appender)(nil)
+
+// Append satisfies the Appender interface.
+func (a *appender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	a.samples = append(a.samples, sample{Labels: l, Timestamp: t, Value: v})
+	return ref, nil
+}
+
+// AppendHistogram satisfies the Appender interface. Native histograms are
+// dropped, as the schema of the files only has room for float samples.
+func (a *appender) AppendHistogram(ref storage.SeriesRef, _ labels.Labels, _ int64, _ *histogram.Histogram, _ *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	a.c.histogramsDropped.Inc()
+	a.c.dropped.Add(drops.ReasonRejected, 1)
+	return ref, nil
+}
+
+// AppendExemplar satisfies the Appender interface. Exemplars are ignored.
+func (a *appender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// UpdateMetadata satisfies the Appender interface. Metadata is ignored.
+func (a *appender) UpdateMetadata(ref storage.SeriesRef, _ labels.Labels, _ metadata.Metadata) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+// Commit satisfies the Appender interface.
+func (a *appender) Commit() error {
+	a.c.commit(a.samples)
+	a.samples = nil
+	return nil
+}
+
+// Rollback satisfies the Appender interface.
+func (a *appender) Rollback() error {
+	a.samples = nil
+	return nil
+}
@@ -0,0 +1,191 @@
+package parquet
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	pq "github.com/apache/arrow/go/v12/parquet"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{name: "path", config: `path = "/tmp/parquet"`},
+		{name: "s3", config: `s3 { bucket = "metrics" }`},
+		{name: "no destination", config: ``, err: "one of path or the s3 block must be set"},
+		{name: "both destinations", config: `
+			path = "/tmp/parquet"
+			s3 { bucket = "metrics" }
+		`, err: "path and the s3 block can't both be set"},
+		{name: "bad partitioning", config: `
+			path = "/tmp/parquet"
+			partition_by = "minute"
+		`, err: `partition_by must be "hour" or "day", got "minute"`},
+		{name: "bad compression", config: `
+			path = "/tmp/parquet"
+			compression = "lz4"
+		`, err: `unsupported compression "lz4", must be one of auto, none, snappy, gzip or zstd`},
+		{name: "auto compression", config: `
+			path = "/tmp/parquet"
+			compression = "auto"
+		`},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.config), &args)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPartition(t *testing.T) {
+	at := func(s string) int64 {
+		ts, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return ts.UnixMilli()
+	}
+	samples := []sample{
+		{Timestamp: at("2024-01-02T15:59:00Z")},
+		{Timestamp: at("2024-01-02T16:00:00Z")},
+		{Timestamp: at("2024-01-02T15:00:00Z")},
+		{Timestamp: at("2024-01-03T00:00:00Z")},
+	}
+
+	var dirs []string
+	var counts []int
+	for _, p := range partition(samples, PartitionByHour) {
+		dirs = append(dirs, p.Dir)
+		counts = append(counts, len(p.Samples))
+	}
+	require.Equal(t, []string{"dt=2024-01-02/hour=15", "dt=2024-01-02/hour=16", "dt=2024-01-03/hour=00"}, dirs)
+	require.Equal(t, []int{2, 1, 1}, counts)
+
+	dirs, counts = nil, nil
+	for _, p := range partition(samples, PartitionByDay) {
+		dirs = append(dirs, p.Dir)
+		counts = append(counts, len(p.Samples))
+	}
+	require.Equal(t, []string{"dt=2024-01-02", "dt=2024-01-03"}, dirs)
+	require.Equal(t, []int{3, 1}, counts)
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	var args Arguments
+	args.SetToDefault()
+	args.Path = dir
+
+	c, err := New(component.Options{
+		ID:            "prometheus.write.parquet.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+		Registerer:    prom.NewRegistry(),
+	}, args)
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 1, 2, 15, 30, 0, 0, time.UTC).UnixMilli()
+	app := c.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "job", "a"), ts, 1)
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "job", "b"), ts+1000, 0)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	// Rolled back samples aren't written.
+	app = c.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "job", "c"), ts, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Rollback())
+
+	c.flush(context.Background())
+
+	files, err := filepath.Glob(filepath.Join(dir, "dt=2024-01-02", "hour=15", "*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	tbl, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(data), pq.NewReaderProperties(memory.DefaultAllocator), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	defer tbl.Release()
+
+	require.EqualValues(t, 2, tbl.NumRows())
+	var fields []string
+	for _, f := range tbl.Schema().Fields() {
+		fields = append(fields, f.Name)
+	}
+	require.Equal(t, []string{"timestamp", "metric_name", "labels", "value"}, fields)
+
+	names := tbl.Column(1).Data().Chunk(0).(*array.String)
+	require.Equal(t, "up", names.Value(0))
+	lbls := tbl.Column(2).Data().Chunk(0).(*array.Map)
+	require.Equal(t, "job", lbls.Keys().(*array.String).Value(0))
+	require.Equal(t, "a", lbls.Items().(*array.String).Value(0))
+	values := tbl.Column(3).Data().Chunk(0).(*array.Float64)
+	require.Equal(t, []float64{1, 0}, values.Float64Values())
+
+	// The buffer is empty after a flush.
+	c.flush(context.Background())
+	files, err = filepath.Glob(filepath.Join(dir, "dt=2024-01-02", "hour=15", "*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestCodecSelector(t *testing.T) {
+	batch := func(n int, gen func(i int) sample) []sample {
+		samples := make([]sample, n)
+		for i := range samples {
+			samples[i] = gen(i)
+		}
+		return samples
+	}
+	redundant := func(int) sample {
+		return sample{Labels: labels.FromStrings("__name__", "up", "job", "node"), Timestamp: 1000, Value: 1}
+	}
+	rnd := rand.New(rand.NewSource(1))
+	random := func(int) sample {
+		return sample{
+			Labels:    labels.FromStrings("id", strconv.FormatUint(rnd.Uint64(), 36)),
+			Timestamp: rnd.Int63(),
+			Value:     rnd.Float64(),
+		}
+	}
+
+	var s codecSelector
+	require.Equal(t, codecNone, s.choose(batch(autoMinSamples-1, redundant)))
+	require.Equal(t, codecZstdFastest, s.choose(batch(autoMinSamples, redundant)))
+
+	// The codec follows the compressibility of the recent batches rather
+	// than switching on a single batch.
+	s = codecSelector{}
+	require.Equal(t, codecSnappy, s.choose(batch(autoMinSamples, random)))
+	require.Equal(t, codecZstd, s.choose(batch(autoMinSamples, redundant)))
+	for i := 0; i < 10; i++ {
+		s.choose(batch(autoMinSamples, redundant))
+	}
+	require.Equal(t, codecZstdFastest, s.choose(batch(autoMinSamples, redundant)))
+}
@@ -0,0 +1,75 @@
+package parquet
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aws_s3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/grafana/agent/internal/component/remote/s3"
+)
+
+// sink stores the files written by the component.
+type sink interface {
+	// Put stores data under key, a slash-separated relative path.
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// localSink writes files to a local directory.
+type localSink struct {
+	dir string
+}
+
+func newLocalSink(dir string) (*localSink, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &localSink{dir: dir}, nil
+}
+
+// Put implements sink. The file is written under a temporary name and then
+// renamed, so that readers never see partial files.
+func (s *localSink) Put(_ context.Context, key string, data []byte) error {
+	name := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return err
+	}
+
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// s3Sink uploads files to an S3 bucket.
+type s3Sink struct {
+	client *aws_s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Sink(args S3Arguments) (*s3Sink, error) {
+	client, err := s3.NewClient(args.Client)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Sink{client: client, bucket: args.Bucket, prefix: args.Prefix}, nil
+}
+
+// Put implements sink.
+func (s *s3Sink) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &aws_s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, key)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
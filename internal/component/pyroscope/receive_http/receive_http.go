@@ -0,0 +1,214 @@
+package receive_http
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/component"
+	fnet "github.com/grafana/agent/internal/component/common/net"
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/component/pyroscope"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/util"
+	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/push/v1/pushv1connect"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "pyroscope.receive_http",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// pyroscope.receive_http component.
+type Arguments struct {
+	Server         *fnet.ServerConfig     `river:",squash"`
+	ForwardTo      []pyroscope.Appendable `river:"forward_to,attr"`
+	RelabelConfigs []*flow_relabel.Config `river:"rule,block,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = Arguments{
+		Server: fnet.DefaultServerConfig(),
+	}
+}
+
+// Component implements the pyroscope.receive_http component.
+type Component struct {
+	opts               component.Options
+	uncheckedCollector *util.UncheckedCollector
+	metrics            *metrics
+	fanout             *pyroscope.Fanout
+
+	serverMut    sync.Mutex
+	server       *fnet.TargetServer
+	serverConfig *fnet.ServerConfig
+
+	rulesMut sync.RWMutex
+	rules    []*relabel.Config
+}
+
+var (
+	_ component.Component                = (*Component)(nil)
+	_ pushv1connect.PusherServiceHandler = (*Component)(nil)
+)
+
+// New creates a new pyroscope.receive_http component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:               opts,
+		uncheckedCollector: util.NewUncheckedCollector(nil),
+		metrics:            newMetrics(opts.Registerer),
+		fanout:             pyroscope.NewFanout(args.ForwardTo, opts.ID, opts.Registerer),
+	}
+	opts.Registerer.MustRegister(c.uncheckedCollector)
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer c.stop()
+
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	c.rulesMut.Lock()
+	c.rules = flow_relabel.ComponentToPromRelabelConfigs(newArgs.RelabelConfigs)
+	c.rulesMut.Unlock()
+
+	c.serverMut.Lock()
+	defer c.serverMut.Unlock()
+
+	if c.server != nil && reflect.DeepEqual(c.serverConfig, newArgs.Server) {
+		return nil
+	}
+	if c.server != nil {
+		c.server.StopAndShutdown()
+		c.server = nil
+	}
+
+	// The server registers new metrics every time it's created. To avoid
+	// issues with re-registering metrics with the same name, create a new
+	// registry for every server and pass it to an unchecked collector to
+	// bypass uniqueness checking.
+	serverRegistry := prometheus.NewRegistry()
+	c.uncheckedCollector.SetCollector(serverRegistry)
+
+	srv, err := fnet.NewTargetServer(c.opts.Logger, "pyroscope_receive_http", serverRegistry, newArgs.Server)
+	if err != nil {
+		return fmt.Errorf("failed to create server: %w", err)
+	}
+	err = srv.MountAndRun(func(router *mux.Router) {
+		pushv1connect.RegisterPusherServiceHandler(router, c)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run server: %w", err)
+	}
+
+	c.server = srv
+	c.serverConfig = newArgs.Server
+	return nil
+}
+
+func (c *Component) stop() {
+	c.serverMut.Lock()
+	defer c.serverMut.Unlock()
+
+	if c.server != nil {
+		c.server.StopAndShutdown()
+		c.server = nil
+	}
+}
+
+// Push implements pushv1connect.PusherServiceHandler. Profiles are relabeled
+// and forwarded to the receivers of the component. Failing to forward
+// profiles returns an unavailable error, so the client retries pushing them.
+func (c *Component) Push(ctx context.Context, req *connect.Request[pushv1.PushRequest]) (*connect.Response[pushv1.PushResponse], error) {
+	c.rulesMut.RLock()
+	rules := c.rules
+	c.rulesMut.RUnlock()
+
+	appender := c.fanout.Appender()
+	for _, series := range req.Msg.Series {
+		c.metrics.receivedProfiles.Add(float64(len(series.Samples)))
+
+		lbls, keep := relabel.Process(seriesLabels(series.Labels), rules...)
+		if !keep {
+			c.metrics.droppedProfiles.Add(float64(len(series.Samples)))
+			continue
+		}
+
+		samples := make([]*pyroscope.RawSample, 0, len(series.Samples))
+		for _, sample := range series.Samples {
+			samples = append(samples, &pyroscope.RawSample{RawProfile: sample.RawProfile})
+		}
+		if err := appender.Append(ctx, lbls, samples); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to forward profiles", "err", err)
+			c.metrics.forwardErrors.Inc()
+			return nil, connect.NewError(connect.CodeUnavailable, err)
+		}
+	}
+	return connect.NewResponse(&pushv1.PushResponse{}), nil
+}
+
+func seriesLabels(pairs []*typesv1.LabelPair) labels.Labels {
+	b := labels.NewScratchBuilder(len(pairs))
+	for _, p := range pairs {
+		b.Add(p.Name, p.Value)
+	}
+	b.Sort()
+	return b.Labels()
+}
+
+type metrics struct {
+	receivedProfiles prometheus.Counter
+	droppedProfiles  prometheus.Counter
+	forwardErrors    prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	m := &metrics{
+		receivedProfiles: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_receive_http_received_profiles_total",
+			Help: "Total number of profiles received.",
+		}),
+		droppedProfiles: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_receive_http_dropped_profiles_total",
+			Help: "Total number of profiles dropped by relabeling rules.",
+		}),
+		forwardErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pyroscope_receive_http_forward_errors_total",
+			Help: "Total number of push requests which failed to be forwarded to the receivers.",
+		}),
+	}
+	if reg != nil {
+		reg.MustRegister(m.receivedProfiles, m.droppedProfiles, m.forwardErrors)
+	}
+	return m
+}
@@ -0,0 +1,159 @@
+package receive_http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/grafana/agent/internal/component"
+	fnet "github.com/grafana/agent/internal/component/common/net"
+	"github.com/grafana/agent/internal/component/pyroscope"
+	"github.com/grafana/agent/internal/util"
+	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
+	"github.com/grafana/pyroscope/api/gen/proto/go/push/v1/pushv1connect"
+	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/grafana/river"
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+type appended struct {
+	labels  labels.Labels
+	profile string
+}
+
+func TestPush(t *testing.T) {
+	var (
+		mut      sync.Mutex
+		received []appended
+		failing  bool
+	)
+	receiver := pyroscope.AppendableFunc(func(_ context.Context, lbls labels.Labels, samples []*pyroscope.RawSample) error {
+		mut.Lock()
+		defer mut.Unlock()
+		if failing {
+			return errors.New("endpoint unavailable")
+		}
+		for _, s := range samples {
+			received = append(received, appended{labels: lbls, profile: string(s.RawProfile)})
+		}
+		return nil
+	})
+
+	httpPort, err := freeport.GetFreePort()
+	require.NoError(t, err)
+
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(fmt.Sprintf(`
+		forward_to = []
+
+		http {
+			listen_address = "127.0.0.1"
+			listen_port    = %d
+		}
+
+		rule {
+			source_labels = ["env"]
+			regex         = "dev"
+			action        = "drop"
+		}
+
+		rule {
+			action = "labeldrop"
+			regex  = "pod"
+		}
+	`, httpPort)), &args))
+	args.ForwardTo = []pyroscope.Appendable{receiver}
+
+	c, err := New(component.Options{
+		ID:            "pyroscope.receive_http.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	client := pushv1connect.NewPusherServiceClient(http.DefaultClient, fmt.Sprintf("http://127.0.0.1:%d", httpPort))
+	push := func() error {
+		_, err := client.Push(context.Background(), connect.NewRequest(&pushv1.PushRequest{
+			Series: []*pushv1.RawProfileSeries{
+				{
+					Labels: []*typesv1.LabelPair{
+						{Name: "__name__", Value: "process_cpu"},
+						{Name: "env", Value: "prod"},
+						{Name: "pod", Value: "a-1"},
+					},
+					Samples: []*pushv1.RawSample{{RawProfile: []byte("prod")}},
+				},
+				{
+					Labels: []*typesv1.LabelPair{
+						{Name: "__name__", Value: "process_cpu"},
+						{Name: "env", Value: "dev"},
+					},
+					Samples: []*pushv1.RawSample{{RawProfile: []byte("dev")}},
+				},
+			},
+		}))
+		return err
+	}
+
+	require.Eventually(t, func() bool { return push() == nil }, 5*time.Second, 20*time.Millisecond)
+	mut.Lock()
+	require.Equal(t, []appended{
+		{labels: labels.FromStrings("__name__", "process_cpu", "env", "prod"), profile: "prod"},
+	}, received)
+	failing = true
+	mut.Unlock()
+
+	// Failing to forward profiles is reported as retryable.
+	err = push()
+	require.Error(t, err)
+	require.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+}
+
+func TestUpdate_RestartsServer(t *testing.T) {
+	ports, err := freeport.GetFreePorts(2)
+	require.NoError(t, err)
+
+	args := Arguments{
+		Server: &fnet.ServerConfig{
+			HTTP: &fnet.HTTPConfig{ListenAddress: "127.0.0.1", ListenPort: ports[0]},
+		},
+	}
+	c, err := New(component.Options{
+		ID:         "pyroscope.receive_http.test",
+		Logger:     util.TestFlowLogger(t),
+		Registerer: prometheus.NewRegistry(),
+	}, args)
+	require.NoError(t, err)
+	defer c.stop()
+
+	server := c.server
+	require.NoError(t, c.Update(args))
+	require.Same(t, server, c.server, "server shouldn't restart when its config is unchanged")
+
+	args.Server = &fnet.ServerConfig{
+		HTTP: &fnet.HTTPConfig{ListenAddress: "127.0.0.1", ListenPort: ports[1]},
+	}
+	require.NoError(t, c.Update(args))
+	require.NotSame(t, server, c.server, "server should restart when its config changes")
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/service/egress"
 	"github.com/grafana/dskit/backoff"
 	pushv1 "github.com/grafana/pyroscope/api/gen/proto/go/push/v1"
 	"github.com/grafana/pyroscope/api/gen/proto/go/push/v1/pushv1connect"
@@ -165,7 +166,7 @@ func NewFanOut(opts component.Options, config Arguments, metrics *metrics) (*fan
 			endpoint.Headers = map[string]string{}
 		}
 		endpoint.Headers[agentseed.HeaderName] = uid
-		httpClient, err := commonconfig.NewClientFromConfig(*endpoint.HTTPClientConfig.Convert(), endpoint.Name)
+		httpClient, err := commonconfig.NewClientFromConfig(*endpoint.HTTPClientConfig.Convert(), endpoint.Name, egress.HTTPClientOption())
 		if err != nil {
 			return nil, err
 		}
@@ -105,6 +105,11 @@ type Options struct {
 	// The result of GetServiceData may be cached as the value will not change at
 	// runtime.
 	GetServiceData func(name string) (interface{}, error)
+
+	// Executor bounds the concurrency of the data processing performed by the
+	// component, as configured by the execution pool the component belongs
+	// to. Executor may be nil; use [Acquire] to acquire workers from it.
+	Executor Executor
 }
 
 // Registration describes a single component.
@@ -139,6 +144,13 @@ type Registration struct {
 	// Build should construct a new component from an initial Arguments and set
 	// of options.
 	Build func(opts Options, args Arguments) (Component, error)
+
+	// Migrations upgrade blocks written for older versions of Args, ordered
+	// by version. The Migrations of a component make breaking changes to its
+	// arguments, such as renaming an argument, survivable for users: blocks
+	// are migrated when the configuration is loaded, and rewritten by the fmt
+	// command with the --migrate flag.
+	Migrations []Migration
 }
 
 // CloneArguments returns a new zero value of the registered Arguments type.
@@ -161,6 +173,10 @@ func Register(r Registration) {
 		panic(fmt.Sprintf("Component %q has an undefined stability level - please provide stability level when registering the component", r.Name))
 	}
 
+	if err := validateMigrations(r.Migrations); err != nil {
+		panic(fmt.Sprintf("Component %q has invalid migrations: %s", r.Name, err))
+	}
+
 	parsed, err := parseComponentName(r.Name)
 	if err != nil {
 		panic(fmt.Sprintf("invalid component name %q: %s", r.Name, err))
@@ -15,6 +15,7 @@ import (
 	common_config "github.com/grafana/agent/internal/component/common/config"
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/egress"
 	"github.com/grafana/agent/internal/useragent"
 	"github.com/grafana/river/rivertypes"
 	prom_config "github.com/prometheus/common/config"
@@ -279,6 +280,7 @@ func (c *Component) Update(args component.Arguments) (err error) {
 		*newArgs.Client.Convert(),
 		c.opts.ID,
 		prom_config.WithUserAgent(customUserAgent),
+		egress.HTTPClientOption(),
 	)
 	if err != nil {
 		return err
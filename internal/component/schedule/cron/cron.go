@@ -0,0 +1,241 @@
+// Package cron implements the schedule.cron component.
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	_ "time/tzdata" // embed timezone data
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/hashicorp/cronexpr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// maxTimerWait is the longest time the component waits before checking the
+	// wall clock again. Timers measure time with the monotonic clock, so they
+	// don't follow jumps of the wall clock the schedule is based on, such as an
+	// NTP step or a resumed virtual machine.
+	maxTimerWait = time.Minute
+
+	// clockJumpThreshold is the difference between the elapsed wall clock time
+	// and the elapsed monotonic time above which the wall clock is considered
+	// to have jumped.
+	clockJumpThreshold = 5 * time.Second
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "schedule.cron",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the schedule.cron
+// component.
+type Arguments struct {
+	Schedule string `river:"schedule,attr"`
+	Timezone string `river:"timezone,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Timezone: "Local",
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	_, _, err := args.parse()
+	return err
+}
+
+// parse returns the parsed schedule and timezone of args. An error is
+// returned if the schedule never triggers.
+func (args *Arguments) parse() (*cronexpr.Expression, *time.Location, error) {
+	expr, err := cronexpr.Parse(args.Schedule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid schedule %q: %w", args.Schedule, err)
+	}
+	loc, err := time.LoadLocation(args.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timezone %q: %w", args.Timezone, err)
+	}
+	if expr.Next(time.Now().In(loc)).IsZero() {
+		return nil, nil, fmt.Errorf("schedule %q never triggers", args.Schedule)
+	}
+	return expr, loc, nil
+}
+
+// Exports holds values which are exported by the schedule.cron component.
+type Exports struct {
+	// Trigger is the number of times the schedule triggered since the
+	// component started. Components referencing it are evaluated again every
+	// time the schedule triggers.
+	Trigger     int    `river:"trigger,attr"`
+	LastTrigger string `river:"last_trigger,attr"`
+	NextTrigger string `river:"next_trigger,attr"`
+}
+
+// Component implements the schedule.cron component.
+type Component struct {
+	log  log.Logger
+	opts component.Options
+
+	mut         sync.Mutex
+	args        Arguments
+	expr        *cronexpr.Expression
+	loc         *time.Location
+	trigger     int
+	lastTrigger time.Time
+
+	clockJumps prometheus.Counter
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new schedule.cron component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		log:  opts.Logger,
+		opts: opts,
+		clockJumps: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "schedule_cron_clock_jumps_total",
+			Help: "Number of jumps of the wall clock detected while waiting for the next trigger",
+		}),
+		updated: make(chan struct{}, 1),
+	}
+	if err := opts.Registerer.Register(c.clockJumps); err != nil {
+		return nil, err
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		start := time.Now()
+		next := c.nextTrigger(start)
+
+		// Schedules limited to some years stop triggering once they're over, in
+		// which case next is zero.
+		wait := maxTimerWait
+		if !next.IsZero() && next.Sub(start) < wait {
+			wait = next.Sub(start)
+		}
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+			now := time.Now()
+			if jump := clockJump(start, now); jump > clockJumpThreshold || jump < -clockJumpThreshold {
+				level.Warn(c.log).Log("msg", "wall clock jumped; computing the next trigger again", "jump", jump)
+				c.clockJumps.Inc()
+			}
+			// Compare wall clock times, since the schedule is based on them. If the
+			// clock jumped forward past several triggers, only the first one fires.
+			if !next.IsZero() && !now.Before(next) {
+				c.fire(next)
+			}
+		case <-c.updated:
+			// The schedule may have changed; compute the next trigger again.
+			timer.Stop()
+		}
+	}
+}
+
+// nextTrigger returns the first time the schedule triggers after now.
+func (c *Component) nextTrigger(now time.Time) time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.next(now)
+}
+
+// next returns the first time the schedule triggers after now and after the
+// last trigger, so that the schedule doesn't trigger twice for the same time if
+// the wall clock jumped backwards. c.mut must be held when calling next.
+func (c *Component) next(now time.Time) time.Time {
+	if now.Before(c.lastTrigger) {
+		now = c.lastTrigger
+	}
+	return c.expr.Next(now.In(c.loc))
+}
+
+// clockJump returns how much the wall clock jumped between start and now,
+// which must both hold a monotonic clock reading.
+func clockJump(start, now time.Time) time.Duration {
+	// Round(0) strips the monotonic clock reading, so that times are compared
+	// with the wall clock.
+	return now.Round(0).Sub(start.Round(0)) - now.Sub(start)
+}
+
+// fire records that the schedule triggered at t and updates the exports.
+func (c *Component) fire(t time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.trigger++
+	c.lastTrigger = t
+	level.Debug(c.log).Log("msg", "schedule triggered", "schedule", c.args.Schedule, "trigger", c.trigger)
+	c.opts.OnStateChange(c.exports())
+}
+
+// exports returns the current exports of the component. c.mut must be held
+// when calling exports.
+func (c *Component) exports() Exports {
+	e := Exports{Trigger: c.trigger}
+	if next := c.next(time.Now()); !next.IsZero() {
+		e.NextTrigger = next.Format(time.RFC3339)
+	}
+	if !c.lastTrigger.IsZero() {
+		e.LastTrigger = c.lastTrigger.In(c.loc).Format(time.RFC3339)
+	}
+	return e
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+	expr, loc, err := newArgs.parse()
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	c.args = newArgs
+	c.expr = expr
+	c.loc = loc
+	c.opts.OnStateChange(c.exports())
+	c.mut.Unlock()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
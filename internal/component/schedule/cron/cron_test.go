@@ -0,0 +1,107 @@
+package cron
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalRiver(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		schedule = "0 9 * * MON-FRI"
+		timezone = "Europe/Paris"
+	`), &args))
+
+	require.NoError(t, river.Unmarshal([]byte(`schedule = "@hourly"`), &args))
+	require.Equal(t, "Local", args.Timezone)
+
+	require.ErrorContains(t, river.Unmarshal([]byte(`schedule = "every day"`), &args), `invalid schedule "every day"`)
+	require.ErrorContains(t, river.Unmarshal([]byte(`
+		schedule = "@daily"
+		timezone = "Mars/Olympus_Mons"
+	`), &args), `invalid timezone "Mars/Olympus_Mons"`)
+	require.EqualError(t, river.Unmarshal([]byte(`schedule = "0 0 1 1 * 2000"`), &args), `schedule "0 0 1 1 * 2000" never triggers`)
+}
+
+func TestNextTrigger_Timezone(t *testing.T) {
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(component.Exports) {},
+	}, Arguments{Schedule: "0 9 * * *", Timezone: "America/New_York"})
+	require.NoError(t, err)
+
+	next := c.nextTrigger(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2024, time.January, 1, 14, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestNextTrigger_ClockJumpedBackwards(t *testing.T) {
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(component.Exports) {},
+	}, Arguments{Schedule: "0 * * * *", Timezone: "UTC"})
+	require.NoError(t, err)
+
+	c.fire(time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC))
+
+	// The schedule already triggered at noon, so it doesn't trigger again at
+	// noon after the clock jumped back to 11:59:30.
+	next := c.nextTrigger(time.Date(2024, time.January, 1, 11, 59, 30, 0, time.UTC))
+	require.Equal(t, time.Date(2024, time.January, 1, 13, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestClockJump(t *testing.T) {
+	start := time.Now()
+	require.Zero(t, clockJump(start, start.Add(time.Hour)))
+	require.InDelta(t, 0, clockJump(start, time.Now()), float64(time.Millisecond))
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mut     sync.Mutex
+		exports Exports
+	)
+	c, err := New(component.Options{
+		Logger:     util.TestFlowLogger(t),
+		Registerer: prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {
+			mut.Lock()
+			defer mut.Unlock()
+			exports = e.(Exports)
+		},
+	}, Arguments{Schedule: "* * * * * * *", Timezone: "UTC"})
+	require.NoError(t, err)
+
+	mut.Lock()
+	require.Equal(t, 0, exports.Trigger)
+	require.Empty(t, exports.LastTrigger)
+	require.NotEmpty(t, exports.NextTrigger)
+	mut.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return exports.Trigger >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mut.Lock()
+	defer mut.Unlock()
+	last, err := time.Parse(time.RFC3339, exports.LastTrigger)
+	require.NoError(t, err)
+	next, err := time.Parse(time.RFC3339, exports.NextTrigger)
+	require.NoError(t, err)
+	require.True(t, next.After(last))
+}
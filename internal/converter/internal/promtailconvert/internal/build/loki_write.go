@@ -55,6 +55,7 @@ func toLokiWriteArguments(config *client.Config, diags *diag.Diagnostics) *lokiw
 			},
 		},
 		ExternalLabels: convertFlagLabels(config.ExternalLabels),
+		StopTimeout:    lokiwrite.DefaultStopTimeout,
 	}
 }
 
@@ -39,5 +39,6 @@ func toLokiApiArguments(config *scrapeconfig.PushTargetConfig, forwardTo []loki.
 		Labels:               convertPromLabels(config.Labels),
 		UseIncomingTimestamp: config.KeepTimestamp,
 		Server:               common.WeaveWorksServerToFlowServer(config.Server),
+		TenantLabel:          api.DefaultTenantLabel,
 	}
 }
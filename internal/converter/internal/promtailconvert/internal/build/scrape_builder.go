@@ -190,9 +190,9 @@ func (s *ScrapeConfigBuilder) getExpandedFileTargetsExpr() string {
 	if s.allExpandedFileTargetsExpr != "" {
 		return s.allExpandedFileTargetsExpr
 	}
-	args := filematch.Arguments{
-		SyncPeriod: s.globalCtx.TargetSyncPeriod,
-	}
+	var args filematch.Arguments
+	args.SetToDefault()
+	args.SyncPeriod = s.globalCtx.TargetSyncPeriod
 	overrideHook := func(val interface{}) interface{} {
 		if _, ok := val.([]discovery.Target); ok {
 			return common.CustomTokenizer{Expr: s.getAllRelabeledTargetsExpr()}
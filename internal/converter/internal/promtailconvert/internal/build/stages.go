@@ -113,12 +113,13 @@ func convertGeoIP(cfg interface{}, diags *diag.Diagnostics) (stages.StageConfig,
 		addInvalidStageError(diags, cfg, err)
 		return stages.StageConfig{}, false
 	}
+	result := &stages.GeoIPConfig{}
+	result.SetToDefault()
+	result.DB = pCfg.DB
+	result.Source = pCfg.Source
+	result.DBType = pCfg.DBType
 	return stages.StageConfig{
-		GeoIPConfig: &stages.GeoIPConfig{
-			DB:     pCfg.DB,
-			Source: pCfg.Source,
-			DBType: pCfg.DBType,
-		},
+		GeoIPConfig: result,
 	}, true
 }
 
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow"
 	"github.com/grafana/agent/internal/flow/internal/testcomponents"
@@ -311,9 +312,23 @@ func TestDeclareError(t *testing.T) {
 			}
 			a "t3" {}
 			`,
-			// using regex here because the order of the node can vary
-			// not ideal because it could technically match "a" and "a"
-			expectedError: regexp.MustCompile(`cycle: declare\.(a|b), declare\.(a|b)`),
+			expectedError: regexp.MustCompile(`cycle: declare\.a -> declare\.b -> declare\.a \(declare\.a instantiates b "t1" at .*:3:5, declare\.b instantiates a "t2" at .*:6:5\)`),
+		},
+		{
+			name: "CircleDependencyBetweenThreeDeclares",
+			config: `
+			declare "a" {
+				b "t1" {}
+			}
+			declare "b" {
+				c "t2" {}
+			}
+			declare "c" {
+				a "t3" {}
+			}
+			a "t4" {}
+			`,
+			expectedError: regexp.MustCompile(`cycle: declare\.a -> declare\.b -> declare\.c -> declare\.a \(declare\.a instantiates b "t1" at .*:3:5, declare\.b instantiates c "t2" at .*:6:5, declare\.c instantiates a "t3" at .*:9:5\)`),
 		},
 		{
 			name: "CircleDependencyWithinDeclare",
@@ -329,7 +344,7 @@ func TestDeclareError(t *testing.T) {
 			}
 			a "t4" {}
 			`,
-			expectedError: regexp.MustCompile(`cycle: declare\.(b|c), declare\.(b|c)`),
+			expectedError: regexp.MustCompile(`cycle: declare\.b -> declare\.c -> declare\.b \(declare\.b instantiates c "t1" at .*:4:6, declare\.c instantiates b "t2" at .*:7:6\)`),
 		},
 		{
 			name: "CircleDependencyWithItself",
@@ -339,7 +354,7 @@ func TestDeclareError(t *testing.T) {
 			}
 			a "t2" {}
 			`,
-			expectedError: regexp.MustCompile(`self reference: declare\.a`),
+			expectedError: regexp.MustCompile(`cycle: declare\.a -> declare\.a \(declare\.a instantiates a "t1" at .*:3:5\)`),
 		},
 		{
 			name: "OutOfScopeReference",
@@ -513,3 +528,72 @@ func TestDeclareUpdateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestDeclareMaxModuleDepth(t *testing.T) {
+	config := `
+	declare "a" {
+		declare "b" {
+			declare "c" {
+				export "output" {
+					value = 1
+				}
+			}
+			c "default" {}
+			export "output" {
+				value = c.default.output
+			}
+		}
+		b "default" {}
+		export "output" {
+			value = b.default.output
+		}
+	}
+	a "default" {}
+	`
+
+	t.Run("WithinLimit", func(t *testing.T) {
+		opts := testOptions(t)
+		opts.MaxModuleDepth = 3
+		ctrl := flow.New(opts)
+		f, err := flow.ParseSource(t.Name(), []byte(config))
+		require.NoError(t, err)
+
+		require.NoError(t, ctrl.LoadSource(f, nil))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			ctrl.Run(ctx)
+			close(done)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		require.Eventually(t, func() bool {
+			info, err := ctrl.GetComponent(component.ID{ModuleID: "a.default/b.default", LocalID: "c.default"}, component.InfoOptions{})
+			return err == nil && info.NestingDepth == 2
+		}, 3*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("ExceedsLimit", func(t *testing.T) {
+		opts := testOptions(t)
+		opts.MaxModuleDepth = 2
+		ctrl := flow.New(opts)
+		f, err := flow.ParseSource(t.Name(), []byte(config))
+		require.NoError(t, err)
+
+		err = ctrl.LoadSource(f, nil)
+		require.ErrorContains(t, err, "maximum module nesting depth of 2 exceeded: a.default -> a.default/b.default -> a.default/b.default/c.default")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			ctrl.Run(ctx)
+			close(done)
+		}()
+		cancel()
+		<-done
+	})
+}
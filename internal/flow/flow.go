@@ -56,6 +56,7 @@ import (
 	"github.com/grafana/agent/internal/flow/internal/worker"
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/flow/modulelock"
 	"github.com/grafana/agent/internal/flow/tracing"
 	"github.com/grafana/agent/internal/service"
 	"github.com/prometheus/client_golang/prometheus"
@@ -95,6 +96,11 @@ type Options struct {
 	// the user, for example, via command-line flags.
 	MinStability featuregate.Stability
 
+	// Features holds the feature flags of the agent, such as whether
+	// clustering is enabled. Features are exposed to the loaded config as
+	// sys.features, along with build information.
+	Features map[string]bool
+
 	// OnExportsChange is called when the exports of the controller change.
 	// Exports are controlled by "export" configuration blocks. If
 	// OnExportsChange is nil, export configuration blocks are not allowed in the
@@ -106,8 +112,23 @@ type Options struct {
 	// Services are configured when LoadFile is invoked. Services are started
 	// when the Flow controller runs after LoadFile is invoked at least once.
 	Services []service.Service
+
+	// MaxModuleDepth is the maximum number of modules and custom components
+	// which may be nested inside each other. Creating a module deeper than
+	// MaxModuleDepth fails with an error describing the full chain of modules.
+	//
+	// DefaultMaxModuleDepth is used if MaxModuleDepth is 0.
+	MaxModuleDepth int
+
+	// ModuleLock is the lockfile the content of import blocks is checked
+	// against, including the import blocks of modules. Imported content is
+	// accepted without checks if ModuleLock is nil.
+	ModuleLock *modulelock.Lockfile
 }
 
+// DefaultMaxModuleDepth is the default value for Options.MaxModuleDepth.
+const DefaultMaxModuleDepth = 20
+
 // Flow is the Flow system.
 type Flow struct {
 	log    *logging.Logger
@@ -120,6 +141,7 @@ type Flow struct {
 	modules     *moduleRegistry
 
 	loadFinished chan struct{}
+	pings        chan struct{}
 
 	loadMut    sync.RWMutex
 	loadedOnce atomic.Bool
@@ -143,6 +165,7 @@ type controllerOptions struct {
 	ComponentRegistry controller.ComponentRegistry // Custom component registry used in tests.
 	ModuleRegistry    *moduleRegistry              // Where to register created modules.
 	IsModule          bool                         // Whether this controller is for a module.
+	ModuleChain       []string                     // IDs of the modules from the root controller down to this controller.
 	// A worker pool to evaluate components asynchronously. A default one will be created if this is nil.
 	WorkerPool worker.Pool
 }
@@ -171,6 +194,10 @@ func newController(o controllerOptions) *Flow {
 		workerPool = worker.NewDefaultWorkerPool()
 	}
 
+	if o.MaxModuleDepth <= 0 {
+		o.MaxModuleDepth = DefaultMaxModuleDepth
+	}
+
 	f := &Flow{
 		log:    log,
 		tracer: tracer,
@@ -182,6 +209,7 @@ func newController(o controllerOptions) *Flow {
 		modules: o.ModuleRegistry,
 
 		loadFinished: make(chan struct{}, 1),
+		pings:        make(chan struct{}),
 	}
 
 	serviceMap := controller.NewServiceMap(o.Services)
@@ -192,6 +220,7 @@ func newController(o controllerOptions) *Flow {
 			TraceProvider: tracer,
 			DataPath:      o.DataPath,
 			MinStability:  o.MinStability,
+			Features:      o.Features,
 			OnBlockNodeUpdate: func(cn controller.BlockNode) {
 				// Changed node should be queued for reevaluation.
 				f.updateQueue.Enqueue(&controller.QueuedNode{Node: cn, LastUpdatedTime: time.Now()})
@@ -199,6 +228,7 @@ func newController(o controllerOptions) *Flow {
 			OnExportsChange: o.OnExportsChange,
 			Registerer:      o.Reg,
 			ControllerID:    o.ControllerID,
+			ModuleLock:      o.ModuleLock,
 			NewModuleController: func(id string) controller.ModuleController {
 				return newModuleController(&moduleControllerOptions{
 					ComponentRegistry: o.ComponentRegistry,
@@ -208,9 +238,13 @@ func newController(o controllerOptions) *Flow {
 					Reg:               o.Reg,
 					DataPath:          o.DataPath,
 					MinStability:      o.MinStability,
+					Features:          o.Features,
 					ID:                id,
 					ServiceMap:        serviceMap,
 					WorkerPool:        workerPool,
+					MaxModuleDepth:    o.MaxModuleDepth,
+					ModuleChain:       o.ModuleChain,
+					ModuleLock:        o.ModuleLock,
 				})
 			},
 			GetServiceData: func(name string) (interface{}, error) {
@@ -279,10 +313,23 @@ func (f *Flow) Run(ctx context.Context) {
 			if err != nil {
 				level.Error(f.log).Log("msg", "failed to load components and services", "err", err)
 			}
+		case <-f.pings:
 		}
 	}
 }
 
+// Ping blocks until the run loop of the controller responds, returning an
+// error if ctx is canceled first. Ping is used to detect a controller which
+// is stuck.
+func (f *Flow) Ping(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case f.pings <- struct{}{}:
+		return nil
+	}
+}
+
 // LoadSource synchronizes the state of the controller with the current config
 // source. Components in the graph will be marked as unhealthy if there was an
 // error encountered during Load.
@@ -323,6 +370,12 @@ func (f *Flow) loadSource(source *Source, args map[string]any, customComponentRe
 	return diags.ErrorOrNil()
 }
 
+// ModuleDepth returns the number of modules the Flow controller is nested
+// under. The root controller has a depth of 0.
+func (f *Flow) ModuleDepth() int {
+	return len(f.opts.ModuleChain)
+}
+
 // Ready returns whether the Flow controller has finished its initial load.
 func (f *Flow) Ready() bool {
 	return f.loadedOnce.Load()
@@ -2,10 +2,14 @@ package flow
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/flow/internal/controller"
 	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/river/ast"
 )
 
 // GetComponent implements [component.Provider].
@@ -63,6 +67,66 @@ func (f *Flow) ListComponents(moduleID string, opts component.InfoOptions) ([]*c
 	return detail, nil
 }
 
+// GetComponentsSnapshot implements [component.SnapshotProvider].
+func (f *Flow) GetComponentsSnapshot(ids []component.ID, opts component.InfoOptions) (*component.Snapshot, error) {
+	f.loadMut.RLock()
+	defer f.loadMut.RUnlock()
+
+	byModule := make(map[string][]component.ID)
+	for _, id := range ids {
+		byModule[id.ModuleID] = append(byModule[id.ModuleID], id)
+	}
+	// Modules are paused in order, so that a module is always paused before the
+	// modules nested in it, whose configs are loaded while evaluating it.
+	moduleIDs := make([]string, 0, len(byModule))
+	for moduleID := range byModule {
+		moduleIDs = append(moduleIDs, moduleID)
+	}
+	sort.Strings(moduleIDs)
+
+	var resumes []func()
+	defer func() {
+		for i := len(resumes) - 1; i >= 0; i-- {
+			resumes[i]()
+		}
+	}()
+
+	infos := make(map[component.ID]*component.Info, len(ids))
+	for _, moduleID := range moduleIDs {
+		mf := f
+		if moduleID != "" {
+			mod, ok := f.modules.Get(moduleID)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", component.ErrComponentNotFound, byModule[moduleID][0])
+			}
+			mf = mod.f
+
+			mf.loadMut.RLock()
+			resumes = append(resumes, mf.loadMut.RUnlock)
+		}
+
+		graph, resume := mf.loader.PauseEvaluation()
+		resumes = append(resumes, resume)
+
+		for _, id := range byModule[moduleID] {
+			cn, ok := graph.GetByID(id.LocalID).(controller.ComponentNode)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", component.ErrComponentNotFound, id)
+			}
+			infos[id] = mf.getComponentDetail(cn, graph, opts)
+		}
+	}
+
+	snapshot := &component.Snapshot{
+		Time:       time.Now(),
+		Components: make([]*component.Info, len(ids)),
+	}
+	for i, id := range ids {
+		snapshot.Components[i] = infos[id]
+	}
+	return snapshot, nil
+}
+
 func (f *Flow) getComponentDetail(cn controller.ComponentNode, graph *dag.Graph, opts component.InfoOptions) *component.Info {
 	var references, referencedBy []string
 
@@ -108,12 +172,14 @@ func (f *Flow) getComponentDetail(cn controller.ComponentNode, graph *dag.Graph,
 			LocalID:  cn.NodeID(),
 		},
 		Label: cn.Label(),
+		Meta:  cn.Meta(),
 
 		References:   references,
 		ReferencedBy: referencedBy,
 
 		ComponentName: cn.ComponentName(),
 		Health:        health,
+		NestingDepth:  f.ModuleDepth(),
 
 		Arguments: arguments,
 		Exports:   exports,
@@ -121,11 +187,71 @@ func (f *Flow) getComponentDetail(cn controller.ComponentNode, graph *dag.Graph,
 		ModuleIDs: cn.ModuleIDs(),
 	}
 
-	if builtinComponent, ok := cn.(*controller.BuiltinComponentNode); ok {
-		componentInfo.Component = builtinComponent.Component()
+	if opts.GetExportsHistory {
+		componentInfo.ExportsHistory = cn.ExportsHistory()
+	}
+	if opts.GetErrorsHistory {
+		componentInfo.ErrorsHistory = cn.ErrorsHistory()
+	}
+	if opts.GetExportReferences {
+		componentInfo.ExportReferences = f.getExportReferences(cn, graph)
+	}
+
+	switch cn := cn.(type) {
+	case *controller.BuiltinComponentNode:
+		componentInfo.Component = cn.Component()
 		if opts.GetDebugInfo {
-			componentInfo.DebugInfo = builtinComponent.DebugInfo()
+			componentInfo.DebugInfo = cn.DebugInfo()
 		}
+	case *controller.CustomComponentNode:
+		componentInfo.ModuleSource = cn.ModuleSource()
 	}
 	return componentInfo
 }
+
+// getExportReferences returns the expressions of the blocks depending on cn
+// which reference cn. Unlike ReferencedBy, references made by configuration
+// blocks are included.
+func (f *Flow) getExportReferences(cn controller.ComponentNode, graph *dag.Graph) []component.ExportReference {
+	var res []component.ExportReference
+	for _, dep := range graph.Dependants(cn) {
+		refs, _ := controller.ComponentReferences(dep, graph)
+		for _, ref := range refs {
+			if ref.Target != cn {
+				continue
+			}
+
+			pos := ast.StartPos(ref.Expression[0]).Position()
+			res = append(res, component.ExportReference{
+				Component:  component.ID{ModuleID: f.opts.ControllerID, LocalID: dep.NodeID()}.String(),
+				Attribute:  strings.Join(ref.Attribute, "."),
+				Expression: joinTraversal(ref.Expression),
+				Field:      joinTraversal(ref.Traversal),
+				File:       pos.Filename,
+				Line:       pos.Line,
+				Column:     pos.Column,
+			})
+		}
+	}
+
+	// Dependants aren't ordered, so sort references for a stable output.
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Component != res[j].Component {
+			return res[i].Component < res[j].Component
+		}
+		if res[i].Line != res[j].Line {
+			return res[i].Line < res[j].Line
+		}
+		return res[i].Column < res[j].Column
+	})
+	return res
+}
+
+// joinTraversal returns the dotted representation of t.
+func joinTraversal(t controller.Traversal) string {
+	names := make([]string, len(t))
+	for i, ident := range t {
+		names[i] = ident.Name
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,155 @@
+package flow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/internal/controller"
+	"github.com/grafana/agent/internal/flow/internal/importsource"
+	"github.com/grafana/agent/internal/flow/internal/testcomponents"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetComponentsSnapshot(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+
+	config := `
+	testcomponents.count "inc" {
+		frequency = "10ms"
+		max = 20
+	}
+
+	testcomponents.summation "sum" {
+		input = testcomponents.count.inc.count
+	}
+	`
+
+	ctrl := New(testOptions(t))
+	f, err := ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	ids := []component.ID{
+		{LocalID: "testcomponents.summation.sum"},
+		{LocalID: "testcomponents.count.inc"},
+	}
+	snapshot := func() (input, count int) {
+		s, err := ctrl.GetComponentsSnapshot(ids, component.InfoOptions{GetArguments: true, GetExports: true})
+		require.NoError(t, err)
+		require.Len(t, s.Components, 2)
+		require.Equal(t, ids[0], s.Components[0].ID)
+		require.Equal(t, ids[1], s.Components[1].ID)
+		return s.Components[0].Arguments.(testcomponents.SummationConfig).Input,
+			s.Components[1].Exports.(testcomponents.CountExports).Count
+	}
+
+	// Exports keep changing while evaluation is paused, but the components
+	// depending on them aren't evaluated until it resumes.
+	require.Eventually(t, func() bool {
+		input, _ := snapshot()
+		return input > 0
+	}, 3*time.Second, 10*time.Millisecond)
+
+	graph, resume := ctrl.loader.PauseEvaluation()
+	sum := graph.GetByID("testcomponents.summation.sum").(controller.ComponentNode)
+	paused := sum.Arguments().(testcomponents.SummationConfig).Input
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, paused, sum.Arguments().(testcomponents.SummationConfig).Input)
+	resume()
+
+	require.Eventually(t, func() bool {
+		input, count := snapshot()
+		return count == 20 && input == count
+	}, 3*time.Second, 10*time.Millisecond)
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := ctrl.GetComponentsSnapshot([]component.ID{{LocalID: "testcomponents.count.unknown"}}, component.InfoOptions{})
+		require.ErrorIs(t, err, component.ErrComponentNotFound)
+
+		_, err = ctrl.GetComponentsSnapshot([]component.ID{{ModuleID: "unknown", LocalID: "testcomponents.count.inc"}}, component.InfoOptions{})
+		require.ErrorIs(t, err, component.ErrComponentNotFound)
+	})
+}
+
+func TestGetComponent_ModuleSource(t *testing.T) {
+	libFile := filepath.Join(t.TempDir(), "lib.river")
+	lib := `
+	import.string "inner" {
+		content = "declare \"leaf\" { argument \"a\" { optional = true } }"
+	}
+
+	declare "wrapper" {
+		inner.leaf "y" {}
+	}
+	`
+	require.NoError(t, os.WriteFile(libFile, []byte(lib), 0664))
+
+	config := `
+	import.file "lib" {
+		filename = "` + filepath.ToSlash(libFile) + `"
+	}
+
+	declare "local" {
+		argument "a" { optional = true }
+	}
+
+	lib.wrapper "x" {}
+	local "z" {}
+	`
+
+	ctrl := New(testOptions(t))
+	f, err := ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Modules of custom components are only registered once they run.
+	moduleSource := func(id component.ID) *component.ModuleSource {
+		var info *component.Info
+		require.Eventually(t, func() bool {
+			info, err = ctrl.GetComponent(id, component.InfoOptions{})
+			return err == nil
+		}, 3*time.Second, 10*time.Millisecond)
+		return info.ModuleSource
+	}
+
+	require.Equal(t, &component.ModuleSource{
+		Imports:  []string{"import.file.lib"},
+		Location: filepath.ToSlash(libFile),
+		SHA256:   importsource.ContentDigest(map[string]string{libFile: lib}),
+		Declare:  "wrapper",
+	}, moduleSource(component.ID{LocalID: "lib.wrapper.x"}))
+
+	require.Equal(t, &component.ModuleSource{
+		Imports: []string{"import.file.lib", "import.string.inner"},
+		SHA256:  importsource.ContentDigest(map[string]string{"import_string": `declare "leaf" { argument "a" { optional = true } }`}),
+		Declare: "leaf",
+	}, moduleSource(component.ID{ModuleID: "lib.wrapper.x", LocalID: "inner.leaf.y"}))
+
+	require.Nil(t, moduleSource(component.ID{LocalID: "local.z"}))
+}
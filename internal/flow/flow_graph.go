@@ -0,0 +1,148 @@
+package flow
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/internal/controller"
+	"github.com/grafana/agent/internal/flow/internal/dag"
+)
+
+// GetGraph implements [component.GraphProvider].
+func (f *Flow) GetGraph(moduleID string, recursive bool) (*component.Graph, error) {
+	f.loadMut.RLock()
+	defer f.loadMut.RUnlock()
+
+	if moduleID != "" {
+		mod, ok := f.modules.Get(moduleID)
+		if !ok {
+			return nil, component.ErrModuleNotFound
+		}
+
+		return mod.f.GetGraph("", recursive)
+	}
+
+	graph := f.loader.OriginalGraph()
+	out := &component.Graph{
+		Nodes: make([]component.GraphNode, 0, len(graph.Nodes())),
+		Edges: make([]component.GraphEdge, 0, len(graph.Edges())),
+	}
+
+	var createdModules []string
+	for _, n := range graph.Nodes() {
+		node := f.graphNode(n)
+		out.Nodes = append(out.Nodes, node)
+		createdModules = append(createdModules, node.CreatedModuleIDs...)
+
+		// Custom components instantiating a local declare block aren't wired to
+		// the declare node itself, only to what the declare block depends on.
+		if node.Definition != "" && !hasEdge(graph, n, node.Definition, f.globalID) {
+			out.Edges = append(out.Edges, component.GraphEdge{
+				From: node.ID,
+				To:   node.Definition,
+				Kind: component.GraphEdgeDefinition,
+			})
+		}
+	}
+	for _, e := range graph.Edges() {
+		out.Edges = append(out.Edges, component.GraphEdge{
+			From: f.globalID(e.From),
+			To:   f.globalID(e.To),
+			Kind: graphEdgeKind(e),
+		})
+	}
+
+	if recursive {
+		for _, id := range createdModules {
+			mod, ok := f.modules.Get(id)
+			if !ok {
+				continue
+			}
+			sub, err := mod.f.GetGraph("", true)
+			if err != nil {
+				return nil, err
+			}
+			out.Nodes = append(out.Nodes, sub.Nodes...)
+			out.Edges = append(out.Edges, sub.Edges...)
+		}
+	}
+
+	sort.Slice(out.Nodes, func(i, j int) bool { return out.Nodes[i].ID < out.Nodes[j].ID })
+	sort.Slice(out.Edges, func(i, j int) bool {
+		if out.Edges[i].From != out.Edges[j].From {
+			return out.Edges[i].From < out.Edges[j].From
+		}
+		return out.Edges[i].To < out.Edges[j].To
+	})
+	return out, nil
+}
+
+func (f *Flow) graphNode(n dag.Node) component.GraphNode {
+	node := component.GraphNode{
+		ID:       f.globalID(n),
+		ModuleID: f.opts.ControllerID,
+		LocalID:  n.NodeID(),
+		Name:     n.NodeID(),
+	}
+	if bn, ok := n.(controller.BlockNode); ok {
+		if b := bn.Block(); b != nil {
+			node.Name = strings.Join(b.Name, ".")
+			node.Label = b.Label
+		}
+	}
+
+	switch n := n.(type) {
+	case *controller.BuiltinComponentNode:
+		node.Kind = component.GraphNodeComponent
+		node.CreatedModuleIDs = n.ModuleIDs()
+	case *controller.CustomComponentNode:
+		node.Kind = component.GraphNodeCustomComponent
+		node.CreatedModuleIDs = n.ModuleIDs()
+		if def := f.loader.CustomComponentDefinition(n); def != nil {
+			node.Definition = f.globalID(def)
+		}
+	case *controller.ForeachNode:
+		node.Kind = component.GraphNodeCustomComponent
+		node.CreatedModuleIDs = n.ModuleIDs()
+	case *controller.DeclareNode:
+		node.Kind = component.GraphNodeDeclare
+	case *controller.ImportConfigNode:
+		node.Kind = component.GraphNodeImport
+	case *controller.ServiceNode:
+		node.Kind = component.GraphNodeService
+	default:
+		node.Kind = component.GraphNodeConfig
+	}
+	return node
+}
+
+// globalID returns the ID of n which is unique across modules.
+func (f *Flow) globalID(n dag.Node) string {
+	return component.ID{ModuleID: f.opts.ControllerID, LocalID: n.NodeID()}.String()
+}
+
+// graphEdgeKind returns the kind of e. Edges from custom components and
+// declare blocks to declare or import blocks are the ones which resolve the
+// definitions of custom components.
+func graphEdgeKind(e dag.Edge) string {
+	switch e.From.(type) {
+	case *controller.CustomComponentNode, *controller.DeclareNode:
+		switch e.To.(type) {
+		case *controller.DeclareNode, *controller.ImportConfigNode:
+			return component.GraphEdgeDefinition
+		}
+	}
+	return component.GraphEdgeReference
+}
+
+// hasEdge returns true if graph has an edge from n to the node with the
+// global ID to.
+func hasEdge(graph *dag.Graph, n dag.Node, to string, globalID func(dag.Node) string) bool {
+	for _, dep := range graph.Dependencies(n) {
+		if globalID(dep) == to {
+			return true
+		}
+	}
+	return false
+}
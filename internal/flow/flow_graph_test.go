@@ -0,0 +1,125 @@
+package flow_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGraph(t *testing.T) {
+	config := `
+	declare "test" {
+		argument "input" {
+			optional = false
+		}
+
+		declare "nested" {
+			export "output" {
+				value = 1
+			}
+		}
+
+		nested "default" {}
+
+		testcomponents.passthrough "pt" {
+			input = argument.input.value
+			lag = "1ms"
+		}
+
+		export "output" {
+			value = testcomponents.passthrough.pt.output
+		}
+	}
+
+	testcomponents.count "inc" {
+		frequency = "10ms"
+		max = 10
+	}
+
+	test "myModule" {
+		input = testcomponents.count.inc.count
+	}
+	`
+
+	ctrl := flow.New(testOptions(t))
+	f, err := flow.ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Modules are registered once they run.
+	require.Eventually(t, func() bool {
+		_, err := ctrl.GetGraph("test.myModule", false)
+		return err == nil
+	}, 3*time.Second, 10*time.Millisecond)
+
+	t.Run("Module", func(t *testing.T) {
+		graph, err := ctrl.GetGraph("", false)
+		require.NoError(t, err)
+
+		custom := findGraphNode(t, graph, "test.myModule")
+		require.Equal(t, component.GraphNodeCustomComponent, custom.Kind)
+		require.Equal(t, "test", custom.Name)
+		require.Equal(t, "myModule", custom.Label)
+		require.Equal(t, "declare.test", custom.Definition)
+		require.Equal(t, []string{"test.myModule"}, custom.CreatedModuleIDs)
+
+		require.Equal(t, component.GraphNodeDeclare, findGraphNode(t, graph, "declare.test").Kind)
+		require.Equal(t, component.GraphNodeComponent, findGraphNode(t, graph, "testcomponents.count.inc").Kind)
+
+		require.Contains(t, graph.Edges, component.GraphEdge{From: "test.myModule", To: "declare.test", Kind: component.GraphEdgeDefinition})
+		require.Contains(t, graph.Edges, component.GraphEdge{From: "test.myModule", To: "testcomponents.count.inc", Kind: component.GraphEdgeReference})
+
+		for _, n := range graph.Nodes {
+			require.Empty(t, n.ModuleID, "nested module %q shouldn't be included", n.ModuleID)
+		}
+	})
+
+	t.Run("Recursive", func(t *testing.T) {
+		graph, err := ctrl.GetGraph("", true)
+		require.NoError(t, err)
+
+		nested := findGraphNode(t, graph, "test.myModule/nested.default")
+		require.Equal(t, "test.myModule", nested.ModuleID)
+		require.Equal(t, "nested.default", nested.LocalID)
+		require.Equal(t, "test.myModule/declare.nested", nested.Definition)
+
+		require.Contains(t, graph.Edges, component.GraphEdge{From: "test.myModule/nested.default", To: "test.myModule/declare.nested", Kind: component.GraphEdgeDefinition})
+	})
+
+	t.Run("NestedModule", func(t *testing.T) {
+		graph, err := ctrl.GetGraph("test.myModule", false)
+		require.NoError(t, err)
+		findGraphNode(t, graph, "test.myModule/testcomponents.passthrough.pt")
+	})
+
+	t.Run("UnknownModule", func(t *testing.T) {
+		_, err := ctrl.GetGraph("unknown", false)
+		require.ErrorIs(t, err, component.ErrModuleNotFound)
+	})
+}
+
+func findGraphNode(t *testing.T, graph *component.Graph, id string) component.GraphNode {
+	t.Helper()
+	for _, n := range graph.Nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	require.FailNow(t, "node not found", "node %q not found in graph", id)
+	return component.GraphNode{}
+}
@@ -80,6 +80,7 @@ func (f *Flow) NewController(id string) service.Controller {
 				MinStability:    f.opts.MinStability,
 				Reg:             f.opts.Reg,
 				Services:        f.opts.Services,
+				MaxModuleDepth:  f.opts.MaxModuleDepth,
 				OnExportsChange: nil, // NOTE(@tpaschalis, @wildum) The isolated controller shouldn't be able to export any values.
 			},
 			IsModule:       true,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/featuregate"
@@ -54,6 +55,112 @@ func TestController_LoadSource_Evaluation(t *testing.T) {
 	require.Equal(t, "hello, world!", out.(testcomponents.PassthroughExports).Output)
 }
 
+func TestController_Sys(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	opts := testOptions(t)
+	opts.Features = map[string]bool{"clustering": true}
+	ctrl := New(opts)
+	defer cleanUpController(ctrl)
+
+	f, err := ParseSource(t.Name(), []byte(`
+		declare "features" {
+			testcomponents.passthrough "inner" {
+				input = format("clustering=%v", sys.features.clustering)
+			}
+
+			export "output" {
+				value = testcomponents.passthrough.inner.output
+			}
+		}
+
+		features "default" { }
+
+		testcomponents.passthrough "root" {
+			input = format("stability=%s beta=%v experimental=%v", sys.stability_level, sys.features.beta, sys.features.experimental)
+		}
+
+		testcomponents.passthrough "module" {
+			input = features.default.output
+		}
+	`))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	in, _ := getFields(t, ctrl.loader.Graph(), "testcomponents.passthrough.root")
+	require.Equal(t, "stability=beta beta=true experimental=false", in.(testcomponents.PassthroughConfig).Input)
+	in, _ = getFields(t, ctrl.loader.Graph(), "testcomponents.passthrough.module")
+	require.Equal(t, "clustering=true", in.(testcomponents.PassthroughConfig).Input)
+}
+
+func TestController_Ping(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	ctrl := New(testOptions(t))
+
+	// The controller only responds to pings while it's running.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, ctrl.Ping(ctx), context.DeadlineExceeded)
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(runCtx)
+		close(done)
+	}()
+	defer func() {
+		runCancel()
+		<-done
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, ctrl.Ping(ctx))
+}
+
+func TestController_ExportReferences(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	f, err := ParseSource(t.Name(), []byte(testFile+`
+	testcomponents.passthrough "formatted" {
+		input = format("%s %s", testcomponents.passthrough.static.output, testcomponents.passthrough.ticker.output)
+	}
+`))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	info, err := ctrl.GetComponent(component.ID{LocalID: "testcomponents.passthrough.ticker"}, component.InfoOptions{
+		GetExportReferences: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []component.ExportReference{
+		{
+			Component:  "testcomponents.passthrough.formatted",
+			Attribute:  "input",
+			Expression: "testcomponents.passthrough.ticker.output",
+			Field:      "output",
+			File:       t.Name(),
+			Line:       19,
+			Column:     69,
+		},
+		{
+			Component:  "testcomponents.passthrough.forwarded",
+			Attribute:  "input",
+			Expression: "testcomponents.passthrough.ticker.output",
+			Field:      "output",
+			File:       t.Name(),
+			Line:       15,
+			Column:     11,
+		},
+	}, info.ExportReferences)
+
+	// References are only set when requested.
+	info, err = ctrl.GetComponent(component.ID{LocalID: "testcomponents.passthrough.ticker"}, component.InfoOptions{})
+	require.NoError(t, err)
+	require.Nil(t, info.ExportReferences)
+}
+
 func getFields(t *testing.T, g *dag.Graph, nodeID string) (component.Arguments, component.Exports) {
 	t.Helper()
 
@@ -0,0 +1,147 @@
+package flow_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/internal/testcomponents"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+const foreachDeclare = `
+	declare "tenant" {
+		argument "max" {
+			optional = false
+		}
+
+		testcomponents.count "inc" {
+			frequency = "10ms"
+			max = argument.max.value
+		}
+	}
+`
+
+func TestForeach(t *testing.T) {
+	config := foreachDeclare + `
+	foreach "tenants" {
+		collection = {a = 10, b = 20}
+
+		tenant "default" {
+			max = each.value
+		}
+	}
+	`
+	newConfig := foreachDeclare + `
+	foreach "tenants" {
+		collection = {a = 10, c = 5}
+
+		tenant "default" {
+			max = each.value
+		}
+	}
+	`
+
+	ctrl := flow.New(testOptions(t))
+	f, err := flow.ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	count := func(key string) int {
+		return getExport[testcomponents.CountExports](t, ctrl, "foreach.tenants/"+key+"/tenant.default", "testcomponents.count.inc").Count
+	}
+	require.Eventually(t, func() bool {
+		return count("a") == 10 && count("b") == 20
+	}, 3*time.Second, 10*time.Millisecond)
+
+	f, err = flow.ParseSource(t.Name(), []byte(newConfig))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	// The instance of a kept running, so its count doesn't start over.
+	require.Equal(t, 10, count("a"))
+	require.Eventually(t, func() bool {
+		return count("c") == 5
+	}, 3*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		_, err := ctrl.GetComponent(component.ID{
+			ModuleID: "foreach.tenants/b/tenant.default",
+			LocalID:  "testcomponents.count.inc",
+		}, component.InfoOptions{})
+		return err == component.ErrComponentNotFound
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestForeachError(t *testing.T) {
+	tt := []struct {
+		name        string
+		config      string
+		expectedErr string
+	}{
+		{
+			name: "InvalidCollection",
+			config: foreachDeclare + `
+			foreach "tenants" {
+				collection = "a"
+
+				tenant "default" {
+					max = 1
+				}
+			}
+			`,
+			expectedErr: "collection must be an array or an object, got string",
+		},
+		{
+			name: "NoTemplate",
+			config: `
+			foreach "tenants" {
+				collection = ["a"]
+			}
+			`,
+			expectedErr: "foreach.tenants must contain exactly one custom component block, found 0 blocks",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := logging.New(os.Stderr, logging.DefaultOptions)
+			require.NoError(t, err)
+			ctrl := flow.New(flow.Options{
+				Logger:       s,
+				DataPath:     t.TempDir(),
+				MinStability: featuregate.StabilityBeta,
+				Reg:          nil,
+				Services:     []service.Service{},
+			})
+			f, err := flow.ParseSource(t.Name(), []byte(tc.config))
+			require.NoError(t, err)
+			require.ErrorContains(t, ctrl.LoadSource(f, nil), tc.expectedErr)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				ctrl.Run(ctx)
+				close(done)
+			}()
+			cancel()
+			<-done
+		})
+	}
+}
@@ -2,6 +2,7 @@ package flow_test
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 	"github.com/grafana/agent/internal/flow"
 	"github.com/grafana/agent/internal/flow/internal/testcomponents"
 	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/flow/modulelock"
 	"github.com/grafana/agent/internal/service"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/tools/txtar"
@@ -348,3 +350,57 @@ func getTestFiles(directory string, t *testing.T) []fs.FileInfo {
 
 	return files
 }
+
+func TestImportModuleLock(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+
+	const config = `
+	import.string "testImport" {
+		content = %q
+	}
+
+	testImport.test "myModule" {}
+	`
+	module := `
+	declare "test" {
+		export "out" {
+			value = %d
+		}
+	}
+	`
+	lockPath := filepath.Join(t.TempDir(), modulelock.FileName)
+
+	load := func(mode modulelock.Mode, version int) error {
+		lock, err := modulelock.Open(lockPath, mode)
+		require.NoError(t, err)
+
+		s, err := logging.New(os.Stderr, logging.DefaultOptions)
+		require.NoError(t, err)
+		ctrl := flow.New(flow.Options{
+			Logger:       s,
+			DataPath:     t.TempDir(),
+			MinStability: featuregate.StabilityBeta,
+			ModuleLock:   lock,
+		})
+		f, err := flow.ParseSource(t.Name(), []byte(fmt.Sprintf(config, fmt.Sprintf(module, version))))
+		require.NoError(t, err)
+
+		err = ctrl.LoadSource(f, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ctrl.Run(ctx)
+		return err
+	}
+
+	// Strict mode refuses modules which aren't locked yet.
+	require.ErrorContains(t, load(modulelock.ModeStrict, 1), "isn't in the module lockfile")
+
+	// Update mode records the module, which strict mode then accepts.
+	require.NoError(t, load(modulelock.ModeUpdate, 1))
+	require.NoError(t, load(modulelock.ModeStrict, 1))
+
+	// Strict mode refuses the module once its content changes.
+	require.ErrorContains(t, load(modulelock.ModeStrict, 2), "doesn't match the module lockfile")
+	require.NoError(t, load(modulelock.ModeUpdate, 2))
+	require.NoError(t, load(modulelock.ModeStrict, 2))
+}
@@ -17,9 +17,20 @@ type ComponentNode interface {
 	// Exports returns the current set of exports from the managed component.
 	Exports() component.Exports
 
+	// ExportsHistory returns the past exports of the managed component,
+	// ordered from oldest to newest.
+	ExportsHistory() []component.ExportsRecord
+
+	// ErrorsHistory returns the past errors of the managed component, ordered
+	// from oldest to newest.
+	ErrorsHistory() []component.ErrorRecord
+
 	// Label returns the component label.
 	Label() string
 
+	// Meta returns the metadata labels of the component from its meta block.
+	Meta() map[string]string
+
 	// ComponentName returns the name of the component.
 	ComponentName() string
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/river/ast"
 )
 
@@ -29,6 +30,12 @@ func NewComponentNodeManager(globals ComponentGlobals, componentReg ComponentReg
 
 // CreateComponentNode creates a new builtin component or a new custom component.
 func (m *ComponentNodeManager) createComponentNode(componentName string, block *ast.BlockStmt) (ComponentNode, error) {
+	if componentName == foreachBlockID {
+		if block.Label == "" {
+			return nil, fmt.Errorf("%s block must have a label", foreachBlockID)
+		}
+		return NewForeachNode(m.globals, block, m.getCustomComponentConfig), nil
+	}
 	if isCustomComponent(m.customComponentReg, block.Name[0]) {
 		return NewCustomComponentNode(m.globals, block, m.getCustomComponentConfig), nil
 	}
@@ -42,6 +49,20 @@ func (m *ComponentNodeManager) createComponentNode(componentName string, block *
 	return NewBuiltinComponentNode(m.globals, registration, block), nil
 }
 
+// migrateBlock applies the migrations of the builtin component of block. The
+// blocks of custom components, and of components which aren't registered, are
+// returned unchanged.
+func (m *ComponentNodeManager) migrateBlock(block *ast.BlockStmt) (*ast.BlockStmt, []component.Migration, error) {
+	if block.GetBlockName() == foreachBlockID || isCustomComponent(m.customComponentReg, block.Name[0]) {
+		return block, nil, nil
+	}
+	registration, err := m.builtinComponentReg.Get(block.GetBlockName())
+	if err != nil {
+		return block, nil, nil
+	}
+	return registration.Migrate(block)
+}
+
 // getCustomComponentConfig is used by the custom component to retrieve its template and the customComponentRegistry associated with it.
 func (m *ComponentNodeManager) getCustomComponentConfig(namespace string, componentName string) (ast.Body, *CustomComponentRegistry, error) {
 	m.mut.Lock()
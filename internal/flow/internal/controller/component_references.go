@@ -22,6 +22,15 @@ type Reference struct {
 	// Traversal describes which nested field relative to Target is being
 	// accessed.
 	Traversal Traversal
+
+	// Expression is the full traversal of the reference, starting with the ID
+	// of Target.
+	Expression Traversal
+
+	// Attribute is the path of the attribute holding the reference, relative
+	// to the block making the reference. For example, a reference in the url
+	// attribute of an endpoint block has an Attribute of (endpoint, url).
+	Attribute []string
 }
 
 // ComponentReferences returns the list of references a component is making to
@@ -29,6 +38,7 @@ type Reference struct {
 func ComponentReferences(cn dag.Node, g *dag.Graph) ([]Reference, diag.Diagnostics) {
 	var (
 		traversals []Traversal
+		attributes [][]string
 
 		diags diag.Diagnostics
 	)
@@ -36,12 +46,12 @@ func ComponentReferences(cn dag.Node, g *dag.Graph) ([]Reference, diag.Diagnosti
 	switch cn := cn.(type) {
 	case BlockNode:
 		if cn.Block() != nil {
-			traversals = expressionsFromBody(cn.Block().Body)
+			traversals, attributes = expressionsFromBody(cn.Block().Body)
 		}
 	}
 
 	refs := make([]Reference, 0, len(traversals))
-	for _, t := range traversals {
+	for i, t := range traversals {
 		// We use an empty scope to determine if a reference refers to something in
 		// the stdlib, since vm.Scope.Lookup will search the scope tree + the
 		// stdlib.
@@ -53,10 +63,21 @@ func ComponentReferences(cn dag.Node, g *dag.Graph) ([]Reference, diag.Diagnosti
 		}
 
 		ref, resolveDiags := resolveTraversal(t, g)
+		if resolveDiags.HasErrors() && t[0].Name == sysIdent {
+			// References to the sys variable don't depend on any node.
+			continue
+		}
+		if _, ok := cn.(*ForeachNode); ok && resolveDiags.HasErrors() && t[0].Name == foreachVarIdent {
+			// References to the element of a foreach block are resolved when
+			// its instances are evaluated.
+			continue
+		}
 		diags = append(diags, resolveDiags...)
 		if resolveDiags.HasErrors() {
 			continue
 		}
+		ref.Expression = t
+		ref.Attribute = attributes[i]
 		refs = append(refs, ref)
 	}
 
@@ -64,25 +85,36 @@ func ComponentReferences(cn dag.Node, g *dag.Graph) ([]Reference, diag.Diagnosti
 }
 
 // expressionsFromSyntaxBody recurses through body and finds all variable
-// references.
-func expressionsFromBody(body ast.Body) []Traversal {
+// references, along with the path of the attribute holding each of them.
+func expressionsFromBody(body ast.Body) ([]Traversal, [][]string) {
 	var w traversalWalker
 	ast.Walk(&w, body)
 
 	// Flush after the walk in case there was an in-progress traversal.
 	w.flush()
-	return w.traversals
+	return w.traversals, w.attributes
 }
 
 type traversalWalker struct {
 	traversals []Traversal
+	attributes [][]string // Path of the attribute holding each traversal.
 
 	buildTraversal   bool      // Whether
 	currentTraversal Traversal // currentTraversal being built.
+
+	statements []string // Names of the attribute and blocks being walked.
 }
 
 func (tw *traversalWalker) Visit(node ast.Node) ast.Visitor {
 	switch n := node.(type) {
+	case *ast.AttributeStmt:
+		tw.walkStatement(n.Name.Name, n.Value)
+		return nil
+
+	case *ast.BlockStmt:
+		tw.walkStatement(n.GetBlockName(), n.Body)
+		return nil
+
 	case *ast.IdentifierExpr:
 		// Identifiers always start new traversals. Pop the last one.
 		tw.flush()
@@ -119,11 +151,23 @@ func (tw *traversalWalker) Visit(node ast.Node) ast.Visitor {
 	return tw
 }
 
+// walkStatement walks the children of the statement called name.
+// Traversals never span statements, so they are flushed before and after
+// walking the children.
+func (tw *traversalWalker) walkStatement(name string, children ast.Node) {
+	tw.flush()
+	tw.statements = append(tw.statements, name)
+	ast.Walk(tw, children)
+	tw.flush()
+	tw.statements = tw.statements[:len(tw.statements)-1]
+}
+
 // flush will flush the in-progress traversal to the traversals list and unset
 // the buildTraversal state.
 func (tw *traversalWalker) flush() {
 	if tw.buildTraversal && len(tw.currentTraversal) > 0 {
 		tw.traversals = append(tw.traversals, tw.currentTraversal)
+		tw.attributes = append(tw.attributes, append([]string(nil), tw.statements...))
 	}
 	tw.buildTraversal = false
 	tw.currentTraversal = nil
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressionsFromBody(t *testing.T) {
+	block := parseBlock(t, `
+		prometheus.scrape "default" {
+			targets    = concat(discovery.a.targets, discovery.b.targets[0].list)
+			forward_to = [prometheus.remote_write.default.receiver]
+
+			endpoint {
+				url = env("URL")
+
+				basic_auth {
+					password = local.file.secret.content
+				}
+			}
+		}
+	`)
+
+	traversals, attributes := expressionsFromBody(block.Body)
+
+	var actual [][2]string
+	for i, tr := range traversals {
+		actual = append(actual, [2]string{joinIdents(tr), strings.Join(attributes[i], ".")})
+	}
+	require.Equal(t, [][2]string{
+		{"concat", "targets"},
+		{"discovery.a.targets", "targets"},
+		{"discovery.b.targets", "targets"},
+		{"prometheus.remote_write.default.receiver", "forward_to"},
+		{"env", "endpoint.url"},
+		{"local.file.secret.content", "endpoint.basic_auth.password"},
+	}, actual)
+}
+
+func joinIdents(tr Traversal) string {
+	names := make([]string, len(tr))
+	for i, ident := range tr {
+		names[i] = ident.Name
+	}
+	return strings.Join(names, ".")
+}
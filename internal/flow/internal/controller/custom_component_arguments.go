@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/grafana/river"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/diag"
+	"github.com/grafana/river/vm"
+)
+
+// argumentTypeAny is the type of arguments which accept values of any type.
+const argumentTypeAny = "any"
+
+// argumentTypeNames are the supported values of the type attribute of
+// argument blocks, in the order they're listed in error messages.
+var argumentTypeNames = []string{argumentTypeAny, "number", "string", "bool", "array", "object", "function", "capsule"}
+
+var argumentTypes = func() map[string]struct{} {
+	m := make(map[string]struct{}, len(argumentTypeNames))
+	for _, name := range argumentTypeNames {
+		m[name] = struct{}{}
+	}
+	return m
+}()
+
+// checkArgumentType returns an error if v isn't a value of the River type typ.
+func checkArgumentType(typ string, v any) error {
+	if typ == argumentTypeAny {
+		return nil
+	}
+	if actual := riverTypeOf(v); actual != typ {
+		return fmt.Errorf("expected %s, got %s", typ, actual)
+	}
+	return nil
+}
+
+var (
+	goCapsule       = reflect.TypeOf((*river.Capsule)(nil)).Elem()
+	goTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	goDuration      = reflect.TypeOf(time.Duration(0))
+)
+
+// riverTypeOf returns the name of the River type of the Go value v, following
+// the same rules River uses to convert Go values.
+func riverTypeOf(v any) string {
+	if v == nil {
+		return "null"
+	}
+
+	t := reflect.TypeOf(v)
+	for {
+		switch {
+		case t.Implements(goCapsule):
+			return "capsule"
+		case t.Implements(goTextMarshaler), t == goDuration:
+			return "string"
+		}
+		if t.Kind() != reflect.Pointer {
+			break
+		}
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Array, reflect.Slice:
+		return "array"
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return "capsule"
+		}
+		return "object"
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if _, tagged := t.Field(i).Tag.Lookup("river"); tagged {
+				return "object"
+			}
+		}
+		return "capsule"
+	case reflect.Func:
+		return "function"
+	default:
+		return "capsule"
+	}
+}
+
+// argumentSpec is what the caller of a custom component can know about one of
+// its arguments without evaluating the declare block.
+type argumentSpec struct {
+	// required is true if the argument isn't optional. It's false if the
+	// optional attribute isn't a literal value.
+	required bool
+	// typ is the type of the argument, or "any" if the type attribute isn't a
+	// literal value.
+	typ string
+}
+
+// argumentSpecs returns the arguments declared by the argument blocks of the
+// template of a custom component.
+func argumentSpecs(template ast.Body) map[string]argumentSpec {
+	specs := make(map[string]argumentSpec)
+	for _, stmt := range template {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok || block.GetBlockName() != argumentBlockID {
+			continue
+		}
+
+		spec := argumentSpec{required: true, typ: argumentTypeAny}
+		for _, stmt := range block.Body {
+			attr, ok := stmt.(*ast.AttributeStmt)
+			if !ok {
+				continue
+			}
+			if _, literal := attr.Value.(*ast.LiteralExpr); !literal {
+				// The attribute may depend on the scope of the custom component.
+				if attr.Name.Name == "optional" {
+					spec.required = false
+				}
+				continue
+			}
+			switch attr.Name.Name {
+			case "optional":
+				var optional bool
+				spec.required = vm.New(attr.Value).Evaluate(nil, &optional) == nil && !optional
+			case "type":
+				var typ string
+				if vm.New(attr.Value).Evaluate(nil, &typ) == nil {
+					if _, ok := argumentTypes[typ]; ok {
+						spec.typ = typ
+					}
+				}
+			}
+		}
+		specs[block.Label] = spec
+	}
+	return specs
+}
+
+// validateCustomComponentArguments checks the arguments passed to a custom
+// component against the argument blocks of its template, so that invalid
+// arguments are reported on the block of the caller instead of inside the
+// custom component.
+func validateCustomComponentArguments(caller *ast.BlockStmt, template ast.Body, args map[string]any) diag.Diagnostics {
+	return checkCustomComponentArguments(caller, template, args, true)
+}
+
+// validateCustomComponentAttributes checks the names of the attributes of the
+// block of the caller of a custom component against the argument blocks of
+// its template. It doesn't need the values of the arguments, so the
+// configuration can be checked when it's loaded.
+func validateCustomComponentAttributes(caller *ast.BlockStmt, template ast.Body) diag.Diagnostics {
+	args := make(map[string]any)
+	for _, stmt := range caller.Body {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok {
+			args[attr.Name.Name] = nil
+		}
+	}
+	return checkCustomComponentArguments(caller, template, args, false)
+}
+
+func checkCustomComponentArguments(caller *ast.BlockStmt, template ast.Body, args map[string]any, checkTypes bool) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	attrs := make(map[string]*ast.AttributeStmt)
+	for _, stmt := range caller.Body {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok {
+			attrs[attr.Name.Name] = attr
+		}
+	}
+	attrDiag := func(name, msg string) diag.Diagnostic {
+		d := diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			Message:  msg,
+			StartPos: ast.StartPos(caller).Position(),
+			EndPos:   ast.EndPos(caller).Position(),
+		}
+		if attr, ok := attrs[name]; ok {
+			d.StartPos = ast.StartPos(attr).Position()
+			d.EndPos = ast.EndPos(attr).Position()
+		}
+		return d
+	}
+
+	specs := argumentSpecs(template)
+	for _, name := range sortedKeys(args) {
+		spec, ok := specs[name]
+		if !ok {
+			diags.Add(attrDiag(name, fmt.Sprintf("unsupported argument %q for custom component %s", name, caller.GetBlockName())))
+			continue
+		}
+		if !checkTypes {
+			continue
+		}
+		if err := checkArgumentType(spec.typ, args[name]); err != nil {
+			diags.Add(attrDiag(name, fmt.Sprintf("invalid type for argument %q: %s", name, err)))
+		}
+	}
+	for _, name := range sortedKeys(specs) {
+		if _, ok := args[name]; !ok && specs[name].required {
+			diags.Add(attrDiag(name, fmt.Sprintf("missing required argument %q for custom component %s", name, caller.GetBlockName())))
+		}
+	}
+	return diags
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
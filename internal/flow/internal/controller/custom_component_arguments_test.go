@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/rivertypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRiverTypeOf(t *testing.T) {
+	type riverStruct struct {
+		Name string `river:"name,attr"`
+	}
+	type goStruct struct{ Name string }
+
+	tt := []struct {
+		value  any
+		expect string
+	}{
+		{nil, "null"},
+		{5, "number"},
+		{1.5, "number"},
+		{"text", "string"},
+		{time.Second, "string"},
+		{rivertypes.Secret("secret"), "capsule"},
+		{true, "bool"},
+		{[]any{1, "a"}, "array"},
+		{map[string]any{"a": 1}, "object"},
+		{map[int]string{1: "a"}, "capsule"},
+		{riverStruct{}, "object"},
+		{&riverStruct{}, "object"},
+		{goStruct{}, "capsule"},
+		{func() int { return 0 }, "function"},
+	}
+	for _, tc := range tt {
+		require.Equal(t, tc.expect, riverTypeOf(tc.value), "%#v", tc.value)
+	}
+}
+
+func TestValidateCustomComponentArguments(t *testing.T) {
+	template, err := parser.ParseFile("", []byte(`
+		argument "required" {
+			type = "number"
+		}
+		argument "optional" {
+			optional = true
+		}
+		argument "dynamic" {
+			optional = env("OPTIONAL") == "true"
+		}
+	`))
+	require.NoError(t, err)
+	caller, err := parser.ParseFile("", []byte(`
+		custom "cc" {
+			required = "ten"
+			unknown  = 1
+		}
+	`))
+	require.NoError(t, err)
+
+	diags := validateCustomComponentArguments(caller.Body[0].(*ast.BlockStmt), template.Body, map[string]any{
+		"required": "ten",
+		"unknown":  1,
+	})
+	var msgs []string
+	for _, d := range diags {
+		msgs = append(msgs, d.Message)
+	}
+	require.Equal(t, []string{
+		`invalid type for argument "required": expected number, got string`,
+		`unsupported argument "unknown" for custom component custom`,
+	}, msgs)
+	require.Equal(t, 3, diags[0].StartPos.Line)
+	require.Equal(t, 4, diags[1].StartPos.Line)
+
+	caller, err = parser.ParseFile("", []byte(`
+		custom "cc" {}
+	`))
+	require.NoError(t, err)
+
+	diags = validateCustomComponentArguments(caller.Body[0].(*ast.BlockStmt), template.Body, nil)
+	require.Len(t, diags, 1)
+	require.Equal(t, `missing required argument "required" for custom component custom`, diags[0].Message)
+	require.Equal(t, 2, diags[0].StartPos.Line)
+}
+
+func TestValidateCustomComponentAttributes(t *testing.T) {
+	template, err := parser.ParseFile("", []byte(`
+		argument "required" {
+			type = "number"
+		}
+		argument "optional" {
+			optional = true
+		}
+	`))
+	require.NoError(t, err)
+	caller, err := parser.ParseFile("", []byte(`
+		custom "cc" {
+			optional = "not checked"
+			unknown  = 1
+		}
+	`))
+	require.NoError(t, err)
+
+	// Only the names of the attributes are checked, since their values aren't
+	// known yet.
+	diags := validateCustomComponentAttributes(caller.Body[0].(*ast.BlockStmt), template.Body)
+	var msgs []string
+	for _, d := range diags {
+		msgs = append(msgs, d.Message)
+	}
+	require.Equal(t, []string{
+		`unsupported argument "unknown" for custom component custom`,
+		`missing required argument "required" for custom component custom`,
+	}, msgs)
+}
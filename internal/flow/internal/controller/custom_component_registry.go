@@ -2,8 +2,10 @@ package controller
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/river/ast"
 )
 
@@ -12,6 +14,7 @@ import (
 // Imported definitions are stored inside of the corresponding import registry.
 type CustomComponentRegistry struct {
 	parent *CustomComponentRegistry // nil if root config
+	source *component.ModuleSource  // Module the declares were imported from, nil if not imported
 
 	mut      sync.RWMutex
 	imports  map[string]*CustomComponentRegistry // importNamespace: importScope
@@ -71,6 +74,7 @@ func (s *CustomComponentRegistry) updateImportContent(importNode *ImportConfigNo
 	}
 	importScope := NewCustomComponentRegistry(nil)
 	importScope.declares = importNode.ImportedDeclares()
+	importScope.source = importedModuleSource(importNode, nil)
 	importScope.updateImportContentChildren(importNode)
 	s.imports[importNode.label] = importScope
 }
@@ -81,7 +85,37 @@ func (s *CustomComponentRegistry) updateImportContentChildren(importNode *Import
 	for _, child := range importNode.ImportConfigNodesChildren() {
 		childScope := NewCustomComponentRegistry(nil)
 		childScope.declares = child.ImportedDeclares()
+		childScope.source = importedModuleSource(child, s.source)
 		childScope.updateImportContentChildren(child)
 		s.imports[child.label] = childScope
 	}
 }
+
+// importedModuleSource returns the source of the module imported by
+// importNode. parent is the source of the module holding the import block,
+// or nil if the import block isn't nested in an imported module.
+func importedModuleSource(importNode *ImportConfigNode, parent *component.ModuleSource) *component.ModuleSource {
+	source := importNode.ModuleSource()
+	if parent != nil {
+		source.Imports = slices.Clone(parent.Imports)
+	}
+	source.Imports = append(source.Imports, importNode.NodeID())
+	return &source
+}
+
+// moduleSource returns the source of the module which defines the declare
+// block called name, or nil if the declare block wasn't imported. Declares
+// nested in the declares of an imported module come from the same module, so
+// the source of the closest imported parent is used.
+func (s *CustomComponentRegistry) moduleSource(name string) *component.ModuleSource {
+	for s != nil && s.source == nil {
+		s = s.parent
+	}
+	if s == nil {
+		return nil
+	}
+	source := *s.source
+	source.Imports = slices.Clone(s.source.Imports)
+	source.Declare = name
+	return &source
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/diag"
+)
+
+// declareReference is a block of a declare instantiating another declare.
+type declareReference struct {
+	to    *DeclareNode
+	block *ast.BlockStmt
+}
+
+// findDeclareCycles returns a diagnostic for every cycle between the declare
+// blocks of the loader, such as a declare "a" instantiating a declare "b"
+// which instantiates the declare "a". Each diagnostic lists the chain of
+// declares of the cycle, along with the blocks creating it.
+//
+// l.mut must be held when calling findDeclareCycles.
+func (l *Loader) findDeclareCycles() diag.Diagnostics {
+	refs := make(map[*DeclareNode][]declareReference, len(l.declareNodes))
+	for _, declare := range l.declareNodes {
+		for ref, block := range l.findCustomComponentReferences(declare.Block()) {
+			if to, ok := ref.(*DeclareNode); ok {
+				refs[declare] = append(refs[declare], declareReference{to: to, block: block})
+			}
+		}
+		sort.Slice(refs[declare], func(i, j int) bool {
+			return refs[declare][i].to.NodeID() < refs[declare][j].to.NodeID()
+		})
+	}
+
+	var (
+		diags diag.Diagnostics
+		// inCycle holds the declares already reported, so that each cycle is
+		// reported once, starting from its declare with the lowest ID.
+		inCycle = make(map[*DeclareNode]struct{})
+	)
+	for _, id := range sortedKeys(l.declareNodes) {
+		start := l.declareNodes[id]
+		if _, ok := inCycle[start]; ok {
+			continue
+		}
+		chain := shortestDeclareCycle(start, refs, inCycle)
+		if chain == nil {
+			continue
+		}
+		for _, ref := range chain {
+			inCycle[ref.to] = struct{}{}
+		}
+		diags.Add(declareCycleDiagnostic(start, chain))
+	}
+	return diags
+}
+
+// shortestDeclareCycle returns the shortest chain of references from start
+// back to start, or nil if start isn't part of a cycle. Declares in skip are
+// ignored.
+func shortestDeclareCycle(start *DeclareNode, refs map[*DeclareNode][]declareReference, skip map[*DeclareNode]struct{}) []declareReference {
+	// Breadth-first search, remembering the reference used to reach each
+	// declare to rebuild the chain.
+	var (
+		queue   = []*DeclareNode{start}
+		reached = make(map[*DeclareNode]declareReference)
+	)
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+
+		for _, ref := range refs[from] {
+			if _, ok := skip[ref.to]; ok {
+				continue
+			}
+			if _, ok := reached[ref.to]; ok {
+				continue
+			}
+			reached[ref.to] = declareReference{to: from, block: ref.block}
+			if ref.to == start {
+				return rebuildDeclareChain(start, reached)
+			}
+			queue = append(queue, ref.to)
+		}
+	}
+	return nil
+}
+
+// rebuildDeclareChain returns the chain of references from start back to
+// start, where reached maps each declare to the declare and block it was
+// reached from.
+func rebuildDeclareChain(start *DeclareNode, reached map[*DeclareNode]declareReference) []declareReference {
+	var chain []declareReference
+	for to := start; ; {
+		from := reached[to]
+		chain = append([]declareReference{{to: to, block: from.block}}, chain...)
+		if from.to == start {
+			return chain
+		}
+		to = from.to
+	}
+}
+
+func declareCycleDiagnostic(start *DeclareNode, chain []declareReference) diag.Diagnostic {
+	var (
+		ids  = []string{start.NodeID()}
+		uses = make([]string, 0, len(chain))
+		from = start
+	)
+	for _, ref := range chain {
+		ids = append(ids, ref.to.NodeID())
+		uses = append(uses, fmt.Sprintf("%s instantiates %s at %s", from.NodeID(), blockDisplayName(ref.block), ast.StartPos(ref.block).Position()))
+		from = ref.to
+	}
+
+	return diag.Diagnostic{
+		Severity: diag.SeverityLevelError,
+		Message:  fmt.Sprintf("cycle: %s (%s)", strings.Join(ids, " -> "), strings.Join(uses, ", ")),
+		StartPos: ast.StartPos(chain[0].block).Position(),
+		EndPos:   ast.EndPos(chain[0].block).Position(),
+	}
+}
+
+// blockDisplayName returns the name and label of b as written in River, such
+// as `prometheus.scrape "default"`.
+func blockDisplayName(b *ast.BlockStmt) string {
+	name := strings.Join(b.Name, ".")
+	if b.Label != "" {
+		return fmt.Sprintf("%s %q", name, b.Label)
+	}
+	return name
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+)
+
+// DefaultErrorsHistoryEntries is the number of past errors retained per
+// component.
+const DefaultErrorsHistoryEntries = 10
+
+// errorsHistory retains the most recent errors of a component, bounded by
+// number of entries.
+type errorsHistory struct {
+	maxEntries int
+
+	mut     sync.Mutex
+	records []component.ErrorRecord
+}
+
+func newErrorsHistory(maxEntries int) *errorsHistory {
+	return &errorsHistory{maxEntries: maxEntries}
+}
+
+// Record adds an error to the history, evicting the oldest entries as needed.
+// stack should be empty for errors which aren't panics.
+func (h *errorsHistory) Record(msg string, stack string) {
+	record := component.ErrorRecord{
+		Time:    time.Now(),
+		Message: msg,
+		Stack:   stack,
+	}
+
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	h.records = append(h.records, record)
+	if len(h.records) > h.maxEntries {
+		h.records = h.records[len(h.records)-h.maxEntries:]
+	}
+}
+
+// List returns the retained errors, ordered from oldest to newest.
+func (h *errorsHistory) List() []component.ErrorRecord {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	res := make([]component.ErrorRecord, len(h.records))
+	copy(res, h.records)
+	return res
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorsHistory(t *testing.T) {
+	h := newErrorsHistory(2)
+	h.Record("a", "")
+	h.Record("b", "")
+	h.Record("c", "stack")
+
+	records := h.List()
+	require.Len(t, records, 2)
+	require.Equal(t, "b", records[0].Message)
+	require.Equal(t, "c", records[1].Message)
+	require.Equal(t, "stack", records[1].Stack)
+}
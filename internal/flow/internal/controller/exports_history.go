@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/encoding/riverjson"
+)
+
+const (
+	// DefaultExportsHistoryEntries is the number of past exports retained per
+	// component.
+	DefaultExportsHistoryEntries = 10
+
+	// DefaultExportsHistoryBytes is the maximum total size of the past exports
+	// retained per component.
+	DefaultExportsHistoryBytes = 1 << 20 // 1MiB
+)
+
+// exportsHistory retains the most recent exports of a component, bounded both
+// by number of entries and by their total estimated encoded size. Exports are
+// only encoded once the history is read.
+type exportsHistory struct {
+	maxEntries int
+	maxBytes   int
+
+	mut     sync.Mutex
+	entries []exportsEntry
+	size    int
+}
+
+// exportsEntry is a retained export. exports is encoded into record the first
+// time the entry is listed.
+type exportsEntry struct {
+	record  component.ExportsRecord
+	exports component.Exports
+	pending bool // Set until exports is encoded.
+	size    int  // Estimated encoded size of exports.
+}
+
+func newExportsHistory(maxEntries, maxBytes int) *exportsHistory {
+	return &exportsHistory{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+	}
+}
+
+// Record adds e to the history, evicting the oldest entries as needed. Exports
+// which are larger than the size limit on their own are recorded as
+// truncated.
+func (h *exportsHistory) Record(e component.Exports) {
+	entry := exportsEntry{record: component.ExportsRecord{Time: time.Now()}}
+
+	if size, ok := exportsSize(e, h.maxBytes); ok {
+		entry.exports, entry.pending, entry.size = e, true, size
+	} else {
+		entry.record.Truncated = true
+	}
+
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	h.entries = append(h.entries, entry)
+	h.size += entry.size
+
+	for len(h.entries) > h.maxEntries || h.size > h.maxBytes {
+		h.size -= h.entries[0].size
+		h.entries = h.entries[1:]
+	}
+}
+
+// List returns the retained exports, ordered from oldest to newest.
+func (h *exportsHistory) List() []component.ExportsRecord {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	res := make([]component.ExportsRecord, len(h.entries))
+	for i := range h.entries {
+		entry := &h.entries[i]
+		if entry.pending {
+			bb, err := riverjson.MarshalBody(entry.exports)
+			if err != nil {
+				entry.record.Truncated = true
+			} else {
+				entry.record.Exports = bb
+			}
+			entry.exports, entry.pending = nil, false
+		}
+		res[i] = entry.record
+	}
+	return res
+}
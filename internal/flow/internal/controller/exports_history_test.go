@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type historyExports struct {
+	Value string `river:"value,attr"`
+}
+
+func TestExportsHistory(t *testing.T) {
+	t.Run("evicts by number of entries", func(t *testing.T) {
+		h := newExportsHistory(2, 1<<20)
+		h.Record(historyExports{Value: "a"})
+		h.Record(historyExports{Value: "b"})
+		h.Record(historyExports{Value: "c"})
+
+		records := h.List()
+		require.Len(t, records, 2)
+		require.Contains(t, string(records[0].Exports), `"b"`)
+		require.Contains(t, string(records[1].Exports), `"c"`)
+	})
+
+	t.Run("evicts by size", func(t *testing.T) {
+		h := newExportsHistory(10, 100)
+		h.Record(historyExports{Value: "a"})
+		h.Record(historyExports{Value: "b"})
+
+		records := h.List()
+		require.Len(t, records, 1)
+		require.Contains(t, string(records[0].Exports), `"b"`)
+	})
+
+	t.Run("encodes exports once listed", func(t *testing.T) {
+		h := newExportsHistory(10, 1<<20)
+		h.Record(historyExports{Value: "a"})
+		require.True(t, h.entries[0].pending)
+
+		records := h.List()
+		require.False(t, h.entries[0].pending)
+		require.Nil(t, h.entries[0].exports)
+		require.JSONEq(t, `[{"name":"value","type":"attr","value":{"type":"string","value":"a"}}]`, string(records[0].Exports))
+		require.Equal(t, records, h.List())
+	})
+
+	t.Run("truncates oversized exports", func(t *testing.T) {
+		h := newExportsHistory(10, 10)
+		h.Record(historyExports{Value: "a"})
+
+		records := h.List()
+		require.Len(t, records, 1)
+		require.True(t, records[0].Truncated)
+		require.Empty(t, records[0].Exports)
+	})
+}
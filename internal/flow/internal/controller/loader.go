@@ -48,10 +48,23 @@ type Loader struct {
 	importConfigNodes map[string]*ImportConfigNode
 	serviceNodes      []*ServiceNode
 	cache             *valueCache
-	blocks            []*ast.BlockStmt // Most recently loaded blocks, used for writing
+	blocks            []*ast.BlockStmt  // Most recently loaded blocks, used for writing
+	appliedBlocks     map[string]string // Node ID -> block of the most recent call to Apply
+	appliedArgs       map[string]any    // Module arguments of the most recent call to Apply
 	cm                *controllerMetrics
 	cc                *controllerCollector
 	moduleExportIndex int
+
+	// evalMut is held for reading while EvaluateDependants evaluates a node,
+	// so that PauseEvaluation can wait for in-flight evaluations. It must be
+	// acquired before mut.
+	evalMut sync.RWMutex
+
+	// failedNodes holds the IDs of the nodes whose most recent evaluation
+	// failed. Nodes are evaluated concurrently by EvaluateDependants, so
+	// failedNodes has its own mutex.
+	failedMut   sync.Mutex
+	failedNodes map[string]struct{}
 }
 
 // LoaderOptions holds options for creating a Loader.
@@ -100,7 +113,9 @@ func NewLoader(opts LoaderOptions) *Loader {
 		originalGraph: &dag.Graph{},
 		cache:         newValueCache(),
 		cm:            newControllerMetrics(globals.ControllerID),
+		failedNodes:   make(map[string]struct{}),
 	}
+	l.cache.sys = buildSysValue(globals)
 	l.cc = newControllerCollector(l, globals.ControllerID)
 
 	if globals.Registerer != nil {
@@ -134,7 +149,10 @@ type ApplyOptions struct {
 // matches the component ID specified by any of the provided River blocks.
 // Reused components will be updated to point at the new River block.
 //
-// Apply will perform an evaluation of all loaded components before returning.
+// Apply will perform an evaluation of the loaded components before returning.
+// Reused components are only evaluated if their block, or one of their
+// dependencies, changed since the previous call to Apply, or if their previous
+// evaluation failed.
 // The provided parentContext can be used to provide global variables and
 // functions to components. A child context will be constructed from the parent
 // to expose values of other components.
@@ -162,6 +180,8 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 		components   = make([]ComponentNode, 0)
 		componentIDs = make([]ComponentID, 0)
 		services     = make([]*ServiceNode, 0, len(l.services))
+		diff         = newReloadDiff(l, &newGraph, options)
+		skipped      int
 	)
 
 	tracer := l.tracer.Tracer("")
@@ -173,13 +193,22 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 	defer func() {
 		span.SetStatus(codes.Ok, "")
 
-		level.Info(logger).Log("msg", "finished complete graph evaluation", "duration", time.Since(start))
+		level.Info(logger).Log("msg", "finished complete graph evaluation", "duration", time.Since(start), "unchanged_components", skipped)
 	}()
 
 	l.cache.ClearModuleExports()
 
 	// Evaluate all the components.
 	_ = dag.WalkTopological(&newGraph, newGraph.Leaves(), func(n dag.Node) error {
+		if bn, ok := n.(BlockNode); ok && !diff.needsEvaluation(bn, l.evaluationFailed(bn.NodeID())) {
+			// Unchanged components keep running with their current arguments.
+			c := n.(ComponentNode)
+			components = append(components, c)
+			componentIDs = append(componentIDs, c.ID())
+			skipped++
+			return nil
+		}
+
 		_, span := tracer.Start(spanCtx, "EvaluateNode", trace.WithSpanKind(trace.SpanKindInternal))
 		span.SetAttributes(attribute.String("node_id", n.NodeID()))
 		defer span.End()
@@ -251,11 +280,15 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 		return nil
 	})
 
+	l.notifyComponentsRemoved(components)
 	l.componentNodes = components
 	l.serviceNodes = services
 	l.graph = &newGraph
 	l.cache.SyncIDs(componentIDs)
 	l.blocks = options.ComponentBlocks
+	l.appliedBlocks = diff.blocks
+	l.appliedArgs = options.Args
+	l.syncFailedNodes(diff.blocks)
 	if l.globals.OnExportsChange != nil && l.cache.ExportChangeIndex() != l.moduleExportIndex {
 		l.moduleExportIndex = l.cache.ExportChangeIndex()
 		l.globals.OnExportsChange(l.cache.CreateModuleExports())
@@ -263,6 +296,37 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 	return diags
 }
 
+// notifyComponentsRemoved calls the services implementing
+// [service.ComponentObserver] with the global IDs of the components which
+// aren't in components anymore.
+func (l *Loader) notifyComponentsRemoved(components []ComponentNode) {
+	kept := make(map[string]struct{}, len(components))
+	for _, c := range components {
+		kept[c.NodeID()] = struct{}{}
+	}
+
+	var removed []string
+	for _, c := range l.componentNodes {
+		if _, ok := kept[c.NodeID()]; ok {
+			continue
+		}
+		globalID := c.NodeID()
+		if l.globals.ControllerID != "" {
+			globalID = path.Join(l.globals.ControllerID, globalID)
+		}
+		removed = append(removed, globalID)
+	}
+	if len(removed) == 0 {
+		return
+	}
+
+	for _, svc := range l.services {
+		if o, ok := svc.(service.ComponentObserver); ok {
+			o.ComponentsRemoved(removed)
+		}
+	}
+}
+
 // Cleanup unregisters any existing metrics and optionally stops the worker pool.
 func (l *Loader) Cleanup(stopWorkerPool bool) {
 	if stopWorkerPool {
@@ -302,6 +366,13 @@ func (l *Loader) loadNewGraph(args map[string]any, componentBlocks []*ast.BlockS
 	wireDiags := l.wireGraphEdges(&g)
 	diags = append(diags, wireDiags...)
 
+	// Cycles between declare blocks are reported with the blocks creating them,
+	// which the generic cycle errors of the graph can't point to.
+	if cycleDiags := l.findDeclareCycles(); len(cycleDiags) > 0 {
+		diags = append(diags, cycleDiags...)
+		return g, diags
+	}
+
 	// Validate graph to detect cycles
 	err := dag.Validate(&g)
 	if err != nil {
@@ -551,6 +622,23 @@ func (l *Loader) populateComponentNodes(g *dag.Graph, componentBlocks []*ast.Blo
 			diags = append(diags, diag)
 			continue
 		}
+
+		migrated, applied, err := l.componentNodeManager.migrateBlock(block)
+		if err != nil {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				Message:  fmt.Sprintf("failed to migrate the configuration of component %q: %s", id, err),
+				StartPos: ast.StartPos(block).Position(),
+				EndPos:   ast.EndPos(block).Position(),
+			})
+			continue
+		}
+		for _, m := range applied {
+			level.Warn(l.log).Log("msg", "migrated outdated component configuration, run the fmt command with --migrate to update the configuration file",
+				"component", id, "version", m.Version, "migration", m.Description)
+		}
+		block = migrated
+
 		// Check the graph from the previous call to Load to see if we can copy an
 		// existing instance of ComponentNode.
 		if exist := l.graph.GetByID(id); exist != nil {
@@ -578,7 +666,12 @@ func (l *Loader) populateComponentNodes(g *dag.Graph, componentBlocks []*ast.Blo
 
 // Wire up all the related nodes
 func (l *Loader) wireGraphEdges(g *dag.Graph) diag.Diagnostics {
-	var diags diag.Diagnostics
+	var (
+		diags          diag.Diagnostics
+		latencyBudgets = make(map[string]*LatencyBudgetConfigNode)
+		executionPools = make(map[string]*ExecutionPoolConfigNode)
+		exportLimits   = make(map[string]*ExportLimitConfigNode)
+	)
 
 	for _, n := range g.Nodes() {
 		switch n := n.(type) {
@@ -610,7 +703,103 @@ func (l *Loader) wireGraphEdges(g *dag.Graph) diag.Diagnostics {
 			// skip here because for now Declare nodes can't reference component nodes.
 			continue
 		case *CustomComponentNode:
-			l.wireCustomComponentNode(g, n)
+			l.wireCustomComponentNode(g, n, n.importNamespace, n.customComponentName)
+
+			// The arguments of custom components of local declares are checked
+			// when loading. The arguments of imported custom components are
+			// only checked once the import is evaluated.
+			if _, imported := l.importConfigNodes[n.importNamespace]; !imported {
+				if declare, ok := l.declareNodes[n.customComponentName]; ok {
+					diags = append(diags, validateCustomComponentAttributes(n.Block(), declare.Block().Body)...)
+				}
+			}
+		case *ForeachNode:
+			// A foreach block depends on the definition of its template.
+			if template := n.Template(); template != nil {
+				importNamespace, customComponentName := ExtractImportAndDeclare(template.GetBlockName())
+				l.wireCustomComponentNode(g, n, importNamespace, customComponentName)
+			}
+		case *LatencyBudgetConfigNode:
+			// Components depend on their latency budget so that the budget is known
+			// before the component is evaluated.
+			var msg string
+			target, ok := g.GetByID(n.Target()).(*BuiltinComponentNode)
+			switch {
+			case n.Target() == "":
+				msg = fmt.Sprintf("%s must set component to a string literal", n.NodeID())
+			case !ok:
+				msg = fmt.Sprintf("%s references unknown component %q", n.NodeID(), n.Target())
+			case latencyBudgets[n.Target()] != nil:
+				msg = fmt.Sprintf("%s redefines the latency budget of component %q", n.NodeID(), n.Target())
+			}
+			if msg != "" {
+				diags.Add(diag.Diagnostic{
+					Severity: diag.SeverityLevelError,
+					Message:  msg,
+					StartPos: ast.StartPos(n.Block()).Position(),
+					EndPos:   ast.EndPos(n.Block()).Position(),
+				})
+				continue
+			}
+			latencyBudgets[n.Target()] = n
+			g.AddEdge(dag.Edge{From: target, To: n})
+		case *ExportLimitConfigNode:
+			// Components depend on their export limit so that the limit is known
+			// before the component is built and exports values.
+			var msg string
+			target, ok := g.GetByID(n.Target()).(*BuiltinComponentNode)
+			switch {
+			case n.Target() == "":
+				msg = fmt.Sprintf("%s must set component to a string literal", n.NodeID())
+			case !ok:
+				msg = fmt.Sprintf("%s references unknown component %q", n.NodeID(), n.Target())
+			case exportLimits[n.Target()] != nil:
+				msg = fmt.Sprintf("%s redefines the export limit of component %q", n.NodeID(), n.Target())
+			}
+			if msg != "" {
+				diags.Add(diag.Diagnostic{
+					Severity: diag.SeverityLevelError,
+					Message:  msg,
+					StartPos: ast.StartPos(n.Block()).Position(),
+					EndPos:   ast.EndPos(n.Block()).Position(),
+				})
+				continue
+			}
+			exportLimits[n.Target()] = n
+			g.AddEdge(dag.Edge{From: target, To: n})
+		case *ExecutionPoolConfigNode:
+			// Components depend on their execution pool so that the pool is
+			// configured before the component is evaluated.
+			if n.Targets() == nil {
+				diags.Add(diag.Diagnostic{
+					Severity: diag.SeverityLevelError,
+					Message:  fmt.Sprintf("%s must set components to an array of string literals", n.NodeID()),
+					StartPos: ast.StartPos(n.Block()).Position(),
+					EndPos:   ast.EndPos(n.Block()).Position(),
+				})
+				continue
+			}
+			for _, id := range n.Targets() {
+				var msg string
+				target, ok := g.GetByID(id).(*BuiltinComponentNode)
+				switch {
+				case !ok:
+					msg = fmt.Sprintf("%s references unknown component %q", n.NodeID(), id)
+				case executionPools[id] != nil:
+					msg = fmt.Sprintf("component %q belongs to both %s and %s", id, executionPools[id].NodeID(), n.NodeID())
+				}
+				if msg != "" {
+					diags.Add(diag.Diagnostic{
+						Severity: diag.SeverityLevelError,
+						Message:  msg,
+						StartPos: ast.StartPos(n.Block()).Position(),
+						EndPos:   ast.EndPos(n.Block()).Position(),
+					})
+					continue
+				}
+				executionPools[id] = n
+				g.AddEdge(dag.Edge{From: target, To: n})
+			}
 		}
 
 		// Finally, wire component references.
@@ -621,25 +810,50 @@ func (l *Loader) wireGraphEdges(g *dag.Graph) diag.Diagnostics {
 		diags = append(diags, nodeDiags...)
 	}
 
+	// Attach latency budgets, execution pools and export limits to the
+	// components they apply to, detaching the ones which have been removed.
+	for _, n := range g.Nodes() {
+		if cn, ok := n.(*BuiltinComponentNode); ok {
+			cn.SetLatencyBudget(latencyBudgets[cn.NodeID()])
+			cn.SetExecutionPool(executionPools[cn.NodeID()])
+			cn.SetExportLimit(exportLimits[cn.NodeID()])
+		}
+	}
+
 	return diags
 }
 
-// wireCustomComponentNode wires a custom component to the import/declare nodes that it depends on.
-func (l *Loader) wireCustomComponentNode(g *dag.Graph, cc *CustomComponentNode) {
+// wireCustomComponentNode wires a node instantiating a custom component to the import/declare nodes that it depends on.
+func (l *Loader) wireCustomComponentNode(g *dag.Graph, n dag.Node, importNamespace, customComponentName string) {
 	// It's important to check first if the importNamespace matches an import node because there might be a
 	// local node that has the same label as an imported declare.
-	if importNode, ok := l.importConfigNodes[cc.importNamespace]; ok {
+	if importNode, ok := l.importConfigNodes[importNamespace]; ok {
 		// add an edge between the custom component and the corresponding import node.
-		g.AddEdge(dag.Edge{From: cc, To: importNode})
-	} else if declare, ok := l.declareNodes[cc.customComponentName]; ok {
+		g.AddEdge(dag.Edge{From: n, To: importNode})
+	} else if declare, ok := l.declareNodes[customComponentName]; ok {
 		refs := l.findCustomComponentReferences(declare.Block())
 		for ref := range refs {
 			// add edges between the custom component and declare/import nodes.
-			g.AddEdge(dag.Edge{From: cc, To: ref})
+			g.AddEdge(dag.Edge{From: n, To: ref})
 		}
 	}
 }
 
+// CustomComponentDefinition returns the import or declare node which defines
+// the custom component cc, resolved the same way cc is wired in the graph. It
+// returns nil if cc is defined by a declare block of a parent module.
+func (l *Loader) CustomComponentDefinition(cc *CustomComponentNode) BlockNode {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	if importNode, ok := l.importConfigNodes[cc.importNamespace]; ok {
+		return importNode
+	} else if declare, ok := l.declareNodes[cc.customComponentName]; ok {
+		return declare
+	}
+	return nil
+}
+
 // Variables returns the Variables the Loader exposes for other Flow components
 // to reference.
 func (l *Loader) Variables() map[string]interface{} {
@@ -682,6 +896,23 @@ func (l *Loader) OriginalGraph() *dag.Graph {
 	return l.originalGraph.Clone()
 }
 
+// PauseEvaluation waits for the nodes being evaluated to finish, and prevents
+// the Loader from evaluating nodes or applying a new config until resume is
+// called. Components may still update their exports while evaluation is
+// paused, but their dependants aren't evaluated until it resumes.
+//
+// The returned graph is the original graph of the Loader, which doesn't change
+// until resume is called. Other methods of the Loader must not be called while
+// evaluation is paused.
+func (l *Loader) PauseEvaluation() (graph *dag.Graph, resume func()) {
+	l.evalMut.Lock()
+	l.mut.RLock()
+	return l.originalGraph.Clone(), func() {
+		l.mut.RUnlock()
+		l.evalMut.Unlock()
+	}
+}
+
 // EvaluateDependants sends nodes which depend directly on nodes in updatedNodes for evaluation to the
 // workerPool. It should be called whenever nodes update their exports.
 // It is beneficial to call EvaluateDependants with a batch of nodes, as it will enqueue the entire batch before
@@ -701,11 +932,16 @@ func (l *Loader) EvaluateDependants(ctx context.Context, updatedNodes []*QueuedN
 	l.cm.controllerEvaluation.Set(1)
 	defer l.cm.controllerEvaluation.Set(0)
 
+	// l.mut is only held while collecting the dependants. Holding it while
+	// waiting for the worker pool to accept them would block Apply, and so
+	// PauseEvaluation, behind evaluations waiting for PauseEvaluation to
+	// resume.
 	l.mut.RLock()
-	defer l.mut.RUnlock()
-
 	dependenciesToParentsMap := make(map[dag.Node]*QueuedNode)
 	for _, parent := range updatedNodes {
+		// affected returns whether a node depending on parent must be evaluated.
+		affected := func(dag.Node) bool { return true }
+
 		switch parentNode := parent.Node.(type) {
 		case ComponentNode:
 			// Make sure we're in-sync with the current exports of parent.
@@ -713,13 +949,24 @@ func (l *Loader) EvaluateDependants(ctx context.Context, updatedNodes []*QueuedN
 		case *ImportConfigNode:
 			// Update the scope with the imported content.
 			l.componentNodeManager.customComponentReg.updateImportContent(parentNode)
+
+			// Only custom components using a declare which changed need to be
+			// reevaluated with the new content.
+			changes := parentNode.takeDeclareChanges()
+			affected = func(n dag.Node) bool {
+				cc, ok := n.(*CustomComponentNode)
+				return !ok || changes.affects(parentNode.label, cc)
+			}
 		}
 		// We collect all nodes directly incoming to parent.
 		_ = dag.WalkIncomingNodes(l.graph, parent.Node, func(n dag.Node) error {
-			dependenciesToParentsMap[n] = parent
+			if affected(n) {
+				dependenciesToParentsMap[n] = parent
+			}
 			return nil
 		})
 	}
+	l.mut.RUnlock()
 
 	// Submit all dependencies for asynchronous evaluation.
 	// During evaluation, if a node's exports change, Flow will add it to updated nodes queue (controller.Queue) and
@@ -786,6 +1033,9 @@ func (l *Loader) concurrentEvalFn(n dag.Node, spanCtx context.Context, tracer tr
 	var err error
 	switch n := n.(type) {
 	case BlockNode:
+		l.evalMut.RLock()
+		defer l.evalMut.RUnlock()
+
 		ectx := l.cache.BuildContext()
 		evalErr := n.Evaluate(ectx)
 
@@ -839,18 +1089,28 @@ func (l *Loader) postEvaluate(logger log.Logger, bn BlockNode, err error) error
 		l.cache.CacheArguments(c.ID(), c.Arguments())
 		l.cache.CacheExports(c.ID(), c.Exports())
 	case *ArgumentConfigNode:
-		if _, found := l.cache.moduleArguments[c.Label()]; !found {
+		if value, found := l.cache.moduleArguments[c.Label()]; !found {
 			if c.Optional() {
+				if def := c.Default(); def != nil {
+					if typeErr := checkArgumentType(c.Type(), def); typeErr != nil {
+						err = fmt.Errorf("invalid default value for argument %q to module: %w", c.Label(), typeErr)
+					}
+				}
 				l.cache.CacheModuleArgument(c.Label(), c.Default())
 			} else {
 				// NOTE: this masks the previous evaluation error, but we treat a missing module arguments as
 				// a more important error to address.
 				err = fmt.Errorf("missing required argument %q to module", c.Label())
 			}
+		} else if err == nil {
+			if typeErr := checkArgumentType(c.Type(), value); typeErr != nil {
+				err = fmt.Errorf("invalid type for argument %q to module: %w", c.Label(), typeErr)
+			}
 		}
 	case *ImportConfigNode:
 		l.componentNodeManager.customComponentReg.updateImportContent(c)
 	}
+	l.setEvaluationFailed(bn.NodeID(), err != nil)
 
 	if err != nil {
 		level.Error(logger).Log("msg", "failed to evaluate config", "node", bn.NodeID(), "err", err)
@@ -859,6 +1119,37 @@ func (l *Loader) postEvaluate(logger log.Logger, bn BlockNode, err error) error
 	return nil
 }
 
+// evaluationFailed returns true if the most recent evaluation of the node
+// with the given ID failed.
+func (l *Loader) evaluationFailed(id string) bool {
+	l.failedMut.Lock()
+	defer l.failedMut.Unlock()
+	_, failed := l.failedNodes[id]
+	return failed
+}
+
+func (l *Loader) setEvaluationFailed(id string, failed bool) {
+	l.failedMut.Lock()
+	defer l.failedMut.Unlock()
+	if failed {
+		l.failedNodes[id] = struct{}{}
+	} else {
+		delete(l.failedNodes, id)
+	}
+}
+
+// syncFailedNodes forgets the failures of nodes which were removed from the
+// graph.
+func (l *Loader) syncFailedNodes(ids map[string]string) {
+	l.failedMut.Lock()
+	defer l.failedMut.Unlock()
+	for id := range l.failedNodes {
+		if _, ok := ids[id]; !ok {
+			delete(l.failedNodes, id)
+		}
+	}
+}
+
 func multierrToDiags(errors error) diag.Diagnostics {
 	var diags diag.Diagnostics
 	for _, err := range errors.(*multierror.Error).Errors {
@@ -876,15 +1167,16 @@ func (l *Loader) isRootController() bool {
 	return l.globals.ControllerID == ""
 }
 
-// findCustomComponentReferences returns references to import/declare nodes in a declare block.
-func (l *Loader) findCustomComponentReferences(declare *ast.BlockStmt) map[BlockNode]struct{} {
-	uniqueReferences := make(map[BlockNode]struct{})
+// findCustomComponentReferences returns references to import/declare nodes in a declare block,
+// along with the first block of the declare referencing each of them.
+func (l *Loader) findCustomComponentReferences(declare *ast.BlockStmt) map[BlockNode]*ast.BlockStmt {
+	uniqueReferences := make(map[BlockNode]*ast.BlockStmt)
 	l.collectCustomComponentReferences(declare.Body, uniqueReferences)
 	return uniqueReferences
 }
 
 // collectCustomComponentDependencies recursively collects references to import/declare nodes through an AST body.
-func (l *Loader) collectCustomComponentReferences(stmts ast.Body, uniqueReferences map[BlockNode]struct{}) {
+func (l *Loader) collectCustomComponentReferences(stmts ast.Body, uniqueReferences map[BlockNode]*ast.BlockStmt) {
 	for _, stmt := range stmts {
 		blockStmt, ok := stmt.(*ast.BlockStmt)
 		if !ok {
@@ -899,12 +1191,16 @@ func (l *Loader) collectCustomComponentReferences(stmts ast.Body, uniqueReferenc
 		)
 
 		switch {
-		case componentName == declareType:
+		case componentName == declareType, componentName == foreachBlockID:
 			l.collectCustomComponentReferences(blockStmt.Body, uniqueReferences)
 		case foundDeclare:
-			uniqueReferences[declareNode] = struct{}{}
+			if _, ok := uniqueReferences[declareNode]; !ok {
+				uniqueReferences[declareNode] = blockStmt
+			}
 		case foundImport:
-			uniqueReferences[importNode] = struct{}{}
+			if _, ok := uniqueReferences[importNode]; !ok {
+				uniqueReferences[importNode] = blockStmt
+			}
 		}
 	}
 }
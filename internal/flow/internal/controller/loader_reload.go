@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"reflect"
+
+	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/river/ast"
+)
+
+// reloadDiff finds the nodes of a graph being applied which changed since the
+// previous call to Apply. Components which didn't change don't need to be
+// evaluated again, which keeps reloading large configurations with few
+// changes cheap.
+//
+// A node changed if its block changed, if one of its dependencies changed, if
+// it's the argument block of a module argument whose value changed, or if it's
+// a custom component whose declare block changed. Nodes must be passed to
+// reloadDiff in topological order, so that the dependencies of a node are
+// checked before the node.
+type reloadDiff struct {
+	graph    *dag.Graph
+	declares map[string]*DeclareNode
+	imports  map[string]*ImportConfigNode
+
+	prevBlocks map[string]string // Node ID -> block of the previous call to Apply.
+	prevArgs   map[string]any    // Module arguments of the previous call to Apply.
+	args       map[string]any    // Module arguments being applied.
+
+	// inherited is true if custom components can use the declares and
+	// imports of a parent module, whose changes can't be detected.
+	inherited bool
+
+	blocks  map[string]string   // Node ID -> block being applied.
+	changed map[string]struct{} // Node IDs of changed nodes.
+}
+
+func newReloadDiff(l *Loader, g *dag.Graph, options ApplyOptions) *reloadDiff {
+	return &reloadDiff{
+		graph:      g,
+		declares:   l.declareNodes,
+		imports:    l.importConfigNodes,
+		prevBlocks: l.appliedBlocks,
+		prevArgs:   l.appliedArgs,
+		args:       options.Args,
+		inherited:  options.CustomComponentRegistry != nil,
+		blocks:     make(map[string]string),
+		changed:    make(map[string]struct{}),
+	}
+}
+
+// needsEvaluation reports whether n must be evaluated. Component nodes are
+// only evaluated if they changed or if their previous evaluation failed.
+// Other nodes are cheap to evaluate and are always evaluated.
+func (d *reloadDiff) needsEvaluation(n BlockNode, failed bool) bool {
+	changed := d.nodeChanged(n)
+	_, isComponent := n.(ComponentNode)
+
+	if changed || (isComponent && failed) {
+		// A component evaluated again may export different values, so its
+		// dependants must be evaluated too.
+		d.changed[n.NodeID()] = struct{}{}
+		return true
+	}
+	return !isComponent
+}
+
+// declareChanged reports whether the local declare defining a custom
+// component changed. Imported declares are tracked by their import node.
+func (d *reloadDiff) declareChanged(importNamespace, customComponentName string) bool {
+	if _, imported := d.imports[importNamespace]; imported {
+		return false
+	}
+	declare, ok := d.declares[customComponentName]
+	return !ok || d.blockChanged(declare)
+}
+
+func (d *reloadDiff) nodeChanged(n BlockNode) bool {
+	if d.blockChanged(n) || callsFunctions(n.Block()) {
+		return true
+	}
+
+	switch n := n.(type) {
+	case *ArgumentConfigNode:
+		prevValue, prevSet := d.prevArgs[n.Label()]
+		value, set := d.args[n.Label()]
+		if prevSet != set || !reflect.DeepEqual(prevValue, value) {
+			return true
+		}
+	case *CustomComponentNode:
+		// Custom components don't depend on their own declare node, and
+		// the declares of a parent module, even when used by nested custom
+		// components, don't belong to the graph.
+		if d.inherited {
+			return true
+		}
+		if d.declareChanged(n.importNamespace, n.customComponentName) {
+			return true
+		}
+	case *ForeachNode:
+		// Same as custom components, for the template of the foreach block.
+		if d.inherited {
+			return true
+		}
+		if template := n.Template(); template != nil {
+			importNamespace, customComponentName := ExtractImportAndDeclare(template.GetBlockName())
+			if d.declareChanged(importNamespace, customComponentName) {
+				return true
+			}
+		}
+	}
+	for _, dep := range d.graph.Dependencies(n) {
+		if _, ok := d.changed[dep.NodeID()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// blockChanged returns true if the block of n changed since the previous call
+// to Apply.
+func (d *reloadDiff) blockChanged(n BlockNode) bool {
+	id := n.NodeID()
+	block, ok := d.blocks[id]
+	if !ok {
+		// Default config nodes, such as the logging node when no logging block
+		// is defined, don't have a block.
+		if b := n.Block(); b != nil {
+			block = printNode(b)
+		}
+		d.blocks[id] = block
+	}
+
+	prev, ok := d.prevBlocks[id]
+	return !ok || prev != block
+}
+
+// callsFunctions returns true if block calls functions. Functions such as env
+// may return different values when called again, so blocks calling them are
+// always considered changed.
+func callsFunctions(block *ast.BlockStmt) bool {
+	if block == nil {
+		return false
+	}
+	var v callVisitor
+	ast.Walk(&v, block.Body)
+	return v.found
+}
+
+type callVisitor struct{ found bool }
+
+func (v *callVisitor) Visit(node ast.Node) ast.Visitor {
+	if v.found {
+		return nil
+	}
+	if _, ok := node.(*ast.CallExpr); ok {
+		v.found = true
+		return nil
+	}
+	return v
+}
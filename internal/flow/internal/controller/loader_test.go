@@ -1,16 +1,21 @@
 package controller_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/internal/controller"
 	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/agent/internal/flow/internal/worker"
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/service"
 	"github.com/grafana/river/ast"
@@ -18,9 +23,12 @@ import (
 	"github.com/grafana/river/parser"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
 
-	_ "github.com/grafana/agent/internal/flow/internal/testcomponents" // Include test components
+	"github.com/grafana/agent/internal/flow/internal/testcomponents"
 )
 
 func TestLoader(t *testing.T) {
@@ -372,6 +380,704 @@ func TestLoader_Services(t *testing.T) {
 	})
 }
 
+func TestLoader_ComponentsRemoved(t *testing.T) {
+	observer := &observerService{fakeService: fakeService{
+		DefinitionFunc: func() service.Definition {
+			return service.Definition{Name: "observer", Stability: featuregate.StabilityStable}
+		},
+	}}
+
+	l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+	loader := controller.NewLoader(controller.LoaderOptions{
+		ComponentGlobals: controller.ComponentGlobals{
+			Logger:            l,
+			TraceProvider:     noop.NewTracerProvider(),
+			DataPath:          t.TempDir(),
+			MinStability:      featuregate.StabilityBeta,
+			OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+			Registerer:        prometheus.NewRegistry(),
+			NewModuleController: func(id string) controller.ModuleController {
+				return fakeModuleController{}
+			},
+			ControllerID: "module.file.parent",
+		},
+		Services: []service.Service{observer},
+	})
+
+	diags := applyFromContent(t, loader, []byte(`
+		testcomponents.passthrough "a" {
+			input = "a"
+		}
+		testcomponents.passthrough "b" {
+			input = "b"
+		}
+	`), nil, nil)
+	require.NoError(t, diags.ErrorOrNil())
+	require.Empty(t, observer.removed)
+
+	// Renaming a component removes the component with the previous name.
+	diags = applyFromContent(t, loader, []byte(`
+		testcomponents.passthrough "a" {
+			input = "a"
+		}
+		testcomponents.passthrough "c" {
+			input = "b"
+		}
+	`), nil, nil)
+	require.NoError(t, diags.ErrorOrNil())
+	require.Equal(t, [][]string{{"module.file.parent/testcomponents.passthrough.b"}}, observer.removed)
+}
+
+func TestLoader_EvaluateDependantsFullQueue(t *testing.T) {
+	pool := worker.NewFixedWorkerPool(1, 1)
+	defer pool.Stop()
+
+	logs := &syncBuffer{}
+	l, _ := logging.New(logs, logging.DefaultOptions)
+	loader := controller.NewLoader(controller.LoaderOptions{
+		ComponentGlobals: controller.ComponentGlobals{
+			Logger:            l,
+			TraceProvider:     noop.NewTracerProvider(),
+			DataPath:          t.TempDir(),
+			MinStability:      featuregate.StabilityBeta,
+			OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+			Registerer:        prometheus.NewRegistry(),
+			NewModuleController: func(id string) controller.ModuleController {
+				return fakeModuleController{}
+			},
+		},
+		WorkerPool: pool,
+	})
+
+	config := `
+		testcomponents.passthrough "a" {
+			input = "a"
+		}
+		testcomponents.passthrough "b" {
+			input = testcomponents.passthrough.a.output
+		}
+		testcomponents.passthrough "c" {
+			input = testcomponents.passthrough.a.output
+		}
+	`
+	require.NoError(t, applyFromContent(t, loader, []byte(config), nil, nil).ErrorOrNil())
+
+	// Fill the queue of the worker pool with a task which waits for the end of
+	// the test.
+	release := make(chan struct{})
+	defer close(release)
+	require.NoError(t, pool.SubmitWithKey("blocker", func() { <-release }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := loader.Graph().GetByID("testcomponents.passthrough.a").(controller.BlockNode)
+	go loader.EvaluateDependants(ctx, []*controller.QueuedNode{{Node: a}})
+	require.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), "failed to submit node for evaluation")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Applying a config and pausing evaluation don't wait for the dependants
+	// to be accepted by the worker pool.
+	applied := make(chan struct{})
+	go func() {
+		defer close(applied)
+		_ = applyFromContent(t, loader, []byte(strings.ReplaceAll(config, `input = "a"`, `input = "b"`)), nil, nil)
+	}()
+	paused := make(chan struct{})
+	go func() {
+		defer close(paused)
+		_, resume := loader.PauseEvaluation()
+		resume()
+	}()
+
+	for _, done := range []chan struct{}{applied, paused} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "loader blocked by a full worker queue")
+		}
+	}
+}
+
+func TestLoader_Migrations(t *testing.T) {
+	passthrough, ok := component.Get("testcomponents.passthrough")
+	require.True(t, ok)
+	// Pretend the input argument of testcomponents.passthrough used to be
+	// called value.
+	passthrough.Migrations = []component.Migration{component.RenameAttribute(2, "value", "input")}
+
+	l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+	loader := controller.NewLoader(controller.LoaderOptions{
+		ComponentGlobals: controller.ComponentGlobals{
+			Logger:            l,
+			TraceProvider:     noop.NewTracerProvider(),
+			DataPath:          t.TempDir(),
+			MinStability:      featuregate.StabilityBeta,
+			OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+			Registerer:        prometheus.NewRegistry(),
+			NewModuleController: func(id string) controller.ModuleController {
+				return nil
+			},
+		},
+		ComponentRegistry: controller.NewRegistryMap(featuregate.StabilityBeta, map[string]component.Registration{
+			"testcomponents.passthrough": passthrough,
+		}),
+	})
+
+	t.Run("Outdated block is migrated", func(t *testing.T) {
+		diags := applyFromContent(t, loader, []byte(`
+			testcomponents.passthrough "static" {
+				value = "hello, world!"
+			}
+		`), nil, nil)
+		require.NoError(t, diags.ErrorOrNil())
+
+		n := loader.Graph().GetByID("testcomponents.passthrough.static").(*controller.BuiltinComponentNode)
+		require.Equal(t, "hello, world!", n.Arguments().(testcomponents.PassthroughConfig).Input)
+	})
+
+	t.Run("Migration failures are reported", func(t *testing.T) {
+		diags := applyFromContent(t, loader, []byte(`
+			testcomponents.passthrough "static" {
+				value = "hello"
+				input = "world"
+			}
+		`), nil, nil)
+		require.ErrorContains(t, diags.ErrorOrNil(), `failed to migrate the configuration of component "testcomponents.passthrough.static"`)
+	})
+}
+
+// evaluationRecorder counts the evaluations of nodes by a loader, from the
+// EvaluateNode spans of the loader.
+type evaluationRecorder struct {
+	spans *tracetest.SpanRecorder
+	seen  int
+}
+
+func newEvaluationRecorder() *evaluationRecorder {
+	return &evaluationRecorder{spans: tracetest.NewSpanRecorder()}
+}
+
+func (r *evaluationRecorder) TracerProvider() trace.TracerProvider {
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(r.spans))
+}
+
+// Take returns the number of evaluations of each node since the previous
+// call to Take.
+func (r *evaluationRecorder) Take() map[string]int {
+	spans := r.spans.Ended()
+	counts := map[string]int{}
+	for _, span := range spans[r.seen:] {
+		if span.Name() != "EvaluateNode" {
+			continue
+		}
+		for _, attr := range span.Attributes() {
+			if attr.Key == "node_id" {
+				counts[attr.Value.AsString()]++
+			}
+		}
+	}
+	r.seen = len(spans)
+	return counts
+}
+
+type counterArgs struct {
+	Input string `river:"input,attr"`
+}
+
+type counterExports struct {
+	Output string `river:"output,attr"`
+}
+
+type counterComponent struct{ opts component.Options }
+
+func (c *counterComponent) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (c *counterComponent) Update(args component.Arguments) error {
+	c.opts.OnStateChange(counterExports{Output: args.(counterArgs).Input})
+	return nil
+}
+
+func newCounterLoader(t *testing.T, evaluations *evaluationRecorder, moduleController controller.ModuleController) *controller.Loader {
+	l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+	return controller.NewLoader(controller.LoaderOptions{
+		ComponentGlobals: controller.ComponentGlobals{
+			Logger:            l,
+			TraceProvider:     evaluations.TracerProvider(),
+			DataPath:          t.TempDir(),
+			MinStability:      featuregate.StabilityBeta,
+			OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+			Registerer:        prometheus.NewRegistry(),
+			NewModuleController: func(id string) controller.ModuleController {
+				return moduleController
+			},
+		},
+		ComponentRegistry: controller.NewRegistryMap(featuregate.StabilityBeta, map[string]component.Registration{
+			"testcomponents.counter": {
+				Name:      "testcomponents.counter",
+				Stability: featuregate.StabilityBeta,
+				Args:      counterArgs{},
+				Exports:   counterExports{},
+				Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+					c := &counterComponent{opts: opts}
+					return c, c.Update(args)
+				},
+			},
+		}),
+	})
+}
+
+func TestLoader_ReloadUnchangedComponents(t *testing.T) {
+	t.Setenv("COUNTER_INPUT", "env")
+
+	evaluations := newEvaluationRecorder()
+	loader := newCounterLoader(t, evaluations, nil)
+
+	apply := func(config string) map[string]int {
+		t.Helper()
+		require.NoError(t, applyFromContent(t, loader, []byte(config), nil, nil).ErrorOrNil())
+		return evaluations.Take()
+	}
+
+	config := `
+		testcomponents.counter "a" {
+			input = "a"
+		}
+
+		testcomponents.counter "b" {
+			input = testcomponents.counter.a.output + "b"
+		}
+
+		testcomponents.counter "c" {
+			input = "c"
+		}
+
+		testcomponents.counter "env" {
+			input = env("COUNTER_INPUT")
+		}
+	`
+	var (
+		a   = "testcomponents.counter.a"
+		b   = "testcomponents.counter.b"
+		c   = "testcomponents.counter.c"
+		env = "testcomponents.counter.env"
+	)
+	require.Equal(t, map[string]int{a: 1, b: 1, c: 1, env: 1}, components(apply(config)))
+
+	// Blocks calling functions are always evaluated.
+	require.Equal(t, map[string]int{env: 1}, components(apply(config)))
+
+	// Changing a block evaluates the component and its dependants.
+	require.Equal(t, map[string]int{a: 1, b: 1, env: 1}, components(apply(strings.Replace(config, `input = "a"`, `input = "x"`, 1))))
+
+	// Removing components doesn't evaluate the others, and removed components
+	// are forgotten, so adding them again evaluates them.
+	require.Empty(t, components(apply(`
+		testcomponents.counter "a" {
+			input = "x"
+		}
+
+		testcomponents.counter "b" {
+			input = testcomponents.counter.a.output + "b"
+		}
+	`)))
+	require.Equal(t, map[string]int{c: 1, env: 1}, components(apply(`
+		testcomponents.counter "a" {
+			input = "x"
+		}
+
+		testcomponents.counter "b" {
+			input = testcomponents.counter.a.output + "b"
+		}
+
+		testcomponents.counter "c" {
+			input = "c"
+		}
+
+		testcomponents.counter "env" {
+			input = env("COUNTER_INPUT")
+		}
+	`)))
+}
+
+// components keeps the evaluations of component nodes, dropping those of
+// the nodes which are always evaluated, such as the logging node.
+func components(evaluations map[string]int) map[string]int {
+	res := map[string]int{}
+	for id, n := range evaluations {
+		if strings.HasPrefix(id, "testcomponents.") || strings.HasPrefix(id, "tenant.") {
+			res[id] = n
+		}
+	}
+	return res
+}
+
+// countingModuleController creates custom components which count how many times
+// their body is loaded.
+type countingModuleController struct {
+	loads map[string]int
+}
+
+func (c *countingModuleController) NewModule(id string, export component.ExportFunc) (component.Module, error) {
+	return nil, errors.New("modules are not supported")
+}
+
+func (c *countingModuleController) ModuleIDs() []string { return nil }
+
+func (c *countingModuleController) NewCustomComponent(id string, export component.ExportFunc) (controller.CustomComponent, error) {
+	return &countingCustomComponent{loads: c.loads, export: export}, nil
+}
+
+type countingCustomComponent struct {
+	loads  map[string]int
+	export component.ExportFunc
+}
+
+func (c *countingCustomComponent) LoadBody(body ast.Body, args map[string]any, _ *controller.CustomComponentRegistry) error {
+	c.loads[fmt.Sprint(args["input"])]++
+	c.export(map[string]any{})
+	return nil
+}
+
+func (c *countingCustomComponent) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestLoader_ReloadDeclare(t *testing.T) {
+	evaluations := newEvaluationRecorder()
+	modules := &countingModuleController{loads: map[string]int{}}
+	loader := newCounterLoader(t, evaluations, modules)
+
+	apply := func(declare string) map[string]int {
+		t.Helper()
+		require.NoError(t, applyFromContent(t, loader, []byte(`
+			tenant "a" {
+				input = "a"
+			}
+		`), nil, []byte(declare)).ErrorOrNil())
+		return components(evaluations.Take())
+	}
+
+	declare := `
+		declare "tenant" {
+			argument "input" {}
+
+			testcomponents.counter "default" {
+				input = argument.input.value
+			}
+		}
+	`
+	require.Equal(t, map[string]int{"tenant.a": 1}, apply(declare))
+	require.Equal(t, map[string]int{"a": 1}, modules.loads)
+
+	// Custom components of an unchanged declare aren't evaluated again.
+	require.Empty(t, apply(declare))
+	require.Equal(t, map[string]int{"a": 1}, modules.loads)
+
+	// Changing the declare evaluates its custom components, which load the
+	// new body.
+	require.Equal(t, map[string]int{"tenant.a": 1}, apply(strings.Replace(declare, `"default"`, `"renamed"`, 1)))
+	require.Equal(t, map[string]int{"a": 2}, modules.loads)
+}
+
+func TestLoader_CustomComponentArguments(t *testing.T) {
+	evaluations := newEvaluationRecorder()
+	modules := &countingModuleController{loads: map[string]int{}}
+	loader := newCounterLoader(t, evaluations, modules)
+
+	diags := applyFromContent(t, loader, []byte(`
+		tenant "a" {
+			input   = "a"
+			unknown = 1
+		}
+	`), nil, []byte(`
+		declare "tenant" {
+			argument "input" {}
+		}
+	`))
+	require.ErrorContains(t, diags.ErrorOrNil(), `unsupported argument "unknown" for custom component tenant`)
+
+	// The arguments are checked when loading, before anything is evaluated.
+	require.Empty(t, evaluations.Take())
+	require.Empty(t, modules.loads)
+}
+
+func TestLoader_LatencyBudget(t *testing.T) {
+	newLoaderOptions := func() controller.LoaderOptions {
+		l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+		return controller.LoaderOptions{
+			ComponentGlobals: controller.ComponentGlobals{
+				Logger:            l,
+				TraceProvider:     noop.NewTracerProvider(),
+				DataPath:          t.TempDir(),
+				MinStability:      featuregate.StabilityBeta,
+				OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+				Registerer:        prometheus.NewRegistry(),
+				NewModuleController: func(id string) controller.ModuleController {
+					return nil
+				},
+			},
+		}
+	}
+
+	testConfig := `
+		latency_budget "slow" {
+			component            = "testcomponents.passthrough.slow"
+			max_evaluation_time  = "1ms"
+			max_violations       = 2
+			recovery_evaluations = 2
+		}
+	`
+
+	componentHealth := func(t *testing.T, l *controller.Loader, id string) component.Health {
+		t.Helper()
+		for _, cn := range l.Components() {
+			if cn.NodeID() == id {
+				return cn.CurrentHealth()
+			}
+		}
+		require.FailNow(t, "component not found", id)
+		return component.Health{}
+	}
+
+	t.Run("Unhealthy after repeated violations", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		apply := func(input string, lag string) {
+			testFile := `
+				testcomponents.passthrough "slow" {
+					input = "` + input + `"
+					lag   = "` + lag + `"
+				}
+			`
+			diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+			require.NoError(t, diags.ErrorOrNil())
+		}
+
+		// Components aren't run by the loader, so their health is unknown unless
+		// they're unhealthy.
+		apply("a", "20ms")
+		require.Equal(t, component.HealthTypeUnknown, componentHealth(t, l, "testcomponents.passthrough.slow").Health)
+
+		apply("b", "20ms")
+		health := componentHealth(t, l, "testcomponents.passthrough.slow")
+		require.Equal(t, component.HealthTypeUnhealthy, health.Health)
+		require.Contains(t, health.Message, "exceeded latency budget of 1ms for 2 consecutive evaluations")
+
+		// A single evaluation within the budget isn't enough to recover.
+		apply("c", "0s")
+		require.Equal(t, component.HealthTypeUnhealthy, componentHealth(t, l, "testcomponents.passthrough.slow").Health)
+
+		apply("d", "0s")
+		require.Equal(t, component.HealthTypeUnknown, componentHealth(t, l, "testcomponents.passthrough.slow").Health)
+	})
+
+	t.Run("Budget removed", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		for _, input := range []string{"a", "b"} {
+			testFile := `
+				testcomponents.passthrough "slow" {
+					input = "` + input + `"
+					lag   = "20ms"
+				}
+			`
+			diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+			require.NoError(t, diags.ErrorOrNil())
+		}
+		require.Equal(t, component.HealthTypeUnhealthy, componentHealth(t, l, "testcomponents.passthrough.slow").Health)
+
+		testFile := `
+			testcomponents.passthrough "slow" {
+				input = "b"
+				lag   = "20ms"
+			}
+		`
+		diags := applyFromContent(t, l, []byte(testFile), nil, nil)
+		require.NoError(t, diags.ErrorOrNil())
+		require.Equal(t, component.HealthTypeUnknown, componentHealth(t, l, "testcomponents.passthrough.slow").Health)
+	})
+
+	t.Run("Unknown component", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		testFile := `
+			testcomponents.passthrough "fast" {
+				input = "a"
+			}
+		`
+		diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+		require.ErrorContains(t, diags.ErrorOrNil(), `latency_budget.slow references unknown component "testcomponents.passthrough.slow"`)
+	})
+}
+
+func TestLoader_ExportLimit(t *testing.T) {
+	newLoaderOptions := func() controller.LoaderOptions {
+		l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+		return controller.LoaderOptions{
+			ComponentGlobals: controller.ComponentGlobals{
+				Logger:            l,
+				TraceProvider:     noop.NewTracerProvider(),
+				DataPath:          t.TempDir(),
+				MinStability:      featuregate.StabilityBeta,
+				OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+				Registerer:        prometheus.NewRegistry(),
+				NewModuleController: func(id string) controller.ModuleController {
+					return nil
+				},
+			},
+		}
+	}
+
+	testConfig := `
+		export_limit "small" {
+			component = "testcomponents.passthrough.small"
+			max_size  = "100B"
+		}
+	`
+
+	getComponent := func(t *testing.T, l *controller.Loader, id string) controller.ComponentNode {
+		t.Helper()
+		for _, cn := range l.Components() {
+			if cn.NodeID() == id {
+				return cn
+			}
+		}
+		require.FailNow(t, "component not found", id)
+		return nil
+	}
+
+	t.Run("Oversized exports rejected", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		apply := func(input string) {
+			testFile := `
+				testcomponents.passthrough "small" {
+					input = "` + input + `"
+				}
+			`
+			diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+			require.NoError(t, diags.ErrorOrNil())
+		}
+
+		apply("a")
+		cn := getComponent(t, l, "testcomponents.passthrough.small")
+		require.Equal(t, testcomponents.PassthroughExports{Output: "a"}, cn.Exports())
+
+		apply(strings.Repeat("b", 50))
+		require.Equal(t, testcomponents.PassthroughExports{Output: "a"}, cn.Exports())
+		health := cn.CurrentHealth()
+		require.Equal(t, component.HealthTypeUnhealthy, health.Health)
+		require.Contains(t, health.Message, "exceed the export limit of 100B")
+
+		apply("c")
+		require.Equal(t, testcomponents.PassthroughExports{Output: "c"}, cn.Exports())
+		require.Equal(t, component.HealthTypeUnknown, cn.CurrentHealth().Health)
+	})
+
+	t.Run("Unknown component", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		testFile := `
+			testcomponents.passthrough "other" {
+				input = "a"
+			}
+		`
+		diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+		require.ErrorContains(t, diags.ErrorOrNil(), `export_limit.small references unknown component "testcomponents.passthrough.small"`)
+	})
+}
+
+func TestLoader_ExecutionPool(t *testing.T) {
+	newLoaderOptions := func() controller.LoaderOptions {
+		l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+		return controller.LoaderOptions{
+			ComponentGlobals: controller.ComponentGlobals{
+				Logger:            l,
+				TraceProvider:     noop.NewTracerProvider(),
+				DataPath:          t.TempDir(),
+				MinStability:      featuregate.StabilityBeta,
+				OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+				Registerer:        prometheus.NewRegistry(),
+				NewModuleController: func(id string) controller.ModuleController {
+					return nil
+				},
+			},
+		}
+	}
+
+	testFile := `
+		testcomponents.passthrough "a" {
+			input = "a"
+		}
+
+		testcomponents.passthrough "b" {
+			input = "b"
+		}
+	`
+
+	t.Run("Valid pools", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		testConfig := `
+			execution_pool "heavy" {
+				components  = ["testcomponents.passthrough.a", "testcomponents.passthrough.b"]
+				max_workers = 2
+			}
+		`
+		diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+		require.NoError(t, diags.ErrorOrNil())
+
+		// Components depend on their pool.
+		for _, id := range []string{"testcomponents.passthrough.a", "testcomponents.passthrough.b"} {
+			var deps []string
+			for _, n := range l.Graph().Dependencies(l.Graph().GetByID(id)) {
+				deps = append(deps, n.NodeID())
+			}
+			require.Contains(t, deps, "execution_pool.heavy")
+		}
+	})
+
+	t.Run("Unknown component", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		testConfig := `
+			execution_pool "heavy" {
+				components  = ["testcomponents.passthrough.c"]
+				max_workers = 2
+			}
+		`
+		diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+		require.ErrorContains(t, diags.ErrorOrNil(), `execution_pool.heavy references unknown component "testcomponents.passthrough.c"`)
+	})
+
+	t.Run("Component in several pools", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		testConfig := `
+			execution_pool "heavy" {
+				components  = ["testcomponents.passthrough.a"]
+				max_workers = 2
+			}
+
+			execution_pool "light" {
+				components  = ["testcomponents.passthrough.a"]
+				max_workers = 4
+			}
+		`
+		diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+		require.ErrorContains(t, diags.ErrorOrNil(), `component "testcomponents.passthrough.a" belongs to both execution_pool.`)
+	})
+
+	t.Run("Components must be literals", func(t *testing.T) {
+		l := controller.NewLoader(newLoaderOptions())
+		testConfig := `
+			execution_pool "heavy" {
+				components  = [testcomponents.passthrough.a.output]
+				max_workers = 2
+			}
+		`
+		diags := applyFromContent(t, l, []byte(testFile), []byte(testConfig), nil)
+		require.ErrorContains(t, diags.ErrorOrNil(), "execution_pool.heavy must set components to an array of string literals")
+	})
+}
+
 // TestScopeWithFailingComponent is used to ensure that the scope is filled out, even if the component
 // fails to properly start.
 func TestScopeWithFailingComponent(t *testing.T) {
@@ -563,3 +1269,32 @@ func (fs *fakeService) Data() any {
 	}
 	return nil
 }
+
+// observerService is a fakeService which records the IDs of the removed
+// components it's notified about.
+type observerService struct {
+	fakeService
+	removed [][]string
+}
+
+func (o *observerService) ComponentsRemoved(ids []string) {
+	o.removed = append(o.removed, ids)
+}
+
+// syncBuffer is a bytes.Buffer which can be written to concurrently.
+type syncBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.buf.String()
+}
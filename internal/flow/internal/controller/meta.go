@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/vm"
+)
+
+// metaBlockName is the name of the block holding the metadata labels of a
+// component, such as the team owning it.
+const metaBlockName = "meta"
+
+// splitMetaBlocks returns the statements of body without its meta blocks,
+// along with the meta blocks which were removed. Meta blocks are removed so
+// that they aren't decoded into the arguments of the component.
+func splitMetaBlocks(body ast.Body) (rest ast.Body, meta []*ast.BlockStmt) {
+	rest = make(ast.Body, 0, len(body))
+	for _, stmt := range body {
+		if b, ok := stmt.(*ast.BlockStmt); ok && len(b.Name) == 1 && b.Name[0] == metaBlockName {
+			meta = append(meta, b)
+			continue
+		}
+		rest = append(rest, stmt)
+	}
+	return rest, meta
+}
+
+// evaluateMeta evaluates the metadata labels of a component from its meta
+// blocks, of which there may be at most one.
+func evaluateMeta(scope *vm.Scope, blocks []*ast.BlockStmt) (map[string]string, error) {
+	switch {
+	case len(blocks) == 0:
+		return nil, nil
+	case len(blocks) > 1:
+		return nil, fmt.Errorf("%s: the meta block may only be specified once", ast.StartPos(blocks[1]).Position())
+	case blocks[0].Label != "":
+		return nil, fmt.Errorf("%s: the meta block must not have a label", ast.StartPos(blocks[0]).Position())
+	}
+
+	var labels map[string]string
+	if err := vm.New(blocks[0].Body).Evaluate(scope, &labels); err != nil {
+		return nil, fmt.Errorf("decoding meta block: %w", err)
+	}
+	return labels, nil
+}
+
+// componentMeta holds the current metadata labels of a component. It's safe
+// for concurrent use, as labels are read by the logger of the component while
+// the component is being evaluated.
+type componentMeta struct {
+	mut    sync.RWMutex
+	labels map[string]string
+}
+
+// Labels returns a copy of the current metadata labels.
+func (m *componentMeta) Labels() map[string]string {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	if len(m.labels) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(m.labels))
+	for k, v := range m.labels {
+		res[k] = v
+	}
+	return res
+}
+
+// Set replaces the current metadata labels.
+func (m *componentMeta) Set(labels map[string]string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.labels = labels
+}
+
+// Logger returns a logger which adds the current metadata labels, sorted by
+// key, to the lines logged to next.
+func (m *componentMeta) Logger(next log.Logger) log.Logger {
+	return log.LoggerFunc(func(keyvals ...interface{}) error {
+		m.mut.RLock()
+		keys := make([]string, 0, len(m.labels))
+		for k := range m.labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		kvs := make([]interface{}, 0, len(keyvals)+2*len(keys))
+		kvs = append(kvs, keyvals...)
+		for _, k := range keys {
+			kvs = append(kvs, k, m.labels[k])
+		}
+		m.mut.RUnlock()
+
+		return next.Log(kvs...)
+	})
+}
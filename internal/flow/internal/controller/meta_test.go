@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+type metaArgs struct {
+	Value string `river:"value,attr,optional"`
+}
+
+type metaComponent struct{}
+
+func (metaComponent) Run(ctx context.Context) error         { <-ctx.Done(); return nil }
+func (metaComponent) Update(args component.Arguments) error { return nil }
+
+func parseBlock(t *testing.T, src string) *ast.BlockStmt {
+	t.Helper()
+	file, err := parser.ParseFile("", []byte(src))
+	require.NoError(t, err)
+	return file.Body[0].(*ast.BlockStmt)
+}
+
+func TestMeta(t *testing.T) {
+	var built metaArgs
+	reg := component.Registration{
+		Name: "testcomponents.meta",
+		Args: metaArgs{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			built = args.(metaArgs)
+			return metaComponent{}, nil
+		},
+	}
+	globals := ComponentGlobals{
+		Registerer:        prometheus.NewRegistry(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+		NewModuleController: func(id string) ModuleController {
+			return nil
+		},
+	}
+
+	cn := NewBuiltinComponentNode(globals, reg, parseBlock(t, `
+		testcomponents.meta "a" {
+			value = "x"
+
+			meta {
+				team = "payments"
+				env  = env_name
+			}
+		}
+	`))
+	scope := &vm.Scope{Variables: map[string]interface{}{"env_name": "prod"}}
+	require.NoError(t, cn.Evaluate(scope))
+	require.Equal(t, metaArgs{Value: "x"}, built)
+	require.Equal(t, map[string]string{"team": "payments", "env": "prod"}, cn.Meta())
+
+	// Meta labels are removed along with the meta block.
+	cn.UpdateBlock(parseBlock(t, `testcomponents.meta "a" { value = "x" }`))
+	require.NoError(t, cn.Evaluate(scope))
+	require.Nil(t, cn.Meta())
+
+	t.Run("rejects multiple meta blocks", func(t *testing.T) {
+		cn.UpdateBlock(parseBlock(t, `
+			testcomponents.meta "a" {
+				meta { team = "a" }
+				meta { team = "b" }
+			}
+		`))
+		require.ErrorContains(t, cn.Evaluate(scope), "the meta block may only be specified once")
+	})
+
+	t.Run("rejects labels", func(t *testing.T) {
+		cn.UpdateBlock(parseBlock(t, `
+			testcomponents.meta "a" {
+				meta "owner" { team = "a" }
+			}
+		`))
+		require.ErrorContains(t, cn.Evaluate(scope), "the meta block must not have a label")
+	})
+
+	t.Run("rejects values which aren't strings", func(t *testing.T) {
+		cn.UpdateBlock(parseBlock(t, `
+			testcomponents.meta "a" {
+				meta { team = ["a", "b"] }
+			}
+		`))
+		require.ErrorContains(t, cn.Evaluate(scope), "decoding meta block")
+	})
+}
+
+func TestMetaLogger(t *testing.T) {
+	var buf bytes.Buffer
+	meta := &componentMeta{}
+	logger := meta.Logger(log.NewLogfmtLogger(&buf))
+
+	meta.Set(map[string]string{"team": "payments", "env": "prod"})
+	require.NoError(t, logger.Log("msg", "hello"))
+	require.Equal(t, "msg=hello env=prod team=payments\n", buf.String())
+}
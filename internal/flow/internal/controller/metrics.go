@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	"path"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -97,18 +98,27 @@ func (cm *controllerMetrics) Describe(ch chan<- *prometheus.Desc) {
 
 type controllerCollector struct {
 	l                      *Loader
+	id                     string
 	runningComponentsTotal *prometheus.Desc
+	componentMetaInfo      *prometheus.Desc
 }
 
 func newControllerCollector(l *Loader, id string) *controllerCollector {
 	return &controllerCollector{
-		l: l,
+		l:  l,
+		id: id,
 		runningComponentsTotal: prometheus.NewDesc(
 			"agent_component_controller_running_components",
 			"Total number of running components.",
 			[]string{"health_type"},
 			map[string]string{"controller_id": id},
 		),
+		componentMetaInfo: prometheus.NewDesc(
+			"agent_component_meta_info",
+			"Metadata labels of components from their meta block, one series per label.",
+			[]string{"component_id", "key", "value"},
+			map[string]string{"controller_id": id},
+		),
 	}
 }
 
@@ -121,6 +131,14 @@ func (cc *controllerCollector) Collect(ch chan<- prometheus.Metric) {
 		if builtinComponent, ok := component.(*BuiltinComponentNode); ok {
 			builtinComponent.registry.Collect(ch)
 		}
+
+		componentID := component.NodeID()
+		if cc.id != "" {
+			componentID = path.Join(cc.id, componentID)
+		}
+		for key, value := range component.Meta() {
+			ch <- prometheus.MustNewConstMetric(cc.componentMetaInfo, prometheus.GaugeValue, 1, componentID, key, value)
+		}
 	}
 
 	for _, im := range cc.l.Imports() {
@@ -136,4 +154,5 @@ func (cc *controllerCollector) Collect(ch chan<- prometheus.Metric) {
 
 func (cc *controllerCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- cc.runningComponentsTotal
+	ch <- cc.componentMetaInfo
 }
@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"runtime/pprof"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/flow/modulelock"
 	"github.com/grafana/agent/internal/flow/tracing"
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/vm"
@@ -67,12 +69,14 @@ type ComponentGlobals struct {
 	TraceProvider       trace.TracerProvider                   // Tracer shared between all managed components.
 	DataPath            string                                 // Shared directory where component data may be stored
 	MinStability        featuregate.Stability                  // Minimum allowed stability level for features
+	Features            map[string]bool                        // Feature flags exposed as sys.features
 	OnBlockNodeUpdate   func(cn BlockNode)                     // Informs controller that we need to reevaluate
 	OnExportsChange     func(exports map[string]any)           // Invoked when the managed component updated its exports
 	Registerer          prometheus.Registerer                  // Registerer for serving agent and component metrics
 	ControllerID        string                                 // ID of controller.
 	NewModuleController func(id string) ModuleController       // Func to generate a module controller.
 	GetServiceData      func(name string) (interface{}, error) // Get data for a service.
+	ModuleLock          *modulelock.Lockfile                   // Lockfile imported modules are checked against.
 }
 
 // BuiltinComponentNode is a controller node which manages a builtin component.
@@ -91,21 +95,40 @@ type BuiltinComponentNode struct {
 	registry          *prometheus.Registry
 	exportsType       reflect.Type
 	moduleController  ModuleController
+	profileLabels     pprof.LabelSet     // Labels of the goroutines of the managed component
 	OnBlockNodeUpdate func(cn BlockNode) // Informs controller that we need to reevaluate
 
-	mut     sync.RWMutex
-	block   *ast.BlockStmt // Current River block to derive args from
-	eval    *vm.Evaluator
-	managed component.Component // Inner managed component
-	args    component.Arguments // Evaluated arguments for the managed component
+	mut        sync.RWMutex
+	block      *ast.BlockStmt // Current River block to derive args from
+	eval       *vm.Evaluator
+	metaBlocks []*ast.BlockStmt    // Meta blocks of the current River block
+	managed    component.Component // Inner managed component
+	args       component.Arguments // Evaluated arguments for the managed component
+
+	meta *componentMeta // Metadata labels from the meta block
 
 	// NOTE(rfratto): health and exports have their own mutex because they may be
 	// set asynchronously while mut is still being held (i.e., when calling Evaluate
 	// and the managed component immediately creates new exports)
 
-	healthMut  sync.RWMutex
-	evalHealth component.Health // Health of the last evaluate
-	runHealth  component.Health // Health of running the component
+	healthMut     sync.RWMutex
+	evalHealth    component.Health         // Health of the last evaluate
+	runHealth     component.Health         // Health of running the component
+	budgetHealth  component.Health         // Health of the latency budget
+	latencyBudget *LatencyBudgetConfigNode // Latency budget of evaluations, if any
+	limitHealth   component.Health         // Health of the export limit
+	exportLimit   *ExportLimitConfigNode   // Limit of the size of exports, if any
+
+	budgetViolations prometheus.Counter // Created when a latency budget is first set.
+	limitViolations  prometheus.Counter // Created when an export limit is first set.
+	panics           prometheus.Counter // Created when the component first panics.
+
+	executorMut   sync.RWMutex
+	executionPool *ExecutionPoolConfigNode // Execution pool of the component, if any
+	executorWait  prometheus.Counter       // Created when an execution pool is first set.
+
+	exportsHistory *exportsHistory // Past exports of the managed component
+	errorsHistory  *errorsHistory  // Past errors of the managed component
 
 	exportsMut sync.RWMutex
 	exports    component.Exports // Evaluated exports for the managed component
@@ -137,6 +160,8 @@ func NewBuiltinComponentNode(globals ComponentGlobals, reg component.Registratio
 		globalID = path.Join(globals.ControllerID, nodeID)
 	}
 
+	body, metaBlocks := splitMetaBlocks(b.Body)
+
 	cn := &BuiltinComponentNode{
 		id:                id,
 		globalID:          globalID,
@@ -146,10 +171,13 @@ func NewBuiltinComponentNode(globals ComponentGlobals, reg component.Registratio
 		reg:               reg,
 		exportsType:       getExportsType(reg),
 		moduleController:  globals.NewModuleController(globalID),
+		profileLabels:     pprof.Labels("component_id", nodeID, "module_path", globals.ControllerID),
 		OnBlockNodeUpdate: globals.OnBlockNodeUpdate,
 
-		block: b,
-		eval:  vm.New(b.Body),
+		block:      b,
+		eval:       vm.New(body),
+		metaBlocks: metaBlocks,
+		meta:       &componentMeta{},
 
 		// Prepopulate arguments and exports with their zero values.
 		args:    reg.Args,
@@ -157,6 +185,14 @@ func NewBuiltinComponentNode(globals ComponentGlobals, reg component.Registratio
 
 		evalHealth: initHealth,
 		runHealth:  initHealth,
+
+		// Healthy with a zero timestamp never takes precedence over other health
+		// values.
+		budgetHealth: component.Health{Health: component.HealthTypeHealthy},
+		limitHealth:  component.Health{Health: component.HealthTypeHealthy},
+
+		exportsHistory: newExportsHistory(DefaultExportsHistoryEntries, DefaultExportsHistoryBytes),
+		errorsHistory:  newErrorsHistory(DefaultErrorsHistoryEntries),
 	}
 	cn.managedOpts = getManagedOptions(globals, cn)
 
@@ -167,7 +203,7 @@ func getManagedOptions(globals ComponentGlobals, cn *BuiltinComponentNode) compo
 	cn.registry = prometheus.NewRegistry()
 	return component.Options{
 		ID:     cn.globalID,
-		Logger: log.With(globals.Logger, "component", cn.globalID),
+		Logger: cn.meta.Logger(log.With(globals.Logger, "component", cn.globalID)),
 		Registerer: prometheus.WrapRegistererWith(prometheus.Labels{
 			"component_id": cn.globalID,
 		}, cn.registry),
@@ -181,6 +217,8 @@ func getManagedOptions(globals ComponentGlobals, cn *BuiltinComponentNode) compo
 		GetServiceData: func(name string) (interface{}, error) {
 			return globals.GetServiceData(name)
 		},
+
+		Executor: component.ExecutorFunc(cn.acquireWorker),
 	}
 }
 
@@ -227,10 +265,13 @@ func (cn *BuiltinComponentNode) UpdateBlock(b *ast.BlockStmt) {
 		panic("UpdateBlock called with an River block with a different component ID")
 	}
 
+	body, metaBlocks := splitMetaBlocks(b.Body)
+
 	cn.mut.Lock()
 	defer cn.mut.Unlock()
 	cn.block = b
-	cn.eval = vm.New(b.Body)
+	cn.eval = vm.New(body)
+	cn.metaBlocks = metaBlocks
 }
 
 // Evaluate implements BlockNode and updates the arguments for the managed component
@@ -238,14 +279,18 @@ func (cn *BuiltinComponentNode) UpdateBlock(b *ast.BlockStmt) {
 // will be built the first time Evaluate is called.
 //
 // Evaluate will return an error if the River block cannot be evaluated or if
-// decoding to arguments fails.
+// decoding to arguments fails. Panics from building or updating the managed
+// component are recovered and returned as errors.
 func (cn *BuiltinComponentNode) Evaluate(scope *vm.Scope) error {
+	start := time.Now()
 	err := cn.evaluate(scope)
+	cn.observeLatencyBudget(time.Since(start))
 
 	switch err {
 	case nil:
 		cn.setEvalHealth(component.HealthTypeHealthy, "component evaluated")
 	default:
+		cn.recordError(err)
 		msg := fmt.Sprintf("component evaluation failed: %s", err)
 		cn.setEvalHealth(component.HealthTypeUnhealthy, msg)
 	}
@@ -256,6 +301,12 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 	cn.mut.Lock()
 	defer cn.mut.Unlock()
 
+	meta, err := evaluateMeta(scope, cn.metaBlocks)
+	if err != nil {
+		return err
+	}
+	cn.meta.Set(meta)
+
 	argsPointer := cn.reg.CloneArguments()
 	if err := cn.eval.Evaluate(scope, argsPointer); err != nil {
 		return fmt.Errorf("decoding River: %w", err)
@@ -267,7 +318,11 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 
 	if cn.managed == nil {
 		// We haven't built the managed component successfully yet.
-		managed, err := cn.reg.Build(cn.managedOpts, argsCopyValue)
+		var managed component.Component
+		err := callRecovered("Build", func() (err error) {
+			managed, err = cn.reg.Build(cn.managedOpts, argsCopyValue)
+			return err
+		})
 		if err != nil {
 			return fmt.Errorf("building component: %w", err)
 		}
@@ -285,7 +340,8 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 	}
 
 	// Update the existing managed component
-	if err := cn.managed.Update(argsCopyValue); err != nil {
+	err = callRecovered("Update", func() error { return cn.managed.Update(argsCopyValue) })
+	if err != nil {
 		return fmt.Errorf("updating component: %w", err)
 	}
 
@@ -298,7 +354,9 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 // error before calling Run.
 //
 // Run will immediately return ErrUnevaluated if Evaluate has never been called
-// successfully. Otherwise, Run will return nil.
+// successfully. Otherwise, Run will return the error of the managed
+// component, if any. If the managed component panics, the panic is recovered,
+// the component is marked as unhealthy, and Run returns an error.
 func (cn *BuiltinComponentNode) Run(ctx context.Context) error {
 	cn.mut.RLock()
 	managed := cn.managed
@@ -309,22 +367,66 @@ func (cn *BuiltinComponentNode) Run(ctx context.Context) error {
 	}
 
 	cn.setRunHealth(component.HealthTypeHealthy, "started component")
-	err := cn.managed.Run(ctx)
+	err := cn.callLabeled(ctx, "Run", managed.Run)
 
-	var exitMsg string
-	logger := cn.managedOpts.Logger
-	if err != nil {
+	var (
+		pe     *panicError
+		logger = cn.managedOpts.Logger
+	)
+	switch {
+	case errors.As(err, &pe):
+		cn.recordError(err)
+		cn.setRunHealth(component.HealthTypeUnhealthy, fmt.Sprintf("component shut down after a panic: %s", err))
+		return err
+	case err != nil:
+		cn.recordError(err)
 		level.Error(logger).Log("msg", "component exited with error", "err", err)
-		exitMsg = fmt.Sprintf("component shut down with error: %s", err)
-	} else {
+		cn.setRunHealth(component.HealthTypeExited, fmt.Sprintf("component shut down with error: %s", err))
+	default:
 		level.Info(logger).Log("msg", "component exited")
-		exitMsg = "component shut down normally"
+		cn.setRunHealth(component.HealthTypeExited, "component shut down normally")
 	}
+	return err
+}
 
-	cn.setRunHealth(component.HealthTypeExited, exitMsg)
+// callLabeled calls f with callRecovered while the calling goroutine is
+// tagged with the pprof labels of the component, so that CPU profiles can be
+// broken down by component. Goroutines started by f inherit the labels.
+//
+// Only Run is labeled. Build and Update are called by the goroutine
+// evaluating the graph, and pprof.Do would replace its own labels.
+func (cn *BuiltinComponentNode) callLabeled(ctx context.Context, op string, f func(ctx context.Context) error) (err error) {
+	pprof.Do(ctx, cn.profileLabels, func(ctx context.Context) {
+		err = callRecovered(op, func() error { return f(ctx) })
+	})
 	return err
 }
 
+// recordError adds err to the errors history of the component. Panics are
+// recorded along with their stack, logged, and counted.
+func (cn *BuiltinComponentNode) recordError(err error) {
+	var pe *panicError
+	if !errors.As(err, &pe) {
+		cn.errorsHistory.Record(err.Error(), "")
+		return
+	}
+
+	level.Error(cn.managedOpts.Logger).Log("msg", "recovered from component panic", "err", err, "stack", string(pe.stack))
+	cn.errorsHistory.Record(err.Error(), string(pe.stack))
+
+	cn.healthMut.Lock()
+	defer cn.healthMut.Unlock()
+
+	if cn.panics == nil {
+		cn.panics = prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_component_panics_total",
+			Help: "Total number of panics recovered from the component.",
+		})
+		_ = cn.managedOpts.Registerer.Register(cn.panics)
+	}
+	cn.panics.Inc()
+}
+
 // ErrUnevaluated is returned if BuiltinComponentNode.Run is called before a managed
 // component is built.
 var ErrUnevaluated = errors.New("managed component not built")
@@ -361,6 +463,11 @@ func (cn *BuiltinComponentNode) setExports(e component.Exports) {
 		panic(fmt.Sprintf("Component %s changed Exports types from %T to %T", cn.nodeID, cn.reg.Exports, e))
 	}
 
+	e, ok := cn.checkExportLimit(e)
+	if !ok {
+		return
+	}
+
 	// Some components may aggressively reexport values even though no exposed
 	// state has changed. This may be done for components which always supply
 	// exports whenever their arguments are evaluated without tracking internal
@@ -378,33 +485,56 @@ func (cn *BuiltinComponentNode) setExports(e component.Exports) {
 	cn.exportsMut.Unlock()
 
 	if changed {
+		cn.exportsHistory.Record(e)
+
 		// Inform the controller that we have new exports.
 		cn.OnBlockNodeUpdate(cn)
 	}
 }
 
+// Meta returns the metadata labels of the component from its meta block.
+func (cn *BuiltinComponentNode) Meta() map[string]string {
+	return cn.meta.Labels()
+}
+
+// ExportsHistory returns the past exports of the managed component, ordered
+// from oldest to newest.
+func (cn *BuiltinComponentNode) ExportsHistory() []component.ExportsRecord {
+	return cn.exportsHistory.List()
+}
+
+// ErrorsHistory returns the past errors of the managed component, ordered
+// from oldest to newest.
+func (cn *BuiltinComponentNode) ErrorsHistory() []component.ErrorRecord {
+	return cn.errorsHistory.List()
+}
+
 // CurrentHealth returns the current health of the BuiltinComponentNode.
 //
 // The health of a BuiltinComponentNode is determined by combining:
 //
 //  1. Health from the call to Run().
 //  2. Health from the last call to Evaluate().
-//  3. Health reported from the component.
+//  3. Health from the latency budget of evaluations.
+//  4. Health from the export limit.
+//  5. Health reported from the component.
 func (cn *BuiltinComponentNode) CurrentHealth() component.Health {
 	cn.healthMut.RLock()
 	defer cn.healthMut.RUnlock()
 
 	var (
-		runHealth  = cn.runHealth
-		evalHealth = cn.evalHealth
+		runHealth    = cn.runHealth
+		evalHealth   = cn.evalHealth
+		budgetHealth = cn.budgetHealth
+		limitHealth  = cn.limitHealth
 	)
 
 	if hc, ok := cn.managed.(component.HealthComponent); ok {
 		componentHealth := hc.CurrentHealth()
-		return component.LeastHealthy(runHealth, evalHealth, componentHealth)
+		return component.LeastHealthy(runHealth, evalHealth, budgetHealth, limitHealth, componentHealth)
 	}
 
-	return component.LeastHealthy(runHealth, evalHealth)
+	return component.LeastHealthy(runHealth, evalHealth, budgetHealth, limitHealth)
 }
 
 // DebugInfo returns debugging information from the managed component (if any).
@@ -444,6 +574,173 @@ func (cn *BuiltinComponentNode) setRunHealth(t component.HealthType, msg string)
 	}
 }
 
+// SetLatencyBudget sets the latency budget which evaluations of the component
+// are checked against. A nil budget removes the current budget.
+func (cn *BuiltinComponentNode) SetLatencyBudget(budget *LatencyBudgetConfigNode) {
+	cn.healthMut.Lock()
+	defer cn.healthMut.Unlock()
+
+	if budget != nil && cn.budgetViolations == nil {
+		cn.budgetViolations = prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_component_latency_budget_violations_total",
+			Help: "Total number of component evaluations which exceeded the latency budget of the component.",
+		})
+		_ = cn.managedOpts.Registerer.Register(cn.budgetViolations)
+	}
+	if budget == nil {
+		cn.budgetHealth = component.Health{Health: component.HealthTypeHealthy}
+	}
+	cn.latencyBudget = budget
+}
+
+// observeLatencyBudget checks an evaluation which took duration against the
+// latency budget of the component. The component is marked as unhealthy once
+// the budget is exceeded for too many consecutive evaluations, and healthy
+// again once enough consecutive evaluations meet the budget.
+func (cn *BuiltinComponentNode) observeLatencyBudget(duration time.Duration) {
+	cn.healthMut.Lock()
+	defer cn.healthMut.Unlock()
+
+	if cn.latencyBudget == nil {
+		return
+	}
+	exceeded, streak := cn.latencyBudget.Observe(duration)
+	args := cn.latencyBudget.Arguments()
+
+	if !exceeded {
+		if cn.budgetHealth.Health == component.HealthTypeUnhealthy && streak >= args.RecoveryEvaluations {
+			level.Info(cn.managedOpts.Logger).Log("msg", "component evaluation is within its latency budget again", "duration", duration, "budget", args.MaxEvaluationTime)
+			cn.budgetHealth = component.Health{
+				Health:     component.HealthTypeHealthy,
+				Message:    "component evaluation within latency budget",
+				UpdateTime: time.Now(),
+			}
+		}
+		return
+	}
+
+	cn.budgetViolations.Inc()
+	level.Warn(cn.managedOpts.Logger).Log("msg", "component evaluation exceeded its latency budget", "duration", duration, "budget", args.MaxEvaluationTime, "violations", streak)
+
+	if streak >= args.MaxViolations {
+		if cn.budgetHealth.Health != component.HealthTypeUnhealthy {
+			level.Error(cn.managedOpts.Logger).Log("msg", "component is unhealthy after repeatedly exceeding its latency budget", "budget", args.MaxEvaluationTime, "violations", streak)
+		}
+		cn.budgetHealth = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("component evaluation exceeded latency budget of %s for %d consecutive evaluations (last took %s)", args.MaxEvaluationTime, streak, duration),
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+// SetExportLimit sets the limit which the exports of the component are
+// checked against. A nil limit removes the current limit.
+func (cn *BuiltinComponentNode) SetExportLimit(limit *ExportLimitConfigNode) {
+	cn.healthMut.Lock()
+	defer cn.healthMut.Unlock()
+
+	if limit != nil && cn.limitViolations == nil {
+		cn.limitViolations = prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_component_export_limit_violations_total",
+			Help: "Total number of exports of the component which exceeded the export limit of the component.",
+		})
+		_ = cn.managedOpts.Registerer.Register(cn.limitViolations)
+	}
+	if limit == nil {
+		cn.limitHealth = component.Health{Health: component.HealthTypeHealthy}
+	}
+	cn.exportLimit = limit
+}
+
+// checkExportLimit checks e against the export limit of the component. It
+// returns the exports to use in place of e, and false if e must be dropped.
+// The component is marked as unhealthy while its exports are truncated or
+// rejected, and healthy again once new exports fit in the limit.
+func (cn *BuiltinComponentNode) checkExportLimit(e component.Exports) (component.Exports, bool) {
+	cn.healthMut.RLock()
+	limit := cn.exportLimit
+	cn.healthMut.RUnlock()
+
+	if limit == nil {
+		return e, true
+	}
+
+	// healthMut isn't held while measuring e, so that large exports don't
+	// block reading the health of the component.
+	res, truncated, err := limit.Limit(e)
+
+	cn.healthMut.Lock()
+	defer cn.healthMut.Unlock()
+
+	switch {
+	case cn.exportLimit == nil:
+		// The limit was removed while e was measured.
+		return e, true
+
+	case err != nil:
+		cn.limitViolations.Inc()
+		level.Warn(cn.managedOpts.Logger).Log("msg", "rejected exports of component", "err", err)
+		cn.limitHealth = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("rejected exports: %s", err),
+			UpdateTime: time.Now(),
+		}
+		return nil, false
+
+	case truncated:
+		cn.limitViolations.Inc()
+		level.Warn(cn.managedOpts.Logger).Log("msg", "truncated exports of component", "limit", limit.Arguments().MaxSize)
+		cn.limitHealth = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("truncated exports to the export limit of %s", limit.Arguments().MaxSize),
+			UpdateTime: time.Now(),
+		}
+
+	case cn.limitHealth.Health == component.HealthTypeUnhealthy:
+		level.Info(cn.managedOpts.Logger).Log("msg", "component exports are within their export limit again")
+		cn.limitHealth = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "component exports within export limit",
+			UpdateTime: time.Now(),
+		}
+	}
+	return res, true
+}
+
+// SetExecutionPool sets the execution pool which bounds the data processing
+// of the component. A nil pool removes the component from its current pool.
+func (cn *BuiltinComponentNode) SetExecutionPool(pool *ExecutionPoolConfigNode) {
+	cn.executorMut.Lock()
+	defer cn.executorMut.Unlock()
+
+	if pool != nil && cn.executorWait == nil {
+		cn.executorWait = prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_component_execution_pool_wait_seconds_total",
+			Help: "Total time spent by the component waiting for a worker of its execution pool.",
+		})
+		_ = cn.managedOpts.Registerer.Register(cn.executorWait)
+	}
+	cn.executionPool = pool
+}
+
+// acquireWorker implements component.Executor for the managed component. It
+// acquires a worker from the execution pool of the component, if any.
+func (cn *BuiltinComponentNode) acquireWorker(ctx context.Context) (release func(), err error) {
+	cn.executorMut.RLock()
+	pool, wait := cn.executionPool, cn.executorWait
+	cn.executorMut.RUnlock()
+
+	if pool == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	release, err = pool.Acquire(ctx)
+	wait.Add(time.Since(start).Seconds())
+	return release, err
+}
+
 // ModuleIDs returns the current list of modules that this component is
 // managing.
 func (cn *BuiltinComponentNode) ModuleIDs() []string {
@@ -1,10 +1,21 @@
 package controller
 
 import (
+	"context"
+	"os"
 	"path/filepath"
+	"runtime/pprof"
 	"testing"
+	"time"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -37,3 +48,206 @@ func TestLocalID(t *testing.T) {
 	})
 	require.Equal(t, "/data/local.id", filepath.ToSlash(mo.DataPath))
 }
+
+func TestExecutionPool(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`
+		execution_pool "heavy" {
+			components  = ["local.id"]
+			max_workers = 1
+		}
+	`))
+	require.NoError(t, err)
+	pool := NewExecutionPoolConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{})
+	require.Equal(t, []string{"local.id"}, pool.Targets())
+	require.NoError(t, pool.Evaluate(&vm.Scope{}))
+
+	cn := &BuiltinComponentNode{nodeID: "local.id", globalID: "local.id"}
+	cn.managedOpts = getManagedOptions(ComponentGlobals{
+		Registerer: prometheus.NewRegistry(),
+		NewModuleController: func(id string) ModuleController {
+			return nil
+		},
+	}, cn)
+
+	// Components don't wait for workers until they belong to a pool.
+	release, err := cn.managedOpts.Executor.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+
+	cn.SetExecutionPool(pool)
+	release, err = cn.managedOpts.Executor.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// The only worker of the pool is busy.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cn.managedOpts.Executor.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Releasing more than once doesn't free other workers.
+	release()
+	release()
+	release, err = cn.managedOpts.Executor.Acquire(context.Background())
+	require.NoError(t, err)
+	_, err = cn.managedOpts.Executor.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	release()
+
+	cn.SetExecutionPool(nil)
+	_, err = cn.managedOpts.Executor.Acquire(ctx)
+	require.NoError(t, err)
+}
+
+type panicArgs struct {
+	PanicOn string `river:"panic_on,attr,optional"`
+}
+
+// panicComponent panics in the method named by its arguments.
+type panicComponent struct{ args panicArgs }
+
+func (c *panicComponent) Run(ctx context.Context) error {
+	if c.args.PanicOn == "Run" {
+		panic("run failed")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (c *panicComponent) Update(args component.Arguments) error {
+	c.args = args.(panicArgs)
+	if c.args.PanicOn == "Update" {
+		panic("update failed")
+	}
+	return nil
+}
+
+func TestPanicRecovery(t *testing.T) {
+	reg := component.Registration{
+		Name: "testcomponents.panic",
+		Args: panicArgs{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			if args.(panicArgs).PanicOn == "Build" {
+				panic("build failed")
+			}
+			return &panicComponent{args: args.(panicArgs)}, nil
+		},
+	}
+	l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+	globals := ComponentGlobals{
+		Logger:            l,
+		Registerer:        prometheus.NewRegistry(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+		NewModuleController: func(id string) ModuleController {
+			return nil
+		},
+	}
+	newNode := func(t *testing.T, panicOn string) *BuiltinComponentNode {
+		file, err := parser.ParseFile("", []byte(`testcomponents.panic "a" { panic_on = "`+panicOn+`" }`))
+		require.NoError(t, err)
+		return NewBuiltinComponentNode(globals, reg, file.Body[0].(*ast.BlockStmt))
+	}
+
+	t.Run("Build", func(t *testing.T) {
+		cn := newNode(t, "Build")
+		err := cn.Evaluate(&vm.Scope{})
+		require.ErrorContains(t, err, "Build panicked: build failed")
+		require.Equal(t, component.HealthTypeUnhealthy, cn.CurrentHealth().Health)
+
+		history := cn.ErrorsHistory()
+		require.Len(t, history, 1)
+		require.Contains(t, history[0].Stack, "TestPanicRecovery")
+		require.Equal(t, 1.0, testutil.ToFloat64(cn.panics))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		cn := newNode(t, "")
+		require.NoError(t, cn.Evaluate(&vm.Scope{}))
+
+		file, err := parser.ParseFile("", []byte(`testcomponents.panic "a" { panic_on = "Update" }`))
+		require.NoError(t, err)
+		cn.UpdateBlock(file.Body[0].(*ast.BlockStmt))
+		err = cn.Evaluate(&vm.Scope{})
+		require.ErrorContains(t, err, "Update panicked: update failed")
+		require.Equal(t, component.HealthTypeUnhealthy, cn.CurrentHealth().Health)
+		require.Len(t, cn.ErrorsHistory(), 1)
+		require.Equal(t, 1.0, testutil.ToFloat64(cn.panics))
+
+		// The component can still be updated after a panic.
+		file, err = parser.ParseFile("", []byte(`testcomponents.panic "a" {}`))
+		require.NoError(t, err)
+		cn.UpdateBlock(file.Body[0].(*ast.BlockStmt))
+		require.NoError(t, cn.Evaluate(&vm.Scope{}))
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		cn := newNode(t, "Run")
+		require.NoError(t, cn.Evaluate(&vm.Scope{}))
+
+		err := cn.Run(context.Background())
+		require.ErrorContains(t, err, "Run panicked: run failed")
+		require.Equal(t, component.HealthTypeUnhealthy, cn.CurrentHealth().Health)
+
+		history := cn.ErrorsHistory()
+		require.Len(t, history, 1)
+		require.Equal(t, "Run panicked: run failed", history[0].Message)
+		require.NotEmpty(t, history[0].Stack)
+		require.Equal(t, 1.0, testutil.ToFloat64(cn.panics))
+	})
+}
+
+// labelsComponent records the pprof labels of the context it runs with.
+type labelsComponent struct{ labels chan map[string]string }
+
+func (c *labelsComponent) Run(ctx context.Context) error {
+	labels := make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+	c.labels <- labels
+	<-ctx.Done()
+	return nil
+}
+
+func (c *labelsComponent) Update(component.Arguments) error { return nil }
+
+func TestProfileLabels(t *testing.T) {
+	c := &labelsComponent{labels: make(chan map[string]string, 1)}
+	reg := component.Registration{
+		Name: "testcomponents.labels",
+		Args: struct{}{},
+		Build: func(component.Options, component.Arguments) (component.Component, error) {
+			return c, nil
+		},
+	}
+	l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+	globals := ComponentGlobals{
+		Logger:            l,
+		Registerer:        prometheus.NewRegistry(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+		ControllerID:      "module.file.a",
+		NewModuleController: func(id string) ModuleController {
+			return nil
+		},
+	}
+	file, err := parser.ParseFile("", []byte(`testcomponents.labels "a" {}`))
+	require.NoError(t, err)
+	cn := NewBuiltinComponentNode(globals, reg, file.Body[0].(*ast.BlockStmt))
+	require.NoError(t, cn.Evaluate(&vm.Scope{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = cn.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Equal(t, map[string]string{
+		"component_id": "testcomponents.labels.a",
+		"module_path":  "module.file.a",
+	}, <-c.labels)
+}
@@ -9,10 +9,13 @@ import (
 )
 
 const (
-	argumentBlockID = "argument"
-	exportBlockID   = "export"
-	loggingBlockID  = "logging"
-	tracingBlockID  = "tracing"
+	argumentBlockID      = "argument"
+	exportBlockID        = "export"
+	loggingBlockID       = "logging"
+	tracingBlockID       = "tracing"
+	latencyBudgetBlockID = "latency_budget"
+	executionPoolBlockID = "execution_pool"
+	exportLimitBlockID   = "export_limit"
 )
 
 // NewConfigNode creates a new ConfigNode from an initial ast.BlockStmt.
@@ -27,7 +30,14 @@ func NewConfigNode(block *ast.BlockStmt, globals ComponentGlobals) (BlockNode, d
 		return NewLoggingConfigNode(block, globals), nil
 	case tracingBlockID:
 		return NewTracingConfigNode(block, globals), nil
-	case importsource.BlockImportFile, importsource.BlockImportString, importsource.BlockImportHTTP, importsource.BlockImportGit:
+	case latencyBudgetBlockID:
+		return NewLatencyBudgetConfigNode(block, globals), nil
+	case executionPoolBlockID:
+		return NewExecutionPoolConfigNode(block, globals), nil
+	case exportLimitBlockID:
+		return NewExportLimitConfigNode(block, globals), nil
+	case importsource.BlockImportFile, importsource.BlockImportString, importsource.BlockImportHTTP, importsource.BlockImportGit,
+		importsource.BlockImportS3, importsource.BlockImportGCS:
 		return NewImportConfigNode(block, globals, importsource.GetSourceType(block.GetBlockName())), nil
 	default:
 		var diags diag.Diagnostics
@@ -45,22 +55,28 @@ func NewConfigNode(block *ast.BlockStmt, globals ComponentGlobals) (BlockNode, d
 // This is helpful when validating node conditions specific to config node
 // types.
 type ConfigNodeMap struct {
-	logging     *LoggingConfigNode
-	tracing     *TracingConfigNode
-	argumentMap map[string]*ArgumentConfigNode
-	exportMap   map[string]*ExportConfigNode
-	importMap   map[string]*ImportConfigNode
+	logging          *LoggingConfigNode
+	tracing          *TracingConfigNode
+	argumentMap      map[string]*ArgumentConfigNode
+	exportMap        map[string]*ExportConfigNode
+	importMap        map[string]*ImportConfigNode
+	latencyBudgetMap map[string]*LatencyBudgetConfigNode
+	executionPoolMap map[string]*ExecutionPoolConfigNode
+	exportLimitMap   map[string]*ExportLimitConfigNode
 }
 
 // NewConfigNodeMap will create an initial ConfigNodeMap. Append must be called
 // to populate NewConfigNodeMap.
 func NewConfigNodeMap() *ConfigNodeMap {
 	return &ConfigNodeMap{
-		logging:     nil,
-		tracing:     nil,
-		argumentMap: map[string]*ArgumentConfigNode{},
-		exportMap:   map[string]*ExportConfigNode{},
-		importMap:   map[string]*ImportConfigNode{},
+		logging:          nil,
+		tracing:          nil,
+		argumentMap:      map[string]*ArgumentConfigNode{},
+		exportMap:        map[string]*ExportConfigNode{},
+		importMap:        map[string]*ImportConfigNode{},
+		latencyBudgetMap: map[string]*LatencyBudgetConfigNode{},
+		executionPoolMap: map[string]*ExecutionPoolConfigNode{},
+		exportLimitMap:   map[string]*ExportLimitConfigNode{},
 	}
 }
 
@@ -80,6 +96,12 @@ func (nodeMap *ConfigNodeMap) Append(configNode BlockNode) diag.Diagnostics {
 		nodeMap.tracing = n
 	case *ImportConfigNode:
 		nodeMap.importMap[n.Label()] = n
+	case *LatencyBudgetConfigNode:
+		nodeMap.latencyBudgetMap[n.Label()] = n
+	case *ExecutionPoolConfigNode:
+		nodeMap.executionPoolMap[n.Label()] = n
+	case *ExportLimitConfigNode:
+		nodeMap.exportLimitMap[n.Label()] = n
 	default:
 		diags.Add(diag.Diagnostic{
 			Severity: diag.SeverityLevelError,
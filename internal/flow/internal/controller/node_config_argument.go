@@ -19,6 +19,7 @@ type ArgumentConfigNode struct {
 	eval         *vm.Evaluator
 	defaultValue any
 	optional     bool
+	typ          string
 }
 
 var _ BlockNode = (*ArgumentConfigNode)(nil)
@@ -40,6 +41,20 @@ type argumentBlock struct {
 	Optional bool   `river:"optional,attr,optional"`
 	Default  any    `river:"default,attr,optional"`
 	Comment  string `river:"comment,attr,optional"`
+	Type     string `river:"type,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *argumentBlock) SetToDefault() {
+	*a = argumentBlock{Type: argumentTypeAny}
+}
+
+// Validate implements river.Validator.
+func (a *argumentBlock) Validate() error {
+	if _, ok := argumentTypes[a.Type]; !ok {
+		return fmt.Errorf("unsupported type %q: must be one of %s", a.Type, strings.Join(argumentTypeNames, ", "))
+	}
+	return nil
 }
 
 // Evaluate implements BlockNode and updates the arguments for the managed config block
@@ -59,6 +74,7 @@ func (cn *ArgumentConfigNode) Evaluate(scope *vm.Scope) error {
 
 	cn.defaultValue = argument.Default
 	cn.optional = argument.Optional
+	cn.typ = argument.Type
 
 	return nil
 }
@@ -75,6 +91,14 @@ func (cn *ArgumentConfigNode) Default() any {
 	return cn.defaultValue
 }
 
+// Type returns the River type of values accepted by the argument, or "any"
+// if the argument accepts values of any type.
+func (cn *ArgumentConfigNode) Type() string {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.typ
+}
+
 func (cn *ArgumentConfigNode) Label() string { return cn.label }
 
 // Block implements BlockNode and returns the current block of the managed config node.
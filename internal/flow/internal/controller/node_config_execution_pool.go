@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/token"
+	"github.com/grafana/river/vm"
+)
+
+// ExecutionPoolArguments holds the arguments of an execution_pool block.
+type ExecutionPoolArguments struct {
+	// Components are the IDs of the components which belong to the pool.
+	Components []string `river:"components,attr"`
+
+	// MaxWorkers is the number of goroutines which may process data for the
+	// components of the pool concurrently.
+	MaxWorkers int `river:"max_workers,attr"`
+}
+
+// Validate implements river.Validator.
+func (args *ExecutionPoolArguments) Validate() error {
+	if args.MaxWorkers <= 0 {
+		return fmt.Errorf("max_workers must be greater than zero")
+	}
+	return nil
+}
+
+// ExecutionPoolConfigNode is a config node for an execution_pool block.
+//
+// ExecutionPoolConfigNode bounds the number of workers which concurrently
+// process data for the components of the pool.
+type ExecutionPoolConfigNode struct {
+	id            ComponentID
+	label         string
+	nodeID        string
+	componentName string
+
+	mut     sync.RWMutex
+	block   *ast.BlockStmt // Current River blocks to derive config from
+	targets []string       // IDs of the components which belong to the pool
+	eval    *vm.Evaluator
+	args    ExecutionPoolArguments
+
+	// workers holds a token for every worker currently processing data. It's
+	// nil until the block has been successfully evaluated.
+	workers chan struct{}
+}
+
+var _ BlockNode = (*ExecutionPoolConfigNode)(nil)
+
+// NewExecutionPoolConfigNode creates a new ExecutionPoolConfigNode from an
+// initial ast.BlockStmt. The underlying config isn't applied until Evaluate is
+// called.
+func NewExecutionPoolConfigNode(block *ast.BlockStmt, globals ComponentGlobals) *ExecutionPoolConfigNode {
+	id := BlockComponentID(block)
+
+	return &ExecutionPoolConfigNode{
+		id:            id,
+		label:         block.Label,
+		nodeID:        id.String(),
+		componentName: block.GetBlockName(),
+
+		block:   block,
+		targets: executionPoolTargets(block),
+		eval:    vm.New(block.Body),
+	}
+}
+
+// executionPoolTargets returns the IDs of the components which belong to an
+// execution_pool block. The components attribute must be an array of string
+// literals, as it's needed to build the graph before any block is evaluated.
+// nil is returned if the attribute is missing or isn't an array of string
+// literals.
+func executionPoolTargets(b *ast.BlockStmt) []string {
+	for _, stmt := range b.Body {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok || attr.Name.Name != "components" {
+			continue
+		}
+		arr, ok := attr.Value.(*ast.ArrayExpr)
+		if !ok {
+			return nil
+		}
+
+		targets := make([]string, 0, len(arr.Elements))
+		for _, elem := range arr.Elements {
+			lit, ok := elem.(*ast.LiteralExpr)
+			if !ok || lit.Kind != token.STRING {
+				return nil
+			}
+			target, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				return nil
+			}
+			targets = append(targets, target)
+		}
+		return targets
+	}
+	return nil
+}
+
+// Evaluate implements BlockNode and updates the pool by re-evaluating its
+// River block with the provided scope.
+//
+// Changing the number of workers doesn't wait for the workers which are
+// currently processing data; their processing isn't accounted for in the
+// resized pool.
+//
+// Evaluate will return an error if the River block cannot be evaluated or if
+// decoding to arguments fails.
+func (cn *ExecutionPoolConfigNode) Evaluate(scope *vm.Scope) error {
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+
+	var args ExecutionPoolArguments
+	if err := cn.eval.Evaluate(scope, &args); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+	if cn.workers == nil || args.MaxWorkers != cn.args.MaxWorkers {
+		cn.workers = make(chan struct{}, args.MaxWorkers)
+	}
+	cn.args = args
+	return nil
+}
+
+// Acquire blocks until one of the workers of the pool is available or ctx is
+// canceled. Acquire returns immediately if the block hasn't been successfully
+// evaluated.
+func (cn *ExecutionPoolConfigNode) Acquire(ctx context.Context) (release func(), err error) {
+	cn.mut.RLock()
+	workers := cn.workers
+	cn.mut.RUnlock()
+
+	if workers == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case workers <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { <-workers }) }, nil
+}
+
+// Label returns the label of the block.
+func (cn *ExecutionPoolConfigNode) Label() string { return cn.label }
+
+// Targets returns the IDs of the components which belong to the pool, or nil
+// if the components attribute isn't an array of string literals.
+func (cn *ExecutionPoolConfigNode) Targets() []string {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.targets
+}
+
+// Arguments returns the current arguments of the pool.
+func (cn *ExecutionPoolConfigNode) Arguments() ExecutionPoolArguments {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.args
+}
+
+// Block implements BlockNode and returns the current block of the managed config node.
+func (cn *ExecutionPoolConfigNode) Block() *ast.BlockStmt {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.block
+}
+
+// NodeID implements dag.Node and returns the unique ID for the config node.
+func (cn *ExecutionPoolConfigNode) NodeID() string { return cn.nodeID }
+
+// UpdateBlock updates the River block used to construct arguments.
+// The new block isn't used until the next time Evaluate is invoked.
+//
+// UpdateBlock will panic if the block does not match the component ID of the
+// ExecutionPoolConfigNode.
+func (cn *ExecutionPoolConfigNode) UpdateBlock(b *ast.BlockStmt) {
+	if !BlockComponentID(b).Equals(cn.id) {
+		panic("UpdateBlock called with an River block with a different ID")
+	}
+
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+	cn.block = b
+	cn.targets = executionPoolTargets(b)
+	cn.eval = vm.New(b.Body)
+}
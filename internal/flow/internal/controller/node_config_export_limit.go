@@ -0,0 +1,452 @@
+package controller
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/units"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/vm"
+)
+
+// ExportLimitPolicy is what happens to exports which exceed an export limit.
+type ExportLimitPolicy string
+
+const (
+	// ExportLimitReject keeps the previous exports of the component.
+	ExportLimitReject ExportLimitPolicy = "reject"
+	// ExportLimitTruncate drops the trailing elements of the list exports of
+	// the component until the exports fit in the limit.
+	ExportLimitTruncate ExportLimitPolicy = "truncate"
+)
+
+// ExportLimitArguments holds the arguments of an export_limit block.
+type ExportLimitArguments struct {
+	// Component is the ID of the component the limit applies to.
+	Component string `river:"component,attr"`
+
+	// MaxSize is the largest the encoded exports of the component may be.
+	MaxSize units.Base2Bytes `river:"max_size,attr"`
+
+	// Policy is what happens to exports which are larger than MaxSize.
+	Policy ExportLimitPolicy `river:"policy,attr,optional"`
+}
+
+// DefaultExportLimitArguments holds default settings for ExportLimitArguments.
+var DefaultExportLimitArguments = ExportLimitArguments{
+	Policy: ExportLimitReject,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *ExportLimitArguments) SetToDefault() {
+	*args = DefaultExportLimitArguments
+}
+
+// Validate implements river.Validator.
+func (args *ExportLimitArguments) Validate() error {
+	if args.MaxSize <= 0 {
+		return fmt.Errorf("max_size must be greater than zero")
+	}
+	switch args.Policy {
+	case ExportLimitReject, ExportLimitTruncate:
+		return nil
+	}
+	return fmt.Errorf("unknown policy %q, must be one of %s, %s", args.Policy, ExportLimitReject, ExportLimitTruncate)
+}
+
+// ExportLimitConfigNode is a config node for an export_limit block.
+type ExportLimitConfigNode struct {
+	id            ComponentID
+	label         string
+	nodeID        string
+	componentName string
+
+	mut    sync.RWMutex
+	block  *ast.BlockStmt // Current River blocks to derive config from
+	target string         // ID of the component the limit applies to
+	eval   *vm.Evaluator
+	args   ExportLimitArguments
+}
+
+var _ BlockNode = (*ExportLimitConfigNode)(nil)
+
+// NewExportLimitConfigNode creates a new ExportLimitConfigNode from an initial
+// ast.BlockStmt. The underlying config isn't applied until Evaluate is called.
+func NewExportLimitConfigNode(block *ast.BlockStmt, globals ComponentGlobals) *ExportLimitConfigNode {
+	id := BlockComponentID(block)
+
+	return &ExportLimitConfigNode{
+		id:            id,
+		label:         block.Label,
+		nodeID:        id.String(),
+		componentName: block.GetBlockName(),
+
+		block:  block,
+		target: targetComponent(block),
+		eval:   vm.New(block.Body),
+	}
+}
+
+// Evaluate implements BlockNode and updates the limit by re-evaluating its
+// River block with the provided scope.
+//
+// Evaluate will return an error if the River block cannot be evaluated or if
+// decoding to arguments fails.
+func (cn *ExportLimitConfigNode) Evaluate(scope *vm.Scope) error {
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+
+	var args ExportLimitArguments
+	if err := cn.eval.Evaluate(scope, &args); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+	cn.args = args
+	return nil
+}
+
+// Label returns the label of the block.
+func (cn *ExportLimitConfigNode) Label() string { return cn.label }
+
+// Target returns the ID of the component the limit applies to, or an empty
+// string if the component attribute isn't a string literal.
+func (cn *ExportLimitConfigNode) Target() string {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.target
+}
+
+// Arguments returns the current limit.
+func (cn *ExportLimitConfigNode) Arguments() ExportLimitArguments {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.args
+}
+
+// Limit checks e against the limit. It returns the exports to use in place of
+// e and a nil error if e fits in the limit or could be truncated to fit in
+// it. truncated reports whether the returned exports were truncated.
+//
+// Limit always accepts e if the block hasn't been successfully evaluated.
+func (cn *ExportLimitConfigNode) Limit(e component.Exports) (res component.Exports, truncated bool, err error) {
+	args := cn.Arguments()
+	if args.MaxSize <= 0 {
+		return e, false, nil
+	}
+	return limitExports(e, int(args.MaxSize), args.Policy)
+}
+
+// limitExports implements ExportLimitConfigNode.Limit.
+func limitExports(e component.Exports, maxSize int, policy ExportLimitPolicy) (component.Exports, bool, error) {
+	if _, ok := exportsSize(e, maxSize); ok {
+		return e, false, nil
+	}
+	exceeded := fmt.Errorf("exports exceed the export limit of %s", units.Base2Bytes(maxSize))
+	if policy != ExportLimitTruncate {
+		return nil, false, exceeded
+	}
+
+	// Only the top-level lists of the exports are truncated, by keeping the
+	// largest fraction of the elements of every list with which the exports
+	// fit.
+	rv := reflect.ValueOf(e)
+	if rv.Kind() != reflect.Struct {
+		return nil, false, fmt.Errorf("%w and can't be truncated", exceeded)
+	}
+
+	var (
+		fixed = 2               // Size of the exports once their lists are empty.
+		lists = map[int][]int{} // Field index -> size of the first N elements.
+	)
+	for i := 0; i < rv.NumField(); i++ {
+		tag, ok := riverTag(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		value := rv.Field(i)
+		if value.Kind() != reflect.Slice || value.Len() == 0 {
+			se := sizeEstimator{max: math.MaxInt}
+			se.statement(tag, value)
+			fixed += se.size + 1
+			continue
+		}
+
+		if !tag.block {
+			fixed += 1 + attrSize(tag.name) + valueSize("array") + 2
+		}
+		prefix := make([]int, value.Len()+1)
+		for j := 0; j < value.Len(); j++ {
+			se := sizeEstimator{max: math.MaxInt}
+			if tag.block {
+				se.statement(tag, value.Index(j))
+			} else {
+				se.value(value.Index(j))
+			}
+			prefix[j+1] = prefix[j] + se.size + 1
+		}
+		lists[i] = prefix
+	}
+	if len(lists) == 0 || fixed > maxSize {
+		return nil, false, fmt.Errorf("%w and can't be truncated to fit", exceeded)
+	}
+
+	keep := func(i int, frac float64) int { return int(float64(len(lists[i])-1) * frac) }
+	fits := func(frac float64) bool {
+		size := fixed
+		for i, prefix := range lists {
+			size += prefix[keep(i, frac)]
+		}
+		return size <= maxSize
+	}
+	lo, hi := 0.0, 1.0
+	for n := 0; n < 50; n++ {
+		if mid := (lo + hi) / 2; fits(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	res := reflect.New(rv.Type()).Elem()
+	res.Set(rv)
+	for i := range lists {
+		field := res.Field(i)
+		field.Set(field.Slice(0, keep(i, lo)))
+	}
+	return res.Interface(), true, nil
+}
+
+// exportsSize estimates the size of the JSON encoding of e, which is used by
+// the API and the UI. The estimate stops once it exceeds maxSize, in which
+// case ok is false.
+func exportsSize(e component.Exports, maxSize int) (size int, ok bool) {
+	se := sizeEstimator{max: maxSize}
+	ok = se.body(reflect.ValueOf(e))
+	return se.size, ok
+}
+
+// capsuleSize is the estimated size of the description of capsule values,
+// such as the receivers of a component.
+const capsuleSize = 32
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// sizeEstimator estimates the size of the JSON encoding of River bodies and
+// values, following the layout of riverjson, without allocating the
+// encoding. The estimate stops once it exceeds max.
+type sizeEstimator struct {
+	size, max int
+}
+
+func (se *sizeEstimator) add(n int) bool {
+	se.size += n
+	return se.size <= se.max
+}
+
+// body estimates a struct or a map encoded as a list of statements.
+func (se *sizeEstimator) body(v reflect.Value) bool {
+	v = indirect(v)
+	if !se.add(2) {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			tag, ok := riverTag(v.Type().Field(i))
+			if !ok || (tag.optional && v.Field(i).IsZero()) {
+				continue
+			}
+			if !se.add(1) || !se.statement(tag, v.Field(i)) {
+				return false
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if !se.add(1+attrSize(iter.Key().String())) || !se.value(iter.Value()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// statement estimates the attribute or blocks of a struct field.
+func (se *sizeEstimator) statement(tag fieldTag, v reflect.Value) bool {
+	if !tag.block {
+		return se.add(attrSize(tag.name)) && se.value(v)
+	}
+	v = indirect(v)
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			if !se.add(1) || !se.statement(tag, v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	// {"name":"NAME","type":"block","body":BODY}, ignoring the label.
+	return se.add(len(tag.name)+34) && se.body(v)
+}
+
+// value estimates a River value.
+func (se *sizeEstimator) value(v reflect.Value) bool {
+	if v.IsValid() && v.Type().Implements(textMarshalerType) && v.CanInterface() && (v.Kind() != reflect.Pointer || !v.IsNil()) {
+		if text, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return se.add(valueSize("string") + len(text) + 2)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return se.add(valueSize("null") + 4)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return se.add(valueSize("null") + 4)
+		}
+		return se.value(v.Elem())
+	case reflect.String:
+		return se.add(valueSize("string") + len(v.String()) + 2)
+	case reflect.Bool:
+		return se.add(valueSize("bool") + 5)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return se.add(valueSize("number") + len(strconv.FormatInt(v.Int(), 10)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return se.add(valueSize("number") + len(strconv.FormatUint(v.Uint(), 10)))
+	case reflect.Float32, reflect.Float64:
+		return se.add(valueSize("number") + len(strconv.FormatFloat(v.Float(), 'g', -1, 64)))
+	case reflect.Slice, reflect.Array:
+		if !se.add(valueSize("array") + 2) {
+			return false
+		}
+		for i := 0; i < v.Len(); i++ {
+			if !se.add(1) || !se.value(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if !se.add(valueSize("object") + 2) {
+			return false
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if !se.add(1+keySize(iter.Key().String())) || !se.value(iter.Value()) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if !hasRiverTags(v.Type()) {
+			break
+		}
+		if !se.add(valueSize("object") + 2) {
+			return false
+		}
+		for i := 0; i < v.NumField(); i++ {
+			tag, ok := riverTag(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if !se.add(1+keySize(tag.name)) || !se.value(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return se.add(valueSize("capsule") + capsuleSize)
+}
+
+// attrSize is the size of an attribute statement, without its value:
+// {"name":"NAME","type":"attr","value":VALUE}
+func attrSize(name string) int { return len(name) + 34 }
+
+// valueSize is the size of a value of type typ, without the value itself:
+// {"type":"TYPE","value":VALUE}
+func valueSize(typ string) int { return len(typ) + 20 }
+
+// keySize is the size of a field of an object, without its value:
+// {"key":"KEY","value":VALUE}
+func keySize(key string) int { return len(key) + 19 }
+
+// fieldTag is the river tag of a struct field.
+type fieldTag struct {
+	name            string
+	block, optional bool
+}
+
+// riverTag returns the river tag of field. ok is false if field isn't
+// encoded.
+func riverTag(field reflect.StructField) (tag fieldTag, ok bool) {
+	text, ok := field.Tag.Lookup("river")
+	if !ok || !field.IsExported() {
+		return fieldTag{}, false
+	}
+	name, flags, _ := strings.Cut(text, ",")
+	for _, flag := range strings.Split(flags, ",") {
+		switch flag {
+		case "block", "enum":
+			tag.block = true
+		case "optional":
+			tag.optional = true
+		case "label":
+			return fieldTag{}, false
+		}
+	}
+	tag.name = name
+	return tag, true
+}
+
+// hasRiverTags returns true if any field of the struct type t has a river
+// tag. Other structs are encoded as capsules.
+func hasRiverTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("river"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// indirect dereferences the pointers to v.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// Block implements BlockNode and returns the current block of the managed config node.
+func (cn *ExportLimitConfigNode) Block() *ast.BlockStmt {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.block
+}
+
+// NodeID implements dag.Node and returns the unique ID for the config node.
+func (cn *ExportLimitConfigNode) NodeID() string { return cn.nodeID }
+
+// UpdateBlock updates the River block used to construct arguments.
+// The new block isn't used until the next time Evaluate is invoked.
+//
+// UpdateBlock will panic if the block does not match the component ID of the
+// ExportLimitConfigNode.
+func (cn *ExportLimitConfigNode) UpdateBlock(b *ast.BlockStmt) {
+	if !BlockComponentID(b).Equals(cn.id) {
+		panic("UpdateBlock called with an River block with a different ID")
+	}
+
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+	cn.block = b
+	cn.target = targetComponent(b)
+	cn.eval = vm.New(b.Body)
+}
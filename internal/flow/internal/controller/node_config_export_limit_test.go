@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/grafana/river/encoding/riverjson"
+	"github.com/stretchr/testify/require"
+)
+
+type listExports struct {
+	Name    string   `river:"name,attr"`
+	Targets []string `river:"targets,attr"`
+}
+
+func TestLimitExports(t *testing.T) {
+	targets := make([]string, 100)
+	for i := range targets {
+		targets[i] = fmt.Sprintf("target-%02d", i)
+	}
+	e := listExports{Name: "pods", Targets: targets}
+	size, ok := exportsSize(e, math.MaxInt)
+	require.True(t, ok)
+
+	t.Run("Within limit", func(t *testing.T) {
+		res, truncated, err := limitExports(e, size, ExportLimitReject)
+		require.NoError(t, err)
+		require.False(t, truncated)
+		require.Equal(t, e, res)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		_, _, err := limitExports(e, size/2, ExportLimitReject)
+		require.ErrorContains(t, err, "exceed the export limit of")
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		res, truncated, err := limitExports(e, size/2, ExportLimitTruncate)
+		require.NoError(t, err)
+		require.True(t, truncated)
+
+		limited := res.(listExports)
+		require.Equal(t, "pods", limited.Name)
+		require.NotEmpty(t, limited.Targets)
+		require.Less(t, len(limited.Targets), len(targets))
+		require.Equal(t, targets[:len(limited.Targets)], limited.Targets)
+
+		limitedSize, ok := exportsSize(limited, size/2)
+		require.True(t, ok)
+		require.Greater(t, limitedSize, size/2-20, "truncation should keep as many targets as fit")
+
+		// The original exports are left untouched.
+		require.Len(t, e.Targets, 100)
+	})
+
+	t.Run("Too large to truncate", func(t *testing.T) {
+		_, _, err := limitExports(e, 10, ExportLimitTruncate)
+		require.ErrorContains(t, err, "can't be truncated to fit")
+	})
+}
+
+type nestedExports struct {
+	Labels   map[string]string `river:"labels,attr"`
+	Enabled  bool              `river:"enabled,attr,optional"`
+	Port     int               `river:"port,attr"`
+	Receiver any               `river:"receiver,attr"`
+	Inner    []listExports     `river:"inner,block"`
+}
+
+func TestExportsSize(t *testing.T) {
+	e := nestedExports{
+		Labels:   map[string]string{"namespace": "default", "pod": "app-0"},
+		Port:     8080,
+		Receiver: &sync.Mutex{},
+		Inner: []listExports{
+			{Name: "a", Targets: []string{"target-0", "target-1"}},
+			{Name: "b", Targets: []string{"target-2"}},
+		},
+	}
+	bb, err := riverjson.MarshalBody(e)
+	require.NoError(t, err)
+
+	// The estimate is close to the size of the encoding.
+	size, ok := exportsSize(e, math.MaxInt)
+	require.True(t, ok)
+	require.InEpsilon(t, len(bb), size, 0.1)
+
+	// The estimate stops once it exceeds the limit.
+	targets := make([]string, 10000)
+	size, ok = exportsSize(listExports{Targets: targets}, 100)
+	require.False(t, ok)
+	require.Less(t, size, 200)
+}
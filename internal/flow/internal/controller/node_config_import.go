@@ -1,12 +1,14 @@
 package controller
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"hash/fnv"
 	"maps"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,10 +19,12 @@ import (
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/flow/internal/importsource"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/flow/modulelock"
 	"github.com/grafana/agent/internal/flow/tracing"
 	"github.com/grafana/agent/internal/runner"
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/parser"
+	"github.com/grafana/river/printer"
 	"github.com/grafana/river/vm"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -51,6 +55,17 @@ type ImportConfigNode struct {
 	importConfigNodesChildren map[string]*ImportConfigNode
 	importChildrenRunning     bool
 	importedDeclares          map[string]ast.Body
+	lockErr                   error                  // Error from checking the last content against the module lockfile.
+	moduleSource              component.ModuleSource // Source of the last accepted content.
+
+	// notifiedDeclares and notifiedImports hold the printed declare and
+	// import blocks of the content last sent to the controller, to find which
+	// declares changed on the next content update.
+	notifiedDeclares map[string]string
+	notifiedImports  map[string]string
+
+	changesMut     sync.Mutex
+	pendingChanges declareChanges // Changes not yet taken by the controller.
 
 	healthMut     sync.RWMutex
 	evalHealth    component.Health // Health of the last source evaluation
@@ -175,6 +190,13 @@ func (cn *ImportConfigNode) CurrentHealth() component.Health {
 // Evaluate implements BlockNode and evaluates the import source.
 func (cn *ImportConfigNode) Evaluate(scope *vm.Scope) error {
 	err := cn.source.Evaluate(scope)
+	if err == nil {
+		// Content refused by the module lockfile must fail the evaluation, so
+		// that the modules aren't silently missing.
+		cn.mut.RLock()
+		err = cn.lockErr
+		cn.mut.RUnlock()
+	}
 	switch err {
 	case nil:
 		cn.setEvalHealth(component.HealthTypeHealthy, "source evaluated")
@@ -193,6 +215,19 @@ func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 	cn.inContentUpdate.Store(true)
 	defer cn.inContentUpdate.Store(false)
 
+	entry := cn.moduleLockEntry(importedContent)
+	cn.lockErr = cn.globals.ModuleLock.Check(cn.globalID, entry)
+	if cn.lockErr != nil {
+		level.Error(cn.logger).Log("msg", "refusing imported content", "err", cn.lockErr)
+		cn.setContentHealth(component.HealthTypeUnhealthy, cn.lockErr.Error())
+		return
+	}
+	cn.moduleSource = component.ModuleSource{
+		Location: cn.source.Location(),
+		Version:  entry.Version,
+		SHA256:   entry.SHA256,
+	}
+
 	// If the source sent the same content, there is no need to reload.
 	if maps.Equal(cn.importedContent, importedContent) {
 		return
@@ -238,10 +273,147 @@ func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 		}
 	}
 
+	cn.recordDeclareChanges()
+
 	cn.setContentHealth(component.HealthTypeHealthy, "content updated")
 	cn.OnBlockNodeUpdate(cn)
 }
 
+// moduleLockEntry returns the entry of the imported content in the module
+// lockfile.
+func (cn *ImportConfigNode) moduleLockEntry(importedContent map[string]string) modulelock.Entry {
+	entry := modulelock.Entry{
+		Block:  cn.componentName,
+		SHA256: importsource.ContentDigest(importedContent),
+	}
+	if versioned, ok := cn.source.(importsource.VersionedImportSource); ok {
+		entry.Version = versioned.ResolvedVersion()
+	}
+	return entry
+}
+
+// declareChanges describes which imported declares changed since the
+// controller last reevaluated the custom components using them.
+type declareChanges struct {
+	all      bool                // All declares must be considered changed.
+	declares map[string]struct{} // Labels of the changed declares.
+}
+
+// affects returns true if the custom component cc, instantiating a declare
+// of the import node called importLabel, must be reevaluated.
+func (c declareChanges) affects(importLabel string, cc *CustomComponentNode) bool {
+	if c.all || cc.importNamespace != importLabel {
+		return true
+	}
+	_, ok := c.declares[cc.customComponentName]
+	return ok
+}
+
+// recordDeclareChanges compares the imported declares with the ones last
+// sent to the controller and adds the changes to the pending changes. A
+// change to an import block marks every declare as changed, as declares may
+// use any declare of nested imports. The caller must hold mut.
+func (cn *ImportConfigNode) recordDeclareChanges() {
+	declares := make(map[string]string, len(cn.importedDeclares))
+	for label, body := range cn.importedDeclares {
+		declares[label] = printNode(body)
+	}
+	imports := make(map[string]string, len(cn.importConfigNodesChildren))
+	for label, child := range cn.importConfigNodesChildren {
+		imports[label] = printNode(child.block)
+	}
+
+	changed := make(map[string]struct{})
+	for label, src := range declares {
+		if old, ok := cn.notifiedDeclares[label]; !ok || old != src {
+			changed[label] = struct{}{}
+		}
+	}
+	for label := range cn.notifiedDeclares {
+		if _, ok := declares[label]; !ok {
+			changed[label] = struct{}{} // Removed declares are changed too.
+		}
+	}
+	addDependentDeclares(cn.importedDeclares, changed)
+
+	cn.changesMut.Lock()
+	if !maps.Equal(imports, cn.notifiedImports) {
+		cn.pendingChanges.all = true
+	}
+	if cn.pendingChanges.declares == nil {
+		cn.pendingChanges.declares = make(map[string]struct{})
+	}
+	for label := range changed {
+		cn.pendingChanges.declares[label] = struct{}{}
+	}
+	cn.changesMut.Unlock()
+
+	if len(changed) > 0 {
+		labels := make([]string, 0, len(changed))
+		for label := range changed {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		level.Info(cn.logger).Log("msg", "imported declares changed", "declares", strings.Join(labels, ","))
+	}
+	cn.notifiedDeclares = declares
+	cn.notifiedImports = imports
+}
+
+// takeDeclareChanges returns the pending changes and resets them.
+func (cn *ImportConfigNode) takeDeclareChanges() declareChanges {
+	cn.changesMut.Lock()
+	defer cn.changesMut.Unlock()
+
+	changes := cn.pendingChanges
+	cn.pendingChanges = declareChanges{}
+	return changes
+}
+
+// addDependentDeclares adds to changed the declares of declares which
+// instantiate a changed declare, directly or transitively.
+func addDependentDeclares(declares map[string]ast.Body, changed map[string]struct{}) {
+	for {
+		added := false
+		for label, body := range declares {
+			if _, ok := changed[label]; ok {
+				continue
+			}
+			if instantiatesAny(body, changed) {
+				changed[label] = struct{}{}
+				added = true
+			}
+		}
+		if !added {
+			return
+		}
+	}
+}
+
+// instantiatesAny returns true if body, or a declare nested in body,
+// instantiates a declare called by any of the names of declares.
+func instantiatesAny(body ast.Body, declares map[string]struct{}) bool {
+	for _, stmt := range body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+		if _, ok := declares[block.GetBlockName()]; ok {
+			return true
+		}
+		if (block.GetBlockName() == declareType || block.GetBlockName() == foreachBlockID) && instantiatesAny(block.Body, declares) {
+			return true
+		}
+	}
+	return false
+}
+
+func printNode(node ast.Node) string {
+	var buf bytes.Buffer
+	_ = printer.Fprint(&buf, node)
+	return buf.String()
+}
+
 // processImportedContent processes declare and import blocks of the provided ast content.
 func (cn *ImportConfigNode) processImportedContent(content *ast.File) error {
 	for _, stmt := range content.Body {
@@ -254,7 +426,8 @@ func (cn *ImportConfigNode) processImportedContent(content *ast.File) error {
 		switch componentName {
 		case declareType:
 			cn.processDeclareBlock(blockStmt)
-		case importsource.BlockImportFile, importsource.BlockImportString, importsource.BlockImportHTTP, importsource.BlockImportGit:
+		case importsource.BlockImportFile, importsource.BlockImportString, importsource.BlockImportHTTP, importsource.BlockImportGit,
+			importsource.BlockImportS3, importsource.BlockImportGCS:
 			err := cn.processImportBlock(blockStmt, componentName)
 			if err != nil {
 				return err
@@ -307,6 +480,12 @@ func (cn *ImportConfigNode) onChildrenContentUpdate(child BlockNode) {
 	// If the node is already updating its content, it will call OnBlockNodeUpdate
 	// so the notification can be ignored.
 	if !cn.inContentUpdate.Load() {
+		// Any declare may use the declares of the child, so they must all be
+		// reevaluated.
+		cn.changesMut.Lock()
+		cn.pendingChanges.all = true
+		cn.changesMut.Unlock()
+
 		cn.OnBlockNodeUpdate(cn)
 	}
 }
@@ -415,6 +594,14 @@ func (cn *ImportConfigNode) Block() *ast.BlockStmt { return cn.block }
 // NodeID implements dag.Node and returns the unique ID for the config node.
 func (cn *ImportConfigNode) NodeID() string { return cn.nodeID }
 
+// ModuleSource returns the source of the last content accepted by the node.
+// The Imports and Declare fields of the returned source aren't set.
+func (cn *ImportConfigNode) ModuleSource() component.ModuleSource {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.moduleSource
+}
+
 // ImportedDeclares returns all declare blocks that it imported.
 func (cn *ImportConfigNode) ImportedDeclares() map[string]ast.Body {
 	cn.mut.RLock()
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportConfigNode_DeclareChanges(t *testing.T) {
+	cn := &ImportConfigNode{label: "mod", logger: log.NewNopLogger()}
+	record := func(src string) {
+		t.Helper()
+		file, err := parser.ParseFile("", []byte(src))
+		require.NoError(t, err)
+
+		cn.importedDeclares = make(map[string]ast.Body)
+		cn.importConfigNodesChildren = make(map[string]*ImportConfigNode)
+		for _, stmt := range file.Body {
+			block := stmt.(*ast.BlockStmt)
+			switch block.GetBlockName() {
+			case declareType:
+				cn.importedDeclares[block.Label] = block.Body
+			default:
+				cn.importConfigNodesChildren[block.Label] = &ImportConfigNode{block: block}
+			}
+		}
+		cn.recordDeclareChanges()
+	}
+	update := func(src string) declareChanges {
+		t.Helper()
+		record(src)
+		return cn.takeDeclareChanges()
+	}
+	affected := func(changes declareChanges, componentName string) bool {
+		namespace, name := ExtractImportAndDeclare(componentName)
+		return changes.affects(cn.label, &CustomComponentNode{importNamespace: namespace, customComponentName: name})
+	}
+
+	changes := update(`
+		declare "a" { }
+		declare "b" { a "x" { } }
+		declare "c" { }
+	`)
+	require.False(t, changes.all)
+	require.Equal(t, map[string]struct{}{"a": {}, "b": {}, "c": {}}, changes.declares)
+
+	// Changing a declare changes the declares instantiating it.
+	changes = update(`
+		declare "a" {
+			argument "x" { }
+		}
+		declare "b" { a "x" { } }
+		declare "c" { }
+	`)
+	require.Equal(t, map[string]struct{}{"a": {}, "b": {}}, changes.declares)
+	require.True(t, affected(changes, "mod.b"))
+	require.False(t, affected(changes, "mod.c"))
+	require.True(t, affected(changes, "other.c"))
+
+	// Removed declares are changed.
+	changes = update(`
+		declare "a" {
+			argument "x" { }
+		}
+		declare "b" { a "x" { } }
+	`)
+	require.Equal(t, map[string]struct{}{"c": {}}, changes.declares)
+
+	// Changes accumulate until they're taken.
+	record(`
+		declare "a" { }
+		declare "b" { a "x" { } }
+	`)
+	changes = update(`
+		declare "a" { }
+		declare "b" { }
+	`)
+	require.Equal(t, map[string]struct{}{"a": {}, "b": {}}, changes.declares)
+
+	// Changing an import block changes every declare.
+	changes = update(`
+		declare "a" {
+			argument "x" { }
+		}
+		declare "b" { a "x" { } }
+		import.string "nested" {
+			content = ""
+		}
+	`)
+	require.True(t, changes.all)
+	require.True(t, affected(changes, "mod.a"))
+}
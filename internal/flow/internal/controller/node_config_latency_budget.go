@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/token"
+	"github.com/grafana/river/vm"
+)
+
+// LatencyBudgetArguments holds the arguments of a latency_budget block.
+type LatencyBudgetArguments struct {
+	// Component is the ID of the component the budget applies to.
+	Component string `river:"component,attr"`
+
+	// MaxEvaluationTime is the longest a single evaluation of the component is
+	// expected to take.
+	MaxEvaluationTime time.Duration `river:"max_evaluation_time,attr"`
+
+	// MaxViolations is the number of consecutive evaluations which may exceed
+	// MaxEvaluationTime before the component is marked as unhealthy.
+	MaxViolations int `river:"max_violations,attr,optional"`
+
+	// RecoveryEvaluations is the number of consecutive evaluations which must
+	// meet MaxEvaluationTime before an unhealthy component is marked as healthy
+	// again.
+	RecoveryEvaluations int `river:"recovery_evaluations,attr,optional"`
+}
+
+// DefaultLatencyBudgetArguments holds default settings for
+// LatencyBudgetArguments.
+var DefaultLatencyBudgetArguments = LatencyBudgetArguments{
+	MaxViolations:       3,
+	RecoveryEvaluations: 3,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *LatencyBudgetArguments) SetToDefault() {
+	*args = DefaultLatencyBudgetArguments
+}
+
+// Validate implements river.Validator.
+func (args *LatencyBudgetArguments) Validate() error {
+	if args.MaxEvaluationTime <= 0 {
+		return fmt.Errorf("max_evaluation_time must be greater than zero")
+	}
+	if args.MaxViolations <= 0 {
+		return fmt.Errorf("max_violations must be greater than zero")
+	}
+	if args.RecoveryEvaluations <= 0 {
+		return fmt.Errorf("recovery_evaluations must be greater than zero")
+	}
+	return nil
+}
+
+// LatencyBudgetConfigNode is a config node for a latency_budget block.
+//
+// LatencyBudgetConfigNode tracks how many consecutive evaluations of the
+// component exceeded or met the budget.
+type LatencyBudgetConfigNode struct {
+	id            ComponentID
+	label         string
+	nodeID        string
+	componentName string
+
+	mut        sync.RWMutex
+	block      *ast.BlockStmt // Current River blocks to derive config from
+	target     string         // ID of the component the budget applies to
+	eval       *vm.Evaluator
+	args       LatencyBudgetArguments
+	violations int // Number of consecutive evaluations which exceeded the budget.
+	recoveries int // Number of consecutive evaluations which met the budget.
+}
+
+var _ BlockNode = (*LatencyBudgetConfigNode)(nil)
+
+// NewLatencyBudgetConfigNode creates a new LatencyBudgetConfigNode from an
+// initial ast.BlockStmt. The underlying config isn't applied until Evaluate is
+// called.
+func NewLatencyBudgetConfigNode(block *ast.BlockStmt, globals ComponentGlobals) *LatencyBudgetConfigNode {
+	id := BlockComponentID(block)
+
+	return &LatencyBudgetConfigNode{
+		id:            id,
+		label:         block.Label,
+		nodeID:        id.String(),
+		componentName: block.GetBlockName(),
+
+		block:  block,
+		target: targetComponent(block),
+		eval:   vm.New(block.Body),
+	}
+}
+
+// targetComponent returns the ID of the component a latency_budget or
+// export_limit block applies to. The component attribute must be a string literal, as it's
+// needed to build the graph before any block is evaluated. An empty string is
+// returned if the attribute is missing or isn't a string literal.
+func targetComponent(b *ast.BlockStmt) string {
+	for _, stmt := range b.Body {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok || attr.Name.Name != "component" {
+			continue
+		}
+		lit, ok := attr.Value.(*ast.LiteralExpr)
+		if !ok || lit.Kind != token.STRING {
+			return ""
+		}
+		target, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return ""
+		}
+		return target
+	}
+	return ""
+}
+
+// Evaluate implements BlockNode and updates the budget by re-evaluating its
+// River block with the provided scope. Changing the budget resets the
+// number of tracked violations and recoveries.
+//
+// Evaluate will return an error if the River block cannot be evaluated or if
+// decoding to arguments fails.
+func (cn *LatencyBudgetConfigNode) Evaluate(scope *vm.Scope) error {
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+
+	var args LatencyBudgetArguments
+	if err := cn.eval.Evaluate(scope, &args); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+	if args != cn.args {
+		cn.violations, cn.recoveries = 0, 0
+	}
+	cn.args = args
+	return nil
+}
+
+// Label returns the label of the block.
+func (cn *LatencyBudgetConfigNode) Label() string { return cn.label }
+
+// Target returns the ID of the component the budget applies to, or an empty
+// string if the component attribute isn't a string literal.
+func (cn *LatencyBudgetConfigNode) Target() string {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.target
+}
+
+// Arguments returns the current budget.
+func (cn *LatencyBudgetConfigNode) Arguments() LatencyBudgetArguments {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.args
+}
+
+// Observe records an evaluation of the component which took duration. It
+// returns whether the evaluation exceeded the budget and the number of
+// consecutive evaluations, including this one, which exceeded it or met it
+// like this one.
+//
+// Observe always reports the budget as met if the block hasn't been
+// successfully evaluated.
+func (cn *LatencyBudgetConfigNode) Observe(duration time.Duration) (exceeded bool, streak int) {
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+
+	if cn.args.MaxEvaluationTime <= 0 || duration <= cn.args.MaxEvaluationTime {
+		cn.violations = 0
+		cn.recoveries++
+		return false, cn.recoveries
+	}
+	cn.recoveries = 0
+	cn.violations++
+	return true, cn.violations
+}
+
+// Block implements BlockNode and returns the current block of the managed config node.
+func (cn *LatencyBudgetConfigNode) Block() *ast.BlockStmt {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.block
+}
+
+// NodeID implements dag.Node and returns the unique ID for the config node.
+func (cn *LatencyBudgetConfigNode) NodeID() string { return cn.nodeID }
+
+// UpdateBlock updates the River block used to construct arguments.
+// The new block isn't used until the next time Evaluate is invoked.
+//
+// UpdateBlock will panic if the block does not match the component ID of the
+// LatencyBudgetConfigNode.
+func (cn *LatencyBudgetConfigNode) UpdateBlock(b *ast.BlockStmt) {
+	if !BlockComponentID(b).Equals(cn.id) {
+		panic("UpdateBlock called with an River block with a different ID")
+	}
+
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+	cn.block = b
+	cn.target = targetComponent(b)
+	cn.eval = vm.New(b.Body)
+}
@@ -38,11 +38,15 @@ type CustomComponentNode struct {
 
 	getConfig getCustomComponentConfig // Retrieve the custom component config.
 
-	mut     sync.RWMutex
-	block   *ast.BlockStmt // Current River block to derive args from
-	eval    *vm.Evaluator
-	managed CustomComponent     // Inner managed custom component
-	args    component.Arguments // Evaluated arguments for the managed component
+	mut        sync.RWMutex
+	block      *ast.BlockStmt // Current River block to derive args from
+	eval       *vm.Evaluator
+	metaBlocks []*ast.BlockStmt        // Meta blocks of the current River block
+	managed    CustomComponent         // Inner managed custom component
+	args       component.Arguments     // Evaluated arguments for the managed component
+	source     *component.ModuleSource // Imported module defining the custom component, if any
+
+	meta *componentMeta // Metadata labels from the meta block
 
 	// NOTE(rfratto): health and exports have their own mutex because they may be
 	// set asynchronously while mut is still being held (i.e., when calling Evaluate
@@ -52,6 +56,9 @@ type CustomComponentNode struct {
 	evalHealth component.Health // Health of the last evaluate
 	runHealth  component.Health // Health of running the component
 
+	exportsHistory *exportsHistory // Past exports of the managed component
+	errorsHistory  *errorsHistory  // Past errors of the managed component
+
 	exportsMut sync.RWMutex
 	exports    component.Exports // Evaluated exports for the managed custom component
 }
@@ -105,6 +112,11 @@ func NewCustomComponentNode(globals ComponentGlobals, b *ast.BlockStmt, getConfi
 	componentName := b.GetBlockName()
 	importNamespace, customComponentName := ExtractImportAndDeclare(componentName)
 
+	var (
+		body, metaBlocks = splitMetaBlocks(b.Body)
+		meta             = &componentMeta{}
+	)
+
 	cn := &CustomComponentNode{
 		id:                  id,
 		globalID:            globalID,
@@ -115,14 +127,19 @@ func NewCustomComponentNode(globals ComponentGlobals, b *ast.BlockStmt, getConfi
 		customComponentName: customComponentName,
 		moduleController:    globals.NewModuleController(globalID),
 		OnBlockNodeUpdate:   globals.OnBlockNodeUpdate,
-		logger:              log.With(globals.Logger, "component", globalID),
+		logger:              meta.Logger(log.With(globals.Logger, "component", globalID)),
 		getConfig:           getConfig,
 
-		block: b,
-		eval:  vm.New(b.Body),
+		block:      b,
+		eval:       vm.New(body),
+		metaBlocks: metaBlocks,
+		meta:       meta,
 
 		evalHealth: initHealth,
 		runHealth:  initHealth,
+
+		exportsHistory: newExportsHistory(DefaultExportsHistoryEntries, DefaultExportsHistoryBytes),
+		errorsHistory:  newErrorsHistory(DefaultErrorsHistoryEntries),
 	}
 
 	return cn
@@ -150,10 +167,13 @@ func (cn *CustomComponentNode) UpdateBlock(b *ast.BlockStmt) {
 		panic("UpdateBlock called with an River block with a different component ID")
 	}
 
+	body, metaBlocks := splitMetaBlocks(b.Body)
+
 	cn.mut.Lock()
 	defer cn.mut.Unlock()
 	cn.block = b
-	cn.eval = vm.New(b.Body)
+	cn.eval = vm.New(body)
+	cn.metaBlocks = metaBlocks
 }
 
 // Evaluate implements BlockNode and updates the arguments by re-evaluating its River block with the provided scope and the custom component by
@@ -169,6 +189,7 @@ func (cn *CustomComponentNode) Evaluate(evalScope *vm.Scope) error {
 	case nil:
 		cn.setEvalHealth(component.HealthTypeHealthy, "component evaluated")
 	default:
+		cn.errorsHistory.Record(err.Error(), "")
 		msg := fmt.Sprintf("component evaluation failed: %s", err)
 		cn.setEvalHealth(component.HealthTypeUnhealthy, msg)
 	}
@@ -179,6 +200,12 @@ func (cn *CustomComponentNode) evaluate(evalScope *vm.Scope) error {
 	cn.mut.Lock()
 	defer cn.mut.Unlock()
 
+	meta, err := evaluateMeta(evalScope, cn.metaBlocks)
+	if err != nil {
+		return err
+	}
+	cn.meta.Set(meta)
+
 	var args map[string]any
 	if err := cn.eval.Evaluate(evalScope, &args); err != nil {
 		return fmt.Errorf("decoding River: %w", err)
@@ -199,6 +226,10 @@ func (cn *CustomComponentNode) evaluate(evalScope *vm.Scope) error {
 	if err != nil {
 		return fmt.Errorf("loading custom component controller: %w", err)
 	}
+	if diags := validateCustomComponentArguments(cn.block, template, args); diags.HasErrors() {
+		return diags
+	}
+	cn.source = customComponentRegistry.moduleSource(cn.customComponentName)
 
 	// Reload the custom component with new config
 	if err := cn.managed.LoadBody(template, args, customComponentRegistry); err != nil {
@@ -220,6 +251,7 @@ func (cn *CustomComponentNode) Run(ctx context.Context) error {
 	cn.setRunHealth(component.HealthTypeHealthy, "started custom component")
 	err := managed.Run(ctx)
 	if err != nil {
+		cn.errorsHistory.Record(err.Error(), "")
 		level.Error(logger).Log("msg", "error running custom component", "id", cn.nodeID, "err", err)
 	}
 
@@ -235,6 +267,14 @@ func (cn *CustomComponentNode) Arguments() component.Arguments {
 	return cn.args
 }
 
+// ModuleSource returns the imported module which defines the custom
+// component, or nil if its declare block wasn't imported.
+func (cn *CustomComponentNode) ModuleSource() *component.ModuleSource {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.source
+}
+
 // Block implements BlockNode and returns the current block of the managed custom component.
 func (cn *CustomComponentNode) Block() *ast.BlockStmt {
 	cn.mut.RLock()
@@ -270,11 +310,30 @@ func (cn *CustomComponentNode) setExports(e component.Exports) {
 	cn.exportsMut.Unlock()
 
 	if changed {
+		cn.exportsHistory.Record(e)
+
 		// Inform the controller that we have new exports.
 		cn.OnBlockNodeUpdate(cn)
 	}
 }
 
+// ExportsHistory returns the past exports of the managed component, ordered
+// from oldest to newest.
+func (cn *CustomComponentNode) ExportsHistory() []component.ExportsRecord {
+	return cn.exportsHistory.List()
+}
+
+// Meta returns the metadata labels of the component from its meta block.
+func (cn *CustomComponentNode) Meta() map[string]string {
+	return cn.meta.Labels()
+}
+
+// ErrorsHistory returns the past errors of the managed component, ordered
+// from oldest to newest.
+func (cn *CustomComponentNode) ErrorsHistory() []component.ErrorRecord {
+	return cn.errorsHistory.List()
+}
+
 // CurrentHealth returns the current health of the CustomComponentNode.
 //
 // The health of a CustomComponentNode is determined by combining:
@@ -0,0 +1,503 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/runner"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/vm"
+)
+
+const (
+	foreachBlockID = "foreach"
+	// foreachVarIdent is the name of the variable exposing the current
+	// element of the collection to the template of a foreach block.
+	foreachVarIdent = "each"
+)
+
+// ForeachArguments holds the arguments of a foreach block.
+type ForeachArguments struct {
+	Collection any `river:"collection,attr"`
+}
+
+// ForeachNode is a controller node which instantiates a custom component once
+// for every element of a collection.
+//
+// The block of a foreach node holds a collection attribute and a single
+// custom component block, the template. Every instance of the template is a
+// CustomComponentNode evaluated with the each variable set to the key and
+// value of its element. Instances are identified by the key of their
+// element, so that the instances of elements which are still in the
+// collection keep running when the collection changes.
+type ForeachNode struct {
+	id                ComponentID
+	globalID          string
+	label             string
+	nodeID            string
+	globals           ComponentGlobals
+	getConfig         getCustomComponentConfig
+	OnBlockNodeUpdate func(cn BlockNode)
+	logger            log.Logger
+
+	instancesUpdate chan struct{} // Signals Run that the instances changed.
+
+	mut         sync.RWMutex
+	block       *ast.BlockStmt
+	eval        *vm.Evaluator  // Evaluates the attributes of the block.
+	template    *ast.BlockStmt // Custom component block instantiated for every element.
+	templateErr error          // Error from finding the template in the block.
+	args        ForeachArguments
+	instances   map[string]*foreachInstance
+
+	healthMut  sync.RWMutex
+	evalHealth component.Health // Health of the last evaluate
+	runHealth  component.Health // Health of running the instances
+
+	errorsHistory *errorsHistory // Past errors of the foreach node
+}
+
+var _ ComponentNode = (*ForeachNode)(nil)
+
+// NewForeachNode creates a new ForeachNode from an initial ast.BlockStmt. The
+// instances aren't created until Evaluate is called.
+func NewForeachNode(globals ComponentGlobals, b *ast.BlockStmt, getConfig getCustomComponentConfig) *ForeachNode {
+	var (
+		id     = BlockComponentID(b)
+		nodeID = id.String()
+	)
+
+	initHealth := component.Health{
+		Health:     component.HealthTypeUnknown,
+		Message:    "node foreach created",
+		UpdateTime: time.Now(),
+	}
+
+	globalID := nodeID
+	if globals.ControllerID != "" {
+		globalID = path.Join(globals.ControllerID, nodeID)
+	}
+
+	fn := &ForeachNode{
+		id:                id,
+		globalID:          globalID,
+		label:             b.Label,
+		nodeID:            nodeID,
+		globals:           globals,
+		getConfig:         getConfig,
+		OnBlockNodeUpdate: globals.OnBlockNodeUpdate,
+		logger:            log.With(globals.Logger, "component", globalID),
+		instancesUpdate:   make(chan struct{}, 1),
+		instances:         make(map[string]*foreachInstance),
+
+		evalHealth: initHealth,
+		runHealth:  initHealth,
+
+		errorsHistory: newErrorsHistory(DefaultErrorsHistoryEntries),
+	}
+	fn.setBlock(b)
+	return fn
+}
+
+// setBlock splits b into its attributes and its template. fn.mut must be
+// held when calling setBlock, or fn must not be shared yet.
+func (fn *ForeachNode) setBlock(b *ast.BlockStmt) {
+	var (
+		attrs     ast.Body
+		templates []*ast.BlockStmt
+	)
+	for _, stmt := range b.Body {
+		switch stmt := stmt.(type) {
+		case *ast.BlockStmt:
+			templates = append(templates, stmt)
+		default:
+			attrs = append(attrs, stmt)
+		}
+	}
+
+	fn.block = b
+	fn.eval = vm.New(attrs)
+	fn.template, fn.templateErr = nil, nil
+	switch {
+	case len(templates) != 1:
+		fn.templateErr = fmt.Errorf("%s must contain exactly one custom component block, found %d blocks", fn.nodeID, len(templates))
+	case templates[0].GetBlockName() == foreachBlockID:
+		fn.templateErr = fmt.Errorf("%s can't contain a foreach block", fn.nodeID)
+	default:
+		fn.template = templates[0]
+	}
+}
+
+// Template returns the custom component block instantiated for every element
+// of the collection, or nil if the block doesn't hold a valid template.
+func (fn *ForeachNode) Template() *ast.BlockStmt {
+	fn.mut.RLock()
+	defer fn.mut.RUnlock()
+	return fn.template
+}
+
+// ID returns the component ID of the foreach node from its River block.
+func (fn *ForeachNode) ID() ComponentID { return fn.id }
+
+// Label returns the label for the block or "" if none was specified.
+func (fn *ForeachNode) Label() string { return fn.label }
+
+// NodeID implements dag.Node and returns the unique ID for this node.
+func (fn *ForeachNode) NodeID() string { return fn.nodeID }
+
+// ComponentName returns the name of the block.
+func (fn *ForeachNode) ComponentName() string { return foreachBlockID }
+
+// UpdateBlock updates the River block of the foreach node. The new block
+// isn't used until the next time Evaluate is invoked.
+//
+// UpdateBlock will panic if the block does not match the component ID of the
+// ForeachNode.
+func (fn *ForeachNode) UpdateBlock(b *ast.BlockStmt) {
+	if !BlockComponentID(b).Equals(fn.id) {
+		panic("UpdateBlock called with an River block with a different component ID")
+	}
+
+	fn.mut.Lock()
+	defer fn.mut.Unlock()
+	fn.setBlock(b)
+}
+
+// Evaluate implements BlockNode and evaluates the collection, creating an
+// instance of the template for every new element, evaluating the instances
+// of every element, and removing the instances of elements which left the
+// collection.
+func (fn *ForeachNode) Evaluate(scope *vm.Scope) error {
+	err := fn.evaluate(scope)
+
+	switch err {
+	case nil:
+		fn.setEvalHealth(component.HealthTypeHealthy, "foreach evaluated")
+	default:
+		fn.errorsHistory.Record(err.Error(), "")
+		msg := fmt.Sprintf("foreach evaluation failed: %s", err)
+		fn.setEvalHealth(component.HealthTypeUnhealthy, msg)
+	}
+	return err
+}
+
+func (fn *ForeachNode) evaluate(scope *vm.Scope) error {
+	fn.mut.Lock()
+	defer fn.mut.Unlock()
+
+	if fn.templateErr != nil {
+		return fn.templateErr
+	}
+
+	var args ForeachArguments
+	if err := fn.eval.Evaluate(scope, &args); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+	items, err := foreachItems(args.Collection)
+	if err != nil {
+		return err
+	}
+	fn.args = args
+
+	var (
+		instances  = make(map[string]*foreachInstance, len(items))
+		templateID = BlockComponentID(fn.template)
+		errs       []error
+	)
+	for _, key := range sortedKeys(items) {
+		inst, ok := fn.instances[key]
+		if ok && inst.node.ID().Equals(templateID) {
+			inst.node.UpdateBlock(fn.template)
+		} else {
+			inst = &foreachInstance{
+				key:  key,
+				node: NewCustomComponentNode(fn.instanceGlobals(key), fn.template, fn.getConfig),
+			}
+		}
+		instances[key] = inst
+
+		instanceScope := &vm.Scope{
+			Parent: scope,
+			Variables: map[string]any{
+				foreachVarIdent: map[string]any{"key": items[key].key, "value": items[key].value},
+			},
+		}
+		if err := inst.node.Evaluate(instanceScope); err != nil {
+			errs = append(errs, fmt.Errorf("instance %q: %w", key, err))
+			continue
+		}
+		inst.evaluated = true
+	}
+	fn.instances = instances
+
+	select {
+	case fn.instancesUpdate <- struct{}{}:
+	default:
+	}
+	return errors.Join(errs...)
+}
+
+// instanceGlobals returns the globals of the instance of the element with the
+// given key. Instances are identified by the global ID of the foreach node
+// followed by the key of their element.
+func (fn *ForeachNode) instanceGlobals(key string) ComponentGlobals {
+	globals := fn.globals
+	globals.ControllerID = path.Join(fn.globalID, key)
+	// The exports of the instances aren't exposed, so changes don't need to
+	// be propagated.
+	globals.OnBlockNodeUpdate = func(BlockNode) {}
+	return globals
+}
+
+// foreachItem is an element of the collection of a foreach block, exposed to
+// its instance as each.key and each.value.
+type foreachItem struct {
+	key   any
+	value any
+}
+
+// foreachItems returns the elements of collection keyed by the key of their
+// instance. The key of an element of an object is its key in the object, and
+// the key of an element of an array is the element itself. Keys which are
+// neither strings nor numbers usable in an ID are replaced by their hash.
+// Identical elements of an array are instantiated once.
+func foreachItems(collection any) (map[string]foreachItem, error) {
+	items := make(map[string]foreachItem)
+	if collection == nil {
+		return items, nil
+	}
+
+	v := reflect.ValueOf(collection)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i).Interface()
+			key := foreachKey(elem)
+			items[key] = foreachItem{key: key, value: elem}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("collection must be an array or an object, got %s", v.Type())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			items[foreachKey(key)] = foreachItem{key: key, value: iter.Value().Interface()}
+		}
+	default:
+		return nil, fmt.Errorf("collection must be an array or an object, got %s", v.Type())
+	}
+	return items, nil
+}
+
+// foreachKey returns the key identifying the instance of elem.
+func foreachKey(elem any) string {
+	var key string
+	switch v := reflect.ValueOf(elem); v.Kind() {
+	case reflect.String:
+		key = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		key = fmt.Sprint(elem)
+	}
+	if validForeachKey(key) {
+		return key
+	}
+
+	// fmt prints the keys of maps in sorted order, so equal elements have
+	// equal hashes.
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%#v", elem)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// validForeachKey returns true if key can be used in the ID of an instance.
+// Keys made only of dots are rejected, since they would be collapsed in the
+// paths of the instances.
+func validForeachKey(key string) bool {
+	if strings.Trim(key, ".") == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs the evaluated instances until ctx is canceled, starting the
+// instances of new elements and stopping the instances of removed elements
+// whenever the foreach node is evaluated.
+func (fn *ForeachNode) Run(ctx context.Context) error {
+	r := runner.New(func(inst *foreachInstance) runner.Worker {
+		return &foreachInstanceRunner{inst: inst, logger: fn.logger}
+	})
+	defer r.Stop()
+
+	fn.setRunHealth(component.HealthTypeHealthy, "started foreach")
+	for {
+		fn.mut.RLock()
+		tasks := make([]*foreachInstance, 0, len(fn.instances))
+		for _, inst := range fn.instances {
+			// Instances which were never evaluated successfully can't run.
+			if inst.evaluated {
+				tasks = append(tasks, inst)
+			}
+		}
+		fn.mut.RUnlock()
+
+		if err := r.ApplyTasks(ctx, tasks); err != nil {
+			level.Error(fn.logger).Log("msg", "failed to update foreach instances", "err", err)
+			fn.setRunHealth(component.HealthTypeUnhealthy, fmt.Sprintf("error encountered while updating instances: %s", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			level.Info(fn.logger).Log("msg", "foreach exited")
+			fn.setRunHealth(component.HealthTypeExited, "foreach shut down")
+			return nil
+		case <-fn.instancesUpdate:
+		}
+	}
+}
+
+// Arguments returns the current arguments of the foreach node.
+func (fn *ForeachNode) Arguments() component.Arguments {
+	fn.mut.RLock()
+	defer fn.mut.RUnlock()
+	return fn.args
+}
+
+// Block implements BlockNode and returns the current block of the foreach
+// node.
+func (fn *ForeachNode) Block() *ast.BlockStmt {
+	fn.mut.RLock()
+	defer fn.mut.RUnlock()
+	return fn.block
+}
+
+// Exports returns nil, as the exports of the instances aren't exposed.
+func (fn *ForeachNode) Exports() component.Exports { return nil }
+
+// ExportsHistory returns nil, as foreach nodes don't have exports.
+func (fn *ForeachNode) ExportsHistory() []component.ExportsRecord { return nil }
+
+// ErrorsHistory returns the past errors of the foreach node, ordered from
+// oldest to newest.
+func (fn *ForeachNode) ErrorsHistory() []component.ErrorRecord {
+	return fn.errorsHistory.List()
+}
+
+// Meta returns nil, as foreach blocks don't have a meta block.
+func (fn *ForeachNode) Meta() map[string]string { return nil }
+
+// Instances returns the keys of the elements of the collection, each of which
+// has an instance of the template.
+func (fn *ForeachNode) Instances() []string {
+	fn.mut.RLock()
+	defer fn.mut.RUnlock()
+	return sortedKeys(fn.instances)
+}
+
+// ModuleIDs returns the modules of the instances.
+func (fn *ForeachNode) ModuleIDs() []string {
+	fn.mut.RLock()
+	defer fn.mut.RUnlock()
+
+	var ids []string
+	for _, inst := range fn.instances {
+		ids = append(ids, inst.node.ModuleIDs()...)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CurrentHealth returns the current health of the ForeachNode.
+//
+// The health of a ForeachNode is determined by combining:
+//
+//  1. Health from the call to Run().
+//  2. Health from the last call to Evaluate().
+//  3. Health of the instances.
+func (fn *ForeachNode) CurrentHealth() component.Health {
+	fn.healthMut.RLock()
+	health := component.LeastHealthy(fn.runHealth, fn.evalHealth)
+	fn.healthMut.RUnlock()
+
+	fn.mut.RLock()
+	defer fn.mut.RUnlock()
+	for _, inst := range fn.instances {
+		health = component.LeastHealthy(health, inst.node.CurrentHealth())
+	}
+	return health
+}
+
+func (fn *ForeachNode) setEvalHealth(t component.HealthType, msg string) {
+	fn.healthMut.Lock()
+	defer fn.healthMut.Unlock()
+
+	fn.evalHealth = component.Health{
+		Health:     t,
+		Message:    msg,
+		UpdateTime: time.Now(),
+	}
+}
+
+func (fn *ForeachNode) setRunHealth(t component.HealthType, msg string) {
+	fn.healthMut.Lock()
+	defer fn.healthMut.Unlock()
+
+	fn.runHealth = component.Health{
+		Health:     t,
+		Message:    msg,
+		UpdateTime: time.Now(),
+	}
+}
+
+// foreachInstance is the instance of the template of a foreach node for an
+// element of the collection.
+type foreachInstance struct {
+	key       string
+	node      *CustomComponentNode
+	evaluated bool // Whether node was evaluated successfully at least once.
+}
+
+var _ runner.Task = (*foreachInstance)(nil)
+
+func (inst *foreachInstance) Hash() uint64 {
+	fnvHash := fnv.New64a()
+	fnvHash.Write([]byte(inst.key))
+	return fnvHash.Sum64()
+}
+
+// Equals returns true if other is the same instance, so that instances keep
+// running as long as their element stays in the collection.
+func (inst *foreachInstance) Equals(other runner.Task) bool {
+	return inst == other.(*foreachInstance)
+}
+
+type foreachInstanceRunner struct {
+	inst   *foreachInstance
+	logger log.Logger
+}
+
+func (r *foreachInstanceRunner) Run(ctx context.Context) {
+	if err := r.inst.node.Run(ctx); err != nil {
+		level.Error(r.logger).Log("msg", "foreach instance stopped running", "key", r.inst.key, "err", err)
+	}
+}
@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForeachKey(t *testing.T) {
+	require.Equal(t, "a", foreachKey("a"))
+	require.Equal(t, "tenant-1.prod", foreachKey("tenant-1.prod"))
+	require.Equal(t, "10", foreachKey(10))
+	require.Equal(t, "true", foreachKey(true))
+
+	// Keys which can't be used in paths are hashed.
+	keys := map[string]struct{}{}
+	for _, elem := range []any{"", ".", "..", "a/b", map[string]any{"a": 1}} {
+		key := foreachKey(elem)
+		require.Len(t, key, 16, "%#v", elem)
+		keys[key] = struct{}{}
+	}
+	require.Len(t, keys, 5)
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// panicError is returned in place of the result of a call to a managed
+// component which panicked.
+type panicError struct {
+	op    string // Name of the method which panicked.
+	value any    // Value passed to panic.
+	stack []byte // Stack of the goroutine which panicked.
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.op, e.value)
+}
+
+// callRecovered calls f, returning a *panicError if f panics so that a
+// misbehaving component doesn't crash the whole process.
+func callRecovered(op string, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{op: op, value: r, stack: debug.Stack()}
+		}
+	}()
+	return f()
+}
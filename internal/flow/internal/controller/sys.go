@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"maps"
+	"strconv"
+
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/agent/internal/featuregate"
+)
+
+// sysIdent is the name of the variable exposing information about the agent
+// to River expressions.
+const sysIdent = "sys"
+
+// buildSysValue returns the value of the sys variable for the given globals.
+//
+// The feature flags of globals are merged with one flag per stability level
+// below stable, which is true when features of that level are enabled.
+func buildSysValue(globals ComponentGlobals) map[string]any {
+	var (
+		minStability = globals.MinStability
+		features     = map[string]bool{
+			"beta":         minStability != featuregate.StabilityUndefined && minStability <= featuregate.StabilityBeta,
+			"experimental": minStability == featuregate.StabilityExperimental,
+		}
+	)
+	maps.Copy(features, globals.Features)
+
+	// Stability.String returns a quoted string, and an invalid level is
+	// exposed as an empty string.
+	stabilityLevel, _ := strconv.Unquote(minStability.String())
+
+	return map[string]any{
+		"version":         build.Version,
+		"revision":        build.Revision,
+		"branch":          build.Branch,
+		"stability_level": stabilityLevel,
+		"features":        features,
+	}
+}
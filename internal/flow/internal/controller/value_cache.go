@@ -21,6 +21,7 @@ type valueCache struct {
 	moduleArguments    map[string]any         // key -> module arguments value
 	moduleExports      map[string]any         // name -> value for the value of module exports
 	moduleChangedIndex int                    // Everytime a change occurs this is incremented
+	sys                map[string]any         // Value of the sys variable, if set
 }
 
 // newValueCache creates a new ValueCache.
@@ -160,6 +161,9 @@ func (vc *valueCache) SyncModuleArgs(args map[string]any) {
 
 // BuildContext builds a vm.Scope based on the current set of cached values.
 // The arguments and exports for the same ID are merged into one object.
+//
+// The sys variable is set in the parent of the returned scope, so that it is
+// shadowed by a component or custom component named sys.
 func (vc *valueCache) BuildContext() *vm.Scope {
 	vc.mut.RLock()
 	defer vc.mut.RUnlock()
@@ -168,6 +172,11 @@ func (vc *valueCache) BuildContext() *vm.Scope {
 		Parent:    nil,
 		Variables: make(map[string]interface{}),
 	}
+	if vc.sys != nil {
+		scope.Parent = &vm.Scope{
+			Variables: map[string]interface{}{sysIdent: vc.sys},
+		}
+	}
 
 	// First, partition components by River block name.
 	var componentsByBlockName = make(map[string][]ComponentID)
@@ -3,6 +3,7 @@ package controller
 import (
 	"testing"
 
+	"github.com/grafana/agent/internal/featuregate"
 	"github.com/stretchr/testify/require"
 )
 
@@ -174,3 +175,28 @@ func TestModuleArgumentCache(t *testing.T) {
 		})
 	}
 }
+
+func TestValueCacheSys(t *testing.T) {
+	vc := newValueCache()
+	vc.sys = buildSysValue(ComponentGlobals{
+		MinStability: featuregate.StabilityExperimental,
+		Features:     map[string]bool{"clustering": true},
+	})
+
+	scope := vc.BuildContext()
+	sys, ok := scope.Lookup("sys")
+	require.True(t, ok)
+	require.Equal(t, "experimental", sys.(map[string]any)["stability_level"])
+	require.Equal(t, map[string]bool{
+		"beta":         true,
+		"clustering":   true,
+		"experimental": true,
+	}, sys.(map[string]any)["features"])
+
+	// A component named sys shadows the sys variable.
+	vc.CacheExports(ComponentID{"sys", "local"}, nil)
+	vc.SyncIDs([]ComponentID{{"sys", "local"}})
+	sys, ok = vc.BuildContext().Lookup("sys")
+	require.True(t, ok)
+	require.Contains(t, sys.(map[string]any), "local")
+}
@@ -0,0 +1,52 @@
+package importsource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContentDigest returns the hex-encoded SHA-256 digest of imported content.
+//
+// Content consisting of a single file is hashed directly, so that the digest
+// matches the output of tools like sha256sum. Content consisting of multiple
+// files is hashed in file name order, with each file contributing its name
+// and its content.
+func ContentDigest(content map[string]string) string {
+	h := sha256.New()
+
+	if len(content) == 1 {
+		for _, v := range content {
+			h.Write([]byte(v))
+		}
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(content[name]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// verifyContentDigest returns an error if expected is set and does not match
+// the digest of content.
+func verifyContentDigest(expected string, content map[string]string) error {
+	if expected == "" {
+		return nil
+	}
+	if actual := ContentDigest(content); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("content digest mismatch: expected sha256 %q, got %q", expected, actual)
+	}
+	return nil
+}
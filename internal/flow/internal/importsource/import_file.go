@@ -17,6 +17,7 @@ import (
 	filedetector "github.com/grafana/agent/internal/filedetector"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/grafana/river/vm"
+	"go.uber.org/atomic"
 )
 
 // ImportFile imports a module from a file or a folder.
@@ -28,6 +29,7 @@ type ImportFile struct {
 
 	reloadCh chan struct{}
 	args     FileArguments
+	location atomic.String // Filename of the last evaluated arguments.
 
 	mut      sync.RWMutex
 	detector io.Closer
@@ -87,6 +89,7 @@ func (im *ImportFile) Evaluate(scope *vm.Scope) error {
 		return nil
 	}
 	im.args = arguments
+	im.location.Store(arguments.Filename)
 
 	// Force an immediate read of the file to report any potential errors early.
 	if err := im.readFile(); err != nil {
@@ -254,3 +257,8 @@ func collectFilesFromDir(path string) ([]string, error) {
 func (im *ImportFile) SetEval(eval *vm.Evaluator) {
 	im.eval = eval
 }
+
+// Location implements ImportSource and returns the file or folder of the module.
+func (im *ImportFile) Location() string {
+	return im.location.Load()
+}
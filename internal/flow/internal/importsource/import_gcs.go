@@ -0,0 +1,161 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/rivertypes"
+	"github.com/grafana/river/vm"
+	"go.uber.org/atomic"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// ImportGCS imports a module from a Google Cloud Storage bucket.
+type ImportGCS struct {
+	eval   *vm.Evaluator
+	args   GCSArguments
+	object *importObject
+
+	location atomic.String // Path of the last evaluated arguments.
+}
+
+var _ ImportSource = (*ImportGCS)(nil)
+
+func NewImportGCS(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportGCS {
+	return &ImportGCS{
+		eval:   eval,
+		object: newImportObject(managedOpts.Logger, onContentChange),
+	}
+}
+
+// GCSArguments holds values which are used to configure the import.gcs block.
+type GCSArguments struct {
+	// Path is the object to import, of the form gs://BUCKET/OBJECT. A path
+	// ending with a slash imports all the .river objects directly under it.
+	Path          string        `river:"path,attr"`
+	PollFrequency time.Duration `river:"poll_frequency,attr,optional"`
+	PollTimeout   time.Duration `river:"poll_timeout,attr,optional"`
+	SHA256        string        `river:"sha256,attr,optional"`
+
+	CredentialsFile string            `river:"credentials_file,attr,optional"`
+	Credentials     rivertypes.Secret `river:"credentials,attr,optional"`
+	Endpoint        string            `river:"endpoint,attr,optional"`
+}
+
+// DefaultGCSArguments holds default settings for GCSArguments.
+var DefaultGCSArguments = GCSArguments{
+	PollFrequency: time.Minute,
+	PollTimeout:   10 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *GCSArguments) SetToDefault() {
+	*args = DefaultGCSArguments
+}
+
+// Validate implements river.Validator.
+func (args *GCSArguments) Validate() error {
+	if args.PollFrequency < 0 {
+		return fmt.Errorf("poll_frequency must not be negative")
+	}
+	if args.PollTimeout < 0 {
+		return fmt.Errorf("poll_timeout must not be negative")
+	}
+	if args.CredentialsFile != "" && args.Credentials != "" {
+		return fmt.Errorf("at most one of credentials_file and credentials can be set")
+	}
+	_, _, err := parseObjectPath("gs", args.Path)
+	return err
+}
+
+func (im *ImportGCS) Evaluate(scope *vm.Scope) error {
+	var arguments GCSArguments
+	if err := im.eval.Evaluate(scope, &arguments); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+
+	if reflect.DeepEqual(im.args, arguments) {
+		return nil
+	}
+
+	// Without credentials, Application Default Credentials are used, which
+	// include workload identity on GKE and the service account of GCE
+	// instances.
+	opts := []option.ClientOption{option.WithScopes(storage.DevstorageReadOnlyScope)}
+	switch {
+	case arguments.CredentialsFile != "":
+		opts = append(opts, option.WithCredentialsFile(arguments.CredentialsFile))
+	case arguments.Credentials != "":
+		opts = append(opts, option.WithCredentialsJSON([]byte(arguments.Credentials)))
+	}
+	if arguments.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(arguments.Endpoint))
+	}
+	service, err := storage.NewService(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("creating GCS client: %w", err)
+	}
+	bucket, key, _ := parseObjectPath("gs", arguments.Path)
+	im.location.Store(arguments.Path)
+
+	err = im.object.update(gcsStore{service: service}, objectLocation{
+		bucket:        bucket,
+		key:           key,
+		sha256:        arguments.SHA256,
+		pollFrequency: arguments.PollFrequency,
+		pollTimeout:   arguments.PollTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	im.args = arguments
+	return nil
+}
+
+func (im *ImportGCS) Run(ctx context.Context) error {
+	return im.object.run(ctx)
+}
+
+func (im *ImportGCS) CurrentHealth() component.Health {
+	return im.object.currentHealth()
+}
+
+// Update the evaluator.
+func (im *ImportGCS) SetEval(eval *vm.Evaluator) {
+	im.eval = eval
+}
+
+// Location implements ImportSource and returns the path of the module.
+func (im *ImportGCS) Location() string {
+	return im.location.Load()
+}
+
+// gcsStore reads objects from Google Cloud Storage.
+type gcsStore struct {
+	service *storage.Service
+}
+
+func (s gcsStore) get(ctx context.Context, bucket, key string) ([]byte, error) {
+	resp, err := s.service.Objects.Get(bucket, key).Context(ctx).Download()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s gcsStore) list(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	err := s.service.Objects.List(bucket).Prefix(prefix).Delimiter("/").Pages(ctx, func(objects *storage.Objects) error {
+		for _, object := range objects.Items {
+			keys = append(keys, object.Name)
+		}
+		return nil
+	})
+	return keys, err
+}
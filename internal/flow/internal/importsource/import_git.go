@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"go.uber.org/atomic"
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/flow/logging/level"
@@ -32,12 +33,15 @@ type ImportGit struct {
 
 	argsChanged chan struct{}
 
+	revision atomic.String // Commit of the content last sent to onContentChange.
+	location atomic.String // Repository and path of the last evaluated arguments.
+
 	healthMut sync.RWMutex
 	health    component.Health
 }
 
 var (
-	_ ImportSource              = (*ImportGit)(nil)
+	_ VersionedImportSource     = (*ImportGit)(nil)
 	_ component.Component       = (*ImportGit)(nil)
 	_ component.HealthComponent = (*ImportGit)(nil)
 )
@@ -47,6 +51,7 @@ type GitArguments struct {
 	Revision      string            `river:"revision,attr,optional"`
 	Path          string            `river:"path,attr"`
 	PullFrequency time.Duration     `river:"pull_frequency,attr,optional"`
+	SHA256        string            `river:"sha256,attr,optional"`
 	GitAuthConfig vcs.GitAuthConfig `river:",squash"`
 }
 
@@ -80,6 +85,7 @@ func (im *ImportGit) Evaluate(scope *vm.Scope) error {
 		return nil
 	}
 
+	im.location.Store(arguments.Repository + "//" + arguments.Path)
 	if err := im.Update(arguments); err != nil {
 		return fmt.Errorf("updating component: %w", err)
 	}
@@ -233,6 +239,9 @@ func (im *ImportGit) pollFile(ctx context.Context, args GitArguments) error {
 	if err := im.repo.Update(ctx); err != nil {
 		return err
 	}
+	if rev, err := im.repo.CurrentRevision(); err == nil {
+		im.revision.Store(rev)
+	}
 
 	info, err := im.repo.Stat(args.Path)
 	if err != nil {
@@ -240,13 +249,13 @@ func (im *ImportGit) pollFile(ctx context.Context, args GitArguments) error {
 	}
 
 	if info.IsDir() {
-		return im.handleDirectory(args.Path)
+		return im.handleDirectory(args.Path, args.SHA256)
 	}
 
-	return im.handleFile(args.Path)
+	return im.handleFile(args.Path, args.SHA256)
 }
 
-func (im *ImportGit) handleDirectory(path string) error {
+func (im *ImportGit) handleDirectory(path string, expectedDigest string) error {
 	filesInfo, err := im.repo.ReadDir(path)
 	if err != nil {
 		return err
@@ -263,16 +272,23 @@ func (im *ImportGit) handleDirectory(path string) error {
 		}
 		content[fi.Name()] = string(bb)
 	}
+	if err := verifyContentDigest(expectedDigest, content); err != nil {
+		return err
+	}
 	im.onContentChange(content)
 	return nil
 }
 
-func (im *ImportGit) handleFile(path string) error {
+func (im *ImportGit) handleFile(path string, expectedDigest string) error {
 	bb, err := im.repo.ReadFile(path)
 	if err != nil {
 		return err
 	}
-	im.onContentChange(map[string]string{path: string(bb)})
+	content := map[string]string{path: string(bb)}
+	if err := verifyContentDigest(expectedDigest, content); err != nil {
+		return err
+	}
+	im.onContentChange(content)
 	return nil
 }
 
@@ -283,7 +299,19 @@ func (im *ImportGit) CurrentHealth() component.Health {
 	return im.health
 }
 
+// ResolvedVersion implements VersionedImportSource. It returns the commit the
+// revision of the block resolved to.
+func (im *ImportGit) ResolvedVersion() string {
+	return im.revision.Load()
+}
+
 // Update the evaluator.
 func (im *ImportGit) SetEval(eval *vm.Evaluator) {
 	im.eval = eval
 }
+
+// Location implements ImportSource and returns the repository of the module
+// followed by its path in the repository, separated by a double slash.
+func (im *ImportGit) Location() string {
+	return im.location.Load()
+}
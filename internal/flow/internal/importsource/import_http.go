@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/grafana/agent/internal/component"
 	common_config "github.com/grafana/agent/internal/component/common/config"
 	remote_http "github.com/grafana/agent/internal/component/remote/http"
 	"github.com/grafana/river/vm"
+	"go.uber.org/atomic"
 )
 
 // ImportHTTP imports a module from a HTTP server via the remote.http component.
@@ -19,19 +21,33 @@ type ImportHTTP struct {
 	arguments         component.Arguments
 	managedOpts       component.Options
 	eval              *vm.Evaluator
+	onContentChange   func(map[string]string)
+	location          atomic.String // URL of the last evaluated arguments.
+
+	mut            sync.Mutex
+	expectedDigest string            // Expected SHA-256 digest of the content, if set.
+	lastContent    map[string]string // Last content received from remote.http.
+	digestErr      error             // Error from the last digest verification.
+	digestErrTime  time.Time         // Time the digest error was last updated.
 }
 
 var _ ImportSource = (*ImportHTTP)(nil)
 
 func NewImportHTTP(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportHTTP {
+	im := &ImportHTTP{
+		eval:            eval,
+		onContentChange: onContentChange,
+	}
+
 	opts := managedOpts
 	opts.OnStateChange = func(e component.Exports) {
-		onContentChange(map[string]string{opts.ID: e.(remote_http.Exports).Content.Value})
-	}
-	return &ImportHTTP{
-		managedOpts: opts,
-		eval:        eval,
+		im.mut.Lock()
+		im.lastContent = map[string]string{opts.ID: e.(remote_http.Exports).Content.Value}
+		im.mut.Unlock()
+		im.forwardContent()
 	}
+	im.managedOpts = opts
+	return im
 }
 
 // HTTPArguments holds values which are used to configure the remote.http component.
@@ -44,6 +60,8 @@ type HTTPArguments struct {
 	Headers map[string]string `river:"headers,attr,optional"`
 	Body    string            `river:"body,attr,optional"`
 
+	SHA256 string `river:"sha256,attr,optional"`
+
 	Client common_config.HTTPClientConfig `river:"client,block,optional"`
 }
 
@@ -65,6 +83,14 @@ func (im *ImportHTTP) Evaluate(scope *vm.Scope) error {
 	if err := im.eval.Evaluate(scope, &arguments); err != nil {
 		return fmt.Errorf("decoding River: %w", err)
 	}
+
+	im.location.Store(arguments.URL)
+
+	im.mut.Lock()
+	digestChanged := im.expectedDigest != arguments.SHA256
+	im.expectedDigest = arguments.SHA256
+	im.mut.Unlock()
+
 	if im.managedRemoteHTTP == nil {
 		var err error
 		im.managedRemoteHTTP, err = remote_http.New(im.managedOpts, remote_http.Arguments{
@@ -80,6 +106,7 @@ func (im *ImportHTTP) Evaluate(scope *vm.Scope) error {
 			return fmt.Errorf("creating http component: %w", err)
 		}
 		im.arguments = arguments
+		return im.digestError()
 	}
 
 	if reflect.DeepEqual(im.arguments, arguments) {
@@ -91,7 +118,38 @@ func (im *ImportHTTP) Evaluate(scope *vm.Scope) error {
 		return fmt.Errorf("updating component: %w", err)
 	}
 	im.arguments = arguments
-	return nil
+
+	// remote.http only reports content when it changes, so previously
+	// rejected content must be verified again against the new digest.
+	if digestChanged {
+		im.forwardContent()
+	}
+	return im.digestError()
+}
+
+// forwardContent verifies the last content received from remote.http against
+// the expected digest and forwards it to the import node if it matches.
+func (im *ImportHTTP) forwardContent() {
+	im.mut.Lock()
+	content := im.lastContent
+	if content == nil {
+		im.mut.Unlock()
+		return
+	}
+	im.digestErr = verifyContentDigest(im.expectedDigest, content)
+	im.digestErrTime = time.Now()
+	err := im.digestErr
+	im.mut.Unlock()
+
+	if err == nil {
+		im.onContentChange(content)
+	}
+}
+
+func (im *ImportHTTP) digestError() error {
+	im.mut.Lock()
+	defer im.mut.Unlock()
+	return im.digestErr
 }
 
 func (im *ImportHTTP) Run(ctx context.Context) error {
@@ -99,6 +157,17 @@ func (im *ImportHTTP) Run(ctx context.Context) error {
 }
 
 func (im *ImportHTTP) CurrentHealth() component.Health {
+	im.mut.Lock()
+	digestErr, digestErrTime := im.digestErr, im.digestErrTime
+	im.mut.Unlock()
+
+	if digestErr != nil {
+		return component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    digestErr.Error(),
+			UpdateTime: digestErrTime,
+		}
+	}
 	return im.managedRemoteHTTP.CurrentHealth()
 }
 
@@ -106,3 +175,8 @@ func (im *ImportHTTP) CurrentHealth() component.Health {
 func (im *ImportHTTP) SetEval(eval *vm.Evaluator) {
 	im.eval = eval
 }
+
+// Location implements ImportSource and returns the URL of the module.
+func (im *ImportHTTP) Location() string {
+	return im.location.Load()
+}
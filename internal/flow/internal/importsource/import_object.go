@@ -0,0 +1,212 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+// objectStore reads objects from an object storage bucket.
+type objectStore interface {
+	// get returns the content of the object named key.
+	get(ctx context.Context, bucket, key string) ([]byte, error)
+	// list returns the names of the objects directly under prefix, without
+	// the objects of nested prefixes.
+	list(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// objectLocation is the object or prefix of objects a module is imported
+// from, along with how to poll it.
+type objectLocation struct {
+	bucket string
+	// key is the name of the object, or a prefix ending with a slash to import
+	// all the .river objects directly under it.
+	key string
+
+	sha256        string
+	pollFrequency time.Duration
+	pollTimeout   time.Duration
+}
+
+// importObject imports a module from an object storage bucket. It holds the
+// logic shared by import.s3 and import.gcs, which only differ in how they
+// access their bucket.
+type importObject struct {
+	log             log.Logger
+	onContentChange func(map[string]string)
+
+	mut      sync.Mutex
+	store    objectStore
+	location objectLocation
+
+	argsChanged chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+func newImportObject(l log.Logger, onContentChange func(map[string]string)) *importObject {
+	return &importObject{
+		log:             l,
+		onContentChange: onContentChange,
+		argsChanged:     make(chan struct{}, 1),
+	}
+}
+
+// parseObjectPath splits a path of the form SCHEME://BUCKET/KEY into its
+// bucket and key.
+func parseObjectPath(scheme, p string) (bucket, key string, err error) {
+	rest, ok := strings.CutPrefix(p, scheme+"://")
+	if !ok {
+		return "", "", fmt.Errorf("path %q must start with %s://", p, scheme)
+	}
+	bucket, key, _ = strings.Cut(rest, "/")
+	if bucket == "" || key == "" || key == "/" {
+		return "", "", fmt.Errorf("path %q must be of the form %s://BUCKET/KEY", p, scheme)
+	}
+	return bucket, key, nil
+}
+
+// update sets the bucket and location the module is imported from, and
+// retrieves the module synchronously so that it's available as soon as the
+// import block is evaluated.
+func (im *importObject) update(store objectStore, location objectLocation) error {
+	im.mut.Lock()
+	im.store = store
+	im.location = location
+	im.mut.Unlock()
+
+	err := im.poll(context.Background())
+
+	select {
+	case im.argsChanged <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// poll retrieves the module, verifies its digest and forwards it to the
+// import node.
+func (im *importObject) poll(ctx context.Context) error {
+	im.mut.Lock()
+	store, location := im.store, im.location
+	im.mut.Unlock()
+
+	if location.pollTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, location.pollTimeout)
+		defer cancel()
+	}
+
+	content, err := readObjects(ctx, store, location)
+	if err == nil {
+		err = verifyContentDigest(location.sha256, content)
+	}
+	im.updateHealth(err)
+	if err != nil {
+		return err
+	}
+	im.onContentChange(content)
+	return nil
+}
+
+// readObjects returns the content of the object of location, or of the .river
+// objects under its prefix, keyed by object name.
+func readObjects(ctx context.Context, store objectStore, location objectLocation) (map[string]string, error) {
+	if !strings.HasSuffix(location.key, "/") {
+		bb, err := store.get(ctx, location.bucket, location.key)
+		if err != nil {
+			return nil, fmt.Errorf("reading object %q: %w", location.key, err)
+		}
+		return map[string]string{location.key: string(bb)}, nil
+	}
+
+	keys, err := store.list(ctx, location.bucket, location.key)
+	if err != nil {
+		return nil, fmt.Errorf("listing objects under %q: %w", location.key, err)
+	}
+	content := make(map[string]string)
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".river") {
+			continue
+		}
+		bb, err := store.get(ctx, location.bucket, key)
+		if err != nil {
+			return nil, fmt.Errorf("reading object %q: %w", key, err)
+		}
+		content[path.Base(key)] = string(bb)
+	}
+	return content, nil
+}
+
+func (im *importObject) run(ctx context.Context) error {
+	var (
+		ticker  *time.Ticker
+		tickerC <-chan time.Time
+	)
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-im.argsChanged:
+			im.mut.Lock()
+			pollFrequency := im.location.pollFrequency
+			im.mut.Unlock()
+
+			// A poll frequency of 0 disables polling.
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tickerC = nil, nil
+			}
+			if pollFrequency > 0 {
+				ticker = time.NewTicker(pollFrequency)
+				tickerC = ticker.C
+			}
+
+		case <-tickerC:
+			if err := im.poll(ctx); err != nil {
+				level.Error(im.log).Log("msg", "failed to poll module", "err", err)
+			}
+		}
+	}
+}
+
+func (im *importObject) updateHealth(err error) {
+	im.healthMut.Lock()
+	defer im.healthMut.Unlock()
+
+	if err != nil {
+		im.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    err.Error(),
+			UpdateTime: time.Now(),
+		}
+	} else {
+		im.health = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "module updated",
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+func (im *importObject) currentHealth() component.Health {
+	im.healthMut.RLock()
+	defer im.healthMut.RUnlock()
+	return im.health
+}
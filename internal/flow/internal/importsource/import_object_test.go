@@ -0,0 +1,115 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestParseObjectPath(t *testing.T) {
+	bucket, key, err := parseObjectPath("s3", "s3://bucket/modules/math.river")
+	require.NoError(t, err)
+	require.Equal(t, "bucket", bucket)
+	require.Equal(t, "modules/math.river", key)
+
+	bucket, key, err = parseObjectPath("gs", "gs://bucket/modules/")
+	require.NoError(t, err)
+	require.Equal(t, "bucket", bucket)
+	require.Equal(t, "modules/", key)
+
+	for _, p := range []string{"gs://bucket/math.river", "s3://bucket", "s3://bucket/", "s3:///math.river"} {
+		_, _, err := parseObjectPath("s3", p)
+		require.Error(t, err, p)
+	}
+}
+
+type fakeObjectStore map[string]string
+
+func (s fakeObjectStore) get(_ context.Context, bucket, key string) ([]byte, error) {
+	content, ok := s[bucket+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("object not found")
+	}
+	return []byte(content), nil
+}
+
+func (s fakeObjectStore) list(_ context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	for name := range s {
+		key, ok := strings.CutPrefix(name, bucket+"/")
+		if ok && strings.HasPrefix(key, prefix) && !strings.Contains(strings.TrimPrefix(key, prefix), "/") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func TestReadObjects(t *testing.T) {
+	store := fakeObjectStore{
+		"bucket/math.river":             "math",
+		"bucket/modules/a.river":        "a",
+		"bucket/modules/b.river":        "b",
+		"bucket/modules/README.md":      "readme",
+		"bucket/modules/nested/c.river": "c",
+	}
+
+	content, err := readObjects(context.Background(), store, objectLocation{bucket: "bucket", key: "math.river"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"math.river": "math"}, content)
+
+	content, err = readObjects(context.Background(), store, objectLocation{bucket: "bucket", key: "modules/"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a.river": "a", "b.river": "b"}, content)
+
+	_, err = readObjects(context.Background(), store, objectLocation{bucket: "bucket", key: "unknown.river"})
+	require.ErrorContains(t, err, `reading object "unknown.river"`)
+}
+
+func TestImportS3_RetriesFailedUpdate(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("math"))
+	}))
+	defer srv.Close()
+
+	file, err := parser.ParseFile("", []byte(fmt.Sprintf(`
+		path         = "s3://bucket/math.river"
+		poll_timeout = "1s"
+		client {
+			endpoint       = %q
+			key            = "key"
+			secret         = "secret"
+			region         = "us-east-1"
+			use_path_style = true
+		}`, srv.URL)))
+	require.NoError(t, err)
+
+	var content map[string]string
+	im := NewImportS3(component.Options{Logger: util.TestFlowLogger(t)}, vm.New(file), func(c map[string]string) {
+		content = c
+	})
+
+	require.Error(t, im.Evaluate(nil))
+
+	// The failed update isn't remembered, so the same arguments are retried.
+	fail.Store(false)
+	require.NoError(t, im.Evaluate(nil))
+	require.Equal(t, map[string]string{"math.river": "math"}, content)
+}
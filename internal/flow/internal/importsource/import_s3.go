@@ -0,0 +1,156 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/grafana/agent/internal/component"
+	remote_s3 "github.com/grafana/agent/internal/component/remote/s3"
+	"github.com/grafana/river/vm"
+	"go.uber.org/atomic"
+)
+
+// ImportS3 imports a module from an S3 bucket.
+type ImportS3 struct {
+	eval   *vm.Evaluator
+	args   S3Arguments
+	object *importObject
+
+	location atomic.String // Path of the last evaluated arguments.
+}
+
+var _ ImportSource = (*ImportS3)(nil)
+
+func NewImportS3(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportS3 {
+	return &ImportS3{
+		eval:   eval,
+		object: newImportObject(managedOpts.Logger, onContentChange),
+	}
+}
+
+// S3Arguments holds values which are used to configure the import.s3 block.
+type S3Arguments struct {
+	// Path is the object to import, of the form s3://BUCKET/KEY. A path ending
+	// with a slash imports all the .river objects directly under it.
+	Path          string        `river:"path,attr"`
+	PollFrequency time.Duration `river:"poll_frequency,attr,optional"`
+	PollTimeout   time.Duration `river:"poll_timeout,attr,optional"`
+	SHA256        string        `river:"sha256,attr,optional"`
+
+	Client remote_s3.Client `river:"client,block,optional"`
+}
+
+// DefaultS3Arguments holds default settings for S3Arguments.
+var DefaultS3Arguments = S3Arguments{
+	PollFrequency: time.Minute,
+	PollTimeout:   10 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *S3Arguments) SetToDefault() {
+	*args = DefaultS3Arguments
+}
+
+// Validate implements river.Validator.
+func (args *S3Arguments) Validate() error {
+	if args.PollFrequency < 0 {
+		return fmt.Errorf("poll_frequency must not be negative")
+	}
+	if args.PollTimeout < 0 {
+		return fmt.Errorf("poll_timeout must not be negative")
+	}
+	_, _, err := parseObjectPath("s3", args.Path)
+	return err
+}
+
+func (im *ImportS3) Evaluate(scope *vm.Scope) error {
+	var arguments S3Arguments
+	if err := im.eval.Evaluate(scope, &arguments); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+
+	if reflect.DeepEqual(im.args, arguments) {
+		return nil
+	}
+
+	// Credentials default to the AWS SDK default chain, which includes IAM
+	// roles for EC2 instances, ECS tasks and Kubernetes service accounts.
+	client, err := remote_s3.NewClient(arguments.Client)
+	if err != nil {
+		return fmt.Errorf("creating S3 client: %w", err)
+	}
+	bucket, key, _ := parseObjectPath("s3", arguments.Path)
+	im.location.Store(arguments.Path)
+
+	err = im.object.update(s3Store{client: client}, objectLocation{
+		bucket:        bucket,
+		key:           key,
+		sha256:        arguments.SHA256,
+		pollFrequency: arguments.PollFrequency,
+		pollTimeout:   arguments.PollTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	im.args = arguments
+	return nil
+}
+
+func (im *ImportS3) Run(ctx context.Context) error {
+	return im.object.run(ctx)
+}
+
+func (im *ImportS3) CurrentHealth() component.Health {
+	return im.object.currentHealth()
+}
+
+// Update the evaluator.
+func (im *ImportS3) SetEval(eval *vm.Evaluator) {
+	im.eval = eval
+}
+
+// Location implements ImportSource and returns the path of the module.
+func (im *ImportS3) Location() string {
+	return im.location.Load()
+}
+
+// s3Store reads objects from S3.
+type s3Store struct {
+	client *s3.Client
+}
+
+func (s s3Store) get(ctx context.Context, bucket, key string) ([]byte, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return io.ReadAll(output.Body)
+}
+
+func (s s3Store) list(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	return keys, nil
+}
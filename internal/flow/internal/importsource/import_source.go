@@ -15,6 +15,8 @@ const (
 	String
 	Git
 	HTTP
+	S3
+	GCS
 )
 
 const (
@@ -22,6 +24,8 @@ const (
 	BlockImportString = "import.string"
 	BlockImportHTTP   = "import.http"
 	BlockImportGit    = "import.git"
+	BlockImportS3     = "import.s3"
+	BlockImportGCS    = "import.gcs"
 )
 
 // ImportSource retrieves a module from a source.
@@ -34,6 +38,18 @@ type ImportSource interface {
 	CurrentHealth() component.Health
 	// Update evaluator
 	SetEval(eval *vm.Evaluator)
+	// Location returns where the content is imported from, such as a URL or
+	// a path. Location returns an empty string if the content is inline.
+	Location() string
+}
+
+// VersionedImportSource is an ImportSource whose content has versions, such
+// as the commits of a Git repository.
+type VersionedImportSource interface {
+	ImportSource
+	// ResolvedVersion returns the version of the content last sent to
+	// onContentChange.
+	ResolvedVersion() string
 }
 
 // NewImportSource creates a new ImportSource depending on the type.
@@ -48,6 +64,10 @@ func NewImportSource(sourceType SourceType, managedOpts component.Options, eval
 		return NewImportHTTP(managedOpts, eval, onContentChange)
 	case Git:
 		return NewImportGit(managedOpts, eval, onContentChange)
+	case S3:
+		return NewImportS3(managedOpts, eval, onContentChange)
+	case GCS:
+		return NewImportGCS(managedOpts, eval, onContentChange)
 	}
 	panic(fmt.Errorf("unsupported source type: %v", sourceType))
 }
@@ -63,6 +83,10 @@ func GetSourceType(fullName string) SourceType {
 		return HTTP
 	case BlockImportGit:
 		return Git
+	case BlockImportS3:
+		return S3
+	case BlockImportGCS:
+		return GCS
 	}
 	panic(fmt.Errorf("name does not map to a known source type: %v", fullName))
 }
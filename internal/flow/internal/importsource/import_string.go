@@ -63,3 +63,7 @@ func (im *ImportString) CurrentHealth() component.Health {
 func (im *ImportString) SetEval(eval *vm.Evaluator) {
 	im.eval = eval
 }
+
+// Location implements ImportSource. The content of import.string is inline,
+// so Location always returns an empty string.
+func (im *ImportString) Location() string { return "" }
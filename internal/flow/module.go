@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"strings"
 	"sync"
 
 	"github.com/grafana/agent/internal/component"
@@ -12,6 +13,7 @@ import (
 	"github.com/grafana/agent/internal/flow/internal/worker"
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/flow/modulelock"
 	"github.com/grafana/agent/internal/flow/tracing"
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/scanner"
@@ -50,9 +52,15 @@ func (m *moduleController) NewModule(id string, export component.ExportFunc) (co
 		fullPath = path.Join(fullPath, id)
 	}
 
+	chain, err := m.moduleChain(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
 	mod := newModule(&moduleOptions{
 		ID:                      fullPath,
 		export:                  export,
+		chain:                   chain,
 		moduleControllerOptions: m.o,
 		parent:                  m,
 	})
@@ -73,9 +81,15 @@ func (m *moduleController) NewCustomComponent(id string, export component.Export
 		fullPath = path.Join(fullPath, id)
 	}
 
+	chain, err := m.moduleChain(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
 	mod := newModule(&moduleOptions{
 		ID:                      fullPath,
 		export:                  export,
+		chain:                   chain,
 		moduleControllerOptions: m.o,
 		parent:                  m,
 	})
@@ -83,6 +97,24 @@ func (m *moduleController) NewCustomComponent(id string, export component.Export
 	return mod, nil
 }
 
+// moduleChain returns the chain of module IDs from the root controller down to
+// a new module with the given ID. An error is returned if the new module would
+// exceed the maximum module nesting depth.
+func (m *moduleController) moduleChain(id string) ([]string, error) {
+	chain := make([]string, 0, len(m.o.ModuleChain)+1)
+	chain = append(chain, m.o.ModuleChain...)
+	chain = append(chain, id)
+
+	maxDepth := m.o.MaxModuleDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxModuleDepth
+	}
+	if len(chain) > maxDepth {
+		return nil, fmt.Errorf("maximum module nesting depth of %d exceeded: %s", maxDepth, strings.Join(chain, " -> "))
+	}
+	return chain, nil
+}
+
 func (m *moduleController) removeModule(mod *module) {
 	m.mut.Lock()
 	defer m.mut.Unlock()
@@ -116,7 +148,8 @@ type module struct {
 }
 
 type moduleOptions struct {
-	ID     string // ID is the full name including all parents, "module.file.example.prometheus.remote_write.id".
+	ID     string   // ID is the full name including all parents, "module.file.example.prometheus.remote_write.id".
+	chain  []string // chain is the list of module IDs from the root controller down to this module.
 	export component.ExportFunc
 	parent *moduleController
 	*moduleControllerOptions
@@ -135,6 +168,7 @@ func newModule(o *moduleOptions) *module {
 			ModuleRegistry:    o.ModuleRegistry,
 			ComponentRegistry: o.ComponentRegistry,
 			WorkerPool:        o.WorkerPool,
+			ModuleChain:       o.chain,
 			Options: Options{
 				ControllerID: o.ID,
 				Tracer:       o.Tracer,
@@ -142,12 +176,15 @@ func newModule(o *moduleOptions) *module {
 				Logger:       o.Logger,
 				DataPath:     o.DataPath,
 				MinStability: o.MinStability,
+				Features:     o.Features,
 				OnExportsChange: func(exports map[string]any) {
 					if o.export != nil {
 						o.export(exports)
 					}
 				},
-				Services: o.ServiceMap.List(),
+				Services:       o.ServiceMap.List(),
+				MaxModuleDepth: o.MaxModuleDepth,
+				ModuleLock:     o.ModuleLock,
 			},
 		}),
 	}
@@ -209,6 +246,9 @@ type moduleControllerOptions struct {
 	// the user, for example, via command-line flags.
 	MinStability featuregate.Stability
 
+	// Features holds the feature flags of the agent.
+	Features map[string]bool
+
 	// ID is the attached components full ID.
 	ID string
 
@@ -226,4 +266,16 @@ type moduleControllerOptions struct {
 	// WorkerPool is a worker pool that can be used to run tasks asynchronously. A default pool will be created if this
 	// is nil.
 	WorkerPool worker.Pool
+
+	// MaxModuleDepth is the maximum number of nested modules allowed.
+	// DefaultMaxModuleDepth is used if MaxModuleDepth is 0.
+	MaxModuleDepth int
+
+	// ModuleChain is the list of module IDs from the root controller down to
+	// the controller which owns this module controller.
+	ModuleChain []string
+
+	// ModuleLock is the lockfile the content of import blocks is checked
+	// against.
+	ModuleLock *modulelock.Lockfile
 }
@@ -0,0 +1,223 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/token/builder"
+	"github.com/grafana/river/vm"
+)
+
+// testBlockID is the name of the blocks which describe example
+// instantiations of declare blocks. It's not "test", which is a common name
+// for custom components.
+const testBlockID = "test_case"
+
+// DefaultModuleTestTimeout is the default value of ModuleTestOptions.Timeout.
+const DefaultModuleTestTimeout = 10 * time.Second
+
+// moduleTestArguments holds the arguments of a test_case block.
+type moduleTestArguments struct {
+	// Arguments of the instance of the custom component.
+	Arguments map[string]any `river:"arguments,attr,optional"`
+
+	// Exports which the instance must export, and their expected values.
+	Exports map[string]any `river:"exports,attr,optional"`
+
+	// Timeout for the exports to match. ModuleTestOptions.Timeout is used if
+	// it's 0.
+	Timeout time.Duration `river:"timeout,attr,optional"`
+}
+
+// ModuleTestOptions configures how RunModuleTests runs tests.
+type ModuleTestOptions struct {
+	// Logger of the sandbox controllers which run the tests. Must not be nil.
+	Logger *logging.Logger
+
+	// MinStability is the minimum stability level of the components which may
+	// be used by the tested declare blocks.
+	MinStability featuregate.Stability
+
+	// NewServices returns the services to run with each sandbox controller.
+	// It may be nil if no services are needed.
+	NewServices func() []service.Service
+
+	// Timeout is how long to wait for the exports of an instance to match
+	// the expected exports, unless the test_case block sets its own timeout.
+	// DefaultModuleTestTimeout is used if Timeout is 0.
+	Timeout time.Duration
+}
+
+// ModuleTestResult is the result of running a single test_case block.
+type ModuleTestResult struct {
+	Declare string // Label of the declare block.
+	Test    string // Label of the test_case block.
+	Err     error  // Why the test failed, or nil if the test passed.
+}
+
+// RunModuleTests runs the test_case blocks of the declare blocks of the
+// River file specified by bb. name is the name of the file used for reporting
+// errors.
+//
+// Every test_case block is run in its own sandbox controller, which loads the
+// whole file and instantiates the declare block with the arguments of the
+// test. The test passes once every expected export of the instance has its
+// expected value.
+//
+// RunModuleTests returns an error if the file can't be parsed. Failures of
+// individual tests are reported in their results.
+func RunModuleTests(ctx context.Context, name string, bb []byte, opts ModuleTestOptions) ([]ModuleTestResult, error) {
+	source, err := ParseSource(name, bb)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultModuleTestTimeout
+	}
+
+	var results []ModuleTestResult
+	for _, declare := range source.declareBlocks {
+		for _, stmt := range declare.Body {
+			test, ok := stmt.(*ast.BlockStmt)
+			if !ok || test.GetBlockName() != testBlockID {
+				continue
+			}
+			results = append(results, ModuleTestResult{
+				Declare: declare.Label,
+				Test:    test.Label,
+				Err:     runModuleTest(ctx, name, bb, declare.Label, test, opts),
+			})
+		}
+	}
+	return results, nil
+}
+
+// runModuleTest runs a single test_case block of the declare block labeled
+// declare.
+func runModuleTest(ctx context.Context, name string, bb []byte, declare string, test *ast.BlockStmt, opts ModuleTestOptions) error {
+	var args moduleTestArguments
+	if err := vm.New(test.Body).Evaluate(nil, &args); err != nil {
+		return fmt.Errorf("decoding test_case block: %w", err)
+	}
+	timeout := opts.Timeout
+	if args.Timeout > 0 {
+		timeout = args.Timeout
+	}
+
+	// Instantiate the declare block alongside the content of the file, so that
+	// it can use the other declare and import blocks of the file.
+	instance := builder.NewBlock([]string{declare}, "test")
+	for _, key := range sortedKeys(args.Arguments) {
+		instance.Body().SetAttributeValue(key, args.Arguments[key])
+	}
+	f := builder.NewFile()
+	f.Body().AppendBlock(instance)
+
+	content := append(append(append([]byte{}, bb...), '\n'), f.Bytes()...)
+	source, err := ParseSource(name, content)
+	if err != nil {
+		return err
+	}
+
+	dataPath, err := os.MkdirTemp("", "agent-test-modules")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dataPath)
+
+	var services []service.Service
+	if opts.NewServices != nil {
+		services = opts.NewServices()
+	}
+	ctrl := New(Options{
+		Logger:       opts.Logger,
+		DataPath:     dataPath,
+		MinStability: opts.MinStability,
+		Services:     services,
+	})
+	if err := ctrl.LoadSource(source, nil); err != nil {
+		return fmt.Errorf("loading instance: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctrl.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	id := component.ID{LocalID: declare + ".test"}
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		info, err := ctrl.GetComponent(id, component.InfoOptions{GetHealth: true, GetExports: true})
+		if err != nil {
+			return err
+		}
+		err = compareModuleExports(args.Exports, info.Exports)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if info.Health.Health == component.HealthTypeUnhealthy {
+				return fmt.Errorf("%w (instance is unhealthy: %s)", err, info.Health.Message)
+			}
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// compareModuleExports returns an error describing the first export of
+// expected which actual doesn't export with the same value. Values are
+// compared through their River representation.
+func compareModuleExports(expected map[string]any, actual component.Exports) error {
+	exports, _ := actual.(map[string]any)
+
+	for _, key := range sortedKeys(expected) {
+		value, ok := exports[key]
+		if !ok {
+			return fmt.Errorf("export %q not found", key)
+		}
+		var (
+			want = riverValue(expected[key])
+			got  = riverValue(value)
+		)
+		if want != got {
+			return fmt.Errorf("export %q: got %s, want %s", key, got, want)
+		}
+	}
+	return nil
+}
+
+func riverValue(v any) string {
+	expr := builder.NewExpr()
+	expr.SetValue(v)
+	return string(expr.Bytes())
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
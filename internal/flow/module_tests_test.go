@@ -0,0 +1,95 @@
+package flow
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunModuleTests(t *testing.T) {
+	file := `
+		declare "add_suffix" {
+			argument "input" {}
+
+			argument "suffix" {
+				optional = true
+				default  = "-suffix"
+			}
+
+			export "output" {
+				value = format("%s%s", argument.input.value, argument.suffix.value)
+			}
+
+			export "parts" {
+				value = [argument.input.value, argument.suffix.value]
+			}
+
+			test_case "default_suffix" {
+				arguments = { input = "a" }
+				exports   = { output = "a-suffix", parts = ["a", "-suffix"] }
+			}
+
+			test_case "wrong_output" {
+				arguments = { input = "a", suffix = "-b" }
+				exports   = { output = "a-c" }
+				timeout   = "200ms"
+			}
+
+			test_case "missing_export" {
+				arguments = { input = "a" }
+				exports   = { missing = true }
+				timeout   = "200ms"
+			}
+		}
+
+		declare "nested" {
+			argument "input" {}
+
+			add_suffix "inner" {
+				input = argument.input.value
+			}
+
+			export "output" {
+				value = add_suffix.inner.output
+			}
+
+			test_case "uses_other_declares" {
+				arguments = { input = "x" }
+				exports   = { output = "x-suffix" }
+			}
+		}
+	`
+
+	l, err := logging.New(os.Stderr, logging.DefaultOptions)
+	require.NoError(t, err)
+
+	results, err := RunModuleTests(context.Background(), "modules.river", []byte(file), ModuleTestOptions{
+		Logger:       l,
+		MinStability: featuregate.StabilityBeta,
+		Timeout:      5 * time.Second,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.Equal(t, "add_suffix", results[0].Declare)
+	require.Equal(t, "default_suffix", results[0].Test)
+	require.NoError(t, results[0].Err)
+
+	require.EqualError(t, results[1].Err, `export "output": got "a-b", want "a-c"`)
+	require.EqualError(t, results[2].Err, `export "missing" not found`)
+
+	require.Equal(t, "nested", results[3].Declare)
+	require.NoError(t, results[3].Err)
+}
+
+func TestParseSource_TopLevelTestBlock(t *testing.T) {
+	_, err := ParseSource("config.river", []byte(`
+		test_case "misplaced" {}
+	`))
+	require.ErrorContains(t, err, "test_case blocks are only allowed inside declare blocks")
+}
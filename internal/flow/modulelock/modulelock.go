@@ -0,0 +1,181 @@
+// Package modulelock records the digests of imported modules in a lockfile,
+// so that modules whose content changes upstream can be refused.
+package modulelock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// FileName is the name of the lockfile in the storage path of the agent.
+const FileName = "modules.lock"
+
+// Mode controls how imported modules are checked against the lockfile.
+type Mode string
+
+const (
+	// ModeOff disables the lockfile.
+	ModeOff Mode = "off"
+	// ModeUpdate records the digest of every imported module in the lockfile,
+	// replacing the digest of modules whose content changed.
+	ModeUpdate Mode = "update"
+	// ModeStrict refuses to load modules which aren't in the lockfile or whose
+	// digest doesn't match the lockfile. The lockfile isn't modified.
+	ModeStrict Mode = "strict"
+)
+
+var _ pflag.Value = (*Mode)(nil)
+
+// AllowedModes returns the allowed values of Mode.
+func AllowedModes() []string {
+	return []string{string(ModeStrict), string(ModeUpdate), string(ModeOff)}
+}
+
+// String implements pflag.Value.
+func (m Mode) String() string { return string(m) }
+
+// Set implements pflag.Value.
+func (m *Mode) Set(s string) error {
+	switch Mode(s) {
+	case ModeOff, ModeUpdate, ModeStrict:
+		*m = Mode(s)
+		return nil
+	}
+	return fmt.Errorf("unknown module lock mode %q, must be one of %s", s, strings.Join(AllowedModes(), ", "))
+}
+
+// Type implements pflag.Value.
+func (m Mode) Type() string { return "mode" }
+
+// Entry is the resolved version of an imported module.
+type Entry struct {
+	// Block is the name of the import block, such as import.git.
+	Block string `json:"block"`
+	// Version is the version the module was resolved to, such as the commit
+	// of a Git revision, if the source has versions.
+	Version string `json:"version,omitempty"`
+	// SHA256 is the digest of the content of the module.
+	SHA256 string `json:"sha256"`
+}
+
+// file is the format of the lockfile.
+type file struct {
+	// Modules maps the global IDs of import blocks to their entry.
+	Modules map[string]Entry `json:"modules"`
+}
+
+// Lockfile checks imported modules against the entries of a lockfile. A nil
+// *Lockfile accepts every module.
+type Lockfile struct {
+	path string
+	mode Mode
+
+	mut     sync.Mutex
+	entries map[string]Entry
+}
+
+// Open reads the lockfile at path. A missing lockfile has no entries. Open
+// returns a nil *Lockfile if mode is ModeOff.
+func Open(path string, mode Mode) (*Lockfile, error) {
+	if mode == ModeOff {
+		return nil, nil
+	}
+
+	l := &Lockfile{
+		path:    path,
+		mode:    mode,
+		entries: make(map[string]Entry),
+	}
+
+	bb, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading module lockfile: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(bb, &f); err != nil {
+		return nil, fmt.Errorf("parsing module lockfile %s: %w", path, err)
+	}
+	for id, entry := range f.Modules {
+		l.entries[id] = entry
+	}
+	return l, nil
+}
+
+// Check checks the module imported by the block with the given global ID
+// against the lockfile. In ModeStrict, Check returns an error if the module
+// isn't in the lockfile or if its digest doesn't match. In ModeUpdate, Check
+// records entry and writes the lockfile if entry changed.
+func (l *Lockfile) Check(id string, entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	locked, ok := l.entries[id]
+	switch l.mode {
+	case ModeStrict:
+		if !ok {
+			return fmt.Errorf("module of %s isn't in the module lockfile %s", id, l.path)
+		}
+		if !strings.EqualFold(locked.SHA256, entry.SHA256) {
+			return fmt.Errorf("module of %s doesn't match the module lockfile: expected sha256 %q, got %q", id, locked.SHA256, entry.SHA256)
+		}
+		return nil
+
+	default:
+		if ok && locked == entry {
+			return nil
+		}
+		l.entries[id] = entry
+		return l.write()
+	}
+}
+
+// Entries returns a copy of the entries of the lockfile.
+func (l *Lockfile) Entries() map[string]Entry {
+	if l == nil {
+		return nil
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	entries := make(map[string]Entry, len(l.entries))
+	for id, entry := range l.entries {
+		entries[id] = entry
+	}
+	return entries
+}
+
+// write writes the lockfile atomically. l.mut must be held when calling
+// write.
+func (l *Lockfile) write() error {
+	bb, err := json.MarshalIndent(file{Modules: l.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0770); err != nil {
+		return fmt.Errorf("writing module lockfile: %w", err)
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, append(bb, '\n'), 0660); err != nil {
+		return fmt.Errorf("writing module lockfile: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("writing module lockfile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package modulelock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	entry := Entry{Block: "import.git", Version: "f3b1c2d", SHA256: "aaaa"}
+
+	// Update mode records entries and writes them.
+	l, err := Open(path, ModeUpdate)
+	require.NoError(t, err)
+	require.NoError(t, l.Check("import.git.math", entry))
+	require.FileExists(t, path)
+
+	changed := Entry{Block: "import.git", Version: "e4a5b6c", SHA256: "bbbb"}
+	require.NoError(t, l.Check("import.git.math", changed))
+
+	// Strict mode accepts the recorded entries and refuses anything else.
+	l, err = Open(path, ModeStrict)
+	require.NoError(t, err)
+	require.Equal(t, map[string]Entry{"import.git.math": changed}, l.Entries())
+	require.NoError(t, l.Check("import.git.math", Entry{Block: "import.git", SHA256: "BBBB"}))
+	require.EqualError(t, l.Check("import.git.math", entry), `module of import.git.math doesn't match the module lockfile: expected sha256 "bbbb", got "aaaa"`)
+	require.ErrorContains(t, l.Check("import.http.other", entry), "module of import.http.other isn't in the module lockfile")
+
+	// Off mode accepts everything.
+	l, err = Open(path, ModeOff)
+	require.NoError(t, err)
+	require.Nil(t, l)
+	require.NoError(t, l.Check("import.http.other", entry))
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0660))
+
+	_, err := Open(path, ModeStrict)
+	require.ErrorContains(t, err, "parsing module lockfile")
+}
+
+func TestMode_Set(t *testing.T) {
+	var m Mode
+	require.NoError(t, m.Set("strict"))
+	require.Equal(t, ModeStrict, m)
+	require.EqualError(t, m.Set("loose"), `unknown module lock mode "loose", must be one of strict, update, off`)
+}
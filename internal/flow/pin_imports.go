@@ -0,0 +1,129 @@
+package flow
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/internal/controller"
+	"github.com/grafana/agent/internal/flow/internal/importsource"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/printer"
+	"github.com/grafana/river/token"
+	"github.com/grafana/river/vm"
+)
+
+// digestAttr is the name of the attribute holding the expected digest of
+// imported content.
+const digestAttr = "sha256"
+
+// PinImports fetches the current content of every import.http, import.git,
+// import.s3 and import.gcs block in the River file bb and sets the sha256 attribute of each block to
+// the digest of the fetched content. The rewritten file is returned.
+//
+// Import blocks nested inside declare blocks are pinned as well. Arguments of
+// import blocks must not reference components, as they are evaluated without
+// a running controller. dataPath is used as scratch space for cloning
+// repositories.
+func PinImports(filename string, bb []byte, dataPath string) ([]byte, error) {
+	file, err := parser.ParseFile(filename, bb)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pinImportBlocks(file.Body, dataPath); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, file); err != nil {
+		return nil, err
+	}
+	_, _ = buf.Write([]byte{'\n'})
+	return buf.Bytes(), nil
+}
+
+func pinImportBlocks(body ast.Body, dataPath string) error {
+	for _, stmt := range body {
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+
+		switch name := block.GetBlockName(); name {
+		case importsource.BlockImportHTTP, importsource.BlockImportGit, importsource.BlockImportS3, importsource.BlockImportGCS:
+			digest, err := fetchImportDigest(block, importsource.GetSourceType(name), dataPath)
+			if err != nil {
+				return fmt.Errorf("pinning %s: %w", controller.BlockComponentID(block).String(), err)
+			}
+			setDigestAttr(block, digest)
+		case "declare":
+			if err := pinImportBlocks(block.Body, dataPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fetchImportDigest retrieves the content of an import block once and returns
+// its digest. Any existing digest on the block is ignored.
+func fetchImportDigest(block *ast.BlockStmt, sourceType importsource.SourceType, dataPath string) (string, error) {
+	id := controller.BlockComponentID(block).String()
+
+	var unpinned ast.Body
+	for _, stmt := range block.Body {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok && attr.Name.Name == digestAttr {
+			continue
+		}
+		unpinned = append(unpinned, stmt)
+	}
+
+	var content map[string]string
+	opts := component.Options{
+		ID:       id,
+		Logger:   log.NewNopLogger(),
+		DataPath: filepath.Join(dataPath, id),
+	}
+	source := importsource.NewImportSource(sourceType, opts, vm.New(unpinned), func(c map[string]string) {
+		content = c
+	})
+
+	if err := source.Evaluate(&vm.Scope{Variables: map[string]interface{}{}}); err != nil {
+		return "", err
+	}
+	if content == nil {
+		return "", fmt.Errorf("no content retrieved")
+	}
+	return importsource.ContentDigest(content), nil
+}
+
+// setDigestAttr sets the digest attribute of block, adding it if it doesn't
+// exist yet.
+func setDigestAttr(block *ast.BlockStmt, digest string) {
+	for _, stmt := range block.Body {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok || attr.Name.Name != digestAttr {
+			continue
+		}
+		attr.Value = &ast.LiteralExpr{
+			Kind:     token.STRING,
+			ValuePos: ast.StartPos(attr.Value),
+			Value:    strconv.Quote(digest),
+		}
+		return
+	}
+
+	block.Body = append(block.Body, &ast.AttributeStmt{
+		Name: &ast.Ident{Name: digestAttr, NamePos: block.RCurlyPos},
+		Value: &ast.LiteralExpr{
+			Kind:     token.STRING,
+			ValuePos: block.RCurlyPos,
+			Value:    strconv.Quote(digest),
+		},
+	})
+}
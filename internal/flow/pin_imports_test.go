@@ -0,0 +1,80 @@
+package flow_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/flow"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+const passthroughModule = `declare "a" {
+	argument "input" {}
+
+	testcomponents.passthrough "pt" {
+		input = argument.input.value
+		lag = "1ms"
+	}
+
+	export "output" {
+		value = testcomponents.passthrough.pt.output
+	}
+}`
+
+func TestPinImports(t *testing.T) {
+	var content atomic.String
+	content.Store(passthroughModule)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(content.Load()))
+	}))
+	// Disable keep-alives so idle client connections don't show up as leaked
+	// goroutines.
+	srv.Config.SetKeepAlivesEnabled(false)
+	defer srv.Close()
+
+	config := fmt.Sprintf(`
+testcomponents.count "inc" {
+	frequency = "10ms"
+	max = 10
+}
+
+import.http "testImport" {
+	url = %q
+}
+
+testImport.a "cc" {
+	input = testcomponents.count.inc.count
+}
+
+testcomponents.summation "sum" {
+	input = testImport.a.cc.output
+}
+`, srv.URL)
+
+	pinned, err := flow.PinImports("main.river", []byte(config), t.TempDir())
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte(passthroughModule))
+	require.Contains(t, string(pinned), fmt.Sprintf("sha256 = %q", hex.EncodeToString(digest[:])))
+
+	// Pinning again must replace the existing digest rather than add another.
+	repinned, err := flow.PinImports("main.river", pinned, t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(repinned), "sha256"))
+
+	t.Run("matching digest", func(t *testing.T) {
+		testConfig(t, string(pinned), "", nil)
+	})
+
+	t.Run("tampered content", func(t *testing.T) {
+		content.Store(passthroughModule + "\n// tampered")
+		defer content.Store(passthroughModule)
+		testConfigError(t, string(pinned), "content digest mismatch")
+	})
+}
@@ -22,6 +22,10 @@ type Source struct {
 	components    []*ast.BlockStmt
 	configBlocks  []*ast.BlockStmt
 	declareBlocks []*ast.BlockStmt
+
+	// testBlocks holds the test_case blocks of the body of a declare block.
+	// They're only used by RunModuleTests, and ignored when loading the body.
+	testBlocks []*ast.BlockStmt
 }
 
 // ParseSource parses the River file specified by bb into a File. name should be
@@ -41,6 +45,15 @@ func ParseSource(name string, bb []byte) (*Source, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(source.testBlocks) > 0 {
+		test := source.testBlocks[0]
+		return nil, diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			StartPos: ast.StartPos(test).Position(),
+			EndPos:   ast.EndPos(test).Position(),
+			Message:  "test_case blocks are only allowed inside declare blocks",
+		}
+	}
 	source.sourceMap = map[string][]byte{name: bb}
 	source.hash = sha256.Sum256(bb)
 	return source, nil
@@ -58,6 +71,7 @@ func sourceFromBody(body ast.Body) (*Source, error) {
 		components []*ast.BlockStmt
 		configs    []*ast.BlockStmt
 		declares   []*ast.BlockStmt
+		tests      []*ast.BlockStmt
 	)
 
 	for _, stmt := range body {
@@ -75,7 +89,9 @@ func sourceFromBody(body ast.Body) (*Source, error) {
 			switch fullName {
 			case "declare":
 				declares = append(declares, stmt)
-			case "logging", "tracing", "argument", "export", "import.file", "import.string", "import.http", "import.git":
+			case testBlockID:
+				tests = append(tests, stmt)
+			case "logging", "tracing", "latency_budget", "execution_pool", "export_limit", "argument", "export", "import.file", "import.string", "import.http", "import.git", "import.s3", "import.gcs":
 				configs = append(configs, stmt)
 			default:
 				components = append(components, stmt)
@@ -95,6 +111,7 @@ func sourceFromBody(body ast.Body) (*Source, error) {
 		components:    components,
 		configBlocks:  configs,
 		declareBlocks: declares,
+		testBlocks:    tests,
 	}, nil
 }
 
@@ -9,6 +9,8 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
 	"github.com/grafana/river/diag"
 	"github.com/grafana/river/parser"
 	"github.com/grafana/river/printer"
@@ -27,7 +29,9 @@ River configuration file.
 
 If the file argument is not supplied or if the file argument is "-", then fmt will read from stdin.
 
-The -w flag can be used to write the formatted file back to disk. -w can not be provided when fmt is reading from stdin. When -w is not provided, fmt will write the result to stdout.`,
+The -w flag can be used to write the formatted file back to disk. -w can not be provided when fmt is reading from stdin. When -w is not provided, fmt will write the result to stdout.
+
+The --migrate flag can be used to upgrade the blocks of components written for older versions of their arguments, such as after an argument was renamed. Every applied migration is reported to stderr.`,
 		Args:         cobra.RangeArgs(0, 1),
 		SilenceUsage: true,
 		Aliases:      []string{"format"},
@@ -55,11 +59,13 @@ The -w flag can be used to write the formatted file back to disk. -w can not be
 	}
 
 	cmd.Flags().BoolVarP(&f.write, "write", "w", f.write, "write result to (source) file instead of stdout")
+	cmd.Flags().BoolVar(&f.migrate, "migrate", f.migrate, "upgrade component blocks written for older versions of their arguments")
 	return cmd
 }
 
 type flowFmt struct {
-	write bool
+	write   bool
+	migrate bool
 }
 
 func (ff *flowFmt) Run(configFile string) error {
@@ -68,7 +74,7 @@ func (ff *flowFmt) Run(configFile string) error {
 		if ff.write {
 			return fmt.Errorf("cannot use -w with standard input")
 		}
-		return format("<stdin>", nil, os.Stdin, false)
+		return format("<stdin>", nil, os.Stdin, false, ff.migrate)
 
 	default:
 		fi, err := os.Stat(configFile)
@@ -84,11 +90,11 @@ func (ff *flowFmt) Run(configFile string) error {
 			return err
 		}
 		defer f.Close()
-		return format(configFile, fi, f, ff.write)
+		return format(configFile, fi, f, ff.write, ff.migrate)
 	}
 }
 
-func format(filename string, fi os.FileInfo, r io.Reader, write bool) error {
+func format(filename string, fi os.FileInfo, r io.Reader, write bool, migrate bool) error {
 	bb, err := io.ReadAll(r)
 	if err != nil {
 		return err
@@ -99,6 +105,13 @@ func format(filename string, fi os.FileInfo, r io.Reader, write bool) error {
 		return err
 	}
 
+	if migrate {
+		f.Body, err = migrateBody(f.Body, component.Get, os.Stderr)
+		if err != nil {
+			return err
+		}
+	}
+
 	var buf bytes.Buffer
 	if err := printer.Fprint(&buf, f); err != nil {
 		return err
@@ -121,3 +134,64 @@ func format(filename string, fi os.FileInfo, r io.Reader, write bool) error {
 	_, err = io.Copy(wf, &buf)
 	return err
 }
+
+// migrateBody applies the migrations of the components of body, including
+// the components of declare blocks, and reports the applied migrations to
+// out. Blocks of components which aren't found by lookup are left unchanged.
+func migrateBody(body ast.Body, lookup func(name string) (component.Registration, bool), out io.Writer) (ast.Body, error) {
+	var (
+		res   = make(ast.Body, len(body))
+		diags diag.Diagnostics
+	)
+	for i, stmt := range body {
+		res[i] = stmt
+
+		block, ok := stmt.(*ast.BlockStmt)
+		if !ok {
+			continue
+		}
+
+		if block.GetBlockName() == "declare" {
+			inner, err := migrateBody(block.Body, lookup, out)
+			if err != nil {
+				return nil, err
+			}
+			migrated := *block
+			migrated.Body = inner
+			res[i] = &migrated
+			continue
+		}
+
+		registration, ok := lookup(block.GetBlockName())
+		if !ok {
+			continue
+		}
+		migrated, applied, err := registration.Migrate(block)
+		if err != nil {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				Message:  fmt.Sprintf("failed to migrate %s: %s", blockID(block), err),
+				StartPos: ast.StartPos(block).Position(),
+				EndPos:   ast.EndPos(block).Position(),
+			})
+			continue
+		}
+		for _, m := range applied {
+			fmt.Fprintf(out, "%s: migrated %s to version %d: %s\n", ast.StartPos(block).Position(), blockID(block), m.Version, m.Description)
+		}
+		res[i] = migrated
+	}
+
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return res, nil
+}
+
+// blockID returns the name of block followed by its label, if any.
+func blockID(block *ast.BlockStmt) string {
+	if block.Label == "" {
+		return block.GetBlockName()
+	}
+	return fmt.Sprintf("%s.%s", block.GetBlockName(), block.Label)
+}
@@ -0,0 +1,70 @@
+package flowmode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/printer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateBody(t *testing.T) {
+	lookup := func(name string) (component.Registration, bool) {
+		if name != "remote.example" {
+			return component.Registration{}, false
+		}
+		return component.Registration{
+			Name:       name,
+			Migrations: []component.Migration{component.RenameAttribute(2, "url", "address")},
+		}, true
+	}
+
+	f, err := parser.ParseFile("config.river", []byte(`remote.example "a" {
+	url = "http://a"
+}
+
+local.file "url" {
+	url = "ignored"
+}
+
+declare "example" {
+	remote.example "b" {
+		url = "http://b"
+	}
+}`))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	f.Body, err = migrateBody(f.Body, lookup, &out)
+	require.NoError(t, err)
+	require.Equal(t, `config.river:1:1: migrated remote.example.a to version 2: the "url" argument was renamed to "address"
+config.river:10:2: migrated remote.example.b to version 2: the "url" argument was renamed to "address"
+`, out.String())
+
+	var buf bytes.Buffer
+	require.NoError(t, printer.Fprint(&buf, f))
+	require.Equal(t, `remote.example "a" {
+	address = "http://a"
+}
+
+local.file "url" {
+	url = "ignored"
+}
+
+declare "example" {
+	remote.example "b" {
+		address = "http://b"
+	}
+}`, buf.String())
+
+	// Migration failures are reported as diagnostics.
+	f, err = parser.ParseFile("config.river", []byte(`remote.example "a" {
+	url     = "http://a"
+	address = "http://a"
+}`))
+	require.NoError(t, err)
+	_, err = migrateBody(f.Body, lookup, &out)
+	require.ErrorContains(t, err, "failed to migrate remote.example.a")
+}
@@ -0,0 +1,213 @@
+package flowmode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/spf13/cobra"
+)
+
+func healthcheckCommand() *cobra.Command {
+	hc := &flowHealthcheck{
+		addr:     "127.0.0.1:12345",
+		uiPrefix: "/",
+		timeout:  5 * time.Second,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "healthcheck [flags]",
+		Short: "Check the health of a running Grafana Agent Flow",
+		Long: `The healthcheck subcommand queries the HTTP server of a running Grafana
+Agent Flow, and exits with a non-zero status if it isn't ready or if any of
+its components is unhealthy or has exited. The unhealthy components are
+listed along with their health message.
+
+When --component is provided, only the components whose ID matches one of
+the given patterns are checked, and every pattern must match at least one
+component. Patterns use the syntax of path.Match, so that "prometheus.*"
+matches every prometheus component of the root module.
+
+healthcheck is intended for container HEALTHCHECK instructions and liveness
+probes, which then don't need any other tool in the image.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := context.WithTimeout(cmd.Context(), hc.timeout)
+			defer cancel()
+			return hc.Run(ctx, os.Stdout)
+		},
+	}
+
+	cmd.Flags().StringVar(&hc.addr, "server.http.address", hc.addr, "Address of the HTTP server of the agent to check")
+	cmd.Flags().StringVar(&hc.uiPrefix, "server.http.ui-path-prefix", hc.uiPrefix, "Prefix the agent serves the HTTP UI at")
+	cmd.Flags().StringSliceVar(&hc.components, "component", hc.components, "Patterns of the IDs of the components to check. Checks every component if empty")
+	cmd.Flags().DurationVar(&hc.timeout, "timeout", hc.timeout, "Timeout for the checks")
+	return cmd
+}
+
+type flowHealthcheck struct {
+	addr       string
+	uiPrefix   string
+	components []string
+	timeout    time.Duration
+}
+
+// healthcheckComponent is the subset of the component details returned by
+// the API of the UI which is used by healthcheck.
+type healthcheckComponent struct {
+	LocalID  string `json:"localID"`
+	ModuleID string `json:"moduleID"`
+	Health   struct {
+		State   string `json:"state"`
+		Message string `json:"message"`
+	} `json:"health"`
+	CreatedModuleIDs []string `json:"createdModuleIDs"`
+}
+
+func (c *healthcheckComponent) ID() string {
+	return component.ID{ModuleID: c.ModuleID, LocalID: c.LocalID}.String()
+}
+
+// Run checks the health of the agent, writing a summary to w. Run returns an
+// error if the agent is unhealthy.
+func (hc *flowHealthcheck) Run(ctx context.Context, w io.Writer) error {
+	for _, pattern := range hc.components {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --component pattern %q: %w", pattern, err)
+		}
+	}
+
+	if err := hc.checkReady(ctx); err != nil {
+		return err
+	}
+
+	components, err := hc.listComponents(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	var (
+		checked   int
+		unhealthy []string
+		matched   = make(map[string]bool, len(hc.components))
+	)
+	for _, c := range components {
+		if !hc.selected(c.ID(), matched) {
+			continue
+		}
+		checked++
+
+		// Components which didn't report their health yet are healthy.
+		switch c.Health.State {
+		case "unhealthy", "exited":
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: %s: %s", c.ID(), c.Health.State, c.Health.Message))
+		}
+	}
+
+	for _, pattern := range hc.components {
+		if !matched[pattern] {
+			return fmt.Errorf("no component matches %q", pattern)
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		for _, line := range unhealthy {
+			fmt.Fprintln(w, line)
+		}
+		return fmt.Errorf("%d of %d components are unhealthy", len(unhealthy), checked)
+	}
+	fmt.Fprintf(w, "agent is ready, %d components are healthy\n", checked)
+	return nil
+}
+
+// selected reports whether the component with the given ID is checked,
+// recording the patterns which match it in matched.
+func (hc *flowHealthcheck) selected(id string, matched map[string]bool) bool {
+	if len(hc.components) == 0 {
+		return true
+	}
+	var ok bool
+	for _, pattern := range hc.components {
+		if m, _ := path.Match(pattern, id); m {
+			matched[pattern] = true
+			ok = true
+		}
+	}
+	return ok
+}
+
+func (hc *flowHealthcheck) checkReady(ctx context.Context) error {
+	resp, err := hc.get(ctx, "/-/ready")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("agent is not ready: %s", resp.Status)
+	}
+	return nil
+}
+
+// listComponents returns the components of the module with the given ID,
+// including the components of the modules they created.
+func (hc *flowHealthcheck) listComponents(ctx context.Context, moduleID string) ([]healthcheckComponent, error) {
+	p := path.Join(hc.uiPrefix, "/api/v0/web/components")
+	if moduleID != "" {
+		p = path.Join(hc.uiPrefix, "/api/v0/web/modules", moduleID, "components")
+	}
+
+	resp, err := hc.get(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing components: %s", resp.Status)
+	}
+	var components []healthcheckComponent
+	if err := json.NewDecoder(resp.Body).Decode(&components); err != nil {
+		return nil, fmt.Errorf("listing components: %w", err)
+	}
+
+	all := components
+	for _, c := range components {
+		for _, id := range c.CreatedModuleIDs {
+			nested, err := hc.listComponents(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, nested...)
+		}
+	}
+	return all, nil
+}
+
+func (hc *flowHealthcheck) get(ctx context.Context, p string) (*http.Response, error) {
+	addr := hc.addr
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	base, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --server.http.address: %w", err)
+	}
+	base.Path = p
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,90 @@
+package flowmode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthcheck(t *testing.T) {
+	ready := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/-/ready":
+			if !ready {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/ui/api/v0/web/components":
+			fmt.Fprint(w, `[
+				{"localID": "prometheus.scrape.default", "moduleID": "", "health": {"state": "healthy"}},
+				{"localID": "loki.write.default", "moduleID": "", "health": {"state": "unhealthy", "message": "connection refused"}},
+				{"localID": "import.file.mod", "moduleID": "", "health": {"state": "healthy"}, "createdModuleIDs": ["import.file.mod"]}
+			]`)
+		case "/ui/api/v0/web/modules/import.file.mod/components":
+			fmt.Fprint(w, `[
+				{"localID": "prometheus.remote_write.default", "moduleID": "import.file.mod", "health": {"state": "exited", "message": "component shut down"}},
+				{"localID": "discovery.kubernetes.pods", "moduleID": "import.file.mod", "health": {"state": "unknown"}}
+			]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tt := []struct {
+		name       string
+		notReady   bool
+		components []string
+		output     string
+		err        string
+	}{
+		{
+			name: "all components",
+			output: "loki.write.default: unhealthy: connection refused\n" +
+				"import.file.mod/prometheus.remote_write.default: exited: component shut down\n",
+			err: "2 of 5 components are unhealthy",
+		},
+		{
+			name:       "healthy components",
+			components: []string{"prometheus.*", "import.file.mod/discovery.*"},
+			output:     "agent is ready, 2 components are healthy\n",
+		},
+		{
+			name:       "unmatched pattern",
+			components: []string{"prometheus.*", "otelcol.*"},
+			err:        `no component matches "otelcol.*"`,
+		},
+		{
+			name:     "not ready",
+			notReady: true,
+			err:      "agent is not ready: 503 Service Unavailable",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ready = !tc.notReady
+			hc := &flowHealthcheck{
+				addr:       srv.URL,
+				uiPrefix:   "/ui",
+				components: tc.components,
+				timeout:    time.Second,
+			}
+
+			var out bytes.Buffer
+			err := hc.Run(context.Background(), &out)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.output, out.String())
+		})
+	}
+}
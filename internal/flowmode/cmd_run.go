@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -26,12 +27,19 @@ import (
 	"github.com/grafana/agent/internal/flow"
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/flow/modulelock"
 	"github.com/grafana/agent/internal/flow/tracing"
 	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/drops"
+	"github.com/grafana/agent/internal/service/egress"
 	httpservice "github.com/grafana/agent/internal/service/http"
 	"github.com/grafana/agent/internal/service/labelstore"
 	otel_service "github.com/grafana/agent/internal/service/otel"
+	"github.com/grafana/agent/internal/service/ports"
 	remotecfgservice "github.com/grafana/agent/internal/service/remotecfg"
+	"github.com/grafana/agent/internal/service/selfupdate"
+	"github.com/grafana/agent/internal/service/storagegc"
+	"github.com/grafana/agent/internal/service/synthetic"
 	uiservice "github.com/grafana/agent/internal/service/ui"
 	"github.com/grafana/agent/internal/static/config/instrumentation"
 	"github.com/grafana/agent/internal/usagestats"
@@ -60,6 +68,9 @@ func runCommand() *cobra.Command {
 		clusterAdvInterfaces:  advertise.DefaultInterfaces,
 		ClusterMaxJoinPeers:   5,
 		clusterRejoinInterval: 60 * time.Second,
+		maxModuleDepth:        flow.DefaultMaxModuleDepth,
+		profile:               profileDefault,
+		moduleLockMode:        modulelock.ModeOff,
 	}
 
 	cmd := &cobra.Command{
@@ -90,12 +101,53 @@ Additionally, the HTTP server exposes the following debug endpoints:
 If reloading the config dir/file-path fails, Grafana Agent Flow will continue running in
 its last valid state. Components which failed may be be listed as unhealthy,
 depending on the nature of the reload error.
+
+When --dry-run is provided, run loads the config dir/file-path, resolves imports
+and builds every component without running them, then prints a JSON report of
+the loaded components and exits. run exits with a non-zero status if the load
+failed.
+
+When --storage.fsck is provided, run checks the integrity of the positions
+files, write-ahead logs and databases stored in --storage.path before starting
+any component. Unreadable data is moved to a new directory under the recovery
+directory of --storage.path, so that components start from a clean state.
+
+run locks --storage.path while it runs, and exits with an error if another
+instance already uses it. Pass --storage.allow-shared to start anyway.
+
+run records the digest of the content of every import block in the
+modules.lock file of --storage.path. When --config.module-lock-mode=strict is
+provided, run refuses to load modules which aren't in the lockfile or whose
+content no longer matches it. Pass --config.module-lock-mode=off to disable
+the lockfile.
+
+When --profile=minimal is provided, run disables the debugging UI and
+clustering, and sets a soft memory limit of 128MiB unless GOMEMLIMIT is set,
+to fit devices with little memory.
+
+When --exit-report.path is provided and run exits because of a fatal error,
+a JSON report describing the error is written to the given path.
 `,
 		Args:         cobra.ExactArgs(1),
 		SilenceUsage: true,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return r.Run(args[0])
+			if r.exitReportPath == "" {
+				return r.Run(args[0])
+			}
+
+			// Remove the report of a previous exit, so that it isn't mistaken
+			// for the report of this run.
+			if err := os.Remove(r.exitReportPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("removing previous exit report: %w", err)
+			}
+			err := r.Run(args[0])
+			if err != nil {
+				if werr := writeExitReport(r.exitReportPath, err, time.Now()); werr != nil {
+					fmt.Fprintf(os.Stderr, "failed to write exit report: %s\n", werr)
+				}
+			}
+			return err
 		},
 	}
 
@@ -131,11 +183,18 @@ depending on the nature of the reload error.
 	cmd.Flags().StringVar(&r.configFormat, "config.format", r.configFormat, fmt.Sprintf("The format of the source file. Supported formats: %s.", supportedFormatsList()))
 	cmd.Flags().BoolVar(&r.configBypassConversionErrors, "config.bypass-conversion-errors", r.configBypassConversionErrors, "Enable bypassing errors when converting")
 	cmd.Flags().StringVar(&r.configExtraArgs, "config.extra-args", r.configExtraArgs, "Extra arguments from the original format used by the converter. Multiple arguments can be passed by separating them with a space.")
+	cmd.Flags().Var(&r.moduleLockMode, "config.module-lock-mode", fmt.Sprintf("How to check imported modules against the lockfile in --storage.path. Supported values: %s", strings.Join(modulelock.AllowedModes(), ", ")))
+	cmd.Flags().IntVar(&r.maxModuleDepth, "config.max-module-depth", r.maxModuleDepth, "Maximum number of modules and custom components which can be nested inside each other")
 
 	// Misc flags
 	cmd.Flags().
 		BoolVar(&r.disableReporting, "disable-reporting", r.disableReporting, "Disable reporting of enabled components to Grafana.")
 	cmd.Flags().StringVar(&r.storagePath, "storage.path", r.storagePath, "Base directory where components can store data")
+	cmd.Flags().BoolVar(&r.storageAllowShared, "storage.allow-shared", r.storageAllowShared, "Start even if another instance uses --storage.path")
+	cmd.Flags().BoolVar(&r.storageFsck, "storage.fsck", r.storageFsck, "Check the integrity of the data in --storage.path before starting, and move unreadable data to a recovery directory")
+	cmd.Flags().StringVar(&r.exitReportPath, "exit-report.path", r.exitReportPath, "Path to write a JSON report of the error to when exiting because of a fatal error")
+	cmd.Flags().BoolVar(&r.dryRun, "dry-run", r.dryRun, "Load the configuration and build all components without running them, print a report and exit")
+	cmd.Flags().Var(&r.profile, "profile", fmt.Sprintf("Profile of subsystems to enable. Supported values: %s", strings.Join(allowedProfiles(), ", ")))
 	cmd.Flags().Var(&r.minStability, "stability.level", fmt.Sprintf("Minimum stability level of features to enable. Supported values: %s", strings.Join(featuregate.AllowedValues(), ", ")))
 	return cmd
 }
@@ -160,9 +219,53 @@ type flowRun struct {
 	configFormat                 string
 	configBypassConversionErrors bool
 	configExtraArgs              string
+	maxModuleDepth               int
+	moduleLockMode               modulelock.Mode
+	dryRun                       bool
+	storageFsck                  bool
+	storageAllowShared           bool
+	exitReportPath               string
+	profile                      runProfile
 }
 
 func (fr *flowRun) Run(configPath string) error {
+	profile := fr.profile.settings()
+	if fr.clusterEnabled && !profile.allowClustering {
+		return fmt.Errorf("clustering can't be enabled with --profile=%s", fr.profile)
+	}
+
+	// Once everything else has shut down, replace the process with the binary
+	// installed by the selfupdate service, if any.
+	var selfUpdateService *selfupdate.Service
+	defer func() {
+		if selfUpdateService == nil {
+			return
+		}
+		if path := selfUpdateService.Installed(); path != "" {
+			fmt.Fprintf(os.Stderr, "starting updated binary %s\n", path)
+			if err := selfupdate.Exec(path); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to start updated binary: %s\n", err)
+			}
+		}
+	}()
+
+	// The storage path stays locked until everything else has shut down.
+	var storage *storageLock
+	defer func() {
+		if storage != nil {
+			_ = storage.Release()
+		}
+	}()
+
+	// The temporary storage path of a dry run is removed once everything else
+	// has shut down.
+	var dryRunPath string
+	defer func() {
+		if dryRunPath != "" {
+			_ = os.RemoveAll(dryRunPath)
+		}
+	}()
+
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
@@ -193,6 +296,7 @@ func (fr *flowRun) Run(configPath string) error {
 
 	// Enable the profiling.
 	setMutexBlockProfiling(l)
+	applyMemoryLimit(l, profile)
 
 	// Immediately start the tracer.
 	go func() {
@@ -202,6 +306,45 @@ func (fr *flowRun) Run(configPath string) error {
 		}
 	}()
 
+	// A dry run doesn't use --storage.path, so that it can check the
+	// configuration of an instance which is running.
+	storagePath := fr.storagePath
+	if fr.dryRun {
+		if dryRunPath, err = dryRunStoragePath(fr.storagePath); err != nil {
+			return newFatalError(exitStorageError, err)
+		}
+		storagePath = dryRunPath
+	}
+
+	// Lock the storage path before anything reads it, since instances sharing
+	// a storage path corrupt the state of each other's components.
+	switch {
+	case fr.dryRun:
+		// Nothing else uses the storage path of a dry run.
+	case fr.storageAllowShared:
+		level.Warn(l).Log("msg", "storage path is not locked, other instances using it can corrupt the state of components", "path", fr.storagePath)
+	default:
+		if storage, err = lockStorage(fr.storagePath); err != nil {
+			var lockedErr *storageLockedError
+			if errors.As(err, &lockedErr) {
+				return newFatalError(exitStorageLocked, err)
+			}
+			return newFatalError(exitStorageError, err)
+		}
+	}
+
+	// Check the data of components before anything reads it.
+	if fr.storageFsck && !fr.dryRun {
+		if _, err := runStorageFsck(l, fr.storagePath, time.Now()); err != nil {
+			return newFatalError(exitStorageError, fmt.Errorf("checking storage: %w", err))
+		}
+	}
+
+	moduleLock, err := modulelock.Open(filepath.Join(storagePath, modulelock.FileName), fr.moduleLockMode)
+	if err != nil {
+		return newFatalError(exitStorageError, err)
+	}
+
 	// TODO(rfratto): many of the dependencies we import register global metrics,
 	// even when their code isn't being used. To reduce the number of series
 	// generated by the agent, we should switch to a custom registry.
@@ -210,7 +353,7 @@ func (fr *flowRun) Run(configPath string) error {
 	// registry that we want to keep can be given a custom registry so desired
 	// metrics are still exposed.
 	reg := prometheus.DefaultRegisterer
-	reg.MustRegister(newResourcesCollector(l))
+	reg.MustRegister(newResourcesCollector(l), boringcrypto.NewCollector())
 
 	// There's a cyclic dependency between the definition of the Flow controller,
 	// the reload/ready functions, and the HTTP service.
@@ -222,6 +365,26 @@ func (fr *flowRun) Run(configPath string) error {
 		ready  func() bool
 	)
 
+	notifier := systemdNotifier{log: l}
+
+	// Accept HTTP traffic on the listener passed by systemd socket activation,
+	// if any, in place of --server.http.listen-addr.
+	httpListener, err := systemdHTTPListener()
+	if err != nil {
+		return fmt.Errorf("failed to use systemd socket activation: %w", err)
+	}
+	if httpListener != nil {
+		fr.httpListenAddr = httpListener.Addr().String()
+		level.Info(l).Log("msg", "using HTTP listener from systemd socket activation", "addr", fr.httpListenAddr)
+	} else if !fr.dryRun {
+		// Listen before starting the HTTP service, which can't stop the agent
+		// if it fails to listen.
+		httpListener, err = net.Listen("tcp", fr.httpListenAddr)
+		if err != nil {
+			return newFatalError(exitHTTPListen, fmt.Errorf("failed to listen on %s: %w", fr.httpListenAddr, err))
+		}
+	}
+
 	clusterService, err := buildClusterService(clusterOptions{
 		Log:     l,
 		Tracer:  t,
@@ -248,24 +411,28 @@ func (fr *flowRun) Run(configPath string) error {
 		Gatherer: prometheus.DefaultGatherer,
 
 		ReadyFunc:  func() bool { return ready() },
-		ReloadFunc: func() (*flow.Source, error) { return reload() },
+		ReloadFunc: func() (*flow.Source, error) { return notifier.reload(reload) },
 
 		HTTPListenAddr:   fr.httpListenAddr,
 		MemoryListenAddr: fr.inMemoryAddr,
 		EnablePProf:      fr.enablePprof,
+		Listener:         httpListener,
 	})
 
 	remoteCfgService, err := remotecfgservice.New(remotecfgservice.Options{
 		Logger:      log.With(l, "service", "remotecfg"),
-		StoragePath: fr.storagePath,
+		StoragePath: storagePath,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create the remotecfg service: %w", err)
 	}
 
-	uiService := uiservice.New(uiservice.Options{
-		UIPrefix: fr.uiPrefix,
-	})
+	services := []service.Service{httpService}
+	if profile.enableUI {
+		services = append(services, uiservice.New(uiservice.Options{
+			UIPrefix: fr.uiPrefix,
+		}))
+	}
 
 	otelService := otel_service.New(l)
 	if otelService == nil {
@@ -273,22 +440,47 @@ func (fr *flowRun) Run(configPath string) error {
 	}
 
 	labelService := labelstore.New(l, reg)
-	agentseed.Init(fr.storagePath, l)
+	egressService := egress.New(log.With(l, "service", "egress"), reg)
+	selfUpdateService = selfupdate.New(selfupdate.Options{
+		Logger:     log.With(l, "service", "selfupdate"),
+		Registerer: reg,
+		Shutdown:   cancel,
+	})
+	syntheticService := synthetic.New(synthetic.Options{
+		Logger:     log.With(l, "service", "synthetic"),
+		Registerer: reg,
+	})
+	storageGCService := storagegc.New(storagegc.Options{
+		Logger:      log.With(l, "service", "storage_gc"),
+		Registerer:  reg,
+		StoragePath: storagePath,
+		Exclude:     []string{fsckRecoveryDir, remotecfgservice.ServiceName},
+	})
+	portsService := ports.New(reg)
+	dropsService := drops.New()
+	agentseed.Init(storagePath, l)
 
 	f := flow.New(flow.Options{
-		Logger:       l,
-		Tracer:       t,
-		DataPath:     fr.storagePath,
-		Reg:          reg,
-		MinStability: fr.minStability,
-		Services: []service.Service{
-			httpService,
-			uiService,
+		Logger:         l,
+		Tracer:         t,
+		DataPath:       storagePath,
+		Reg:            reg,
+		MinStability:   fr.minStability,
+		Features:       map[string]bool{"clustering": fr.clusterEnabled},
+		MaxModuleDepth: fr.maxModuleDepth,
+		ModuleLock:     moduleLock,
+		Services: append(services,
 			clusterService,
 			otelService,
 			labelService,
 			remoteCfgService,
-		},
+			egressService,
+			selfUpdateService,
+			syntheticService,
+			storageGCService,
+			portsService,
+			dropsService,
+		),
 	})
 
 	ready = f.Ready
@@ -307,6 +499,10 @@ func (fr *flowRun) Run(configPath string) error {
 		return flowSource, nil
 	}
 
+	if fr.dryRun {
+		return fr.runDry(f, reload)
+	}
+
 	// Flow controller
 	{
 		wg.Add(1)
@@ -315,6 +511,7 @@ func (fr *flowRun) Run(configPath string) error {
 			f.Run(ctx)
 		}()
 	}
+	go notifier.runWatchdog(ctx, f)
 
 	// Report usage of enabled components
 	if !fr.disableReporting {
@@ -334,23 +531,8 @@ func (fr *flowRun) Run(configPath string) error {
 	// that /metric and pprof endpoints are available while the Flow controller
 	// is loading.
 	if source, err := reload(); err != nil {
-		var diags diag.Diagnostics
-		if errors.As(err, &diags) {
-			p := diag.NewPrinter(diag.PrinterConfig{
-				Color:              !color.NoColor,
-				ContextLinesBefore: 1,
-				ContextLinesAfter:  1,
-			})
-			_ = p.Fprint(os.Stderr, source.RawConfigs(), diags)
-
-			// Print newline after the diagnostics.
-			fmt.Println()
-
-			return fmt.Errorf("could not perform the initial load successfully")
-		}
-
 		// Exit if the initial load fails.
-		return err
+		return initialLoadError(source, err)
 	}
 
 	// By now, have either joined or started a new cluster.
@@ -361,6 +543,7 @@ func (fr *flowRun) Run(configPath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to set clusterer state to Participant after initial load")
 	}
+	notifier.ready()
 
 	reloadSignal := make(chan os.Signal, 1)
 	signal.Notify(reloadSignal, syscall.SIGHUP)
@@ -369,9 +552,10 @@ func (fr *flowRun) Run(configPath string) error {
 	for {
 		select {
 		case <-ctx.Done():
+			notifier.stopping(selfUpdateService.Installed() != "")
 			return nil
 		case <-reloadSignal:
-			if _, err := reload(); err != nil {
+			if _, err := notifier.reload(reload); err != nil {
 				level.Error(l).Log("msg", "failed to reload config", "err", err)
 			} else {
 				level.Info(l).Log("msg", "config reloaded")
@@ -380,6 +564,46 @@ func (fr *flowRun) Run(configPath string) error {
 	}
 }
 
+// runDry performs the initial load of the configuration without running the
+// Flow controller, and writes a report of the loaded components to stdout.
+func (fr *flowRun) runDry(f *flow.Flow, reload func() (*flow.Source, error)) error {
+	source, err := reload()
+
+	report := buildDryRunReport(f, err)
+	if werr := writeDryRunReport(os.Stdout, report); werr != nil {
+		return fmt.Errorf("writing dry run report: %w", werr)
+	}
+	if err != nil {
+		return initialLoadError(source, err)
+	}
+	if !report.Success {
+		return newFatalError(exitConfigInvalid, fmt.Errorf("dry run found unhealthy components"))
+	}
+	return nil
+}
+
+// initialLoadError prints the diagnostics of a failed initial load, if any,
+// and returns the error to exit with.
+func initialLoadError(source *flow.Source, err error) error {
+	var diags diag.Diagnostics
+	if errors.As(err, &diags) {
+		p := diag.NewPrinter(diag.PrinterConfig{
+			Color:              !color.NoColor,
+			ContextLinesBefore: 1,
+			ContextLinesAfter:  1,
+		})
+		_ = p.Fprint(os.Stderr, source.RawConfigs(), diags)
+
+		// Print newline after the diagnostics.
+		fmt.Println()
+
+		fe := newFatalError(exitConfigInvalid, fmt.Errorf("could not perform the initial load successfully"))
+		fe.Diagnostics = diags
+		return fe
+	}
+	return newFatalError(exitConfigInvalid, err)
+}
+
 // getEnabledComponentsFunc returns a function that gets the current enabled components
 func getEnabledComponentsFunc(f *flow.Flow) func() map[string]interface{} {
 	return func() map[string]interface{} {
@@ -0,0 +1,147 @@
+package flowmode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/labelstore"
+	otel_service "github.com/grafana/agent/internal/service/otel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+)
+
+func testModulesCommand() *cobra.Command {
+	tm := &flowTestModules{
+		minStability: featuregate.StabilityExperimental,
+		timeout:      flow.DefaultModuleTestTimeout,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "test-modules [flags] path",
+		Short: "Run the tests of modules",
+		Long: `The test-modules subcommand runs the test_case blocks of the declare blocks
+in the specified River file, or in every .river file of the specified
+directory and its subdirectories.
+
+Every test_case block instantiates its declare block in a sandbox controller with
+the arguments of the test, and passes once the exports of the instance match
+the expected exports of the test.
+
+test-modules exits with a non-zero status if any test fails.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tm.Run(cmd.Context(), cmd.OutOrStdout(), args[0])
+		},
+	}
+
+	cmd.Flags().Var(&tm.minStability, "stability.level", fmt.Sprintf("Minimum stability level of features to enable. Supported values: %s", strings.Join(featuregate.AllowedValues(), ", ")))
+	cmd.Flags().DurationVar(&tm.timeout, "timeout", tm.timeout, "How long to wait for the exports of a test to match, unless the test case sets its own timeout")
+	cmd.Flags().BoolVarP(&tm.verbose, "verbose", "v", tm.verbose, "Show the logs of the sandbox controllers")
+	return cmd
+}
+
+type flowTestModules struct {
+	minStability featuregate.Stability
+	timeout      time.Duration
+	verbose      bool
+}
+
+// Run runs the tests of the River files at path, and writes their results to
+// w.
+func (tm *flowTestModules) Run(ctx context.Context, w io.Writer, path string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	files, err := riverFiles(path)
+	if err != nil {
+		return err
+	}
+
+	logOutput := io.Discard
+	if tm.verbose {
+		logOutput = os.Stderr
+	}
+	l, err := logging.New(logOutput, logging.DefaultOptions)
+	if err != nil {
+		return err
+	}
+
+	opts := flow.ModuleTestOptions{
+		Logger:       l,
+		MinStability: tm.minStability,
+		Timeout:      tm.timeout,
+		NewServices: func() []service.Service {
+			services := []service.Service{labelstore.New(l, prometheus.NewRegistry())}
+			if otelService := otel_service.New(l); otelService != nil {
+				services = append(services, otelService)
+			}
+			return services
+		},
+	}
+
+	var passed, failed int
+	for _, file := range files {
+		bb, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		results, err := flow.RunModuleTests(ctx, file, bb, opts)
+		if err != nil {
+			fmt.Fprintf(w, "FAIL %s: %s\n", file, err)
+			failed++
+			continue
+		}
+
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Fprintf(w, "FAIL %s %s/%s: %s\n", file, r.Declare, r.Test, r.Err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(w, "ok   %s %s/%s\n", file, r.Declare, r.Test)
+			passed++
+		}
+	}
+
+	fmt.Fprintf(w, "%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d module tests failed", failed)
+	}
+	return nil
+}
+
+// riverFiles returns path if it's a file, or the .river files of the
+// directory at path and its subdirectories.
+func riverFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(p) == ".river" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,50 @@
+package flowmode
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestModules(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib", "modules.river"), []byte(`
+		declare "identity" {
+			argument "input" {}
+
+			export "output" {
+				value = argument.input.value
+			}
+
+			test_case "passes" {
+				arguments = { input = "a" }
+				exports   = { output = "a" }
+			}
+
+			test_case "fails" {
+				arguments = { input = "a" }
+				exports   = { output = "b" }
+				timeout   = "100ms"
+			}
+		}
+	`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a module"), 0600))
+
+	tm := &flowTestModules{minStability: featuregate.StabilityBeta, timeout: 5 * time.Second}
+
+	var out bytes.Buffer
+	err := tm.Run(context.Background(), &out, dir)
+	require.EqualError(t, err, "1 module tests failed")
+
+	file := filepath.Join(dir, "lib", "modules.river")
+	require.Equal(t, "ok   "+file+" identity/passes\n"+
+		"FAIL "+file+` identity/fails: export "output": got "a", want "b"`+"\n"+
+		"1 passed, 1 failed\n", out.String())
+}
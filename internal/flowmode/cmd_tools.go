@@ -2,8 +2,10 @@ package flowmode
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/grafana/agent/internal/component/prometheus/remotewrite"
+	"github.com/grafana/agent/internal/flow"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +18,7 @@ func toolsCommand() *cobra.Command {
 
 	cmd.AddCommand(
 		getTools("prometheus.remote_write", remotewrite.InstallTools),
+		pinImportsCommand(),
 	)
 
 	return cmd
@@ -29,3 +32,50 @@ func getTools(name string, installFunc func(*cobra.Command)) *cobra.Command {
 	installFunc(groupCommand)
 	return groupCommand
 }
+
+func pinImportsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pin-imports [flags] file",
+		Short: "Pin the content digest of import blocks",
+		Long: `The pin-imports subcommand fetches the current content of every import.http,
+import.git, import.s3 and import.gcs block in the specified River file, and
+sets the sha256 attribute of each block to the digest of the fetched content.
+
+The file is rewritten in place. Once pinned, an import block fails to load
+if its content no longer matches the digest.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return pinImports(args[0])
+		},
+	}
+	return cmd
+}
+
+func pinImports(filename string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("cannot pin imports of a directory")
+	}
+
+	bb, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	scratchDir, err := os.MkdirTemp("", "agent-pin-imports")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratchDir)
+
+	pinned, err := flow.PinImports(filename, bb, scratchDir)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, pinned, fi.Mode().Perm())
+}
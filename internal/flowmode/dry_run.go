@@ -0,0 +1,106 @@
+package flowmode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/modulelock"
+)
+
+// dryRunStoragePath creates the temporary directory used as the storage path
+// of a dry run, so that building components doesn't change the data of an
+// instance running with storagePath. The module lockfile of storagePath is
+// copied to the directory, so imports are still checked against it.
+func dryRunStoragePath(storagePath string) (string, error) {
+	dir, err := os.MkdirTemp("", "agent-dry-run-")
+	if err != nil {
+		return "", fmt.Errorf("creating dry run storage path: %w", err)
+	}
+
+	bb, err := os.ReadFile(filepath.Join(storagePath, modulelock.FileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return dir, nil
+	} else if err == nil {
+		err = os.WriteFile(filepath.Join(dir, modulelock.FileName), bb, 0660)
+	}
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("copying module lockfile: %w", err)
+	}
+	return dir, nil
+}
+
+// dryRunReport is the structured report written by `run --dry-run`.
+type dryRunReport struct {
+	// Success is true when the configuration was loaded and no component is
+	// unhealthy.
+	Success bool `json:"success"`
+
+	// Error holds the reason the configuration failed to load, if any.
+	Error string `json:"error,omitempty"`
+
+	// Components lists the components which were built, including the
+	// components of modules and custom components.
+	Components []dryRunComponent `json:"components"`
+}
+
+// dryRunComponent describes a single component in a dryRunReport. As
+// components are built but never run, their health is unknown unless they
+// failed to be evaluated.
+type dryRunComponent struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	ModuleID     string   `json:"moduleID,omitempty"`
+	Health       string   `json:"health"`
+	Message      string   `json:"message,omitempty"`
+	References   []string `json:"referencesTo"`
+	ReferencedBy []string `json:"referencedBy"`
+}
+
+// buildDryRunReport creates a dryRunReport from the components of p. loadErr
+// is the error returned when loading the configuration, if any.
+func buildDryRunReport(p component.Provider, loadErr error) dryRunReport {
+	report := dryRunReport{
+		Success:    loadErr == nil,
+		Components: []dryRunComponent{},
+	}
+	if loadErr != nil {
+		report.Error = loadErr.Error()
+	}
+
+	for _, info := range component.GetAllComponents(p, component.InfoOptions{GetHealth: true}) {
+		c := dryRunComponent{
+			ID:           info.ID.String(),
+			Name:         info.ComponentName,
+			ModuleID:     info.ID.ModuleID,
+			Health:       info.Health.Health.String(),
+			Message:      info.Health.Message,
+			References:   info.References,
+			ReferencedBy: info.ReferencedBy,
+		}
+		if c.References == nil {
+			c.References = []string{}
+		}
+		if c.ReferencedBy == nil {
+			c.ReferencedBy = []string{}
+		}
+		if info.Health.Health == component.HealthTypeUnhealthy {
+			report.Success = false
+		}
+		report.Components = append(report.Components, c)
+	}
+	return report
+}
+
+// writeDryRunReport writes report to w as indented JSON.
+func writeDryRunReport(w io.Writer, report dryRunReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
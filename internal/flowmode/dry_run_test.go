@@ -0,0 +1,165 @@
+package flowmode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/modulelock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDryRunReport(t *testing.T) {
+	p := fakeProvider{
+		"": {
+			{
+				ID:            component.ID{LocalID: "prometheus.scrape.default"},
+				ComponentName: "prometheus.scrape",
+				References:    []string{"module.file.nested"},
+				Health:        component.Health{Health: component.HealthTypeUnknown},
+			},
+			{
+				ID:            component.ID{LocalID: "module.file.nested"},
+				ComponentName: "module.file",
+				ModuleIDs:     []string{"module.file.nested"},
+				ReferencedBy:  []string{"prometheus.scrape.default"},
+				Health:        component.Health{Health: component.HealthTypeUnknown},
+			},
+		},
+		"module.file.nested": {
+			{
+				ID:            component.ID{ModuleID: "module.file.nested", LocalID: "prometheus.remote_write.default"},
+				ComponentName: "prometheus.remote_write",
+				Health:        component.Health{Health: component.HealthTypeUnknown},
+			},
+		},
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		report := buildDryRunReport(p, nil)
+		require.True(t, report.Success)
+		require.Empty(t, report.Error)
+
+		var ids []string
+		for _, c := range report.Components {
+			ids = append(ids, c.ID)
+		}
+		require.Equal(t, []string{
+			"prometheus.scrape.default",
+			"module.file.nested",
+			"module.file.nested/prometheus.remote_write.default",
+		}, ids)
+
+		nested := report.Components[2]
+		require.Equal(t, "module.file.nested", nested.ModuleID)
+		require.Equal(t, "unknown", nested.Health)
+		require.Equal(t, []string{}, nested.References)
+		require.Equal(t, []string{}, nested.ReferencedBy)
+	})
+
+	t.Run("load error", func(t *testing.T) {
+		report := buildDryRunReport(p, errors.New("bad config"))
+		require.False(t, report.Success)
+		require.Equal(t, "bad config", report.Error)
+		require.Len(t, report.Components, 3)
+	})
+
+	t.Run("unhealthy component", func(t *testing.T) {
+		unhealthy := fakeProvider{
+			"": {
+				{
+					ID:            component.ID{LocalID: "prometheus.relabel.default"},
+					ComponentName: "prometheus.relabel",
+					Health: component.Health{
+						Health:  component.HealthTypeUnhealthy,
+						Message: "component evaluation failed",
+					},
+				},
+			},
+		}
+
+		report := buildDryRunReport(unhealthy, nil)
+		require.False(t, report.Success)
+		require.Equal(t, "unhealthy", report.Components[0].Health)
+		require.Equal(t, "component evaluation failed", report.Components[0].Message)
+	})
+}
+
+func TestWriteDryRunReport(t *testing.T) {
+	report := dryRunReport{
+		Success: true,
+		Components: []dryRunComponent{{
+			ID:           "prometheus.scrape.default",
+			Name:         "prometheus.scrape",
+			Health:       "unknown",
+			References:   []string{},
+			ReferencedBy: []string{},
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeDryRunReport(&buf, report))
+
+	var actual map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &actual))
+	require.Equal(t, map[string]interface{}{
+		"success": true,
+		"components": []interface{}{
+			map[string]interface{}{
+				"id":           "prometheus.scrape.default",
+				"name":         "prometheus.scrape",
+				"health":       "unknown",
+				"referencesTo": []interface{}{},
+				"referencedBy": []interface{}{},
+			},
+		},
+	}, actual)
+}
+
+func TestDryRunStoragePath(t *testing.T) {
+	storagePath := t.TempDir()
+	lockfile := []byte(`{"modules":{}}`)
+	require.NoError(t, os.WriteFile(filepath.Join(storagePath, modulelock.FileName), lockfile, 0660))
+
+	dir, err := dryRunStoragePath(storagePath)
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NotEqual(t, storagePath, dir)
+
+	bb, err := os.ReadFile(filepath.Join(dir, modulelock.FileName))
+	require.NoError(t, err)
+	require.Equal(t, lockfile, bb)
+
+	// Storage paths without a lockfile give an empty directory.
+	empty, err := dryRunStoragePath(t.TempDir())
+	require.NoError(t, err)
+	defer os.RemoveAll(empty)
+	entries, err := os.ReadDir(empty)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+// fakeProvider implements component.Provider over a static set of components
+// keyed by module ID.
+type fakeProvider map[string][]*component.Info
+
+func (p fakeProvider) GetComponent(id component.ID, _ component.InfoOptions) (*component.Info, error) {
+	for _, info := range p[id.ModuleID] {
+		if info.ID == id {
+			return info, nil
+		}
+	}
+	return nil, component.ErrComponentNotFound
+}
+
+func (p fakeProvider) ListComponents(moduleID string, _ component.InfoOptions) ([]*component.Info, error) {
+	infos, ok := p[moduleID]
+	if !ok {
+		return nil, component.ErrModuleNotFound
+	}
+	return infos, nil
+}
@@ -0,0 +1,151 @@
+package flowmode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/river/diag"
+)
+
+// exitCode identifies the kind of fatal error which made `run` exit.
+type exitCode string
+
+// Exit codes reported in exit reports. Codes are stable so that orchestration
+// can act on them.
+const (
+	exitConfigInvalid   exitCode = "config_invalid"
+	exitHTTPListen      exitCode = "http_listen_failed"
+	exitStorageLocked   exitCode = "storage_locked"
+	exitStorageError    exitCode = "storage_error"
+	exitInternalFailure exitCode = "internal_error"
+)
+
+// exitCategory tells whether a fatal error is caused by the configuration
+// of the agent or by its environment.
+type exitCategory string
+
+const (
+	exitCategoryConfig      exitCategory = "configuration"
+	exitCategoryEnvironment exitCategory = "environment"
+	exitCategoryInternal    exitCategory = "internal"
+)
+
+var exitCodeDetails = map[exitCode]struct {
+	category exitCategory
+	hint     string
+}{
+	exitConfigInvalid: {
+		category: exitCategoryConfig,
+		hint:     "Fix the errors in the configuration file, and check them with the --dry-run flag before restarting.",
+	},
+	exitHTTPListen: {
+		category: exitCategoryEnvironment,
+		hint:     "Check that no other process listens on --server.http.listen-addr, and that the address is valid on this host.",
+	},
+	exitStorageLocked: {
+		category: exitCategoryEnvironment,
+		hint:     "Stop the other instance, or give each instance its own --storage.path.",
+	},
+	exitStorageError: {
+		category: exitCategoryEnvironment,
+		hint:     "Check that --storage.path is writable by the agent and that its file system has free space. Pass --storage.fsck to move unreadable data aside.",
+	},
+	exitInternalFailure: {
+		category: exitCategoryInternal,
+	},
+}
+
+// fatalError is an error which made `run` exit, classified by its exit code.
+type fatalError struct {
+	Code exitCode
+	Err  error
+
+	// Diagnostics of the configuration file, if any.
+	Diagnostics diag.Diagnostics
+}
+
+func newFatalError(code exitCode, err error) *fatalError {
+	return &fatalError{Code: code, Err: err}
+}
+
+func (e *fatalError) Error() string { return e.Err.Error() }
+func (e *fatalError) Unwrap() error { return e.Err }
+
+// exitReport is the machine-readable report written to --exit-report.path
+// when `run` exits because of a fatal error.
+type exitReport struct {
+	Time        time.Time              `json:"time"`
+	Version     string                 `json:"version"`
+	Code        exitCode               `json:"code"`
+	Category    exitCategory           `json:"category"`
+	Error       string                 `json:"error"`
+	Hint        string                 `json:"hint,omitempty"`
+	Diagnostics []exitReportDiagnostic `json:"diagnostics,omitempty"`
+}
+
+type exitReportDiagnostic struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// buildExitReport returns the report of the fatal error err. Errors which
+// aren't a *fatalError are reported as internal errors.
+func buildExitReport(err error, now time.Time) exitReport {
+	fe := &fatalError{Code: exitInternalFailure, Err: err}
+	_ = errors.As(err, &fe)
+
+	details := exitCodeDetails[fe.Code]
+	report := exitReport{
+		Time:     now.UTC(),
+		Version:  build.Version,
+		Code:     fe.Code,
+		Category: details.category,
+		Error:    err.Error(),
+		Hint:     details.hint,
+	}
+	for _, d := range fe.Diagnostics {
+		severity := "error"
+		if d.Severity == diag.SeverityLevelWarn {
+			severity = "warning"
+		}
+		report.Diagnostics = append(report.Diagnostics, exitReportDiagnostic{
+			Severity: severity,
+			File:     d.StartPos.Filename,
+			Line:     d.StartPos.Line,
+			Column:   d.StartPos.Column,
+			Message:  d.Message,
+		})
+	}
+	return report
+}
+
+// writeExitReport writes the report of the fatal error err to path. The
+// report is written to a temporary file first, so that readers never see a
+// partial report.
+func writeExitReport(path string, err error, now time.Time) error {
+	bb, merr := json.MarshalIndent(buildExitReport(err, now), "", "  ")
+	if merr != nil {
+		return merr
+	}
+
+	tmp := path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	if err := os.WriteFile(tmp, append(bb, '\n'), 0640); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("writing exit report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package flowmode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/river/diag"
+	"github.com/grafana/river/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildExitReport(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("config error", func(t *testing.T) {
+		diags := diag.Diagnostics{{
+			Severity: diag.SeverityLevelError,
+			StartPos: token.Position{Filename: "config.river", Line: 3, Column: 5},
+			Message:  "unrecognized attribute name \"foo\"",
+		}}
+		err := initialLoadError(nil, fmt.Errorf("error during the initial grafana/agent load: %w", diags))
+
+		report := buildExitReport(err, now)
+		require.Equal(t, exitConfigInvalid, report.Code)
+		require.Equal(t, exitCategoryConfig, report.Category)
+		require.Equal(t, "could not perform the initial load successfully", report.Error)
+		require.NotEmpty(t, report.Hint)
+		require.Equal(t, []exitReportDiagnostic{{
+			Severity: "error",
+			File:     "config.river",
+			Line:     3,
+			Column:   5,
+			Message:  "unrecognized attribute name \"foo\"",
+		}}, report.Diagnostics)
+	})
+
+	t.Run("environment error", func(t *testing.T) {
+		err := newFatalError(exitStorageLocked, &storageLockedError{Path: "data-agent"})
+
+		report := buildExitReport(fmt.Errorf("wrapped: %w", err), now)
+		require.Equal(t, exitStorageLocked, report.Code)
+		require.Equal(t, exitCategoryEnvironment, report.Category)
+		require.Contains(t, report.Error, "storage path data-agent is in use")
+	})
+
+	t.Run("unclassified error", func(t *testing.T) {
+		report := buildExitReport(errors.New("something failed"), now)
+		require.Equal(t, exitInternalFailure, report.Code)
+		require.Equal(t, exitCategoryInternal, report.Category)
+		require.Empty(t, report.Hint)
+	})
+}
+
+func TestWriteExitReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports", "exit.json")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err := newFatalError(exitHTTPListen, errors.New("failed to listen on 127.0.0.1:12345: address already in use"))
+	require.NoError(t, writeExitReport(path, err, now))
+
+	bb, rerr := os.ReadFile(path)
+	require.NoError(t, rerr)
+
+	var report map[string]any
+	require.NoError(t, json.Unmarshal(bb, &report))
+	require.Equal(t, "2024-01-02T03:04:05Z", report["time"])
+	require.Equal(t, "http_listen_failed", report["code"])
+	require.Equal(t, "environment", report["category"])
+	require.Equal(t, "failed to listen on 127.0.0.1:12345: address already in use", report["error"])
+	require.NotContains(t, report, "diagnostics")
+
+	require.NoFileExists(t, path+".tmp")
+}
@@ -26,7 +26,9 @@ func Run() {
 	cmd.AddCommand(
 		convertCommand(),
 		fmtCommand(),
+		healthcheckCommand(),
 		runCommand(),
+		testModulesCommand(),
 		toolsCommand(),
 	)
 
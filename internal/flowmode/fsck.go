@@ -0,0 +1,294 @@
+package flowmode
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component/common/loki/positions"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"go.etcd.io/bbolt"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// fsckRecoveryDir is the directory, relative to the storage path, unreadable
+// state is moved to by `run --storage.fsck`.
+const fsckRecoveryDir = "recovery"
+
+// walCheckpointPrefix is the prefix of the names of write-ahead log
+// checkpoint directories.
+const walCheckpointPrefix = "checkpoint."
+
+// boltMagic is the magic number found in the meta pages of bbolt databases,
+// such as the ones written by otelcol.storage.file.
+const boltMagic = 0xED0CDAED
+
+// fsckReport summarizes the integrity pass run by `run --storage.fsck`.
+type fsckReport struct {
+	// RecoveryDir is the directory unreadable state was moved to.
+	RecoveryDir string
+
+	// Checked is the number of positions files, WAL segments and databases
+	// which were checked.
+	Checked int
+
+	// Quarantined lists the state which was moved to RecoveryDir.
+	Quarantined []fsckQuarantined
+}
+
+// fsckQuarantined describes unreadable state moved to the recovery directory.
+type fsckQuarantined struct {
+	Path   string // Path relative to the storage path.
+	Reason string
+}
+
+// storageFsck checks the integrity of the state components persisted in
+// storagePath, and moves the state which can't be read to a new directory
+// under the recovery directory, so that components start from a clean state
+// instead of failing or silently discarding it.
+//
+// The following state is checked:
+//
+//   - Positions files of loki.source components.
+//   - Segments and checkpoints of write-ahead logs, such as the ones of
+//     prometheus.remote_write and loki.write. As segments must be
+//     contiguous, every segment following a corrupted segment is moved too.
+//     Torn writes at the end of the last segment are left to be repaired by
+//     the write-ahead log.
+//   - bbolt databases, such as the ones of otelcol.storage.file.
+type storageFsck struct {
+	storagePath string
+	report      fsckReport
+}
+
+// runStorageFsck runs storageFsck over storagePath and logs a summary of its
+// report. now is used to name the recovery directory.
+func runStorageFsck(l log.Logger, storagePath string, now time.Time) (fsckReport, error) {
+	start := time.Now()
+
+	fsck := &storageFsck{
+		storagePath: storagePath,
+		report: fsckReport{
+			RecoveryDir: filepath.Join(storagePath, fsckRecoveryDir, now.UTC().Format("20060102T150405Z")),
+		},
+	}
+	if err := fsck.run(); err != nil {
+		return fsck.report, err
+	}
+
+	for _, q := range fsck.report.Quarantined {
+		level.Warn(l).Log("msg", "quarantined unreadable state", "path", q.Path, "reason", q.Reason)
+	}
+	if len(fsck.report.Quarantined) > 0 {
+		level.Warn(l).Log("msg", "storage check moved unreadable state to the recovery directory", "recovery_dir", fsck.report.RecoveryDir, "quarantined", len(fsck.report.Quarantined))
+	}
+	level.Info(l).Log("msg", "storage check finished", "path", storagePath, "checked", fsck.report.Checked, "quarantined", len(fsck.report.Quarantined), "duration", time.Since(start))
+	return fsck.report, nil
+}
+
+func (f *storageFsck) run() error {
+	if _, err := os.Stat(f.storagePath); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	recoveryRoot := filepath.Join(f.storagePath, fsckRecoveryDir)
+
+	return filepath.WalkDir(f.storagePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir() && path == recoveryRoot:
+			return filepath.SkipDir
+		case d.IsDir():
+			// Quarantined files and directories are moved before WalkDir lists
+			// the content of the directory, so they aren't visited.
+			return f.checkWAL(path)
+		case d.Name() == "positions.yml":
+			return f.checkPositions(path)
+		case d.Type().IsRegular():
+			return f.checkBolt(path)
+		}
+		return nil
+	})
+}
+
+// checkPositions checks that the positions file at path can be read.
+func (f *storageFsck) checkPositions(path string) error {
+	f.report.Checked++
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var p positions.File
+	if err := yaml.UnmarshalStrict(buf, &p); err != nil {
+		return f.quarantine(path, fmt.Sprintf("invalid positions file: %s", err))
+	}
+	return nil
+}
+
+// checkWAL checks the segments of the write-ahead log in dir, if any.
+func (f *storageFsck) checkWAL(dir string) error {
+	segments, err := listWALSegments(dir)
+	if err != nil || len(segments) == 0 {
+		return err
+	}
+	isCheckpoint := strings.HasPrefix(filepath.Base(dir), walCheckpointPrefix)
+
+	for i, segment := range segments {
+		f.report.Checked++
+
+		err := readWALSegment(segment)
+		if err == nil {
+			continue
+		}
+		last := i == len(segments)-1
+		if last && !isCheckpoint && isTornWrite(err) {
+			// Torn writes from crashes are repaired by the write-ahead log.
+			return nil
+		}
+
+		reason := fmt.Sprintf("corrupted write-ahead log segment %s: %s", filepath.Base(segment), err)
+		if isCheckpoint {
+			// Checkpoints are only usable if they're complete.
+			return f.quarantine(dir, reason)
+		}
+		for _, s := range segments[i:] {
+			if err := f.quarantine(s, reason); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// listWALSegments returns the paths of the write-ahead log segments in dir in
+// order.
+func listWALSegments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	for _, e := range entries {
+		if !e.Type().IsRegular() || len(e.Name()) != 8 {
+			continue
+		}
+		if k, err := strconv.Atoi(e.Name()); err == nil {
+			indexes = append(indexes, k)
+		}
+	}
+	sort.Ints(indexes)
+
+	paths := make([]string, 0, len(indexes))
+	for _, k := range indexes {
+		paths = append(paths, wlog.SegmentName(dir, k))
+	}
+	return paths, nil
+}
+
+// isTornWrite returns whether err reports a record cut short at the end of a
+// segment, as written when crashing.
+func isTornWrite(err error) bool {
+	var cerr *wlog.CorruptionErr
+	if errors.As(err, &cerr) {
+		err = cerr.Err
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || err.Error() == "last record is torn"
+}
+
+func readWALSegment(path string) error {
+	s, err := wlog.OpenReadSegment(path)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	r := wlog.NewReader(s)
+	for r.Next() {
+	}
+	return r.Err()
+}
+
+// checkBolt checks the bbolt database at path, if path is a bbolt database.
+func (f *storageFsck) checkBolt(path string) error {
+	ok, err := isBoltFile(path)
+	if err != nil || !ok {
+		return err
+	}
+	f.report.Checked++
+
+	if err := checkBoltFile(path); err != nil {
+		return f.quarantine(path, fmt.Sprintf("corrupted database: %s", err))
+	}
+	return nil
+}
+
+func isBoltFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	// The magic number follows the 16 bytes header of the first meta page.
+	var header [20]byte
+	if _, err := io.ReadFull(file, header[:]); err != nil {
+		return false, nil
+	}
+	return binary.LittleEndian.Uint32(header[16:]) == boltMagic, nil
+}
+
+func checkBoltFile(path string) (err error) {
+	// bbolt panics on some kinds of corruption instead of returning errors.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bbolt.Tx) error {
+		var errs []error
+		for err := range tx.Check() {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// quarantine moves path to the recovery directory, keeping its path relative
+// to the storage path.
+func (f *storageFsck) quarantine(path, reason string) error {
+	rel, err := filepath.Rel(f.storagePath, path)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(f.report.RecoveryDir, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0750); err != nil {
+		return fmt.Errorf("creating recovery directory: %w", err)
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return fmt.Errorf("quarantining %s: %w", rel, err)
+	}
+
+	f.report.Quarantined = append(f.report.Quarantined, fsckQuarantined{Path: rel, Reason: reason})
+	return nil
+}
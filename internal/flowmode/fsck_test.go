@@ -0,0 +1,120 @@
+package flowmode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestStorageFsck(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile := func(name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	writeFile("loki.source.file.good/positions.yml", "positions:\n  ? path: /var/log/app.log\n    labels: '{job=\"app\"}'\n  : \"42\"\n")
+	writeFile("loki.source.file.bad/positions.yml", "positions: [[[")
+
+	// A write-ahead log with a corrupted segment in the middle, and one with a
+	// torn write at the end of its last segment.
+	corruptedWAL := writeWAL(t, filepath.Join(dir, "prometheus.remote_write.default", "wal"), 3)
+	corruptFile(t, wlog.SegmentName(corruptedWAL, 1), 0)
+	tornWAL := writeWAL(t, filepath.Join(dir, "loki.write.default", "wal"), 2)
+	last := wlog.SegmentName(tornWAL, 1)
+	fi, err := os.Stat(last)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(last, fi.Size()-5))
+
+	writeBolt(t, filepath.Join(dir, "otelcol.storage.file.good", "queue"))
+	badBolt := filepath.Join(dir, "otelcol.storage.file.bad", "queue")
+	writeBolt(t, badBolt)
+	// Break the checksums of both meta pages.
+	corruptFile(t, badBolt, 40)
+	corruptFile(t, badBolt, int64(os.Getpagesize())+40)
+
+	report, err := runStorageFsck(log.NewNopLogger(), dir, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	recoveryDir := filepath.Join(dir, "recovery", "20240101T000000Z")
+	require.Equal(t, recoveryDir, report.RecoveryDir)
+	// Checking a write-ahead log stops at its first corrupted segment.
+	require.Equal(t, 2+2+2+2, report.Checked)
+
+	var quarantined []string
+	for _, q := range report.Quarantined {
+		quarantined = append(quarantined, q.Path)
+	}
+	require.ElementsMatch(t, []string{
+		filepath.Join("loki.source.file.bad", "positions.yml"),
+		filepath.Join("prometheus.remote_write.default", "wal", "00000001"),
+		filepath.Join("prometheus.remote_write.default", "wal", "00000002"),
+		filepath.Join("otelcol.storage.file.bad", "queue"),
+	}, quarantined)
+
+	for _, path := range quarantined {
+		require.NoFileExists(t, filepath.Join(dir, path))
+		require.FileExists(t, filepath.Join(recoveryDir, path))
+	}
+	require.FileExists(t, wlog.SegmentName(corruptedWAL, 0))
+	require.FileExists(t, last)
+
+	// Checking again finds nothing else to quarantine, and skips the recovery
+	// directory.
+	report, err = runStorageFsck(log.NewNopLogger(), dir, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, report.Quarantined)
+}
+
+func TestStorageFsck_MissingDirectory(t *testing.T) {
+	report, err := runStorageFsck(log.NewNopLogger(), filepath.Join(t.TempDir(), "missing"), time.Now())
+	require.NoError(t, err)
+	require.Zero(t, report.Checked)
+}
+
+// writeWAL writes a write-ahead log with the given number of segments to dir.
+func writeWAL(t *testing.T, dir string, segments int) string {
+	w, err := wlog.New(nil, nil, dir, wlog.CompressionNone)
+	require.NoError(t, err)
+	for i := 0; i < segments; i++ {
+		if i > 0 {
+			_, err := w.NextSegment()
+			require.NoError(t, err)
+		}
+		for j := 0; j < 10; j++ {
+			require.NoError(t, w.Log([]byte("record")))
+		}
+	}
+	require.NoError(t, w.Close())
+	return dir
+}
+
+func writeBolt(t *testing.T, path string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	db, err := bbolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucket([]byte("default"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("key"), []byte("value"))
+	}))
+	require.NoError(t, db.Close())
+}
+
+// corruptFile overwrites a few bytes of path at offset.
+func corruptFile(t *testing.T, path string, offset int64) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, offset)
+	require.NoError(t, err)
+}
@@ -0,0 +1,91 @@
+package flowmode
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/spf13/pflag"
+)
+
+// runProfile selects which subsystems `run` enables, trading features for a
+// smaller footprint.
+type runProfile string
+
+const (
+	// profileDefault enables every subsystem.
+	profileDefault runProfile = "default"
+	// profileMinimal targets devices with little memory, such as ARM boards
+	// with 256MiB of RAM. It disables the UI and clustering, and sets a soft
+	// memory limit.
+	profileMinimal runProfile = "minimal"
+)
+
+// minimalMemoryLimit is the soft memory limit of the Go runtime under the
+// minimal profile. It leaves room for the rest of the system on a device with
+// 256MiB of RAM.
+const minimalMemoryLimit = 128 << 20
+
+// minimalHeapEnvelope is the expected heap in use under the minimal profile
+// once a small pipeline is loaded. TestMinimalProfile fails if
+// the heap grows past it.
+const minimalHeapEnvelope = 96 << 20
+
+// profileSettings describes the subsystems enabled by a profile.
+type profileSettings struct {
+	enableUI        bool
+	allowClustering bool
+	// memoryLimit is the soft memory limit of the Go runtime, or 0 to keep the
+	// default. GOMEMLIMIT takes precedence over it.
+	memoryLimit int64
+}
+
+var profiles = map[runProfile]profileSettings{
+	profileDefault: {
+		enableUI:        true,
+		allowClustering: true,
+	},
+	profileMinimal: {
+		memoryLimit: minimalMemoryLimit,
+	},
+}
+
+var _ pflag.Value = (*runProfile)(nil)
+
+// String implements pflag.Value.
+func (p runProfile) String() string { return string(p) }
+
+// Set implements pflag.Value.
+func (p *runProfile) Set(s string) error {
+	if _, ok := profiles[runProfile(s)]; !ok {
+		return fmt.Errorf("unknown profile %q, must be one of %s", s, strings.Join(allowedProfiles(), ", "))
+	}
+	*p = runProfile(s)
+	return nil
+}
+
+// Type implements pflag.Value.
+func (p runProfile) Type() string { return "profile" }
+
+func (p runProfile) settings() profileSettings { return profiles[p] }
+
+func allowedProfiles() []string {
+	return []string{string(profileDefault), string(profileMinimal)}
+}
+
+// applyMemoryLimit sets the soft memory limit of the Go runtime to the limit
+// of the profile, unless it's set through GOMEMLIMIT.
+func applyMemoryLimit(l log.Logger, settings profileSettings) {
+	if settings.memoryLimit == 0 {
+		return
+	}
+	if _, ok := os.LookupEnv("GOMEMLIMIT"); ok {
+		level.Info(l).Log("msg", "using the memory limit set by GOMEMLIMIT in place of the limit of the profile")
+		return
+	}
+	debug.SetMemoryLimit(settings.memoryLimit)
+	level.Info(l).Log("msg", "set soft memory limit", "bytes", settings.memoryLimit)
+}
@@ -0,0 +1,141 @@
+package flowmode
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phayes/freeport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunProfile(t *testing.T) {
+	var p runProfile
+	require.NoError(t, p.Set("minimal"))
+	require.Equal(t, profileMinimal, p)
+	require.False(t, p.settings().enableUI)
+	require.False(t, p.settings().allowClustering)
+
+	require.ErrorContains(t, p.Set("tiny"), `unknown profile "tiny", must be one of default, minimal`)
+	require.Equal(t, profileMinimal, p)
+}
+
+func TestRun_MinimalProfileRejectsClustering(t *testing.T) {
+	fr := &flowRun{profile: profileMinimal, clusterEnabled: true}
+	require.EqualError(t, fr.Run("config.river"), "clustering can't be enabled with --profile=minimal")
+}
+
+// profileChildEnv holds the arguments of the run command when the test binary
+// is started as a child process by TestMinimalProfile.
+const profileChildEnv = "AGENT_TEST_PROFILE_CHILD_ARGS"
+
+// TestMinimalProfile runs an agent with --profile=minimal in a child process,
+// so that the profile is applied by the run command the same way as in
+// production and the heap isn't shared with other tests. It checks that the
+// UI is disabled, that the soft memory limit is set, and that the heap in use
+// stays within the envelope documented for the minimal profile once a small
+// pipeline is running.
+func TestMinimalProfile(t *testing.T) {
+	if args, ok := os.LookupEnv(profileChildEnv); ok {
+		cmd := runCommand()
+		cmd.SetArgs(strings.Split(args, "\n"))
+		if err := cmd.Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("the child process is stopped with an interrupt, which isn't supported on Windows")
+	}
+
+	config := `
+	prometheus.remote_write "default" {
+		endpoint {
+			url = "http://127.0.0.1:1/api/v1/write"
+		}
+	}
+
+	prometheus.relabel "default" {
+		forward_to = [prometheus.remote_write.default.receiver]
+
+		rule {
+			action       = "replace"
+			target_label = "profile"
+			replacement  = "minimal"
+		}
+	}
+	`
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.river")
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0o600))
+
+	port, err := freeport.GetFreePort()
+	require.NoError(t, err)
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	args := []string{
+		"--profile=minimal",
+		"--server.http.listen-addr=" + addr,
+		"--storage.path=" + filepath.Join(dir, "data"),
+		"--disable-reporting",
+		configPath,
+	}
+	var stderr bytes.Buffer
+	child := exec.Command(os.Args[0], "-test.run=^TestMinimalProfile$")
+	// GOMEMLIMIT would take precedence over the limit set by the profile.
+	child.Env = slices.DeleteFunc(os.Environ(), func(env string) bool { return strings.HasPrefix(env, "GOMEMLIMIT=") })
+	child.Env = append(child.Env, profileChildEnv+"="+strings.Join(args, "\n"))
+	child.Stderr = &stderr
+	require.NoError(t, child.Start())
+	defer func() {
+		_ = child.Process.Signal(os.Interrupt)
+		_ = child.Wait()
+		if t.Failed() {
+			t.Logf("agent output:\n%s", stderr.String())
+		}
+	}()
+
+	get := func(path string) (int, string, error) {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), err
+	}
+
+	require.Eventually(t, func() bool {
+		code, _, err := get("/-/ready")
+		return err == nil && code == http.StatusOK
+	}, time.Minute, 100*time.Millisecond, "agent never became ready")
+
+	// The UI and its API aren't served.
+	code, _, err := get("/api/v0/web/components")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, code)
+
+	// Collect garbage before reading the heap statistics of the agent.
+	code, heap, err := get("/debug/pprof/heap?gc=1&debug=1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, code)
+	match := regexp.MustCompile(`(?m)^# HeapInuse = (\d+)$`).FindStringSubmatch(heap)
+	require.NotNil(t, match, "heap profile doesn't report HeapInuse")
+	heapInuse, err := strconv.ParseUint(match[1], 10, 64)
+	require.NoError(t, err)
+	require.Less(t, heapInuse, uint64(minimalHeapEnvelope), "heap in use exceeds the envelope of the minimal profile")
+	t.Logf("heap in use: %d MiB", heapInuse>>20)
+
+	require.Contains(t, stderr.String(), fmt.Sprintf(`msg="set soft memory limit" bytes=%d`, minimalMemoryLimit))
+}
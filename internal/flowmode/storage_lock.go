@@ -0,0 +1,120 @@
+package flowmode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// storageLockFile is the file, relative to the storage path, which `run`
+// locks while it uses the storage path.
+const storageLockFile = "agent.lock"
+
+// errLockHeld is returned by tryLockFile when another process holds the lock.
+var errLockHeld = errors.New("lock is held by another process")
+
+// storageLock is an advisory lock on the storage path, held by the running
+// agent instance so that a second instance can't share the storage path and
+// corrupt the state of its components.
+type storageLock struct {
+	f *os.File
+}
+
+// storageLockOwner describes the instance which holds a storage lock. It's
+// written to the lock file to report which instance holds the lock.
+type storageLockOwner struct {
+	PID      int       `json:"pid"`
+	Hostname string    `json:"hostname"`
+	Started  time.Time `json:"started"`
+}
+
+// storageLockedError is returned by lockStorage when another instance holds
+// the lock of the storage path.
+type storageLockedError struct {
+	Path  string
+	Owner *storageLockOwner // nil if the owner is unknown.
+}
+
+func (e *storageLockedError) Error() string {
+	owner := "another agent instance"
+	if e.Owner != nil {
+		owner = fmt.Sprintf("another agent instance (pid %d on host %q, started %s)", e.Owner.PID, e.Owner.Hostname, e.Owner.Started.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("storage path %s is in use by %s; give each instance its own --storage.path, or pass --storage.allow-shared to start anyway", e.Path, owner)
+}
+
+// lockStorage locks storagePath, creating it if it doesn't exist. lockStorage
+// returns a *storageLockedError if another instance holds the lock. The lock
+// is released by Release, or when the process exits.
+func lockStorage(storagePath string) (*storageLock, error) {
+	if err := os.MkdirAll(storagePath, 0750); err != nil {
+		return nil, fmt.Errorf("creating storage path: %w", err)
+	}
+
+	path := filepath.Join(storagePath, storageLockFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	if err := tryLockFile(f); err != nil {
+		defer f.Close()
+		if errors.Is(err, errLockHeld) {
+			return nil, &storageLockedError{Path: storagePath, Owner: readStorageLockOwner(f)}
+		}
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+
+	hostname, _ := os.Hostname()
+	owner, err := json.Marshal(storageLockOwner{
+		PID:      os.Getpid(),
+		Hostname: hostname,
+		Started:  time.Now().UTC().Truncate(time.Second),
+	})
+	if err == nil {
+		err = writeStorageLockOwner(f, owner)
+	}
+	if err != nil {
+		_ = unlockFile(f)
+		f.Close()
+		return nil, fmt.Errorf("writing lock file: %w", err)
+	}
+	return &storageLock{f: f}, nil
+}
+
+func writeStorageLockOwner(f *os.File, owner []byte) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(owner, 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func readStorageLockOwner(f *os.File) *storageLockOwner {
+	contents, err := io.ReadAll(io.NewSectionReader(f, 0, 4096))
+	if err != nil {
+		return nil
+	}
+	var owner storageLockOwner
+	if err := json.Unmarshal(contents, &owner); err != nil {
+		return nil
+	}
+	return &owner
+}
+
+// Release releases the lock. The lock file is left in place, since removing
+// it would let another instance lock a new file while a third instance still
+// holds the old one.
+func (l *storageLock) Release() error {
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,34 @@
+package flowmode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockStorage(t *testing.T) {
+	storagePath := filepath.Join(t.TempDir(), "data-agent")
+
+	lock, err := lockStorage(storagePath)
+	require.NoError(t, err)
+
+	// A second instance can't lock the storage path, and reports which
+	// instance holds it.
+	_, err = lockStorage(storagePath)
+	var lockedErr *storageLockedError
+	require.True(t, errors.As(err, &lockedErr), "unexpected error %v", err)
+	require.Equal(t, storagePath, lockedErr.Path)
+	require.NotNil(t, lockedErr.Owner)
+	require.Equal(t, os.Getpid(), lockedErr.Owner.PID)
+	require.Contains(t, err.Error(), "pass --storage.allow-shared to start anyway")
+
+	// The lock can be taken again once it's released.
+	require.NoError(t, lock.Release())
+	lock, err = lockStorage(storagePath)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+	require.FileExists(t, filepath.Join(storagePath, storageLockFile))
+}
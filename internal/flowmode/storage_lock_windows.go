@@ -0,0 +1,31 @@
+//go:build windows
+
+package flowmode
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// The lock is taken on one byte far past the end of the lock file, since
+// locked regions can't be read by other processes, and the contents of the
+// lock file report which instance holds the lock.
+var lockRegion = windows.Overlapped{Offset: 0, OffsetHigh: 0x7FFFFFFF}
+
+// tryLockFile takes an exclusive lock on f without blocking. The lock is
+// released when f is closed, including when the process exits.
+func tryLockFile(f *os.File) error {
+	ol := lockRegion
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &ol)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	ol := lockRegion
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &ol)
+}
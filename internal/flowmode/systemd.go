@@ -0,0 +1,122 @@
+package flowmode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/activation"
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+// systemdNotifier reports the state of the agent to systemd when it runs as a
+// Type=notify or Type=notify-reload unit. Notifications are no-ops when the
+// agent isn't run by systemd.
+type systemdNotifier struct {
+	log log.Logger
+}
+
+func (n systemdNotifier) notify(state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		level.Warn(n.log).Log("msg", "failed to notify systemd", "state", state, "err", err)
+	}
+}
+
+// ready reports that the agent finished starting up or reloading.
+func (n systemdNotifier) ready() { n.notify(daemon.SdNotifyReady) }
+
+// stopping reports that the agent is shutting down. If restarting is true,
+// the agent reports that it's reloading instead, so that systemd waits for
+// the binary which replaces the process to report that it's ready.
+func (n systemdNotifier) stopping(restarting bool) {
+	if restarting {
+		n.notify(daemon.SdNotifyReloading + monotonicUsec())
+		return
+	}
+	n.notify(daemon.SdNotifyStopping)
+}
+
+// reload calls reload, reporting that the agent is reloading its
+// configuration while it runs.
+func (n systemdNotifier) reload(reload func() (*flow.Source, error)) (*flow.Source, error) {
+	n.notify(daemon.SdNotifyReloading + monotonicUsec())
+	defer n.ready()
+	return reload()
+}
+
+// runWatchdog sends keepalives to the systemd watchdog as long as the Flow
+// controller responds to pings, until ctx is canceled. runWatchdog returns
+// immediately if the watchdog isn't enabled for the unit.
+func (n systemdNotifier) runWatchdog(ctx context.Context, f *flow.Flow) {
+	timeout, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		level.Warn(n.log).Log("msg", "failed to read systemd watchdog settings", "err", err)
+		return
+	} else if timeout == 0 {
+		return
+	}
+
+	// Keepalives are sent at half the watchdog timeout, as recommended by
+	// sd_watchdog_enabled(3).
+	interval := timeout / 2
+	level.Info(n.log).Log("msg", "sending keepalives to the systemd watchdog", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			err := f.Ping(pingCtx)
+			cancel()
+
+			if ctx.Err() != nil {
+				return
+			} else if err != nil {
+				level.Warn(n.log).Log("msg", "flow controller is not responding, skipping systemd watchdog keepalive")
+				continue
+			}
+			n.notify(daemon.SdNotifyWatchdog)
+		}
+	}
+}
+
+// systemdHTTPListener returns the listener passed to the agent by systemd
+// socket activation for the HTTP server, or nil if the agent wasn't
+// socket-activated. If systemd passes more than one listener, the HTTP server
+// uses the one named "http" with FileDescriptorName.
+func systemdHTTPListener() (net.Listener, error) {
+	named, err := activation.ListenersWithNames()
+	if err != nil {
+		return nil, err
+	}
+
+	var listeners []net.Listener
+	for name, lis := range named {
+		for _, l := range lis {
+			if l == nil {
+				continue
+			}
+			if name == "http" {
+				return l, nil
+			}
+			listeners = append(listeners, l)
+		}
+	}
+
+	switch len(listeners) {
+	case 0:
+		return nil, nil
+	case 1:
+		return listeners[0], nil
+	default:
+		return nil, fmt.Errorf("systemd passed %d listeners; set FileDescriptorName=http on the socket for the HTTP server", len(listeners))
+	}
+}
@@ -0,0 +1,17 @@
+package flowmode
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// monotonicUsec returns the MONOTONIC_USEC field which systemd requires
+// along with RELOADING=1 for Type=notify-reload units.
+func monotonicUsec() string {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\nMONOTONIC_USEC=%d", ts.Nano()/1000)
+}
@@ -0,0 +1,6 @@
+//go:build !linux
+
+package flowmode
+
+// monotonicUsec returns an empty string, since systemd only runs on Linux.
+func monotonicUsec() string { return "" }
@@ -0,0 +1,104 @@
+//go:build linux
+
+package flowmode
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+// listenNotifySocket listens for systemd notifications, returning a channel
+// of received states.
+func listenNotifySocket(t *testing.T) <-chan string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	states := make(chan string, 10)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			states <- string(buf[:n])
+		}
+	}()
+	return states
+}
+
+func TestSystemdNotifier_Reload(t *testing.T) {
+	states := listenNotifySocket(t)
+	n := systemdNotifier{log: util.TestLogger(t)}
+
+	n.ready()
+	require.Equal(t, "READY=1", <-states)
+
+	_, err := n.reload(func() (*flow.Source, error) { return nil, nil })
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(<-states, "RELOADING=1\nMONOTONIC_USEC="))
+	require.Equal(t, "READY=1", <-states)
+
+	n.stopping(false)
+	require.Equal(t, "STOPPING=1", <-states)
+}
+
+func TestSystemdNotifier_Watchdog(t *testing.T) {
+	states := listenNotifySocket(t)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	l, err := logging.New(os.Stderr, logging.DefaultOptions)
+	require.NoError(t, err)
+	f := flow.New(flow.Options{Logger: l, DataPath: t.TempDir()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := systemdNotifier{log: util.TestLogger(t)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.runWatchdog(ctx, f)
+	}()
+
+	// Keepalives aren't sent while the controller isn't running.
+	select {
+	case state := <-states:
+		require.FailNow(t, "unexpected notification", state)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	go f.Run(ctx)
+	select {
+	case state := <-states:
+		require.Equal(t, "WATCHDOG=1", state)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no watchdog keepalive received")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestSystemdHTTPListener(t *testing.T) {
+	lis, err := systemdHTTPListener()
+	require.NoError(t, err)
+	require.Nil(t, lis)
+}
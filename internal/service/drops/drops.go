@@ -0,0 +1,222 @@
+// Package drops implements the drops service, which counts the data dropped
+// by components under a single metric family, and keeps a summary of the
+// drops of every running component which can be queried through the API.
+package drops
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ServiceName defines the name used for the drops service.
+const ServiceName = "drops"
+
+// Signals of the dropped data.
+const (
+	SignalMetrics = "metrics"
+	SignalLogs    = "logs"
+)
+
+// Reasons for dropping data.
+const (
+	// ReasonRelabel is used for data dropped by relabeling rules.
+	ReasonRelabel = "relabel"
+	// ReasonNoRoute is used for data which didn't match any route.
+	ReasonNoRoute = "no_route"
+	// ReasonLimit is used for data which exceeded a limit of the component.
+	ReasonLimit = "limit"
+	// ReasonRejected is used for data rejected by the remote endpoint with a
+	// 4xx status code.
+	ReasonRejected = "rejected"
+	// ReasonSendFailed is used for data which couldn't be sent to the remote
+	// endpoint for any other reason after all retries.
+	ReasonSendFailed = "send_failed"
+)
+
+// Recorder counts the data of a signal dropped by a component.
+type Recorder struct {
+	componentID string
+	signal      string
+	dropped     *prometheus.CounterVec
+
+	mut    sync.Mutex
+	counts map[string]uint64
+}
+
+// NewRecorder returns a Recorder for the data of signal dropped by the
+// component with opts, whose drops are exposed as the agent_dropped_total
+// metric registered to the Registerer of the component. If the drops service
+// is available, the drops are also added to its summary, where the returned
+// Recorder replaces any previous Recorder of the component for signal.
+func NewRecorder(opts component.Options, signal string) *Recorder {
+	r := newRecorder(opts.ID, signal, opts.Registerer)
+	if opts.GetServiceData == nil {
+		return r
+	}
+	if data, err := opts.GetServiceData(ServiceName); err == nil {
+		if reg, ok := data.(*Registry); ok {
+			reg.add(r)
+		}
+	}
+	return r
+}
+
+func newRecorder(componentID, signal string, reg prometheus.Registerer) *Recorder {
+	r := &Recorder{
+		componentID: componentID,
+		signal:      signal,
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "agent_dropped_total",
+			Help:        "Total number of samples or log entries dropped by the component.",
+			ConstLabels: prometheus.Labels{"signal": signal},
+		}, []string{"reason"}),
+		counts: map[string]uint64{},
+	}
+	if reg != nil {
+		r.dropped = util.MustRegisterOrGet(reg, r.dropped).(*prometheus.CounterVec)
+	}
+	return r
+}
+
+// Add records that n samples or log entries were dropped for reason. It's a
+// no-op if r is nil.
+func (r *Recorder) Add(reason string, n int) {
+	if r == nil || n <= 0 {
+		return
+	}
+	r.dropped.WithLabelValues(reason).Add(float64(n))
+
+	r.mut.Lock()
+	r.counts[reason] += uint64(n)
+	r.mut.Unlock()
+}
+
+// Drops is the number of samples or log entries of a signal dropped by a
+// component for a reason.
+type Drops struct {
+	ComponentID string `json:"componentID"`
+	Signal      string `json:"signal"`
+	Reason      string `json:"reason"`
+	Count       uint64 `json:"count"`
+}
+
+type recorderKey struct {
+	componentID, signal string
+}
+
+// Registry holds the Recorders of the running components.
+type Registry struct {
+	mut       sync.RWMutex
+	recorders map[recorderKey]*Recorder
+}
+
+func newRegistry() *Registry {
+	return &Registry{recorders: make(map[recorderKey]*Recorder)}
+}
+
+func (reg *Registry) add(r *Recorder) {
+	reg.mut.Lock()
+	defer reg.mut.Unlock()
+	reg.recorders[recorderKey{r.componentID, r.signal}] = r
+}
+
+// remove forgets the Recorders of the components with the global IDs ids, and
+// of the components running in modules created by them.
+func (reg *Registry) remove(ids []string) {
+	reg.mut.Lock()
+	defer reg.mut.Unlock()
+
+	for key := range reg.recorders {
+		for _, id := range ids {
+			if key.componentID == id || strings.HasPrefix(key.componentID, id+"/") {
+				delete(reg.recorders, key)
+				break
+			}
+		}
+	}
+}
+
+// Summary returns the drops recorded by the Recorders of every component,
+// sorted by component, signal and reason.
+func (reg *Registry) Summary() []Drops {
+	reg.mut.RLock()
+	defer reg.mut.RUnlock()
+
+	res := []Drops{}
+	for _, r := range reg.recorders {
+		r.mut.Lock()
+		for reason, count := range r.counts {
+			res = append(res, Drops{ComponentID: r.componentID, Signal: r.signal, Reason: reason, Count: count})
+		}
+		r.mut.Unlock()
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.ComponentID != b.ComponentID {
+			return a.ComponentID < b.ComponentID
+		}
+		if a.Signal != b.Signal {
+			return a.Signal < b.Signal
+		}
+		return a.Reason < b.Reason
+	})
+	return res
+}
+
+// Service implements the drops service.
+type Service struct {
+	reg *Registry
+}
+
+var (
+	_ service.Service           = (*Service)(nil)
+	_ service.ComponentObserver = (*Service)(nil)
+)
+
+// New returns a new, unstarted instance of the drops service.
+func New() *Service {
+	return &Service{reg: newRegistry()}
+}
+
+// Definition returns the definition of the drops service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: nil, // drops does not accept configuration.
+		DependsOn:  nil, // drops has no dependencies.
+		Stability:  featuregate.StabilityStable,
+	}
+}
+
+// Data returns the *Registry of the service.
+func (s *Service) Data() any {
+	return s.reg
+}
+
+// Run implements [service.Service]. Recorders are added by components on
+// demand, so Run only waits for ctx to be canceled.
+func (s *Service) Run(ctx context.Context, _ service.Host) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements [service.Service]. It is never called, since the drops
+// service has no settings.
+func (s *Service) Update(_ any) error {
+	return nil
+}
+
+// ComponentsRemoved implements [service.ComponentObserver]. The drops of
+// removed components are removed from the summary.
+func (s *Service) ComponentsRemoved(ids []string) {
+	s.reg.remove(ids)
+}
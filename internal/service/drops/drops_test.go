@@ -0,0 +1,78 @@
+package drops
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	svc := New()
+	reg := prometheus.NewRegistry()
+	r := NewRecorder(testOptions(svc, "test.recorder", reg), SignalLogs)
+	r.Add(ReasonRelabel, 2)
+	r.Add(ReasonRejected, 5)
+	r.Add(ReasonRelabel, 1)
+
+	expect := `
+# HELP agent_dropped_total Total number of samples or log entries dropped by the component.
+# TYPE agent_dropped_total counter
+agent_dropped_total{reason="rejected",signal="logs"} 5
+agent_dropped_total{reason="relabel",signal="logs"} 3
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expect), "agent_dropped_total"))
+
+	summary := svc.Data().(*Registry)
+	require.Equal(t, []Drops{
+		{ComponentID: "test.recorder", Signal: SignalLogs, Reason: ReasonRejected, Count: 5},
+		{ComponentID: "test.recorder", Signal: SignalLogs, Reason: ReasonRelabel, Count: 3},
+	}, summary.Summary())
+
+	// A new Recorder for the same component and signal replaces the previous
+	// one in the summary.
+	r = NewRecorder(testOptions(svc, "test.recorder", prometheus.NewRegistry()), SignalLogs)
+	r.Add(ReasonNoRoute, 1)
+	require.Equal(t, []Drops{
+		{ComponentID: "test.recorder", Signal: SignalLogs, Reason: ReasonNoRoute, Count: 1},
+	}, summary.Summary())
+}
+
+func TestService_ComponentsRemoved(t *testing.T) {
+	svc := New()
+	for _, id := range []string{"loki.route.a", "module.file.b", "module.file.b/loki.route.c", "module.file.bb/loki.route.d"} {
+		NewRecorder(testOptions(svc, id, prometheus.NewRegistry()), SignalLogs).Add(ReasonNoRoute, 1)
+	}
+
+	// Components created by a removed module are removed with it.
+	svc.ComponentsRemoved([]string{"module.file.b"})
+	require.Equal(t, []Drops{
+		{ComponentID: "loki.route.a", Signal: SignalLogs, Reason: ReasonNoRoute, Count: 1},
+		{ComponentID: "module.file.bb/loki.route.d", Signal: SignalLogs, Reason: ReasonNoRoute, Count: 1},
+	}, svc.Data().(*Registry).Summary())
+}
+
+func TestRecorder_WithoutService(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r := NewRecorder(component.Options{ID: "test.recorder", Registerer: reg}, SignalMetrics)
+	r.Add(ReasonRelabel, 1)
+	require.Equal(t, 1.0, testutil.ToFloat64(r.dropped.WithLabelValues(ReasonRelabel)))
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var r *Recorder
+	require.NotPanics(t, func() { r.Add(ReasonRelabel, 1) })
+}
+
+func testOptions(svc *Service, componentID string, reg prometheus.Registerer) component.Options {
+	return component.Options{
+		ID:         componentID,
+		Registerer: reg,
+		GetServiceData: func(name string) (interface{}, error) {
+			return svc.Data(), nil
+		},
+	}
+}
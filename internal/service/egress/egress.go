@@ -0,0 +1,273 @@
+// Package egress implements the egress service, which restricts the outbound
+// connections of the agent to an allowlist of endpoints.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	commonconfig "github.com/prometheus/common/config"
+)
+
+// ServiceName defines the name used for the egress service.
+const ServiceName = "egress"
+
+// Arguments holds runtime settings for the egress service.
+type Arguments struct {
+	// Allow lists the endpoints which the agent may connect to. Outbound
+	// connections aren't restricted if Allow is nil.
+	Allow []string `river:"allow,attr,optional"`
+
+	// AuditOnly reports connections which aren't allowed without blocking
+	// them.
+	AuditOnly bool `river:"audit_only,attr,optional"`
+
+	// DNSRefreshInterval is how often the addresses of the allowed hostnames
+	// are resolved again.
+	DNSRefreshInterval time.Duration `river:"dns_refresh_interval,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	DNSRefreshInterval: 5 * time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.DNSRefreshInterval <= 0 {
+		return fmt.Errorf("dns_refresh_interval must be greater than 0")
+	}
+	for _, entry := range a.Allow {
+		if _, err := parseRule(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// active is the service whose allowlist is enforced by DialContext.
+var active atomic.Pointer[Service]
+
+var dialer net.Dialer
+
+// DialContext connects to address on the named network, like
+// net.Dialer.DialContext. If the egress service restricts outbound
+// connections, DialContext returns an error wrapping ErrBlocked when address
+// isn't allowed, and connects to the pinned addresses of allowed hostnames.
+func DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if s := active.Load(); s != nil {
+		return s.dialContext(ctx, network, address)
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// HTTPClientOption returns an option which makes HTTP clients created from
+// Prometheus HTTP client configs connect with DialContext.
+func HTTPClientOption() commonconfig.HTTPClientOption {
+	return commonconfig.WithDialContextFunc(DialContext)
+}
+
+var installTransport sync.Once
+
+// Service implements the egress service.
+type Service struct {
+	log    log.Logger
+	lookup lookupFunc
+
+	blockedTotal       prometheus.Counter
+	dnsRefreshFailures prometheus.Counter
+
+	updated chan struct{}
+
+	mut    sync.RWMutex
+	args   Arguments
+	policy *policy // nil if outbound connections aren't restricted.
+}
+
+var _ service.Service = (*Service)(nil)
+
+// New returns a new, unstarted instance of the egress service. The service
+// enforces its allowlist on the connections made with DialContext, including
+// the connections of http.DefaultTransport.
+func New(l log.Logger, r prometheus.Registerer) *Service {
+	s := &Service{
+		log:    l,
+		lookup: lookupHost,
+
+		blockedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_egress_blocked_connections_total",
+			Help: "Total number of outbound connections which weren't allowed by the egress allowlist.",
+		}),
+		dnsRefreshFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_egress_dns_refresh_failures_total",
+			Help: "Total number of times the addresses of an allowed hostname couldn't be resolved.",
+		}),
+
+		updated: make(chan struct{}, 1),
+	}
+	if r != nil {
+		r.MustRegister(s.blockedTotal, s.dnsRefreshFailures)
+	}
+
+	active.Store(s)
+	installTransport.Do(func() {
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			t.DialContext = DialContext
+		}
+	})
+	return s
+}
+
+// Definition returns the definition of the egress service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  nil, // egress has no dependencies.
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Data is a no-op for the egress service; connections are restricted
+// through DialContext.
+func (s *Service) Data() any {
+	return nil
+}
+
+// Run implements [service.Service]. It resolves the addresses of the allowed
+// hostnames again every dns_refresh_interval until ctx is canceled.
+func (s *Service) Run(ctx context.Context, _ service.Host) error {
+	for {
+		s.mut.RLock()
+		interval := s.args.DNSRefreshInterval
+		s.mut.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.updated:
+		case <-time.After(interval):
+			s.mut.RLock()
+			p := s.policy
+			s.mut.RUnlock()
+
+			if p != nil {
+				s.pin(ctx, p)
+			}
+		}
+	}
+}
+
+// Update implements [service.Service]. The addresses of the allowed hostnames
+// are resolved and pinned before the new allowlist is enforced.
+func (s *Service) Update(newConfig any) error {
+	newArgs := newConfig.(Arguments)
+
+	var p *policy
+	if newArgs.Allow != nil {
+		var err error
+		p, err = newPolicy(newArgs.Allow, newArgs.AuditOnly)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s.pin(ctx, p)
+	}
+
+	s.mut.Lock()
+	s.args = newArgs
+	s.policy = p
+	s.mut.Unlock()
+
+	if p == nil {
+		level.Info(s.log).Log("msg", "outbound connections are not restricted")
+	} else {
+		level.Info(s.log).Log("msg", "applied egress allowlist", "allow", strings.Join(newArgs.Allow, ","), "audit_only", newArgs.AuditOnly)
+	}
+
+	select {
+	case s.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pin resolves and pins the addresses of the hostnames of p. The previous
+// addresses of a hostname are kept if it can't be resolved.
+func (s *Service) pin(ctx context.Context, p *policy) {
+	for _, host := range p.pinnedHosts() {
+		addrs, err := s.lookup(ctx, host)
+		if err != nil {
+			s.dnsRefreshFailures.Inc()
+			level.Warn(s.log).Log("msg", "failed to resolve allowed hostname", "host", host, "err", err)
+			continue
+		}
+		p.pin(host, addrs)
+	}
+}
+
+func (s *Service) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	s.mut.RLock()
+	p := s.policy
+	s.mut.RUnlock()
+
+	// Unix sockets aren't outbound connections.
+	if p == nil || strings.HasPrefix(network, "unix") {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := p.resolve(ctx, host, port, s.lookup)
+	if errors.Is(err, ErrBlocked) {
+		s.blockedTotal.Inc()
+		level.Warn(s.log).Log("msg", "outbound connection not allowed by the egress allowlist", "network", network, "address", address, "audit_only", p.auditOnly)
+		if p.auditOnly {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return nil, &net.OpError{Op: "dial", Net: network, Err: fmt.Errorf("%s: %w", address, ErrBlocked)}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return dialAddrs(ctx, network, addrs, port)
+}
+
+// dialAddrs connects to the first of addrs which accepts the connection.
+func dialAddrs(ctx context.Context, network string, addrs []netip.Addr, port string) (net.Conn, error) {
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
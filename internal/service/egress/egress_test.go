@@ -0,0 +1,157 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	tt := []struct {
+		entry  string
+		expect rule
+		err    string
+	}{
+		{entry: "Mimir.Example.org", expect: rule{host: "mimir.example.org"}},
+		{entry: "mimir.example.org:443", expect: rule{host: "mimir.example.org", port: "443"}},
+		{entry: "*.grafana.net:443", expect: rule{host: ".grafana.net", wildcard: true, port: "443"}},
+		{entry: "10.1.2.3", expect: rule{prefix: netip.MustParsePrefix("10.1.2.3/32")}},
+		{entry: "[2001:db8::1]:9090", expect: rule{prefix: netip.MustParsePrefix("2001:db8::1/128"), port: "9090"}},
+		{entry: "10.1.2.3/8", expect: rule{prefix: netip.MustParsePrefix("10.0.0.0/8")}},
+		{entry: "example.org:http", err: `invalid port in "example.org:http"`},
+		{entry: "10.0.0.0/33", err: `invalid CIDR range in "10.0.0.0/33": netip.ParsePrefix("10.0.0.0/33"): prefix length out of range`},
+		{entry: "foo*.example.org", err: `invalid hostname in "foo*.example.org"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.entry, func(t *testing.T) {
+			r, err := parseRule(tc.entry)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, r)
+		})
+	}
+}
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(``), &args))
+	require.Nil(t, args.Allow)
+
+	require.NoError(t, river.Unmarshal([]byte(`allow = []`), &args))
+	require.NotNil(t, args.Allow)
+
+	require.EqualError(t, river.Unmarshal([]byte(`allow = ["example.org:0"]`), &args), `invalid port in "example.org:0"`)
+}
+
+func TestService(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+
+	var lookups int
+	s := newTestService(t, func(_ context.Context, host string) ([]netip.Addr, error) {
+		lookups++
+		switch host {
+		case "allowed.example", "api.wildcard.example":
+			return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, nil
+		case "pinned.example":
+			return []netip.Addr{netip.MustParseAddr("192.0.2.10")}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	})
+
+	args := DefaultArguments
+	args.Allow = []string{"allowed.example", "pinned.example:443", "*.wildcard.example", "198.51.100.0/24:80"}
+	require.NoError(t, s.Update(args))
+	require.Equal(t, 2, lookups, "exact hostnames must be resolved when the allowlist is applied")
+
+	dial := func(address string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		conn, err := s.dialContext(ctx, "tcp", address)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	// Allowed hostnames connect to their pinned addresses.
+	require.NoError(t, dial("allowed.example:"+port))
+	require.NoError(t, dial("api.wildcard.example:"+port))
+	require.NoError(t, dial("127.0.0.1:"+port), "loopback addresses are always allowed")
+	require.Equal(t, 3, lookups, "pinned hostnames must not be resolved when dialing")
+
+	for _, address := range []string{
+		"other.example:" + port,
+		"wildcard.example:" + port,
+		"pinned.example:80",
+		"192.0.2.1:" + port,
+		"198.51.100.1:81",
+	} {
+		err := dial(address)
+		require.True(t, errors.Is(err, ErrBlocked), "expected %s to be blocked, got %v", address, err)
+	}
+	require.Equal(t, 5.0, testutil.ToFloat64(s.blockedTotal))
+
+	// Addresses pinned for allowed hostnames and allowed ranges may be dialed
+	// directly; the dials fail since nothing listens there.
+	require.False(t, errors.Is(dial("192.0.2.10:443"), ErrBlocked))
+	require.False(t, errors.Is(dial("198.51.100.1:80"), ErrBlocked))
+
+	// Connections which aren't allowed are only reported in audit mode.
+	args.AuditOnly = true
+	require.NoError(t, s.Update(args))
+	require.False(t, errors.Is(dial("192.0.2.1:"+port), ErrBlocked))
+	require.Equal(t, 6.0, testutil.ToFloat64(s.blockedTotal))
+
+	// Outbound connections aren't restricted without an allowlist.
+	require.NoError(t, s.Update(DefaultArguments))
+	require.False(t, errors.Is(dial("192.0.2.1:"+port), ErrBlocked))
+	require.Equal(t, 6.0, testutil.ToFloat64(s.blockedTotal))
+}
+
+func TestService_BlockAll(t *testing.T) {
+	s := newTestService(t, func(context.Context, string) ([]netip.Addr, error) {
+		return nil, fmt.Errorf("no lookups expected")
+	})
+
+	args := DefaultArguments
+	args.Allow = []string{}
+	require.NoError(t, s.Update(args))
+
+	_, err := s.dialContext(context.Background(), "tcp", "example.org:443")
+	require.EqualError(t, err, "dial tcp: example.org:443: blocked by the egress allowlist")
+}
+
+func newTestService(t *testing.T, lookup lookupFunc) *Service {
+	s := New(util.TestLogger(t), prometheus.NewRegistry())
+	s.lookup = lookup
+	t.Cleanup(func() { active.CompareAndSwap(s, nil) })
+	return s
+}
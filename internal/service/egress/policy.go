@@ -0,0 +1,231 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrBlocked is returned when dialing an address which isn't allowed by the
+// egress allowlist.
+var ErrBlocked = errors.New("blocked by the egress allowlist")
+
+// rule is an entry of the egress allowlist.
+type rule struct {
+	host     string       // Lowercase hostname, or "" for IP rules.
+	wildcard bool         // Whether host matches its subdomains rather than itself.
+	prefix   netip.Prefix // Addresses matched by IP rules.
+	port     string       // Port to match, or "" to match any port.
+}
+
+// parseRule parses an entry of the allow argument. Entries are hostnames,
+// wildcard hostnames such as *.example.org, IP addresses or CIDR ranges,
+// optionally followed by a port.
+func parseRule(entry string) (rule, error) {
+	var r rule
+
+	host := entry
+	if h, port, err := net.SplitHostPort(entry); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return r, fmt.Errorf("invalid port in %q", entry)
+		}
+		host, r.port = h, port
+	}
+
+	if strings.Contains(host, "/") {
+		prefix, err := netip.ParsePrefix(host)
+		if err != nil {
+			return r, fmt.Errorf("invalid CIDR range in %q: %w", entry, err)
+		}
+		r.prefix = prefix.Masked()
+		return r, nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		r.prefix = netip.PrefixFrom(addr, addr.BitLen())
+		return r, nil
+	}
+
+	host = normalizeHost(host)
+	if strings.HasPrefix(host, "*.") {
+		r.wildcard = true
+		host = strings.TrimPrefix(host, "*")
+	}
+	if host == "" || host == "." || strings.ContainsAny(host, "*:[]") {
+		return r, fmt.Errorf("invalid hostname in %q", entry)
+	}
+	r.host = host
+	return r, nil
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+func (r rule) matchesPort(port string) bool { return r.port == "" || r.port == port }
+
+// matchesHost reports whether the hostname rule matches host.
+func (r rule) matchesHost(host string) bool {
+	if r.wildcard {
+		return strings.HasSuffix(host, r.host)
+	}
+	return r.host != "" && r.host == host
+}
+
+// policy decides which addresses may be dialed.
+type policy struct {
+	rules     []rule
+	auditOnly bool
+
+	mut    sync.RWMutex
+	pinned map[string][]netip.Addr // Pinned addresses of hostname rules.
+}
+
+func newPolicy(allow []string, auditOnly bool) (*policy, error) {
+	p := &policy{
+		auditOnly: auditOnly,
+		pinned:    make(map[string][]netip.Addr),
+	}
+	for _, entry := range allow {
+		r, err := parseRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+// pinnedHosts returns the hostnames whose addresses are pinned.
+func (p *policy) pinnedHosts() []string {
+	seen := make(map[string]struct{})
+	var hosts []string
+	for _, r := range p.rules {
+		if r.host == "" || r.wildcard {
+			continue
+		}
+		if _, ok := seen[r.host]; !ok {
+			seen[r.host] = struct{}{}
+			hosts = append(hosts, r.host)
+		}
+	}
+	return hosts
+}
+
+// pin sets the pinned addresses of host.
+func (p *policy) pin(host string, addrs []netip.Addr) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.pinned[host] = addrs
+}
+
+// lookupPinned returns the pinned addresses of host.
+func (p *policy) lookupPinned(host string) []netip.Addr {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	return p.pinned[host]
+}
+
+// allowsAddr reports whether the IP address addr may be dialed on port.
+// Addresses pinned for allowed hostnames are allowed.
+func (p *policy) allowsAddr(addr netip.Addr, port string) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() {
+		return true
+	}
+
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	for _, r := range p.rules {
+		if !r.matchesPort(port) {
+			continue
+		}
+		if r.host == "" && r.prefix.Contains(addr) {
+			return true
+		}
+		for _, pinned := range p.pinned[r.host] {
+			if pinned == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve returns the addresses to dial for host and port. It returns
+// ErrBlocked if host isn't allowed. The addresses of hostnames matching
+// exact rules are their pinned addresses; the addresses of hostnames
+// matching wildcard rules are resolved with lookup.
+func (p *policy) resolve(ctx context.Context, host, port string, lookup lookupFunc) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !p.allowsAddr(addr, port) {
+			return nil, ErrBlocked
+		}
+		return []netip.Addr{addr}, nil
+	}
+
+	host = normalizeHost(host)
+	if host == "localhost" {
+		addrs, err := lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		return loopbackOnly(addrs)
+	}
+
+	var exact, wildcard bool
+	for _, r := range p.rules {
+		if !r.matchesPort(port) || !r.matchesHost(host) {
+			continue
+		}
+		if r.wildcard {
+			wildcard = true
+		} else {
+			exact = true
+		}
+	}
+
+	switch {
+	case exact:
+		if addrs := p.lookupPinned(host); len(addrs) > 0 {
+			return addrs, nil
+		}
+		// The host couldn't be resolved when it was pinned; pin it now.
+		addrs, err := lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		p.pin(host, addrs)
+		return addrs, nil
+	case wildcard:
+		return lookup(ctx, host)
+	default:
+		return nil, ErrBlocked
+	}
+}
+
+// loopbackOnly returns the loopback addresses of addrs, or ErrBlocked if
+// there's none.
+func loopbackOnly(addrs []netip.Addr) ([]netip.Addr, error) {
+	var loopback []netip.Addr
+	for _, addr := range addrs {
+		if addr.Unmap().IsLoopback() {
+			loopback = append(loopback, addr)
+		}
+	}
+	if len(loopback) == 0 {
+		return nil, ErrBlocked
+	}
+	return loopback, nil
+}
+
+type lookupFunc func(ctx context.Context, host string) ([]netip.Addr, error)
+
+func lookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
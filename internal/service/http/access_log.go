@@ -0,0 +1,124 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+// AccessLogArguments configures the access log of the HTTP server.
+type AccessLogArguments struct {
+	// SampleRate is the fraction of requests which are logged.
+	SampleRate float64 `river:"sample_rate,attr,optional"`
+
+	// AlwaysLogErrors logs every request which receives an error response,
+	// regardless of SampleRate.
+	AlwaysLogErrors bool `river:"always_log_errors,attr,optional"`
+
+	// ExcludePaths lists path prefixes of requests which aren't logged.
+	ExcludePaths []string `river:"exclude_paths,attr,optional"`
+}
+
+// DefaultAccessLogArguments holds default settings for AccessLogArguments.
+var DefaultAccessLogArguments = AccessLogArguments{
+	SampleRate:      1,
+	AlwaysLogErrors: true,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *AccessLogArguments) SetToDefault() {
+	*args = DefaultAccessLogArguments
+}
+
+// Validate implements river.Validator.
+func (args *AccessLogArguments) Validate() error {
+	if args.SampleRate < 0 || args.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be between 0 and 1, got %g", args.SampleRate)
+	}
+	for _, p := range args.ExcludePaths {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("exclude_paths entry %q must start with /", p)
+		}
+	}
+	return nil
+}
+
+func (args *AccessLogArguments) excluded(path string) bool {
+	for _, p := range args.ExcludePaths {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (args *AccessLogArguments) sampled(status int) bool {
+	if args.AlwaysLogErrors && status >= 400 {
+		return true
+	}
+	return args.SampleRate >= 1 || rand.Float64() < args.SampleRate
+}
+
+// accessLogEntry holds details about a request which are only known to the
+// handler serving it.
+type accessLogEntry struct {
+	componentID string
+}
+
+type accessLogEntryKey struct{}
+
+// setAccessLogComponent records that the request of ctx is served by the
+// component with the given ID.
+func setAccessLogComponent(ctx context.Context, componentID string) {
+	if entry, ok := ctx.Value(accessLogEntryKey{}).(*accessLogEntry); ok {
+		entry.componentID = componentID
+	}
+}
+
+// accessLogHandler wraps next to log the requests it serves, as configured by
+// the access_log block.
+func (s *Service) accessLogHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.accessLogMut.RLock()
+		args := s.accessLog
+		s.accessLogMut.RUnlock()
+
+		if args == nil || args.excluded(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Handlers may rewrite the path of the request, so it's read before
+		// serving the request.
+		var (
+			method = r.Method
+			path   = r.URL.Path
+			entry  = &accessLogEntry{}
+		)
+		r = r.WithContext(context.WithValue(r.Context(), accessLogEntryKey{}, entry))
+
+		m := httpsnoop.CaptureMetrics(next, w, r)
+		if !args.sampled(m.Code) {
+			return
+		}
+
+		keyvals := []interface{}{
+			"msg", "handled HTTP request",
+			"method", method,
+			"path", path,
+			"status", m.Code,
+			"duration", m.Duration,
+			"response_size", m.Written,
+			"remote_addr", r.RemoteAddr,
+		}
+		if entry.componentID != "" {
+			keyvals = append(keyvals, "component", entry.componentID)
+		}
+		level.Info(s.log).Log(keyvals...)
+	})
+}
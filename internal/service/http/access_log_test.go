@@ -0,0 +1,84 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`access_log {}`), &args))
+	require.Equal(t, DefaultAccessLogArguments, *args.AccessLog)
+
+	err := river.Unmarshal([]byte(`access_log { sample_rate = 1.5 }`), &args)
+	require.EqualError(t, err, "sample_rate must be between 0 and 1, got 1.5")
+
+	err = river.Unmarshal([]byte(`access_log { exclude_paths = ["metrics"] }`), &args)
+	require.EqualError(t, err, `exclude_paths entry "metrics" must start with /`)
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	s := New(Options{Logger: log.NewLogfmtLogger(&buf)})
+
+	host := &fakeServiceHost{
+		components: map[component.ID]struct{}{
+			component.ParseID("prometheus.exporter.unix.default"): {},
+		},
+	}
+
+	handler := s.accessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, s.componentHttpPathPrefix):
+			s.componentHandler(host).ServeHTTP(w, r)
+		case r.URL.Path == "/fail":
+			http.Error(w, "failed", http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+
+	serve := func(path string) string {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return buf.String()
+	}
+
+	// Requests aren't logged without an access_log block.
+	require.NoError(t, s.Update(Arguments{}))
+	require.Empty(t, serve("/-/ready"))
+
+	args := DefaultAccessLogArguments
+	args.ExcludePaths = []string{"/metrics"}
+	require.NoError(t, s.Update(Arguments{AccessLog: &args}))
+
+	line := serve("/-/ready")
+	require.Contains(t, line, `level=info msg="handled HTTP request" method=GET path=/-/ready status=200 duration=`)
+	require.Contains(t, line, "response_size=2 remote_addr=192.0.2.1:1234\n")
+
+	line = serve("/api/v0/component/prometheus.exporter.unix.default/metrics")
+	require.Contains(t, line, "path=/api/v0/component/prometheus.exporter.unix.default/metrics status=404")
+	require.Contains(t, line, "component=prometheus.exporter.unix.default\n")
+
+	require.Empty(t, serve("/metrics"))
+
+	// Error responses are logged regardless of the sample rate.
+	args.SampleRate = 0
+	require.NoError(t, s.Update(Arguments{AccessLog: &args}))
+	require.Empty(t, serve("/-/ready"))
+	require.Contains(t, serve("/fail"), "status=500")
+
+	args.AlwaysLogErrors = false
+	require.NoError(t, s.Update(Arguments{AccessLog: &args}))
+	require.Empty(t, serve("/fail"))
+}
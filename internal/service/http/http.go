@@ -4,6 +4,7 @@ package http
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -20,6 +21,7 @@ import (
 	"github.com/grafana/agent/internal/flow"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/egress"
 	"github.com/grafana/agent/internal/static/server"
 	"github.com/grafana/ckit/memconn"
 	_ "github.com/grafana/pyroscope-go/godeltaprof/http/pprof" // Register godeltaprof handler
@@ -48,11 +50,17 @@ type Options struct {
 	HTTPListenAddr   string // Address to listen for HTTP traffic on.
 	MemoryListenAddr string // Address to accept in-memory traffic on.
 	EnablePProf      bool   // Whether pprof endpoints should be exposed.
+
+	// Listener accepts HTTP traffic instead of listening on HTTPListenAddr,
+	// such as a listener passed by systemd socket activation. HTTPListenAddr
+	// must be the address of Listener.
+	Listener net.Listener
 }
 
 // Arguments holds runtime settings for the HTTP service.
 type Arguments struct {
-	TLS *TLSArguments `river:"tls,block,optional"`
+	TLS       *TLSArguments       `river:"tls,block,optional"`
+	AccessLog *AccessLogArguments `river:"access_log,block,optional"`
 }
 
 type Service struct {
@@ -64,6 +72,13 @@ type Service struct {
 	winMut sync.Mutex
 	win    *server.WinCertStoreHandler
 
+	accessLogMut sync.RWMutex
+	accessLog    *AccessLogArguments // nil if requests aren't logged.
+
+	sniMut     sync.RWMutex
+	sni        []SNIArguments // Routes of TLS connections by server name.
+	sniChanged chan struct{}  // Signals that the components of sni must be checked.
+
 	// publicLis and tcpLis are used to lazily enable TLS, since TLS is
 	// optionally configurable at runtime.
 	//
@@ -119,6 +134,8 @@ func New(opts Options) *Service {
 		tcpLis:    tcpLis,
 		memLis:    memconn.NewListener(l),
 
+		sniChanged: make(chan struct{}, 1),
+
 		componentHttpPathPrefix: "/api/v0/component/",
 	}
 }
@@ -149,9 +166,13 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 		}
 	}()
 
-	netLis, err := net.Listen("tcp", s.opts.HTTPListenAddr)
-	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", s.opts.HTTPListenAddr, err)
+	netLis := s.opts.Listener
+	if netLis == nil {
+		var err error
+		netLis, err = net.Listen("tcp", s.opts.HTTPListenAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", s.opts.HTTPListenAddr, err)
+		}
 	}
 	if err := s.tcpLis.SetInner(netLis); err != nil {
 		return fmt.Errorf("failed to use listener: %w", err)
@@ -210,7 +231,7 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 		r.PathPrefix(route.Base).Handler(route.Handler)
 	}
 
-	srv := &http.Server{Handler: h2c.NewHandler(r, &http2.Server{})}
+	srv := &http.Server{Handler: h2c.NewHandler(s.accessLogHandler(s.sniHandler(host, r)), &http2.Server{})}
 
 	level.Info(s.log).Log("msg", "now listening for http traffic", "addr", s.opts.HTTPListenAddr)
 
@@ -229,10 +250,52 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 
 	defer func() { _ = srv.Shutdown(ctx) }()
 
+	// Components are looked up once loading the config is done, so the
+	// components routed to by the sni blocks are checked in the background.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.sniChanged:
+				if err := s.checkSNIComponents(host); err != nil {
+					level.Warn(s.log).Log("msg", "sni blocks route to invalid components", "err", err)
+				}
+			}
+		}
+	}()
+
 	<-ctx.Done()
 	return nil
 }
 
+// checkSNIComponents returns an error for each component routed to by the sni
+// blocks which doesn't exist or doesn't have HTTP endpoints.
+func (s *Service) checkSNIComponents(host service.Host) error {
+	s.sniMut.RLock()
+	sni := s.sni
+	s.sniMut.RUnlock()
+
+	var errs []error
+	for _, block := range sni {
+		if block.Component == "" {
+			continue
+		}
+		info, err := host.GetComponent(component.ParseID(block.Component), component.InfoOptions{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("component %q: %w", block.Component, err))
+			continue
+		}
+		// Components which failed to build are reported by the component.
+		if _, ok := info.Component.(Component); !ok && info.Component != nil {
+			errs = append(errs, fmt.Errorf("component %q doesn't have HTTP endpoints", block.Component))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // getServiceRoutes returns a sorted list of service routes for services which
 // depend on the HTTP service.
 //
@@ -273,35 +336,76 @@ func (s *Service) componentHandler(host service.Host) http.HandlerFunc {
 			w.WriteHeader(http.StatusBadRequest)
 			fmt.Fprintf(w, "failed to parse URL path %q: %s\n", r.URL.Path, err)
 		}
+		serveComponent(host, componentID, componentPath, w, r)
+	}
+}
 
-		info, err := host.GetComponent(componentID, component.InfoOptions{})
-		if err != nil {
-			w.WriteHeader(http.StatusNotFound)
+// sniHandler sends requests made over TLS connections whose server name is
+// routed to a component by an sni block to the HTTP handler of the component.
+// Other requests are sent to next.
+func (s *Service) sniHandler(host service.Host, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		component, ok := info.Component.(Component)
-		if !ok {
-			w.WriteHeader(http.StatusNotFound)
-			return
+		s.sniMut.RLock()
+		var target string
+		for _, sni := range s.sni {
+			if sni.Component != "" && sni.Matches(r.TLS.ServerName) {
+				target = sni.Component
+				break
+			}
 		}
-		handler := component.Handler()
-		if handler == nil {
-			w.WriteHeader(http.StatusNotFound)
+		s.sniMut.RUnlock()
+
+		if target == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
+		// The component is at the root of routed server names, so the full path
+		// is sent to the component.
+		serveComponent(host, component.ParseID(target), r.URL.Path, w, r)
+	}
+}
+
+// serveComponent sends r to the HTTP handler of the component with the given
+// ID, with the path of r replaced by componentPath.
+func serveComponent(host service.Host, componentID component.ID, componentPath string, w http.ResponseWriter, r *http.Request) {
+	setAccessLogComponent(r.Context(), componentID.String())
 
-		// Send just the remaining path to our component so each component can
-		// handle paths from their own root path.
-		r.URL.Path = componentPath
-		handler.ServeHTTP(w, r)
+	info, err := host.GetComponent(componentID, component.InfoOptions{})
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	component, ok := info.Component.(Component)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
 	}
+	handler := component.Handler()
+	if handler == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Send just the remaining path to our component so each component can
+	// handle paths from their own root path.
+	r.URL.Path = componentPath
+	handler.ServeHTTP(w, r)
 }
 
 // Update implements [service.Service] and applies settings.
 func (s *Service) Update(newConfig any) error {
 	newArgs := newConfig.(Arguments)
 
+	s.accessLogMut.Lock()
+	s.accessLog = newArgs.AccessLog
+	s.accessLogMut.Unlock()
+
 	if newArgs.TLS != nil {
 		var tlsConfig *tls.Config
 		var err error
@@ -332,6 +436,18 @@ func (s *Service) Update(newConfig any) error {
 		}
 	}
 
+	s.sniMut.Lock()
+	s.sni = nil
+	if newArgs.TLS != nil {
+		s.sni = newArgs.TLS.SNI
+	}
+	s.sniMut.Unlock()
+
+	select {
+	case s.sniChanged <- struct{}{}:
+	default:
+	}
+
 	return nil
 }
 
@@ -350,7 +466,7 @@ func (s *Service) Data() any {
 			case s.opts.MemoryListenAddr:
 				return s.memLis.DialContext(ctx)
 			default:
-				return (&net.Dialer{}).DialContext(ctx, network, address)
+				return egress.DialContext(ctx, network, address)
 			}
 		},
 	}
@@ -3,6 +3,8 @@ package http
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"testing"
 
@@ -45,6 +47,38 @@ func TestHTTP(t *testing.T) {
 	})
 }
 
+func TestHTTP_Listener(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	svc := New(Options{
+		Logger:   util.TestLogger(t),
+		Tracer:   noop.NewTracerProvider(),
+		Gatherer: prometheus.NewRegistry(),
+
+		ReadyFunc: func() bool { return true },
+
+		HTTPListenAddr:   lis.Addr().String(),
+		MemoryListenAddr: "agent.internal:12345",
+		Listener:         lis,
+	})
+	require.NoError(t, svc.Update(Arguments{}))
+
+	go func() {
+		require.NoError(t, svc.Run(ctx, fakeHost{}))
+	}()
+
+	util.Eventually(t, func(t require.TestingT) {
+		resp, err := http.Get(fmt.Sprintf("http://%s/-/ready", lis.Addr()))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
 func TestTLS(t *testing.T) {
 	ctx := componenttest.TestContext(t)
 
@@ -155,6 +189,98 @@ func Test_Toggle_TLS(t *testing.T) {
 	}
 }
 
+func TestTLS_SNI(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+
+	env, err := newTestEnvironment(t)
+	require.NoError(t, err)
+	require.NoError(t, env.ApplyConfig(`
+		tls {
+			cert_file = "testdata/test-cert.crt"
+			key_file = "testdata/test-key.key"
+
+			sni {
+				server_names = ["logs.example.com", "*.tenants.example.com"]
+				cert_file = "testdata/sni-cert.crt"
+				key_file = "testdata/sni-key.key"
+				component = "loki.source.api.default"
+			}
+		}
+	`))
+
+	host := handlerHost{id: "loki.source.api.default", handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	})}
+	go func() {
+		require.NoError(t, env.svc.Run(ctx, host))
+	}()
+
+	get := func(t require.TestingT, caFile, serverName, path string) (int, string) {
+		cli, err := config.NewClientFromConfig(config.HTTPClientConfig{
+			TLSConfig: config.TLSConfig{
+				CAFile:     caFile,
+				ServerName: serverName,
+			},
+		}, "test")
+		require.NoError(t, err)
+
+		resp, err := cli.Get(fmt.Sprintf("https://%s%s", env.ListenAddr(), path))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// Connections without a routed server name use the default certificate
+	// and the agent's routes.
+	util.Eventually(t, func(t require.TestingT) {
+		code, _ := get(t, "testdata/test-cert.crt", "", "/-/ready")
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	// Connections with a routed server name use the certificate of the sni
+	// block and are sent to the component.
+	for _, serverName := range []string{"logs.example.com", "a.tenants.example.com"} {
+		code, body := get(t, "testdata/sni-cert.crt", serverName, "/-/ready")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "/-/ready", body)
+	}
+}
+
+func TestTLS_SNIComponents(t *testing.T) {
+	env, err := newTestEnvironment(t)
+	require.NoError(t, err)
+	require.NoError(t, env.ApplyConfig(`
+		tls {
+			cert_file = "testdata/test-cert.crt"
+			key_file = "testdata/test-key.key"
+
+			sni {
+				server_names = ["logs.example.com"]
+				cert_file = "testdata/sni-cert.crt"
+				key_file = "testdata/sni-key.key"
+				component = "loki.source.api.default"
+			}
+
+			sni {
+				server_names = ["metrics.example.com"]
+				cert_file = "testdata/sni-cert.crt"
+				key_file = "testdata/sni-key.key"
+				component = "prometheus.receive_http.missing"
+			}
+		}
+	`))
+
+	host := handlerHost{id: "loki.source.api.default", handler: http.NotFoundHandler()}
+	require.ErrorIs(t, env.svc.checkSNIComponents(host), component.ErrComponentNotFound)
+	require.EqualError(t, env.svc.checkSNIComponents(host), `component "prometheus.receive_http.missing": `+component.ErrComponentNotFound.Error())
+
+	host.id = "prometheus.receive_http.missing"
+	require.EqualError(t, env.svc.checkSNIComponents(host), `component "loki.source.api.default": `+component.ErrComponentNotFound.Error())
+}
+
 type testEnvironment struct {
 	svc  *Service
 	addr string
@@ -199,6 +325,31 @@ func (env *testEnvironment) Run(ctx context.Context) error {
 
 func (env *testEnvironment) ListenAddr() string { return env.addr }
 
+// handlerHost is a fakeHost with a single component which has an HTTP
+// handler.
+type handlerHost struct {
+	fakeHost
+	id      string
+	handler http.Handler
+}
+
+func (h handlerHost) GetComponent(id component.ID, opts component.InfoOptions) (*component.Info, error) {
+	if id.String() != h.id {
+		return nil, component.ErrComponentNotFound
+	}
+	return &component.Info{ID: id, Component: handlerComponent{handler: h.handler}}, nil
+}
+
+type handlerComponent struct {
+	handler http.Handler
+}
+
+func (handlerComponent) Run(ctx context.Context) error { <-ctx.Done(); return nil }
+
+func (handlerComponent) Update(component.Arguments) error { return nil }
+
+func (c handlerComponent) Handler() http.Handler { return c.handler }
+
 type fakeHost struct{}
 
 var _ service.Host = (fakeHost{})
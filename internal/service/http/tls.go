@@ -6,8 +6,10 @@ import (
 	"encoding"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/grafana/agent/internal/boringcrypto"
 	"github.com/grafana/regexp"
 	"github.com/grafana/river"
 	"github.com/grafana/river/rivertypes"
@@ -27,10 +29,77 @@ type TLSArguments struct {
 	MinVersion       TLSVersion        `river:"min_version,attr,optional"`
 	MaxVersion       TLSVersion        `river:"max_version,attr,optional"`
 
+	// Certificates and routes selected by the server name of clients.
+	SNI []SNIArguments `river:"sni,block,optional"`
+
 	// Windows Certificate Filter
 	WindowsFilter *WindowsCertificateFilter `river:"windows_certificate_filter,block,optional"`
 }
 
+// SNIArguments configures the certificate and the route of TLS connections
+// for a set of server names.
+type SNIArguments struct {
+	ServerNames []string          `river:"server_names,attr"`
+	Cert        string            `river:"cert_pem,attr,optional"`
+	CertFile    string            `river:"cert_file,attr,optional"`
+	Key         rivertypes.Secret `river:"key_pem,attr,optional"`
+	KeyFile     string            `river:"key_file,attr,optional"`
+	Component   string            `river:"component,attr,optional"`
+}
+
+// validate returns whether args is valid.
+func (args *SNIArguments) validate() error {
+	if len(args.ServerNames) == 0 {
+		return fmt.Errorf("sni: server_names must not be empty")
+	}
+	if len(args.Cert) > 0 && len(args.CertFile) > 0 {
+		return fmt.Errorf("sni: cannot specify both cert_pem and cert_file")
+	}
+	if len(args.Key) > 0 && len(args.KeyFile) > 0 {
+		return fmt.Errorf("sni: cannot specify both key_pem and key_file")
+	}
+	if len(args.Cert) == 0 && len(args.CertFile) == 0 {
+		return fmt.Errorf("sni: must specify either cert_pem or cert_file")
+	}
+	if len(args.Key) == 0 && len(args.KeyFile) == 0 {
+		return fmt.Errorf("sni: must specify either key_pem or key_file")
+	}
+	return nil
+}
+
+// Matches reports whether args applies to serverName. Server names of args
+// may start with a "*." wildcard which matches a single label.
+func (args *SNIArguments) Matches(serverName string) bool {
+	for _, name := range args.ServerNames {
+		if strings.EqualFold(name, serverName) {
+			return true
+		}
+		suffix, ok := strings.CutPrefix(name, "*")
+		if !ok {
+			continue
+		}
+		label, rest, ok := strings.Cut(serverName, ".")
+		if ok && label != "" && strings.EqualFold("."+rest, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSNI returns the first SNI block of args which matches serverName, or nil
+// if none matches.
+func (args *TLSArguments) findSNI(serverName string) *SNIArguments {
+	if serverName == "" {
+		return nil
+	}
+	for i := range args.SNI {
+		if args.SNI[i].Matches(serverName) {
+			return &args.SNI[i]
+		}
+	}
+	return nil
+}
+
 // WindowsCertificateFilter represents the configuration for accessing the Windows store
 type WindowsCertificateFilter struct {
 	Server *WindowsServerFilter `river:"server,block"`
@@ -64,10 +133,41 @@ var _ river.Validator = (*TLSArguments)(nil)
 
 // Validate returns whether args is valid.
 func (args *TLSArguments) Validate() error {
+	var err error
 	if args.WindowsFilter == nil {
-		return args.validateTLS()
+		err = args.validateTLS()
+	} else {
+		err = args.validateWindowsCertificateFilterTLS()
+	}
+	if err != nil {
+		return err
+	}
+	return args.validateFIPS()
+}
+
+// validateFIPS returns an error if the agent enforces FIPS-approved settings
+// and args uses settings which aren't approved.
+func (args *TLSArguments) validateFIPS() error {
+	suites := make([]uint16, 0, len(args.CipherSuites))
+	for _, s := range args.CipherSuites {
+		suites = append(suites, uint16(s))
+	}
+	if err := boringcrypto.CheckCipherSuites("cipher_suites", suites); err != nil {
+		return err
+	}
+
+	curves := make([]tls.CurveID, 0, len(args.CurvePreferences))
+	for _, c := range args.CurvePreferences {
+		curves = append(curves, tls.CurveID(c))
+	}
+	if err := boringcrypto.CheckCurves("curve_preferences", curves); err != nil {
+		return err
+	}
+
+	if err := boringcrypto.CheckTLSVersion("min_version", uint16(args.MinVersion)); err != nil {
+		return err
 	}
-	return args.validateWindowsCertificateFilterTLS()
+	return boringcrypto.CheckTLSVersion("max_version", uint16(args.MaxVersion))
 }
 
 // validateWindowsCertificateFilterTLS validates the Windows Certificate filter details.
@@ -81,6 +181,9 @@ func (args *TLSArguments) validateWindowsCertificateFilterTLS() error {
 		len(args.KeyFile) > 0:
 		return fmt.Errorf("cannot specify any key, certificate or CA when using windows certificate filter")
 	}
+	if len(args.SNI) > 0 {
+		return fmt.Errorf("cannot specify sni blocks when using windows certificate filter")
+	}
 	if args.WindowsFilter.Server == nil {
 		return fmt.Errorf("windows_certificate_filter requires a server block defined")
 	}
@@ -121,6 +224,20 @@ func (args *TLSArguments) validateTLS() error {
 		return fmt.Errorf("cannot specify client_ca_pem or client_ca_file when client_auth_type is NoClientCert")
 	}
 
+	serverNames := make(map[string]struct{})
+	for _, sni := range args.SNI {
+		if err := sni.validate(); err != nil {
+			return err
+		}
+		for _, name := range sni.ServerNames {
+			name = strings.ToLower(name)
+			if _, ok := serverNames[name]; ok {
+				return fmt.Errorf("sni: server name %q is specified more than once", name)
+			}
+			serverNames[name] = struct{}{}
+		}
+	}
+
 	return nil
 }
 
@@ -131,7 +248,10 @@ func (args *TLSArguments) tlsConfig() (*tls.Config, error) {
 		MaxVersion: uint16(args.MaxVersion),
 		ClientAuth: tls.ClientAuthType(args.ClientAuth),
 
-		GetCertificate: func(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if sni := args.findSNI(hello.ServerName); sni != nil {
+				return loadCertificate(sni.Cert, sni.CertFile, sni.Key, sni.KeyFile)
+			}
 			return args.tlsCertificate()
 		},
 	}
@@ -154,36 +274,41 @@ func (args *TLSArguments) tlsConfig() (*tls.Config, error) {
 
 // tlsCertificate generates a TLS certificate from the arguments.
 func (args *TLSArguments) tlsCertificate() (*tls.Certificate, error) {
+	return loadCertificate(args.Cert, args.CertFile, args.Key, args.KeyFile)
+}
+
+// loadCertificate generates a TLS certificate from PEM data or from files.
+func loadCertificate(cert, certFile string, key rivertypes.Secret, keyFile string) (*tls.Certificate, error) {
 	var (
 		certPEM []byte
 		keyPEM  []byte
 	)
 
-	if len(args.Cert) > 0 {
-		certPEM = []byte(args.Cert)
+	if len(cert) > 0 {
+		certPEM = []byte(cert)
 	} else {
 		var err error
-		certPEM, err = os.ReadFile(args.CertFile)
+		certPEM, err = os.ReadFile(certFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read cert_file: %w", err)
 		}
 	}
 
-	if len(args.Key) > 0 {
-		keyPEM = []byte(args.Key)
+	if len(key) > 0 {
+		keyPEM = []byte(key)
 	} else {
 		var err error
-		keyPEM, err = os.ReadFile(args.KeyFile)
+		keyPEM, err = os.ReadFile(keyFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read key_file: %w", err)
 		}
 	}
 
-	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		return nil, err
 	}
-	return &cert, nil
+	return &pair, nil
 }
 
 // clientCAPool generates a client CA pool from the arguments. If client CA
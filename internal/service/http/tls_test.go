@@ -27,3 +27,33 @@ func TestTLSWindowsCertificate(t *testing.T) {
 	err = cfg.validateWindowsCertificateFilterTLS()
 	require.NoError(t, err)
 }
+
+func TestTLSArguments_SNI(t *testing.T) {
+	sni := SNIArguments{
+		ServerNames: []string{"logs.example.com", "*.tenants.example.com"},
+		CertFile:    "testdata/sni-cert.crt",
+		KeyFile:     "testdata/sni-key.key",
+	}
+	cfg := &TLSArguments{
+		CertFile: "testdata/test-cert.crt",
+		KeyFile:  "testdata/test-key.key",
+		SNI:      []SNIArguments{sni},
+	}
+	require.NoError(t, cfg.Validate())
+
+	require.Equal(t, &cfg.SNI[0], cfg.findSNI("LOGS.example.com"))
+	require.Equal(t, &cfg.SNI[0], cfg.findSNI("a.tenants.example.com"))
+	require.Nil(t, cfg.findSNI("a.b.tenants.example.com"))
+	require.Nil(t, cfg.findSNI("tenants.example.com"))
+	require.Nil(t, cfg.findSNI(""))
+
+	cfg.SNI = append(cfg.SNI, SNIArguments{
+		ServerNames: []string{"logs.EXAMPLE.com"},
+		CertFile:    "testdata/sni-cert.crt",
+		KeyFile:     "testdata/sni-key.key",
+	})
+	require.EqualError(t, cfg.Validate(), `sni: server name "logs.example.com" is specified more than once`)
+
+	cfg.SNI = []SNIArguments{{ServerNames: []string{"logs.example.com"}, CertFile: "testdata/sni-cert.crt"}}
+	require.EqualError(t, cfg.Validate(), "sni: must specify either key_pem or key_file")
+}
@@ -0,0 +1,184 @@
+// Package ports implements the ports service, which allocates free listen
+// ports to components from the port ranges they request.
+package ports
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ServiceName defines the name used for the ports service.
+const ServiceName = "ports"
+
+// Arguments holds runtime settings for the ports service.
+type Arguments struct{}
+
+// Range is an inclusive range of ports.
+type Range struct {
+	Min, Max int
+}
+
+// ParseRange parses a range of ports in the form MIN-MAX.
+func ParseRange(s string) (Range, error) {
+	minText, maxText, ok := strings.Cut(s, "-")
+	if !ok {
+		return Range{}, fmt.Errorf("invalid port range %q, must be in the form MIN-MAX", s)
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(minText))
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	max, err := strconv.Atoi(strings.TrimSpace(maxText))
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid port range %q: %w", s, err)
+	}
+	if min < 1 || max > 65535 || min > max {
+		return Range{}, fmt.Errorf("invalid port range %q, must be between 1 and 65535 with MIN not greater than MAX", s)
+	}
+	return Range{Min: min, Max: max}, nil
+}
+
+// String returns the range in the form MIN-MAX.
+func (r Range) String() string { return fmt.Sprintf("%d-%d", r.Min, r.Max) }
+
+// Contains reports whether port is in r.
+func (r Range) Contains(port int) bool { return port >= r.Min && port <= r.Max }
+
+// lease is a port allocated to a listener of a component.
+type lease struct {
+	owner, name string
+}
+
+// Allocator allocates ports to the listeners of components. Ports stay
+// allocated to their listener until the component releases them, so
+// components which restart their listeners keep their ports.
+type Allocator struct {
+	allocated prometheus.Gauge
+
+	mut    sync.Mutex
+	leases map[int]lease
+	ports  map[string]map[string]int // Owner -> listener name -> port.
+}
+
+func newAllocator(allocated prometheus.Gauge) *Allocator {
+	return &Allocator{
+		allocated: allocated,
+		leases:    make(map[int]lease),
+		ports:     make(map[string]map[string]int),
+	}
+}
+
+// Allocate returns a port in r for the listener called name of the component
+// with the global ID owner. The port is free on host and isn't allocated to
+// another listener. If the listener already has a port in r, Allocate returns
+// that port.
+func (a *Allocator) Allocate(owner, name, host string, r Range) (int, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if port, ok := a.ports[owner][name]; ok {
+		if r.Contains(port) {
+			return port, nil
+		}
+		a.release(port)
+	}
+
+	for port := r.Min; port <= r.Max; port++ {
+		if _, ok := a.leases[port]; ok {
+			continue
+		}
+		lis, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			continue
+		}
+		_ = lis.Close()
+
+		a.leases[port] = lease{owner: owner, name: name}
+		if a.ports[owner] == nil {
+			a.ports[owner] = make(map[string]int)
+		}
+		a.ports[owner][name] = port
+		a.allocated.Set(float64(len(a.leases)))
+		return port, nil
+	}
+	return 0, fmt.Errorf("no free port in range %s for %s", r, owner)
+}
+
+// Release releases the ports allocated to the listeners of the component with
+// the global ID owner.
+func (a *Allocator) Release(owner string) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	for _, port := range a.ports[owner] {
+		a.release(port)
+	}
+}
+
+// release releases port. a.mut must be held when calling release.
+func (a *Allocator) release(port int) {
+	l, ok := a.leases[port]
+	if !ok {
+		return
+	}
+	delete(a.leases, port)
+	delete(a.ports[l.owner], l.name)
+	if len(a.ports[l.owner]) == 0 {
+		delete(a.ports, l.owner)
+	}
+	a.allocated.Set(float64(len(a.leases)))
+}
+
+// Service implements the ports service.
+type Service struct {
+	alloc *Allocator
+}
+
+var _ service.Service = (*Service)(nil)
+
+// New returns a new, unstarted instance of the ports service.
+func New(r prometheus.Registerer) *Service {
+	allocated := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "agent_ports_allocated",
+		Help: "Number of listen ports allocated to components.",
+	})
+	if r != nil {
+		r.MustRegister(allocated)
+	}
+	return &Service{alloc: newAllocator(allocated)}
+}
+
+// Definition returns the definition of the ports service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  nil, // ports has no dependencies.
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Data returns the *Allocator of the service.
+func (s *Service) Data() any {
+	return s.alloc
+}
+
+// Run implements [service.Service]. Ports are allocated on demand, so Run
+// only waits for ctx to be canceled.
+func (s *Service) Run(ctx context.Context, _ service.Host) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements [service.Service]. The ports service has no settings.
+func (s *Service) Update(_ any) error {
+	return nil
+}
@@ -0,0 +1,71 @@
+package ports
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/phayes/freeport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	r, err := ParseRange("20000-20010")
+	require.NoError(t, err)
+	require.Equal(t, Range{Min: 20000, Max: 20010}, r)
+	require.Equal(t, "20000-20010", r.String())
+
+	_, err = ParseRange("20000")
+	require.EqualError(t, err, `invalid port range "20000", must be in the form MIN-MAX`)
+	_, err = ParseRange("20010-20000")
+	require.EqualError(t, err, `invalid port range "20010-20000", must be between 1 and 65535 with MIN not greater than MAX`)
+	_, err = ParseRange("0-100")
+	require.Error(t, err)
+}
+
+func TestAllocator(t *testing.T) {
+	ports, err := freeport.GetFreePorts(3)
+	require.NoError(t, err)
+	r := Range{Min: ports[0], Max: ports[0]}
+
+	s := New(prometheus.NewRegistry())
+	alloc := s.Data().(*Allocator)
+
+	port, err := alloc.Allocate("loki.source.api.a", "http", "127.0.0.1", r)
+	require.NoError(t, err)
+	require.Equal(t, ports[0], port)
+	require.Equal(t, 1.0, testutil.ToFloat64(alloc.allocated))
+
+	// The listener keeps its port, even while it's bound.
+	lis, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	require.NoError(t, err)
+	defer lis.Close()
+	port, err = alloc.Allocate("loki.source.api.a", "http", "127.0.0.1", r)
+	require.NoError(t, err)
+	require.Equal(t, ports[0], port)
+
+	// Other listeners don't get allocated ports.
+	_, err = alloc.Allocate("loki.source.api.b", "http", "127.0.0.1", r)
+	require.EqualError(t, err, "no free port in range "+r.String()+" for loki.source.api.b")
+
+	// Released ports can be allocated again once they're free.
+	require.NoError(t, lis.Close())
+	alloc.Release("loki.source.api.a")
+	require.Equal(t, 0.0, testutil.ToFloat64(alloc.allocated))
+	port, err = alloc.Allocate("loki.source.api.b", "http", "127.0.0.1", r)
+	require.NoError(t, err)
+	require.Equal(t, ports[0], port)
+}
+
+func TestAllocator_SkipsBoundPorts(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	bound := lis.Addr().(*net.TCPAddr).Port
+
+	alloc := New(nil).Data().(*Allocator)
+	_, err = alloc.Allocate("loki.source.api.a", "http", "127.0.0.1", Range{Min: bound, Max: bound})
+	require.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/egress"
 	"github.com/grafana/river"
 	commonconfig "github.com/prometheus/common/config"
 )
@@ -189,7 +190,7 @@ func (s *Service) Update(newConfig any) error {
 	s.ch = s.ticker.C
 	// Update the HTTP client last since it might fail.
 	if !reflect.DeepEqual(s.args.HTTPClientConfig, newArgs.HTTPClientConfig) {
-		httpClient, err := commonconfig.NewClientFromConfig(*newArgs.HTTPClientConfig.Convert(), "remoteconfig")
+		httpClient, err := commonconfig.NewClientFromConfig(*newArgs.HTTPClientConfig.Convert(), "remoteconfig", egress.HTTPClientOption())
 		if err != nil {
 			return err
 		}
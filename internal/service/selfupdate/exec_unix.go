@@ -0,0 +1,17 @@
+//go:build !windows
+
+package selfupdate
+
+import (
+	"os"
+	"syscall"
+)
+
+const execSupported = true
+
+// Exec replaces the running process with the binary at path, keeping the
+// arguments, environment, and process ID of the running process. Exec only
+// returns if the binary couldn't be started.
+func Exec(path string) error {
+	return syscall.Exec(path, append([]string{path}, os.Args[1:]...), os.Environ())
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package selfupdate
+
+import "fmt"
+
+// Windows can't replace a running process, and the agent is usually run
+// there by the service manager, which must start the new binary instead.
+const execSupported = false
+
+// Exec is not supported on Windows.
+func Exec(path string) error {
+	return fmt.Errorf("self-update is not supported on windows")
+}
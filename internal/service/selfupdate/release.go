@@ -0,0 +1,206 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+)
+
+// Stages of a self-update, used as values of the stage label.
+const (
+	stageCheck    = "check"
+	stageDownload = "download"
+	stageVerify   = "verify"
+	stageInstall  = "install"
+)
+
+// manifest describes the latest release of a channel for one platform.
+// Manifests are served at <url>/<channel>/<GOOS>-<GOARCH>.json, next to a
+// .sig file holding the base64-encoded Ed25519 signature of the manifest.
+type manifest struct {
+	Version string `json:"version"`
+	Channel string `json:"channel"`
+	URL     string `json:"url"`    // URL of the binary, relative to the manifest.
+	SHA256  string `json:"sha256"` // Hex-encoded SHA-256 digest of the binary.
+
+	binaryURL *url.URL
+	version   semver.Version
+}
+
+// fetchManifest downloads the manifest of channel from the repository at
+// baseURL and verifies it against key.
+func fetchManifest(ctx context.Context, client *http.Client, baseURL, channel string, key ed25519.PublicKey) (*manifest, error) {
+	manifestURL, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/" + channel + "/" + runtime.GOOS + "-" + runtime.GOARCH + ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := get(ctx, client, manifestURL.String(), 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := get(ctx, client, manifestURL.String()+".sig", 1<<10)
+	if err != nil {
+		return nil, err
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return nil, fmt.Errorf("decoding manifest signature: %w", err)
+	}
+	if !ed25519.Verify(key, body, rawSig) {
+		return nil, fmt.Errorf("manifest %s has an invalid signature", manifestURL)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	// The channel is part of the signed manifest so that a manifest of one
+	// channel can't be served for another.
+	if m.Channel != channel {
+		return nil, fmt.Errorf("manifest %s is for channel %q", manifestURL, m.Channel)
+	}
+	if m.version, err = semver.ParseTolerant(m.Version); err != nil {
+		return nil, fmt.Errorf("invalid version %q in manifest: %w", m.Version, err)
+	}
+	if channel == ChannelStable && len(m.version.Pre) > 0 {
+		return nil, fmt.Errorf("manifest of the %s channel has pre-release version %s", channel, m.Version)
+	}
+	if m.binaryURL, err = manifestURL.Parse(m.URL); err != nil {
+		return nil, fmt.Errorf("invalid binary URL %q in manifest: %w", m.URL, err)
+	}
+	if _, err := hex.DecodeString(m.SHA256); err != nil || len(m.SHA256) != 2*sha256.Size {
+		return nil, fmt.Errorf("invalid sha256 %q in manifest", m.SHA256)
+	}
+	return &m, nil
+}
+
+// newerThan reports whether the release of m is newer than the running
+// version.
+func (m *manifest) newerThan(running string) (bool, error) {
+	v, err := semver.ParseTolerant(running)
+	if err != nil {
+		return false, fmt.Errorf("running version %q is not a release version", running)
+	}
+	return m.version.GT(v), nil
+}
+
+func get(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// install downloads and verifies the binary of m and replaces exe with it.
+// The replaced binary is kept next to exe with an .old suffix. If install
+// fails, it returns the stage which failed, and exe is left unchanged.
+func install(ctx context.Context, client *http.Client, m *manifest, exe string) (stage string, err error) {
+	// The binary is downloaded next to exe so it can be renamed in place.
+	f, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+".update-*")
+	if err != nil {
+		return stageInstall, err
+	}
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	digest, err := download(ctx, client, m.binaryURL.String(), f)
+	if err != nil {
+		return stageDownload, err
+	}
+	if digest != m.SHA256 {
+		return stageVerify, fmt.Errorf("binary has sha256 %s, expected %s", digest, m.SHA256)
+	}
+	if err := f.Close(); err != nil {
+		return stageDownload, err
+	}
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		return stageInstall, err
+	}
+	if err := checkBinary(ctx, f.Name(), m.Version); err != nil {
+		return stageVerify, err
+	}
+
+	old := exe + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return stageInstall, err
+	}
+	if err := os.Rename(f.Name(), exe); err != nil {
+		_ = os.Rename(old, exe)
+		return stageInstall, err
+	}
+	return "", nil
+}
+
+// download writes the body of url to w, returning its hex-encoded SHA-256
+// digest.
+func download(ctx context.Context, client *http.Client, url string, w io.Writer) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: unexpected status %s", url, resp.Status)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkBinary runs the binary at path with --version, to make sure that it
+// starts on this host and reports the expected version before the running
+// binary is replaced.
+func checkBinary(ctx context.Context, path, version string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("running %s --version: %w", path, err)
+	}
+	if !bytes.Contains(out, []byte(version)) {
+		return fmt.Errorf("new binary does not report version %s: %s", version, bytes.TrimSpace(out))
+	}
+	return nil
+}
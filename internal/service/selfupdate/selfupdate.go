@@ -0,0 +1,356 @@
+// Package selfupdate implements the selfupdate service, which replaces the
+// running agent binary with newer signed releases from an artifact
+// repository.
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/egress"
+	"github.com/prometheus/client_golang/prometheus"
+	commonconfig "github.com/prometheus/common/config"
+)
+
+// ServiceName defines the name used for the selfupdate service.
+const ServiceName = "selfupdate"
+
+// Release channels.
+const (
+	ChannelStable = "stable"
+	ChannelRC     = "rc"
+)
+
+// Arguments holds runtime settings for the selfupdate service.
+type Arguments struct {
+	// URL of the artifact repository. The binary isn't updated if URL is
+	// empty.
+	URL string `river:"url,attr,optional"`
+
+	// Channel is the release channel which updates are taken from.
+	Channel string `river:"channel,attr,optional"`
+
+	// CheckInterval is how often the artifact repository is checked for a
+	// new release.
+	CheckInterval time.Duration `river:"check_interval,attr,optional"`
+
+	// PublicKey is the PEM-encoded Ed25519 public key which release
+	// manifests are signed with.
+	PublicKey string `river:"public_key,attr,optional"`
+
+	HTTPClientConfig *config.HTTPClientConfig `river:",squash"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Channel:       ChannelStable,
+	CheckInterval: time.Hour,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+	a.HTTPClientConfig = config.CloneDefaultHTTPClientConfig()
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.URL == "" {
+		return nil
+	}
+
+	if _, err := url.Parse(a.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if a.Channel != ChannelStable && a.Channel != ChannelRC {
+		return fmt.Errorf("channel must be %q or %q, got %q", ChannelStable, ChannelRC, a.Channel)
+	}
+	if a.CheckInterval <= 0 {
+		return fmt.Errorf("check_interval must be greater than 0")
+	}
+	if a.PublicKey == "" {
+		return fmt.Errorf("public_key must be set when url is set")
+	}
+	if _, err := parsePublicKey(a.PublicKey); err != nil {
+		return err
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it
+	// won't run otherwise.
+	if a.HTTPClientConfig != nil {
+		return a.HTTPClientConfig.Validate()
+	}
+	return nil
+}
+
+func parsePublicKey(s string) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, fmt.Errorf("public_key must be a PEM-encoded public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public_key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public_key must be an Ed25519 key, got %T", key)
+	}
+	return edKey, nil
+}
+
+// Options are used to configure the selfupdate service. Options are constant
+// for the lifetime of the selfupdate service.
+type Options struct {
+	Logger     log.Logger            // Where to send logs.
+	Registerer prometheus.Registerer // Where to register metrics.
+
+	// Shutdown is called once a new binary has been installed, and must
+	// gracefully stop the agent so the new binary can be started with Exec.
+	Shutdown func()
+
+	// Version of the running binary. Defaults to build.Version.
+	Version string
+
+	// Executable is the path of the running binary. Defaults to
+	// os.Executable.
+	Executable string
+}
+
+// Service implements the selfupdate service.
+type Service struct {
+	opts Options
+
+	lastCheck        prometheus.Gauge
+	failures         *prometheus.CounterVec
+	availableVersion *prometheus.GaugeVec
+	updatesInstalled prometheus.Counter
+
+	updated chan struct{}
+
+	mut       sync.RWMutex
+	args      Arguments
+	publicKey ed25519.PublicKey
+	client    *http.Client
+	installed string // Path of the installed binary, if any.
+}
+
+var _ service.Service = (*Service)(nil)
+
+// New returns a new, unstarted instance of the selfupdate service.
+func New(opts Options) *Service {
+	if opts.Logger == nil {
+		opts.Logger = log.NewNopLogger()
+	}
+	if opts.Version == "" {
+		opts.Version = build.Version
+	}
+
+	s := &Service{
+		opts: opts,
+
+		lastCheck: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_selfupdate_last_check_timestamp_seconds",
+			Help: "Unix timestamp of the last successful check for a new release.",
+		}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_selfupdate_failures_total",
+			Help: "Total number of failed self-update attempts, by the stage which failed.",
+		}, []string{"stage"}),
+		availableVersion: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "agent_selfupdate_available_version_info",
+			Help: "Latest release available on the configured channel. The value is 1 if the release is newer than the running binary.",
+		}, []string{"version", "channel"}),
+		updatesInstalled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_selfupdate_installed_total",
+			Help: "Total number of new binaries installed by the self-update service.",
+		}),
+
+		updated: make(chan struct{}, 1),
+	}
+	if opts.Registerer != nil {
+		opts.Registerer.MustRegister(s.lastCheck, s.failures, s.availableVersion, s.updatesInstalled)
+	}
+	return s
+}
+
+// Definition returns the definition of the selfupdate service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  nil, // selfupdate has no dependencies.
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Data is a no-op for the selfupdate service.
+func (s *Service) Data() any {
+	return nil
+}
+
+// Installed returns the path of the binary installed by the service, or an
+// empty string if no binary has been installed. The caller should start the
+// installed binary with Exec once the agent has shut down.
+func (s *Service) Installed() string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.installed
+}
+
+// Run implements [service.Service]. It checks the artifact repository for a
+// new release every check_interval until ctx is canceled or a new binary is
+// installed.
+func (s *Service) Run(ctx context.Context, _ service.Host) error {
+	// Configuration applied before the service started is picked up by the
+	// first check.
+	select {
+	case <-s.updated:
+	default:
+	}
+
+	for {
+		s.mut.RLock()
+		var (
+			enabled  = s.args.URL != ""
+			interval = s.args.CheckInterval
+		)
+		s.mut.RUnlock()
+
+		var tick <-chan time.Time
+		if enabled {
+			// The repository is checked right away when the service is
+			// started or updated.
+			if s.check(ctx) {
+				return nil
+			}
+			tick = time.After(interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.updated:
+		case <-tick:
+		}
+	}
+}
+
+// Update implements [service.Service].
+func (s *Service) Update(newConfig any) error {
+	newArgs := newConfig.(Arguments)
+
+	var (
+		key    ed25519.PublicKey
+		client *http.Client
+	)
+	if newArgs.URL != "" {
+		if !execSupported {
+			return fmt.Errorf("self-update is not supported on this platform")
+		}
+
+		var err error
+		if key, err = parsePublicKey(newArgs.PublicKey); err != nil {
+			return err
+		}
+		httpClientConfig := newArgs.HTTPClientConfig
+		if httpClientConfig == nil {
+			httpClientConfig = config.CloneDefaultHTTPClientConfig()
+		}
+		client, err = commonconfig.NewClientFromConfig(*httpClientConfig.Convert(), ServiceName, egress.HTTPClientOption())
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mut.Lock()
+	s.args = newArgs
+	s.publicKey = key
+	s.client = client
+	s.mut.Unlock()
+
+	select {
+	case s.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// check looks for a new release and installs it, returning true if a new
+// binary was installed.
+func (s *Service) check(ctx context.Context) bool {
+	s.mut.RLock()
+	var (
+		args   = s.args
+		key    = s.publicKey
+		client = s.client
+	)
+	s.mut.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	m, err := fetchManifest(ctx, client, args.URL, args.Channel, key)
+	if err != nil {
+		s.failures.WithLabelValues(stageCheck).Inc()
+		level.Warn(s.opts.Logger).Log("msg", "failed to check for a new release", "err", err)
+		return false
+	}
+	s.lastCheck.SetToCurrentTime()
+
+	newer, err := m.newerThan(s.opts.Version)
+	if err != nil {
+		s.failures.WithLabelValues(stageCheck).Inc()
+		level.Warn(s.opts.Logger).Log("msg", "failed to compare release versions", "err", err)
+		return false
+	}
+
+	s.availableVersion.Reset()
+	if !newer {
+		s.availableVersion.WithLabelValues(m.Version, args.Channel).Set(0)
+		level.Debug(s.opts.Logger).Log("msg", "agent is up to date", "version", s.opts.Version, "channel", args.Channel)
+		return false
+	}
+	s.availableVersion.WithLabelValues(m.Version, args.Channel).Set(1)
+
+	exe := s.opts.Executable
+	if exe == "" {
+		if exe, err = os.Executable(); err != nil {
+			s.failures.WithLabelValues(stageInstall).Inc()
+			level.Error(s.opts.Logger).Log("msg", "failed to find the running binary", "err", err)
+			return false
+		}
+	}
+
+	level.Info(s.opts.Logger).Log("msg", "installing new release", "version", m.Version, "channel", args.Channel)
+	if stage, err := install(ctx, client, m, exe); err != nil {
+		s.failures.WithLabelValues(stage).Inc()
+		level.Error(s.opts.Logger).Log("msg", "failed to install new release", "version", m.Version, "stage", stage, "err", err)
+		return false
+	}
+	s.updatesInstalled.Inc()
+
+	s.mut.Lock()
+	s.installed = exe
+	s.mut.Unlock()
+
+	level.Info(s.opts.Logger).Log("msg", "installed new release, restarting", "version", m.Version, "path", exe)
+	if s.opts.Shutdown != nil {
+		s.opts.Shutdown()
+	}
+	return true
+}
@@ -0,0 +1,304 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(``), &args))
+	require.Equal(t, ChannelStable, args.Channel)
+	require.Equal(t, time.Hour, args.CheckInterval)
+
+	_, publicKey := generateKey(t)
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "missing public key",
+			config: `url = "https://example.org"`,
+			err:    "public_key must be set when url is set",
+		},
+		{
+			name:   "invalid channel",
+			config: "url = \"https://example.org\"\nchannel = \"nightly\"",
+			err:    `channel must be "stable" or "rc", got "nightly"`,
+		},
+		{
+			name:   "invalid public key",
+			config: "url = \"https://example.org\"\npublic_key = \"key\"",
+			err:    "public_key must be a PEM-encoded public key",
+		},
+		{
+			name:   "valid",
+			config: "url = \"https://example.org\"\nchannel = \"rc\"\npublic_key = " + strconv.Quote(publicKey),
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var args Arguments
+			err := river.Unmarshal([]byte(tc.config), &args)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestService(t *testing.T) {
+	if !execSupported || runtime.GOOS == "windows" {
+		t.Skip("self-update is not supported on this platform")
+	}
+
+	privateKey, publicKey := generateKey(t)
+	repo := newRepository(t, privateKey)
+
+	tt := []struct {
+		name      string
+		channel   string
+		release   release
+		installed bool
+		metrics   string
+	}{
+		{
+			name:      "update",
+			channel:   ChannelStable,
+			release:   release{version: "v0.41.0", channel: ChannelStable},
+			installed: true,
+			metrics: `
+				# HELP agent_selfupdate_available_version_info Latest release available on the configured channel. The value is 1 if the release is newer than the running binary.
+				# TYPE agent_selfupdate_available_version_info gauge
+				agent_selfupdate_available_version_info{channel="stable",version="v0.41.0"} 1
+				# HELP agent_selfupdate_installed_total Total number of new binaries installed by the self-update service.
+				# TYPE agent_selfupdate_installed_total counter
+				agent_selfupdate_installed_total 1
+			`,
+		},
+		{
+			name:    "up to date",
+			channel: ChannelStable,
+			release: release{version: "v0.40.0", channel: ChannelStable},
+			metrics: `
+				# HELP agent_selfupdate_available_version_info Latest release available on the configured channel. The value is 1 if the release is newer than the running binary.
+				# TYPE agent_selfupdate_available_version_info gauge
+				agent_selfupdate_available_version_info{channel="stable",version="v0.40.0"} 0
+				# HELP agent_selfupdate_installed_total Total number of new binaries installed by the self-update service.
+				# TYPE agent_selfupdate_installed_total counter
+				agent_selfupdate_installed_total 0
+			`,
+		},
+		{
+			name:    "invalid signature",
+			channel: ChannelStable,
+			release: release{version: "v0.41.0", channel: ChannelStable, badSignature: true},
+			metrics: failureMetrics(stageCheck),
+		},
+		{
+			name:    "manifest of another channel",
+			channel: ChannelStable,
+			release: release{version: "v0.41.0-rc.0", channel: ChannelRC},
+			metrics: failureMetrics(stageCheck),
+		},
+		{
+			name:    "pre-release on stable channel",
+			channel: ChannelStable,
+			release: release{version: "v0.41.0-rc.0", channel: ChannelStable},
+			metrics: failureMetrics(stageCheck),
+		},
+		{
+			name:    "digest mismatch",
+			channel: ChannelStable,
+			release: release{version: "v0.41.0", channel: ChannelStable, badDigest: true},
+			metrics: failureMetrics(stageVerify),
+		},
+		{
+			name:    "binary reports another version",
+			channel: ChannelStable,
+			release: release{version: "v0.41.0", channel: ChannelStable, binaryVersion: "v0.39.0"},
+			metrics: failureMetrics(stageVerify),
+		},
+		{
+			name:      "rc channel",
+			channel:   ChannelRC,
+			release:   release{version: "v0.41.0-rc.0", channel: ChannelRC},
+			installed: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			repo.publish(tc.channel, tc.release)
+
+			exe := filepath.Join(t.TempDir(), "grafana-agent")
+			require.NoError(t, os.WriteFile(exe, fakeBinary("v0.40.0"), 0755))
+
+			var (
+				reg      = prometheus.NewRegistry()
+				shutdown = make(chan struct{})
+			)
+			s := New(Options{
+				Logger:     util.TestLogger(t),
+				Registerer: reg,
+				Shutdown:   func() { close(shutdown) },
+				Version:    "v0.40.0",
+				Executable: exe,
+			})
+
+			args := DefaultArguments
+			args.URL = repo.URL
+			args.Channel = tc.channel
+			args.PublicKey = publicKey
+			require.NoError(t, s.Update(args))
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			require.Equal(t, tc.installed, s.check(ctx))
+
+			content, err := os.ReadFile(exe)
+			require.NoError(t, err)
+			if tc.installed {
+				require.Equal(t, exe, s.Installed())
+				require.Equal(t, fakeBinary(tc.release.version), content)
+				require.FileExists(t, exe+".old")
+				require.Eventually(t, func() bool {
+					select {
+					case <-shutdown:
+						return true
+					default:
+						return false
+					}
+				}, time.Second, 10*time.Millisecond)
+			} else {
+				require.Empty(t, s.Installed())
+				require.Equal(t, fakeBinary("v0.40.0"), content)
+			}
+
+			// Downloads which fail verification are removed.
+			entries, err := os.ReadDir(filepath.Dir(exe))
+			require.NoError(t, err)
+			for _, e := range entries {
+				require.False(t, strings.Contains(e.Name(), ".update-"), "leftover download %s", e.Name())
+			}
+
+			if tc.metrics != "" {
+				names := []string{"agent_selfupdate_installed_total", "agent_selfupdate_failures_total"}
+				if strings.Contains(tc.metrics, "agent_selfupdate_available_version_info") {
+					names = append(names, "agent_selfupdate_available_version_info")
+				}
+				require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(tc.metrics), names...))
+			}
+		})
+	}
+}
+
+func failureMetrics(stage string) string {
+	return `
+		# HELP agent_selfupdate_failures_total Total number of failed self-update attempts, by the stage which failed.
+		# TYPE agent_selfupdate_failures_total counter
+		agent_selfupdate_failures_total{stage="` + stage + `"} 1
+		# HELP agent_selfupdate_installed_total Total number of new binaries installed by the self-update service.
+		# TYPE agent_selfupdate_installed_total counter
+		agent_selfupdate_installed_total 0
+	`
+}
+
+func generateKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	return privateKey, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// fakeBinary returns a script which prints version like the agent does.
+func fakeBinary(version string) []byte {
+	return []byte("#!/bin/sh\necho \"agent, version " + version + "\"\n")
+}
+
+type release struct {
+	version       string
+	channel       string
+	binaryVersion string // Version reported by the binary, if not version.
+	badSignature  bool
+	badDigest     bool
+}
+
+type repository struct {
+	*httptest.Server
+	key   ed25519.PrivateKey
+	files map[string][]byte
+}
+
+func newRepository(t *testing.T, key ed25519.PrivateKey) *repository {
+	r := &repository{key: key, files: map[string][]byte{}}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		content, ok := r.files[req.URL.Path]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// publish makes rel the latest release of channel.
+func (r *repository) publish(channel string, rel release) {
+	binaryVersion := rel.binaryVersion
+	if binaryVersion == "" {
+		binaryVersion = rel.version
+	}
+	binary := fakeBinary(binaryVersion)
+
+	digest := sha256.Sum256(binary)
+	if rel.badDigest {
+		digest = sha256.Sum256(nil)
+	}
+	m, _ := json.Marshal(manifest{
+		Version: rel.version,
+		Channel: rel.channel,
+		URL:     "../binaries/" + rel.version + "/grafana-agent",
+		SHA256:  hex.EncodeToString(digest[:]),
+	})
+	sig := ed25519.Sign(r.key, m)
+	if rel.badSignature {
+		sig = ed25519.Sign(r.key, []byte("something else"))
+	}
+
+	manifestPath := "/" + channel + "/" + runtime.GOOS + "-" + runtime.GOARCH + ".json"
+	r.files = map[string][]byte{
+		manifestPath:          m,
+		manifestPath + ".sig": []byte(base64.StdEncoding.EncodeToString(sig)),
+		"/binaries/" + rel.version + "/grafana-agent": binary,
+	}
+}
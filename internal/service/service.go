@@ -143,3 +143,13 @@ type Service interface {
 	// Data may be invoked before Run.
 	Data() any
 }
+
+// ComponentObserver is an optional interface implemented by services which
+// keep state for components. Controllers call ComponentsRemoved with the
+// global IDs of the components removed from them after a reload, so the
+// service can forget their state. The components running in a module are
+// removed along with the component which created the module, so services
+// should also forget the state of IDs prefixed by a removed ID and "/".
+type ComponentObserver interface {
+	ComponentsRemoved(ids []string)
+}
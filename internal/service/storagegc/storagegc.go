@@ -0,0 +1,315 @@
+// Package storagegc implements the storage_gc service, which reconciles the
+// data directories of the storage path with the running components and
+// deletes the directories of components which were removed.
+package storagegc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ServiceName defines the name used for the storage_gc service.
+const ServiceName = "storage_gc"
+
+// importPrefix is the prefix of the IDs of import blocks, whose data
+// directories are never orphaned as import blocks aren't components.
+const importPrefix = "import."
+
+// Arguments holds runtime settings for the storage_gc service.
+type Arguments struct {
+	// Interval is how often to reconcile the data directories.
+	Interval time.Duration `river:"interval,attr,optional"`
+
+	// GracePeriod is how long a directory must be orphaned before it's
+	// deleted.
+	GracePeriod time.Duration `river:"grace_period,attr,optional"`
+
+	// Delete enables deleting orphaned directories. Orphaned directories are
+	// only reported when Delete is false.
+	Delete bool `river:"delete,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval:    time.Hour,
+	GracePeriod: 24 * time.Hour,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if a.GracePeriod < 0 {
+		return fmt.Errorf("grace_period must not be negative")
+	}
+	return nil
+}
+
+// Options are used to configure the storage_gc service. Options are constant
+// for the lifetime of the storage_gc service.
+type Options struct {
+	Logger      log.Logger            // Where to send logs.
+	Registerer  prometheus.Registerer // Where to register metrics.
+	StoragePath string                // Storage path holding the data directories of components.
+
+	// Exclude lists the names of the entries of StoragePath which aren't owned
+	// by components, such as the directories of services, and must never be
+	// deleted.
+	Exclude []string
+}
+
+// Service implements the storage_gc service.
+type Service struct {
+	opts    Options
+	exclude map[string]struct{}
+
+	orphanedDirs  prometheus.Gauge
+	orphanedBytes prometheus.Gauge
+	deletedDirs   prometheus.Counter
+	reclaimed     prometheus.Counter
+	failures      prometheus.Counter
+
+	mut     sync.Mutex
+	args    Arguments
+	orphans map[string]time.Time // Time each orphaned directory was first found.
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+}
+
+var _ service.Service = (*Service)(nil)
+
+// New returns a new, unstarted instance of the storage_gc service.
+func New(opts Options) *Service {
+	if opts.Logger == nil {
+		opts.Logger = log.NewNopLogger()
+	}
+
+	s := &Service{
+		opts:    opts,
+		exclude: make(map[string]struct{}, len(opts.Exclude)),
+		args:    DefaultArguments,
+		orphans: make(map[string]time.Time),
+		updated: make(chan struct{}, 1),
+
+		orphanedDirs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_storage_gc_orphaned_directories",
+			Help: "Number of data directories not owned by any running component.",
+		}),
+		orphanedBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_storage_gc_orphaned_bytes",
+			Help: "Total size of the data directories not owned by any running component.",
+		}),
+		deletedDirs: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_storage_gc_deleted_directories_total",
+			Help: "Total number of orphaned data directories deleted.",
+		}),
+		reclaimed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_storage_gc_reclaimed_bytes_total",
+			Help: "Total size of the orphaned data directories deleted.",
+		}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_storage_gc_failures_total",
+			Help: "Total number of failures to inspect or delete orphaned data directories.",
+		}),
+	}
+	for _, name := range opts.Exclude {
+		s.exclude[name] = struct{}{}
+	}
+	if opts.Registerer != nil {
+		opts.Registerer.MustRegister(s.orphanedDirs, s.orphanedBytes, s.deletedDirs, s.reclaimed, s.failures)
+	}
+	return s
+}
+
+// Definition returns the definition of the storage_gc service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  nil, // storage_gc has no dependencies.
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Run implements [service.Service]. The data directories are reconciled on
+// every interval, and after every update.
+func (s *Service) Run(ctx context.Context, host service.Host) error {
+	for {
+		s.reconcile(host, time.Now())
+
+		s.mut.Lock()
+		interval := s.args.Interval
+		s.mut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		case <-s.updated:
+		}
+	}
+}
+
+// reconcile finds the directories of the storage path which aren't owned by
+// a running component, and deletes the ones which have been orphaned for
+// longer than the grace period when deletion is enabled.
+func (s *Service) reconcile(host service.Host, now time.Time) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	infos, err := host.ListComponents("", component.InfoOptions{})
+	if err != nil {
+		level.Error(s.opts.Logger).Log("msg", "failed to list components", "err", err)
+		return
+	}
+	if len(infos) == 0 {
+		// Without components, every directory looks orphaned, such as when no
+		// configuration has been loaded yet. Wait for components to exist
+		// rather than risk deleting state which is still in use.
+		level.Debug(s.opts.Logger).Log("msg", "skipping reconciliation of data directories, no components are running")
+		return
+	}
+	owned := make(map[string]struct{}, len(infos))
+	for _, info := range infos {
+		owned[info.ID.LocalID] = struct{}{}
+	}
+
+	entries, err := os.ReadDir(s.opts.StoragePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	} else if err != nil {
+		s.failures.Inc()
+		level.Error(s.opts.Logger).Log("msg", "failed to read storage path", "path", s.opts.StoragePath, "err", err)
+		return
+	}
+
+	var (
+		found         = make(map[string]struct{})
+		orphanedDirs  int
+		orphanedBytes int64
+	)
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || s.isExcluded(name) {
+			continue
+		}
+		if _, ok := owned[name]; ok {
+			continue
+		}
+		found[name] = struct{}{}
+
+		firstSeen, ok := s.orphans[name]
+		if !ok {
+			firstSeen = now
+			s.orphans[name] = now
+			level.Info(s.opts.Logger).Log("msg", "found orphaned data directory", "dir", name, "grace_period", s.args.GracePeriod)
+		}
+
+		path := filepath.Join(s.opts.StoragePath, name)
+		size, err := dirSize(path)
+		if err != nil {
+			s.failures.Inc()
+			level.Warn(s.opts.Logger).Log("msg", "failed to compute the size of orphaned data directory", "dir", name, "err", err)
+		}
+
+		if s.args.Delete && now.Sub(firstSeen) >= s.args.GracePeriod {
+			if err := os.RemoveAll(path); err != nil {
+				s.failures.Inc()
+				level.Error(s.opts.Logger).Log("msg", "failed to delete orphaned data directory", "dir", name, "err", err)
+			} else {
+				s.deletedDirs.Inc()
+				s.reclaimed.Add(float64(size))
+				delete(s.orphans, name)
+				delete(found, name)
+				level.Info(s.opts.Logger).Log("msg", "deleted orphaned data directory", "dir", name, "bytes", size)
+				continue
+			}
+		}
+
+		orphanedDirs++
+		orphanedBytes += size
+	}
+
+	// Forget directories which are owned again or were deleted by someone
+	// else, so they get a new grace period if they are orphaned again.
+	for name := range s.orphans {
+		if _, ok := found[name]; !ok {
+			delete(s.orphans, name)
+		}
+	}
+
+	s.orphanedDirs.Set(float64(orphanedDirs))
+	s.orphanedBytes.Set(float64(orphanedBytes))
+}
+
+// isExcluded returns true if the entry of the storage path called name is
+// never owned by components.
+func (s *Service) isExcluded(name string) bool {
+	if _, ok := s.exclude[name]; ok {
+		return true
+	}
+	return strings.HasPrefix(name, importPrefix)
+}
+
+// dirSize returns the total size of the regular files under path.
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+	return size, err
+}
+
+// Update implements [service.Service].
+func (s *Service) Update(newConfig any) error {
+	args := newConfig.(Arguments)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.args = args
+
+	// Send an updated event if one wasn't already read.
+	select {
+	case s.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Data implements [service.Service]. It returns nil, as the storage_gc
+// service does not have any runtime data.
+func (s *Service) Data() any {
+	return nil
+}
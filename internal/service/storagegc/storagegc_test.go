@@ -0,0 +1,113 @@
+package storagegc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(``), &args))
+	require.Equal(t, DefaultArguments, args)
+
+	require.ErrorContains(t, river.Unmarshal([]byte(`interval = "0s"`), &args), "interval must be greater than 0")
+	require.ErrorContains(t, river.Unmarshal([]byte(`grace_period = "-1s"`), &args), "grace_period must not be negative")
+}
+
+type fakeHost struct {
+	service.Host
+	components []string
+}
+
+func (h *fakeHost) ListComponents(_ string, _ component.InfoOptions) ([]*component.Info, error) {
+	infos := make([]*component.Info, 0, len(h.components))
+	for _, id := range h.components {
+		infos = append(infos, &component.Info{ID: component.ID{LocalID: id}})
+	}
+	return infos, nil
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0640))
+}
+
+func TestReconcile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "prometheus.remote_write.kept", "wal", "00000000"), 10)
+	writeFile(t, filepath.Join(dir, "prometheus.remote_write.removed", "wal", "00000000"), 100)
+	writeFile(t, filepath.Join(dir, "loki.source.file.removed", "positions.yml"), 20)
+	writeFile(t, filepath.Join(dir, "import.git.modules", "repo", "main.river"), 5)
+	writeFile(t, filepath.Join(dir, "remotecfg", "config"), 5)
+	writeFile(t, filepath.Join(dir, "agent_seed.json"), 5)
+
+	host := &fakeHost{components: []string{"prometheus.remote_write.kept"}}
+	s := New(Options{
+		Registerer:  prometheus.NewRegistry(),
+		StoragePath: dir,
+		Exclude:     []string{"remotecfg"},
+	})
+	require.NoError(t, s.Update(Arguments{Interval: time.Hour, GracePeriod: time.Hour, Delete: true}))
+
+	start := time.Now()
+	s.reconcile(host, start)
+	require.Equal(t, 2.0, testutil.ToFloat64(s.orphanedDirs))
+	require.Equal(t, 120.0, testutil.ToFloat64(s.orphanedBytes))
+
+	// The component using the positions file is back before the end of the
+	// grace period, so its directory isn't deleted.
+	host.components = append(host.components, "loki.source.file.removed")
+	s.reconcile(host, start.Add(time.Hour))
+	require.Equal(t, 0.0, testutil.ToFloat64(s.orphanedDirs))
+	require.Equal(t, 1.0, testutil.ToFloat64(s.deletedDirs))
+	require.Equal(t, 100.0, testutil.ToFloat64(s.reclaimed))
+
+	for _, name := range []string{"prometheus.remote_write.kept", "loki.source.file.removed", "import.git.modules", "remotecfg", "agent_seed.json"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+	}
+	require.NoDirExists(t, filepath.Join(dir, "prometheus.remote_write.removed"))
+
+	// The grace period restarts for directories orphaned again.
+	host.components = host.components[:1]
+	s.reconcile(host, start.Add(2*time.Hour))
+	s.reconcile(host, start.Add(2*time.Hour+time.Minute))
+	require.DirExists(t, filepath.Join(dir, "loki.source.file.removed"))
+	require.Equal(t, 1.0, testutil.ToFloat64(s.orphanedDirs))
+}
+
+func TestReconcile_NoDelete(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "prometheus.remote_write.removed", "wal", "00000000"), 100)
+
+	host := &fakeHost{components: []string{"prometheus.remote_write.kept"}}
+	s := New(Options{Registerer: prometheus.NewRegistry(), StoragePath: dir})
+	require.NoError(t, s.Update(Arguments{Interval: time.Hour, GracePeriod: 0}))
+
+	s.reconcile(host, time.Now())
+	require.DirExists(t, filepath.Join(dir, "prometheus.remote_write.removed"))
+	require.Equal(t, 1.0, testutil.ToFloat64(s.orphanedDirs))
+	require.Equal(t, 100.0, testutil.ToFloat64(s.orphanedBytes))
+}
+
+func TestReconcile_NoComponents(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "prometheus.remote_write.removed", "wal", "00000000"), 100)
+
+	s := New(Options{Registerer: prometheus.NewRegistry(), StoragePath: dir})
+	require.NoError(t, s.Update(Arguments{Interval: time.Hour, GracePeriod: 0, Delete: true}))
+
+	s.reconcile(&fakeHost{}, time.Now())
+	require.DirExists(t, filepath.Join(dir, "prometheus.remote_write.removed"))
+	require.Equal(t, 0.0, testutil.ToFloat64(s.orphanedDirs))
+}
@@ -0,0 +1,421 @@
+// Package synthetic implements the synthetic service, which exposes an HTTP
+// API to send synthetic samples and log entries to the receivers of running
+// components, so pipelines can be tested end to end.
+package synthetic
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/grafana/river/rivertypes"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// ServiceName defines the name used for the synthetic service.
+const ServiceName = "synthetic"
+
+// maxRequestSize is the maximum size of the body of a request.
+const maxRequestSize = 1 << 20
+
+// Arguments holds runtime settings for the synthetic service.
+type Arguments struct {
+	// BearerToken authenticates requests. The API is disabled when
+	// BearerToken is empty.
+	BearerToken rivertypes.Secret `river:"bearer_token,attr,optional"`
+
+	// MaxBatchSize is the maximum number of samples or log entries of a
+	// request.
+	MaxBatchSize int `river:"max_batch_size,attr,optional"`
+
+	// Timeout is the maximum time to wait for a receiver to accept the log
+	// entries of a request.
+	Timeout time.Duration `river:"timeout,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	MaxBatchSize: 100,
+	Timeout:      5 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.MaxBatchSize <= 0 {
+		return fmt.Errorf("max_batch_size must be greater than 0")
+	}
+	if a.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Options are used to configure the synthetic service. Options are constant
+// for the lifetime of the synthetic service.
+type Options struct {
+	Logger     log.Logger            // Where to send logs.
+	Registerer prometheus.Registerer // Where to register metrics.
+}
+
+// Service implements the synthetic service.
+type Service struct {
+	opts Options
+
+	injected *prometheus.CounterVec
+	rejected *prometheus.CounterVec
+
+	mut  sync.RWMutex
+	args Arguments
+}
+
+var (
+	_ service.Service             = (*Service)(nil)
+	_ http_service.ServiceHandler = (*Service)(nil)
+)
+
+// New returns a new, unstarted instance of the synthetic service.
+func New(opts Options) *Service {
+	if opts.Logger == nil {
+		opts.Logger = log.NewNopLogger()
+	}
+
+	s := &Service{
+		opts: opts,
+		args: DefaultArguments,
+
+		injected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_synthetic_injected_total",
+			Help: "Total number of synthetic samples and log entries sent to components, by component and type.",
+		}, []string{"component", "type"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_synthetic_rejected_requests_total",
+			Help: "Total number of rejected requests to send synthetic data, by reason.",
+		}, []string{"reason"}),
+	}
+	if opts.Registerer != nil {
+		opts.Registerer.MustRegister(s.injected, s.rejected)
+	}
+	return s
+}
+
+// Definition returns the definition of the synthetic service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  []string{http_service.ServiceName},
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Run implements [service.Service]. The synthetic service doesn't do any
+// work outside of handling requests.
+func (s *Service) Run(ctx context.Context, host service.Host) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements [service.Service].
+func (s *Service) Update(newConfig any) error {
+	args := newConfig.(Arguments)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.args = args
+	return nil
+}
+
+// Data implements [service.Service]. It returns nil, as the synthetic
+// service does not have any runtime data.
+func (s *Service) Data() any {
+	return nil
+}
+
+// ServiceHandler implements [http_service.ServiceHandler].
+func (s *Service) ServiceHandler(host service.Host) (base string, handler http.Handler) {
+	r := mux.NewRouter()
+	r.Handle("/api/v0/synthetic/metrics", s.authenticate(s.metricsHandler(host))).Methods(http.MethodPost)
+	r.Handle("/api/v0/synthetic/logs", s.authenticate(s.logsHandler(host))).Methods(http.MethodPost)
+	return "/api/v0/synthetic/", r
+}
+
+func (s *Service) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mut.RLock()
+		token := string(s.args.BearerToken)
+		s.mut.RUnlock()
+
+		if token == "" {
+			s.reject(w, "disabled", http.StatusNotFound, "the synthetic data API is disabled")
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			s.reject(w, "unauthorized", http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Service) reject(w http.ResponseWriter, reason string, code int, msg string) {
+	s.rejected.WithLabelValues(reason).Inc()
+	http.Error(w, msg, code)
+}
+
+// MetricsRequest is the body of a request to send synthetic samples.
+type MetricsRequest struct {
+	// Component is the ID of the component to send samples to, such as
+	// prometheus.relabel.default.
+	Component string   `json:"component"`
+	Samples   []Sample `json:"samples"`
+}
+
+// Sample is a synthetic sample.
+type Sample struct {
+	// Labels of the sample, including its __name__ label.
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+
+	// Timestamp of the sample, in milliseconds since the Unix epoch. Defaults
+	// to the time the request is received.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// LogsRequest is the body of a request to send synthetic log entries.
+type LogsRequest struct {
+	// Component is the ID of the component to send log entries to, such as
+	// loki.process.default.
+	Component string     `json:"component"`
+	Entries   []LogEntry `json:"entries"`
+}
+
+// LogEntry is a synthetic log entry.
+type LogEntry struct {
+	Labels map[string]string `json:"labels"`
+	Line   string            `json:"line"`
+
+	// Timestamp of the log entry. Defaults to the time the request is
+	// received.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Response is the body of the response to a successful request.
+type Response struct {
+	Component string `json:"component"`
+	Accepted  int    `json:"accepted"`
+}
+
+var (
+	appendableType   = reflect.TypeOf((*storage.Appendable)(nil)).Elem()
+	logsReceiverType = reflect.TypeOf((*loki.LogsReceiver)(nil)).Elem()
+)
+
+func (s *Service) metricsHandler(host service.Host) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MetricsRequest
+		if !s.decode(w, r, &req, func() int { return len(req.Samples) }) {
+			return
+		}
+		receiver, ok := s.findReceiver(w, host, req.Component, appendableType)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		app := receiver.(storage.Appendable).Appender(r.Context())
+		for i, sample := range req.Samples {
+			lset := labels.FromMap(sample.Labels)
+			if err := validateLabels(lset, true); err != nil {
+				_ = app.Rollback()
+				s.reject(w, "invalid_data", http.StatusBadRequest, fmt.Sprintf("sample %d: %s", i, err))
+				return
+			}
+			ts := sample.Timestamp
+			if ts == 0 {
+				ts = now.UnixMilli()
+			}
+			if _, err := app.Append(0, lset, ts, sample.Value); err != nil {
+				_ = app.Rollback()
+				http.Error(w, fmt.Sprintf("appending sample %d: %s", i, err), http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := app.Commit(); err != nil {
+			http.Error(w, fmt.Sprintf("committing samples: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		s.injected.WithLabelValues(req.Component, "sample").Add(float64(len(req.Samples)))
+		s.respond(w, r, req.Component, len(req.Samples))
+	}
+}
+
+func (s *Service) logsHandler(host service.Host) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LogsRequest
+		if !s.decode(w, r, &req, func() int { return len(req.Entries) }) {
+			return
+		}
+		receiver, ok := s.findReceiver(w, host, req.Component, logsReceiverType)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		entries := make([]loki.Entry, 0, len(req.Entries))
+		for i, e := range req.Entries {
+			if err := validateLabels(labels.FromMap(e.Labels), false); err != nil {
+				s.reject(w, "invalid_data", http.StatusBadRequest, fmt.Sprintf("entry %d: %s", i, err))
+				return
+			}
+			lset := make(model.LabelSet, len(e.Labels))
+			for n, v := range e.Labels {
+				lset[model.LabelName(n)] = model.LabelValue(v)
+			}
+			ts := e.Timestamp
+			if ts.IsZero() {
+				ts = now
+			}
+			entries = append(entries, loki.Entry{
+				Labels: lset,
+				Entry:  logproto.Entry{Timestamp: ts, Line: e.Line},
+			})
+		}
+
+		s.mut.RLock()
+		timeout := s.args.Timeout
+		s.mut.RUnlock()
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		ch := receiver.(loki.LogsReceiver).Chan()
+		for i, e := range entries {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				s.injected.WithLabelValues(req.Component, "log_entry").Add(float64(i))
+				http.Error(w, fmt.Sprintf("component didn't accept entry %d in time", i), http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		s.injected.WithLabelValues(req.Component, "log_entry").Add(float64(len(entries)))
+		s.respond(w, r, req.Component, len(entries))
+	}
+}
+
+// decode decodes the body of r into v, and checks that the number of items
+// returned by count doesn't exceed the maximum batch size. If decoding
+// fails, an error is written to w and decode returns false.
+func (s *Service) decode(w http.ResponseWriter, r *http.Request, v any, count func() int) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.reject(w, "too_large", http.StatusRequestEntityTooLarge, err.Error())
+			return false
+		}
+		s.reject(w, "invalid_data", http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return false
+	}
+
+	s.mut.RLock()
+	maxBatchSize := s.args.MaxBatchSize
+	s.mut.RUnlock()
+
+	switch n := count(); {
+	case n == 0:
+		s.reject(w, "invalid_data", http.StatusBadRequest, "request doesn't contain any data")
+		return false
+	case n > maxBatchSize:
+		s.reject(w, "too_large", http.StatusRequestEntityTooLarge, fmt.Sprintf("request contains %d items, more than the maximum of %d", n, maxBatchSize))
+		return false
+	}
+	return true
+}
+
+// findReceiver returns the first export of the component id which type is
+// typ. If the component doesn't exist or doesn't have such an export, an
+// error is written to w and findReceiver returns false.
+func (s *Service) findReceiver(w http.ResponseWriter, host service.Host, id string, typ reflect.Type) (any, bool) {
+	if id == "" {
+		s.reject(w, "invalid_data", http.StatusBadRequest, "component must be set")
+		return nil, false
+	}
+	info, err := host.GetComponent(component.ParseID(id), component.InfoOptions{GetExports: true})
+	if err != nil {
+		s.reject(w, "unknown_component", http.StatusNotFound, fmt.Sprintf("component %q not found", id))
+		return nil, false
+	}
+	if receiver := findExport(info.Exports, typ); receiver != nil {
+		return receiver, true
+	}
+	s.reject(w, "unknown_component", http.StatusBadRequest, fmt.Sprintf("component %q doesn't export a receiver for this type of data", id))
+	return nil, false
+}
+
+// findExport returns the first non-nil field of exports of type typ, or nil
+// if there isn't any.
+func findExport(exports component.Exports, typ reflect.Type) any {
+	v := reflect.ValueOf(exports)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Type() == typ && !f.IsNil() {
+			return f.Interface()
+		}
+	}
+	return nil
+}
+
+func validateLabels(lset labels.Labels, requireName bool) error {
+	if requireName && !model.IsValidMetricName(model.LabelValue(lset.Get(labels.MetricName))) {
+		return fmt.Errorf("invalid metric name %q", lset.Get(labels.MetricName))
+	}
+	var err error
+	lset.Range(func(l labels.Label) {
+		if err == nil && !model.LabelName(l.Name).IsValid() {
+			err = fmt.Errorf("invalid label name %q", l.Name)
+		}
+	})
+	return err
+}
+
+func (s *Service) respond(w http.ResponseWriter, r *http.Request, id string, accepted int) {
+	level.Info(s.opts.Logger).Log("msg", "sent synthetic data to component", "component", id, "count", accepted, "remote_addr", r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Response{Component: id, Accepted: accepted})
+}
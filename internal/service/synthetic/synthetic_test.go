@@ -0,0 +1,256 @@
+package synthetic
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`bearer_token = "secret"`), &args))
+	require.Equal(t, 100, args.MaxBatchSize)
+
+	err := river.Unmarshal([]byte(`max_batch_size = 0`), &args)
+	require.ErrorContains(t, err, "max_batch_size must be greater than 0")
+}
+
+type sample struct {
+	labels labels.Labels
+	ts     int64
+	value  float64
+}
+
+// metricsExports and logsExports mimic the exports of components receiving
+// metrics and logs.
+type metricsExports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+type logsExports struct {
+	Receiver loki.LogsReceiver `river:"receiver,attr"`
+}
+
+type fakeHost struct {
+	service.Host
+	components map[string]component.Exports
+}
+
+func (h fakeHost) GetComponent(id component.ID, _ component.InfoOptions) (*component.Info, error) {
+	exports, ok := h.components[id.String()]
+	if !ok {
+		return nil, component.ErrComponentNotFound
+	}
+	return &component.Info{ID: id, Exports: exports}, nil
+}
+
+func newTestServer(t *testing.T, args Arguments) (*Service, *httptest.Server, chan sample, loki.LogsReceiver) {
+	t.Helper()
+
+	samples := make(chan sample, 10)
+	ls := labelstore.New(nil, prom.NewRegistry())
+	receiver := prometheus.NewInterceptor(nil, ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, ts int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+			samples <- sample{labels: l, ts: ts, value: v}
+			return ref, nil
+		}),
+	)
+	logs := loki.NewLogsReceiverWithChannel(make(chan loki.Entry, 10))
+
+	host := fakeHost{components: map[string]component.Exports{
+		"prometheus.relabel.entry":                metricsExports{Receiver: receiver},
+		"module.file.pipeline/loki.process.entry": logsExports{Receiver: logs},
+		"discovery.file.targets":                  struct{}{},
+	}}
+
+	s := New(Options{Registerer: prom.NewRegistry()})
+	require.NoError(t, s.Update(args))
+	_, handler := s.ServiceHandler(host)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return s, srv, samples, logs
+}
+
+func post(t *testing.T, url, token, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, strings.TrimSpace(string(b))
+}
+
+func TestMetrics(t *testing.T) {
+	args := DefaultArguments
+	args.BearerToken = "secret"
+	s, srv, samples, _ := newTestServer(t, args)
+
+	code, body := post(t, srv.URL+"/api/v0/synthetic/metrics", "secret", `{
+		"component": "prometheus.relabel.entry",
+		"samples": [
+			{"labels": {"__name__": "synthetic_probe", "env": "prod"}, "value": 1, "timestamp": 1700000000000},
+			{"labels": {"__name__": "synthetic_probe", "env": "dev"}, "value": 2}
+		]
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+	require.JSONEq(t, `{"component": "prometheus.relabel.entry", "accepted": 2}`, body)
+
+	s1 := <-samples
+	require.Equal(t, labels.FromStrings("__name__", "synthetic_probe", "env", "prod"), s1.labels)
+	require.Equal(t, int64(1700000000000), s1.ts)
+	require.Equal(t, 1.0, s1.value)
+	s2 := <-samples
+	require.Equal(t, labels.FromStrings("__name__", "synthetic_probe", "env", "dev"), s2.labels)
+	require.InDelta(t, time.Now().UnixMilli(), s2.ts, float64(time.Minute.Milliseconds()))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(s.injected.WithLabelValues("prometheus.relabel.entry", "sample")))
+}
+
+func TestLogs(t *testing.T) {
+	args := DefaultArguments
+	args.BearerToken = "secret"
+	_, srv, _, logs := newTestServer(t, args)
+
+	code, body := post(t, srv.URL+"/api/v0/synthetic/logs", "secret", `{
+		"component": "module.file.pipeline/loki.process.entry",
+		"entries": [
+			{"labels": {"job": "synthetic"}, "line": "hello", "timestamp": "2024-01-02T03:04:05Z"}
+		]
+	}`)
+	require.Equal(t, http.StatusOK, code, body)
+
+	e := <-logs.Chan()
+	require.Equal(t, "hello", e.Line)
+	require.Equal(t, "synthetic", string(e.Labels["job"]))
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), e.Timestamp.UTC())
+}
+
+func TestLogs_Timeout(t *testing.T) {
+	args := DefaultArguments
+	args.BearerToken = "secret"
+	args.Timeout = 50 * time.Millisecond
+	s, srv, _, logs := newTestServer(t, args)
+
+	// Fill the channel of the receiver so it doesn't accept more entries.
+	for i := 0; i < cap(logs.Chan()); i++ {
+		logs.Chan() <- loki.Entry{}
+	}
+
+	code, body := post(t, srv.URL+"/api/v0/synthetic/logs", "secret", `{
+		"component": "module.file.pipeline/loki.process.entry",
+		"entries": [{"labels": {"job": "synthetic"}, "line": "hello"}]
+	}`)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "component didn't accept entry 0 in time", body)
+	require.Equal(t, 0.0, testutil.ToFloat64(s.injected.WithLabelValues("module.file.pipeline/loki.process.entry", "log_entry")))
+}
+
+func TestRejected(t *testing.T) {
+	args := DefaultArguments
+	args.BearerToken = "secret"
+	args.MaxBatchSize = 1
+	s, srv, _, _ := newTestServer(t, args)
+
+	tt := []struct {
+		name   string
+		path   string
+		token  string
+		body   string
+		code   int
+		reason string
+	}{
+		{
+			name:   "missing token",
+			path:   "metrics",
+			body:   `{}`,
+			code:   http.StatusUnauthorized,
+			reason: "unauthorized",
+		},
+		{
+			name:   "wrong token",
+			path:   "metrics",
+			token:  "guess",
+			body:   `{}`,
+			code:   http.StatusUnauthorized,
+			reason: "unauthorized",
+		},
+		{
+			name:   "unknown component",
+			path:   "metrics",
+			token:  "secret",
+			body:   `{"component": "prometheus.relabel.other", "samples": [{"labels": {"__name__": "up"}}]}`,
+			code:   http.StatusNotFound,
+			reason: "unknown_component",
+		},
+		{
+			name:   "component without receiver",
+			path:   "logs",
+			token:  "secret",
+			body:   `{"component": "prometheus.relabel.entry", "entries": [{"line": "hello"}]}`,
+			code:   http.StatusBadRequest,
+			reason: "unknown_component",
+		},
+		{
+			name:   "batch too large",
+			path:   "metrics",
+			token:  "secret",
+			body:   `{"component": "prometheus.relabel.entry", "samples": [{"labels": {"__name__": "up"}}, {"labels": {"__name__": "up"}}]}`,
+			code:   http.StatusRequestEntityTooLarge,
+			reason: "too_large",
+		},
+		{
+			name:   "invalid metric name",
+			path:   "metrics",
+			token:  "secret",
+			body:   `{"component": "prometheus.relabel.entry", "samples": [{"labels": {"__name__": "not a name"}}]}`,
+			code:   http.StatusBadRequest,
+			reason: "invalid_data",
+		},
+		{
+			name:   "empty batch",
+			path:   "logs",
+			token:  "secret",
+			body:   `{"component": "module.file.pipeline/loki.process.entry"}`,
+			code:   http.StatusBadRequest,
+			reason: "invalid_data",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			before := testutil.ToFloat64(s.rejected.WithLabelValues(tc.reason))
+			code, body := post(t, srv.URL+"/api/v0/synthetic/"+tc.path, tc.token, tc.body)
+			require.Equal(t, tc.code, code, body)
+			require.Equal(t, before+1, testutil.ToFloat64(s.rejected.WithLabelValues(tc.reason)))
+		})
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	_, srv, _, _ := newTestServer(t, DefaultArguments)
+
+	code, body := post(t, srv.URL+"/api/v0/synthetic/metrics", "", `{}`)
+	require.Equal(t, http.StatusNotFound, code)
+	require.Equal(t, "the synthetic data API is disabled", body)
+}
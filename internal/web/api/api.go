@@ -5,14 +5,26 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/boringcrypto"
 	"github.com/grafana/agent/internal/component"
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/component/discovery"
 	"github.com/grafana/agent/internal/service"
 	"github.com/grafana/agent/internal/service/cluster"
+	"github.com/grafana/agent/internal/service/drops"
+	"github.com/grafana/river"
 	"github.com/prometheus/prometheus/util/httputil"
 )
 
@@ -34,8 +46,18 @@ func (f *FlowAPI) RegisterRoutes(urlPrefix string, r *mux.Router) {
 
 	r.Handle(path.Join(urlPrefix, "/modules/{moduleID:.+}/components"), httputil.CompressionHandler{Handler: f.listComponentsHandler()})
 	r.Handle(path.Join(urlPrefix, "/components"), httputil.CompressionHandler{Handler: f.listComponentsHandler()})
+	r.Handle(path.Join(urlPrefix, "/modules/{moduleID:.+}/graph"), httputil.CompressionHandler{Handler: f.getGraphHandler()})
+	r.Handle(path.Join(urlPrefix, "/graph"), httputil.CompressionHandler{Handler: f.getGraphHandler()})
+	r.Handle(path.Join(urlPrefix, "/components/{id:.+}/exports/history"), httputil.CompressionHandler{Handler: f.getExportsHistoryHandler()})
+	r.Handle(path.Join(urlPrefix, "/components/{id:.+}/exports/diff"), httputil.CompressionHandler{Handler: f.getExportsDiffHandler()})
+	r.Handle(path.Join(urlPrefix, "/components/{id:.+}/errors/history"), httputil.CompressionHandler{Handler: f.getErrorsHistoryHandler()})
+	r.Handle(path.Join(urlPrefix, "/components/{id:.+}/exports/references"), httputil.CompressionHandler{Handler: f.getExportReferencesHandler()})
+	r.Handle(path.Join(urlPrefix, "/components/{id:.+}/relabel/preview"), httputil.CompressionHandler{Handler: f.previewRelabelHandler()})
 	r.Handle(path.Join(urlPrefix, "/components/{id:.+}"), httputil.CompressionHandler{Handler: f.getComponentHandler()})
+	r.Handle(path.Join(urlPrefix, "/snapshot"), httputil.CompressionHandler{Handler: f.getSnapshotHandler()})
 	r.Handle(path.Join(urlPrefix, "/peers"), httputil.CompressionHandler{Handler: f.getClusteringPeersHandler()})
+	r.Handle(path.Join(urlPrefix, "/crypto"), httputil.CompressionHandler{Handler: f.getCryptoHandler()})
+	r.Handle(path.Join(urlPrefix, "/drops"), httputil.CompressionHandler{Handler: f.getDropsHandler()})
 }
 
 func (f *FlowAPI) listComponentsHandler() http.HandlerFunc {
@@ -47,6 +69,12 @@ func (f *FlowAPI) listComponentsHandler() http.HandlerFunc {
 			moduleID = vars["moduleID"]
 		}
 
+		filter, err := parseMetaFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		components, err := f.flow.ListComponents(moduleID, component.InfoOptions{
 			GetHealth: true,
 		})
@@ -54,6 +82,7 @@ func (f *FlowAPI) listComponentsHandler() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		components = filterByMeta(components, filter)
 
 		bb, err := json.Marshal(components)
 		if err != nil {
@@ -64,6 +93,170 @@ func (f *FlowAPI) listComponentsHandler() http.HandlerFunc {
 	}
 }
 
+// parseMetaFilter returns the metadata labels which listed components must
+// have, from the meta query parameters of r. Each parameter is formatted as
+// key=value.
+func parseMetaFilter(r *http.Request) (map[string]string, error) {
+	params := r.URL.Query()["meta"]
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	filter := make(map[string]string, len(params))
+	for _, p := range params {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid meta filter %q, expected key=value", p)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// filterByMeta returns the components which have all the metadata labels of
+// filter.
+func filterByMeta(components []*component.Info, filter map[string]string) []*component.Info {
+	if len(filter) == 0 {
+		return components
+	}
+
+	res := make([]*component.Info, 0, len(components))
+	for _, c := range components {
+		if matchesMeta(c.Meta, filter) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func matchesMeta(meta, filter map[string]string) bool {
+	for key, value := range filter {
+		if v, ok := meta[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// getGraphHandler returns the dependency graph of the blocks of a module,
+// including the declare and import blocks defining its custom components. The
+// format query parameter selects the json (default) or dot output, and the
+// recursive query parameter includes the graphs of nested modules.
+func (f *FlowAPI) getGraphHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// moduleID is set from the /modules/{moduleID:.+}/graph route above but
+		// not from the /graph route.
+		var moduleID string
+		if vars := mux.Vars(r); vars != nil {
+			moduleID = vars["moduleID"]
+		}
+
+		provider, ok := f.flow.(component.GraphProvider)
+		if !ok {
+			http.Error(w, "dependency graph not supported", http.StatusNotImplemented)
+			return
+		}
+
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "dot" {
+			http.Error(w, fmt.Sprintf("invalid format %q, expected json or dot", format), http.StatusBadRequest)
+			return
+		}
+		var recursive bool
+		if v := query.Get("recursive"); v != "" {
+			var err error
+			if recursive, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid recursive %q: must be a boolean", v), http.StatusBadRequest)
+				return
+			}
+		}
+
+		graph, err := provider.GetGraph(moduleID, recursive)
+		if errors.Is(err, component.ErrModuleNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "dot" {
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			_, _ = w.Write(graphDOT(graph))
+			return
+		}
+		bb, err := json.Marshal(graph)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// graphDOT renders g in the DOT language of Graphviz. The blocks of each
+// module are grouped in a cluster.
+func graphDOT(g *component.Graph) []byte {
+	var (
+		buf     bytes.Buffer
+		modules []string
+		nodes   = make(map[string][]component.GraphNode)
+	)
+	for _, n := range g.Nodes {
+		if _, ok := nodes[n.ModuleID]; !ok {
+			modules = append(modules, n.ModuleID)
+		}
+		nodes[n.ModuleID] = append(nodes[n.ModuleID], n)
+	}
+	sort.Strings(modules)
+
+	buf.WriteString("digraph {\n")
+	for _, module := range modules {
+		indent := "\t"
+		if module != "" {
+			fmt.Fprintf(&buf, "\tsubgraph %s {\n", strconv.Quote("cluster_"+module))
+			fmt.Fprintf(&buf, "\t\tlabel=%s;\n", strconv.Quote(module))
+			indent = "\t\t"
+		}
+		for _, n := range nodes[module] {
+			fmt.Fprintf(&buf, "%s%s [label=%s, shape=%s];\n", indent, strconv.Quote(n.ID), strconv.Quote(n.LocalID), dotShape(n.Kind))
+		}
+		if module != "" {
+			buf.WriteString("\t}\n")
+		}
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&buf, "\t%s -> %s", strconv.Quote(e.From), strconv.Quote(e.To))
+		if e.Kind == component.GraphEdgeDefinition {
+			buf.WriteString(" [style=dashed]")
+		}
+		buf.WriteString(";\n")
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func dotShape(kind string) string {
+	switch kind {
+	case component.GraphNodeCustomComponent:
+		return "box3d"
+	case component.GraphNodeDeclare:
+		return "component"
+	case component.GraphNodeImport:
+		return "folder"
+	case component.GraphNodeService:
+		return "ellipse"
+	case component.GraphNodeConfig:
+		return "note"
+	default:
+		return "box"
+	}
+}
+
 func (f *FlowAPI) getComponentHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -89,6 +282,240 @@ func (f *FlowAPI) getComponentHandler() http.HandlerFunc {
 	}
 }
 
+// getSnapshotHandler returns the health, arguments and exports of the
+// components listed by the id query parameters, captured while none of them
+// is being evaluated.
+func (f *FlowAPI) getSnapshotHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := f.flow.(component.SnapshotProvider)
+		if !ok {
+			http.Error(w, "component snapshots not supported", http.StatusNotImplemented)
+			return
+		}
+
+		params := r.URL.Query()["id"]
+		if len(params) == 0 {
+			http.Error(w, "at least one id query parameter is required", http.StatusBadRequest)
+			return
+		}
+		ids := make([]component.ID, len(params))
+		for i, p := range params {
+			ids[i] = component.ParseID(p)
+		}
+
+		snapshot, err := provider.GetComponentsSnapshot(ids, component.InfoOptions{
+			GetHealth:    true,
+			GetArguments: true,
+			GetExports:   true,
+		})
+		if errors.Is(err, component.ErrComponentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := json.Marshal(snapshot)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+func (f *FlowAPI) getExportsHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		history, ok := f.getExportsHistory(w, r)
+		if !ok {
+			return
+		}
+
+		bb, err := json.Marshal(history)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+func (f *FlowAPI) getExportsDiffHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		history, ok := f.getExportsHistory(w, r)
+		if !ok {
+			return
+		}
+
+		diffs, err := component.DiffExportsHistory(history)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := json.Marshal(diffs)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// getExportsHistory returns the exports history of the component requested
+// by r. If the component doesn't exist, a 404 is written to w and ok is false.
+func (f *FlowAPI) getExportsHistory(w http.ResponseWriter, r *http.Request) (history []component.ExportsRecord, ok bool) {
+	vars := mux.Vars(r)
+	requestedComponent := component.ParseID(vars["id"])
+
+	info, err := f.flow.GetComponent(requestedComponent, component.InfoOptions{
+		GetExportsHistory: true,
+	})
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	history = info.ExportsHistory
+	if history == nil {
+		history = []component.ExportsRecord{}
+	}
+	return history, true
+}
+
+func (f *FlowAPI) getErrorsHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		requestedComponent := component.ParseID(vars["id"])
+
+		info, err := f.flow.GetComponent(requestedComponent, component.InfoOptions{
+			GetErrorsHistory: true,
+		})
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		history := info.ErrorsHistory
+		if history == nil {
+			history = []component.ErrorRecord{}
+		}
+
+		bb, err := json.Marshal(history)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// getExportReferencesHandler returns the expressions which reference the
+// exports of a component. The optional field query parameter limits the
+// references to the ones of an export field, such as "receiver", including
+// references to fields nested in it.
+func (f *FlowAPI) getExportReferencesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		requestedComponent := component.ParseID(vars["id"])
+
+		info, err := f.flow.GetComponent(requestedComponent, component.InfoOptions{
+			GetExportReferences: true,
+		})
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		field := r.URL.Query().Get("field")
+		refs := []component.ExportReference{}
+		for _, ref := range info.ExportReferences {
+			if field == "" || ref.Field == field || strings.HasPrefix(ref.Field, field+".") {
+				refs = append(refs, ref)
+			}
+		}
+
+		bb, err := json.Marshal(refs)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+const (
+	// defaultRelabelPreviewSamples is the number of relabeled targets returned
+	// by a relabel preview when the samples query parameter isn't set.
+	defaultRelabelPreviewSamples = 10
+
+	// maxRelabelPreviewBodySize is the maximum size of the relabeling rules
+	// of a relabel preview.
+	maxRelabelPreviewBodySize = 1 << 20
+)
+
+// previewRelabelHandler applies the relabeling rules in the body of the
+// request, written as River rule blocks, to the current targets exported by
+// a component. The targets aren't changed, so candidate rules can be checked
+// before being applied. The optional samples query parameter sets how many
+// relabeled targets are returned.
+func (f *FlowAPI) previewRelabelHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		samples := defaultRelabelPreviewSamples
+		if v := r.URL.Query().Get("samples"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, fmt.Sprintf("invalid samples %q: must be a non-negative integer", v), http.StatusBadRequest)
+				return
+			}
+			samples = n
+		}
+
+		bb, err := io.ReadAll(io.LimitReader(r.Body, maxRelabelPreviewBodySize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var rules struct {
+			Rules []*flow_relabel.Config `river:"rule,block,optional"`
+		}
+		if err := river.Unmarshal(bb, &rules); err != nil {
+			http.Error(w, fmt.Sprintf("invalid relabeling rules: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		vars := mux.Vars(r)
+		requestedComponent := component.ParseID(vars["id"])
+
+		info, err := f.flow.GetComponent(requestedComponent, component.InfoOptions{
+			GetExports: true,
+		})
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		targets, ok := discovery.ExportedTargets(info.Exports)
+		if !ok {
+			http.Error(w, fmt.Sprintf("component %q doesn't export targets", requestedComponent), http.StatusBadRequest)
+			return
+		}
+
+		bb, err = json.Marshal(discovery.PreviewRelabel(targets, rules.Rules, samples))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
 func (f *FlowAPI) getClusteringPeersHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
 		// TODO(@tpaschalis) Detect if clustering is disabled and propagate to
@@ -107,3 +534,36 @@ func (f *FlowAPI) getClusteringPeersHandler() http.HandlerFunc {
 		_, _ = w.Write(bb)
 	}
 }
+
+// cryptoInfo describes the cryptography used by the agent.
+type cryptoInfo struct {
+	// BoringCrypto is true if the agent was built with FIPS-validated
+	// cryptography and rejects non-FIPS-approved settings.
+	BoringCrypto bool `json:"boringcrypto"`
+}
+
+func (f *FlowAPI) getCryptoHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		bb, err := json.Marshal(cryptoInfo{BoringCrypto: boringcrypto.Enabled})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+func (f *FlowAPI) getDropsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		summary := []drops.Drops{}
+		if svc, ok := f.flow.GetService(drops.ServiceName); ok {
+			summary = svc.Data().(*drops.Registry).Summary()
+		}
+		bb, err := json.Marshal(summary)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
@@ -13,17 +13,42 @@ interface ComponentListProps {
   handleSorting?: (sortField: string, sortOrder: SortOrder) => void;
 }
 
-const TABLEHEADERS = ['Health', 'ID'];
+const TABLEHEADERS = ['Health', 'ID', 'Meta'];
 
 const ComponentList = ({ components, moduleID, handleSorting }: ComponentListProps) => {
   const tableStyles = { width: '130px' };
   const pathPrefix = moduleID ? moduleID + '/' : '';
 
+  /**
+   * Renders the metadata labels of a component. Labels link to the list of
+   * components with the same label, which is only available for the root
+   * module.
+   */
+  const renderMeta = (meta?: Record<string, string>) => {
+    return Object.entries(meta ?? {})
+      .sort(([a], [b]) => a.localeCompare(b))
+      .map(([key, value]) => {
+        const label = key + '=' + value;
+        if (moduleID) {
+          return (
+            <span key={key} className={styles.metaLabel}>
+              {label}
+            </span>
+          );
+        }
+        return (
+          <NavLink key={key} to={'/?meta=' + encodeURIComponent(label)} className={styles.metaLabel}>
+            {label}
+          </NavLink>
+        );
+      });
+  };
+
   /**
    * Custom renderer for table data
    */
   const renderTableData = () => {
-    return components.map(({ health, localID: id }) => (
+    return components.map(({ health, localID: id, meta }) => (
       <tr key={id} style={{ lineHeight: '2.5' }}>
         <td>
           <HealthLabel health={health.state} />
@@ -34,6 +59,7 @@ const ComponentList = ({ components, moduleID, handleSorting }: ComponentListPro
             View
           </NavLink>
         </td>
+        <td>{renderMeta(meta)}</td>
       </tr>
     ));
   };
@@ -8,7 +8,8 @@ import { partitionBody } from '../../utils/partition';
 import ComponentBody from './ComponentBody';
 import ComponentList from './ComponentList';
 import { HealthLabel } from './HealthLabel';
-import { ComponentDetail, ComponentInfo, PartitionedBody } from './types';
+import Table from './Table';
+import { ComponentDetail, ComponentInfo, ModuleSource, PartitionedBody } from './types';
 
 import styles from './ComponentView.module.css';
 
@@ -27,6 +28,26 @@ export const ComponentView: FC<ComponentViewProps> = (props) => {
   const exportsPartition = props.component.exports && partitionBody(props.component.exports, 'Exports');
   const debugPartition = props.component.debugInfo && partitionBody(props.component.debugInfo, 'Debug info');
 
+  function renderModuleSource(source: ModuleSource): ReactElement[] {
+    const rows: [string, string | undefined][] = [
+      ['Imports', source.imports.join(' / ')],
+      ['Location', source.location],
+      ['Version', source.version],
+      ['SHA-256', source.sha256],
+      ['Declare', source.declare],
+    ];
+    return rows
+      .filter(([, value]) => value)
+      .map(([name, value]) => (
+        <tr key={name}>
+          <td className={styles.nameColumn}>{name}</td>
+          <td>
+            <pre className={styles.pre}>{value}</pre>
+          </td>
+        </tr>
+      ));
+  }
+
   function partitionTOC(partition: PartitionedBody): ReactElement {
     return (
       <li>
@@ -55,6 +76,13 @@ export const ComponentView: FC<ComponentViewProps> = (props) => {
               {props.component.localID}
             </Link>
           </li>
+          {props.component.moduleSource && (
+            <li>
+              <Link to="#module-source" target="_top">
+                Module source
+              </Link>
+            </li>
+          )}
           {argsPartition && partitionTOC(argsPartition)}
           {exportsPartition && partitionTOC(exportsPartition)}
           {debugPartition && partitionTOC(debugPartition)}
@@ -112,6 +140,21 @@ export const ComponentView: FC<ComponentViewProps> = (props) => {
           </blockquote>
         )}
 
+        {props.component.moduleSource && (
+          <section id="module-source">
+            <h2>Module source</h2>
+            <div className={styles.sectionContent}>
+              <div className={styles.list}>
+                <Table
+                  tableHeaders={['Name', 'Value']}
+                  renderTableData={() => renderModuleSource(props.component.moduleSource as ModuleSource)}
+                  style={{ width: '210px' }}
+                />
+              </div>
+            </div>
+          </section>
+        )}
+
         <ComponentBody partition={argsPartition} />
         {exportsPartition && <ComponentBody partition={exportsPartition} />}
         {debugPartition && <ComponentBody partition={debugPartition} />}
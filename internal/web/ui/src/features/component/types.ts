@@ -27,6 +27,12 @@ export interface ComponentInfo {
    */
   label?: string;
 
+  /**
+   * Metadata labels of the component from its meta block, such as the team
+   * owning it.
+   */
+  meta?: Record<string, string>;
+
   /**
    * Health information for a component. Components always have a health status
    * associated with them.
@@ -42,6 +48,38 @@ export interface ComponentInfo {
    * IDs of components which this component is referencing.
    */
   referencesTo: string[];
+
+  /**
+   * Number of modules or custom components this component is nested under.
+   * Components defined in the root configuration have a nesting depth of 0.
+   */
+  nestingDepth: number;
+
+  /**
+   * The imported module which defines the component. Only set for custom
+   * components whose declare block was imported.
+   */
+  moduleSource?: ModuleSource;
+}
+
+/**
+ * ModuleSource describes the imported module which defines a custom
+ * component.
+ */
+export interface ModuleSource {
+  /**
+   * IDs of the import blocks the module was imported through, starting from
+   * the import block of the module of the custom component.
+   */
+  imports: string[];
+  /** Where the module was imported from, such as a URL or a path. */
+  location?: string;
+  /** Version the module was resolved to, if its source has versions. */
+  version?: string;
+  /** SHA-256 digest of the content of the module. */
+  sha256: string;
+  /** Label of the declare block of the custom component. */
+  declare: string;
 }
 
 /**
@@ -7,16 +7,23 @@ import { ComponentInfo } from '../features/component/types';
  *
  * @param fromComponent The component requesting component info. Required for
  * determining the proper list of components from the context of a module.
+ * @param metaFilters Metadata labels, formatted as key=value, which the
+ * returned components must have.
  */
 export const useComponentInfo = (
-  moduleID: string
+  moduleID: string,
+  metaFilters: string[] = []
 ): [ComponentInfo[], React.Dispatch<React.SetStateAction<ComponentInfo[]>>] => {
   const [components, setComponents] = useState<ComponentInfo[]>([]);
+  const query = metaFilters.map((filter) => 'meta=' + encodeURIComponent(filter)).join('&');
 
   useEffect(
     function () {
       const worker = async () => {
-        const infoPath = moduleID === '' ? './api/v0/web/components' : `./api/v0/web/modules/${moduleID}/components`;
+        let infoPath = moduleID === '' ? './api/v0/web/components' : `./api/v0/web/modules/${moduleID}/components`;
+        if (query !== '') {
+          infoPath += '?' + query;
+        }
 
         // Request is relative to the <base> tag inside of <head>.
         const resp = await fetch(infoPath, {
@@ -28,7 +35,7 @@ export const useComponentInfo = (
 
       worker().catch(console.error);
     },
-    [moduleID]
+    [moduleID, query]
   );
 
   return [components, setComponents];
@@ -1,3 +1,4 @@
+import { useSearchParams } from 'react-router-dom';
 import { faCubes } from '@fortawesome/free-solid-svg-icons';
 
 import ComponentList from '../features/component/ComponentList';
@@ -8,6 +9,11 @@ import { useComponentInfo } from '../hooks/componentInfo';
 const fieldMappings: { [key: string]: (comp: ComponentInfo) => string | undefined } = {
   Health: (comp) => comp.health?.state?.toString(),
   ID: (comp) => comp.localID,
+  Meta: (comp) =>
+    Object.entries(comp.meta ?? {})
+      .map(([key, value]) => key + '=' + value)
+      .sort()
+      .join(',') || undefined,
   // Add new fields if needed here.
 };
 
@@ -17,7 +23,9 @@ function getSortValue(component: ComponentInfo, field: string): string | undefin
 }
 
 function PageComponentList() {
-  const [components, setComponents] = useComponentInfo('');
+  const [searchParams] = useSearchParams();
+  const metaFilters = searchParams.getAll('meta');
+  const [components, setComponents] = useComponentInfo('', metaFilters);
 
   // TODO: make this sorting logic reusable
   const handleSorting = (sortField: string, sortOrder: SortOrder): void => {
@@ -37,7 +45,11 @@ function PageComponentList() {
   };
 
   return (
-    <Page name="Components" desc="List of defined components" icon={faCubes}>
+    <Page
+      name="Components"
+      desc={metaFilters.length > 0 ? 'Components with ' + metaFilters.join(', ') : 'List of defined components'}
+      icon={faCubes}
+    >
       <ComponentList components={components} handleSorting={handleSorting} />
     </Page>
   );